@@ -0,0 +1,4 @@
+// Package deliveryv1 contains the generated gRPC bindings for the delivery API
+package deliveryv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative delivery/v1/delivery.proto
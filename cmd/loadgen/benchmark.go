@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// pacerTick is how often the pacer releases the requests that have come due
+const pacerTick = time.Millisecond
+
+// report summarizes a benchmark run. Latencies are in milliseconds.
+type report struct {
+	DurationSeconds float64 `json:"duration_seconds"`
+	Requests        int     `json:"requests"`
+	Throughput      float64 `json:"throughput"`
+	// Dropped counts requests not sent because every worker was busy
+	Dropped    int64   `json:"dropped"`
+	Errors     int     `json:"errors"`
+	FirstError string  `json:"first_error,omitempty"`
+	MatchRate  float64 `json:"match_rate"`
+	Latency    struct {
+		P50  float64 `json:"p50"`
+		P90  float64 `json:"p90"`
+		P95  float64 `json:"p95"`
+		P99  float64 `json:"p99"`
+		P999 float64 `json:"p999"`
+		Max  float64 `json:"max"`
+	} `json:"latency_ms"`
+}
+
+// workerStats is what a single worker observed
+type workerStats struct {
+	latencies []time.Duration
+	matched   int
+	errors    int
+	firstErr  error
+}
+
+// benchmark sends requests from source to t at opts.qps for opts.duration,
+// or until ctx is cancelled
+func benchmark(ctx context.Context, t target, source requestSource, opts *options) *report {
+	ctx, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+
+	jobs := make(chan struct{}, opts.concurrency)
+	stats := make([]workerStats, opts.concurrency)
+	var wg sync.WaitGroup
+	for i := range stats {
+		wg.Add(1)
+		go func(w *workerStats, r *rand.Rand) {
+			defer wg.Done()
+			for range jobs {
+				req := source(r)
+				start := time.Now()
+				matched, err := t.deliver(context.WithoutCancel(ctx), req)
+				w.latencies = append(w.latencies, time.Since(start))
+				switch {
+				case err != nil:
+					w.errors++
+					if w.firstErr == nil {
+						w.firstErr = err
+					}
+				case matched:
+					w.matched++
+				}
+			}
+		}(&stats[i], rand.New(rand.NewPCG(opts.seed, uint64(i))))
+	}
+
+	// Release requests as they come due rather than one per tick, so rates
+	// above the tick frequency are reached
+	start := time.Now()
+	ticker := time.NewTicker(max(pacerTick, time.Duration(float64(time.Second)/opts.qps)))
+	var sent, dropped int64
+pace:
+	for {
+		select {
+		case <-ctx.Done():
+			break pace
+		case now := <-ticker.C:
+			due := int64(now.Sub(start).Seconds() * opts.qps)
+			for ; sent < due; sent++ {
+				select {
+				case jobs <- struct{}{}:
+				default:
+					dropped++
+				}
+			}
+		}
+	}
+	ticker.Stop()
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	r := &report{DurationSeconds: elapsed.Seconds(), Dropped: dropped}
+	var latencies []time.Duration
+	matched := 0
+	for _, w := range stats {
+		latencies = append(latencies, w.latencies...)
+		matched += w.matched
+		r.Errors += w.errors
+		if r.FirstError == "" && w.firstErr != nil {
+			r.FirstError = w.firstErr.Error()
+		}
+	}
+	r.Requests = len(latencies)
+	if r.Requests == 0 {
+		return r
+	}
+	r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	r.MatchRate = float64(matched) / float64(r.Requests)
+
+	slices.Sort(latencies)
+	r.Latency.P50 = percentile(latencies, 0.50)
+	r.Latency.P90 = percentile(latencies, 0.90)
+	r.Latency.P95 = percentile(latencies, 0.95)
+	r.Latency.P99 = percentile(latencies, 0.99)
+	r.Latency.P999 = percentile(latencies, 0.999)
+	r.Latency.Max = milliseconds(latencies[len(latencies)-1])
+	return r
+}
+
+// percentile returns the q-th percentile of sorted latencies in milliseconds
+func percentile(sorted []time.Duration, q float64) float64 {
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return milliseconds(sorted[max(rank, 0)])
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,185 @@
+// Command loadgen sends delivery requests to the targeting engine at a fixed
+// rate and reports latency percentiles, so matcher performance regressions
+// are caught before release.
+//
+// Requests are either synthetic, drawn from weighted distributions of
+// countries, OSes, apps and device types, or replayed from a JSON Lines file.
+// They are sent to a running server over HTTP, or matched in-process through
+// pkg/engine against synthetic campaigns built from the same distributions.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Targets requests can be sent to
+const (
+	modeInProcess = "inprocess"
+	modeHTTP      = "http"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// options holds the command line flags
+type options struct {
+	mode        string
+	server      string
+	apiKey      string
+	tenant      string
+	timeout     time.Duration
+	qps         float64
+	duration    time.Duration
+	concurrency int
+	requests    string
+	seed        uint64
+	output      string
+	maxP99      time.Duration
+
+	countries   string
+	os          string
+	apps        string
+	deviceTypes string
+	users       int
+	limit       int
+
+	campaigns int
+	cacheSize int
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &options{}
+	cmd := &cobra.Command{
+		Use:   "loadgen",
+		Short: "Benchmark delivery latency at a fixed request rate",
+		Long: "Send delivery requests at a fixed rate for a while and report latency percentiles. " +
+			"Requests that can't be sent on time because every worker is busy are counted as dropped rather than delayed, " +
+			"so an overloaded target shows up in the report.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			return run(ctx, opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.mode, "mode", modeInProcess, "where requests are sent: inprocess or http")
+	flags.StringVar(&opts.server, "server", "http://localhost:8080", "base URL of the targeting engine, with --mode http")
+	flags.StringVar(&opts.apiKey, "api-key", os.Getenv("LOADGEN_API_KEY"), "API key sent in X-API-Key, with --mode http")
+	flags.StringVar(&opts.tenant, "tenant", "", "tenant sent in X-Tenant-ID, with --mode http")
+	flags.DurationVar(&opts.timeout, "timeout", 5*time.Second, "timeout of each HTTP request")
+	flags.Float64Var(&opts.qps, "qps", 1000, "requests sent per second")
+	flags.DurationVar(&opts.duration, "duration", 30*time.Second, "how long to send requests")
+	flags.IntVar(&opts.concurrency, "concurrency", 32, "maximum requests in flight")
+	flags.StringVar(&opts.requests, "requests", "", "JSON Lines file of delivery requests to replay in a loop instead of synthetic ones")
+	flags.Uint64Var(&opts.seed, "seed", 0, "seed of the synthetic requests and campaigns; zero picks a random one")
+	flags.StringVarP(&opts.output, "output", "o", outputTable, "output format: table or json")
+	flags.DurationVar(&opts.maxP99, "max-p99", 0, "fail if the 99th percentile latency exceeds this")
+
+	flags.StringVar(&opts.countries, "countries", "US=40,IN=25,BR=10,DE=10,GB=10,CA=5", "weighted countries of synthetic requests")
+	flags.StringVar(&opts.os, "os", "android=70,ios=30", "weighted OSes of synthetic requests")
+	flags.StringVar(&opts.apps, "apps", "com.example.game=40,com.example.news=30,com.example.music=20,com.example.finance=10", "weighted apps of synthetic requests")
+	flags.StringVar(&opts.deviceTypes, "device-types", "phone=80,tablet=15,ctv=5", "weighted device types of synthetic requests")
+	flags.IntVar(&opts.users, "users", 0, "distinct user IDs of synthetic requests; zero sends none")
+	flags.IntVar(&opts.limit, "limit", 0, "campaigns asked for by synthetic requests; zero asks for every match")
+
+	flags.IntVar(&opts.campaigns, "campaigns", 500, "synthetic campaigns matched against, with --mode inprocess")
+	flags.IntVar(&opts.cacheSize, "cache-size", 0, "query cache size, with --mode inprocess; zero uses the engine default")
+	return cmd
+}
+
+// run builds the request source and target from opts and runs the benchmark
+func run(ctx context.Context, opts *options) error {
+	switch {
+	case opts.output != outputTable && opts.output != outputJSON:
+		return fmt.Errorf("invalid output %q, expected %s or %s", opts.output, outputTable, outputJSON)
+	case opts.qps <= 0:
+		return fmt.Errorf("--qps must be positive")
+	case opts.duration <= 0:
+		return fmt.Errorf("--duration must be positive")
+	case opts.concurrency <= 0:
+		return fmt.Errorf("--concurrency must be positive")
+	}
+	if opts.seed == 0 {
+		opts.seed = rand.Uint64()
+	}
+
+	dims, err := parseDimensions(opts)
+	if err != nil {
+		return err
+	}
+	source := dims.request
+	if opts.requests != "" {
+		if source, err = readRequests(opts.requests); err != nil {
+			return err
+		}
+	}
+
+	var t target
+	switch opts.mode {
+	case modeInProcess:
+		if t, err = newEngineTarget(ctx, dims, opts); err != nil {
+			return err
+		}
+	case modeHTTP:
+		t = newHTTPTarget(opts)
+	default:
+		return fmt.Errorf("invalid mode %q, expected %s or %s", opts.mode, modeInProcess, modeHTTP)
+	}
+
+	report := benchmark(ctx, t, source, opts)
+	if err := printReport(opts.output, report); err != nil {
+		return err
+	}
+	if opts.maxP99 > 0 && report.Latency.P99 > float64(opts.maxP99)/float64(time.Millisecond) {
+		return fmt.Errorf("p99 latency %.3fms exceeds %s", report.Latency.P99, opts.maxP99)
+	}
+	return nil
+}
+
+// printReport writes the report as indented JSON or as a table
+func printReport(output string, r *report) error {
+	if output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "DURATION\t%.1fs\n", r.DurationSeconds)
+	fmt.Fprintf(w, "REQUESTS\t%d\n", r.Requests)
+	fmt.Fprintf(w, "THROUGHPUT\t%.1f/s\n", r.Throughput)
+	fmt.Fprintf(w, "DROPPED\t%d\n", r.Dropped)
+	fmt.Fprintf(w, "ERRORS\t%d\n", r.Errors)
+	fmt.Fprintf(w, "MATCH RATE\t%.1f%%\n", r.MatchRate*100)
+	fmt.Fprintf(w, "P50\t%.3fms\n", r.Latency.P50)
+	fmt.Fprintf(w, "P90\t%.3fms\n", r.Latency.P90)
+	fmt.Fprintf(w, "P95\t%.3fms\n", r.Latency.P95)
+	fmt.Fprintf(w, "P99\t%.3fms\n", r.Latency.P99)
+	fmt.Fprintf(w, "P99.9\t%.3fms\n", r.Latency.P999)
+	fmt.Fprintf(w, "MAX\t%.3fms\n", r.Latency.Max)
+	if r.FirstError != "" {
+		fmt.Fprintf(w, "FIRST ERROR\t%s\n", r.FirstError)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// requestSource returns the next request to send. Each worker passes its own
+// random source.
+type requestSource func(r *rand.Rand) *model.DeliveryRequest
+
+// distribution is a set of values picked with probability proportional to
+// their weights
+type distribution struct {
+	values     []string
+	cumulative []int
+}
+
+// parseDistribution parses a distribution written as value=weight pairs
+// separated by commas, e.g. "US=60,IN=40". A value without a weight counts
+// once.
+func parseDistribution(name, s string) (*distribution, error) {
+	d := &distribution{}
+	total := 0
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		value, weightText, hasWeight := strings.Cut(pair, "=")
+		weight := 1
+		if hasWeight {
+			var err error
+			if weight, err = strconv.Atoi(strings.TrimSpace(weightText)); err != nil || weight <= 0 {
+				return nil, fmt.Errorf("invalid --%s weight %q, expected a positive integer", name, weightText)
+			}
+		}
+		total += weight
+		d.values = append(d.values, strings.TrimSpace(value))
+		d.cumulative = append(d.cumulative, total)
+	}
+	if len(d.values) == 0 {
+		return nil, fmt.Errorf("--%s needs at least one value", name)
+	}
+	return d, nil
+}
+
+// pick returns a random value
+func (d *distribution) pick(r *rand.Rand) string {
+	n := r.IntN(d.cumulative[len(d.cumulative)-1])
+	for i, bound := range d.cumulative {
+		if n < bound {
+			return d.values[i]
+		}
+	}
+	return d.values[len(d.values)-1]
+}
+
+// dimensions are the distributions synthetic requests and campaigns are
+// drawn from
+type dimensions struct {
+	countries   *distribution
+	os          *distribution
+	apps        *distribution
+	deviceTypes *distribution
+	users       int
+	limit       int
+}
+
+func parseDimensions(opts *options) (*dimensions, error) {
+	d := &dimensions{users: opts.users, limit: opts.limit}
+	var err error
+	if d.countries, err = parseDistribution("countries", opts.countries); err != nil {
+		return nil, err
+	}
+	if d.os, err = parseDistribution("os", opts.os); err != nil {
+		return nil, err
+	}
+	if d.apps, err = parseDistribution("apps", opts.apps); err != nil {
+		return nil, err
+	}
+	if d.deviceTypes, err = parseDistribution("device-types", opts.deviceTypes); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// request returns a synthetic request
+func (d *dimensions) request(r *rand.Rand) *model.DeliveryRequest {
+	req := &model.DeliveryRequest{
+		Country:    d.countries.pick(r),
+		OS:         d.os.pick(r),
+		App:        d.apps.pick(r),
+		DeviceType: d.deviceTypes.pick(r),
+		Limit:      d.limit,
+	}
+	if d.users > 0 {
+		req.UserID = "user-" + strconv.Itoa(r.IntN(d.users))
+	}
+	return req
+}
+
+// readRequests reads a JSON Lines file of delivery requests and returns a
+// source replaying them in order, starting over at the end. A line may also
+// be an object holding the request under "request", as in recordings that
+// store the response alongside.
+func readRequests(path string) (requestSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var requests []*model.DeliveryRequest
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var recorded struct {
+			Request *model.DeliveryRequest `json:"request"`
+		}
+		if err := json.Unmarshal([]byte(text), &recorded); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		req := recorded.Request
+		if req == nil {
+			req = &model.DeliveryRequest{}
+			if err := json.Unmarshal([]byte(text), req); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+		}
+		requests = append(requests, req)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("%s holds no requests", path)
+	}
+
+	var next atomic.Uint64
+	return func(*rand.Rand) *model.DeliveryRequest {
+		// Requests are copied since targets may modify them
+		req := *requests[(next.Add(1)-1)%uint64(len(requests))]
+		return &req
+	}, nil
+}
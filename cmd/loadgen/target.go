@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/pkg/client"
+	"github.com/Harshi-itaSinha/target-engine/pkg/engine"
+)
+
+// target serves delivery requests, reporting whether any campaign matched
+type target interface {
+	deliver(ctx context.Context, req *model.DeliveryRequest) (matched bool, err error)
+}
+
+// httpTarget sends requests to GET /v1/delivery
+type httpTarget struct {
+	endpoint string
+	apiKey   string
+	tenant   string
+	client   *http.Client
+}
+
+func newHTTPTarget(opts *options) *httpTarget {
+	return &httpTarget{
+		endpoint: strings.TrimRight(opts.server, "/") + "/v1/delivery",
+		apiKey:   opts.apiKey,
+		tenant:   opts.tenant,
+		client: &http.Client{
+			Timeout: opts.timeout,
+			// Keep a connection per worker instead of reconnecting
+			Transport: &http.Transport{MaxIdleConnsPerHost: opts.concurrency},
+		},
+	}
+}
+
+func (t *httpTarget) deliver(ctx context.Context, req *model.DeliveryRequest) (bool, error) {
+	query := client.DeliveryQuery(req)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, t.endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return false, err
+	}
+	if t.apiKey != "" {
+		httpReq.Header.Set("X-API-Key", t.apiKey)
+	}
+	if t.tenant != "" {
+		httpReq.Header.Set("X-Tenant-ID", t.tenant)
+	}
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection is reused
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNoContent:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+}
+
+// engineTarget matches requests in-process
+type engineTarget struct {
+	engine *engine.Engine
+}
+
+// newEngineTarget creates an engine loaded with synthetic campaigns, each
+// targeting a few values drawn from the request distributions so that match
+// rates resemble those of the synthetic requests
+func newEngineTarget(ctx context.Context, dims *dimensions, opts *options) (*engineTarget, error) {
+	e := engine.New(engine.Options{CacheSize: opts.cacheSize})
+	r := rand.New(rand.NewPCG(opts.seed, opts.seed))
+
+	for i := 0; i < opts.campaigns; i++ {
+		priority, weight := r.IntN(5), 1+r.IntN(10)
+		req := engine.CampaignRequest{
+			ID:       fmt.Sprintf("campaign-%d", i),
+			Name:     fmt.Sprintf("Campaign %d", i),
+			Image:    fmt.Sprintf("https://cdn.example.com/campaign-%d.png", i),
+			CTA:      "Install",
+			Priority: &priority,
+			Weight:   &weight,
+		}
+		rule := engine.TargetingRule{IncludeCountry: pickDistinct(r, dims.countries, 1+r.IntN(3))}
+		if r.IntN(2) == 0 {
+			rule.IncludeOS = []string{dims.os.pick(r)}
+		}
+		if r.IntN(3) == 0 {
+			rule.IncludeApp = pickDistinct(r, dims.apps, 1+r.IntN(2))
+		}
+		if r.IntN(5) == 0 {
+			rule.ExcludeDeviceType = []string{dims.deviceTypes.pick(r)}
+		}
+		if _, err := e.CreateCampaign(ctx, req, rule); err != nil {
+			return nil, fmt.Errorf("failed to create campaign %s: %w", req.ID, err)
+		}
+	}
+	return &engineTarget{engine: e}, nil
+}
+
+// pickDistinct picks up to n distinct values
+func pickDistinct(r *rand.Rand, d *distribution, n int) []string {
+	seen := make(map[string]bool, n)
+	values := make([]string, 0, n)
+	for attempts := 0; len(values) < n && attempts < 10*n; attempts++ {
+		if value := d.pick(r); !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (t *engineTarget) deliver(ctx context.Context, req *model.DeliveryRequest) (bool, error) {
+	results, err := t.engine.Match(ctx, *req)
+	return len(results) > 0, err
+}
@@ -0,0 +1,269 @@
+// Command migrate copies campaigns and targeting rules between repositories
+// and verifies the copy with checksums.
+//
+// To move a deployment to another repository without downtime, run the
+// servers with database.dualWrite pointing at the new repository so writes
+// are mirrored to it, run migrate copy to copy the existing data and repair
+// what changed meanwhile, then switch database.driver over once migrate
+// verify reports both consistent.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/database.go"
+	"github.com/Harshi-itaSinha/target-engine/internal/migration"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/spf13/cobra"
+)
+
+// Supported repository drivers. A memory source holds the sample campaigns
+// and a memory target starts empty, for trying a migration out.
+const (
+	driverMemory = "memory"
+	driverMongo  = "mongo"
+	driverRedis  = "redis"
+	driverSQLite = "sqlite"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// endpoint selects a repository
+type endpoint struct {
+	driver string
+	uri    string
+	name   string
+}
+
+// options holds the command line flags
+type options struct {
+	from      endpoint
+	to        endpoint
+	batchSize int
+	output    string
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &options{}
+	root := &cobra.Command{
+		Use:          "migrate",
+		Short:        "Copy campaigns and targeting rules between repositories",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if opts.output != outputTable && opts.output != outputJSON {
+				return fmt.Errorf("invalid output %q, expected %s or %s", opts.output, outputTable, outputJSON)
+			}
+			return nil
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.from.driver, "from", driverMongo, "source repository driver: memory, mongo, redis or sqlite")
+	flags.StringVar(&opts.from.uri, "from-uri", os.Getenv("MIGRATE_FROM_URI"), "source connection URI, or database file with sqlite")
+	flags.StringVar(&opts.from.name, "from-name", "target-engine", "source database name, or key prefix with redis")
+	flags.StringVar(&opts.to.driver, "to", driverRedis, "target repository driver: memory, mongo, redis or sqlite")
+	flags.StringVar(&opts.to.uri, "to-uri", os.Getenv("MIGRATE_TO_URI"), "target connection URI, or database file with sqlite")
+	flags.StringVar(&opts.to.name, "to-name", "target-engine", "target database name, or key prefix with redis")
+	flags.IntVar(&opts.batchSize, "batch-size", migration.DefaultBatchSize, "campaigns or rules imported at a time")
+	flags.StringVarP(&opts.output, "output", "o", outputTable, "output format: table or json")
+
+	root.AddCommand(
+		newCopyCommand(opts),
+		newVerifyCommand(opts),
+	)
+	return root
+}
+
+func newCopyCommand(opts *options) *cobra.Command {
+	var passes int
+	var deleteExtra bool
+	cmd := &cobra.Command{
+		Use:   "copy",
+		Short: "Copy every campaign and targeting rule, then verify and repair the copy",
+		Long: "Copy every campaign and targeting rule of the source to the target, keeping their IDs and timestamps. " +
+			"The copy is then verified by checksum, and campaigns and rules that are missing or differ, " +
+			"e.g. because they changed while copying, are copied again until both match or --passes is exhausted.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+
+			source, target, err := opts.open(ctx)
+			if err != nil {
+				return err
+			}
+			defer source.Close()
+			defer target.Close()
+
+			migrator, err := migration.New(source, target, migration.Options{
+				BatchSize: opts.batchSize,
+				Progress: func(p migration.Progress) {
+					fmt.Fprintf(os.Stderr, "%s: %d/%d\n", p.Stage, p.Done, p.Total)
+				},
+			})
+			if err != nil {
+				return err
+			}
+			copied, err := migrator.Copy(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "copied %d campaigns and %d targeting rules\n", copied.Campaigns, copied.Rules)
+
+			report, err := migrator.Verify(ctx)
+			for pass := 1; err == nil && !report.Consistent() && pass <= passes; pass++ {
+				fmt.Fprintf(os.Stderr, "repair pass %d: %d campaigns and %d targeting rules differ\n", pass,
+					differences(report.Campaigns), differences(report.Rules))
+				if err = migrator.Repair(ctx, report, deleteExtra); err == nil {
+					report, err = migrator.Verify(ctx)
+				}
+			}
+			if err != nil {
+				return err
+			}
+			return printReport(opts.output, report)
+		},
+	}
+	cmd.Flags().IntVar(&passes, "passes", 3, "repair passes run while the copy differs from the source")
+	cmd.Flags().BoolVar(&deleteExtra, "delete-extra", false, "delete campaigns and rules only the target holds")
+	return cmd
+}
+
+func newVerifyCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Compare the campaigns and targeting rules of both repositories by checksum",
+		Long:  "Compare the campaigns and targeting rules of both repositories by checksum. It fails if they differ.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+
+			source, target, err := opts.open(ctx)
+			if err != nil {
+				return err
+			}
+			defer source.Close()
+			defer target.Close()
+
+			migrator, err := migration.New(source, target, migration.Options{BatchSize: opts.batchSize})
+			if err != nil {
+				return err
+			}
+			report, err := migrator.Verify(ctx)
+			if err != nil {
+				return err
+			}
+			return printReport(opts.output, report)
+		},
+	}
+}
+
+// open connects to the source and target repositories and prepares the
+// target's collections and indexes
+func (o *options) open(ctx context.Context) (source, target repository.RepositoryManager, err error) {
+	if o.from == o.to && o.from.driver != driverMemory {
+		return nil, nil, fmt.Errorf("source and target are the same repository")
+	}
+	if source, err = o.from.open(true); err != nil {
+		return nil, nil, fmt.Errorf("source: %w", err)
+	}
+	if target, err = o.to.open(false); err != nil {
+		source.Close()
+		return nil, nil, fmt.Errorf("target: %w", err)
+	}
+	if err := target.Migrate(ctx); err != nil {
+		source.Close()
+		target.Close()
+		return nil, nil, fmt.Errorf("target: failed to migrate: %w", err)
+	}
+	return source, target, nil
+}
+
+// open connects to the repository. A memory source holds the sample data.
+func (e endpoint) open(source bool) (repository.RepositoryManager, error) {
+	switch e.driver {
+	case driverMemory:
+		if source {
+			return repository.NewMemoryRepository(), nil
+		}
+		return repository.NewEmptyMemoryRepository(), nil
+
+	case driverMongo:
+		client, err := database.NewMongoClient(e.uri, database.MongoOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize MongoDB client: %w", err)
+		}
+		return repository.NewRepository(client.Database(e.name), client), nil
+
+	case driverRedis:
+		client, err := database.NewRedisClient(e.uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Redis client: %w", err)
+		}
+		return repository.NewRedisRepository(client, e.name), nil
+
+	case driverSQLite:
+		db, err := database.NewSQLiteDB(e.uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+		}
+		return repository.NewSQLiteRepository(db), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported driver %q, expected %s, %s, %s or %s", e.driver, driverMemory, driverMongo, driverRedis, driverSQLite)
+	}
+}
+
+// differences counts the items a comparison found missing, extra or different
+func differences(c migration.Comparison) int {
+	return len(c.Missing) + len(c.Extra) + len(c.Different)
+}
+
+// printReport writes the report as indented JSON or as a table, and fails
+// when the repositories differ
+func printReport(output string, report *migration.Report) error {
+	if output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tSOURCE\tTARGET\tMISSING\tEXTRA\tDIFFERENT\tSOURCE CHECKSUM\tTARGET CHECKSUM")
+		for _, row := range []struct {
+			kind       string
+			comparison migration.Comparison
+		}{
+			{"campaigns", report.Campaigns},
+			{"rules", report.Rules},
+		} {
+			c := row.comparison
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n", row.kind, c.SourceCount, c.TargetCount,
+				len(c.Missing), len(c.Extra), len(c.Different), c.SourceChecksum[:12], c.TargetChecksum[:12])
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if !report.Consistent() {
+		return fmt.Errorf("source and target differ")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"text/tabwriter"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func newCacheCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and refresh the targeting cache",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "stats",
+		Short: "Show targeting cache statistics",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var stats map[string]any
+			if err := a.client().do(cmd.Context(), http.MethodGet, "/v1/stats", nil, nil, &stats); err != nil {
+				return err
+			}
+			return a.print(stats, func(w *tabwriter.Writer) {
+				names := make([]string, 0, len(stats))
+				for name := range stats {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					value := stats[name]
+					switch value.(type) {
+					case map[string]any, []any:
+						// Nested breakdowns such as traffic are shown as JSON
+						data, _ := json.Marshal(value)
+						value = string(data)
+					}
+					fmt.Fprintf(w, "%s\t%v\n", name, value)
+				}
+			})
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "refresh",
+		Short: "Reload the targeting cache from the database",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var result model.CacheRefreshResult
+			if err := a.client().do(cmd.Context(), http.MethodPost, "/v1/admin/cache/refresh", nil, nil, &result); err != nil {
+				return err
+			}
+			return a.print(&result, func(w *tabwriter.Writer) {
+				fmt.Fprintf(w, "CAMPAIGNS\t%d\n", result.Campaigns)
+				fmt.Fprintf(w, "TARGETING RULES\t%d\n", result.TargetingRules)
+				fmt.Fprintf(w, "DURATION\t%dms\n", result.DurationMS)
+			})
+		},
+	})
+	return cmd
+}
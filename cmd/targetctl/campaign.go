@@ -0,0 +1,603 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func newCampaignCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "campaign",
+		Aliases: []string{"campaigns"},
+		Short:   "Manage campaigns",
+	}
+	cmd.AddCommand(
+		newCampaignListCommand(a),
+		newCampaignSearchCommand(a),
+		newCampaignCreateCommand(a),
+		newCampaignUpdateCommand(a),
+		newCampaignCloneCommand(a),
+		newCampaignShowCommand(a),
+		newCampaignTrackingCommand(a),
+		newCampaignStatsCommand(a),
+		newCampaignStatusCommand(a, "pause", "Stop delivering a campaign", model.StatusPaused),
+		newCampaignStatusCommand(a, "resume", "Resume delivering a paused or archived campaign", model.StatusActive),
+		newCampaignStatusCommand(a, "complete", "End a campaign for good", model.StatusCompleted),
+		newCampaignDeleteCommand(a),
+		newCampaignExportCommand(a),
+		newCampaignImportCommand(a),
+	)
+	return cmd
+}
+
+func newCampaignListCommand(a *app) *cobra.Command {
+	var status, search string
+	var includeArchived bool
+	var limit, offset int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List campaigns, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{}
+			setQuery(query, "status", status)
+			setQuery(query, "search", search)
+			if includeArchived {
+				query.Set("include_archived", "true")
+			}
+			if limit > 0 {
+				query.Set("limit", strconv.Itoa(limit))
+			}
+			if offset > 0 {
+				query.Set("offset", strconv.Itoa(offset))
+			}
+
+			var list model.CampaignList
+			if err := a.client().do(cmd.Context(), http.MethodGet, "/v1/campaigns", query, nil, &list); err != nil {
+				return err
+			}
+			return a.print(&list, func(w *tabwriter.Writer) {
+				fmt.Fprintln(w, "CID\tNAME\tSTATUS\tPRIORITY\tUPDATED")
+				for _, c := range list.Campaigns {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", c.ID, c.Name, c.Status, c.Priority, c.UpdatedAt.Format(time.RFC3339))
+				}
+				fmt.Fprintf(w, "\n%d of %d campaigns\n", len(list.Campaigns), list.Total)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&status, "status", "", "only campaigns with this status, e.g. ACTIVE, PAUSED or ARCHIVED")
+	cmd.Flags().StringVar(&search, "search", "", "only campaigns whose ID or name contains this")
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "also list archived campaigns")
+	cmd.Flags().IntVar(&limit, "limit", 0, "page size")
+	cmd.Flags().IntVar(&offset, "offset", 0, "number of campaigns to skip")
+	return cmd
+}
+
+func newCampaignSearchCommand(a *app) *cobra.Command {
+	var includeArchived bool
+	var limit, offset int
+	cmd := &cobra.Command{
+		Use:   "search QUERY",
+		Short: "Search campaign names and CTAs, most relevant first",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{"q": {strings.Join(args, " ")}}
+			if includeArchived {
+				query.Set("include_archived", "true")
+			}
+			if limit > 0 {
+				query.Set("limit", strconv.Itoa(limit))
+			}
+			if offset > 0 {
+				query.Set("offset", strconv.Itoa(offset))
+			}
+
+			var result model.CampaignSearchResult
+			if err := a.client().do(cmd.Context(), http.MethodGet, "/v1/campaigns/search", query, nil, &result); err != nil {
+				return err
+			}
+			return a.print(&result, func(w *tabwriter.Writer) {
+				fmt.Fprintln(w, "CID\tNAME\tCTA\tSTATUS\tSCORE")
+				for _, c := range result.Campaigns {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.2f\n", c.ID, c.Name, c.CTA, c.Status, c.Score)
+				}
+				fmt.Fprintf(w, "\n%d of %d campaigns\n", len(result.Campaigns), result.Total)
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "also search archived campaigns")
+	cmd.Flags().IntVar(&limit, "limit", 0, "page size")
+	cmd.Flags().IntVar(&offset, "offset", 0, "number of campaigns to skip")
+	return cmd
+}
+
+// campaignFlags binds the flags that set campaign fields
+type campaignFlags struct {
+	file              string
+	name              string
+	image             string
+	cta               string
+	clickURL          string
+	status            string
+	priority          int
+	weight            int
+	frequencyCap      int
+	dailyBudget       int64
+	totalBudget       int64
+	appDailyCap       int
+	maxDailyApps      int
+	startAt           string
+	endAt             string
+	blockedCategories []string
+}
+
+func (f *campaignFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.file, "file", "f", "", "JSON campaign request to send, - for standard input")
+	cmd.Flags().StringVar(&f.name, "name", "", "campaign name")
+	cmd.Flags().StringVar(&f.image, "img", "", "image URL")
+	cmd.Flags().StringVar(&f.cta, "cta", "", "call to action")
+	cmd.Flags().StringVar(&f.clickURL, "click-url", "", "click tracking URL template, with macros such as {CID} or {REQUEST_ID}")
+	cmd.Flags().StringVar(&f.status, "status", "", "DRAFT, SCHEDULED, ACTIVE, PAUSED or COMPLETED")
+	cmd.Flags().IntVar(&f.priority, "priority", 0, "delivery priority")
+	cmd.Flags().IntVar(&f.weight, "weight", 0, "weight among campaigns of the same priority")
+	cmd.Flags().IntVar(&f.frequencyCap, "frequency-cap", 0, "impressions per user per day, 0 for no cap")
+	cmd.Flags().Int64Var(&f.dailyBudget, "daily-budget", 0, "impressions per day, 0 for unlimited")
+	cmd.Flags().Int64Var(&f.totalBudget, "total-budget", 0, "lifetime impressions, 0 for unlimited")
+	cmd.Flags().IntVar(&f.appDailyCap, "app-daily-cap", 0, "impressions per app bundle per day, 0 for no cap")
+	cmd.Flags().IntVar(&f.maxDailyApps, "max-daily-apps", 0, "distinct app bundles served in per day, 0 for no cap")
+	cmd.Flags().StringVar(&f.startAt, "start", "", "start of the flight, RFC 3339")
+	cmd.Flags().StringVar(&f.endAt, "end", "", "end of the flight, RFC 3339")
+	cmd.Flags().StringSliceVar(&f.blockedCategories, "blocked-categories", nil, "IAB content categories never to serve in, comma separated; empty to remove them")
+}
+
+// request builds a campaign request from --file, overridden by the fields
+// set through flags
+func (f *campaignFlags) request(cmd *cobra.Command) (*model.CampaignRequest, error) {
+	req := &model.CampaignRequest{}
+	if f.file != "" {
+		if err := readJSONFile(f.file, req); err != nil {
+			return nil, err
+		}
+	}
+
+	changed := cmd.Flags().Changed
+	if changed("name") {
+		req.Name = f.name
+	}
+	if changed("img") {
+		req.Image = f.image
+	}
+	if changed("cta") {
+		req.CTA = f.cta
+	}
+	if changed("click-url") {
+		req.ClickURL = f.clickURL
+	}
+	if changed("status") {
+		req.Status = strings.ToUpper(f.status)
+	}
+	if changed("priority") {
+		req.Priority = &f.priority
+	}
+	if changed("weight") {
+		req.Weight = &f.weight
+	}
+	if changed("frequency-cap") {
+		req.FrequencyCap = &f.frequencyCap
+	}
+	if changed("daily-budget") {
+		req.DailyBudget = &f.dailyBudget
+	}
+	if changed("total-budget") {
+		req.TotalBudget = &f.totalBudget
+	}
+	if changed("app-daily-cap") {
+		req.AppDailyCap = &f.appDailyCap
+	}
+	if changed("max-daily-apps") {
+		req.MaxDailyApps = &f.maxDailyApps
+	}
+	if changed("blocked-categories") {
+		req.BlockedCategories = append([]string{}, f.blockedCategories...)
+	}
+	flight := []struct {
+		name, value string
+		dst         **time.Time
+	}{{"start", f.startAt, &req.StartAt}, {"end", f.endAt, &req.EndAt}}
+	for _, date := range flight {
+		if !changed(date.name) {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, date.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s %q, expected an RFC 3339 time", date.name, date.value)
+		}
+		*date.dst = &t
+	}
+	return req, nil
+}
+
+func newCampaignCreateCommand(a *app) *cobra.Command {
+	flags := &campaignFlags{}
+	cmd := &cobra.Command{
+		Use:   "create [CID]",
+		Short: "Create a campaign from flags or a JSON file",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, err := flags.request(cmd)
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				req.ID = args[0]
+			}
+
+			var campaign model.Campaign
+			if err := a.client().do(cmd.Context(), http.MethodPost, "/v1/campaign", nil, req, &campaign); err != nil {
+				return err
+			}
+			return a.print(&campaign, campaignTable(&campaign))
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+func newCampaignUpdateCommand(a *app) *cobra.Command {
+	flags := &campaignFlags{}
+	cmd := &cobra.Command{
+		Use:   "update CID",
+		Short: "Update the given fields of a campaign",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, err := flags.request(cmd)
+			if err != nil {
+				return err
+			}
+			return updateCampaign(cmd, a, args[0], req)
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+func newCampaignStatusCommand(a *app, use, short, status string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " CID",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var campaign model.Campaign
+			path := "/v1/campaign/" + url.PathEscape(args[0]) + "/status"
+			if err := a.client().do(cmd.Context(), http.MethodPut, path, nil, &model.CampaignStatusRequest{Status: status}, &campaign); err != nil {
+				return err
+			}
+			return a.print(&campaign, campaignTable(&campaign))
+		},
+	}
+}
+
+// updateCampaign sends req to update campaign id and prints the result
+func updateCampaign(cmd *cobra.Command, a *app, id string, req *model.CampaignRequest) error {
+	var campaign model.Campaign
+	if err := a.client().do(cmd.Context(), http.MethodPut, "/v1/campaign/"+url.PathEscape(id), nil, req, &campaign); err != nil {
+		return err
+	}
+	return a.print(&campaign, campaignTable(&campaign))
+}
+
+func newCampaignCloneCommand(a *app) *cobra.Command {
+	flags := &campaignFlags{}
+	cmd := &cobra.Command{
+		Use:   "clone CID [NEW_CID]",
+		Short: "Copy a campaign and its rules to a new DRAFT campaign",
+		Long: "Copies a campaign and all its targeting rules. Fields given as flags or in --file\n" +
+			"override those of the copy, which is a DRAFT unless --status says otherwise.",
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, err := flags.request(cmd)
+			if err != nil {
+				return err
+			}
+			if len(args) == 2 {
+				req.ID = args[1]
+			}
+
+			var clone model.CampaignClone
+			path := "/v1/campaign/" + url.PathEscape(args[0]) + "/clone"
+			if err := a.client().do(cmd.Context(), http.MethodPost, path, nil, req, &clone); err != nil {
+				return err
+			}
+			return a.print(&clone, func(w *tabwriter.Writer) {
+				campaignTable(clone.Campaign)(w)
+				fmt.Fprintf(w, "RULES\t%d\n", len(clone.Rules))
+			})
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+func newCampaignShowCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show CID",
+		Short: "Show a campaign with its rules, serving status and recent activity",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var view model.CampaignView
+			path := "/v1/campaign/" + url.PathEscape(args[0]) + "/full"
+			if err := a.client().do(cmd.Context(), http.MethodGet, path, nil, nil, &view); err != nil {
+				return err
+			}
+			return a.print(&view, func(w *tabwriter.Writer) {
+				campaignTable(view.Campaign)(w)
+				fmt.Fprintf(w, "RULES\t%d\n", len(view.Rules))
+				serving := "yes"
+				if !view.Status.Serving {
+					serving = "no, " + view.Status.Reason
+				}
+				fmt.Fprintf(w, "SERVING\t%s\n", serving)
+				fmt.Fprintf(w, "FLIGHT\t%s\n", view.Schedule.Phase)
+				if view.Served != nil {
+					fmt.Fprintf(w, "SERVED\t%d\n", view.Served.Served)
+				}
+				fmt.Fprintf(w, "IMPRESSIONS\t%d\n", view.Tracking.Total.Impressions)
+				fmt.Fprintf(w, "CLICKS\t%d\n", view.Tracking.Total.Clicks)
+				fmt.Fprintf(w, "CHANGES\t%d\n", len(view.RecentChanges))
+			})
+		},
+	}
+}
+
+func newCampaignTrackingCommand(a *app) *cobra.Command {
+	var day string
+	cmd := &cobra.Command{
+		Use:   "tracking CID",
+		Short: "Show the impressions and clicks tracked for a campaign",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{}
+			if day != "" {
+				query.Set("day", day)
+			}
+
+			var tracking model.CampaignTracking
+			path := "/v1/campaign/" + url.PathEscape(args[0]) + "/tracking"
+			if err := a.client().do(cmd.Context(), http.MethodGet, path, query, nil, &tracking); err != nil {
+				return err
+			}
+			return a.print(&tracking, func(w *tabwriter.Writer) {
+				fmt.Fprintln(w, "PERIOD\tIMPRESSIONS\tCLICKS\tCTR")
+				fmt.Fprintf(w, "%s\t%d\t%d\t%.2f%%\n", tracking.Day, tracking.Daily.Impressions, tracking.Daily.Clicks, 100*tracking.Daily.ClickThroughRate)
+				fmt.Fprintf(w, "total\t%d\t%d\t%.2f%%\n", tracking.Total.Impressions, tracking.Total.Clicks, 100*tracking.Total.ClickThroughRate)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&day, "day", "", "UTC day as YYYYMMDD (default today)")
+	return cmd
+}
+
+func newCampaignStatsCommand(a *app) *cobra.Command {
+	var from, to string
+	cmd := &cobra.Command{
+		Use:   "stats [CID]",
+		Short: "Show how often campaigns were served, most served first",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{}
+			if len(args) == 1 {
+				query.Set("campaign_id", args[0])
+			}
+			if from != "" {
+				query.Set("from", from)
+			}
+			if to != "" {
+				query.Set("to", to)
+			}
+
+			var report model.CampaignStatsReport
+			if err := a.client().do(cmd.Context(), http.MethodGet, "/v1/stats/campaigns", query, nil, &report); err != nil {
+				return err
+			}
+			return a.print(&report, func(w *tabwriter.Writer) {
+				fmt.Fprintln(w, "CID\tSERVED\tCOUNTRIES\tOS")
+				for _, c := range report.Campaigns {
+					fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", c.CID, c.Served, formatCounts(c.Countries), formatCounts(c.OS))
+				}
+				fmt.Fprintf(w, "\n%s to %s\n", report.From, report.To)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&from, "from", "", "first UTC day as YYYYMMDD (default today)")
+	cmd.Flags().StringVar(&to, "to", "", "last UTC day as YYYYMMDD (default today)")
+	return cmd
+}
+
+// formatCounts renders counts as value=count pairs, largest first, or "-"
+// for none
+func formatCounts(counts map[string]int64) string {
+	if len(counts) == 0 {
+		return "-"
+	}
+	values := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), strings.Compare(a, b))
+	})
+	pairs := make([]string, len(values))
+	for i, value := range values {
+		pairs[i] = fmt.Sprintf("%s=%d", value, counts[value])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func newCampaignDeleteCommand(a *app) *cobra.Command {
+	var hard bool
+	cmd := &cobra.Command{
+		Use:   "delete CID",
+		Short: "Archive a campaign, or remove it with --hard",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{}
+			if hard {
+				query.Set("hard", "true")
+			}
+			if err := a.client().do(cmd.Context(), http.MethodDelete, "/v1/campaign/"+url.PathEscape(args[0]), query, nil, nil); err != nil {
+				return err
+			}
+			if hard {
+				fmt.Printf("campaign %s deleted\n", args[0])
+			} else {
+				fmt.Printf("campaign %s archived\n", args[0])
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&hard, "hard", false, "remove the campaign and its rules instead of archiving it")
+	return cmd
+}
+
+func newCampaignExportCommand(a *app) *cobra.Command {
+	var format, status, out string
+	var includeArchived bool
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export campaigns with their rules as JSON Lines or CSV",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{}
+			setQuery(query, "format", format)
+			setQuery(query, "status", status)
+			if includeArchived {
+				query.Set("include_archived", "true")
+			}
+
+			resp, err := a.client().send(cmd.Context(), http.MethodGet, "/v1/campaigns/export", query, "", nil)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			dst := io.Writer(os.Stdout)
+			if out != "" && out != "-" {
+				file, err := os.Create(out)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				dst = file
+			}
+			_, err = io.Copy(dst, resp.Body)
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "jsonl", "jsonl or csv")
+	cmd.Flags().StringVar(&status, "status", "", "only campaigns with this status")
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "also export archived campaigns")
+	cmd.Flags().StringVar(&out, "out", "", "file to write, standard output by default")
+	return cmd
+}
+
+func newCampaignImportCommand(a *app) *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Create or replace campaigns from an export file",
+		Long:  "Create or replace campaigns with their rules from a JSON Lines or CSV export. The format is taken from the file extension unless --format is given.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "" {
+				format = "jsonl"
+				if strings.EqualFold(filepath.Ext(args[0]), ".csv") {
+					format = "csv"
+				}
+			}
+			contentType := "application/x-ndjson"
+			if format == "csv" {
+				contentType = "text/csv"
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			resp, err := a.client().send(cmd.Context(), http.MethodPost, "/v1/campaigns/import", nil, contentType, file)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			var results []*model.BulkCampaignResult
+			if err := decodeBody(resp.Body, &results); err != nil {
+				return err
+			}
+			return a.print(results, resultsTable(results))
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "", "jsonl or csv")
+	return cmd
+}
+
+// campaignTable renders a single campaign
+func campaignTable(c *model.Campaign) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "CID\t%s\n", c.ID)
+		fmt.Fprintf(w, "NAME\t%s\n", c.Name)
+		fmt.Fprintf(w, "STATUS\t%s\n", c.Status)
+		fmt.Fprintf(w, "IMG\t%s\n", c.Image)
+		fmt.Fprintf(w, "CTA\t%s\n", c.CTA)
+		if c.ClickURL != "" {
+			fmt.Fprintf(w, "CLICK URL\t%s\n", c.ClickURL)
+		}
+		fmt.Fprintf(w, "PRIORITY\t%d\n", c.Priority)
+		fmt.Fprintf(w, "WEIGHT\t%d\n", c.Weight)
+		if len(c.BlockedCategories) > 0 {
+			fmt.Fprintf(w, "BLOCKED CATEGORIES\t%s\n", strings.Join(c.BlockedCategories, ", "))
+		}
+		if c.StartAt != nil {
+			fmt.Fprintf(w, "START\t%s\n", c.StartAt.Format(time.RFC3339))
+		}
+		if c.EndAt != nil {
+			fmt.Fprintf(w, "END\t%s\n", c.EndAt.Format(time.RFC3339))
+		}
+		fmt.Fprintf(w, "UPDATED\t%s\n", c.UpdatedAt.Format(time.RFC3339))
+	}
+}
+
+// resultsTable renders the outcome of a bulk create or import
+func resultsTable(results []*model.BulkCampaignResult) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "CID\tRESULT\tERROR")
+		for _, r := range results {
+			outcome := "failed"
+			if r.Success {
+				outcome = "ok"
+				if r.Action != "" {
+					outcome = r.Action
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.CID, outcome, r.Error)
+		}
+	}
+}
+
+// setQuery sets a query parameter unless value is empty
+func setQuery(query url.Values, name, value string) {
+	if value != "" {
+		query.Set(name, value)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// client calls the targeting engine's HTTP API
+type client struct {
+	server string
+	apiKey string
+	token  string
+	tenant string
+	http   *http.Client
+}
+
+// apiError is an error response from the API
+type apiError struct {
+	status   int
+	response model.ErrorResponse
+}
+
+func (e *apiError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d %s", e.status, http.StatusText(e.status))
+	if e.response.Message != "" {
+		b.WriteString(": " + e.response.Message)
+	}
+	for _, field := range e.response.Fields {
+		fmt.Fprintf(&b, "\n  %s: %s", field.Field, field.Reason)
+	}
+	for _, conflict := range e.response.Conflicts {
+		fmt.Fprintf(&b, "\n  %s", conflict.Message)
+	}
+	return b.String()
+}
+
+// do sends body, if any, as JSON and decodes the JSON response into out, if
+// non-nil
+func (c *client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	resp, err := c.send(ctx, method, path, query, contentType, reader)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return decodeBody(resp.Body, out)
+}
+
+// decodeBody decodes a JSON response body into out
+func decodeBody(body io.Reader, out any) error {
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send sends a request with a raw body and returns the response of a
+// successful call; the caller closes its body. Error responses are returned
+// as an *apiError.
+func (c *client) send(ctx context.Context, method, path string, query url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	endpoint := strings.TrimRight(c.server, "/") + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant-ID", c.tenant)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", c.server, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		apiErr := &apiError{status: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(&apiErr.response)
+		return nil, apiErr
+	}
+	return resp, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func newDecisionsCommand(a *app) *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "decisions [REQUEST_ID]",
+		Short: "Show logged delivery decisions, newest first, or those of one request",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{}
+			if len(args) == 1 {
+				query.Set("request_id", args[0])
+			}
+			if limit > 0 {
+				query.Set("limit", strconv.Itoa(limit))
+			}
+
+			var list model.DecisionList
+			if err := a.client().do(cmd.Context(), http.MethodGet, "/v1/decisions", query, nil, &list); err != nil {
+				return err
+			}
+			return a.print(&list, func(w *tabwriter.Writer) {
+				fmt.Fprintln(w, "AT\tREQUEST ID\tAPP\tCOUNTRY\tOS\tCAMPAIGNS\tLATENCY\tCACHE HIT")
+				for _, d := range list.Decisions {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%.2fms\t%t\n", d.At.Format(time.RFC3339), d.RequestID, d.App, d.Country, d.OS,
+						strings.Join(d.CampaignIDs, ","), d.LatencyMs, d.CacheHit)
+				}
+			})
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 0, "maximum number of decisions (default 20)")
+	return cmd
+}
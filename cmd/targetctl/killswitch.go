@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"text/tabwriter"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func newKillSwitchCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "killswitch",
+		Short: "Stop and resume serving campaigns",
+	}
+	cmd.AddCommand(
+		newKillSwitchEngageCommand(a),
+		newKillSwitchResumeCommand(a),
+		newKillSwitchListCommand(a),
+	)
+	return cmd
+}
+
+func newKillSwitchEngageCommand(a *app) *cobra.Command {
+	var req model.KillSwitchRequest
+	cmd := &cobra.Command{
+		Use:   "engage",
+		Short: "Stop serving the campaigns of the tenant, or of every tenant with --global",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var sw model.KillSwitch
+			if err := a.client().do(cmd.Context(), http.MethodPost, "/v1/admin/killswitch", nil, &req, &sw); err != nil {
+				return err
+			}
+			return a.print(&sw, func(w *tabwriter.Writer) {
+				fmt.Fprintf(w, "kill switch %s engaged\n", sw.Scope())
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&req.Global, "global", false, "stop serving the campaigns of every tenant")
+	cmd.Flags().StringVar(&req.Reason, "reason", "", "why serving is stopped, for the audit log")
+	return cmd
+}
+
+func newKillSwitchResumeCommand(a *app) *cobra.Command {
+	var req model.KillSwitchRequest
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Resume serving after killswitch engage",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := a.client().do(cmd.Context(), http.MethodPost, "/v1/admin/killswitch/resume", nil, &req, nil); err != nil {
+				return err
+			}
+			fmt.Printf("kill switch %s released\n", model.KillSwitchScope(req.Global, a.tenant))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&req.Global, "global", false, "release the kill switch of every tenant")
+	cmd.Flags().StringVar(&req.Reason, "reason", "", "why serving resumes, for the audit log")
+	return cmd
+}
+
+func newKillSwitchListCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the engaged kill switches",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var list model.KillSwitchList
+			if err := a.client().do(cmd.Context(), http.MethodGet, "/v1/admin/killswitch", nil, nil, &list); err != nil {
+				return err
+			}
+			return a.print(&list, func(w *tabwriter.Writer) {
+				fmt.Fprintln(w, "SCOPE\tENGAGED\tBY\tREASON")
+				for _, sw := range list.KillSwitches {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", sw.Scope(), sw.EngagedAt.Format(time.RFC3339), sw.EngagedBy, sw.Reason)
+				}
+			})
+		},
+	}
+}
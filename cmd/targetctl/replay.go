@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/recording"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+	"github.com/spf13/cobra"
+)
+
+func newReplayCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "replay FILE",
+		Short: "Replay recorded delivery requests and report changed responses",
+		Long: "Sends every delivery request of a recording file to the server and reports those\n" +
+			"served different campaigns than recorded. Fails if any response changed.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			// Only rejected requests are outcomes; any other failure stops the replay
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			var failure error
+			serve := func(ctx context.Context, req *model.DeliveryRequest) ([]*model.DeliveryResponse, error) {
+				c := a.client()
+				if id := tenant.FromContext(ctx); id != "" {
+					c.tenant = id
+				}
+				var campaigns []*model.DeliveryResponse
+				err := c.do(ctx, http.MethodPost, "/v1/delivery", nil, req, &campaigns)
+				var apiErr *apiError
+				if err != nil && (!errors.As(err, &apiErr) || apiErr.status != http.StatusBadRequest) {
+					failure = err
+					cancel()
+				}
+				return campaigns, err
+			}
+
+			report, err := recording.Replay(ctx, file, serve)
+			if failure != nil {
+				return failure
+			}
+			if err != nil {
+				return fmt.Errorf("failed to replay %s: %w", args[0], err)
+			}
+
+			if err := a.print(report, func(w *tabwriter.Writer) {
+				fmt.Fprintf(w, "REPLAYED\t%d\n", report.Replayed)
+				fmt.Fprintf(w, "SKIPPED\t%d\n", report.Skipped)
+				fmt.Fprintf(w, "MISMATCHES\t%d\n", len(report.Mismatches))
+				if len(report.Mismatches) > 0 {
+					fmt.Fprintln(w)
+					fmt.Fprintln(w, "LINE\tTENANT\tRECORDED\tREPLAYED")
+					for _, m := range report.Mismatches {
+						fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", m.Line, m.Tenant, formatOutcome(m.Recorded), formatOutcome(m.Replayed))
+					}
+				}
+			}); err != nil {
+				return err
+			}
+			if len(report.Mismatches) > 0 {
+				return fmt.Errorf("%d of %d replayed requests were served differently", len(report.Mismatches), report.Replayed)
+			}
+			return nil
+		},
+	}
+}
+
+// formatOutcome renders an outcome as its campaign IDs, "-" for none, or the
+// error
+func formatOutcome(outcome recording.Outcome) string {
+	switch {
+	case outcome.Error != "":
+		return "error: " + strings.ReplaceAll(outcome.Error, "\n", " ")
+	case len(outcome.Campaigns) == 0:
+		return "-"
+	default:
+		return strings.Join(outcome.Campaigns, ",")
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func newRuleCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "rule",
+		Aliases: []string{"rules"},
+		Short:   "Manage targeting rules",
+	}
+	cmd.AddCommand(
+		newRuleListCommand(a),
+		newRuleCreateCommand(a),
+		newRuleUpdateCommand(a),
+		newRuleAnalyzeCommand(a),
+		newRuleEstimateCommand(a),
+		newRuleDeleteCommand(a),
+	)
+	return cmd
+}
+
+func newRuleListCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list CID",
+		Short: "List the targeting rules of a campaign",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{"campaign_id": {args[0]}}
+			var rules []*model.TargetingRule
+			if err := a.client().do(cmd.Context(), http.MethodGet, "/v1/target", query, nil, &rules); err != nil {
+				return err
+			}
+			return a.print(rules, func(w *tabwriter.Writer) {
+				fmt.Fprintln(w, "ID\tINCLUDE\tEXCLUDE\tSHADOW")
+				for _, rule := range rules {
+					fmt.Fprintf(w, "%d\t%s\t%s\t%t\n", rule.ID, describeValues(rule, true), describeValues(rule, false), rule.Shadow)
+				}
+			})
+		},
+	}
+}
+
+// ruleFlags binds the flags that set the dimension lists of a rule
+type ruleFlags struct {
+	file       string
+	campaign   string
+	appVersion string
+	from       string
+	until      string
+	shadow     bool
+	values     map[string]*[]string
+}
+
+// ruleDimensions are the built-in dimensions settable through flags, in the
+// order they are listed
+var ruleDimensions = []string{"country", "os", "app", "device-type", "region", "city", "segment"}
+
+func (f *ruleFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.file, "file", "f", "", "JSON targeting rule to send, - for standard input")
+	cmd.Flags().StringVar(&f.campaign, "campaign", "", "ID of the campaign the rule targets")
+	cmd.Flags().StringVar(&f.appVersion, "app-version", "", `app version constraint, e.g. ">=2.3.0 <3.0.0"`)
+	cmd.Flags().StringVar(&f.from, "effective-from", "", "RFC 3339 time the rule starts applying at")
+	cmd.Flags().StringVar(&f.until, "effective-until", "", "RFC 3339 time the rule stops applying at")
+	cmd.Flags().BoolVar(&f.shadow, "shadow", false, "evaluate the rule without letting it affect delivery")
+	f.values = make(map[string]*[]string)
+	for _, dimension := range ruleDimensions {
+		for _, kind := range []string{"include", "exclude"} {
+			name := kind + "-" + dimension
+			f.values[name] = cmd.Flags().StringSlice(name, nil, fmt.Sprintf("%s these %s values", kind, strings.ReplaceAll(dimension, "-", " ")))
+		}
+	}
+}
+
+// rule builds a targeting rule from --file, overridden by the values set
+// through flags
+func (f *ruleFlags) rule(cmd *cobra.Command) (*model.TargetingRule, error) {
+	rule := &model.TargetingRule{}
+	if f.file != "" {
+		if err := readJSONFile(f.file, rule); err != nil {
+			return nil, err
+		}
+	}
+
+	changed := cmd.Flags().Changed
+	if changed("campaign") {
+		rule.CampaignID = f.campaign
+	}
+	if changed("app-version") {
+		rule.AppVersion = f.appVersion
+	}
+	if changed("shadow") {
+		rule.Shadow = f.shadow
+	}
+	window := []struct {
+		name, value string
+		dst         **time.Time
+	}{{"effective-from", f.from, &rule.EffectiveFrom}, {"effective-until", f.until, &rule.EffectiveUntil}}
+	for _, bound := range window {
+		if !changed(bound.name) {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s %q, expected an RFC 3339 time", bound.name, bound.value)
+		}
+		*bound.dst = &t
+	}
+	lists := map[string]*[]string{
+		"include-country": &rule.IncludeCountry, "exclude-country": &rule.ExcludeCountry,
+		"include-os": &rule.IncludeOS, "exclude-os": &rule.ExcludeOS,
+		"include-app": &rule.IncludeApp, "exclude-app": &rule.ExcludeApp,
+		"include-device-type": &rule.IncludeDeviceType, "exclude-device-type": &rule.ExcludeDeviceType,
+		"include-region": &rule.IncludeRegion, "exclude-region": &rule.ExcludeRegion,
+		"include-city": &rule.IncludeCity, "exclude-city": &rule.ExcludeCity,
+		"include-segment": &rule.IncludeSegment, "exclude-segment": &rule.ExcludeSegment,
+	}
+	for name, dst := range lists {
+		if changed(name) {
+			*dst = *f.values[name]
+		}
+	}
+	return rule, nil
+}
+
+func newRuleCreateCommand(a *app) *cobra.Command {
+	flags := &ruleFlags{}
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a targeting rule from flags or a JSON file",
+		Example: "  targetctl rule create --campaign spotify --include-country US,CA --include-os android\n" +
+			"  targetctl rule create -f rule.json",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rule, err := flags.rule(cmd)
+			if err != nil {
+				return err
+			}
+			var created ruleResult
+			if err := a.client().do(cmd.Context(), http.MethodPost, "/v1/target", nil, rule, &created); err != nil {
+				return err
+			}
+			printConflicts(created.Conflicts)
+			return a.print(&created, ruleTable(&created.TargetingRule))
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+func newRuleUpdateCommand(a *app) *cobra.Command {
+	flags := &ruleFlags{}
+	cmd := &cobra.Command{
+		Use:   "update ID",
+		Short: "Replace a targeting rule",
+		Long:  "Replace a targeting rule with the one built from flags or a JSON file. Lists that aren't given are cleared, so pass every list the rule should keep.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := strconv.ParseInt(args[0], 10, 64); err != nil {
+				return fmt.Errorf("invalid targeting rule id %q", args[0])
+			}
+			rule, err := flags.rule(cmd)
+			if err != nil {
+				return err
+			}
+			var updated ruleResult
+			if err := a.client().do(cmd.Context(), http.MethodPut, "/v1/target/"+args[0], nil, rule, &updated); err != nil {
+				return err
+			}
+			printConflicts(updated.Conflicts)
+			return a.print(&updated, ruleTable(&updated.TargetingRule))
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+func newRuleAnalyzeCommand(a *app) *cobra.Command {
+	flags := &ruleFlags{}
+	var id int64
+	cmd := &cobra.Command{
+		Use:   "analyze",
+		Short: "Report the conflicts of a targeting rule without saving it",
+		Long: "Report whether a targeting rule built from flags or a JSON file contradicts itself, " +
+			"or duplicates or overlaps the other rules of its campaign, without saving it.",
+		Example: "  targetctl rule analyze --campaign spotify --include-country US --exclude-country US\n" +
+			"  targetctl rule analyze -f rule.json --id 42",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rule, err := flags.rule(cmd)
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("id") {
+				rule.ID = id
+			}
+			var analysis model.RuleAnalysis
+			if err := a.client().do(cmd.Context(), http.MethodPost, "/v1/target/analyze", nil, rule, &analysis); err != nil {
+				return err
+			}
+			return a.print(&analysis, func(w *tabwriter.Writer) {
+				if len(analysis.Conflicts) == 0 {
+					fmt.Fprintln(w, "no conflicts")
+					return
+				}
+				fmt.Fprintln(w, "SEVERITY\tKIND\tMESSAGE")
+				for _, conflict := range analysis.Conflicts {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", conflict.Severity, conflict.Kind, conflict.Message)
+				}
+			})
+		},
+	}
+	flags.register(cmd)
+	cmd.Flags().Int64Var(&id, "id", 0, "analyze the rule as a replacement of the rule with this ID")
+	return cmd
+}
+
+func newRuleEstimateCommand(a *app) *cobra.Command {
+	flags := &ruleFlags{}
+	cmd := &cobra.Command{
+		Use:   "estimate",
+		Short: "Estimate how many recent requests a targeting rule would match",
+		Long: "Estimate the reach of a targeting rule built from flags or a JSON file: how many of the " +
+			"delivery requests recorded over the server's reach window it would have matched.",
+		Example: "  targetctl rule estimate --include-country US,CA --include-os android",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rule, err := flags.rule(cmd)
+			if err != nil {
+				return err
+			}
+			var estimate model.ReachEstimate
+			body := &model.ReachRequest{Rules: []*model.TargetingRule{rule}}
+			if err := a.client().do(cmd.Context(), http.MethodPost, "/v1/target/estimate", nil, body, &estimate); err != nil {
+				return err
+			}
+			return a.print(&estimate, func(w *tabwriter.Writer) {
+				fmt.Fprintf(w, "REACH\t%d\n", estimate.Reach)
+				fmt.Fprintf(w, "REQUESTS\t%d\n", estimate.Requests)
+				fmt.Fprintf(w, "SHARE\t%.1f%%\n", estimate.Share*100)
+				fmt.Fprintf(w, "WINDOW\t%s\n", time.Duration(estimate.WindowSeconds*float64(time.Second)).Round(time.Second))
+				if estimate.Untracked > 0 {
+					fmt.Fprintf(w, "UNTRACKED\t%d\n", estimate.Untracked)
+				}
+			})
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+func newRuleDeleteCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete ID",
+		Short: "Delete a targeting rule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := strconv.ParseInt(args[0], 10, 64); err != nil {
+				return fmt.Errorf("invalid targeting rule id %q", args[0])
+			}
+			if err := a.client().do(cmd.Context(), http.MethodDelete, "/v1/target/"+args[0], nil, nil, nil); err != nil {
+				return err
+			}
+			fmt.Printf("targeting rule %s deleted\n", args[0])
+			return nil
+		},
+	}
+}
+
+// ruleResult is a stored targeting rule along with the conflicts it has with
+// the other rules of its campaign
+type ruleResult struct {
+	model.TargetingRule
+	Conflicts []model.RuleConflict `json:"conflicts,omitempty"`
+}
+
+// printConflicts warns about the conflicts of a stored rule on standard
+// error, so they don't mix with JSON output
+func printConflicts(conflicts []model.RuleConflict) {
+	for _, conflict := range conflicts {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", conflict.Severity, conflict.Message)
+	}
+}
+
+// ruleTable renders a single targeting rule
+func ruleTable(rule *model.TargetingRule) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "ID\t%d\n", rule.ID)
+		fmt.Fprintf(w, "CAMPAIGN\t%s\n", rule.CampaignID)
+		fmt.Fprintf(w, "INCLUDE\t%s\n", describeValues(rule, true))
+		fmt.Fprintf(w, "EXCLUDE\t%s\n", describeValues(rule, false))
+		if rule.AppVersion != "" {
+			fmt.Fprintf(w, "APP VERSION\t%s\n", rule.AppVersion)
+		}
+		if rule.Expression != nil {
+			fmt.Fprintf(w, "EXPRESSION\t%s\n", rule.Expression)
+		}
+		if rule.EffectiveFrom != nil {
+			fmt.Fprintf(w, "EFFECTIVE FROM\t%s\n", rule.EffectiveFrom.Format(time.RFC3339))
+		}
+		if rule.EffectiveUntil != nil {
+			fmt.Fprintf(w, "EFFECTIVE UNTIL\t%s\n", rule.EffectiveUntil.Format(time.RFC3339))
+		}
+		fmt.Fprintf(w, "SHADOW\t%t\n", rule.Shadow)
+	}
+}
+
+// describeValues summarizes the include or exclude lists of a rule, such as
+// country=US,CA os=android
+func describeValues(rule *model.TargetingRule, include bool) string {
+	lists := [][]string{rule.ExcludeCountry, rule.ExcludeOS, rule.ExcludeApp, rule.ExcludeDeviceType, rule.ExcludeRegion, rule.ExcludeCity, rule.ExcludeSegment}
+	if include {
+		lists = [][]string{rule.IncludeCountry, rule.IncludeOS, rule.IncludeApp, rule.IncludeDeviceType, rule.IncludeRegion, rule.IncludeCity, rule.IncludeSegment}
+	}
+
+	var parts []string
+	for i, values := range lists {
+		if len(values) > 0 {
+			parts = append(parts, ruleDimensions[i]+"="+strings.Join(values, ","))
+		}
+	}
+	for name, values := range rule.Custom {
+		list := values.Exclude
+		if include {
+			list = values.Include
+		}
+		if len(list) > 0 {
+			parts = append(parts, name+"="+strings.Join(list, ","))
+		}
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/spf13/cobra"
+)
+
+// sampleCampaigns are the campaigns created by seed, matching the sample data
+// of the in-memory repository
+var sampleCampaigns = []*model.BulkCampaignRequest{
+	{
+		CampaignRequest: model.CampaignRequest{ID: "spotify", Name: "Spotify - Music for everyone", Image: "https://somelink", CTA: "Download"},
+		Rules:           []*model.TargetingRule{{IncludeCountry: []string{"US", "Canada"}}},
+	},
+	{
+		CampaignRequest: model.CampaignRequest{ID: "duolingo", Name: "Duolingo: Best way to learn", Image: "https://somelink2", CTA: "Install"},
+		Rules:           []*model.TargetingRule{{IncludeOS: []string{"Android", "iOS"}, ExcludeCountry: []string{"US"}}},
+	},
+	{
+		CampaignRequest: model.CampaignRequest{ID: "subwaysurfer", Name: "Subway Surfer", Image: "https://somelink3", CTA: "Play"},
+		Rules:           []*model.TargetingRule{{IncludeOS: []string{"Android"}, IncludeApp: []string{"com.gametion.ludokinggame"}}},
+	},
+}
+
+func newSeedCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "seed",
+		Short: "Create the sample campaigns and their targeting rules",
+		Long:  "Create the sample campaigns and their targeting rules, e.g. to set up a development or CI environment. They are created together, so nothing is created if any of them already exists.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var results []*model.BulkCampaignResult
+			if err := a.client().do(cmd.Context(), http.MethodPost, "/v1/campaigns/bulk", nil, sampleCampaigns, &results); err != nil {
+				return err
+			}
+			return a.print(results, resultsTable(results))
+		},
+	}
+}
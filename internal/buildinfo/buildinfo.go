@@ -0,0 +1,49 @@
+// Package buildinfo identifies the running build. Release builds set its
+// variables at link time:
+//
+//	go build -ldflags "-X github.com/Harshi-itaSinha/target-engine/internal/buildinfo.Version=v1.4.0 \
+//	  -X github.com/Harshi-itaSinha/target-engine/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/Harshi-itaSinha/target-engine/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// Set with -ldflags "-X ..." at link time
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Get returns the build info. Without a commit set at link time, it falls
+// back to the VCS revision the Go toolchain stamps into binaries built from a
+// repository checkout.
+func Get() model.BuildInfo {
+	info := model.BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+	if info.Commit != "" {
+		return info
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			}
+		}
+	}
+	return info
+}
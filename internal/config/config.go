@@ -7,27 +7,57 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert/yaml"
 )
 
+// configFile is the name of the YAML config file under internal/config
+const configFile = "config.dev.yml"
+
 // Config holds all configuration for the application
 type Config struct {
-	Server    ServerConfig
-	Cache     CacheConfig
-	Metrics   MetricsConfig
-	Database  DatabaseConfig
-	RateLimit RateLimitConfig
+	Server        ServerConfig
+	Cache         CacheConfig
+	Metrics       MetricsConfig
+	Database      DatabaseConfig
+	RateLimit     RateLimitConfig     `yaml:"rateLimit"`
+	Auth          AuthConfig          `yaml:"auth"`
+	GRPC          GRPCConfig          `yaml:"grpc"`
+	Log           LogConfig           `yaml:"log"`
+	Counters      CountersConfig      `yaml:"counters"`
+	Tracing       TracingConfig       `yaml:"tracing"`
+	ErrorTracking ErrorTrackingConfig `yaml:"errorTracking"`
+	Events        EventsConfig        `yaml:"events"`
+	Dimensions    DimensionsConfig    `yaml:"dimensions"`
+	Geo           GeoConfig           `yaml:"geo"`
+	Compression   CompressionConfig   `yaml:"compression"`
+	Tenancy       TenancyConfig       `yaml:"tenancy"`
+	Stats         StatsConfig         `yaml:"stats"`
+	Idempotency   IdempotencyConfig   `yaml:"idempotency"`
+	Delivery      DeliveryConfig      `yaml:"delivery"`
+	CORS          CORSConfig          `yaml:"cors"`
+	Recording     RecordingConfig     `yaml:"recording"`
+	Lifecycle     LifecycleConfig     `yaml:"lifecycle"`
+	Tracking      TrackingConfig      `yaml:"tracking"`
+	Segments      SegmentsConfig      `yaml:"segments"`
+	KillSwitch    KillSwitchConfig    `yaml:"killSwitch"`
+	Stream        StreamConfig        `yaml:"stream"`
+	Campaigns     CampaignsConfig     `yaml:"campaigns"`
+	DecisionLog   DecisionLogConfig   `yaml:"decisionLog"`
 }
 
-// ServerConfig holds server configuration
+// ServerConfig holds server configuration. TrustedProxies lists the IP
+// addresses or CIDR ranges of the load balancers and proxies in front of the
+// server, whose X-Forwarded-For and X-Real-IP headers identify clients.
 type ServerConfig struct {
-	Port         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Port           string        `yaml:"port"`
+	ReadTimeout    time.Duration `yaml:"readTimeout"`
+	WriteTimeout   time.Duration `yaml:"writeTimeout"`
+	IdleTimeout    time.Duration `yaml:"idleTimeout"`
+	TrustedProxies []string      `yaml:"trustedProxies"`
 }
 
 // CacheConfig holds cache configuration
@@ -35,31 +65,441 @@ type CacheConfig struct {
 	TTL             time.Duration `yaml:"ttl"`
 	CleanupInterval time.Duration `yaml:"cleanupInterval"`
 	MaxSize         int           `yaml:"maxSize"`
+	WatchChanges    bool          `yaml:"watchChanges"`
+	// RefreshJitter spreads scheduled cache refreshes by up to this fraction
+	// of CleanupInterval either way, so replicas don't all reload at once.
+	// Defaults to 0.1.
+	RefreshJitter float64 `yaml:"refreshJitter"`
+	// WarmupTimeout bounds how long startup waits for the first cache load
+	// before serving anyway; readiness keeps failing until the load succeeds
+	WarmupTimeout time.Duration `yaml:"warmupTimeout"`
+	// ServeStale keeps delivery going from the last loaded cache while
+	// refreshes fail
+	ServeStale ServeStaleConfig `yaml:"serveStale"`
+	// Standby shares the targeting cache with other instances through Redis
+	Standby CacheStandbyConfig `yaml:"standby"`
+	// File persists the targeting cache to disk between restarts
+	File CacheFileConfig `yaml:"file"`
+}
+
+// CacheStandbyConfig publishes the targeting cache loaded from the
+// repository, and the query results computed from it, to Redis. New
+// instances warm up from a published cache up to MaxAge old (5m by default)
+// instead of loading the repository, and instances holding the same cache
+// share query results. Timeout bounds the Redis lookups made while serving
+// delivery requests (50ms by default).
+type CacheStandbyConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	RedisURI string        `yaml:"redisUri"`
+	Prefix   string        `yaml:"prefix"`
+	MaxAge   time.Duration `yaml:"maxAge"`
+	Timeout  time.Duration `yaml:"timeout"`
 }
 
-// MetricsConfig holds metrics configuration
+// CacheFileConfig saves the targeting cache, with the engaged kill switches,
+// to the file at Path every Interval it changed (a minute by default). At
+// startup the cache is restored from a file up to MaxAge old (24h by
+// default) before the repository is reached, so instances started during a
+// database outage serve the last known campaigns until it is back.
+type CacheFileConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Path     string        `yaml:"path"`
+	Interval time.Duration `yaml:"interval"`
+	MaxAge   time.Duration `yaml:"maxAge"`
+}
+
+// ServeStaleConfig lets delivery be served from the last successfully loaded
+// targeting cache while refreshes fail, e.g. during a database outage, for up
+// to MaxStaleness after the first failure (an hour by default). Responses
+// served meanwhile carry X-Cache-Stale: true and readiness keeps passing.
+type ServeStaleConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	MaxStaleness time.Duration `yaml:"maxStaleness"`
+}
+
+// CompressionConfig controls response compression. Bodies smaller than
+// MinSize bytes are sent uncompressed.
+type CompressionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	MinSize int  `yaml:"minSize"`
+}
+
+// MetricsConfig holds metrics configuration. Debug additionally serves pprof,
+// expvar and a runtime snapshot on the metrics port.
 type MetricsConfig struct {
 	Enabled bool
 	Port    string
 	Path    string
+	Debug   bool
+	Labels  MetricsLabelsConfig
+}
+
+// MetricsLabelsConfig enables the delivery metrics by tenant and by campaign.
+// Only the TopTenants (20 by default) and TopCampaigns (100 by default) most
+// frequent ones, re-ranked every RankInterval (1m by default), get series of
+// their own; the others are counted as "other".
+type MetricsLabelsConfig struct {
+	Tenant       bool          `yaml:"tenant"`
+	Campaign     bool          `yaml:"campaign"`
+	TopTenants   int           `yaml:"topTenants"`
+	TopCampaigns int           `yaml:"topCampaigns"`
+	RankInterval time.Duration `yaml:"rankInterval"`
 }
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
-	Driver           string        `yaml:"driver"`
-	ConnectionString string        `yaml:"uri"`
-	MaxOpenConns     int           `yaml:"maxOpenConns"`
-	MaxIdleConns     int           `yaml:"maxIdleConns"`
-	ConnMaxLifetime  time.Duration `yaml:"connMaxLifetime"`
-	DatabaseName     string        `yaml:"name"`
+	Driver           string           `yaml:"driver"`
+	ConnectionString string           `yaml:"uri"`
+	MaxOpenConns     int              `yaml:"maxOpenConns"`
+	MaxIdleConns     int              `yaml:"maxIdleConns"`
+	ConnMaxLifetime  time.Duration    `yaml:"connMaxLifetime"`
+	DatabaseName     string           `yaml:"name"`
+	// Mongo client timeouts and read preference; zero values use the
+	// database package defaults
+	ConnectTimeout         time.Duration `yaml:"connectTimeout"`
+	ServerSelectionTimeout time.Duration `yaml:"serverSelectionTimeout"`
+	SocketTimeout          time.Duration `yaml:"socketTimeout"`
+	ReadPreference         string        `yaml:"readPreference"`
+	Resilience       ResilienceConfig `yaml:"resilience"`
+	DualWrite        DualWriteConfig  `yaml:"dualWrite"`
 }
 
-// RateLimitConfig holds rate limiting configuration
+// DualWriteConfig mirrors campaign and rule writes to a second repository
+// while data is migrated to it with cmd/migrate
+type DualWriteConfig struct {
+	Enabled          bool   `yaml:"enabled"`
+	Driver           string `yaml:"driver"`
+	ConnectionString string `yaml:"uri"`
+	DatabaseName     string `yaml:"name"`
+}
+
+// ResilienceConfig controls retries and the circuit breaker around MongoDB
+// operations. Zero values use the repository defaults.
+type ResilienceConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	MaxRetries       int           `yaml:"maxRetries"`
+	BaseDelay        time.Duration `yaml:"baseDelay"`
+	MaxDelay         time.Duration `yaml:"maxDelay"`
+	FailureThreshold int           `yaml:"failureThreshold"`
+	OpenTimeout      time.Duration `yaml:"openTimeout"`
+}
+
+// RateLimitConfig holds rate limiting configuration. Limits apply per API key,
+// or per client IP for requests without a valid one; Routes override them for
+// individual routes, e.g. to be stricter on write endpoints. The redis
+// backend shares the limits between all server instances.
 type RateLimitConfig struct {
-	Enabled    bool
-	RPS        int
-	BurstSize  int
-	WindowSize time.Duration
+	Enabled         bool                   `yaml:"enabled"`
+	Backend         string                 `yaml:"backend"` // memory or redis
+	RedisURI        string                 `yaml:"redisUri"`
+	Prefix          string                 `yaml:"prefix"`
+	RPS             int                    `yaml:"rps"`
+	BurstSize       int                    `yaml:"burstSize"`
+	WindowSize      time.Duration          `yaml:"windowSize"`
+	CleanupInterval time.Duration          `yaml:"cleanupInterval"` // how often idle clients are forgotten
+	IdleTTL         time.Duration          `yaml:"idleTTL"`         // how long a client may go unseen before it is forgotten
+	MaxClients      int                    `yaml:"maxClients"`      // clients tracked per limiter by the memory backend
+	Routes          []RouteRateLimitConfig `yaml:"routes"`
+}
+
+// RouteRateLimitConfig overrides the rate limit of one route. Path is the
+// route template as registered, e.g. /v1/campaign/{id}, and an empty Method
+// matches every method.
+type RouteRateLimitConfig struct {
+	Method    string `yaml:"method"`
+	Path      string `yaml:"path"`
+	RPS       int    `yaml:"rps"`
+	BurstSize int    `yaml:"burstSize"`
+}
+
+// CountersConfig selects the store used for frequency caps
+type CountersConfig struct {
+	Backend  string `yaml:"backend"` // memory or redis
+	RedisURI string `yaml:"redisUri"`
+	Prefix   string `yaml:"prefix"`
+}
+
+// EventsConfig holds configuration for publishing delivery events to Kafka
+type EventsConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Brokers      []string      `yaml:"brokers"`
+	Topic        string        `yaml:"topic"`
+	BatchSize    int           `yaml:"batchSize"`
+	BatchTimeout time.Duration `yaml:"batchTimeout"`
+	BufferSize   int           `yaml:"bufferSize"` // events queued before new ones are dropped
+}
+
+// DimensionsConfig registers custom targeting dimensions in addition to the
+// built-in app, country, os and device_type. Names are lower case letters,
+// digits and underscores.
+type DimensionsConfig struct {
+	Custom []string `yaml:"custom"`
+}
+
+// StatsConfig controls the traffic breakdowns served by the stats endpoint.
+// Window is the sliding window they cover, a minute by default.
+type StatsConfig struct {
+	Window    time.Duration       `yaml:"window"`
+	Campaigns CampaignStatsConfig `yaml:"campaigns"`
+	Reach     ReachStatsConfig    `yaml:"reach"`
+}
+
+// ReachStatsConfig sizes the frequency tables of request dimension
+// combinations that reach estimates are computed from. They cover Window, an
+// hour by default, and hold up to MaxCombinations distinct combinations per
+// twelfth of it, 10000 by default.
+type ReachStatsConfig struct {
+	Window          time.Duration `yaml:"window"`
+	MaxCombinations int           `yaml:"maxCombinations"`
+}
+
+// CampaignStatsConfig controls the daily delivery counts per campaign served
+// by /v1/stats/campaigns. Counts are aggregated in memory and flushed to the
+// repository every FlushInterval, 30 seconds by default. ByCountry and ByOS
+// break them down by the country and OS of the requests.
+type CampaignStatsConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	FlushInterval time.Duration `yaml:"flushInterval"`
+	ByCountry     bool          `yaml:"byCountry"`
+	ByOS          bool          `yaml:"byOS"`
+}
+
+// DecisionLogConfig controls the log of delivery decisions served by
+// /v1/decisions. SampleRate is the percentage of delivery requests logged.
+// Decisions are buffered in memory, up to BufferSize (10000 by default), and
+// added to the repository every FlushInterval, 5 seconds by default. They
+// expire after TTL, a week by default.
+type DecisionLogConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	SampleRate    float64       `yaml:"sampleRate"`
+	TTL           time.Duration `yaml:"ttl"`
+	FlushInterval time.Duration `yaml:"flushInterval"`
+	BufferSize    int           `yaml:"bufferSize"`
+}
+
+// IdempotencyConfig controls Idempotency-Key handling on create endpoints.
+// TTL is how long the result of a request is replayed, a day by default.
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// DeliveryConfig controls campaign delivery. SelectStrategy is the default
+// strategy of /v1/delivery/select: priority_weight, random or round_robin.
+// BatchTimeout is the deadline shared by the requests of a
+// /v1/delivery/batch call, a second by default.
+type DeliveryConfig struct {
+	SelectStrategy string              `yaml:"selectStrategy"`
+	BatchTimeout   time.Duration       `yaml:"batchTimeout"`
+	Cache          DeliveryCacheConfig `yaml:"cache"`
+}
+
+// DeliveryCacheConfig sets the Cache-Control of /v1/delivery responses.
+// MaxAge is how long CDNs and HTTP caches may reuse a response; zero makes
+// them revalidate it with its ETag every time. StaleWhileRevalidate lets
+// them serve an expired response for that long while they revalidate it.
+// Responses to requests with a user_id are never cached.
+type DeliveryCacheConfig struct {
+	MaxAge               time.Duration `yaml:"maxAge"`
+	StaleWhileRevalidate time.Duration `yaml:"staleWhileRevalidate"`
+}
+
+// LifecycleConfig controls the campaign status transitions triggered by
+// flight dates. Interval is how often they are applied, a minute by default.
+type LifecycleConfig struct {
+	Interval time.Duration `yaml:"interval"`
+}
+
+// TrackingConfig controls the impression and click tracking endpoints. With
+// CountImpressions, frequency caps and budgets count the impressions tracked
+// for a campaign instead of every time it is served. Events are only
+// accepted with a token served with the campaign, signed with Secret and
+// valid for TokenTTL (24h by default); without a secret every event is
+// rejected.
+type TrackingConfig struct {
+	CountImpressions bool          `yaml:"countImpressions"`
+	Secret           string        `yaml:"secret"`
+	TokenTTL         time.Duration `yaml:"tokenTTL"`
+}
+
+// KillSwitchConfig controls how often the kill switches engaged on other
+// instances are picked up, ten seconds by default. Switches engaged through
+// an instance take effect on it immediately.
+type KillSwitchConfig struct {
+	PollInterval time.Duration `yaml:"pollInterval"`
+}
+
+// StreamConfig controls /v1/stream, the Server-Sent Events stream of
+// campaign changes. History is how many recent changes are kept for clients
+// resuming with Last-Event-ID (1000 by default), Heartbeat how often idle
+// streams get a comment to keep proxies from closing them (15s by default),
+// and MaxSubscribers bounds the streams served at once (1000 by default).
+type StreamConfig struct {
+	History        int           `yaml:"history"`
+	Heartbeat      time.Duration `yaml:"heartbeat"`
+	MaxSubscribers int           `yaml:"maxSubscribers"`
+}
+
+// CampaignsConfig controls the validation of campaign writes. AllowedCTAs
+// lists the calls to action campaigns and creatives may use, compared
+// ignoring case; without any the service's default list applies, and "*"
+// allows any.
+type CampaignsConfig struct {
+	AllowedCTAs []string `yaml:"allowedCTAs"`
+}
+
+// SegmentsConfig selects where the audience segments of users are looked up
+// for rules with include_segment or exclude_segment. The redis provider reads
+// the set <Prefix>:<user_id>; the http provider calls
+// GET <URL>?user_id=<user_id>, giving up after Timeout (100ms by default).
+// Lookups are cached for CacheTTL, a minute by default, for up to CacheSize
+// users. Without a provider users belong to no segment.
+type SegmentsConfig struct {
+	Provider  string        `yaml:"provider"` // redis or http; empty disables lookups
+	RedisURI  string        `yaml:"redisUri"`
+	Prefix    string        `yaml:"prefix"`
+	URL       string        `yaml:"url"`
+	Timeout   time.Duration `yaml:"timeout"`
+	CacheTTL  time.Duration `yaml:"cacheTTL"`
+	CacheSize int           `yaml:"cacheSize"`
+}
+
+// CORSConfig controls which browser origins may call the API. Origins may
+// contain wildcards, e.g. https://*.example.com, or be "*" for any origin;
+// without origins no cross-origin requests are allowed. Empty methods and
+// headers and a zero MaxAge use the middleware defaults.
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowedOrigins"`
+	AllowedMethods   []string      `yaml:"allowedMethods"`
+	AllowedHeaders   []string      `yaml:"allowedHeaders"`
+	AllowCredentials bool          `yaml:"allowCredentials"`
+	MaxAge           time.Duration `yaml:"maxAge"` // how long browsers cache preflight responses
+}
+
+// RecordingConfig controls recording of sampled /v1/delivery requests and
+// the responses served as JSON Lines, for replaying against later builds.
+// SampleRate is the percentage of requests recorded. The file sink appends to
+// Path and rotates it at MaxSize bytes; the s3 sink uploads an object every
+// FlushInterval, a minute by default.
+type RecordingConfig struct {
+	Enabled       bool              `yaml:"enabled"`
+	SampleRate    float64           `yaml:"sampleRate"`
+	Sink          string            `yaml:"sink"` // file or s3
+	Path          string            `yaml:"path"`
+	MaxSize       int64             `yaml:"maxSize"`  // bytes before the file is rotated, 100MB by default
+	MaxFiles      int               `yaml:"maxFiles"` // rotated files kept, 10 by default
+	S3            S3RecordingConfig `yaml:"s3"`
+	FlushInterval time.Duration     `yaml:"flushInterval"`
+	BufferSize    int               `yaml:"bufferSize"` // recordings queued before new ones are dropped
+}
+
+// S3RecordingConfig holds the bucket recordings are uploaded to. Endpoint
+// points the sink at an S3-compatible store such as MinIO.
+type S3RecordingConfig struct {
+	Bucket        string `yaml:"bucket"`
+	Prefix        string `yaml:"prefix"`
+	Region        string `yaml:"region"`
+	Endpoint      string `yaml:"endpoint"`
+	MaxObjectSize int    `yaml:"maxObjectSize"` // bytes buffered before uploading early, 16MB by default
+}
+
+// GeoConfig holds configuration for resolving the country of delivery
+// requests that don't carry one from the client IP
+type GeoConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	DatabasePath string `yaml:"databasePath"` // MaxMind GeoIP2/GeoLite2 Country or City mmdb file
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	Endpoint    string  `yaml:"endpoint"` // OTLP gRPC collector address, host:port
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"serviceName"`
+	SampleRatio float64 `yaml:"sampleRatio"` // fraction of new traces sampled; zero samples all
+}
+
+// ErrorTrackingConfig holds configuration for reporting recovered panics to
+// Sentry. Events are sent from a buffer of BufferSize (100 by default) in the
+// background, each giving up after Timeout (5s by default), and dropped
+// while the buffer is full.
+type ErrorTrackingConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	DSN         string        `yaml:"dsn"`         // Sentry project DSN, https://<key>@<host>/<project>
+	Environment string        `yaml:"environment"` // reported with each event, e.g. production
+	Timeout     time.Duration `yaml:"timeout"`
+	BufferSize  int           `yaml:"bufferSize"`
+}
+
+// LogConfig holds logging configuration
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn or error
+	Format string `yaml:"format"` // json or text
+}
+
+// GRPCConfig holds configuration for the gRPC delivery server
+type GRPCConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Port    string `yaml:"port"`
+}
+
+// AuthConfig holds authentication configuration for the admin endpoints.
+// Enabled turns on API key checks; JWT bearer tokens are configured
+// separately and, when both are on, either is accepted.
+type AuthConfig struct {
+	Enabled     bool      `yaml:"enabled"`
+	APIKeys     []string  `yaml:"apiKeys"`
+	APIKeysFile string    `yaml:"apiKeysFile"`
+	JWT         JWTConfig `yaml:"jwt"`
+}
+
+// TenancyConfig enables multi-tenancy. Requests act for the tenant bound to
+// their API key in APIKeys or named in the Claim of their bearer token, and
+// otherwise for the tenant in the Header, which defaults to X-Tenant-ID. With
+// tenancy disabled every request acts for the default tenant.
+type TenancyConfig struct {
+	Enabled bool              `yaml:"enabled"`
+	Header  string            `yaml:"header"`
+	Claim   string            `yaml:"claim"`   // defaults to tenant
+	APIKeys map[string]string `yaml:"apiKeys"` // API key to the tenant it is bound to
+}
+
+// JWTConfig configures bearer token verification. Signing keys come from
+// JWKSURL when set, otherwise tokens must be HMAC signed with Secret.
+type JWTConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	JWKSURL  string `yaml:"jwksUrl"`
+	Secret   string `yaml:"secret"`
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+}
+
+// LoadAPIKeys returns the configured API keys merged with those read from
+// APIKeysFile, which holds one key per line. Blank lines and lines starting
+// with # are ignored.
+func (c AuthConfig) LoadAPIKeys() ([]string, error) {
+	keys := make([]string, 0, len(c.APIKeys))
+	for _, key := range c.APIKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	if c.APIKeysFile == "" {
+		return keys, nil
+	}
+
+	data, err := os.ReadFile(c.APIKeysFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file '%s': %w", c.APIKeysFile, err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	return keys, nil
 }
 
 // LoadConfig loads configuration from environment variables
@@ -75,17 +515,32 @@ func LoadConfig() *Config {
 		env = "dev" // fallback to dev if not set
 	}
 
-	getConfigPath:= getConfigPath("config.dev.yml")
-	data, err := ioutil.ReadFile(getConfigPath)
+	cfg, err := readConfig(getConfigPath(configFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return cfg
+}
+
+// readConfig reads and parses the YAML config file at path, applies the
+// environment variable overrides, then fills in defaults and validates it
+func readConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Fatalf("failed to read config file '%s': %v",getConfigPath, err)
+		return nil, fmt.Errorf("failed to read config file '%s': %w", path, err)
 	}
 
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		log.Fatalf("failed to unmarshal config: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid config environment variables: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config file '%s': %w", path, err)
 	}
-	return &cfg
+	return &cfg, nil
 }
 
 func getConfigPath(filename string) string {
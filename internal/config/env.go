@@ -0,0 +1,166 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// EnvPrefix starts the names of the environment variables overriding config
+// settings. A setting's variable is named after its YAML path in upper snake
+// case, e.g. TARGET_CACHE_TTL for cache.ttl and TARGET_RATE_LIMIT_RPS for
+// rateLimit.rps.
+const EnvPrefix = "TARGET_"
+
+// envAliases are shorter names accepted for commonly overridden settings.
+// The full name wins when both are set.
+var envAliases = map[string]string{
+	"TARGET_DATABASE_URI":    "TARGET_DB_URI",
+	"TARGET_DATABASE_NAME":   "TARGET_DB_NAME",
+	"TARGET_DATABASE_DRIVER": "TARGET_DB_DRIVER",
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides the settings of cfg with the environment variables set
+// for them, on top of the values read from the config file. Lists are comma
+// separated and maps are comma separated key=value pairs. Lists of objects,
+// such as rateLimit.routes, can only be set in the file.
+func applyEnv(cfg *Config) error {
+	return applyEnvLookup(cfg, os.LookupEnv)
+}
+
+// applyEnvLookup is applyEnv reading variables through lookup
+func applyEnvLookup(cfg *Config, lookup func(string) (string, bool)) error {
+	var errs []error
+	walkSettings(reflect.ValueOf(cfg).Elem(), strings.TrimSuffix(EnvPrefix, "_"), func(name string, field reflect.Value) {
+		value, ok := lookup(name)
+		if !ok {
+			if alias, hasAlias := envAliases[name]; hasAlias {
+				name = alias
+				value, ok = lookup(alias)
+			}
+		}
+		if !ok {
+			return
+		}
+		if err := setSetting(field, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// walkSettings calls fn with the environment variable name of every setting
+// of the struct v, descending into nested structs
+func walkSettings(v reflect.Value, prefix string, fn func(name string, field reflect.Value)) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			// Untagged fields are read from their lower cased name
+			key = strings.ToLower(field.Name)
+		}
+		name := prefix + "_" + envName(key)
+		value := v.Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			walkSettings(value, name, fn)
+			continue
+		}
+		fn(name, value)
+	}
+}
+
+// envName converts a camel case YAML key to upper snake case, e.g. maxSize
+// to MAX_SIZE and jwksUrl to JWKS_URL
+func envName(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && !unicode.IsUpper(runes[i-1]) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// setSetting parses value into the setting field
+func setSetting(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return errors.New("can only be set in the config file")
+		}
+		list := splitList(value)
+		field.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		if field.Type().Key().Kind() != reflect.String || field.Type().Elem().Kind() != reflect.String {
+			return errors.New("can only be set in the config file")
+		}
+		entries := make(map[string]string)
+		for _, pair := range splitList(value) {
+			key, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid entry %q, expected key=value", pair)
+			}
+			entries[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+		field.Set(reflect.ValueOf(entries))
+	default:
+		return errors.New("can only be set in the config file")
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, dropping blank entries
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
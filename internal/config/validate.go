@@ -0,0 +1,298 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// Defaults filled in by Validate for settings left unset
+const (
+	DefaultPort           = "8080"
+	DefaultReadTimeout    = 10 * time.Second
+	DefaultWriteTimeout   = 10 * time.Second
+	DefaultIdleTimeout    = 60 * time.Second
+	DefaultDatabaseDriver = "mongo"
+	DefaultMetricsPath    = "/metrics"
+)
+
+// ValidationError reports every problem Validate found in a config, each
+// prefixed with the YAML path of the offending setting
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config, %d problem(s):\n  %s", len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+// validator collects the problems found in a config
+type validator struct {
+	problems []string
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(path, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.fail(path, "is required")
+	}
+}
+
+func (v *validator) positive(path string, value int64) {
+	if value <= 0 {
+		v.fail(path, "must be greater than 0")
+	}
+}
+
+func (v *validator) notNegative(path string, value int64) {
+	if value < 0 {
+		v.fail(path, "must not be negative")
+	}
+}
+
+func (v *validator) between(path string, value, low, high float64) {
+	if value < low || value > high {
+		v.fail(path, "must be between %g and %g", low, high)
+	}
+}
+
+// oneOf checks an optional setting; empty values select the consumer's default
+func (v *validator) oneOf(path, value string, allowed ...string) {
+	if value != "" && !slices.Contains(allowed, value) {
+		v.fail(path, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
+
+func (v *validator) port(path, value string) {
+	if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+		v.fail(path, "must be a port number between 1 and 65535, got %q", value)
+	}
+}
+
+// Validate fills in the defaults of unset optional settings, then checks
+// that required settings are present and values are in range. It reports
+// every problem at once in a *ValidationError, so a broken config file can
+// be fixed in one go.
+func (c *Config) Validate() error {
+	c.applyDefaults()
+
+	v := &validator{}
+	v.port("server.port", c.Server.Port)
+	v.notNegative("server.readTimeout", int64(c.Server.ReadTimeout))
+	v.notNegative("server.writeTimeout", int64(c.Server.WriteTimeout))
+	v.notNegative("server.idleTimeout", int64(c.Server.IdleTimeout))
+	for i, proxy := range c.Server.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				v.fail(fmt.Sprintf("server.trustedProxies[%d]", i), "must be an IP address or CIDR range, got %q", proxy)
+			}
+		}
+	}
+
+	v.oneOf("log.level", c.Log.Level, "debug", "info", "warn", "warning", "error")
+	v.oneOf("log.format", c.Log.Format, "json", "text")
+
+	v.positive("cache.ttl", int64(c.Cache.TTL))
+	v.positive("cache.maxSize", int64(c.Cache.MaxSize))
+	v.notNegative("cache.cleanupInterval", int64(c.Cache.CleanupInterval))
+	v.between("cache.refreshJitter", c.Cache.RefreshJitter, 0, 1)
+	v.notNegative("cache.warmupTimeout", int64(c.Cache.WarmupTimeout))
+	v.notNegative("cache.serveStale.maxStaleness", int64(c.Cache.ServeStale.MaxStaleness))
+	if c.Cache.Standby.Enabled {
+		v.required("cache.standby.redisUri", c.Cache.Standby.RedisURI)
+		v.notNegative("cache.standby.maxAge", int64(c.Cache.Standby.MaxAge))
+		v.notNegative("cache.standby.timeout", int64(c.Cache.Standby.Timeout))
+	}
+	if c.Cache.File.Enabled {
+		v.required("cache.file.path", c.Cache.File.Path)
+		v.notNegative("cache.file.interval", int64(c.Cache.File.Interval))
+		v.notNegative("cache.file.maxAge", int64(c.Cache.File.MaxAge))
+	}
+
+	if c.Metrics.Enabled {
+		v.port("metrics.port", c.Metrics.Port)
+		if !strings.HasPrefix(c.Metrics.Path, "/") {
+			v.fail("metrics.path", "must start with /, got %q", c.Metrics.Path)
+		}
+		v.notNegative("metrics.labels.topTenants", int64(c.Metrics.Labels.TopTenants))
+		v.notNegative("metrics.labels.topCampaigns", int64(c.Metrics.Labels.TopCampaigns))
+		v.notNegative("metrics.labels.rankInterval", int64(c.Metrics.Labels.RankInterval))
+	}
+	if c.GRPC.Enabled {
+		v.port("grpc.port", c.GRPC.Port)
+		if c.GRPC.Port == c.Server.Port {
+			v.fail("grpc.port", "must differ from server.port")
+		}
+	}
+
+	c.validateDatabase(v)
+
+	v.oneOf("counters.backend", c.Counters.Backend, "memory", "redis")
+	if c.RateLimit.Enabled {
+		v.oneOf("rateLimit.backend", c.RateLimit.Backend, "memory", "redis")
+		v.positive("rateLimit.rps", int64(c.RateLimit.RPS))
+		v.notNegative("rateLimit.burstSize", int64(c.RateLimit.BurstSize))
+		v.notNegative("rateLimit.idleTTL", int64(c.RateLimit.IdleTTL))
+		v.notNegative("rateLimit.maxClients", int64(c.RateLimit.MaxClients))
+		for i, route := range c.RateLimit.Routes {
+			path := fmt.Sprintf("rateLimit.routes[%d]", i)
+			v.required(path+".path", route.Path)
+			v.positive(path+".rps", int64(route.RPS))
+			v.notNegative(path+".burstSize", int64(route.BurstSize))
+		}
+	}
+
+	if c.Events.Enabled {
+		if len(c.Events.Brokers) == 0 {
+			v.fail("events.brokers", "is required when events are enabled")
+		}
+		v.required("events.topic", c.Events.Topic)
+		v.notNegative("events.batchSize", int64(c.Events.BatchSize))
+		v.notNegative("events.bufferSize", int64(c.Events.BufferSize))
+	}
+	if c.Tracing.Enabled {
+		v.required("tracing.endpoint", c.Tracing.Endpoint)
+		v.between("tracing.sampleRatio", c.Tracing.SampleRatio, 0, 1)
+	}
+	if c.ErrorTracking.Enabled {
+		v.required("errorTracking.dsn", c.ErrorTracking.DSN)
+		v.notNegative("errorTracking.timeout", int64(c.ErrorTracking.Timeout))
+		v.notNegative("errorTracking.bufferSize", int64(c.ErrorTracking.BufferSize))
+	}
+	if c.Geo.Enabled {
+		v.required("geo.databasePath", c.Geo.DatabasePath)
+	}
+	if c.Auth.JWT.Enabled && c.Auth.JWT.JWKSURL == "" && c.Auth.JWT.Secret == "" {
+		v.fail("auth.jwt", "jwksUrl or secret is required when JWT is enabled")
+	}
+	if c.Compression.Enabled {
+		v.notNegative("compression.minSize", int64(c.Compression.MinSize))
+	}
+
+	v.notNegative("stats.window", int64(c.Stats.Window))
+	v.notNegative("stats.campaigns.flushInterval", int64(c.Stats.Campaigns.FlushInterval))
+	v.notNegative("stats.reach.window", int64(c.Stats.Reach.Window))
+	v.notNegative("stats.reach.maxCombinations", int64(c.Stats.Reach.MaxCombinations))
+	v.notNegative("idempotency.ttl", int64(c.Idempotency.TTL))
+	v.oneOf("delivery.selectStrategy", c.Delivery.SelectStrategy,
+		model.SelectionPriorityWeight, model.SelectionRandom, model.SelectionRoundRobin)
+	v.notNegative("delivery.batchTimeout", int64(c.Delivery.BatchTimeout))
+	v.notNegative("delivery.cache.maxAge", int64(c.Delivery.Cache.MaxAge))
+	v.notNegative("delivery.cache.staleWhileRevalidate", int64(c.Delivery.Cache.StaleWhileRevalidate))
+	v.notNegative("lifecycle.interval", int64(c.Lifecycle.Interval))
+	v.notNegative("tracking.tokenTTL", int64(c.Tracking.TokenTTL))
+	if c.Tracking.CountImpressions && c.Tracking.Secret == "" {
+		v.fail("tracking.secret", "is required when countImpressions is enabled")
+	}
+	v.notNegative("killSwitch.pollInterval", int64(c.KillSwitch.PollInterval))
+	v.notNegative("stream.history", int64(c.Stream.History))
+	v.notNegative("stream.heartbeat", int64(c.Stream.Heartbeat))
+	v.notNegative("stream.maxSubscribers", int64(c.Stream.MaxSubscribers))
+	v.notNegative("cors.maxAge", int64(c.CORS.MaxAge))
+	for i, cta := range c.Campaigns.AllowedCTAs {
+		v.required(fmt.Sprintf("campaigns.allowedCTAs[%d]", i), cta)
+	}
+
+	if c.DecisionLog.Enabled {
+		v.between("decisionLog.sampleRate", c.DecisionLog.SampleRate, 0, 100)
+		v.notNegative("decisionLog.ttl", int64(c.DecisionLog.TTL))
+		v.notNegative("decisionLog.flushInterval", int64(c.DecisionLog.FlushInterval))
+		v.notNegative("decisionLog.bufferSize", int64(c.DecisionLog.BufferSize))
+	}
+
+	if c.Recording.Enabled {
+		v.between("recording.sampleRate", c.Recording.SampleRate, 0, 100)
+		v.oneOf("recording.sink", c.Recording.Sink, "file", "s3")
+		if c.Recording.Sink == "s3" {
+			v.required("recording.s3.bucket", c.Recording.S3.Bucket)
+		} else {
+			v.required("recording.path", c.Recording.Path)
+		}
+		v.notNegative("recording.maxSize", c.Recording.MaxSize)
+		v.notNegative("recording.maxFiles", int64(c.Recording.MaxFiles))
+	}
+
+	v.oneOf("segments.provider", c.Segments.Provider, "redis", "http")
+	switch c.Segments.Provider {
+	case "redis":
+		v.required("segments.redisUri", c.Segments.RedisURI)
+	case "http":
+		v.required("segments.url", c.Segments.URL)
+	}
+	v.notNegative("segments.timeout", int64(c.Segments.Timeout))
+	v.notNegative("segments.cacheTTL", int64(c.Segments.CacheTTL))
+	v.notNegative("segments.cacheSize", int64(c.Segments.CacheSize))
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// validateDatabase checks the repository settings and those of the
+// repository writes are mirrored to
+func (c *Config) validateDatabase(v *validator) {
+	db := c.Database
+	v.oneOf("database.driver", db.Driver, "mongo", "redis", "sqlite")
+	v.required("database.name", db.DatabaseName)
+	v.notNegative("database.maxOpenConns", int64(db.MaxOpenConns))
+	v.notNegative("database.maxIdleConns", int64(db.MaxIdleConns))
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		v.fail("database.maxIdleConns", "must not exceed maxOpenConns")
+	}
+	v.notNegative("database.connMaxLifetime", int64(db.ConnMaxLifetime))
+	v.notNegative("database.connectTimeout", int64(db.ConnectTimeout))
+	v.notNegative("database.serverSelectionTimeout", int64(db.ServerSelectionTimeout))
+	v.notNegative("database.socketTimeout", int64(db.SocketTimeout))
+	v.oneOf("database.readPreference", db.ReadPreference,
+		"primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest")
+
+	if db.Resilience.Enabled {
+		v.notNegative("database.resilience.maxRetries", int64(db.Resilience.MaxRetries))
+		v.notNegative("database.resilience.failureThreshold", int64(db.Resilience.FailureThreshold))
+		if db.Resilience.MaxDelay > 0 && db.Resilience.BaseDelay > db.Resilience.MaxDelay {
+			v.fail("database.resilience.baseDelay", "must not exceed maxDelay")
+		}
+	}
+
+	if dualWrite := db.DualWrite; dualWrite.Enabled {
+		v.required("database.dualWrite.driver", dualWrite.Driver)
+		v.oneOf("database.dualWrite.driver", dualWrite.Driver, "mongo", "redis", "sqlite")
+		v.required("database.dualWrite.name", dualWrite.DatabaseName)
+		if dualWrite.Driver == db.Driver && dualWrite.ConnectionString == db.ConnectionString && dualWrite.DatabaseName == db.DatabaseName {
+			v.fail("database.dualWrite", "must not be the same repository as database")
+		}
+	}
+}
+
+// applyDefaults fills in the server and database settings every deployment
+// needs but the config file may leave out
+func (c *Config) applyDefaults() {
+	if c.Server.Port == "" {
+		c.Server.Port = DefaultPort
+	}
+	if c.Server.ReadTimeout == 0 {
+		c.Server.ReadTimeout = DefaultReadTimeout
+	}
+	if c.Server.WriteTimeout == 0 {
+		c.Server.WriteTimeout = DefaultWriteTimeout
+	}
+	if c.Server.IdleTimeout == 0 {
+		c.Server.IdleTimeout = DefaultIdleTimeout
+	}
+	if c.Database.Driver == "" {
+		c.Database.Driver = DefaultDatabaseDriver
+	}
+	if c.Metrics.Path == "" {
+		c.Metrics.Path = DefaultMetricsPath
+	}
+}
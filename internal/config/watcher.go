@@ -0,0 +1,133 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce coalesces the burst of file events editors emit on save
+const reloadDebounce = 200 * time.Millisecond
+
+// Watcher reloads the config file on SIGHUP or when it changes on disk and
+// notifies registered callbacks, so running components can pick up new
+// settings without a restart. Settings that components only read at startup,
+// such as listen ports and the database connection, still need a restart.
+type Watcher struct {
+	path      string
+	current   *Config
+	callbacks []func(old, updated *Config)
+	mutex     sync.Mutex
+}
+
+// NewWatcher creates a watcher for the config file LoadConfig reads, starting
+// from the already loaded cfg
+func NewWatcher(cfg *Config) *Watcher {
+	return &Watcher{
+		path:    getConfigPath(configFile),
+		current: cfg,
+	}
+}
+
+// OnChange registers fn to be called with the previous and the reloaded
+// config after every successful reload. Callbacks run one at a time, in
+// registration order.
+func (w *Watcher) OnChange(fn func(old, updated *Config)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.callbacks = append(w.callbacks, fn)
+}
+
+// Current returns the most recently loaded config
+func (w *Watcher) Current() *Config {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.current
+}
+
+// Reload re-reads the config file and notifies callbacks. On error the
+// current config is kept.
+func (w *Watcher) Reload() error {
+	updated, err := readConfig(w.path)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	old := w.current
+	w.current = updated
+	for _, fn := range w.callbacks {
+		fn(old, updated)
+	}
+	slog.Info("config reloaded", "path", w.path)
+	return nil
+}
+
+// Run reloads the config on SIGHUP and on writes to the config file until
+// ctx is cancelled. The file's directory is watched rather than the file
+// itself so that editors replacing the file on save are picked up.
+func (w *Watcher) Run(ctx context.Context) error {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer fsWatcher.Close()
+
+	if err := fsWatcher.Add(filepath.Dir(w.path)); err != nil {
+		return fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	reload := func() {
+		if err := w.Reload(); err != nil {
+			slog.Error("failed to reload config", "error", err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-hup:
+			reload()
+
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != w.path {
+				continue
+			}
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+				debounce.Reset(reloadDebounce)
+			}
+
+		case <-debounce.C:
+			reload()
+
+		case err, ok := <-fsWatcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error("config file watcher error", "error", err)
+		}
+	}
+}
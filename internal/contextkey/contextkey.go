@@ -0,0 +1,22 @@
+// Package contextkey holds the typed keys of request-scoped values that cross
+// package boundaries, such as the request ID set by the HTTP middleware and
+// read by logging, events and the repositories. Using unexported key types
+// keeps them from colliding with keys of other packages.
+package contextkey
+
+import "context"
+
+type key int
+
+const requestIDKey key = iota
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID ctx carries, or "" if none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
@@ -2,26 +2,134 @@ package database
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"time"
 
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-func NewMongoClient(uri string) (*mongo.Client, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Defaults for unset MongoOptions. The driver waits 30s for a server by
+// default, long enough to stall every request while the cluster is down.
+const (
+	DefaultMongoMaxPoolSize            = 100
+	DefaultMongoConnectTimeout         = 10 * time.Second
+	DefaultMongoServerSelectionTimeout = 5 * time.Second
+	DefaultMongoSocketTimeout          = 30 * time.Second
+)
+
+// MongoOptions tunes the MongoDB connection pool and timeouts. Zero values
+// leave the setting to the connection string, or else the defaults above.
+type MongoOptions struct {
+	// MaxPoolSize bounds the open connections per server
+	MaxPoolSize uint64
+	// MinPoolSize is the number of connections per server kept open while
+	// idle
+	MinPoolSize uint64
+	// MaxConnIdleTime closes connections idle for longer
+	MaxConnIdleTime        time.Duration
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	// SocketTimeout bounds each read or write on a connection
+	SocketTimeout time.Duration
+	// ReadPreference is primary, primaryPreferred, secondary,
+	// secondaryPreferred or nearest
+	ReadPreference string
+	// AllowUnreachable returns the client even when no server answers the
+	// ping, so the process can start during an outage; the driver connects
+	// once one is reachable
+	AllowUnreachable bool
+}
+
+// NewMongoClient connects to MongoDB and pings it, failing if no suitable
+// server answers within the connect and server selection timeouts unless
+// opts.AllowUnreachable is set
+func NewMongoClient(uri string, opts MongoOptions) (*mongo.Client, error) {
+	clientOptions := options.Client().
+		SetMaxPoolSize(DefaultMongoMaxPoolSize).
+		SetConnectTimeout(DefaultMongoConnectTimeout).
+		SetServerSelectionTimeout(DefaultMongoServerSelectionTimeout).
+		SetSocketTimeout(DefaultMongoSocketTimeout).
+		SetMonitor(commandMonitor()).
+		ApplyURI(uri)
+
+	if opts.MaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(opts.MaxPoolSize)
+	}
+	if opts.MinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(opts.MinPoolSize)
+	}
+	if opts.MaxConnIdleTime > 0 {
+		clientOptions.SetMaxConnIdleTime(opts.MaxConnIdleTime)
+	}
+	if opts.ConnectTimeout > 0 {
+		clientOptions.SetConnectTimeout(opts.ConnectTimeout)
+	}
+	if opts.ServerSelectionTimeout > 0 {
+		clientOptions.SetServerSelectionTimeout(opts.ServerSelectionTimeout)
+	}
+	if opts.SocketTimeout > 0 {
+		clientOptions.SetSocketTimeout(opts.SocketTimeout)
+	}
+	if opts.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(opts.ReadPreference)
+		if err != nil {
+			return nil, fmt.Errorf("invalid read preference %q: %w", opts.ReadPreference, err)
+		}
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid read preference %q: %w", opts.ReadPreference, err)
+		}
+		clientOptions.SetReadPreference(rp)
+	}
+	if err := clientOptions.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid MongoDB options: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *clientOptions.ConnectTimeout+*clientOptions.ServerSelectionTimeout)
 	defer cancel()
 
-	clientOptions := options.Client().ApplyURI(uri)
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := client.Ping(ctx, nil); err != nil {
-		return nil, err
+	// Ping with the configured read preference, so a client reading from
+	// secondaries starts without a reachable primary
+	if err := client.Ping(ctx, clientOptions.ReadPreference); err != nil {
+		if opts.AllowUnreachable {
+			slog.Warn("MongoDB is unreachable, starting without it", "error", err)
+			return client, nil
+		}
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
 
 	return client, nil
 }
 
+// commandMonitor logs the commands sent to MongoDB: failures as warnings and
+// successes at debug level. The driver passes the context of the operation,
+// so the records carry the ID of the request that issued the command.
+func commandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			slog.DebugContext(ctx, "mongo command succeeded",
+				"command", evt.CommandName,
+				"database", evt.DatabaseName,
+				"duration", evt.Duration,
+			)
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			slog.WarnContext(ctx, "mongo command failed",
+				"command", evt.CommandName,
+				"database", evt.DatabaseName,
+				"duration", evt.Duration,
+				"error", evt.Failure,
+			)
+		},
+	}
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// DefaultSQLitePath is the database file used when none is configured
+const DefaultSQLitePath = "target-engine.db"
+
+// NewSQLiteDB opens the SQLite database at path, creating the file if it
+// doesn't exist. ":memory:" opens a database that lives as long as the
+// process. Writes are serialized over a single connection, which SQLite
+// needs anyway, and also keeps an in-memory database shared.
+func NewSQLiteDB(path string) (*sql.DB, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if path == "" {
+		path = DefaultSQLitePath
+	}
+	if !strings.HasPrefix(path, "file:") {
+		path = "file:" + path
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	pragmas := url.Values{"_pragma": {"busy_timeout(5000)", "journal_mode(WAL)"}}
+	db, err := sql.Open("sqlite", path+separator+pragmas.Encode())
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,96 @@
+// Package errortracking captures the stack traces of recovered panics and
+// reports them, with the request they broke, to an error tracker such as
+// Sentry, so production panics are aggregated and can be alerted on
+package errortracking
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// maxFrames bounds the frames captured of a stack
+const maxFrames = 64
+
+// Event is a recovered panic
+type Event struct {
+	// Type is the Go type of the panic value and Message its text
+	Type    string
+	Message string
+	Stack   Stack
+	At      time.Time
+
+	RequestID string
+	TraceID   string
+	Method    string
+	// URL is the absolute URL of the request without its query
+	URL   string
+	Query string
+	Route string
+	// Headers are the request headers, without those carrying credentials
+	Headers map[string]string
+}
+
+// Reporter sends events to an error tracker. Report must not block the
+// request that panicked; implementations drop events rather than wait when
+// they fall behind.
+type Reporter interface {
+	Report(event Event)
+}
+
+// Frame is a function call of a stack
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// Stack is a goroutine's stack, innermost call first
+type Stack []Frame
+
+// String formats the stack like a goroutine trace, a function per line
+// followed by its file and line
+func (s Stack) String() string {
+	var b strings.Builder
+	for _, frame := range s {
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+	}
+	return b.String()
+}
+
+// PanicStack returns the stack of the panicking goroutine from the point the
+// panic was raised. It must be called while the deferred function that
+// recovered runs; its frames are skipped along with the runtime's.
+func PanicStack() Stack {
+	pcs := make([]uintptr, maxFrames)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack Stack
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			// Everything captured so far ran during the panic
+			stack = stack[:0]
+		} else {
+			stack = append(stack, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	// Runtime errors such as nil dereferences start below the faulting call
+	for len(stack) > 0 && strings.HasPrefix(stack[0].Function, "runtime.") {
+		stack = stack[1:]
+	}
+	return stack
+}
+
+// Panic describes a panic value as the type and message of an event
+func Panic(value any) (typ, message string) {
+	if err, ok := value.(error); ok {
+		return fmt.Sprintf("%T", err), err.Error()
+	}
+	return fmt.Sprintf("%T", value), fmt.Sprint(value)
+}
@@ -0,0 +1,263 @@
+package errortracking
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/buildinfo"
+	"github.com/Harshi-itaSinha/target-engine/internal/config"
+)
+
+const (
+	defaultSentryTimeout    = 5 * time.Second
+	defaultSentryBufferSize = 100
+	// modulePath marks the frames of this module as in-app, so Sentry groups
+	// panics by the code that raised them rather than by library frames
+	modulePath = "github.com/Harshi-itaSinha/target-engine"
+)
+
+// SentryReporter sends events to Sentry's envelope endpoint from a
+// background goroutine
+type SentryReporter struct {
+	endpoint    string
+	auth        string
+	environment string
+	release     string
+	serverName  string
+	client      *http.Client
+	events      chan Event
+	dropped     atomic.Int64
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewSentryReporter creates a reporter for the DSN in cfg and starts its
+// sending loop
+func NewSentryReporter(cfg config.ErrorTrackingConfig) (*SentryReporter, error) {
+	dsn, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	key := dsn.User.Username()
+	path := strings.TrimSuffix(dsn.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	if dsn.Host == "" || key == "" || slash < 0 || slash == len(path)-1 {
+		return nil, fmt.Errorf("invalid Sentry DSN: want <scheme>://<key>@<host>/<project>")
+	}
+	prefix, project := path[:slash], path[slash+1:]
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultSentryTimeout
+	}
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultSentryBufferSize
+	}
+	serverName, _ := os.Hostname()
+	release := buildinfo.Get()
+
+	r := &SentryReporter{
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/envelope/", dsn.Scheme, dsn.Host, prefix, project),
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_client=target-engine/%s, sentry_key=%s", release.Version, key),
+		environment: cfg.Environment,
+		release:     release.Version,
+		serverName:  serverName,
+		client:      &http.Client{Timeout: timeout},
+		events:      make(chan Event, bufferSize),
+		done:        make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+// Report queues an event, dropping it if the buffer is full
+func (r *SentryReporter) Report(event Event) {
+	select {
+	case r.events <- event:
+	default:
+		if dropped := r.dropped.Add(1); dropped%100 == 1 {
+			slog.Warn("dropping panic reports, error tracking buffer is full", "dropped_total", dropped)
+		}
+	}
+}
+
+// Close sends the queued events, giving up when ctx is done. Report must not
+// be called after Close.
+func (r *SentryReporter) Close(ctx context.Context) error {
+	r.closeOnce.Do(func() { close(r.events) })
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *SentryReporter) run() {
+	defer close(r.done)
+	for event := range r.events {
+		if err := r.send(event); err != nil {
+			slog.Error("failed to report panic to Sentry", "error", err)
+		}
+	}
+}
+
+// send posts an event to Sentry as an envelope holding one event item
+func (r *SentryReporter) send(event Event) error {
+	payload, err := json.Marshal(r.sentryEvent(event))
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	fmt.Fprintf(&body, `{"sent_at":%q}`+"\n", time.Now().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&body, `{"type":"event","length":%d}`+"\n", len(payload))
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry answered %s", resp.Status)
+	}
+	return nil
+}
+
+// sentryEvent is the subset of Sentry's event payload reports fill in
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Transaction string            `json:"transaction,omitempty"`
+	Exception   []sentryException `json:"exception"`
+	Request     *sentryRequest    `json:"request,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+type sentryException struct {
+	Type       string           `json:"type"`
+	Value      string           `json:"value"`
+	Mechanism  sentryMechanism  `json:"mechanism"`
+	Stacktrace sentryStacktrace `json:"stacktrace"`
+}
+
+type sentryMechanism struct {
+	Type    string `json:"type"`
+	Handled bool   `json:"handled"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type sentryRequest struct {
+	Method      string            `json:"method,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// sentryEvent converts an event to Sentry's payload
+func (r *SentryReporter) sentryEvent(event Event) sentryEvent {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	// Sentry lists frames outermost first
+	frames := make([]sentryFrame, 0, len(event.Stack))
+	for i := len(event.Stack) - 1; i >= 0; i-- {
+		frame := event.Stack[i]
+		module, function := splitFunction(frame.Function)
+		frames = append(frames, sentryFrame{
+			Function: function,
+			Module:   module,
+			AbsPath:  frame.File,
+			Lineno:   frame.Line,
+			InApp:    strings.HasPrefix(module, modulePath),
+		})
+	}
+
+	tags := make(map[string]string)
+	if event.RequestID != "" {
+		tags["request_id"] = event.RequestID
+	}
+	if event.TraceID != "" {
+		tags["trace_id"] = event.TraceID
+	}
+
+	payload := sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   event.At.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       "fatal",
+		Logger:      "target-engine",
+		ServerName:  r.serverName,
+		Release:     r.release,
+		Environment: r.environment,
+		Exception: []sentryException{{
+			Type:       event.Type,
+			Value:      event.Message,
+			Mechanism:  sentryMechanism{Type: "panic", Handled: false},
+			Stacktrace: sentryStacktrace{Frames: frames},
+		}},
+		Tags: tags,
+	}
+	if event.Route != "" {
+		payload.Transaction = event.Method + " " + event.Route
+	}
+	if event.Method != "" {
+		payload.Request = &sentryRequest{
+			Method:      event.Method,
+			URL:         event.URL,
+			QueryString: event.Query,
+			Headers:     event.Headers,
+		}
+	}
+	return payload
+}
+
+// splitFunction splits a qualified function name such as
+// github.com/org/repo/pkg.(*T).Method into its package path and the rest
+func splitFunction(name string) (module, function string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+2+dot:]
+}
@@ -0,0 +1,27 @@
+// Package events publishes delivery decisions for downstream analytics
+package events
+
+import "time"
+
+// DeliveryEvent records one delivery request and the campaigns served for it.
+// An empty CampaignIDs is a no-fill.
+type DeliveryEvent struct {
+	RequestID   string    `json:"request_id,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	App         string    `json:"app"`
+	Country     string    `json:"country"`
+	OS          string    `json:"os"`
+	DeviceType  string    `json:"device_type,omitempty"`
+	CampaignIDs []string  `json:"campaign_ids"`
+	// Creatives maps campaign IDs to the creative variant served, for
+	// campaigns that have any
+	Creatives map[string]string `json:"creatives,omitempty"`
+	LatencyMs float64           `json:"latency_ms"`
+}
+
+// Publisher sends delivery events. Publish must not block the delivery path;
+// implementations drop events rather than wait when they fall behind.
+type Publisher interface {
+	Publish(event DeliveryEvent)
+	Close() error
+}
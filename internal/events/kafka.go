@@ -0,0 +1,130 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/config"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultBatchTimeout = time.Second
+	defaultBufferSize   = 10000
+	// writeTimeout bounds a single batch write so a broker outage can't wedge the publisher
+	writeTimeout = 10 * time.Second
+)
+
+// KafkaPublisher buffers events in memory and writes them to a Kafka topic in
+// batches from a background goroutine. Events are keyed by app so a
+// consumer partition sees all events for an app in order.
+type KafkaPublisher struct {
+	writer       *kafka.Writer
+	events       chan DeliveryEvent
+	batchSize    int
+	batchTimeout time.Duration
+	dropped      atomic.Int64
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// NewKafkaPublisher creates a publisher for cfg and starts its batching loop
+func NewKafkaPublisher(cfg config.EventsConfig) *KafkaPublisher {
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	batchTimeout := cfg.BatchTimeout
+	if batchTimeout <= 0 {
+		batchTimeout = defaultBatchTimeout
+	}
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	p := &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Topic:        cfg.Topic,
+			Balancer:     &kafka.Hash{},
+			BatchSize:    batchSize,
+			// Batches are assembled by run; don't hold partial per-partition batches back
+			BatchTimeout: 10 * time.Millisecond,
+			RequiredAcks: kafka.RequireOne,
+		},
+		events:       make(chan DeliveryEvent, bufferSize),
+		batchSize:    batchSize,
+		batchTimeout: batchTimeout,
+		done:         make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Publish queues an event, dropping it if the buffer is full
+func (p *KafkaPublisher) Publish(event DeliveryEvent) {
+	select {
+	case p.events <- event:
+	default:
+		if dropped := p.dropped.Add(1); dropped%1000 == 1 {
+			slog.Warn("dropping delivery events, publisher buffer is full", "dropped_total", dropped)
+		}
+	}
+}
+
+// Close flushes queued events and closes the Kafka writer. Publish must not
+// be called after Close.
+func (p *KafkaPublisher) Close() error {
+	p.closeOnce.Do(func() { close(p.events) })
+	<-p.done
+	return p.writer.Close()
+}
+
+// run collects events into batches, writing one whenever it is full or the
+// batch timeout passes
+func (p *KafkaPublisher) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.batchTimeout)
+	defer ticker.Stop()
+
+	batch := make([]kafka.Message, 0, p.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+		defer cancel()
+		if err := p.writer.WriteMessages(ctx, batch...); err != nil {
+			slog.Error("failed to publish delivery events", "count", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case event, ok := <-p.events:
+			if !ok {
+				flush()
+				return
+			}
+			value, err := json.Marshal(event)
+			if err != nil {
+				slog.Error("failed to encode delivery event", "error", err)
+				continue
+			}
+			batch = append(batch, kafka.Message{Key: []byte(event.App), Value: value, Time: event.Timestamp})
+			if len(batch) >= p.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// ErrInvalidIP is returned for addresses that can't be parsed
+var ErrInvalidIP = errors.New("invalid IP address")
+
+// Resolver resolves client IP addresses to countries using a MaxMind
+// GeoIP2 or GeoLite2 database. Both the Country and City editions work.
+type Resolver struct {
+	db *geoip2.Reader
+}
+
+// Open loads the mmdb database at path
+func Open(path string) (*Resolver, error) {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	return &Resolver{db: db}, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 country code of ip, or an empty
+// string when the database has no country for it, as for private addresses
+func (r *Resolver) Country(ip string) (string, error) {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidIP, ip)
+	}
+
+	record, err := r.db.Country(parsed)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up country: %w", err)
+	}
+	return record.Country.IsoCode, nil
+}
+
+// Close releases the database
+func (r *Resolver) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/service"
+	"github.com/Harshi-itaSinha/target-engine/pkg/response"
+)
+
+// GetDecisions handles GET /v1/decisions requests, returning the latest
+// logged delivery decisions of the tenant, newest first. The request_id
+// query parameter looks up the decisions of one request, such as the
+// X-Request-ID quoted in a support ticket, and limit bounds how many are
+// returned.
+func (h *DeliveryHandler) GetDecisions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var limit int
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil {
+			response.BadRequest(w, "invalid limit")
+			return
+		}
+	}
+
+	decisions, err := h.targetingService.Decisions(r.Context(), query.Get("request_id"), limit)
+	if errors.Is(err, service.ErrDecisionLogDisabled) {
+		response.NotFound(w, err.Error())
+		return
+	}
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+	response.Success(w, decisions)
+}
@@ -1,48 +1,555 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
-    "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/middleware"
+	"github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/recording"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
 	"github.com/Harshi-itaSinha/target-engine/internal/service"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
 	"github.com/Harshi-itaSinha/target-engine/pkg/response"
+	"github.com/gorilla/mux"
 )
 
 // DeliveryHandler handles delivery endpoint requests
 type DeliveryHandler struct {
-	targetingService *service.TargetingService
+	targetingService service.Targeting
+	geo              CountryResolver
+	// heartbeat is how often idle change streams send a comment
+	heartbeat time.Duration
+	// cacheControl and vary are the caching headers of delivery responses
+	cacheControl string
+	vary         string
+	// trackingTokens verifies the tokens of tracking events
+	trackingTokens *service.TrackingTokens
+}
+
+// CountryResolver resolves a client IP address to an ISO country code
+type CountryResolver interface {
+	Country(ip string) (string, error)
 }
 
-// NewDeliveryHandler creates a new delivery handler
-func NewDeliveryHandler(targetingService *service.TargetingService) *DeliveryHandler {
+// NewDeliveryHandler creates a new delivery handler. geo may be nil to
+// require the country on every delivery request.
+func NewDeliveryHandler(targetingService service.Targeting, geo CountryResolver) *DeliveryHandler {
 	return &DeliveryHandler{
 		targetingService: targetingService,
+		geo:              geo,
 	}
 }
 
-func (h *DeliveryHandler) CreateCampaign (w http.ResponseWriter, r *http.Request) {
+// Headers for idempotent create requests. A retry replaying the stored
+// result is marked with the replayed header.
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// cacheStaleHeader marks delivery responses served from a targeting cache
+// that is failing to refresh
+const cacheStaleHeader = "X-Cache-Stale"
 
+// CreateCampaign handles POST /v1/campaign requests. Retries carrying the
+// same Idempotency-Key header get the campaign created the first time.
+func (h *DeliveryHandler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	var req model.CampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	campaign, replayed, err := h.targetingService.CreateCampaignIdempotent(r.Context(), r.Header.Get(idempotencyKeyHeader), &req)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	writeCreated(w, campaign, replayed)
 }
 
-func (h *DeliveryHandler) CreateTargetingRule(w http.ResponseWriter, r *http.Request){
-	
+// writeCreated writes the result of an idempotent create request
+func writeCreated(w http.ResponseWriter, created any, replayed bool) {
+	if replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+	}
+	response.Created(w, created)
 }
 
-// GetCampaigns handles GET /v1/delivery requests
-func (h *DeliveryHandler) GetCampaigns(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
+// ListCampaigns handles GET /v1/campaigns requests. It accepts status,
+// include_archived, created_after (RFC 3339), search, limit and offset query
+// parameters.
+func (h *DeliveryHandler) ListCampaigns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := campaignFilterFromQuery(query)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	for name, dst := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				response.BadRequest(w, "invalid "+name)
+				return
+			}
+			*dst = n
+		}
+	}
+
+	campaigns, err := h.targetingService.ListCampaigns(r.Context(), filter)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, campaigns)
+}
+
+// SearchCampaigns handles GET /v1/campaigns/search requests. It accepts q,
+// include_archived, limit and offset query parameters.
+func (h *DeliveryHandler) SearchCampaigns(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
+	search := repository.CampaignSearch{Query: query.Get("q")}
+	if includeArchived := query.Get("include_archived"); includeArchived != "" {
+		include, err := strconv.ParseBool(includeArchived)
+		if err != nil {
+			response.BadRequest(w, "invalid include_archived")
+			return
+		}
+		search.IncludeArchived = include
+	}
+	for name, dst := range map[string]*int{"limit": &search.Limit, "offset": &search.Offset} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				response.BadRequest(w, "invalid "+name)
+				return
+			}
+			*dst = n
+		}
+	}
+
+	result, err := h.targetingService.SearchCampaigns(r.Context(), search)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, result)
+}
+
+// campaignFilterFromQuery parses the status, search, created_after and
+// include_archived query parameters shared by campaign listing and export
+func campaignFilterFromQuery(query url.Values) (repository.CampaignFilter, error) {
+	filter := repository.CampaignFilter{
+		Status: query.Get("status"),
+		Search: query.Get("search"),
+	}
+
+	if createdAfter := query.Get("created_after"); createdAfter != "" {
+		t, err := time.Parse(time.RFC3339, createdAfter)
+		if err != nil {
+			return filter, errors.New("invalid created_after, expected RFC 3339 time")
+		}
+		filter.CreatedAfter = t
+	}
+	if includeArchived := query.Get("include_archived"); includeArchived != "" {
+		include, err := strconv.ParseBool(includeArchived)
+		if err != nil {
+			return filter, errors.New("invalid include_archived")
+		}
+		filter.IncludeArchived = include
+	}
+	return filter, nil
+}
+
+// BulkCreateCampaigns handles POST /v1/campaigns/bulk requests
+func (h *DeliveryHandler) BulkCreateCampaigns(w http.ResponseWriter, r *http.Request) {
+	var items []*model.BulkCampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	results, err := h.targetingService.BulkCreateCampaigns(r.Context(), items)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, results)
+}
+
+// UpdateCampaign handles PUT /v1/campaign/{id} requests
+func (h *DeliveryHandler) UpdateCampaign(w http.ResponseWriter, r *http.Request) {
+	var req model.CampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	campaign, err := h.targetingService.UpdateCampaign(r.Context(), mux.Vars(r)["id"], &req)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, campaign)
+}
+
+// UpdateCampaignStatus handles PUT /v1/campaign/{id}/status requests, moving
+// a campaign to the status in the body along its lifecycle
+func (h *DeliveryHandler) UpdateCampaignStatus(w http.ResponseWriter, r *http.Request) {
+	var req model.CampaignStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	campaign, err := h.targetingService.UpdateCampaignStatus(r.Context(), mux.Vars(r)["id"], req.Status)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, campaign)
+}
+
+// CloneCampaign handles POST /v1/campaign/{id}/clone requests. The optional
+// body holds campaign fields overriding those of the copy.
+func (h *DeliveryHandler) CloneCampaign(w http.ResponseWriter, r *http.Request) {
+	var req model.CampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	clone, replayed, err := h.targetingService.CloneCampaignIdempotent(r.Context(), r.Header.Get(idempotencyKeyHeader), mux.Vars(r)["id"], &req)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	writeCreated(w, clone, replayed)
+}
+
+// DeleteCampaign handles DELETE /v1/campaign/{id} requests. Campaigns are
+// archived unless the hard query parameter is true.
+func (h *DeliveryHandler) DeleteCampaign(w http.ResponseWriter, r *http.Request) {
+	var hard bool
+	if value := r.URL.Query().Get("hard"); value != "" {
+		var err error
+		if hard, err = strconv.ParseBool(value); err != nil {
+			response.BadRequest(w, "invalid hard")
+			return
+		}
+	}
+
+	if err := h.targetingService.DeleteCampaign(r.Context(), mux.Vars(r)["id"], hard); err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// GetCampaignView handles GET /v1/campaign/{id}/full requests, returning a
+// campaign along with its targeting rules, serving status, flight, recent
+// serve counts and latest changes, as admin UIs show them
+func (h *DeliveryHandler) GetCampaignView(w http.ResponseWriter, r *http.Request) {
+	view, err := h.targetingService.CampaignView(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+	response.Success(w, view)
+}
+
+// writeCampaignError maps campaign service errors to HTTP responses
+func writeCampaignError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCampaign):
+		response.UnprocessableFields(w, err.Error(), validation.Fields(err))
+	case errors.Is(err, service.ErrInvalidRule), errors.Is(err, service.ErrInvalidFilter), errors.Is(err, service.ErrInvalidKillSwitch):
+		response.InvalidFields(w, err.Error(), validation.Fields(err))
+	case errors.Is(err, service.ErrInvalidTransition):
+		response.Conflict(w, err.Error())
+	case errors.Is(err, service.ErrRuleConflict):
+		response.RuleConflicts(w, err.Error(), service.RuleConflicts(err))
+	case errors.Is(err, service.ErrInvalidIdempotencyKey):
+		response.BadRequest(w, err.Error())
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		response.UnprocessableEntity(w, err.Error())
+	case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+		response.Conflict(w, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		response.NotFound(w, err.Error())
+	case errors.Is(err, repository.ErrAlreadyExists):
+		response.Conflict(w, err.Error())
+	default:
+		response.InternalServerError(w, err.Error())
+	}
+}
+
+// ruleResponse is a stored targeting rule along with the conflicts it has
+// with the other rules of its campaign
+type ruleResponse struct {
+	*model.TargetingRule
+	Conflicts []model.RuleConflict `json:"conflicts,omitempty"`
+}
+
+// withConflicts analyzes a stored rule for the response. The write has
+// already succeeded, so a failed analysis is logged and left out.
+func (h *DeliveryHandler) withConflicts(ctx context.Context, rule *model.TargetingRule) *ruleResponse {
+	analysis, err := h.targetingService.AnalyzeTargetingRule(ctx, rule)
+	if err != nil {
+		slog.Error("failed to analyze targeting rule", "rule_id", rule.ID, "error", err)
+		return &ruleResponse{TargetingRule: rule}
+	}
+	return &ruleResponse{TargetingRule: rule, Conflicts: analysis.Conflicts}
+}
+
+// CreateTargetingRule handles POST /v1/target requests, which are idempotent
+// like CreateCampaign. Rules that can never match are rejected, and the
+// response lists the rule's conflicts with the campaign's other rules.
+func (h *DeliveryHandler) CreateTargetingRule(w http.ResponseWriter, r *http.Request) {
+	var rule model.TargetingRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	created, replayed, err := h.targetingService.CreateTargetingRuleIdempotent(r.Context(), r.Header.Get(idempotencyKeyHeader), &rule)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	writeCreated(w, h.withConflicts(r.Context(), created), replayed)
+}
+
+// AnalyzeTargetingRule handles POST /v1/target/analyze requests, reporting
+// the conflicts a rule would have without storing it. A rule with an id is
+// analyzed as a replacement of that rule.
+func (h *DeliveryHandler) AnalyzeTargetingRule(w http.ResponseWriter, r *http.Request) {
+	var rule model.TargetingRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	analysis, err := h.targetingService.AnalyzeTargetingRule(r.Context(), &rule)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, analysis)
+}
+
+// EstimateReach handles POST /v1/target/estimate requests, estimating how
+// many recent delivery requests a proposed set of rules would match
+func (h *DeliveryHandler) EstimateReach(w http.ResponseWriter, r *http.Request) {
+	var req model.ReachRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	estimate, err := h.targetingService.EstimateReach(r.Context(), &req)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, estimate)
+}
+
+// ListTargetingRules handles GET /v1/target?campaign_id= requests
+func (h *DeliveryHandler) ListTargetingRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.targetingService.ListTargetingRules(r.Context(), r.URL.Query().Get("campaign_id"))
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, rules)
+}
+
+// UpdateTargetingRule handles PUT /v1/target/{id} requests, checked for
+// conflicts like CreateTargetingRule
+func (h *DeliveryHandler) UpdateTargetingRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid targeting rule id")
+		return
+	}
+
+	var rule model.TargetingRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	updated, err := h.targetingService.UpdateTargetingRule(r.Context(), id, &rule)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, h.withConflicts(r.Context(), updated))
+}
+
+// DeleteTargetingRule handles DELETE /v1/target/{id} requests
+func (h *DeliveryHandler) DeleteTargetingRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		response.BadRequest(w, "invalid targeting rule id")
+		return
+	}
+
+	if err := h.targetingService.DeleteTargetingRule(r.Context(), id); err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// deliveryQueryParams are the query parameters with a fixed meaning on the
+// delivery endpoints besides the delivery dimensions; any others are passed
+// on as custom dimensions
+var deliveryQueryParams = map[string]bool{
+	"user_id": true, "app_version": true, "limit": true, "at": true, "campaign_id": true,
+	"strategy": true, "fields": true, "categories": true,
+}
+
+// deliveryRequestFromQuery parses the delivery request query parameters.
+// The parameters of the delivery dimensions, such as country, may be
+// repeated, as in country=us&country=ca, for requests with several values.
+func deliveryRequestFromQuery(query url.Values) (*model.DeliveryRequest, error) {
 	req := &model.DeliveryRequest{
-		App:     query.Get("app"),
-		Country: query.Get("country"),
-		OS:      query.Get("os"),
+		UserID:     query.Get("user_id"),
+		AppVersion: query.Get("app_version"),
+	}
+	for _, name := range model.DeliveryDimensions {
+		req.SetValues(name, query[name])
+	}
+	if categories := query.Get("categories"); categories != "" {
+		req.Categories = strings.Split(categories, ",")
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, errors.New("invalid limit")
+		}
+		req.Limit = n
+	}
+	for name, values := range query {
+		if deliveryQueryParams[name] || slices.Contains(model.DeliveryDimensions, name) || len(values) == 0 {
+			continue
+		}
+		if req.Custom == nil {
+			req.Custom = make(map[string]string)
+		}
+		req.Custom[name] = values[0]
+	}
+	return req, nil
+}
+
+// GetCampaigns handles GET /v1/delivery requests, and HEAD requests, which
+// are answered with the same status and headers but no body
+func (h *DeliveryHandler) GetCampaigns(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	req, err := deliveryRequestFromQuery(r.URL.Query())
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	h.deliver(w, r, req)
+}
+
+// PostCampaigns handles POST /v1/delivery requests carrying a JSON
+// DeliveryRequest body, for clients that can't easily build query strings
+func (h *DeliveryHandler) PostCampaigns(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		response.UnsupportedMediaType(w, "request body must be application/json")
+		return
+	}
+
+	var req model.DeliveryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+
+	h.deliver(w, r, &req)
+}
+
+// BatchDelivery handles POST /v1/delivery/batch requests, serving several
+// delivery requests in one call. Every request gets a result, so a failed one
+// doesn't fail the batch.
+func (h *DeliveryHandler) BatchDelivery(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		response.UnsupportedMediaType(w, "request body must be application/json")
+		return
+	}
+
+	var batch model.BatchDeliveryRequest
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		response.BadRequest(w, "invalid request body")
+		return
+	}
+	for _, req := range batch.Requests {
+		if req != nil {
+			h.resolveCountry(r, req)
+		}
 	}
 
+	results, err := h.targetingService.GetMatchingCampaignsBatch(r.Context(), &batch)
+	if err != nil {
+		writeDeliveryError(w, r, err)
+		return
+	}
+	h.markStale(w)
+
+	response.Success(w, &model.BatchDeliveryResponse{Results: results})
+}
+
+// deliver serves the campaigns matching req for both forms of /v1/delivery
+func (h *DeliveryHandler) deliver(w http.ResponseWriter, r *http.Request, req *model.DeliveryRequest) {
+	// Let bandwidth-constrained clients ask for only the fields they use
+	fields, err := response.ParseFields(r.URL.Query().Get("fields"), model.DeliveryResponse{})
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	h.resolveCountry(r, req)
+	recording.Capture(r.Context(), req)
+
 	// Get matching campaigns from service
 	campaigns, err := h.targetingService.GetMatchingCampaigns(r.Context(), req)
 	if err != nil {
-		response.BadRequest(w, err.Error())
+		writeDeliveryError(w, r, err)
 		return
 	}
+	h.markStale(w)
+	h.setDeliveryCaching(w, r, req, campaigns)
 
 	// Return appropriate response
 	if len(campaigns) == 0 {
@@ -50,22 +557,194 @@ func (h *DeliveryHandler) GetCampaigns(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response.Success(w, campaigns)
+	// Let clients polling with the same parameters skip an unchanged body.
+	// Tracking tokens and click URLs with per-request macros are only valid
+	// for this request, so those responses are never revalidated.
+	if !slices.ContainsFunc(campaigns, service.ServedPerRequest) {
+		etag := campaignsETag(campaigns, fields)
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			response.NotModified(w)
+			return
+		}
+	}
+
+	body, err := response.Project(campaigns, fields)
+	if err != nil {
+		response.InternalServerError(w, "failed to project response fields")
+		return
+	}
+	response.Success(w, body)
+}
+
+// SelectCampaign handles GET /v1/delivery/select requests, serving the single
+// campaign picked by the strategy query parameter, or the configured default
+// strategy, from the campaigns matching the delivery parameters
+func (h *DeliveryHandler) SelectCampaign(w http.ResponseWriter, r *http.Request) {
+	req, err := deliveryRequestFromQuery(r.URL.Query())
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	h.resolveCountry(r, req)
+
+	campaign, err := h.targetingService.SelectCampaign(r.Context(), req, r.URL.Query().Get("strategy"))
+	if err != nil {
+		writeDeliveryError(w, r, err)
+		return
+	}
+	h.markStale(w)
+	if campaign == nil {
+		response.NoContent(w)
+		return
+	}
+
+	response.Success(w, campaign)
 }
 
-// GetStats handles GET /v1/stats requests for monitoring
+// writeDeliveryError writes the response to a failed delivery request: 400
+// listing the invalid fields of a request that failed validation, 503 once
+// the targeting cache is too stale to serve, and 500 otherwise. Other
+// failures come from the repository or the service, so they are logged
+// rather than returned.
+func writeDeliveryError(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid *validation.Error
+	switch {
+	case errors.As(err, &invalid):
+		response.InvalidFields(w, err.Error(), invalid.Fields)
+	case errors.Is(err, service.ErrCacheStale):
+		response.ServiceUnavailable(w, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "delivery failed", "error", err)
+		response.InternalServerError(w, "failed to match campaigns")
+	}
+}
+
+// markStale sets X-Cache-Stale on a delivery response served from a cache
+// that is failing to refresh
+func (h *DeliveryHandler) markStale(w http.ResponseWriter) {
+	if h.targetingService.ServingStale() {
+		w.Header().Set(cacheStaleHeader, "true")
+	}
+}
+
+// resolveCountry fills in a missing country from the client IP when a geo
+// resolver is configured. Lookup failures leave it empty so validation
+// reports the missing country.
+func (h *DeliveryHandler) resolveCountry(r *http.Request, req *model.DeliveryRequest) {
+	if h.geo == nil || strings.TrimSpace(req.Country) != "" {
+		return
+	}
+
+	ip := middleware.ClientIP(r)
+	country, err := h.geo.Country(ip)
+	if err != nil {
+		slog.Debug("failed to resolve country from client IP", "ip", ip, "error", err)
+		return
+	}
+	req.Country = country
+}
+
+// ExplainDelivery handles GET /v1/delivery/explain requests. It takes the
+// /v1/delivery parameters plus an optional campaign_id and an optional at
+// time (RFC 3339) to evaluate rule schedules at.
+func (h *DeliveryHandler) ExplainDelivery(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req, err := deliveryRequestFromQuery(query)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	var at time.Time
+	if value := query.Get("at"); value != "" {
+		if at, err = time.Parse(time.RFC3339, value); err != nil {
+			response.BadRequest(w, "invalid at, expected RFC 3339 time")
+			return
+		}
+	}
+
+	explanations, err := h.targetingService.ExplainDelivery(r.Context(), req, query.Get("campaign_id"), at)
+	if err != nil {
+		if fields := validation.Fields(err); fields != nil {
+			response.InvalidFields(w, err.Error(), fields)
+			return
+		}
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, explanations)
+}
+
+// GetStats handles GET /v1/stats requests for monitoring, combining cache
+// statistics with a breakdown of recent traffic
 func (h *DeliveryHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 	stats := h.targetingService.GetCacheStats()
+	stats["traffic"] = h.targetingService.TrafficStats()
 	response.Success(w, stats)
 }
 
-// Health handles GET /health requests
-func (h *DeliveryHandler) Health(w http.ResponseWriter, r *http.Request) {
-	healthStatus := map[string]interface{}{
+// GetCampaignStats handles GET /v1/stats/campaigns requests, returning how
+// often campaigns were served from the from day through the to day
+// (YYYYMMDD, both today by default), for every campaign or the one named by
+// campaign_id
+func (h *DeliveryHandler) GetCampaignStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	report, err := h.targetingService.CampaignStats(r.Context(), query.Get("campaign_id"), query.Get("from"), query.Get("to"))
+	if errors.Is(err, service.ErrCampaignStatsDisabled) {
+		response.NotFound(w, err.Error())
+		return
+	}
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+	response.Success(w, report)
+}
+
+// RefreshCache handles POST /v1/admin/cache/refresh requests, reloading the
+// targeting cache without waiting for the next scheduled refresh
+func (h *DeliveryHandler) RefreshCache(w http.ResponseWriter, r *http.Request) {
+	result, err := h.targetingService.RefreshCache()
+	if err != nil {
+		slog.Error("manual cache refresh failed", "error", err)
+		response.InternalServerError(w, err.Error())
+		return
+	}
+	response.Success(w, result)
+}
+
+// Live handles GET /healthz liveness probes. It doesn't touch any
+// dependency, so a slow database never gets the process restarted.
+func (h *DeliveryHandler) Live(w http.ResponseWriter, r *http.Request) {
+	response.Success(w, map[string]interface{}{
 		"status":    "ok",
-		"service":   "targeting-engine",
-		"version":   "1.0.0",
-		"timestamp": "2025-01-31T00:00:00Z",
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// Health handles GET /health requests, reporting the state of the process
+// and its dependencies. It always responds 200 so existing probes of /health
+// keep passing; the status field reports degradation.
+func (h *DeliveryHandler) Health(w http.ResponseWriter, r *http.Request) {
+	response.Success(w, h.targetingService.Health(r.Context()))
+}
+
+// Ready handles GET /readyz requests. It responds 503 with the failing
+// checks until the repository is reachable and the cache has been loaded.
+func (h *DeliveryHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	checks, ready := h.targetingService.CheckReadiness(r.Context())
+	if !ready {
+		response.JSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status": "not ready",
+			"checks": checks,
+		})
+		return
 	}
-	response.Success(w, healthStatus)
+
+	response.Success(w, map[string]interface{}{
+		"status": "ready",
+		"checks": checks,
+	})
 }
\ No newline at end of file
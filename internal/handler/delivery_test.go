@@ -0,0 +1,373 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/handler"
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/service"
+	"github.com/Harshi-itaSinha/target-engine/internal/service/mocks"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// servedCampaign returns the delivery response of a campaign last updated at
+// updatedAt
+func servedCampaign(id string, updatedAt time.Time) *model.DeliveryResponse {
+	return (&model.Campaign{
+		ID:        id,
+		Image:     "https://cdn.example.com/" + id + ".png",
+		CTA:       "Install",
+		UpdatedAt: updatedAt,
+	}).ToDeliveryResponse()
+}
+
+// newDeliveryHandler returns a handler over a mock targeting service
+func newDeliveryHandler(t *testing.T) (*handler.DeliveryHandler, *mocks.MockTargeting) {
+	targeting := mocks.NewMockTargeting(gomock.NewController(t))
+	return handler.NewDeliveryHandler(targeting, nil), targeting
+}
+
+// serve runs one request against fn and returns the recorded response
+func serve(fn http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	fn.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDeliverOK(t *testing.T) {
+	h, targeting := newDeliveryHandler(t)
+	h.SetDeliveryCaching(time.Minute, 0)
+	updated := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	targeting.EXPECT().GetMatchingCampaigns(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ any, req *model.DeliveryRequest) ([]*model.DeliveryResponse, error) {
+			assert.Equal(t, "com.example.app", req.App)
+			assert.Equal(t, []string{"us", "ca"}, req.Values("country"))
+			assert.Equal(t, "android", req.OS)
+			assert.Equal(t, 2, req.Limit)
+			assert.Equal(t, map[string]string{"tier": "gold"}, req.Custom)
+			return []*model.DeliveryResponse{servedCampaign("spotify", updated), servedCampaign("duolingo", updated)}, nil
+		})
+	targeting.EXPECT().ServingStale().Return(false)
+
+	rec := serve(h.GetCampaigns, httptest.NewRequest(http.MethodGet,
+		"/v1/delivery?app=com.example.app&country=us&country=ca&os=android&limit=2&tier=gold", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[
+		{"cid": "spotify", "img": "https://cdn.example.com/spotify.png", "cta": "Install"},
+		{"cid": "duolingo", "img": "https://cdn.example.com/duolingo.png", "cta": "Install"}
+	]`, rec.Body.String())
+	assert.NotEmpty(t, rec.Header().Get("ETag"))
+	assert.Equal(t, "public, max-age=60", rec.Header().Get("Cache-Control"))
+	assert.Empty(t, rec.Header().Get("X-Cache-Stale"))
+}
+
+func TestDeliverPostOK(t *testing.T) {
+	h, targeting := newDeliveryHandler(t)
+	targeting.EXPECT().GetMatchingCampaigns(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ any, req *model.DeliveryRequest) ([]*model.DeliveryResponse, error) {
+			assert.Equal(t, []string{"DE", "AT"}, req.Values("country"))
+			return []*model.DeliveryResponse{servedCampaign("spotify", time.Time{})}, nil
+		})
+	targeting.EXPECT().ServingStale().Return(true)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/delivery?fields=cid",
+		strings.NewReader(`{"app": "com.example.app", "country": "DE", "countries": ["AT"], "os": "ios"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := serve(h.PostCampaigns, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"cid": "spotify"}]`, rec.Body.String())
+	assert.Equal(t, "true", rec.Header().Get("X-Cache-Stale"))
+	assert.Empty(t, rec.Header().Get("Cache-Control"), "POST responses aren't cacheable")
+}
+
+func TestDeliverNoContent(t *testing.T) {
+	h, targeting := newDeliveryHandler(t)
+	targeting.EXPECT().GetMatchingCampaigns(gomock.Any(), gomock.Any()).Return(nil, nil)
+	targeting.EXPECT().ServingStale().Return(false)
+
+	rec := serve(h.GetCampaigns, httptest.NewRequest(http.MethodGet, "/v1/delivery?app=a&country=us&os=ios", nil))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("ETag"))
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+}
+
+func TestDeliverBadRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		wantStatus  int
+		wantMessage string
+	}{
+		{
+			name:        "invalid limit",
+			method:      http.MethodGet,
+			target:      "/v1/delivery?app=a&country=us&os=ios&limit=ten",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "invalid limit",
+		},
+		{
+			name:        "unknown field",
+			method:      http.MethodGet,
+			target:      "/v1/delivery?app=a&country=us&os=ios&fields=cid,budget",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "budget",
+		},
+		{
+			name:        "invalid body",
+			method:      http.MethodPost,
+			target:      "/v1/delivery",
+			contentType: "application/json",
+			body:        `{"app": `,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "invalid request body",
+		},
+		{
+			name:        "body not json",
+			method:      http.MethodPost,
+			target:      "/v1/delivery",
+			contentType: "text/plain",
+			body:        `app=a`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantMessage: "application/json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The request is rejected before the service is called, which
+			// the mock would report as an unexpected call
+			h, _ := newDeliveryHandler(t)
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			fn := h.GetCampaigns
+			if tt.method == http.MethodPost {
+				req.Header.Set("Content-Type", tt.contentType)
+				fn = h.PostCampaigns
+			}
+			rec := serve(fn, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			var resp model.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Contains(t, resp.Message, tt.wantMessage)
+		})
+	}
+}
+
+func TestDeliverErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string
+		wantFields  []model.FieldError
+	}{
+		{
+			name:        "invalid request",
+			err:         validation.Invalid("os", "must be one of android ios"),
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "must be one of android ios",
+			wantFields:  []model.FieldError{{Field: "os", Reason: "must be one of android ios"}},
+		},
+		{
+			name:        "stale cache",
+			err:         fmt.Errorf("failed to find matching campaigns: %w", service.ErrCacheStale),
+			wantStatus:  http.StatusServiceUnavailable,
+			wantMessage: service.ErrCacheStale.Error(),
+		},
+		{
+			name:        "other failure",
+			err:         fmt.Errorf("failed to find matching campaigns: %w", errors.New("mongo: connection refused to 10.0.0.7:27017")),
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: "failed to match campaigns",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, targeting := newDeliveryHandler(t)
+			targeting.EXPECT().GetMatchingCampaigns(gomock.Any(), gomock.Any()).Return(nil, tt.err)
+
+			rec := serve(h.GetCampaigns, httptest.NewRequest(http.MethodGet, "/v1/delivery?app=a&country=us&os=windows", nil))
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			var resp model.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			assert.Contains(t, resp.Message, tt.wantMessage)
+			assert.NotContains(t, rec.Body.String(), "mongo", "internal errors must not reach the caller")
+			assert.Equal(t, tt.wantFields, resp.Fields)
+			assert.Empty(t, rec.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestDeliverNotModified(t *testing.T) {
+	updated := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	const target = "/v1/delivery?app=a&country=us&os=ios"
+
+	first := func() []*model.DeliveryResponse {
+		return []*model.DeliveryResponse{servedCampaign("a", updated), servedCampaign("b", updated)}
+	}
+	h, targeting := newDeliveryHandler(t)
+	targeting.EXPECT().GetMatchingCampaigns(gomock.Any(), gomock.Any()).Return(first(), nil)
+	targeting.EXPECT().ServingStale().Return(false).AnyTimes()
+	rec := serve(h.GetCampaigns, httptest.NewRequest(http.MethodGet, target, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.True(t, strings.HasPrefix(etag, "W/"), "the tag covers the campaigns, not the bytes: %s", etag)
+
+	// The same campaigns in another order, with another creative, are
+	// equivalent
+	rotated := servedCampaign("b", updated)
+	rotated.Image, rotated.Creative = "https://cdn.example.com/b2.png", "b2"
+
+	tests := []struct {
+		name        string
+		campaigns   []*model.DeliveryResponse
+		ifNoneMatch string
+		wantStatus  int
+		wantNewTag  bool
+	}{
+		{name: "same campaigns", campaigns: first(), ifNoneMatch: etag, wantStatus: http.StatusNotModified},
+		{name: "strong and listed tags", campaigns: first(), ifNoneMatch: `"other", ` + strings.TrimPrefix(etag, "W/"), wantStatus: http.StatusNotModified},
+		{name: "reordered and rotated", campaigns: []*model.DeliveryResponse{rotated, servedCampaign("a", updated)}, ifNoneMatch: etag, wantStatus: http.StatusNotModified},
+		{name: "campaign updated", campaigns: []*model.DeliveryResponse{servedCampaign("a", updated), servedCampaign("b", updated.Add(time.Second))}, ifNoneMatch: etag, wantStatus: http.StatusOK, wantNewTag: true},
+		{name: "campaign dropped", campaigns: []*model.DeliveryResponse{servedCampaign("a", updated)}, ifNoneMatch: etag, wantStatus: http.StatusOK, wantNewTag: true},
+		{name: "other tag", campaigns: first(), ifNoneMatch: `"other"`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targeting.EXPECT().GetMatchingCampaigns(gomock.Any(), gomock.Any()).Return(tt.campaigns, nil)
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			rec := serve(h.GetCampaigns, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantNewTag {
+				assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+			} else {
+				assert.Equal(t, etag, rec.Header().Get("ETag"))
+			}
+			if tt.wantStatus == http.StatusNotModified {
+				assert.Empty(t, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeliverPerRequestClickURLNotRevalidated(t *testing.T) {
+	perRequest := (&model.Campaign{ID: "tracked", ClickURL: "https://t.example.com/c?rid={REQUEST_ID}"}).ToDeliveryResponse()
+	h, targeting := newDeliveryHandler(t)
+	targeting.EXPECT().GetMatchingCampaigns(gomock.Any(), gomock.Any()).Return([]*model.DeliveryResponse{servedCampaign("a", time.Time{}), perRequest}, nil).Times(2)
+	targeting.EXPECT().ServingStale().Return(false).Times(2)
+
+	rec := serve(h.GetCampaigns, httptest.NewRequest(http.MethodGet, "/v1/delivery?app=a&country=us&os=ios", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("ETag"))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/delivery?app=a&country=us&os=ios", nil)
+	req.Header.Set("If-None-Match", "*")
+	rec = serve(h.GetCampaigns, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "a click URL of another request must not be reused")
+	assert.NotEmpty(t, rec.Body.String())
+}
+
+func TestDeliverCacheControl(t *testing.T) {
+	perRequest := (&model.Campaign{ID: "tracked", ClickURL: "https://t.example.com/c?rid={REQUEST_ID}"}).ToDeliveryResponse()
+	perQuery := (&model.Campaign{ID: "tracked", ClickURL: "https://t.example.com/c?cid={CID}&app={APP}"}).ToDeliveryResponse()
+	withToken := servedCampaign("a", time.Time{})
+	withToken.TrackingToken = "1767225600.c2lnbmF0dXJl"
+
+	tests := []struct {
+		name      string
+		target    string
+		campaigns []*model.DeliveryResponse
+		want      string
+	}{
+		{name: "anonymous", target: "/v1/delivery?app=a&country=us&os=ios", campaigns: []*model.DeliveryResponse{servedCampaign("a", time.Time{})}, want: "public, max-age=30"},
+		{name: "user", target: "/v1/delivery?app=a&country=us&os=ios&user_id=u1", campaigns: []*model.DeliveryResponse{servedCampaign("a", time.Time{})}, want: "private, no-store"},
+		{name: "per-request click url", target: "/v1/delivery?app=a&country=us&os=ios", campaigns: []*model.DeliveryResponse{servedCampaign("a", time.Time{}), perRequest}, want: "private, no-store"},
+		{name: "per-query click url", target: "/v1/delivery?app=a&country=us&os=ios", campaigns: []*model.DeliveryResponse{perQuery}, want: "public, max-age=30"},
+		{name: "tracking token", target: "/v1/delivery?app=a&country=us&os=ios", campaigns: []*model.DeliveryResponse{withToken}, want: "private, no-store"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, targeting := newDeliveryHandler(t)
+			h.SetDeliveryCaching(30*time.Second, 0)
+			targeting.EXPECT().GetMatchingCampaigns(gomock.Any(), gomock.Any()).Return(tt.campaigns, nil)
+			targeting.EXPECT().ServingStale().Return(false)
+
+			rec := serve(h.GetCampaigns, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.want, rec.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestTrackRejectsUnauthenticatedEvents(t *testing.T) {
+	tokens := service.NewTrackingTokens("secret", time.Hour)
+	now := time.Now()
+	valid := tokens.Issue("req-1", "spotify", "", now)
+
+	tests := []struct {
+		name   string
+		tokens *service.TrackingTokens
+		query  string
+		want   int
+	}{
+		{name: "no request id", tokens: tokens, query: "campaign_id=spotify&token=" + valid, want: http.StatusBadRequest},
+		{name: "no token", tokens: tokens, query: "campaign_id=spotify&request_id=req-1", want: http.StatusForbidden},
+		{name: "malformed token", tokens: tokens, query: "campaign_id=spotify&request_id=req-1&token=garbage", want: http.StatusForbidden},
+		{name: "other request", tokens: tokens, query: "campaign_id=spotify&request_id=req-2&token=" + valid, want: http.StatusForbidden},
+		{name: "other campaign", tokens: tokens, query: "campaign_id=duolingo&request_id=req-1&token=" + valid, want: http.StatusForbidden},
+		{name: "other secret", tokens: tokens, query: "campaign_id=spotify&request_id=req-1&token=" + service.NewTrackingTokens("other", time.Hour).Issue("req-1", "spotify", "", now), want: http.StatusForbidden},
+		{name: "expired", tokens: tokens, query: "campaign_id=spotify&request_id=req-1&token=" + tokens.Issue("req-1", "spotify", "", now.Add(-2*time.Hour)), want: http.StatusForbidden},
+		{name: "tracking disabled", query: "campaign_id=spotify&request_id=req-1&token=" + valid, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The mock fails the test if the event reaches TrackEvent
+			h, _ := newDeliveryHandler(t)
+			h.SetTrackingTokens(tt.tokens)
+
+			rec := serve(h.TrackClick, httptest.NewRequest(http.MethodGet, "/v1/track/click?"+tt.query, nil))
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestTrackValidToken(t *testing.T) {
+	tokens := service.NewTrackingTokens("secret", time.Hour)
+	h, targeting := newDeliveryHandler(t)
+	h.SetTrackingTokens(tokens)
+	targeting.EXPECT().TrackEvent(gomock.Any(), &model.TrackingEvent{Type: model.TrackImpression, CampaignID: "spotify", RequestID: "req-1"}).Return(nil)
+
+	token := tokens.Issue("req-1", "spotify", "", time.Now())
+	rec := serve(h.TrackImpression, httptest.NewRequest(http.MethodGet, "/v1/track/impression?campaign_id=spotify&request_id=req-1&token="+token, nil))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/service"
+	"github.com/Harshi-itaSinha/target-engine/pkg/response"
+)
+
+// Export formats
+const (
+	formatJSONLines = "jsonl"
+	formatCSV       = "csv"
+)
+
+// csvColumns are the columns of a CSV export. Experiments, creatives,
+// blocked categories and targeting rules are nested, so their cells hold
+// JSON.
+var csvColumns = []string{
+	"cid", "name", "img", "cta", "status", "frequency_cap", "priority", "weight",
+	"daily_budget", "total_budget", "app_daily_cap", "max_daily_apps", "experiment",
+	"creatives", "rotation", "blocked_categories", "rules", "start_at", "end_at",
+	"created_at", "updated_at", "click_url",
+}
+
+// ExportCampaigns handles GET /v1/campaigns/export requests. It streams the
+// campaigns matching the status, include_archived, created_after and search
+// query parameters together with their targeting rules, as JSON Lines or,
+// with format=csv, as CSV.
+func (h *DeliveryHandler) ExportCampaigns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := campaignFilterFromQuery(query)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = formatJSONLines
+	}
+
+	var write func(*model.CampaignExport) error
+	var finish func() error
+	switch format {
+	case formatJSONLines:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		write = func(export *model.CampaignExport) error { return encoder.Encode(export) }
+		finish = func() error { return nil }
+	case formatCSV:
+		w.Header().Set("Content-Type", "text/csv")
+		writer := csv.NewWriter(w)
+		header := false
+		write = func(export *model.CampaignExport) error {
+			if !header {
+				header = true
+				if err := writer.Write(csvColumns); err != nil {
+					return err
+				}
+			}
+			return writer.Write(campaignCSVRecord(export))
+		}
+		finish = func() error {
+			if !header {
+				writer.Write(csvColumns)
+			}
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		response.BadRequest(w, "invalid format, expected jsonl or csv")
+		return
+	}
+
+	started := false
+	err = h.targetingService.ExportCampaigns(r.Context(), filter, func(export *model.CampaignExport) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="campaigns.%s"`, format))
+		}
+		return write(export)
+	})
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		if !started {
+			writeCampaignError(w, err)
+			return
+		}
+		// The status has been sent, so all that can be done is cut the body short
+		slog.Error("campaign export failed", "error", err)
+	}
+}
+
+// ImportCampaigns handles POST /v1/campaigns/import requests. The body holds
+// campaigns in the format written by ExportCampaigns: JSON Lines, or CSV with
+// a text/csv content type. Campaigns are created or replaced together with
+// their targeting rules and reported individually.
+func (h *DeliveryHandler) ImportCampaigns(w http.ResponseWriter, r *http.Request) {
+	var items []*model.BulkCampaignRequest
+	var err error
+	switch mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType {
+	case "application/x-ndjson", "application/jsonl", "application/json":
+		items, err = decodeJSONLines(r.Body)
+	case "text/csv":
+		items, err = decodeCSV(r.Body)
+	default:
+		response.UnsupportedMediaType(w, "request body must be application/x-ndjson or text/csv")
+		return
+	}
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	results, err := h.targetingService.ImportCampaigns(r.Context(), items)
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, results)
+}
+
+// decodeJSONLines reads one campaign per JSON value, stopping once more
+// campaigns than an import accepts have been read
+func decodeJSONLines(body io.Reader) ([]*model.BulkCampaignRequest, error) {
+	decoder := json.NewDecoder(body)
+	var items []*model.BulkCampaignRequest
+	for len(items) <= service.MaxImportCampaigns {
+		var item model.BulkCampaignRequest
+		if err := decoder.Decode(&item); err != nil {
+			if errors.Is(err, io.EOF) {
+				return items, nil
+			}
+			return nil, fmt.Errorf("invalid campaign on line %d", len(items)+1)
+		}
+		items = append(items, &item)
+	}
+	return items, nil
+}
+
+// decodeCSV reads campaigns from CSV with a header row naming the columns.
+// Columns may appear in any order; only cid is required and unknown columns
+// are ignored.
+func decodeCSV(body io.Reader) ([]*model.BulkCampaignRequest, error) {
+	reader := csv.NewReader(body)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("invalid csv header")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	if _, ok := columns["cid"]; !ok {
+		return nil, errors.New("csv header must include cid")
+	}
+
+	var items []*model.BulkCampaignRequest
+	for row := 2; len(items) <= service.MaxImportCampaigns; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv on row %d", row)
+		}
+		item, err := campaignFromCSV(columns, record)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// campaignCSVRecord flattens a campaign into a row of csvColumns
+func campaignCSVRecord(export *model.CampaignExport) []string {
+	c := export.Campaign
+	jsonCell := func(v any, empty bool) string {
+		if empty {
+			return ""
+		}
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return []string{
+		c.ID, c.Name, c.Image, c.CTA, c.Status,
+		strconv.Itoa(c.FrequencyCap), strconv.Itoa(c.Priority), strconv.Itoa(c.Weight),
+		strconv.FormatInt(c.DailyBudget, 10), strconv.FormatInt(c.TotalBudget, 10),
+		strconv.Itoa(c.AppDailyCap), strconv.Itoa(c.MaxDailyApps),
+		jsonCell(c.Experiment, c.Experiment == nil),
+		jsonCell(c.Creatives, len(c.Creatives) == 0),
+		c.Rotation,
+		jsonCell(c.BlockedCategories, len(c.BlockedCategories) == 0),
+		jsonCell(export.Rules, len(export.Rules) == 0),
+		timeCell(c.StartAt), timeCell(c.EndAt),
+		c.CreatedAt.UTC().Format(time.RFC3339), c.UpdatedAt.UTC().Format(time.RFC3339),
+		c.ClickURL,
+	}
+}
+
+// timeCell formats an optional time for a CSV cell
+func timeCell(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// campaignFromCSV builds a campaign import from a CSV row. Empty cells leave
+// the field unset.
+func campaignFromCSV(columns map[string]int, record []string) (*model.BulkCampaignRequest, error) {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	item := &model.BulkCampaignRequest{}
+	item.ID = cell("cid")
+	item.Name = cell("name")
+	item.Image = cell("img")
+	item.CTA = cell("cta")
+	item.Status = cell("status")
+	item.Rotation = cell("rotation")
+	item.ClickURL = cell("click_url")
+
+	for name, dst := range map[string]**int{
+		"frequency_cap": &item.FrequencyCap, "priority": &item.Priority, "weight": &item.Weight,
+		"app_daily_cap": &item.AppDailyCap, "max_daily_apps": &item.MaxDailyApps,
+	} {
+		if value := cell(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			*dst = &n
+		}
+	}
+	for name, dst := range map[string]**int64{"daily_budget": &item.DailyBudget, "total_budget": &item.TotalBudget} {
+		if value := cell(name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			*dst = &n
+		}
+	}
+	for name, dst := range map[string]**time.Time{"start_at": &item.StartAt, "end_at": &item.EndAt} {
+		if value := cell(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			*dst = &t
+		}
+	}
+	for name, dst := range map[string]any{
+		"experiment": &item.Experiment, "creatives": &item.Creatives,
+		"blocked_categories": &item.BlockedCategories, "rules": &item.Rules,
+	} {
+		if value := cell(name); value != "" {
+			if err := json.Unmarshal([]byte(value), dst); err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+		}
+	}
+	return item, nil
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/middleware"
+	"github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/pkg/response"
+)
+
+// EngageKillSwitch handles POST /v1/admin/killswitch requests, which stop
+// serving the campaigns of the tenant the request acts for, or of every
+// tenant with "global": true, until the switch is released. The body is
+// optional.
+func (h *DeliveryHandler) EngageKillSwitch(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeKillSwitchRequest(w, r)
+	if !ok {
+		return
+	}
+
+	sw, err := h.targetingService.EngageKillSwitch(r.Context(), req, middleware.Caller(r))
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.Success(w, sw)
+}
+
+// ReleaseKillSwitch handles POST /v1/admin/killswitch/resume requests,
+// resuming serving after EngageKillSwitch. The body is optional.
+func (h *DeliveryHandler) ReleaseKillSwitch(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeKillSwitchRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.targetingService.ReleaseKillSwitch(r.Context(), req, middleware.Caller(r)); err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// ListKillSwitches handles GET /v1/admin/killswitch requests, listing the
+// kill switches that stop serving the campaigns of the request's tenant
+func (h *DeliveryHandler) ListKillSwitches(w http.ResponseWriter, r *http.Request) {
+	response.Success(w, h.targetingService.KillSwitches(r.Context()))
+}
+
+// decodeKillSwitchRequest decodes the optional body of a kill switch request,
+// responding 400 when it is malformed
+func decodeKillSwitchRequest(w http.ResponseWriter, r *http.Request) (*model.KillSwitchRequest, bool) {
+	var req model.KillSwitchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.BadRequest(w, "invalid request body")
+		return nil, false
+	}
+	return &req, true
+}
@@ -0,0 +1,20 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec is the OpenAPI 3.0 description of the HTTP API. It is
+// maintained by hand; update it together with the routes in main.go and the
+// request and response models.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPI handles GET /v1/openapi.json requests
+func (h *DeliveryHandler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPISpec)
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/service"
+	"github.com/Harshi-itaSinha/target-engine/pkg/response"
+)
+
+// SetStreamHeartbeat sets how often idle change streams send a comment, so
+// proxies don't close them. Non-positive values use
+// service.DefaultStreamHeartbeat.
+func (h *DeliveryHandler) SetStreamHeartbeat(interval time.Duration) {
+	h.heartbeat = interval
+}
+
+// StreamCampaignChanges handles GET /v1/stream requests. It streams the
+// changes of the request tenant's campaigns as Server-Sent Events: each
+// event is named after the change type, carries the change as JSON and has
+// the change's ID, which clients send back in Last-Event-ID when they
+// reconnect to catch up. A "reset" event means changes may have been missed,
+// so everything cached should be dropped.
+func (h *DeliveryHandler) StreamCampaignChanges(w http.ResponseWriter, r *http.Request) {
+	changes, err := h.targetingService.SubscribeCampaignChanges(r.Context(), r.Header.Get("Last-Event-ID"))
+	if errors.Is(err, service.ErrTooManySubscribers) {
+		response.ServiceUnavailable(w, err.Error())
+		return
+	}
+	if err != nil {
+		response.InternalServerError(w, err.Error())
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		slog.ErrorContext(r.Context(), "change stream can't be flushed", "error", err)
+		return
+	}
+
+	interval := h.heartbeat
+	if interval <= 0 {
+		interval = service.DefaultStreamHeartbeat
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case change, ok := <-changes:
+			if !ok {
+				// Fell too far behind; the client reconnects with the last ID
+				return
+			}
+			if err := writeChangeEvent(w, change); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeChangeEvent writes a campaign change as a Server-Sent Event
+func writeChangeEvent(w http.ResponseWriter, change *model.CampaignChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", change.ID, change.Type, data)
+	return err
+}
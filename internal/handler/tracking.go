@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/service"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+	"github.com/Harshi-itaSinha/target-engine/pkg/response"
+	"github.com/gorilla/mux"
+)
+
+// formatGIF asks a tracking endpoint for a pixel rather than a 204
+const formatGIF = "gif"
+
+// pixel is a transparent 1x1 GIF
+var pixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// TrackImpression handles GET /v1/track/impression requests reporting that
+// a served campaign was shown
+func (h *DeliveryHandler) TrackImpression(w http.ResponseWriter, r *http.Request) {
+	h.track(w, r, model.TrackImpression)
+}
+
+// TrackClick handles GET /v1/track/click requests reporting that a served
+// campaign was clicked
+func (h *DeliveryHandler) TrackClick(w http.ResponseWriter, r *http.Request) {
+	h.track(w, r, model.TrackClick)
+}
+
+// SetTrackingTokens sets the tokens tracking events are verified with. It
+// must be called before requests are served. Without tokens every event is
+// rejected.
+func (h *DeliveryHandler) SetTrackingTokens(tokens *service.TrackingTokens) {
+	h.trackingTokens = tokens
+}
+
+// track records the event of eventType described by the campaign_id,
+// request_id, user_id and app query parameters. The token parameter must be
+// the tracking token served with the campaign to that request, so events
+// can't be made up for campaigns that weren't served. It responds 204, or
+// with a 1x1 GIF when format=gif so the URL can be embedded as an image.
+func (h *DeliveryHandler) track(w http.ResponseWriter, r *http.Request, eventType string) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != formatGIF {
+		response.BadRequest(w, "invalid format, expected gif")
+		return
+	}
+
+	event := &model.TrackingEvent{
+		Type:       eventType,
+		CampaignID: query.Get("campaign_id"),
+		RequestID:  query.Get("request_id"),
+		UserID:     query.Get("user_id"),
+		App:        query.Get("app"),
+	}
+	if event.RequestID == "" {
+		writeTrackingError(w, fmt.Errorf("%w: %w", service.ErrInvalidTrackingEvent, validation.Invalid("request_id", "is required")))
+		return
+	}
+	if h.trackingTokens == nil || h.trackingTokens.Verify(query.Get("token"), event.RequestID, event.CampaignID, tenant.FromContext(r.Context()), time.Now()) != nil {
+		response.Forbidden(w, "invalid tracking token")
+		return
+	}
+	if err := h.targetingService.TrackEvent(r.Context(), event); err != nil {
+		writeTrackingError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if format != formatGIF {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "image/gif")
+	w.WriteHeader(http.StatusOK)
+	w.Write(pixel)
+}
+
+// GetCampaignTracking handles GET /v1/campaign/{id}/tracking requests,
+// returning the impressions and clicks tracked for a campaign on the UTC
+// day of the day query parameter (YYYYMMDD, today by default) and over its
+// lifetime
+func (h *DeliveryHandler) GetCampaignTracking(w http.ResponseWriter, r *http.Request) {
+	tracking, err := h.targetingService.CampaignTracking(r.Context(), mux.Vars(r)["id"], r.URL.Query().Get("day"))
+	if err != nil {
+		writeCampaignError(w, err)
+		return
+	}
+	response.Success(w, tracking)
+}
+
+// writeTrackingError maps tracking service errors to HTTP responses
+func writeTrackingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTrackingEvent):
+		response.InvalidFields(w, err.Error(), validation.Fields(err))
+	case errors.Is(err, repository.ErrNotFound):
+		response.NotFound(w, err.Error())
+	default:
+		slog.Error("failed to track event", "error", err)
+		response.InternalServerError(w, err.Error())
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// clientIPKey is the context key of the client address resolved by
+// TrustedProxies
+type clientIPKey struct{}
+
+// TrustedProxies resolves the address of the client that made a request.
+// X-Forwarded-For and X-Real-IP are only believed when the connection comes
+// from one of the trusted proxies, since any client can set them. Anyone
+// else is identified by the connection's remote address.
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+}
+
+// NewTrustedProxies trusts the proxies in proxies, given as IP addresses or
+// CIDR ranges, e.g. 10.0.0.0/8. No proxies trusts none, so forwarding
+// headers are ignored.
+func NewTrustedProxies(proxies []string) (*TrustedProxies, error) {
+	p := &TrustedProxies{}
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy '%s': %w", proxy, err)
+			}
+			p.prefixes = append(p.prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %w", proxy, err)
+		}
+		addr = addr.Unmap()
+		p.prefixes = append(p.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return p, nil
+}
+
+// trusted reports whether addr belongs to a trusted proxy
+func (p *TrustedProxies) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the address of the client that made r. When the remote
+// address is a trusted proxy, the X-Forwarded-For entries are walked from
+// the right, skipping the trusted proxies that appended them, and the first
+// untrusted one is the client; entries further left were set by the client
+// and can't be believed. Without X-Forwarded-For, a trusted proxy's
+// X-Real-IP is used. Otherwise the client is the remote address.
+func (p *TrustedProxies) Resolve(r *http.Request) string {
+	remote := remoteIP(r)
+	addr, err := netip.ParseAddr(remote)
+	if err != nil || !p.trusted(addr) {
+		return remote
+	}
+
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		client := remote
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				// Whatever a trusted proxy couldn't have appended ends the
+				// chain, so the last trusted hop is the client
+				break
+			}
+			client = hop.Unmap().String()
+			if !p.trusted(hop) {
+				break
+			}
+		}
+		return client
+	}
+
+	if xri, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return xri.Unmap().String()
+	}
+	return remote
+}
+
+// Handler resolves the client address of every request, which ClientIP
+// returns to the handlers and middleware after it
+func (p *TrustedProxies) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, p.Resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientIP returns the address of the client that made the request as
+// resolved by TrustedProxies.Handler, or the connection's remote address for
+// requests that didn't pass through it. Forwarding headers are never read
+// here, so clients can't pick the address they are identified by.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// remoteIP returns the host of the connection's remote address
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap().String()
+	}
+	return host
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedProxiesResolve(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		xri    string
+		want   string
+	}{
+		{name: "no headers", remote: "203.0.113.5:4000", want: "203.0.113.5"},
+		{name: "untrusted remote forwarded for", remote: "203.0.113.5:4000", xff: []string{"198.51.100.1"}, want: "203.0.113.5"},
+		{name: "untrusted remote real ip", remote: "203.0.113.5:4000", xri: "198.51.100.1", want: "203.0.113.5"},
+		{name: "trusted remote", remote: "10.1.2.3:4000", xff: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "rightmost untrusted hop", remote: "10.1.2.3:4000", xff: []string{"1.1.1.1, 198.51.100.1, 192.0.2.1"}, want: "198.51.100.1"},
+		{name: "multiple headers", remote: "10.1.2.3:4000", xff: []string{"1.1.1.1", "198.51.100.1, 10.9.9.9"}, want: "198.51.100.1"},
+		{name: "only trusted hops", remote: "10.1.2.3:4000", xff: []string{"10.4.4.4, 192.0.2.1"}, want: "10.4.4.4"},
+		{name: "garbage hop", remote: "10.1.2.3:4000", xff: []string{"198.51.100.1, bogus, 10.4.4.4"}, want: "10.4.4.4"},
+		{name: "trusted remote real ip", remote: "10.1.2.3:4000", xri: "198.51.100.1", want: "198.51.100.1"},
+		{name: "ipv4-mapped remote", remote: "[::ffff:192.0.2.1]:4000", xff: []string{"198.51.100.1"}, want: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/delivery", nil)
+			r.RemoteAddr = tt.remote
+			for _, xff := range tt.xff {
+				r.Header.Add("X-Forwarded-For", xff)
+			}
+			if tt.xri != "" {
+				r.Header.Set("X-Real-IP", tt.xri)
+			}
+			assert.Equal(t, tt.want, proxies.Resolve(r))
+		})
+	}
+}
+
+func TestNewTrustedProxiesInvalid(t *testing.T) {
+	_, err := NewTrustedProxies([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+	_, err = NewTrustedProxies([]string{"proxy.internal"})
+	assert.Error(t, err)
+}
+
+func TestClientIPIgnoresHeadersWithoutMiddleware(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/delivery", nil)
+	r.RemoteAddr = "203.0.113.5:4000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	r.Header.Set("X-Real-IP", "198.51.100.2")
+	assert.Equal(t, "203.0.113.5", ClientIP(r))
+}
+
+func TestRateLimitSpoofedForwardedFor(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	limiter := NewRateLimiter(1, 2)
+	limiter.maxClients = 3
+	handler := proxies.Handler(limiter.RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	serve := func(remote, xff string) int {
+		r := httptest.NewRequest(http.MethodGet, "/v1/delivery", nil)
+		r.RemoteAddr = remote
+		if xff != "" {
+			r.Header.Set("X-Forwarded-For", xff)
+			r.Header.Set("X-Real-IP", xff)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	// Another client, behind the trusted proxy, uses up its burst
+	victim := "198.51.100.1"
+	for range 2 {
+		require.Equal(t, http.StatusOK, serve("10.0.0.1:4000", victim))
+	}
+	require.Equal(t, http.StatusTooManyRequests, serve("10.0.0.1:4000", victim))
+
+	// A direct client making up a new address for every request is still
+	// limited by its own
+	var allowed int
+	for i := range 20 {
+		if serve("203.0.113.5:4000", fmt.Sprintf("192.0.2.%d", i)) == http.StatusOK {
+			allowed++
+		}
+	}
+	assert.Equal(t, 2, allowed)
+	assert.Equal(t, 2, limiter.Clients())
+
+	// and didn't evict the other client's bucket to give it a fresh one
+	assert.Equal(t, http.StatusTooManyRequests, serve("10.0.0.1:4000", victim))
+}
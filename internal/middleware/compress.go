@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DefaultCompressMinSize is the smallest body Compress compresses when no
+// minimum is configured; below it the encoding overhead outweighs the gain
+const DefaultCompressMinSize = 1024
+
+// Supported content encodings, in order of preference
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// encoder is implemented by the gzip and brotli writers
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Encoders are pooled since each one holds sizeable compression state
+var encoders = map[string]*sync.Pool{
+	encodingBrotli: {New: func() any { return brotli.NewWriterLevel(nil, brotli.DefaultCompression) }},
+	encodingGzip:   {New: func() any { return gzip.NewWriter(nil) }},
+}
+
+// Compress returns a middleware that compresses responses with brotli or
+// gzip, whichever the client prefers in Accept-Encoding. Bodies are buffered
+// until they reach minSize bytes and then streamed through the encoder;
+// smaller bodies, HEAD requests and responses that can't have a body are sent
+// as is.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	if minSize <= 0 {
+		minSize = DefaultCompressMinSize
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks the supported encoding with the highest quality in
+// an Accept-Encoding header, preferring brotli on a tie. It returns an empty
+// string when the client accepts neither.
+func negotiateEncoding(header string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		qualities[name] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// compressWriter holds back the status and the start of the body until it
+// knows whether the body is large enough to compress
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+	buf      []byte
+	decided  bool
+	encoder  encoder
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.status != 0 {
+		// Superfluous, as for the underlying writer
+		return
+	}
+	if code < http.StatusOK {
+		// Informational responses precede the real one
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.status = code
+	if !bodyAllowed(code) || cw.Header().Get("Content-Encoding") != "" {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what has been written so far. A streamed body is assumed to be
+// large, so it is compressed even if it is still below the minimum size.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.start(cw.Header().Get("Content-Encoding") == "")
+	}
+	if cw.encoder != nil {
+		cw.encoder.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close sends a body that never reached the minimum size uncompressed and
+// finishes the encoded stream otherwise
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// Nothing was written; let the server send its default response
+			return nil
+		}
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.start(false); err != nil {
+			return err
+		}
+	}
+	if cw.encoder == nil {
+		return nil
+	}
+
+	err := cw.encoder.Close()
+	cw.encoder.Reset(nil)
+	encoders[cw.encoding].Put(cw.encoder)
+	cw.encoder = nil
+	return err
+}
+
+// start writes the status and headers, with or without compression, followed
+// by the buffered body
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+	if compress {
+		header := cw.Header()
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", cw.encoding)
+		cw.encoder = encoders[cw.encoding].Get().(encoder)
+		cw.encoder.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	if cw.encoder != nil {
+		_, err := cw.encoder.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// bodyAllowed reports whether a response with the status may have a body
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for the CORSPolicy options left empty
+var (
+	DefaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	DefaultCORSHeaders = []string{"Content-Type", "Authorization", "X-Request-ID", "X-API-Key", "X-Tenant-ID", "Idempotency-Key"}
+)
+
+// DefaultCORSMaxAge is how long browsers may cache a preflight response when
+// no max age is configured
+const DefaultCORSMaxAge = 24 * time.Hour
+
+// CORSPolicy decides which cross-origin requests browsers may make. The
+// origin of an allowed request is echoed back in Access-Control-Allow-Origin
+// rather than answered with *, so the policy also works with credentials,
+// and every response varies by Origin so caches keep them apart.
+type CORSPolicy struct {
+	origins     []string
+	anyOrigin   bool
+	methods     string
+	headers     string
+	credentials bool
+	maxAge      string
+}
+
+// NewCORSPolicy creates a policy allowing requests from origins, which are
+// matched case-insensitively and may contain wildcards, e.g.
+// https://*.example.com, or be "*" to allow every origin. No origins allows
+// no cross-origin requests. Empty methods and headers and a zero maxAge use
+// the defaults.
+func NewCORSPolicy(origins, methods, headers []string, credentials bool, maxAge time.Duration) (*CORSPolicy, error) {
+	p := &CORSPolicy{credentials: credentials}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin == "*" {
+			p.anyOrigin = true
+			continue
+		}
+		if _, err := path.Match(origin, ""); err != nil {
+			return nil, fmt.Errorf("invalid CORS origin '%s': %w", origin, err)
+		}
+		p.origins = append(p.origins, origin)
+	}
+
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	if len(headers) == 0 {
+		headers = DefaultCORSHeaders
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultCORSMaxAge
+	}
+	p.methods = strings.ToUpper(strings.Join(methods, ", "))
+	p.headers = strings.Join(headers, ", ")
+	p.maxAge = strconv.Itoa(int(maxAge.Seconds()))
+	return p, nil
+}
+
+// Handler is a middleware applying the policy. Preflight requests are
+// answered here and never reach next.
+func (p *CORSPolicy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		w.Header().Add("Vary", "Origin")
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin != "" && p.allowed(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if p.credentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			if preflight {
+				w.Header().Set("Access-Control-Allow-Methods", p.methods)
+				w.Header().Set("Access-Control-Allow-Headers", p.headers)
+				w.Header().Set("Access-Control-Max-Age", p.maxAge)
+			}
+		}
+
+		// Preflights from disallowed origins get no CORS headers, which
+		// makes the browser refuse the actual request
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowed reports whether requests from origin are allowed
+func (p *CORSPolicy) allowed(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, pattern := range p.origins {
+		if matched, _ := path.Match(pattern, origin); matched {
+			return true
+		}
+	}
+	return false
+}
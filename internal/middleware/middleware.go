@@ -1,20 +1,45 @@
 package middleware
 
 import (
+	"container/list"
 	"context"
+	"crypto/subtle"
 	"fmt"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Harshi-itaSinha/target-engine/internal/contextkey"
+	"github.com/Harshi-itaSinha/target-engine/internal/errortracking"
+	"github.com/Harshi-itaSinha/target-engine/internal/logger"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 )
 
+// MaxRequestIDLength bounds the length of an inbound X-Request-ID
+const MaxRequestIDLength = 128
+
+// RequestID tags each request with an ID, echoed in the X-Request-ID response
+// header and carried by the request context into logs, events and
+// repository calls. An inbound X-Request-ID, e.g. set by a proxy or the
+// calling service, is kept when well-formed so one ID follows the request
+// across services; otherwise a new one is generated.
 func RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := generateRequestID()
-		ctx := context.WithValue(r.Context(), "request_id", requestID)
+		requestID := r.Header.Get("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = generateRequestID()
+		}
+		ctx := contextkey.WithRequestID(r.Context(), requestID)
 		w.Header().Set("X-Request-ID", requestID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -33,156 +58,313 @@ func Logger(next http.Handler) http.Handler {
 		
 
 		duration := time.Since(start)
-		requestID := getRequestID(r.Context())
-		
-		fmt.Printf("[%s] %s %s %d %v %s\n",
-			time.Now().Format("2006-01-02 15:04:05"),
-			r.Method,
-			r.URL.Path,
-			wrapped.statusCode,
-			duration,
-			requestID,
+
+		logger.FromContext(r.Context()).Info("request completed",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", wrapped.statusCode,
+			"duration", duration,
 		)
 	})
 }
 
+// Tracing starts a server span per request, continuing any trace propagated
+// in the request headers, and stores it in the request context so the
+// service and repository spans nest under it
+func Tracing(next http.Handler) http.Handler {
+	tracer := otel.Tracer("github.com/Harshi-itaSinha/target-engine/internal/middleware")
 
-func CORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
-		w.Header().Set("Access-Control-Max-Age", "86400")
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+		// Name spans by route template so IDs don't explode span cardinality
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
 		}
 
-		next.ServeHTTP(w, r)
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+			),
+		)
+		defer span.End()
+
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", wrapped.statusCode))
+		if wrapped.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrapped.statusCode))
+		}
 	})
 }
 
-func Recovery(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				requestID := getRequestID(r.Context())
-				fmt.Printf("PANIC [%s]: %v\n", requestID, err)
-				
+// Recovery returns a middleware that turns a panic in the handler into a 500.
+// The panic is logged with its stack trace and request, and sent to reporter
+// unless it is nil.
+func Recovery(reporter errortracking.Reporter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					// Deliberate aborts are left to net/http, which doesn't log them
+					panic(p)
+				}
+
+				event := panicEvent(r, p)
+				logger.FromContext(r.Context()).Error("panic recovered",
+					"panic", event.Message,
+					"panic_type", event.Type,
+					"method", r.Method,
+					"path", r.URL.Path,
+					"route", event.Route,
+					"stack", event.Stack.String(),
+				)
+				if reporter != nil {
+					reporter.Report(event)
+				}
+
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusInternalServerError)
 				w.Write([]byte(`{"error": "Internal Server Error", "message": "An unexpected error occurred"}`))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// recoveredPanic is a panic recovered on another goroutine and raised again
+// on the request's, keeping the stack it was first raised on
+type recoveredPanic struct {
+	value any
+	stack errortracking.Stack
+}
+
+// sensitiveHeaders carry credentials, so they're left out of panic reports
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// panicEvent describes a panic recovered from the handler of r. It must be
+// called while the deferred function that recovered p runs.
+func panicEvent(r *http.Request, p any) errortracking.Event {
+	stack := errortracking.PanicStack()
+	if recovered, ok := p.(*recoveredPanic); ok {
+		p, stack = recovered.value, recovered.stack
+	}
+	typ, message := errortracking.Panic(p)
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	headers := make(map[string]string, len(r.Header))
+	for name, values := range r.Header {
+		if !slices.Contains(sensitiveHeaders, name) {
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+	route := ""
+	if current := mux.CurrentRoute(r); current != nil {
+		route, _ = current.GetPathTemplate()
+	}
+	traceID := ""
+	if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.HasTraceID() {
+		traceID = spanContext.TraceID().String()
+	}
+
+	return errortracking.Event{
+		Type:      typ,
+		Message:   message,
+		Stack:     stack,
+		At:        time.Now(),
+		RequestID: contextkey.RequestID(r.Context()),
+		TraceID:   traceID,
+		Method:    r.Method,
+		URL:       scheme + "://" + r.Host + r.URL.Path,
+		Query:     r.URL.RawQuery,
+		Route:     route,
+		Headers:   headers,
+	}
+}
+
+// APIKeyAuth returns a middleware that rejects requests whose X-API-Key header
+// does not match one of the given keys
+func APIKeyAuth(keys []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := r.Header.Get("X-API-Key")
+			if provided == "" || !validAPIKey(keys, provided) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error": "Unauthorized", "message": "Missing or invalid API key"}`))
+				return
 			}
-		}()
-		
-		next.ServeHTTP(w, r)
-	})
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
+// validAPIKey compares the provided key against every configured key in constant time
+func validAPIKey(keys []string, provided string) bool {
+	valid := false
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(provided)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
 
+// Defaults of the in-process rate limiter
+const (
+	DefaultIdleTTL    = 10 * time.Minute
+	DefaultMaxClients = 100000
+)
+
+// RateLimiter keeps a token bucket per client in process. Clients not seen
+// for idleTTL are forgotten by Cleanup, and at most maxClients are tracked:
+// a new client beyond that evicts the least recently seen one.
 type RateLimiter struct {
-	limiters map[string]*rate.Limiter
-	mutex    sync.RWMutex
-	rate     rate.Limit
-	burst    int
+	limiters   map[string]*list.Element
+	seen       *list.List // of *trackedClient, most recently seen first
+	mutex      sync.Mutex
+	rate       rate.Limit
+	burst      int
+	idleTTL    time.Duration
+	maxClients int
 }
 
+// trackedClient is the token bucket of one client
+type trackedClient struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
 
 func NewRateLimiter(rps int, burst int) *RateLimiter {
 	return &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rate:     rate.Limit(rps),
-		burst:    burst,
+		limiters:   make(map[string]*list.Element),
+		seen:       list.New(),
+		rate:       rate.Limit(rps),
+		burst:      burst,
+		idleTTL:    DefaultIdleTTL,
+		maxClients: DefaultMaxClients,
 	}
 }
 
-
 func (rl *RateLimiter) getLimiter(ip string) *rate.Limiter {
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 
-	limiter, exists := rl.limiters[ip]
-	if !exists {
-		limiter = rate.NewLimiter(rl.rate, rl.burst)
-		rl.limiters[ip] = limiter
+	now := time.Now()
+	if elem, exists := rl.limiters[ip]; exists {
+		client := elem.Value.(*trackedClient)
+		client.lastSeen = now
+		rl.seen.MoveToFront(elem)
+		return client.limiter
 	}
 
-	return limiter
+	client := &trackedClient{key: ip, limiter: rate.NewLimiter(rl.rate, rl.burst), lastSeen: now}
+	rl.limiters[ip] = rl.seen.PushFront(client)
+	for rl.seen.Len() > rl.maxClients {
+		rl.remove(rl.seen.Back())
+	}
+	return client.limiter
 }
 
-// RateLimit returns a middleware that implements rate limiting
-func (rl *RateLimiter) RateLimit(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := getClientIP(r)
-		limiter := rl.getLimiter(ip)
-
-		if !limiter.Allow() {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			w.Write([]byte(`{"error": "Too Many Requests", "message": "Rate limit exceeded"}`))
-			return
-		}
+// remove forgets a tracked client
+func (rl *RateLimiter) remove(elem *list.Element) {
+	rl.seen.Remove(elem)
+	delete(rl.limiters, elem.Value.(*trackedClient).key)
+}
 
-		next.ServeHTTP(w, r)
-	})
+// Clients returns the number of clients tracked
+func (rl *RateLimiter) Clients() int {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	return rl.seen.Len()
 }
 
-func (rl *RateLimiter) Cleanup() {
+// SetLimit changes the rate and burst for new and already tracked clients
+func (rl *RateLimiter) SetLimit(rps int, burst int) {
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 
+	rl.rate = rate.Limit(rps)
+	rl.burst = burst
+	for _, elem := range rl.limiters {
+		limiter := elem.Value.(*trackedClient).limiter
+		limiter.SetLimit(rl.rate)
+		limiter.SetBurst(rl.burst)
+	}
+}
 
-	for ip, limiter := range rl.limiters {
-	
-		if limiter.Tokens() == float64(rl.burst) {
-			delete(rl.limiters, ip)
-		}
+// Allow reports whether the client may make a request now and, if not, how
+// long it has to wait before it may
+func (rl *RateLimiter) Allow(ctx context.Context, key string) (bool, time.Duration) {
+	reservation := rl.getLimiter(key).Reserve()
+	if !reservation.OK() {
+		// A zero burst never admits a request
+		return false, time.Second
+	}
+	delay := reservation.Delay()
+	if delay == 0 {
+		return true, 0
 	}
+	reservation.Cancel()
+	return false, delay
 }
 
-func Health(next http.Handler) http.Handler {
+// RateLimit returns a middleware that implements rate limiting
+func (rl *RateLimiter) RateLimit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/health" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"status": "ok", "timestamp": "` + time.Now().Format(time.RFC3339) + `"}`))
+		if ok, retryAfter := rl.Allow(r.Context(), ClientIP(r)); !ok {
+			tooManyRequests(w, retryAfter)
 			return
 		}
+
 		next.ServeHTTP(w, r)
 	})
 }
 
-
-func Timeout(duration time.Duration) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx, cancel := context.WithTimeout(r.Context(), duration)
-			defer cancel()
-
-			r = r.WithContext(ctx)
-			
-			done := make(chan bool, 1)
-			go func() {
-				next.ServeHTTP(w, r)
-				done <- true
-			}()
-
-			select {
-			case <-done:
-				return
-			case <-ctx.Done():
-				if ctx.Err() == context.DeadlineExceeded {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusRequestTimeout)
-					w.Write([]byte(`{"error": "Request Timeout", "message": "Request took too long to process"}`))
-				}
-			}
-		})
+// tooManyRequests writes a 429 response telling the client when to retry
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
 	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write([]byte(`{"error": "Too Many Requests", "message": "Rate limit exceeded"}`))
 }
 
+// Cleanup forgets clients not seen for the idle TTL and those back at their
+// full burst, whose buckets a new client would start with anyway
+func (rl *RateLimiter) Cleanup() {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
 
+	idleSince := time.Now().Add(-rl.idleTTL)
+	for elem := rl.seen.Back(); elem != nil; {
+		prev := elem.Prev()
+		client := elem.Value.(*trackedClient)
+		if client.lastSeen.Before(idleSince) || client.limiter.Tokens() == float64(rl.burst) {
+			rl.remove(elem)
+		}
+		elem = prev
+	}
+}
 
 type responseWriter struct {
 	http.ResponseWriter
@@ -194,37 +376,29 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func generateRequestID() string {
 	return fmt.Sprintf("%d", time.Now().UnixNano())
 }
 
-func getRequestID(ctx context.Context) string {
-	if requestID, ok := ctx.Value("request_id").(string); ok {
-		return requestID
+// validRequestID reports whether id is a non-empty request ID of at most
+// MaxRequestIDLength letters, digits and the punctuation common in trace and
+// UUID formats, so a client can't inject arbitrary text into logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLength {
+		return false
 	}
-	return "unknown"
-}
-
-func getClientIP(r *http.Request) string {
-	
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		
-		if idx := strings.Index(xff, ","); idx != -1 {
-			return strings.TrimSpace(xff[:idx])
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '/', c == '+', c == '=':
+		default:
+			return false
 		}
-		return strings.TrimSpace(xff)
-	}
-
-
-	xri := r.Header.Get("X-Real-IP")
-	if xri != "" {
-		return strings.TrimSpace(xri)
-	}
-
-
-	if idx := strings.LastIndex(r.RemoteAddr, ":"); idx != -1 {
-		return r.RemoteAddr[:idx]
 	}
-	return r.RemoteAddr
+	return true
 }
\ No newline at end of file
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Limiter keeps a token bucket per client key
+type Limiter interface {
+	// Allow reports whether the client may make a request now and, if not,
+	// how long it has to wait before it may
+	Allow(ctx context.Context, key string) (bool, time.Duration)
+	// SetLimit changes the rate and burst for every client
+	SetLimit(rps int, burst int)
+	// Cleanup forgets idle clients
+	Cleanup()
+}
+
+// LimiterFactory creates a limiter. name identifies the default limiter or a
+// route override so limiters sharing a backend keep separate buckets.
+type LimiterFactory func(name string, rps int, burst int) Limiter
+
+// MemoryLimiters returns a factory of limiters that keep their buckets in
+// process, each forgetting clients idle for idleTTL and tracking at most
+// maxClients. Non-positive values use DefaultIdleTTL and DefaultMaxClients.
+func MemoryLimiters(idleTTL time.Duration, maxClients int) LimiterFactory {
+	if idleTTL <= 0 {
+		idleTTL = DefaultIdleTTL
+	}
+	if maxClients <= 0 {
+		maxClients = DefaultMaxClients
+	}
+	return func(name string, rps int, burst int) Limiter {
+		limiter := NewRateLimiter(rps, burst)
+		limiter.idleTTL, limiter.maxClients = idleTTL, maxClients
+		return limiter
+	}
+}
+
+// defaultLimiterName names the limiter of routes without an override
+const defaultLimiterName = "default"
+
+// RouteRateLimiter rate limits each client per route. Routes with an
+// override get their own limits and token buckets; all other routes share
+// the default limiter.
+type RouteRateLimiter struct {
+	newLimiter LimiterFactory
+	clientKey  func(r *http.Request) string
+	defaults   Limiter
+	routes     map[string]Limiter
+	mutex      sync.RWMutex
+	// onReject, if set, is called with the name of the limiter that
+	// rejected a request
+	onReject func(limiter string)
+}
+
+// NewRouteRateLimiter creates a limiter applying rps and burst to every
+// route without an override. clientKey identifies the client a request is
+// counted against and defaults to ClientIP when nil.
+func NewRouteRateLimiter(newLimiter LimiterFactory, clientKey func(r *http.Request) string, rps int, burst int) *RouteRateLimiter {
+	if clientKey == nil {
+		clientKey = ClientIP
+	}
+	return &RouteRateLimiter{
+		newLimiter: newLimiter,
+		clientKey:  clientKey,
+		defaults:   newLimiter(defaultLimiterName, rps, burst),
+		routes:     make(map[string]Limiter),
+	}
+}
+
+// SetLimit changes the default limits
+func (rl *RouteRateLimiter) SetLimit(rps int, burst int) {
+	rl.defaults.SetLimit(rps, burst)
+}
+
+// SetRouteLimit sets the limits for requests to the route with the given
+// path template, e.g. /v1/campaign/{id}. An empty method applies them to
+// every method of the route.
+func (rl *RouteRateLimiter) SetRouteLimit(method, path string, rps int, burst int) {
+	key := routeKey(method, path)
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if limiter, exists := rl.routes[key]; exists {
+		limiter.SetLimit(rps, burst)
+		return
+	}
+	rl.routes[key] = rl.newLimiter(key, rps, burst)
+}
+
+// SetOnReject sets a function called with the name of the limiter, default
+// or a route override such as "POST /v1/campaign", whenever it rejects a
+// request. It must be called before requests are served.
+func (rl *RouteRateLimiter) SetOnReject(fn func(limiter string)) {
+	rl.onReject = fn
+}
+
+// RateLimit returns a middleware that applies the limits of the matched
+// route. It must be installed with Router.Use so the route is known.
+func (rl *RouteRateLimiter) RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, limiter := rl.limiterFor(r)
+		if ok, retryAfter := limiter.Allow(r.Context(), rl.clientKey(r)); !ok {
+			if rl.onReject != nil {
+				rl.onReject(name)
+			}
+			tooManyRequests(w, retryAfter)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Cleanup drops the state of idle clients on every route
+func (rl *RouteRateLimiter) Cleanup() {
+	rl.defaults.Cleanup()
+
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+	for _, limiter := range rl.routes {
+		limiter.Cleanup()
+	}
+}
+
+// Clients returns the number of clients each in-process limiter tracks,
+// keyed by limiter name. Limiters keeping their buckets elsewhere, such as
+// Redis, are left out.
+func (rl *RouteRateLimiter) Clients() map[string]int {
+	clients := make(map[string]int)
+	if counter, ok := rl.defaults.(*RateLimiter); ok {
+		clients[defaultLimiterName] = counter.Clients()
+	}
+
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+	for name, limiter := range rl.routes {
+		if counter, ok := limiter.(*RateLimiter); ok {
+			clients[name] = counter.Clients()
+		}
+	}
+	return clients
+}
+
+// limiterFor returns the name and limiter of the request's route, preferring
+// an override for its method over one for any method
+func (rl *RouteRateLimiter) limiterFor(r *http.Request) (string, Limiter) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return defaultLimiterName, rl.defaults
+	}
+	path, err := route.GetPathTemplate()
+	if err != nil {
+		return defaultLimiterName, rl.defaults
+	}
+
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+	for _, key := range []string{routeKey(r.Method, path), routeKey("", path)} {
+		if limiter, exists := rl.routes[key]; exists {
+			return strings.TrimSpace(key), limiter
+		}
+	}
+	return defaultLimiterName, rl.defaults
+}
+
+// routeKey identifies a route override
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// APIKeyOrIP returns a client key function that counts requests carrying one
+// of the given API keys against the key, so clients behind a shared address
+// get their own limits, and all other requests against the client IP. Keys
+// that aren't configured are ignored so callers can't mint fresh buckets.
+func APIKeyOrIP(keys []string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if provided := r.Header.Get("X-API-Key"); provided != "" && validAPIKey(keys, provided) {
+			// Hash the key so it never ends up in a shared backend
+			sum := sha256.Sum256([]byte(provided))
+			return "key:" + hex.EncodeToString(sum[:16])
+		}
+		return "ip:" + ClientIP(r)
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// Defaults for where TenantResolver looks for the tenant
+const (
+	DefaultTenantHeader = "X-Tenant-ID"
+	DefaultTenantClaim  = "tenant"
+)
+
+// TenantResolver works out the tenant a request acts for and stores it in
+// the request context. A tenant bound to the request's API key, or named in
+// the tenant claim of its bearer token, takes precedence; otherwise the
+// tenant header is used, e.g. by public delivery requests and by operator
+// keys without a binding. A header naming a different tenant than the key or
+// token is rejected so credentials can't reach across tenants.
+type TenantResolver struct {
+	header string
+	claim  string
+	keys   map[string]string
+}
+
+// NewTenantResolver creates a resolver reading the tenant from header and
+// the bearer token claim, with keyTenants mapping API keys to the tenant
+// they are bound to. Empty names use the defaults.
+func NewTenantResolver(header, claim string, keyTenants map[string]string) *TenantResolver {
+	if header == "" {
+		header = DefaultTenantHeader
+	}
+	if claim == "" {
+		claim = DefaultTenantClaim
+	}
+	return &TenantResolver{header: header, claim: claim, keys: keyTenants}
+}
+
+// Headers returns the request headers the tenant may be resolved from, for
+// responses to list in Vary
+func (tr *TenantResolver) Headers() []string {
+	headers := []string{tr.header, "Authorization"}
+	if len(tr.keys) > 0 {
+		headers = append(headers, "X-API-Key")
+	}
+	return headers
+}
+
+// Resolve is a middleware that scopes the request to its tenant. It must run
+// after authentication so bearer token claims are available.
+func (tr *TenantResolver) Resolve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested := strings.TrimSpace(r.Header.Get(tr.header))
+		id := requested
+		if bound, ok := tr.boundTenant(r); ok {
+			if requested != "" && requested != bound {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"error": "Forbidden", "message": "Credentials are not valid for the requested tenant"}`))
+				return
+			}
+			id = bound
+		}
+
+		if err := tenant.Validate(id); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "Bad Request", "message": "Invalid tenant ID"}`))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), id)))
+	})
+}
+
+// boundTenant returns the tenant the request's credentials are bound to, if
+// any. API keys are compared in constant time.
+func (tr *TenantResolver) boundTenant(r *http.Request) (string, bool) {
+	if provided := r.Header.Get("X-API-Key"); provided != "" {
+		bound, found := "", false
+		for key, id := range tr.keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(provided)) == 1 {
+				bound, found = id, true
+			}
+		}
+		return bound, found
+	}
+
+	if claims, ok := ClaimsFromContext(r.Context()); ok {
+		if id, ok := claims[tr.claim].(string); ok && id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
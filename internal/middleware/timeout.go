@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/errortracking"
+	"github.com/gorilla/mux"
+)
+
+// timeoutBody is sent with the 408 of a request that ran out of time
+const timeoutBody = `{"error": "Request Timeout", "message": "Request took too long to process"}`
+
+// Timeout returns a middleware that bounds each request to duration. The
+// handler runs with a context that is cancelled at the deadline and writes to
+// a buffer, which is copied to the client once it returns in time. A handler
+// still running at the deadline gets a 408 sent in its place, and its later
+// writes fail with http.ErrHandlerTimeout, so exactly one response is
+// written. A panic in the handler is re-raised on the request's goroutine
+// for Recovery to handle, with the stack it was raised on.
+//
+// Routes whose path template is among streams, such as event streams, are
+// long-lived and unbuffered, so they are passed through unbounded.
+func Timeout(duration time.Duration, streams ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil && len(streams) > 0 {
+				if tpl, err := route.GetPathTemplate(); err == nil && slices.Contains(streams, tpl) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), duration)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{w: w, header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						if _, ok := p.(*recoveredPanic); !ok && p != http.ErrAbortHandler {
+							p = &recoveredPanic{value: p, stack: errortracking.PanicStack()}
+						}
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.flush()
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				// The client went away; there is nobody to answer
+				if ctx.Err() != context.DeadlineExceeded {
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusRequestTimeout)
+				w.Write([]byte(timeoutBody))
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers the response of a handler run by Timeout until it is
+// known whether the handler finished in time
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.header }
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	tw.wroteHeader = true
+	tw.code = code
+}
+
+// flush copies the buffered response to the client. The caller holds mu.
+func (tw *timeoutWriter) flush() {
+	dst := tw.w.Header()
+	for key, values := range tw.header {
+		// Vary accumulates the request headers every layer depends on, such
+		// as Accept-Encoding set by Compress
+		if key == "Vary" {
+			dst[key] = append(dst[key], values...)
+			continue
+		}
+		dst[key] = values
+	}
+	if !tw.wroteHeader {
+		tw.code = http.StatusOK
+	}
+	tw.w.WriteHeader(tw.code)
+	tw.w.Write(tw.buf.Bytes())
+}
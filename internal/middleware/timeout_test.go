@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/errortracking"
+)
+
+// recordingReporter keeps the events reported to it
+type recordingReporter struct {
+	events []errortracking.Event
+}
+
+func (r *recordingReporter) Report(event errortracking.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestTimeoutSlowHandler(t *testing.T) {
+	release := make(chan struct{})
+	lateWrite := make(chan error, 1)
+	handler := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "slow")
+		<-r.Context().Done()
+		<-release
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte("too late"))
+		lateWrite <- err
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/delivery", nil))
+	close(release)
+
+	assert.Equal(t, http.StatusRequestTimeout, rec.Code)
+	assert.JSONEq(t, timeoutBody, rec.Body.String())
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Empty(t, rec.Header().Get("X-Handler"), "headers of a timed out handler must not be sent")
+
+	select {
+	case err := <-lateWrite:
+		assert.ErrorIs(t, err, http.ErrHandlerTimeout)
+	case <-time.After(time.Second):
+		t.Fatal("handler never wrote after the timeout")
+	}
+	assert.JSONEq(t, timeoutBody, rec.Body.String(), "late writes must not reach the client")
+}
+
+func TestTimeoutFastHandler(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		assert.True(t, hasDeadline)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Handler", "fast")
+		w.Header().Add("Vary", "Origin")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ok":`))
+		w.Write([]byte(`true}`))
+	}))
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Vary", "Accept-Encoding")
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/delivery", nil))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `{"ok":true}`, rec.Body.String())
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "fast", rec.Header().Get("X-Handler"))
+	assert.Equal(t, []string{"Accept-Encoding", "Origin"}, rec.Header().Values("Vary"))
+}
+
+func TestTimeoutImplicitOK(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/delivery", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestTimeoutPanicReachesRecovery(t *testing.T) {
+	reporter := &recordingReporter{}
+	handler := Recovery(reporter)(Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("boom")
+	})))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/delivery", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "partial")
+	require.Len(t, reporter.events, 1)
+	assert.Equal(t, "boom", reporter.events[0].Message)
+	assert.Contains(t, reporter.events[0].Stack.String(), "TestTimeoutPanicReachesRecovery",
+		"the stack must be the one the handler panicked on")
+}
+
+func TestTimeoutAbortHandlerPassesThrough(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/delivery", nil))
+	})
+}
+
+func TestTimeoutStreamBypassesBuffering(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Timeout(10*time.Millisecond, "/v1/stream"))
+	router.HandleFunc("/v1/stream", func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		assert.False(t, hasDeadline, "streams must not be bounded")
+		_, flushes := w.(http.Flusher)
+		assert.True(t, flushes, "streams must write to the client directly")
+
+		w.Write([]byte("event: one\n\n"))
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("event: two\n\n"))
+	})
+	router.HandleFunc("/v1/delivery", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/stream", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "event: one\n\nevent: two\n\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/delivery", nil))
+	assert.Equal(t, http.StatusRequestTimeout, rec.Code, "other routes stay bounded")
+}
+
+func TestTimeoutClientGone(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/delivery", nil).WithContext(ctx))
+
+	assert.False(t, rec.Flushed)
+	assert.Empty(t, rec.Body.String(), "nothing is written for a client that went away")
+}
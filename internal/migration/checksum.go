@@ -0,0 +1,181 @@
+package migration
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// Report compares the campaigns and targeting rules of two repositories
+type Report struct {
+	Campaigns Comparison `json:"campaigns"`
+	Rules     Comparison `json:"rules"`
+}
+
+// Consistent reports whether both repositories hold the same data
+func (r *Report) Consistent() bool {
+	return r.Campaigns.Consistent() && r.Rules.Consistent()
+}
+
+// Comparison compares one kind of item of two repositories. A checksum
+// covers every item of a repository, so equal checksums mean equal data.
+// Missing items are only in the source, extra ones only in the target.
+type Comparison struct {
+	SourceCount    int      `json:"source_count"`
+	TargetCount    int      `json:"target_count"`
+	SourceChecksum string   `json:"source_checksum"`
+	TargetChecksum string   `json:"target_checksum"`
+	Missing        []string `json:"missing,omitempty"`
+	Extra          []string `json:"extra,omitempty"`
+	Different      []string `json:"different,omitempty"`
+}
+
+// Consistent reports whether both sides hold the same items
+func (c *Comparison) Consistent() bool {
+	return c.SourceChecksum == c.TargetChecksum
+}
+
+// itemChecksum is the checksum of one item, keyed by its ID
+type itemChecksum struct {
+	id  string
+	sum string
+}
+
+// checksums returns the checksum of each item, in the order of items
+func checksums[T any](items []T, id func(T) string, sum func(T) string) []itemChecksum {
+	sums := make([]itemChecksum, len(items))
+	for i, item := range items {
+		sums[i] = itemChecksum{id: id(item), sum: sum(item)}
+	}
+	return sums
+}
+
+// compare matches the item checksums of a source and a target by ID
+func compare(source, target []itemChecksum) Comparison {
+	comparison := Comparison{
+		SourceCount:    len(source),
+		TargetCount:    len(target),
+		SourceChecksum: combinedChecksum(source),
+		TargetChecksum: combinedChecksum(target),
+	}
+	targetSums := make(map[string]string, len(target))
+	for _, item := range target {
+		targetSums[item.id] = item.sum
+	}
+	for _, item := range source {
+		sum, ok := targetSums[item.id]
+		switch {
+		case !ok:
+			comparison.Missing = append(comparison.Missing, item.id)
+		case sum != item.sum:
+			comparison.Different = append(comparison.Different, item.id)
+		}
+		delete(targetSums, item.id)
+	}
+	for id := range targetSums {
+		comparison.Extra = append(comparison.Extra, id)
+	}
+	slices.Sort(comparison.Extra)
+	return comparison
+}
+
+// combinedChecksum hashes the IDs and checksums of every item in ID order
+func combinedChecksum(items []itemChecksum) string {
+	sorted := slices.Clone(items)
+	slices.SortFunc(sorted, func(a, b itemChecksum) int { return cmp.Compare(a.id, b.id) })
+	hash := sha256.New()
+	for _, item := range sorted {
+		hash.Write([]byte(item.id))
+		hash.Write([]byte{0})
+		hash.Write([]byte(item.sum))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// campaignChecksum hashes a campaign in a form every repository stores
+// alike: times in UTC at millisecond precision
+func campaignChecksum(campaign *model.Campaign) string {
+	c := *campaign
+	c.CreatedAt = canonicalTime(c.CreatedAt)
+	c.UpdatedAt = canonicalTime(c.UpdatedAt)
+	c.StartAt = canonicalTimePtr(c.StartAt)
+	c.EndAt = canonicalTimePtr(c.EndAt)
+	c.DeletedAt = canonicalTimePtr(c.DeletedAt)
+	return canonicalChecksum(&c)
+}
+
+// ruleChecksum hashes a targeting rule like campaignChecksum
+func ruleChecksum(rule *model.TargetingRule) string {
+	r := *rule
+	r.CreatedAt = canonicalTime(r.CreatedAt)
+	r.UpdatedAt = canonicalTime(r.UpdatedAt)
+	return canonicalChecksum(&r)
+}
+
+func canonicalTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Millisecond)
+}
+
+func canonicalTimePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	canonical := canonicalTime(*t)
+	return &canonical
+}
+
+// canonicalChecksum hashes the JSON encoding of v with nulls, empty lists
+// and empty objects left out, since repositories decode absent lists and
+// maps differently. Object keys are encoded in sorted order.
+func canonicalChecksum(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return ""
+	}
+	data, _ = json.Marshal(dropEmpty(decoded))
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// dropEmpty removes nulls, empty lists and empty objects from decoded JSON
+func dropEmpty(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if value = dropEmpty(value); isEmpty(value) {
+				delete(v, key)
+			} else {
+				v[key] = value
+			}
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = dropEmpty(value)
+		}
+		return v
+	}
+	return v
+}
+
+func isEmpty(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+// Package migration copies campaigns and targeting rules from one repository
+// to another and verifies that both hold the same data.
+//
+// A migration without downtime runs the servers with writes mirrored to the
+// target (database.dualWrite), copies the existing data, then verifies and
+// repairs the target until it matches the source. Writes made while copying
+// are mirrored, so a repair pass only has to fix the items the copy raced
+// with.
+package migration
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+)
+
+// DefaultBatchSize is the number of campaigns or rules imported at a time
+// when Options.BatchSize is unset
+const DefaultBatchSize = 500
+
+// Stages reported through Options.Progress
+const (
+	StageCampaigns = "campaigns"
+	StageRules     = "rules"
+)
+
+// allStatuses lists every campaign status, so campaigns of every tenant in
+// any state are migrated
+var allStatuses = []string{
+	model.StatusDraft, model.StatusScheduled, model.StatusActive, model.StatusPaused,
+	model.StatusCompleted, model.StatusArchived, model.StatusInactive,
+}
+
+// Progress reports how many of the campaigns or rules of a stage have been
+// copied
+type Progress struct {
+	Stage string
+	Done  int
+	Total int
+}
+
+// Options tune a Migrator
+type Options struct {
+	BatchSize int
+	// Progress, if set, is called after every batch
+	Progress func(Progress)
+}
+
+// Migrator copies data from a source repository to a target one
+type Migrator struct {
+	source   repository.Repository
+	target   repository.Repository
+	importer repository.Importer
+	opts     Options
+}
+
+// New creates a Migrator. The target must support imports.
+func New(source, target repository.Repository, opts Options) (*Migrator, error) {
+	importer, ok := target.(repository.Importer)
+	if !ok {
+		return nil, errors.New("target repository does not support imports")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	return &Migrator{source: source, target: target, importer: importer, opts: opts}, nil
+}
+
+// CopyResult counts the campaigns and rules copied
+type CopyResult struct {
+	Campaigns int `json:"campaigns"`
+	Rules     int `json:"rules"`
+}
+
+// Copy imports every campaign and targeting rule of the source into the
+// target, keeping their IDs and timestamps. Campaigns are copied before
+// rules so repositories indexing rules by campaign see the campaign first.
+func (m *Migrator) Copy(ctx context.Context) (*CopyResult, error) {
+	campaigns, err := loadCampaigns(ctx, m.source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source campaigns: %w", err)
+	}
+	if err := importBatches(ctx, m, StageCampaigns, campaigns, m.importer.ImportCampaigns); err != nil {
+		return nil, fmt.Errorf("failed to import campaigns: %w", err)
+	}
+
+	rules, err := loadRules(ctx, m.source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source targeting rules: %w", err)
+	}
+	if err := importBatches(ctx, m, StageRules, rules, m.importer.ImportTargetingRules); err != nil {
+		return nil, fmt.Errorf("failed to import targeting rules: %w", err)
+	}
+	return &CopyResult{Campaigns: len(campaigns), Rules: len(rules)}, nil
+}
+
+// importBatches imports items a batch at a time, reporting progress
+func importBatches[T any](ctx context.Context, m *Migrator, stage string, items []T, fn func(context.Context, []T) error) error {
+	for start := 0; start < len(items); start += m.opts.BatchSize {
+		end := min(start+m.opts.BatchSize, len(items))
+		if err := fn(ctx, items[start:end]); err != nil {
+			return err
+		}
+		if m.opts.Progress != nil {
+			m.opts.Progress(Progress{Stage: stage, Done: end, Total: len(items)})
+		}
+	}
+	return nil
+}
+
+// Verify compares the campaigns and rules of the source and target by
+// checksum
+func (m *Migrator) Verify(ctx context.Context) (*Report, error) {
+	sourceCampaigns, err := loadCampaigns(ctx, m.source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source campaigns: %w", err)
+	}
+	targetCampaigns, err := loadCampaigns(ctx, m.target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target campaigns: %w", err)
+	}
+	sourceRules, err := loadRules(ctx, m.source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source targeting rules: %w", err)
+	}
+	targetRules, err := loadRules(ctx, m.target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target targeting rules: %w", err)
+	}
+
+	campaignID := func(c *model.Campaign) string { return c.ID }
+	ruleID := func(r *model.TargetingRule) string { return strconv.FormatInt(r.ID, 10) }
+	return &Report{
+		Campaigns: compare(checksums(sourceCampaigns, campaignID, campaignChecksum), checksums(targetCampaigns, campaignID, campaignChecksum)),
+		Rules:     compare(checksums(sourceRules, ruleID, ruleChecksum), checksums(targetRules, ruleID, ruleChecksum)),
+	}, nil
+}
+
+// Repair copies the campaigns and rules a report found missing from or
+// different in the target again. With deleteExtra, those only the target
+// holds are deleted from it.
+func (m *Migrator) Repair(ctx context.Context, report *Report, deleteExtra bool) error {
+	var campaigns []*model.Campaign
+	for _, id := range append(slices.Clone(report.Campaigns.Missing), report.Campaigns.Different...) {
+		campaign, err := m.source.Campaign().GetCampaignByID(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			// Deleted since it was verified
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read source campaign: %w", err)
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	if len(campaigns) > 0 {
+		if err := m.importer.ImportCampaigns(ctx, campaigns); err != nil {
+			return fmt.Errorf("failed to import campaigns: %w", err)
+		}
+	}
+
+	var rules []*model.TargetingRule
+	for _, id := range append(slices.Clone(report.Rules.Missing), report.Rules.Different...) {
+		rule, err := m.source.TargetingRule().GetTargetingRuleByID(ctx, parseRuleID(id))
+		if errors.Is(err, repository.ErrNotFound) {
+			// Deleted since it was verified
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read source targeting rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) > 0 {
+		if err := m.importer.ImportTargetingRules(ctx, rules); err != nil {
+			return fmt.Errorf("failed to import targeting rules: %w", err)
+		}
+	}
+
+	if !deleteExtra {
+		return nil
+	}
+	for _, id := range report.Rules.Extra {
+		if err := m.target.TargetingRule().DeleteTargetingRule(ctx, parseRuleID(id)); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to delete targeting rule %s: %w", id, err)
+		}
+	}
+	for _, id := range report.Campaigns.Extra {
+		if err := m.target.Campaign().DeleteCampaign(ctx, id); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to delete campaign %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// parseRuleID parses a rule ID of a report, which are formatted from int64s
+func parseRuleID(id string) int64 {
+	n, _ := strconv.ParseInt(id, 10, 64)
+	return n
+}
+
+// loadCampaigns returns every campaign of a repository, ordered by ID
+func loadCampaigns(ctx context.Context, repo repository.Repository) ([]*model.Campaign, error) {
+	campaigns, err := repo.Campaign().GetCampaignsByStatus(ctx, allStatuses)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(campaigns, func(a, b *model.Campaign) int { return cmp.Compare(a.ID, b.ID) })
+	return campaigns, nil
+}
+
+// loadRules returns every targeting rule of a repository, ordered by ID
+func loadRules(ctx context.Context, repo repository.Repository) ([]*model.TargetingRule, error) {
+	rules, err := repo.TargetingRule().GetTargetingRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(rules, func(a, b *model.TargetingRule) int { return cmp.Compare(a.ID, b.ID) })
+	return rules, nil
+}
@@ -1,39 +1,754 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
 
-// Campaign represents an advertising campaign
+// Campaign represents an advertising campaign.
+//
+// FrequencyCap is the maximum number of impressions per user per day, with
+// zero disabling capping. Matched campaigns are ordered by Priority, highest
+// first, and Weight is the relative chance of being ordered first among
+// campaigns of equal priority (zero counts as one). DailyBudget and
+// TotalBudget cap the impressions served per UTC day and over the campaign's
+// lifetime; zero means unlimited. Campaigns with a daily budget are paced to
+// spread it evenly across the day. AppDailyCap caps the impressions served
+// in each app bundle per UTC day, and MaxDailyApps the number of distinct
+// app bundles served in per UTC day; zero means unlimited. Experiment
+// optionally limits the campaign to a share of users. Deleted campaigns are
+// kept as ARCHIVED, with DeletedAt recording when they were archived.
+// TenantID is the tenant owning the campaign; campaign IDs are unique across
+// tenants. A campaign with Creatives serves one of them per request, chosen
+// by Rotation, instead of its own Image and CTA. StartAt and EndAt bound the
+// campaign's flight: a SCHEDULED campaign becomes ACTIVE once StartAt
+// passes, and a running one COMPLETED once EndAt passes.
 type Campaign struct {
-	ID        string    `bson:"cid" json:"cid"` // Mongo `_id` mapped to ID
-	Name      string    `bson:"name" json:"name"`
-	Image     string    `bson:"img" json:"img"`
-	CTA       string    `bson:"cta" json:"cta"`
-	Status    string    `bson:"status" json:"status"`
-	CreatedAt time.Time `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	ID           string      `bson:"cid" json:"cid"` // Mongo `_id` mapped to ID
+	TenantID     string      `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	Name         string      `bson:"name" json:"name"`
+	Image        string      `bson:"img" json:"img"`
+	CTA          string      `bson:"cta" json:"cta"`
+	Status       string      `bson:"status" json:"status"`
+	FrequencyCap int         `bson:"frequency_cap" json:"frequency_cap"`
+	Priority     int         `bson:"priority" json:"priority"`
+	Weight       int         `bson:"weight" json:"weight"`
+	DailyBudget  int64       `bson:"daily_budget" json:"daily_budget"`
+	TotalBudget  int64       `bson:"total_budget" json:"total_budget"`
+	AppDailyCap  int         `bson:"app_daily_cap" json:"app_daily_cap"`
+	MaxDailyApps int         `bson:"max_daily_apps" json:"max_daily_apps"`
+	Experiment   *Experiment `bson:"experiment,omitempty" json:"experiment,omitempty"`
+	Creatives    []Creative  `bson:"creatives,omitempty" json:"creatives,omitempty"`
+	Rotation     string      `bson:"rotation,omitempty" json:"rotation,omitempty"`
+	StartAt      *time.Time  `bson:"start_at,omitempty" json:"start_at,omitempty"`
+	EndAt        *time.Time  `bson:"end_at,omitempty" json:"end_at,omitempty"`
+	CreatedAt    time.Time   `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time   `bson:"updated_at" json:"updated_at"`
+	DeletedAt    *time.Time  `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	// BlockedCategories are IAB content categories, such as IAB7 or IAB25-3,
+	// the campaign never serves in whatever its targeting rules, for brand
+	// safety. Blocking a category blocks its subcategories too.
+	BlockedCategories []string `bson:"blocked_categories,omitempty" json:"blocked_categories,omitempty"`
+	// ClickURL is the template of the tracking URL clicks on the campaign
+	// lead to. Its macros, such as {CID} or {REQUEST_ID}, are expanded each
+	// time the campaign is served.
+	ClickURL string `bson:"click_url,omitempty" json:"click_url,omitempty"`
 }
 
 //
 
-// TargetingRule represents targeting criteria for campaigns
+// TargetingRule represents targeting criteria for campaigns. Regions are ISO
+// 3166-2 subdivision codes such as US-CA; regions and cities are matched
+// case-insensitively. Shadow rules are evaluated on every delivery and their
+// would-be impact is recorded in metrics, but they never affect results.
+// TenantID is always that of the rule's campaign.
 type TargetingRule struct {
-	ID             int64     `json:"id" db:"id"`
-	CampaignID     string    `json:"campaign_id" db:"campaign_id"`
-	IncludeCountry []string  `json:"include_country" db:"include_country"`
-	ExcludeCountry []string  `json:"exclude_country" db:"exclude_country"`
-	IncludeOS      []string  `json:"include_os" db:"include_os"`
-	ExcludeOS      []string  `json:"exclude_os" db:"exclude_os"`
-	IncludeApp     []string  `json:"include_app" db:"include_app"`
-	ExcludeApp     []string  `json:"exclude_app" db:"exclude_app"`
-	CreatedAt      time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
-}
-
-// DeliveryRequest represents the incoming request parameters
+	ID                int64    `bson:"id" json:"id" db:"id"`
+	CampaignID        string   `bson:"campaign_id" json:"campaign_id" db:"campaign_id"`
+	TenantID          string   `bson:"tenant_id,omitempty" json:"tenant_id,omitempty" db:"tenant_id"`
+	IncludeCountry    []string `bson:"include_country" json:"include_country" db:"include_country"`
+	ExcludeCountry    []string `bson:"exclude_country" json:"exclude_country" db:"exclude_country"`
+	IncludeOS         []string `bson:"include_os" json:"include_os" db:"include_os"`
+	ExcludeOS         []string `bson:"exclude_os" json:"exclude_os" db:"exclude_os"`
+	IncludeApp        []string `bson:"include_app" json:"include_app" db:"include_app"`
+	ExcludeApp        []string `bson:"exclude_app" json:"exclude_app" db:"exclude_app"`
+	IncludeDeviceType []string `bson:"include_device_type" json:"include_device_type" db:"include_device_type"`
+	ExcludeDeviceType []string `bson:"exclude_device_type" json:"exclude_device_type" db:"exclude_device_type"`
+	IncludeRegion     []string `bson:"include_region,omitempty" json:"include_region,omitempty" db:"include_region"`
+	ExcludeRegion     []string `bson:"exclude_region,omitempty" json:"exclude_region,omitempty" db:"exclude_region"`
+	IncludeCity       []string `bson:"include_city,omitempty" json:"include_city,omitempty" db:"include_city"`
+	ExcludeCity       []string `bson:"exclude_city,omitempty" json:"exclude_city,omitempty" db:"exclude_city"`
+	// IncludeSegment and ExcludeSegment target the audience segments the
+	// requesting user belongs to, looked up by user_id from the configured
+	// segment provider. A user matches when any of their segments is
+	// included, and is kept out when any is excluded.
+	IncludeSegment []string `bson:"include_segment,omitempty" json:"include_segment,omitempty" db:"include_segment"`
+	ExcludeSegment []string `bson:"exclude_segment,omitempty" json:"exclude_segment,omitempty" db:"exclude_segment"`
+	// Operators maps a dimension name (country, os, app, device_type, region,
+	// city or a custom dimension) to the operator used for its include and
+	// exclude values. Dimensions not listed use exact matching.
+	Operators map[string]string `bson:"operators,omitempty" json:"operators,omitempty" db:"operators"`
+	// Custom holds the include and exclude lists of custom dimensions,
+	// keyed by a dimension name registered in the dimensions config
+	Custom map[string]DimensionValues `bson:"custom,omitempty" json:"custom,omitempty" db:"custom"`
+	// AppVersion optionally limits the rule to app versions satisfying a
+	// semantic version constraint such as ">=2.3.0 <3.0.0". Requests without
+	// an app version don't match it.
+	AppVersion string `bson:"app_version,omitempty" json:"app_version,omitempty" db:"app_version"`
+	// Schedule optionally limits the rule to certain days and hours
+	Schedule *Schedule `bson:"schedule,omitempty" json:"schedule,omitempty" db:"schedule"`
+	// EffectiveFrom and EffectiveUntil optionally stage the rule ahead of
+	// time: it applies from EffectiveFrom on and stops applying at
+	// EffectiveUntil. Either may be left out.
+	EffectiveFrom  *time.Time `bson:"effective_from,omitempty" json:"effective_from,omitempty" db:"effective_from"`
+	EffectiveUntil *time.Time `bson:"effective_until,omitempty" json:"effective_until,omitempty" db:"effective_until"`
+	// Expression optionally adds a boolean expression over dimensions, such
+	// as (country=US AND os=android) OR app=com.x. It is ANDed with the
+	// include and exclude lists.
+	Expression *Expression `bson:"expression,omitempty" json:"expression,omitempty" db:"expression"`
+	Shadow     bool        `bson:"shadow,omitempty" json:"shadow,omitempty" db:"shadow"`
+	CreatedAt  time.Time   `bson:"created_at" json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time   `bson:"updated_at" json:"updated_at" db:"updated_at"`
+}
+
+// InEffect reports whether at falls within the rule's effective window
+func (r *TargetingRule) InEffect(at time.Time) bool {
+	if r.EffectiveFrom != nil && at.Before(*r.EffectiveFrom) {
+		return false
+	}
+	return r.EffectiveUntil == nil || at.Before(*r.EffectiveUntil)
+}
+
+// DimensionLists returns the include and exclude lists the rule holds for
+// the built-in or custom dimension name
+func (r *TargetingRule) DimensionLists(name string) (include, exclude []string) {
+	switch name {
+	case "country":
+		return r.IncludeCountry, r.ExcludeCountry
+	case "os":
+		return r.IncludeOS, r.ExcludeOS
+	case "app":
+		return r.IncludeApp, r.ExcludeApp
+	case "device_type":
+		return r.IncludeDeviceType, r.ExcludeDeviceType
+	case "region":
+		return r.IncludeRegion, r.ExcludeRegion
+	case "city":
+		return r.IncludeCity, r.ExcludeCity
+	}
+	values := r.Custom[name]
+	return values.Include, values.Exclude
+}
+
+// DimensionValues holds the include and exclude lists of a custom dimension
+type DimensionValues struct {
+	Include []string `bson:"include,omitempty" json:"include,omitempty"`
+	Exclude []string `bson:"exclude,omitempty" json:"exclude,omitempty"`
+}
+
+// Expression is a node of a boolean targeting expression. Exactly one of
+// And, Or, Not and Dimension is set: And and Or combine their children, Not
+// negates its child, and a leaf matches when the request value of Dimension
+// matches any of Values using Operator (exact when empty).
+type Expression struct {
+	And       []*Expression `bson:"and,omitempty" json:"and,omitempty"`
+	Or        []*Expression `bson:"or,omitempty" json:"or,omitempty"`
+	Not       *Expression   `bson:"not,omitempty" json:"not,omitempty"`
+	Dimension string        `bson:"dimension,omitempty" json:"dimension,omitempty"`
+	Operator  string        `bson:"operator,omitempty" json:"operator,omitempty"`
+	Values    []string      `bson:"values,omitempty" json:"values,omitempty"`
+}
+
+// String renders the expression in a readable form such as
+// (country in [US] AND os in [android]) OR app in [com.x]
+func (e *Expression) String() string {
+	if e == nil {
+		return ""
+	}
+	join := func(children []*Expression, op string) string {
+		parts := make([]string, len(children))
+		for i, child := range children {
+			parts[i] = child.String()
+			if len(child.And) > 0 || len(child.Or) > 0 {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		}
+		return strings.Join(parts, " "+op+" ")
+	}
+	switch {
+	case len(e.And) > 0:
+		return join(e.And, "AND")
+	case len(e.Or) > 0:
+		return join(e.Or, "OR")
+	case e.Not != nil:
+		return "NOT " + join([]*Expression{e.Not}, "")
+	}
+	operator := "in"
+	if e.Operator != "" && e.Operator != OperatorExact {
+		operator = e.Operator
+	}
+	return fmt.Sprintf("%s %s [%s]", e.Dimension, operator, strings.Join(e.Values, ", "))
+}
+
+// Schedule restricts a targeting rule to certain days and hours. Weekdays
+// holds three-letter lower-case day names (mon to sun) and Hours the allowed
+// hour ranges; an empty list allows every day or hour. Both are evaluated in
+// Timezone, an IANA zone name defaulting to UTC.
+type Schedule struct {
+	Weekdays []string    `bson:"weekdays,omitempty" json:"weekdays,omitempty"`
+	Hours    []HourRange `bson:"hours,omitempty" json:"hours,omitempty"`
+	Timezone string      `bson:"timezone,omitempty" json:"timezone,omitempty"`
+}
+
+// HourRange allows the hours from Start up to but excluding End, 0 to 24. A
+// range with Start after End wraps past midnight, e.g. 22 to 6.
+type HourRange struct {
+	Start int `bson:"start" json:"start"`
+	End   int `bson:"end" json:"end"`
+}
+
+// Experiment serves a campaign to TrafficPercent of users only. Users are
+// bucketed by a hash of the experiment name and the request value named by
+// BucketKey, so each user is consistently in or out of the experiment.
+// Requests without a value for the bucket key are left out unless the
+// experiment covers all traffic.
+type Experiment struct {
+	Name           string `bson:"name" json:"name"`
+	TrafficPercent int    `bson:"traffic_percent" json:"traffic_percent" validate:"min=0,max=100"`
+	BucketKey      string `bson:"bucket_key" json:"bucket_key" validate:"omitempty,oneof=user_id"`
+}
+
+// Creative is one image and call to action a campaign can serve. Weight is
+// its relative share under weighted rotation; zero counts as one.
+type Creative struct {
+	ID     string `bson:"id" json:"id" validate:"required,max=64"`
+	Image  string `bson:"img" json:"img" validate:"required"`
+	CTA    string `bson:"cta" json:"cta" validate:"required"`
+	Weight int    `bson:"weight,omitempty" json:"weight,omitempty" validate:"min=0"`
+}
+
+// ExperimentAssignment reports the experiment a delivered campaign is part
+// of and the bucket, 0 to 99, the user was assigned to
+type ExperimentAssignment struct {
+	Name   string `json:"name"`
+	Bucket int    `json:"bucket"`
+}
+
+// DeliveryRequest represents the incoming request parameters. Region is an
+// ISO 3166-2 subdivision code; a bare subdivision such as CA is prefixed with
+// the two-letter country.
 type DeliveryRequest struct {
-	OS      string `json:"os" validate:"required,oneof=android ios"`
-	Country string `json:"country" validate:"required"`
-	App     string `json:"app" validate:"required"`
+	OS         string `json:"os" validate:"required,oneof=android ios"`
+	Country    string `json:"country" validate:"required"`
+	App        string `json:"app" validate:"required"`
+	DeviceType string `json:"device_type" validate:"omitempty,oneofci=phone tablet ctv"`
+	Region     string `json:"region" validate:"omitempty,max=16"`
+	City       string `json:"city" validate:"omitempty,max=128"`
+	UserID     string `json:"user_id" validate:"omitempty,max=128"`
+	AppVersion string `json:"app_version" validate:"omitempty,max=64"`  // semantic version of the requesting app
+	Limit      int    `json:"limit" validate:"omitempty,min=1,max=100"` // zero returns all matches
+	// Custom carries values for custom dimensions keyed by name. Names that
+	// aren't registered are ignored.
+	Custom map[string]string `json:"custom,omitempty" validate:"omitempty,max=32,dive,keys,max=64,endkeys,max=256"`
+	// Categories are the IAB content categories of the placement, such as
+	// IAB9-30; campaigns blocking any of them aren't served
+	Categories []string `json:"categories,omitempty" validate:"omitempty,max=32,dive,required,max=64"`
+	// Segments are the segments of the user, resolved by the service from
+	// UserID rather than sent by clients
+	Segments []string `json:"-"`
+	// Countries, OSes, Apps, DeviceTypes, Regions and Cities carry further
+	// values of their dimension for requests that have several, such as a
+	// household seen in two countries behind a VPN. A rule includes the
+	// request when it includes any of the values and excludes it when it
+	// excludes any. Each holds at most 7 values, 8 in all with the first.
+	Countries   []string `json:"countries,omitempty" validate:"omitempty,max=7,dive,required,max=64"`
+	OSes        []string `json:"oses,omitempty" validate:"omitempty,max=7,dive,oneof=android ios"`
+	Apps        []string `json:"apps,omitempty" validate:"omitempty,max=7,dive,required,max=256"`
+	DeviceTypes []string `json:"device_types,omitempty" validate:"omitempty,max=7,dive,oneofci=phone tablet ctv"`
+	Regions     []string `json:"regions,omitempty" validate:"omitempty,max=7,dive,required,max=16"`
+	Cities      []string `json:"cities,omitempty" validate:"omitempty,max=7,dive,required,max=128"`
+}
+
+// DeliveryDimensions lists the built-in dimensions a delivery request may
+// carry several values of, which rules target with include and exclude lists
+var DeliveryDimensions = []string{"country", "os", "app", "device_type", "region", "city"}
+
+// Names of the request dimensions that aren't delivery dimensions
+const (
+	// AppVersionDimension names the request's app version, which rules
+	// constrain with a version range rather than include and exclude lists
+	AppVersionDimension = "app_version"
+	// SegmentDimension names the segments of the requesting user. Unlike
+	// other dimensions a request carries it once per segment.
+	SegmentDimension = "segment"
+)
+
+// dimensionFields returns the fields holding the first and further values
+// of the delivery dimension name, or nil for other names
+func (r *DeliveryRequest) dimensionFields(name string) (*string, *[]string) {
+	switch name {
+	case "country":
+		return &r.Country, &r.Countries
+	case "os":
+		return &r.OS, &r.OSes
+	case "app":
+		return &r.App, &r.Apps
+	case "device_type":
+		return &r.DeviceType, &r.DeviceTypes
+	case "region":
+		return &r.Region, &r.Regions
+	case "city":
+		return &r.City, &r.Cities
+	}
+	return nil, nil
+}
+
+// Values returns the values the request carries of the delivery dimension
+// name, the first value first, or nil when it carries none
+func (r *DeliveryRequest) Values(name string) []string {
+	first, further := r.dimensionFields(name)
+	if first == nil || (*first == "" && len(*further) == 0) {
+		return nil
+	}
+	return append([]string{*first}, *further...)
+}
+
+// SetValues replaces the values of the delivery dimension name with values,
+// the first of which becomes its first value
+func (r *DeliveryRequest) SetValues(name string, values []string) {
+	first, further := r.dimensionFields(name)
+	if first == nil {
+		return
+	}
+	*first, *further = "", nil
+	if len(values) > 0 {
+		*first = values[0]
+	}
+	if len(values) > 1 {
+		*further = values[1:]
+	}
+}
+
+// Dimensions returns the targeting dimensions the request carries: every
+// value of the delivery dimensions, then its app version, its custom
+// dimensions by name and its user segments. Empty values are left out.
+func (r *DeliveryRequest) Dimensions() []Dimension {
+	var dimensions []Dimension
+	add := func(name string, values ...string) {
+		for _, value := range values {
+			if value != "" {
+				dimensions = append(dimensions, Dimension{Name: name, Value: value})
+			}
+		}
+	}
+	for _, name := range DeliveryDimensions {
+		add(name, r.Values(name)...)
+	}
+	add(AppVersionDimension, r.AppVersion)
+	names := make([]string, 0, len(r.Custom))
+	for name := range r.Custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(name, r.Custom[name])
+	}
+	add(SegmentDimension, r.Segments...)
+	return dimensions
+}
+
+// BatchDeliveryRequest asks for the campaigns of several placements, such as
+// different apps of the same user, in one call. UserID applies to every
+// request that doesn't carry its own.
+type BatchDeliveryRequest struct {
+	UserID   string             `json:"user_id,omitempty"`
+	Requests []*DeliveryRequest `json:"requests"`
+}
+
+// BatchDeliveryResult is the outcome of one request of a batch. Campaigns is
+// empty when nothing matched or the request failed, in which case Error says
+// why and Fields lists its invalid fields.
+type BatchDeliveryResult struct {
+	Campaigns []*DeliveryResponse `json:"campaigns"`
+	Error     string              `json:"error,omitempty"`
+	Fields    []FieldError        `json:"fields,omitempty"`
+}
+
+// BatchDeliveryResponse holds the results of a batch in request order
+type BatchDeliveryResponse struct {
+	Results []*BatchDeliveryResult `json:"results"`
+}
+
+// CampaignRequest represents the payload for creating or updating a campaign.
+// On update, empty fields leave the existing value unchanged.
+type CampaignRequest struct {
+	ID           string `json:"cid"`
+	Name         string `json:"name"`
+	Image        string `json:"img"`
+	CTA          string `json:"cta"`
+	Status       string `json:"status" validate:"omitempty,oneof=DRAFT SCHEDULED ACTIVE PAUSED COMPLETED INACTIVE"`
+	FrequencyCap *int   `json:"frequency_cap" validate:"omitempty,min=0"`
+	Priority     *int   `json:"priority"`
+	Weight       *int   `json:"weight" validate:"omitempty,min=0"`
+	DailyBudget  *int64 `json:"daily_budget" validate:"omitempty,min=0"`
+	TotalBudget  *int64 `json:"total_budget" validate:"omitempty,min=0"`
+	AppDailyCap  *int   `json:"app_daily_cap" validate:"omitempty,min=0"`
+	MaxDailyApps *int   `json:"max_daily_apps" validate:"omitempty,min=0"`
+	// Experiment sets the campaign's experiment; one without a name removes it
+	Experiment *Experiment `json:"experiment"`
+	// Creatives replaces the campaign's creatives; an empty list removes them
+	Creatives []Creative `json:"creatives" validate:"omitempty,max=50,dive"`
+	Rotation  string     `json:"rotation" validate:"omitempty,oneof=round_robin weighted random"`
+	StartAt   *time.Time `json:"start_at"`
+	EndAt     *time.Time `json:"end_at"`
+	// BlockedCategories replaces the campaign's blocked content categories;
+	// an empty list removes them
+	BlockedCategories []string `json:"blocked_categories" validate:"omitempty,max=100,dive,required,max=64"`
+	ClickURL          string   `json:"click_url" validate:"omitempty,max=2048"`
+}
+
+// CampaignStatusRequest is the payload for moving a campaign to another
+// status
+type CampaignStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// BulkCampaignRequest represents one campaign in a bulk import, together
+// with the targeting rules to create for it
+type BulkCampaignRequest struct {
+	CampaignRequest
+	Rules []*TargetingRule `json:"rules"`
+}
+
+// BulkCampaignResult reports the outcome of importing a single campaign
+type BulkCampaignResult struct {
+	CID     string `json:"cid"`
+	Success bool   `json:"success"`
+	// Action is "created" or "updated" for a successful import upsert
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Import actions reported in BulkCampaignResult
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+)
+
+// CampaignExport is one campaign together with its targeting rules, as
+// written by the export endpoint. It decodes back into a BulkCampaignRequest
+// for import.
+type CampaignExport struct {
+	*Campaign
+	Rules []*TargetingRule `json:"rules"`
+}
+
+// CampaignList is a page of campaigns from the admin listing endpoint. Total
+// counts every campaign matching the filters, not just this page.
+type CampaignList struct {
+	Campaigns []*Campaign `json:"campaigns"`
+	Total     int64       `json:"total"`
+	Limit     int         `json:"limit"`
+	Offset    int         `json:"offset"`
+}
+
+// CampaignClone is a campaign created by cloning another, with the copies
+// of its targeting rules
+type CampaignClone struct {
+	Campaign *Campaign        `json:"campaign"`
+	Rules    []*TargetingRule `json:"rules"`
+}
+
+// Tracked event types
+const (
+	TrackImpression = "impression"
+	TrackClick      = "click"
+)
+
+// TrackingEvent is an impression or click reported for a served campaign.
+// RequestID is the X-Request-ID of the delivery that served it; events
+// repeating the request ID of one already tracked aren't counted again. App
+// is the app bundle the campaign was shown in, for campaigns capped per app.
+type TrackingEvent struct {
+	Type       string `json:"type"`
+	CampaignID string `json:"campaign_id"`
+	RequestID  string `json:"request_id,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	App        string `json:"app,omitempty"`
+}
+
+// TrackingCounts counts the impressions and clicks tracked for a campaign
+type TrackingCounts struct {
+	Impressions      int64   `json:"impressions"`
+	Clicks           int64   `json:"clicks"`
+	ClickThroughRate float64 `json:"click_through_rate"`
+}
+
+// CampaignTracking holds the impressions and clicks tracked for a campaign
+// on a UTC day (YYYYMMDD) and over its lifetime
+type CampaignTracking struct {
+	CID   string         `json:"cid"`
+	Day   string         `json:"day"`
+	Daily TrackingCounts `json:"daily"`
+	Total TrackingCounts `json:"total"`
+}
+
+// CampaignView is everything admin UIs show about a campaign in one
+// response: the campaign with its targeting rules, its status and whether it
+// is being served, its flight, how often it was served over the last days,
+// the impressions and clicks tracked for it, and its latest changes, newest
+// first. Served is nil while campaign stats are disabled.
+type CampaignView struct {
+	Campaign      *Campaign              `json:"campaign"`
+	Rules         []*TargetingRule       `json:"rules"`
+	Status        CampaignServingStatus  `json:"status"`
+	Schedule      CampaignSchedule       `json:"schedule"`
+	Served        *CampaignDeliveryStats `json:"served,omitempty"`
+	Tracking      *CampaignTracking      `json:"tracking"`
+	RecentChanges []*CampaignChange      `json:"recent_changes"`
+}
+
+// CampaignServingStatus is the status of a campaign and whether it is being
+// served right now, with the reason when it isn't
+type CampaignServingStatus struct {
+	Status  string `json:"status"`
+	Serving bool   `json:"serving"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// CampaignSchedule is the flight of a campaign and the phase of it now
+type CampaignSchedule struct {
+	StartAt *time.Time `json:"start_at,omitempty"`
+	EndAt   *time.Time `json:"end_at,omitempty"`
+	Phase   string     `json:"phase"`
+}
+
+// Flight phases reported in CampaignSchedule
+const (
+	FlightUpcoming = "upcoming"
+	FlightRunning  = "running"
+	FlightEnded    = "ended"
+)
+
+// ScoredCampaign is a campaign found by a search, with the relevance of its
+// name and CTA to the query. Scores only compare campaigns of one search.
+type ScoredCampaign struct {
+	*Campaign
+	Score float64 `json:"score"`
+}
+
+// CampaignSearchResult is a page of campaigns matching a search query, most
+// relevant first. Total counts every matching campaign, not just this page.
+type CampaignSearchResult struct {
+	Query     string            `json:"query"`
+	Campaigns []*ScoredCampaign `json:"campaigns"`
+	Total     int64             `json:"total"`
+	Limit     int               `json:"limit"`
+	Offset    int               `json:"offset"`
+}
+
+// CacheRefreshResult reports a manually triggered reload of the targeting
+// cache
+type CacheRefreshResult struct {
+	Campaigns      int       `json:"campaigns"`
+	TargetingRules int       `json:"targeting_rules"`
+	DurationMS     int64     `json:"duration_ms"`
+	RefreshedAt    time.Time `json:"refreshed_at"`
+}
+
+// KillSwitch stops serving campaigns while it is engaged: those of every
+// tenant when Global is set, otherwise those of TenantID. EngagedBy names
+// the caller that engaged it, when known.
+type KillSwitch struct {
+	Global    bool      `bson:"global" json:"global"`
+	TenantID  string    `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	Reason    string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	EngagedBy string    `bson:"engaged_by,omitempty" json:"engaged_by,omitempty"`
+	EngagedAt time.Time `bson:"engaged_at" json:"engaged_at"`
+}
+
+// Scope identifies the campaigns a kill switch stops: "*" for every tenant,
+// otherwise "tenant:" followed by the tenant ID
+func (k *KillSwitch) Scope() string {
+	return KillSwitchScope(k.Global, k.TenantID)
+}
+
+// KillSwitchScope returns the Scope of a kill switch
+func KillSwitchScope(global bool, tenantID string) string {
+	if global {
+		return "*"
+	}
+	return "tenant:" + tenantID
+}
+
+// KillSwitchRequest engages or releases the kill switch of every tenant
+// when Global is set, otherwise that of the tenant the request acts for
+type KillSwitchRequest struct {
+	Global bool   `json:"global"`
+	Reason string `json:"reason" validate:"omitempty,max=512"`
+}
+
+// KillSwitchList lists the engaged kill switches
+type KillSwitchList struct {
+	KillSwitches []*KillSwitch `json:"kill_switches"`
+}
+
+// Types of CampaignChange
+const (
+	CampaignCreated = "created"
+	CampaignUpdated = "updated"
+	CampaignPaused  = "paused"
+	CampaignDeleted = "deleted"
+	// CampaignsReset tells subscribers that changes were missed, so every
+	// campaign they hold may be out of date
+	CampaignsReset = "reset"
+)
+
+// CampaignChange notifies subscribers of /v1/stream that a campaign changed.
+// A campaign left paused is reported as paused, an archived one as deleted,
+// and changes to a campaign's targeting rules as an update of it.
+type CampaignChange struct {
+	ID         uint64    `json:"id"`
+	Type       string    `json:"type"`
+	CampaignID string    `json:"campaign_id,omitempty"`
+	TenantID   string    `json:"tenant_id,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// Decision is a delivery decision kept in the decision log: the dimensions
+// of a served request as they were matched, the campaigns served for it, how
+// long matching took and whether the matches came from the query cache.
+// RequestID is the X-Request-ID of the request; the requests of a batch
+// share it. Decisions are removed once ExpiresAt passes.
+type Decision struct {
+	RequestID   string            `bson:"request_id" json:"request_id"`
+	TenantID    string            `bson:"tenant_id" json:"tenant_id,omitempty"`
+	At          time.Time         `bson:"at" json:"at"`
+	App         string            `bson:"app" json:"app"`
+	Country     string            `bson:"country" json:"country"`
+	OS          string            `bson:"os" json:"os"`
+	DeviceType  string            `bson:"device_type,omitempty" json:"device_type,omitempty"`
+	Region      string            `bson:"region,omitempty" json:"region,omitempty"`
+	City        string            `bson:"city,omitempty" json:"city,omitempty"`
+	AppVersion  string            `bson:"app_version,omitempty" json:"app_version,omitempty"`
+	Categories  []string          `bson:"categories,omitempty" json:"categories,omitempty"`
+	Custom      map[string]string `bson:"custom,omitempty" json:"custom,omitempty"`
+	CampaignIDs []string          `bson:"campaign_ids" json:"campaign_ids"`
+	LatencyMs   float64           `bson:"latency_ms" json:"latency_ms"`
+	CacheHit    bool              `bson:"cache_hit" json:"cache_hit"`
+	ExpiresAt   time.Time         `bson:"expires_at" json:"expires_at"`
+}
+
+// DecisionList lists logged delivery decisions, newest first
+type DecisionList struct {
+	Decisions []*Decision `json:"decisions"`
+}
+
+// HealthDetail reports the state of the process and its dependencies.
+// Status is ok, or degraded when a dependency fails or the targeting cache
+// hasn't been loaded.
+type HealthDetail struct {
+	Status        string                      `json:"status"`
+	Timestamp     time.Time                   `json:"timestamp"`
+	UptimeSeconds float64                     `json:"uptime_seconds"`
+	Goroutines    int                         `json:"goroutines"`
+	Build         BuildInfo                   `json:"build"`
+	Dependencies  map[string]DependencyHealth `json:"dependencies"`
+	Cache         CacheHealth                 `json:"cache"`
+}
+
+// BuildInfo identifies the running build
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// DependencyHealth reports whether a dependency answered a ping and how
+// long it took
+type DependencyHealth struct {
+	Status         string  `json:"status"`
+	LatencyMS      float64 `json:"latency_ms"`
+	Error          string  `json:"error,omitempty"`
+	CircuitBreaker string  `json:"circuit_breaker,omitempty"`
+}
+
+// CacheHealth reports the state of the targeting cache. AgeSeconds is the
+// time since it was last loaded or updated, and LastRefreshError the error
+// of the last full reload, cleared once one succeeds.
+type CacheHealth struct {
+	Loaded           bool       `json:"loaded"`
+	Campaigns        int        `json:"campaigns"`
+	TargetingRules   int        `json:"targeting_rules"`
+	LastRefresh      *time.Time `json:"last_refresh,omitempty"`
+	AgeSeconds       float64    `json:"age_seconds"`
+	StaleSeconds     float64    `json:"stale_seconds,omitempty"`
+	LastRefreshError string     `json:"last_refresh_error,omitempty"`
+}
+
+// TrafficStats summarizes delivery traffic over the stats window
+type TrafficStats struct {
+	WindowSeconds     float64 `json:"window_seconds"`
+	Requests          int64   `json:"requests"`
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	// MatchRate is the share of requests served at least one campaign
+	MatchRate float64 `json:"match_rate"`
+	// LatencyMS holds matcher latency percentiles in milliseconds
+	LatencyMS     LatencyPercentiles        `json:"latency_ms"`
+	CacheHitRatio float64                   `json:"cache_hit_ratio"`
+	Countries     map[string]DimensionStats `json:"countries"`
+	OS            map[string]DimensionStats `json:"os"`
+	TopCampaigns  []CampaignServed          `json:"top_campaigns"`
+	// Impressions and Clicks count the events reported to the tracking
+	// endpoints
+	Impressions      int64   `json:"impressions"`
+	Clicks           int64   `json:"clicks"`
+	ClickThroughRate float64 `json:"click_through_rate"`
+}
+
+// CampaignDeliveryStats counts how often a campaign was served over a range
+// of days, in total, by UTC day (YYYYMMDD) and, when delivery counts are
+// broken down by them, by country and OS
+type CampaignDeliveryStats struct {
+	CID       string           `json:"cid"`
+	Served    int64            `json:"served"`
+	Days      map[string]int64 `json:"days"`
+	Countries map[string]int64 `json:"countries,omitempty"`
+	OS        map[string]int64 `json:"os,omitempty"`
+}
+
+// CampaignStatsReport holds the delivery counts of campaigns from day From
+// through day To, most served first
+type CampaignStatsReport struct {
+	From      string                   `json:"from"`
+	To        string                   `json:"to"`
+	Campaigns []*CampaignDeliveryStats `json:"campaigns"`
+}
+
+// LatencyPercentiles holds latency percentiles in milliseconds
+type LatencyPercentiles struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+// DimensionStats counts the requests carrying one dimension value
+type DimensionStats struct {
+	Requests  int64   `json:"requests"`
+	MatchRate float64 `json:"match_rate"`
+}
+
+// CampaignServed counts how often a campaign was served, and the
+// impressions and clicks tracked for it
+type CampaignServed struct {
+	CID         string `json:"cid"`
+	Served      int64  `json:"served"`
+	Impressions int64  `json:"impressions"`
+	Clicks      int64  `json:"clicks"`
 }
 
 // DeliveryResponse represents the response for matching campaigns
@@ -41,36 +756,313 @@ type DeliveryResponse struct {
 	CID   string `json:"cid"`
 	Image string `json:"img"`
 	CTA   string `json:"cta"`
+	// Experiment is set when the campaign was served as part of an experiment
+	Experiment *ExperimentAssignment `json:"experiment,omitempty"`
+	// Creative is the ID of the creative served when the campaign has several
+	Creative string `json:"creative,omitempty"`
+	// ClickURL is the campaign's tracking URL with its macros expanded for
+	// the request
+	ClickURL string `json:"click_url,omitempty"`
+	// TrackingToken authenticates the impressions and clicks reported for
+	// the campaign served to this request
+	TrackingToken string `json:"tracking_token,omitempty"`
+
+	frequencyCap int
+	priority     int
+	weight       int
+	dailyBudget  int64
+	totalBudget  int64
+	appDailyCap  int
+	maxDailyApps int
+	experiment   *Experiment
+	creatives    []Creative
+	rotation     string
+	clickURL     string
+	updatedAt    time.Time
+}
+
+// CampaignExplanation describes how a delivery request was evaluated against
+// one campaign's targeting rules
+type CampaignExplanation struct {
+	CID     string            `json:"cid"`
+	Status  string            `json:"status"`
+	Matched bool              `json:"matched"`
+	Reason  string            `json:"reason,omitempty"`
+	Rules   []RuleExplanation `json:"rules"`
 }
 
+// RuleExplanation describes the outcome of a single targeting rule. When the
+// rule fails, Dimension names the first dimension it failed on. Shadow rules
+// are reported but don't count towards the campaign matching.
+type RuleExplanation struct {
+	RuleID    int64  `json:"rule_id"`
+	Matched   bool   `json:"matched"`
+	Shadow    bool   `json:"shadow,omitempty"`
+	Dimension string `json:"dimension,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Dimension is one value of a dimension carried by a delivery request.
+// Requests are matched as a slice of them, which holds a dimension once per
+// value.
 type Dimension struct {
 	Name  string
 	Value string
 }
 
-// ErrorResponse represents error response structure
+// ErrorResponse represents error response structure. Fields lists the
+// invalid fields of a payload that failed validation, Conflicts the
+// conflicts a targeting rule was rejected for.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
-	Code    int    `json:"code,omitempty"`
+	Error     string         `json:"error"`
+	Message   string         `json:"message,omitempty"`
+	Code      int            `json:"code,omitempty"`
+	Fields    []FieldError   `json:"fields,omitempty"`
+	Conflicts []RuleConflict `json:"conflicts,omitempty"`
+}
+
+// FieldError describes why a single field or query parameter is invalid.
+// Field is the JSON name, with the path to nested fields such as
+// creatives[0].cta; Allowed lists the accepted values when there is a fixed
+// set of them.
+type FieldError struct {
+	Field   string   `json:"field"`
+	Reason  string   `json:"reason"`
+	Allowed []string `json:"allowed,omitempty"`
+}
+
+// RuleConflict is a problem found by analyzing a targeting rule on its own and
+// against the other rules of its campaign. Dimension and Values locate it
+// within the rule, and RuleID names the other rule involved, if any.
+type RuleConflict struct {
+	Severity  string   `json:"severity"`
+	Kind      string   `json:"kind"`
+	Dimension string   `json:"dimension,omitempty"`
+	Values    []string `json:"values,omitempty"`
+	RuleID    int64    `json:"rule_id,omitempty"`
+	Message   string   `json:"message"`
+}
+
+// RuleAnalysis is the conflict report of a targeting rule
+type RuleAnalysis struct {
+	Conflicts []RuleConflict `json:"conflicts"`
+}
+
+// ReachRequest is a proposed set of targeting rules to estimate the reach
+// of. As with the rules of a campaign, a request matches when any rule does.
+type ReachRequest struct {
+	Rules []*TargetingRule `json:"rules"`
+}
+
+// ReachEstimate is the approximate reach of a set of targeting rules: how
+// many of the delivery requests recorded over the window they would have
+// matched. RuleReach holds the reach of each rule on its own, in request
+// order, and Share is Reach over Requests. Untracked requests weren't
+// recorded because the frequency tables were full.
+type ReachEstimate struct {
+	Reach         int64   `json:"reach"`
+	RuleReach     []int64 `json:"rule_reach"`
+	Requests      int64   `json:"requests"`
+	Share         float64 `json:"share"`
+	Combinations  int     `json:"combinations"`
+	Untracked     int64   `json:"untracked"`
+	WindowSeconds float64 `json:"window_seconds"`
 }
 
-// CampaignStatus constants
+// Severities of rule conflicts. Rules with errors are rejected; warnings
+// point at conditions that have no effect.
 const (
-	StatusActive   = "ACTIVE"
+	ConflictError   = "error"
+	ConflictWarning = "warning"
+)
+
+// Kinds of rule conflicts
+const (
+	// ConflictEmptyInclude: every included value is also excluded, so the
+	// rule never matches
+	ConflictEmptyInclude = "empty_include"
+	// ConflictIncludeExcluded: some values are both included and excluded
+	ConflictIncludeExcluded = "include_excluded"
+	// ConflictRedundantExclude: excluded values the include list already
+	// leaves out
+	ConflictRedundantExclude = "redundant_exclude"
+	// ConflictDuplicate: another rule has the same conditions
+	ConflictDuplicate = "duplicate"
+	// ConflictCovered: another rule matches every request this one does
+	ConflictCovered = "covered"
+	// ConflictCovers: this rule matches every request another one does
+	ConflictCovers = "covers"
+)
+
+// CampaignStatus constants. Campaigns move through DRAFT, SCHEDULED, ACTIVE,
+// PAUSED and COMPLETED; only ACTIVE campaigns are delivered. Deleted
+// campaigns are kept as ARCHIVED.
+const (
+	StatusDraft     = "DRAFT"
+	StatusScheduled = "SCHEDULED"
+	StatusActive    = "ACTIVE"
+	StatusPaused    = "PAUSED"
+	StatusCompleted = "COMPLETED"
+	StatusArchived  = "ARCHIVED"
+	// StatusInactive is how earlier versions stored paused campaigns. It is
+	// still accepted and treated as PAUSED.
 	StatusInactive = "INACTIVE"
 )
 
+// Creative rotation strategies
+const (
+	RotationRoundRobin = "round_robin"
+	RotationWeighted   = "weighted"
+	RotationRandom     = "random"
+)
+
+// Strategies for picking the single campaign served by /v1/delivery/select
+const (
+	// SelectionPriorityWeight picks among the highest priority campaigns with
+	// a probability proportional to their weight, like delivery ordering
+	SelectionPriorityWeight = "priority_weight"
+	// SelectionRandom picks any matched campaign with equal probability
+	SelectionRandom = "random"
+	// SelectionRoundRobin cycles through the matched campaigns of each
+	// distinct request
+	SelectionRoundRobin = "round_robin"
+)
+
+// Match operators for targeting rule values
+const (
+	OperatorExact    = "exact"
+	OperatorPrefix   = "prefix"
+	OperatorSuffix   = "suffix"
+	OperatorRegex    = "regex"
+	OperatorWildcard = "wildcard"
+)
+
+// DeviceType constants
+const (
+	DeviceTypePhone  = "phone"
+	DeviceTypeTablet = "tablet"
+	DeviceTypeCTV    = "ctv"
+)
+
 // IsActive checks if the campaign is active
 func (c *Campaign) IsActive() bool {
 	return c.Status == StatusActive
 }
 
+// IsPaused checks if the campaign has been paused
+func (c *Campaign) IsPaused() bool {
+	return c.Status == StatusPaused || c.Status == StatusInactive
+}
+
+// InFlight reports whether t falls within the campaign's flight dates
+func (c *Campaign) InFlight(t time.Time) bool {
+	if c.StartAt != nil && t.Before(*c.StartAt) {
+		return false
+	}
+	return c.EndAt == nil || t.Before(*c.EndAt)
+}
+
+// BlockedCategory returns the first of categories the campaign blocks, or
+// an empty string if it blocks none. A blocked category such as IAB7 blocks
+// its subcategories such as IAB7-39 too.
+func (c *Campaign) BlockedCategory(categories []string) string {
+	for _, category := range categories {
+		for _, blocked := range c.BlockedCategories {
+			if category == blocked || strings.HasPrefix(category, blocked+"-") {
+				return category
+			}
+		}
+	}
+	return ""
+}
+
+// IsArchived checks if the campaign has been soft-deleted
+func (c *Campaign) IsArchived() bool {
+	return c.Status == StatusArchived
+}
+
 // ToDeliveryResponse converts Campaign to DeliveryResponse
 func (c *Campaign) ToDeliveryResponse() *DeliveryResponse {
 	return &DeliveryResponse{
 		CID:   c.ID,
 		Image: c.Image,
 		CTA:   c.CTA,
+
+		frequencyCap: c.FrequencyCap,
+		priority:     c.Priority,
+		weight:       c.Weight,
+		dailyBudget:  c.DailyBudget,
+		totalBudget:  c.TotalBudget,
+		appDailyCap:  c.AppDailyCap,
+		maxDailyApps: c.MaxDailyApps,
+		experiment:   c.Experiment,
+		creatives:    c.Creatives,
+		rotation:     c.Rotation,
+		clickURL:     c.ClickURL,
+		updatedAt:    c.UpdatedAt,
 	}
 }
+
+// FrequencyCap returns the per-user daily impression cap of the campaign
+// the response was built from
+func (d *DeliveryResponse) FrequencyCap() int {
+	return d.frequencyCap
+}
+
+// Priority returns the priority of the campaign the response was built from
+func (d *DeliveryResponse) Priority() int {
+	return d.priority
+}
+
+// Weight returns the weight of the campaign the response was built from
+func (d *DeliveryResponse) Weight() int {
+	return d.weight
+}
+
+// DailyBudget returns the daily impression budget of the campaign the
+// response was built from
+func (d *DeliveryResponse) DailyBudget() int64 {
+	return d.dailyBudget
+}
+
+// TotalBudget returns the lifetime impression budget of the campaign the
+// response was built from
+func (d *DeliveryResponse) TotalBudget() int64 {
+	return d.totalBudget
+}
+
+// AppDailyCap returns the per-app daily impression cap of the campaign the
+// response was built from
+func (d *DeliveryResponse) AppDailyCap() int {
+	return d.appDailyCap
+}
+
+// MaxDailyApps returns the daily cap on distinct apps of the campaign the
+// response was built from
+func (d *DeliveryResponse) MaxDailyApps() int {
+	return d.maxDailyApps
+}
+
+// CampaignExperiment returns the experiment of the campaign the response was
+// built from, or nil
+func (d *DeliveryResponse) CampaignExperiment() *Experiment {
+	return d.experiment
+}
+
+// CampaignCreatives returns the creatives of the campaign the response was
+// built from and how they are rotated
+func (d *DeliveryResponse) CampaignCreatives() ([]Creative, string) {
+	return d.creatives, d.rotation
+}
+
+// ClickURLTemplate returns the click URL template of the campaign the
+// response was built from, with its macros unexpanded
+func (d *DeliveryResponse) ClickURLTemplate() string {
+	return d.clickURL
+}
+
+// CampaignUpdatedAt returns when the campaign the response was built from was
+// last updated
+func (d *DeliveryResponse) CampaignUpdatedAt() time.Time {
+	return d.updatedAt
+}
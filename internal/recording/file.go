@@ -0,0 +1,134 @@
+package recording
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Defaults for the FileSink options left unset
+const (
+	DefaultMaxFileSize = 100 << 20
+	DefaultMaxFiles    = 10
+)
+
+// rotatedTimeFormat stamps rotated files so that they sort by age
+const rotatedTimeFormat = "20060102T150405.000"
+
+// FileSink appends recordings to a file. Once the file reaches its size
+// limit it is renamed with a timestamp, e.g. recordings-20250101T120000.000.jsonl,
+// and a new one started; only the newest rotated files are kept.
+type FileSink struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// NewFileSink opens path for appending, creating it and its directory if
+// needed. A non-positive maxSize or maxFiles uses the default.
+func NewFileSink(path string, maxSize int64, maxFiles int) (*FileSink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("recording path is required for the %s sink", SinkFile)
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFileSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	s := &FileSink{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open recording file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat recording file: %w", err)
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// Write appends a line, rotating the file first if the line would take it
+// past its size limit
+func (s *FileSink) Write(line []byte) error {
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// Flush syncs the file to disk
+func (s *FileSink) Flush() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Sync()
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// rotate renames the current file with a timestamp, starts a new one and
+// removes the oldest rotated files beyond the limit
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close recording file: %w", err)
+	}
+	s.file = nil
+
+	ext := filepath.Ext(s.path)
+	base := strings.TrimSuffix(s.path, ext)
+	rotated := base + "-" + time.Now().UTC().Format(rotatedTimeFormat) + ext
+	if err := os.Rename(s.path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate recording file: %w", err)
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	matches, err := filepath.Glob(base + "-*" + ext)
+	if err != nil || len(matches) <= s.maxFiles {
+		return nil
+	}
+	slices.Sort(matches)
+	for _, old := range matches[:len(matches)-s.maxFiles] {
+		if err := os.Remove(old); err != nil {
+			return fmt.Errorf("failed to remove old recording file: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/config"
+)
+
+// Sink names accepted in RecordingConfig.Sink
+const (
+	SinkFile = "file"
+	SinkS3   = "s3"
+)
+
+const (
+	defaultBufferSize    = 1000
+	defaultFlushInterval = time.Minute
+	// maxResponseSize bounds the response bodies kept; larger responses
+	// aren't recorded
+	maxResponseSize = 1 << 20
+)
+
+// Recorder samples the requests passing through its middleware and writes
+// them to a sink from a background goroutine, so recording never blocks a
+// request. Recordings are dropped when the sink falls behind.
+type Recorder struct {
+	sink          Sink
+	sampleRate    float64
+	flushInterval time.Duration
+	entries       chan *Entry
+	dropped       atomic.Int64
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// New creates a recorder writing to the sink configured in cfg
+func New(ctx context.Context, cfg config.RecordingConfig) (*Recorder, error) {
+	var sink Sink
+	var err error
+	switch cfg.Sink {
+	case SinkFile, "":
+		sink, err = NewFileSink(cfg.Path, cfg.MaxSize, cfg.MaxFiles)
+	case SinkS3:
+		sink, err = NewS3Sink(ctx, cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown recording sink '%s', expected %s or %s", cfg.Sink, SinkFile, SinkS3)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewRecorder(sink, cfg)
+}
+
+// NewRecorder creates a recorder writing to sink, sampling and buffering as
+// configured in cfg, and starts its writing loop
+func NewRecorder(sink Sink, cfg config.RecordingConfig) (*Recorder, error) {
+	if cfg.SampleRate < 0 || cfg.SampleRate > 100 {
+		return nil, fmt.Errorf("invalid recording sample rate %v, expected a percentage between 0 and 100", cfg.SampleRate)
+	}
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	flushInterval := cfg.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+
+	r := &Recorder{
+		sink:          sink,
+		sampleRate:    cfg.SampleRate,
+		flushInterval: flushInterval,
+		entries:       make(chan *Entry, bufferSize),
+		done:          make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+// Middleware records the sampled requests of the handler it wraps. The
+// handler must call Capture with the parsed delivery request; requests it
+// rejects before that, and 304 responses, aren't recorded.
+func (r *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if rand.Float64()*100 >= r.sampleRate {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		entry := &Entry{Time: time.Now().UTC()}
+		rw := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rw, req.WithContext(context.WithValue(req.Context(), contextKey{}, entry)))
+
+		if entry.Request == nil || rw.status == http.StatusNotModified || rw.truncated {
+			return
+		}
+		entry.Status = rw.status
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+		if body := bytes.TrimSpace(rw.body.Bytes()); len(body) > 0 && json.Valid(body) {
+			entry.Response = body
+		}
+		r.record(entry)
+	})
+}
+
+// record queues an entry, dropping it if the buffer is full
+func (r *Recorder) record(entry *Entry) {
+	select {
+	case r.entries <- entry:
+	default:
+		if dropped := r.dropped.Add(1); dropped%1000 == 1 {
+			slog.Warn("dropping delivery recordings, recorder buffer is full", "dropped_total", dropped)
+		}
+	}
+}
+
+// Close writes the queued recordings and closes the sink. The middleware
+// must not serve requests after Close.
+func (r *Recorder) Close() error {
+	r.closeOnce.Do(func() { close(r.entries) })
+	<-r.done
+	return r.sink.Close()
+}
+
+// run writes queued recordings to the sink, flushing it every flush interval
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case entry, ok := <-r.entries:
+			if !ok {
+				return
+			}
+			line, err := json.Marshal(entry)
+			if err != nil {
+				slog.Error("failed to encode delivery recording", "error", err)
+				continue
+			}
+			if err := r.sink.Write(append(line, '\n')); err != nil {
+				slog.Error("failed to write delivery recording", "error", err)
+			}
+		case <-ticker.C:
+			if err := r.sink.Flush(); err != nil {
+				slog.Error("failed to flush delivery recordings", "error", err)
+			}
+		}
+	}
+}
+
+// responseRecorder keeps a copy of the status and body written through it
+type responseRecorder struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (rw *responseRecorder) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	if rw.body.Len()+len(b) > maxResponseSize {
+		rw.truncated = true
+	} else if !rw.truncated {
+		rw.body.Write(b)
+	}
+	return rw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,49 @@
+// Package recording samples delivery requests and records them together with
+// the responses served, as JSON Lines, so that recordings of production
+// traffic can be replayed against later builds to catch behavioral
+// regressions.
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// Entry is one recorded delivery request. Response holds the body served
+// with a 200 or 400 status; a 204 has none.
+type Entry struct {
+	Time     time.Time              `json:"time"`
+	Tenant   string                 `json:"tenant,omitempty"`
+	Request  *model.DeliveryRequest `json:"request"`
+	Status   int                    `json:"status"`
+	Response json.RawMessage        `json:"response,omitempty"`
+}
+
+// Sink stores recordings. Write is passed one JSON line at a time, ending in
+// a newline. Sinks are only used from the recorder's background goroutine.
+type Sink interface {
+	Write(line []byte) error
+	// Flush makes the lines written so far durable
+	Flush() error
+	Close() error
+}
+
+type contextKey struct{}
+
+// Capture records req as the request of a sampled delivery request, with the
+// tenant ctx acts for. Handlers call it once the request has been parsed; it
+// does nothing for requests that weren't sampled. The request is copied, so
+// it may be modified afterwards.
+func Capture(ctx context.Context, req *model.DeliveryRequest) {
+	entry, ok := ctx.Value(contextKey{}).(*Entry)
+	if !ok {
+		return
+	}
+	captured := *req
+	entry.Request = &captured
+	entry.Tenant = tenant.FromContext(ctx)
+}
@@ -0,0 +1,133 @@
+package recording
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// ServeFunc serves a delivery request, e.g. TargetingService.GetMatchingCampaigns
+// or a client of a running server. The tenant of the recording is set on ctx.
+type ServeFunc func(ctx context.Context, req *model.DeliveryRequest) ([]*model.DeliveryResponse, error)
+
+// Outcome is what a delivery request was served: the IDs of the matching
+// campaigns, sorted, or the error it was rejected with
+type Outcome struct {
+	Campaigns []string `json:"campaigns"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Mismatch is a recording whose replay didn't reproduce the recorded outcome
+type Mismatch struct {
+	Line     int                    `json:"line"`
+	Tenant   string                 `json:"tenant,omitempty"`
+	Request  *model.DeliveryRequest `json:"request"`
+	Recorded Outcome                `json:"recorded"`
+	Replayed Outcome                `json:"replayed"`
+}
+
+// ReplayReport summarizes a replay. Recordings with a status other than 200,
+// 204 or 400 are skipped since they don't record an outcome.
+type ReplayReport struct {
+	Replayed   int        `json:"replayed"`
+	Skipped    int        `json:"skipped"`
+	Mismatches []Mismatch `json:"mismatches"`
+}
+
+// Replay serves every request recorded in r again and reports those whose
+// outcome changed, for catching behavioral regressions before release.
+// Campaigns are compared as sets, since weighted ordering is random, and
+// errors only by their presence. Recordings are replayed against the
+// campaigns served now, so they should be checked against the data they were
+// recorded with; frequency caps and schedules can also change outcomes.
+func Replay(ctx context.Context, r io.Reader, serve ServeFunc) (*ReplayReport, error) {
+	report := &ReplayReport{Mismatches: []Mismatch{}}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 2*maxResponseSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if entry.Request == nil {
+			return nil, fmt.Errorf("line %d: recording holds no request", line)
+		}
+		recorded, ok := recordedOutcome(&entry)
+		if !ok {
+			report.Skipped++
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Serving may modify the request, so the recorded one is kept intact
+		req := *entry.Request
+		campaigns, err := serve(tenant.WithID(ctx, entry.Tenant), &req)
+		replayed := Outcome{Campaigns: campaignIDs(campaigns)}
+		if err != nil {
+			replayed = Outcome{Campaigns: []string{}, Error: err.Error()}
+		}
+		report.Replayed++
+
+		if (recorded.Error != "") != (replayed.Error != "") || !slices.Equal(recorded.Campaigns, replayed.Campaigns) {
+			report.Mismatches = append(report.Mismatches, Mismatch{
+				Line:     line,
+				Tenant:   entry.Tenant,
+				Request:  entry.Request,
+				Recorded: recorded,
+				Replayed: replayed,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recordings: %w", err)
+	}
+	return report, nil
+}
+
+// recordedOutcome returns the outcome recorded in entry, or false if its
+// status records none
+func recordedOutcome(entry *Entry) (Outcome, bool) {
+	switch entry.Status {
+	case http.StatusOK:
+		var campaigns []*model.DeliveryResponse
+		if err := json.Unmarshal(entry.Response, &campaigns); err != nil {
+			return Outcome{}, false
+		}
+		return Outcome{Campaigns: campaignIDs(campaigns)}, true
+	case http.StatusNoContent:
+		return Outcome{Campaigns: []string{}}, true
+	case http.StatusBadRequest:
+		var errResp model.ErrorResponse
+		json.Unmarshal(entry.Response, &errResp)
+		if errResp.Message == "" {
+			errResp.Message = http.StatusText(entry.Status)
+		}
+		return Outcome{Campaigns: []string{}, Error: errResp.Message}, true
+	default:
+		return Outcome{}, false
+	}
+}
+
+// campaignIDs returns the sorted IDs of campaigns
+func campaignIDs(campaigns []*model.DeliveryResponse) []string {
+	ids := make([]string, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		ids = append(ids, campaign.CID)
+	}
+	slices.Sort(ids)
+	return ids
+}
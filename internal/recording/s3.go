@@ -0,0 +1,105 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// DefaultMaxObjectSize is the size at which the S3 sink uploads its buffered
+// recordings without waiting for the next flush
+const DefaultMaxObjectSize = 16 << 20
+
+// uploadTimeout bounds a single upload so an S3 outage can't wedge the recorder
+const uploadTimeout = 30 * time.Second
+
+// S3Sink buffers recordings in memory and uploads them as one object per
+// flush, keyed by date, time and host so that replicas don't overwrite each
+// other, e.g. recordings/2025/01/01/120000.000000-host.jsonl. Recordings of a
+// failed upload are dropped.
+type S3Sink struct {
+	client  *s3.Client
+	bucket  string
+	prefix  string
+	host    string
+	maxSize int
+	buf     bytes.Buffer
+}
+
+// NewS3Sink creates a sink uploading to the bucket in cfg. Credentials come
+// from the default AWS chain: environment, shared config or instance role.
+func NewS3Sink(ctx context.Context, cfg config.S3RecordingConfig) (*S3Sink, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("recording bucket is required for the %s sink", SinkS3)
+	}
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	maxSize := cfg.MaxObjectSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxObjectSize
+	}
+	return &S3Sink{client: client, bucket: cfg.Bucket, prefix: cfg.Prefix, host: host, maxSize: maxSize}, nil
+}
+
+// Write buffers a line, uploading the buffer once it reaches the object size
+func (s *S3Sink) Write(line []byte) error {
+	s.buf.Write(line)
+	if s.buf.Len() >= s.maxSize {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Flush uploads the buffered recordings, if any, as a new object
+func (s *S3Sink) Flush() error {
+	if s.buf.Len() == 0 {
+		return nil
+	}
+	defer s.buf.Reset()
+
+	now := time.Now().UTC()
+	key := path.Join(s.prefix, now.Format("2006/01/02"), now.Format("150405.000000")+"-"+s.host+".jsonl")
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(s.buf.Bytes()),
+		ContentType: aws.String("application/x-ndjson"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload recordings to s3://%s/%s: %w", s.bucket, key, err)
+	}
+	return nil
+}
+
+// Close uploads the remaining recordings
+func (s *S3Sink) Close() error {
+	return s.Flush()
+}
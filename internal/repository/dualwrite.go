@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// DualWriteRepository serves reads from a primary repository and mirrors
+// campaign, targeting rule and delivery count writes to a secondary one, so
+// data can be migrated between repositories without downtime: the secondary
+// is kept current while existing data is copied to it, and becomes the
+// primary once both are verified to hold the same data.
+//
+// Writes succeed once the primary has stored them. Mirroring failures are
+// logged and left to the migration tool to detect and repair. Mirrored
+// campaigns and rules are imported with the IDs and timestamps the primary
+// gave them. Idempotency keys and logged decisions are only kept in the
+// primary.
+type DualWriteRepository struct {
+	primary   RepositoryManager
+	secondary RepositoryManager
+	importer  Importer
+}
+
+// NewDualWriteRepository creates a repository mirroring the writes of
+// primary to secondary, which must support imports
+func NewDualWriteRepository(primary, secondary RepositoryManager) (*DualWriteRepository, error) {
+	importer, ok := secondary.(Importer)
+	if !ok {
+		return nil, errors.New("secondary repository does not support imports")
+	}
+	return &DualWriteRepository{primary: primary, secondary: secondary, importer: importer}, nil
+}
+
+func (d *DualWriteRepository) Campaign() CampaignRepository {
+	return &dualWriteCampaignRepo{CampaignRepository: d.primary.Campaign(), d: d}
+}
+
+func (d *DualWriteRepository) TargetingRule() TargetingRuleRepository {
+	return &dualWriteRuleRepo{TargetingRuleRepository: d.primary.TargetingRule(), d: d}
+}
+
+func (d *DualWriteRepository) Idempotency() IdempotencyRepository {
+	return d.primary.Idempotency()
+}
+
+func (d *DualWriteRepository) DeliveryStats() DeliveryStatsRepository {
+	return &dualWriteDeliveryStatsRepo{DeliveryStatsRepository: d.primary.DeliveryStats(), d: d}
+}
+
+func (d *DualWriteRepository) KillSwitch() KillSwitchRepository {
+	return &dualWriteKillSwitchRepo{KillSwitchRepository: d.primary.KillSwitch(), d: d}
+}
+
+func (d *DualWriteRepository) DecisionLog() DecisionLogRepository {
+	return d.primary.DecisionLog()
+}
+
+func (d *DualWriteRepository) Close() error {
+	return errors.Join(d.primary.Close(), d.secondary.Close())
+}
+
+func (d *DualWriteRepository) Health(ctx context.Context) error {
+	return d.primary.Health(ctx)
+}
+
+// Migrate prepares both repositories
+func (d *DualWriteRepository) Migrate(ctx context.Context) error {
+	if err := d.primary.Migrate(ctx); err != nil {
+		return err
+	}
+	if err := d.secondary.Migrate(ctx); err != nil {
+		return fmt.Errorf("secondary: %w", err)
+	}
+	return nil
+}
+
+// WatchChanges streams changes from the primary repository
+func (d *DualWriteRepository) WatchChanges(ctx context.Context, handle func(ChangeEvent)) error {
+	watcher, ok := d.primary.(ChangeWatcher)
+	if !ok {
+		return errors.New("repository does not support change streams")
+	}
+	return watcher.WatchChanges(ctx, handle)
+}
+
+// WithTransaction runs fn in a transaction of the primary repository, if it
+// supports them. The writes fn makes are mirrored once the transaction has
+// committed, and not at all if it rolls back.
+func (d *DualWriteRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	transactor, ok := d.primary.(Transactor)
+	if !ok {
+		return fn(ctx)
+	}
+	if _, ok := ctx.Value(pendingMirrorsKey{}).(*pendingMirrors); ok {
+		// Joining an open transaction, whose writes are already deferred
+		return transactor.WithTransaction(ctx, fn)
+	}
+
+	pending := &pendingMirrors{}
+	err := transactor.WithTransaction(context.WithValue(ctx, pendingMirrorsKey{}, pending), func(ctx context.Context) error {
+		// The transaction may be retried; only the last attempt commits
+		pending.reset()
+		return fn(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	for _, write := range pending.take() {
+		d.mirror(ctx, write.operation, write.fn)
+	}
+	return nil
+}
+
+// pendingMirrorsKey is the context key of the mirror writes deferred until
+// the transaction they were made in commits
+type pendingMirrorsKey struct{}
+
+// pendingMirrors collects the mirror writes of a transaction
+type pendingMirrors struct {
+	mutex  sync.Mutex
+	writes []pendingMirror
+}
+
+type pendingMirror struct {
+	operation string
+	fn        func(ctx context.Context) error
+}
+
+func (p *pendingMirrors) add(operation string, fn func(ctx context.Context) error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.writes = append(p.writes, pendingMirror{operation: operation, fn: fn})
+}
+
+func (p *pendingMirrors) reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.writes = nil
+}
+
+func (p *pendingMirrors) take() []pendingMirror {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	writes := p.writes
+	p.writes = nil
+	return writes
+}
+
+// mirror applies a write to the secondary repository. It runs after the
+// primary write has succeeded, so it isn't cancelled with the request.
+// Writes made in a transaction are deferred until it commits.
+func (d *DualWriteRepository) mirror(ctx context.Context, operation string, fn func(ctx context.Context) error) {
+	if pending, ok := ctx.Value(pendingMirrorsKey{}).(*pendingMirrors); ok {
+		pending.add(operation, fn)
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	if err := fn(ctx); err != nil && !errors.Is(err, ErrNotFound) {
+		slog.ErrorContext(ctx, "failed to mirror write to secondary repository", "operation", operation, "error", err)
+	}
+}
+
+// mirrorCampaign copies a campaign as the primary stores it
+func (d *DualWriteRepository) mirrorCampaign(ctx context.Context, operation, id string) {
+	d.mirror(ctx, operation, func(ctx context.Context) error {
+		campaign, err := d.primary.Campaign().GetCampaignByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return d.importer.ImportCampaigns(ctx, []*model.Campaign{campaign})
+	})
+}
+
+// mirrorRule copies a targeting rule as the primary stores it
+func (d *DualWriteRepository) mirrorRule(ctx context.Context, operation string, id int64) {
+	d.mirror(ctx, operation, func(ctx context.Context) error {
+		rule, err := d.primary.TargetingRule().GetTargetingRuleByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return d.importer.ImportTargetingRules(ctx, []*model.TargetingRule{rule})
+	})
+}
+
+// dualWriteCampaignRepo reads campaigns from the primary and mirrors writes
+type dualWriteCampaignRepo struct {
+	CampaignRepository
+	d *DualWriteRepository
+}
+
+func (c *dualWriteCampaignRepo) CreateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	if err := c.CampaignRepository.CreateCampaign(ctx, campaign); err != nil {
+		return err
+	}
+	c.d.mirrorCampaign(ctx, "create_campaign", campaign.ID)
+	return nil
+}
+
+func (c *dualWriteCampaignRepo) BulkCreateCampaigns(ctx context.Context, campaigns []*model.Campaign, rules []*model.TargetingRule) error {
+	if err := c.CampaignRepository.BulkCreateCampaigns(ctx, campaigns, rules); err != nil {
+		return err
+	}
+	c.d.mirror(ctx, "bulk_create_campaigns", func(ctx context.Context) error {
+		if err := c.d.importer.ImportCampaigns(ctx, campaigns); err != nil {
+			return err
+		}
+		return c.d.importer.ImportTargetingRules(ctx, rules)
+	})
+	return nil
+}
+
+func (c *dualWriteCampaignRepo) UpdateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	if err := c.CampaignRepository.UpdateCampaign(ctx, campaign); err != nil {
+		return err
+	}
+	c.d.mirrorCampaign(ctx, "update_campaign", campaign.ID)
+	return nil
+}
+
+func (c *dualWriteCampaignRepo) DeleteCampaign(ctx context.Context, id string) error {
+	if err := c.CampaignRepository.DeleteCampaign(ctx, id); err != nil {
+		return err
+	}
+	c.d.mirror(ctx, "delete_campaign", func(ctx context.Context) error {
+		return c.d.secondary.Campaign().DeleteCampaign(ctx, id)
+	})
+	return nil
+}
+
+func (c *dualWriteCampaignRepo) UpdateCampaignStatus(ctx context.Context, id, status string) error {
+	if err := c.CampaignRepository.UpdateCampaignStatus(ctx, id, status); err != nil {
+		return err
+	}
+	c.d.mirrorCampaign(ctx, "update_campaign_status", id)
+	return nil
+}
+
+// dualWriteRuleRepo reads targeting rules from the primary and mirrors writes
+type dualWriteRuleRepo struct {
+	TargetingRuleRepository
+	d *DualWriteRepository
+}
+
+func (t *dualWriteRuleRepo) CreateTargetingRule(ctx context.Context, rule *model.TargetingRule) error {
+	if err := t.TargetingRuleRepository.CreateTargetingRule(ctx, rule); err != nil {
+		return err
+	}
+	t.d.mirrorRule(ctx, "create_targeting_rule", rule.ID)
+	return nil
+}
+
+func (t *dualWriteRuleRepo) UpdateTargetingRule(ctx context.Context, rule *model.TargetingRule) error {
+	if err := t.TargetingRuleRepository.UpdateTargetingRule(ctx, rule); err != nil {
+		return err
+	}
+	t.d.mirrorRule(ctx, "update_targeting_rule", rule.ID)
+	return nil
+}
+
+func (t *dualWriteRuleRepo) DeleteTargetingRule(ctx context.Context, id int64) error {
+	if err := t.TargetingRuleRepository.DeleteTargetingRule(ctx, id); err != nil {
+		return err
+	}
+	t.d.mirror(ctx, "delete_targeting_rule", func(ctx context.Context) error {
+		return t.d.secondary.TargetingRule().DeleteTargetingRule(ctx, id)
+	})
+	return nil
+}
+
+func (t *dualWriteRuleRepo) DeleteTargetingRulesByCampaignID(ctx context.Context, campaignID string) error {
+	if err := t.TargetingRuleRepository.DeleteTargetingRulesByCampaignID(ctx, campaignID); err != nil {
+		return err
+	}
+	t.d.mirror(ctx, "delete_targeting_rules", func(ctx context.Context) error {
+		return t.d.secondary.TargetingRule().DeleteTargetingRulesByCampaignID(ctx, campaignID)
+	})
+	return nil
+}
+
+// dualWriteDeliveryStatsRepo reads delivery counts from the primary and
+// mirrors additions
+type dualWriteDeliveryStatsRepo struct {
+	DeliveryStatsRepository
+	d *DualWriteRepository
+}
+
+func (s *dualWriteDeliveryStatsRepo) AddDeliveryCounts(ctx context.Context, counts []DeliveryCount) error {
+	if err := s.DeliveryStatsRepository.AddDeliveryCounts(ctx, counts); err != nil {
+		return err
+	}
+	s.d.mirror(ctx, "add_delivery_counts", func(ctx context.Context) error {
+		return s.d.secondary.DeliveryStats().AddDeliveryCounts(ctx, counts)
+	})
+	return nil
+}
+
+// dualWriteKillSwitchRepo reads kill switches from the primary and mirrors
+// engaging and releasing them
+type dualWriteKillSwitchRepo struct {
+	KillSwitchRepository
+	d *DualWriteRepository
+}
+
+func (k *dualWriteKillSwitchRepo) EngageKillSwitch(ctx context.Context, sw *model.KillSwitch) error {
+	if err := k.KillSwitchRepository.EngageKillSwitch(ctx, sw); err != nil {
+		return err
+	}
+	k.d.mirror(ctx, "engage_kill_switch", func(ctx context.Context) error {
+		return k.d.secondary.KillSwitch().EngageKillSwitch(ctx, sw)
+	})
+	return nil
+}
+
+func (k *dualWriteKillSwitchRepo) ReleaseKillSwitch(ctx context.Context, scope string) error {
+	if err := k.KillSwitchRepository.ReleaseKillSwitch(ctx, scope); err != nil {
+		return err
+	}
+	k.d.mirror(ctx, "release_kill_switch", func(ctx context.Context) error {
+		return k.d.secondary.KillSwitch().ReleaseKillSwitch(ctx, scope)
+	})
+	return nil
+}
@@ -2,19 +2,76 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	model "github.com/Harshi-itaSinha/target-engine/internal/models"
 )
 
+//go:generate mockgen -source=interface.go -destination=mocks/repository.go -package=mocks
+
+// ErrNotFound is returned when a requested campaign or targeting rule does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrAlreadyExists is returned when creating a campaign whose ID is already taken
+var ErrAlreadyExists = errors.New("already exists")
+
+// ErrUnavailable is returned when the backing store can't be reached, either
+// because retries were exhausted or because the circuit breaker is open
+var ErrUnavailable = errors.New("repository unavailable")
+
+// CampaignFilter selects a page of campaigns for ListCampaigns. Zero values
+// leave a criterion unset; a zero Limit returns every remaining campaign.
+// Archived campaigns are only listed when IncludeArchived is set or Status
+// asks for them. TenantID is always applied; the empty ID lists the default
+// tenant's campaigns.
+type CampaignFilter struct {
+	TenantID        string
+	Status          string
+	IncludeArchived bool
+	CreatedAfter    time.Time
+	// Search matches campaigns whose ID or name contains it, ignoring case
+	Search string
+	Limit  int
+	Offset int
+}
+
+// CampaignSearch selects a page of campaigns for SearchCampaigns. Query is
+// matched against campaign names and CTAs; a campaign matches if any of its
+// words does. Archived campaigns are only found with IncludeArchived.
+type CampaignSearch struct {
+	TenantID        string
+	Query           string
+	IncludeArchived bool
+	Limit           int
+	Offset          int
+}
+
 type CampaignRepository interface {
 	GetActiveCampaigns(ctx context.Context) ([]*model.Campaign, error)
 
+	// GetCampaignsByStatus returns the campaigns of every tenant in any of
+	// statuses
+	GetCampaignsByStatus(ctx context.Context, statuses []string) ([]*model.Campaign, error)
+
 	GetCampaignByID(ctx context.Context, id string) (*model.Campaign, error)
 
 	GetCampaignsByIDs(ctx context.Context, ids []string) ([]*model.Campaign, error)
 
+	// ListCampaigns returns the page of campaigns matching filter, newest
+	// first, along with the total number of matching campaigns
+	ListCampaigns(ctx context.Context, filter CampaignFilter) ([]*model.Campaign, int64, error)
+
+	// SearchCampaigns returns the page of campaigns matching search, most
+	// relevant first, along with the total number of matching campaigns
+	SearchCampaigns(ctx context.Context, search CampaignSearch) ([]*model.ScoredCampaign, int64, error)
+
 	CreateCampaign(ctx context.Context, campaign *model.Campaign) error
 
+	// BulkCreateCampaigns atomically creates campaigns and their targeting
+	// rules: either everything is stored or nothing is
+	BulkCreateCampaigns(ctx context.Context, campaigns []*model.Campaign, rules []*model.TargetingRule) error
+
 	UpdateCampaign(ctx context.Context, campaign *model.Campaign) error
 
 	DeleteCampaign(ctx context.Context, id string) error
@@ -29,6 +86,8 @@ type TargetingRuleRepository interface {
 
 	GetTargetingRulesByCampaignID(ctx context.Context, campaignID string) ([]*model.TargetingRule, error)
 
+	GetTargetingRuleByID(ctx context.Context, id int64) (*model.TargetingRule, error)
+
 	CreateTargetingRule(ctx context.Context, rule *model.TargetingRule) error
 
 	UpdateTargetingRule(ctx context.Context, rule *model.TargetingRule) error
@@ -38,9 +97,107 @@ type TargetingRuleRepository interface {
 	DeleteTargetingRulesByCampaignID(ctx context.Context, campaignID string) error
 }
 
+// IdempotencyRecord is a request made with an idempotency key. Result is nil
+// while the request is in progress.
+type IdempotencyRecord struct {
+	Key string `bson:"_id" json:"key"`
+	// Fingerprint identifies the request body, so a key reused for a
+	// different request can be told apart from a retry
+	Fingerprint string    `bson:"fingerprint" json:"fingerprint"`
+	Result      []byte    `bson:"result,omitempty" json:"result,omitempty"`
+	ExpiresAt   time.Time `bson:"expires_at" json:"expires_at"`
+}
+
+// IdempotencyRepository stores the results of requests made with an
+// idempotency key until they expire
+type IdempotencyRepository interface {
+	// ReserveIdempotencyKey claims record.Key for a request and returns nil.
+	// If an unexpired record already holds the key, it returns that record
+	// and claims nothing.
+	ReserveIdempotencyKey(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error)
+
+	// CompleteIdempotencyKey stores the result of the request holding key
+	CompleteIdempotencyKey(ctx context.Context, key string, result []byte) error
+
+	// ReleaseIdempotencyKey removes key, so the request can be retried
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+// deliveryDayLayout is the format of the days of delivery counts
+const deliveryDayLayout = "20060102"
+
+// DeliveryCount is how often a campaign was served on a UTC day (YYYYMMDD),
+// to requests from Country and OS. Country and OS are empty for counts that
+// aren't broken down by them.
+type DeliveryCount struct {
+	TenantID   string `bson:"tenant_id" json:"tenant_id,omitempty"`
+	CampaignID string `bson:"campaign_id" json:"campaign_id"`
+	Day        string `bson:"day" json:"day"`
+	Country    string `bson:"country" json:"country,omitempty"`
+	OS         string `bson:"os" json:"os,omitempty"`
+	Served     int64  `bson:"served" json:"served"`
+}
+
+// DeliveryCountFilter selects the delivery counts of a tenant's campaigns
+// from day From through day To, both YYYYMMDD. An empty CampaignID selects
+// every campaign.
+type DeliveryCountFilter struct {
+	TenantID   string
+	CampaignID string
+	From       string
+	To         string
+}
+
+// DeliveryStatsRepository stores delivery counts aggregated by the service
+type DeliveryStatsRepository interface {
+	// AddDeliveryCounts adds the Served of each count to the stored count
+	// with the same tenant, campaign, day, country and OS
+	AddDeliveryCounts(ctx context.Context, counts []DeliveryCount) error
+
+	// GetDeliveryCounts returns the stored counts matching filter
+	GetDeliveryCounts(ctx context.Context, filter DeliveryCountFilter) ([]DeliveryCount, error)
+}
+
+// KillSwitchRepository stores the engaged kill switches, so they survive
+// restarts and reach every instance
+type KillSwitchRepository interface {
+	// GetKillSwitches returns every engaged kill switch
+	GetKillSwitches(ctx context.Context) ([]*model.KillSwitch, error)
+
+	// EngageKillSwitch stores sw, replacing the switch with the same scope
+	EngageKillSwitch(ctx context.Context, sw *model.KillSwitch) error
+
+	// ReleaseKillSwitch removes the switch with the given scope. Releasing
+	// a switch that isn't engaged is not an error.
+	ReleaseKillSwitch(ctx context.Context, scope string) error
+}
+
+// DecisionFilter selects the logged decisions of a tenant for GetDecisions.
+// An empty RequestID selects the tenant's latest decisions. Limit bounds the
+// decisions returned; zero returns all of them.
+type DecisionFilter struct {
+	TenantID  string
+	RequestID string
+	Limit     int
+}
+
+// DecisionLogRepository stores sampled delivery decisions until they expire
+type DecisionLogRepository interface {
+	// AddDecisions stores decisions
+	AddDecisions(ctx context.Context, decisions []*model.Decision) error
+
+	// GetDecisions returns the unexpired decisions matching filter, newest
+	// first
+	GetDecisions(ctx context.Context, filter DecisionFilter) ([]*model.Decision, error)
+}
+
 type Repository interface {
 	Campaign() CampaignRepository
 	TargetingRule() TargetingRuleRepository
+	Idempotency() IdempotencyRepository
+	DeliveryStats() DeliveryStatsRepository
+	KillSwitch() KillSwitchRepository
+	DecisionLog() DecisionLogRepository
 	Close() error
 }
 
@@ -51,3 +208,65 @@ type RepositoryManager interface {
 
 	Migrate(ctx context.Context) error
 }
+
+// Importer is implemented by repositories that can store campaigns and
+// targeting rules as they are, keeping their IDs and timestamps, so data can
+// be copied from another repository. Campaigns and rules with the same IDs
+// are replaced, and rule IDs allocated afterwards follow the imported ones.
+type Importer interface {
+	ImportCampaigns(ctx context.Context, campaigns []*model.Campaign) error
+
+	ImportTargetingRules(ctx context.Context, rules []*model.TargetingRule) error
+}
+
+// Transactor is implemented by repositories that can apply several writes
+// atomically
+type Transactor interface {
+	// WithTransaction runs fn in a transaction, committing it if fn succeeds
+	// and rolling it back if it fails. Only the repository calls fn makes
+	// with the context it is given take part in the transaction.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// WithTransaction runs fn in a transaction of repo, so the writes it makes
+// through the context it is given either all take effect or none do. With
+// a repository that doesn't support transactions fn simply runs, and a
+// failure may leave part of its writes applied.
+func WithTransaction(ctx context.Context, repo Repository, fn func(ctx context.Context) error) error {
+	transactor, ok := repo.(Transactor)
+	if !ok {
+		return fn(ctx)
+	}
+	return transactor.WithTransaction(ctx, fn)
+}
+
+// ChangeKind identifies the entity a ChangeEvent refers to
+type ChangeKind string
+
+const (
+	ChangeKindCampaign      ChangeKind = "campaign"
+	ChangeKindTargetingRule ChangeKind = "targeting_rule"
+)
+
+// ChangeEvent describes a write observed in the underlying store. Campaign or
+// Rule holds the document after the change; both are nil for deletes, where
+// the store may not be able to report which entity was removed.
+type ChangeEvent struct {
+	Kind      ChangeKind
+	Operation string
+	Campaign  *model.Campaign
+	Rule      *model.TargetingRule
+}
+
+// IsDelete reports whether the event removed a document
+func (e ChangeEvent) IsDelete() bool {
+	return e.Operation == "delete"
+}
+
+// ChangeWatcher is implemented by repositories that can stream writes as they
+// happen, allowing callers to update caches incrementally
+type ChangeWatcher interface {
+	// WatchChanges blocks, invoking handle for every change, until ctx is
+	// cancelled or the stream fails
+	WatchChanges(ctx context.Context, handle func(ChangeEvent)) error
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// filterCampaigns applies a CampaignFilter in memory for stores without
+// secondary indexes. campaigns is sorted in place.
+func filterCampaigns(campaigns []*model.Campaign, filter CampaignFilter) ([]*model.Campaign, int64) {
+	search := strings.ToLower(filter.Search)
+	matched := campaigns[:0:0]
+	for _, campaign := range campaigns {
+		if campaign.TenantID != filter.TenantID {
+			continue
+		}
+		if filter.Status != "" && campaign.Status != filter.Status {
+			continue
+		}
+		if filter.Status == "" && !filter.IncludeArchived && campaign.IsArchived() {
+			continue
+		}
+		if !filter.CreatedAfter.IsZero() && !campaign.CreatedAt.After(filter.CreatedAfter) {
+			continue
+		}
+		if search != "" &&
+			!strings.Contains(strings.ToLower(campaign.ID), search) &&
+			!strings.Contains(strings.ToLower(campaign.Name), search) {
+			continue
+		}
+		matched = append(matched, campaign)
+	}
+
+	sortCampaigns(matched)
+
+	total := int64(len(matched))
+	if filter.Offset >= len(matched) {
+		return []*model.Campaign{}, total
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(matched) {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total
+}
+
+// Relative weights of the campaign fields searched by SearchCampaigns: a
+// match in the name counts more than one in the CTA
+const (
+	nameSearchWeight = 3
+	ctaSearchWeight  = 1
+)
+
+// searchCampaigns applies a CampaignSearch in memory by a substring scan.
+// Each word of the query found in a campaign's name or CTA, ignoring case,
+// adds that field's weight to its score. campaigns is not modified.
+func searchCampaigns(campaigns []*model.Campaign, search CampaignSearch) ([]*model.ScoredCampaign, int64) {
+	terms := strings.Fields(strings.ToLower(search.Query))
+	matched := []*model.ScoredCampaign{}
+	for _, campaign := range campaigns {
+		if campaign.TenantID != search.TenantID {
+			continue
+		}
+		if !search.IncludeArchived && campaign.IsArchived() {
+			continue
+		}
+		name, cta := strings.ToLower(campaign.Name), strings.ToLower(campaign.CTA)
+		score := 0
+		for _, term := range terms {
+			if strings.Contains(name, term) {
+				score += nameSearchWeight
+			}
+			if strings.Contains(cta, term) {
+				score += ctaSearchWeight
+			}
+		}
+		if score > 0 {
+			matched = append(matched, &model.ScoredCampaign{Campaign: campaign, Score: float64(score)})
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+
+	total := int64(len(matched))
+	if search.Offset >= len(matched) {
+		return []*model.ScoredCampaign{}, total
+	}
+	matched = matched[search.Offset:]
+	if search.Limit > 0 && search.Limit < len(matched) {
+		matched = matched[:search.Limit]
+	}
+	return matched, total
+}
+
+// sortCampaigns orders campaigns newest first, then by ID, matching the
+// order ListCampaigns uses in MongoDB
+func sortCampaigns(campaigns []*model.Campaign) {
+	sort.Slice(campaigns, func(i, j int) bool {
+		if !campaigns[i].CreatedAt.Equal(campaigns[j].CreatedAt) {
+			return campaigns[i].CreatedAt.After(campaigns[j].CreatedAt)
+		}
+		return campaigns[i].ID < campaigns[j].ID
+	})
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// campaignMatchesDimensions reports whether a campaign with the given rules
+// matches every requested dimension. Rules are ORed together, dimensions
+// within a rule are ANDed, rules outside their effective window match
+// nothing, and a campaign without rules matches everything.
+func campaignMatchesDimensions(rules []*model.TargetingRule, dimensions []model.Dimension) bool {
+	if len(rules) == 0 {
+		return true
+	}
+
+	now := time.Now()
+	for _, rule := range rules {
+		if rule.InEffect(now) && ruleMatchesDimensions(rule, dimensions) {
+			return true
+		}
+	}
+	return false
+}
+
+// liveRules returns the rules that take part in delivery, leaving out shadow
+// rules
+func liveRules(rules []*model.TargetingRule) []*model.TargetingRule {
+	live := make([]*model.TargetingRule, 0, len(rules))
+	for _, rule := range rules {
+		if !rule.Shadow {
+			live = append(live, rule)
+		}
+	}
+	return live
+}
+
+// ruleMatchesDimensions checks a single rule against the requested
+// dimensions and its expression. A dimension the request carries several
+// values of is excluded when any value is, and included when any value is.
+func ruleMatchesDimensions(rule *model.TargetingRule, dimensions []model.Dimension) bool {
+	for name, values := range groupDimensions(dimensions) {
+		if !slices.Contains(model.DeliveryDimensions, name) {
+			continue
+		}
+		include, exclude := rule.DimensionLists(name)
+		caseSensitive := name == "app"
+
+		included := len(include) == 0
+		for _, value := range values {
+			if containsValue(exclude, value, caseSensitive) {
+				return false
+			}
+			if containsValue(include, value, caseSensitive) {
+				included = true
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return segmentsMatch(rule, dimensions) && expressionMayMatch(rule.Expression, dimensions) != expressionFalse
+}
+
+// groupDimensions collects the requested values of each dimension
+func groupDimensions(dimensions []model.Dimension) map[string][]string {
+	grouped := make(map[string][]string, len(dimensions))
+	for _, d := range dimensions {
+		grouped[d.Name] = append(grouped[d.Name], d.Value)
+	}
+	return grouped
+}
+
+// segmentsMatch checks the segment lists of a rule against the segments the
+// request carries, one segment dimension each: any excluded segment rules the
+// user out, and any included one lets them in
+func segmentsMatch(rule *model.TargetingRule, dimensions []model.Dimension) bool {
+	included := len(rule.IncludeSegment) == 0
+	for _, d := range dimensions {
+		if d.Name != model.SegmentDimension {
+			continue
+		}
+		if containsValue(rule.ExcludeSegment, d.Value, true) {
+			return false
+		}
+		if containsValue(rule.IncludeSegment, d.Value, true) {
+			included = true
+		}
+	}
+	return included
+}
+
+// expressionResult is the outcome of evaluating an expression where only
+// exact matching of built-in dimensions is known
+type expressionResult int
+
+const (
+	expressionTrue expressionResult = iota
+	expressionFalse
+	expressionUnknown
+)
+
+// expressionMayMatch evaluates a rule's expression against the requested
+// dimensions. Leaves on custom dimensions or segments, or using non-exact
+// operators, are unknown and left to the targeting cache, so a rule is only
+// ruled out when its expression is false whatever they evaluate to.
+func expressionMayMatch(expr *model.Expression, dimensions []model.Dimension) expressionResult {
+	switch {
+	case expr == nil:
+		return expressionTrue
+	case len(expr.And) > 0:
+		result := expressionTrue
+		for _, child := range expr.And {
+			switch expressionMayMatch(child, dimensions) {
+			case expressionFalse:
+				return expressionFalse
+			case expressionUnknown:
+				result = expressionUnknown
+			}
+		}
+		return result
+	case len(expr.Or) > 0:
+		result := expressionFalse
+		for _, child := range expr.Or {
+			switch expressionMayMatch(child, dimensions) {
+			case expressionTrue:
+				return expressionTrue
+			case expressionUnknown:
+				result = expressionUnknown
+			}
+		}
+		return result
+	case expr.Not != nil:
+		switch expressionMayMatch(expr.Not, dimensions) {
+		case expressionTrue:
+			return expressionFalse
+		case expressionFalse:
+			return expressionTrue
+		}
+		return expressionUnknown
+	}
+
+	if expr.Operator != "" && expr.Operator != model.OperatorExact {
+		return expressionUnknown
+	}
+	if !slices.Contains(model.DeliveryDimensions, expr.Dimension) {
+		return expressionUnknown
+	}
+	values := groupDimensions(dimensions)[expr.Dimension]
+	if len(values) == 0 {
+		values = []string{""}
+	}
+	for _, value := range values {
+		if containsValue(expr.Values, value, expr.Dimension == "app") {
+			return expressionTrue
+		}
+	}
+	return expressionFalse
+}
+
+// containsValue checks if a slice contains a value
+func containsValue(slice []string, value string, caseSensitive bool) bool {
+	for _, item := range slice {
+		if caseSensitive {
+			if item == value {
+				return true
+			}
+		} else if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
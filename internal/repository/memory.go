@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -15,19 +16,39 @@ type MemoryRepository struct {
 	rulesByID      map[int64]*model.TargetingRule
 	mutex          sync.RWMutex
 	nextRuleID     int64
+
+	idempotencyKeys map[string]*IdempotencyRecord
+	deliveryCounts  map[deliveryCountKey]int64
+	killSwitches    map[string]*model.KillSwitch // keyed by scope
+	decisions       []*model.Decision            // oldest first
+}
+
+// deliveryCountKey identifies a stored delivery count
+type deliveryCountKey struct {
+	tenantID, campaignID, day, country, os string
 }
 
 func NewMemoryRepository() *MemoryRepository {
-	repo := &MemoryRepository{
+	repo := NewEmptyMemoryRepository()
+
+	repo.initializeSampleData()
+
+	return repo
+}
+
+// NewEmptyMemoryRepository creates a memory repository without the sample
+// campaigns, for callers that load their own
+func NewEmptyMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
 		campaigns:      make(map[string]*model.Campaign),
 		targetingRules: make(map[string][]*model.TargetingRule),
 		rulesByID:      make(map[int64]*model.TargetingRule),
 		nextRuleID:     1,
-	}
 
-	repo.initializeSampleData()
-
-	return repo
+		idempotencyKeys: make(map[string]*IdempotencyRecord),
+		deliveryCounts:  make(map[deliveryCountKey]int64),
+		killSwitches:    make(map[string]*model.KillSwitch),
+	}
 }
 
 func (r *MemoryRepository) Campaign() CampaignRepository {
@@ -38,6 +59,22 @@ func (r *MemoryRepository) TargetingRule() TargetingRuleRepository {
 	return r
 }
 
+func (r *MemoryRepository) Idempotency() IdempotencyRepository {
+	return r
+}
+
+func (r *MemoryRepository) DeliveryStats() DeliveryStatsRepository {
+	return r
+}
+
+func (r *MemoryRepository) KillSwitch() KillSwitchRepository {
+	return r
+}
+
+func (r *MemoryRepository) DecisionLog() DecisionLogRepository {
+	return r
+}
+
 func (r *MemoryRepository) Close() error {
 	return nil
 }
@@ -64,8 +101,33 @@ func (r *MemoryRepository) GetActiveCampaigns(ctx context.Context) ([]*model.Cam
 	return activeCampaigns, nil
 }
 
+func (r *MemoryRepository) GetCampaignsByStatus(ctx context.Context, statuses []string) ([]*model.Campaign, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var campaigns []*model.Campaign
+	for _, campaign := range r.campaigns {
+		if slices.Contains(statuses, campaign.Status) {
+			campaigns = append(campaigns, campaign)
+		}
+	}
+
+	return campaigns, nil
+}
+
 func (r *MemoryRepository) GetMatchingCampaignIDs(ctx context.Context, dimensions []model.Dimension) ([]string, error) {
-	return nil, nil
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var matched []string
+	for id, campaign := range r.campaigns {
+		if campaign.IsActive() && campaignMatchesDimensions(liveRules(r.targetingRules[id]), dimensions) {
+			matched = append(matched, id)
+		}
+	}
+	slices.Sort(matched)
+
+	return matched, nil
 }
 
 func (r *MemoryRepository) GetCampaignByID(ctx context.Context, id string) (*model.Campaign, error) {
@@ -74,7 +136,7 @@ func (r *MemoryRepository) GetCampaignByID(ctx context.Context, id string) (*mod
 
 	campaign, exists := r.campaigns[id]
 	if !exists {
-		return nil, fmt.Errorf("campaign with ID %s not found", id)
+		return nil, fmt.Errorf("campaign with ID %s %w", id, ErrNotFound)
 	}
 
 	return campaign, nil
@@ -84,12 +146,38 @@ func (r *MemoryRepository) GetCampaignsByIDs(ctx context.Context, ids []string)
 	return nil, nil
 }
 
+func (r *MemoryRepository) ListCampaigns(ctx context.Context, filter CampaignFilter) ([]*model.Campaign, int64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	campaigns := make([]*model.Campaign, 0, len(r.campaigns))
+	for _, campaign := range r.campaigns {
+		campaigns = append(campaigns, campaign)
+	}
+
+	page, total := filterCampaigns(campaigns, filter)
+	return page, total, nil
+}
+
+func (r *MemoryRepository) SearchCampaigns(ctx context.Context, search CampaignSearch) ([]*model.ScoredCampaign, int64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	campaigns := make([]*model.Campaign, 0, len(r.campaigns))
+	for _, campaign := range r.campaigns {
+		campaigns = append(campaigns, campaign)
+	}
+
+	page, total := searchCampaigns(campaigns, search)
+	return page, total, nil
+}
+
 func (r *MemoryRepository) CreateCampaign(ctx context.Context, campaign *model.Campaign) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	if _, exists := r.campaigns[campaign.ID]; exists {
-		return fmt.Errorf("campaign with ID %s already exists", campaign.ID)
+		return fmt.Errorf("campaign with ID %s %w", campaign.ID, ErrAlreadyExists)
 	}
 
 	campaign.CreatedAt = time.Now()
@@ -99,12 +187,42 @@ func (r *MemoryRepository) CreateCampaign(ctx context.Context, campaign *model.C
 	return nil
 }
 
+func (r *MemoryRepository) BulkCreateCampaigns(ctx context.Context, campaigns []*model.Campaign, rules []*model.TargetingRule) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, campaign := range campaigns {
+		if _, exists := r.campaigns[campaign.ID]; exists {
+			return fmt.Errorf("campaign with ID %s %w", campaign.ID, ErrAlreadyExists)
+		}
+	}
+
+	now := time.Now()
+	for _, campaign := range campaigns {
+		campaign.CreatedAt = now
+		campaign.UpdatedAt = now
+		r.campaigns[campaign.ID] = campaign
+	}
+
+	for _, rule := range rules {
+		rule.ID = r.nextRuleID
+		r.nextRuleID++
+		rule.CreatedAt = now
+		rule.UpdatedAt = now
+
+		r.targetingRules[rule.CampaignID] = append(r.targetingRules[rule.CampaignID], rule)
+		r.rulesByID[rule.ID] = rule
+	}
+
+	return nil
+}
+
 func (r *MemoryRepository) UpdateCampaign(ctx context.Context, campaign *model.Campaign) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	if _, exists := r.campaigns[campaign.ID]; !exists {
-		return fmt.Errorf("campaign with ID %s not found", campaign.ID)
+		return fmt.Errorf("campaign with ID %s %w", campaign.ID, ErrNotFound)
 	}
 
 	campaign.UpdatedAt = time.Now()
@@ -118,7 +236,7 @@ func (r *MemoryRepository) DeleteCampaign(ctx context.Context, id string) error
 	defer r.mutex.Unlock()
 
 	if _, exists := r.campaigns[id]; !exists {
-		return fmt.Errorf("campaign with ID %s not found", id)
+		return fmt.Errorf("campaign with ID %s %w", id, ErrNotFound)
 	}
 
 	delete(r.campaigns, id)
@@ -133,11 +251,15 @@ func (r *MemoryRepository) UpdateCampaignStatus(ctx context.Context, id, status
 
 	campaign, exists := r.campaigns[id]
 	if !exists {
-		return fmt.Errorf("campaign with ID %s not found", id)
+		return fmt.Errorf("campaign with ID %s %w", id, ErrNotFound)
 	}
 
-	campaign.Status = status
-	campaign.UpdatedAt = time.Now()
+	// Stored campaigns may be shared with readers, so they are replaced
+	// rather than modified
+	updated := *campaign
+	updated.Status = status
+	updated.UpdatedAt = time.Now()
+	r.campaigns[id] = &updated
 
 	return nil
 }
@@ -169,6 +291,18 @@ func (r *MemoryRepository) GetTargetingRulesByCampaignID(ctx context.Context, ca
 	return rules, nil
 }
 
+func (r *MemoryRepository) GetTargetingRuleByID(ctx context.Context, id int64) (*model.TargetingRule, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	rule, exists := r.rulesByID[id]
+	if !exists {
+		return nil, fmt.Errorf("targeting rule with ID %d %w", id, ErrNotFound)
+	}
+
+	return rule, nil
+}
+
 func (r *MemoryRepository) CreateTargetingRule(ctx context.Context, rule *model.TargetingRule) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
@@ -190,20 +324,17 @@ func (r *MemoryRepository) UpdateTargetingRule(ctx context.Context, rule *model.
 
 	existingRule, exists := r.rulesByID[rule.ID]
 	if !exists {
-		return fmt.Errorf("targeting rule with ID %d not found", rule.ID)
+		return fmt.Errorf("targeting rule with ID %d %w", rule.ID, ErrNotFound)
 	}
 
+	rule.CreatedAt = existingRule.CreatedAt
 	rule.UpdatedAt = time.Now()
 
 	r.rulesByID[rule.ID] = rule
 
-	rules := r.targetingRules[existingRule.CampaignID]
-	for i, r := range rules {
-		if r.ID == rule.ID {
-			rules[i] = rule
-			break
-		}
-	}
+	// The rule may have moved between campaigns
+	r.removeRule(existingRule.CampaignID, rule.ID)
+	r.targetingRules[rule.CampaignID] = append(r.targetingRules[rule.CampaignID], rule)
 
 	return nil
 }
@@ -212,11 +343,31 @@ func (r *MemoryRepository) DeleteTargetingRule(ctx context.Context, id int64) er
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	existingRule, exists := r.rulesByID[id]
+	if !exists {
+		return fmt.Errorf("targeting rule with ID %d %w", id, ErrNotFound)
+	}
+
 	delete(r.rulesByID, id)
+	r.removeRule(existingRule.CampaignID, id)
 
 	return nil
 }
 
+// removeRule drops a rule from its campaign's list. The caller must hold the write lock.
+func (r *MemoryRepository) removeRule(campaignID string, id int64) {
+	rules := r.targetingRules[campaignID]
+	for i, rule := range rules {
+		if rule.ID == id {
+			r.targetingRules[campaignID] = append(rules[:i:i], rules[i+1:]...)
+			break
+		}
+	}
+	if len(r.targetingRules[campaignID]) == 0 {
+		delete(r.targetingRules, campaignID)
+	}
+}
+
 // DeleteTargetingRulesByCampaignID deletes all targeting rules for a campaign
 func (r *MemoryRepository) DeleteTargetingRulesByCampaignID(ctx context.Context, campaignID string) error {
 	r.mutex.Lock()
@@ -236,6 +387,184 @@ func (r *MemoryRepository) DeleteTargetingRulesByCampaignID(ctx context.Context,
 	return nil
 }
 
+// Import Methods
+
+// ImportCampaigns stores campaigns as they are, replacing those with the
+// same IDs
+func (r *MemoryRepository) ImportCampaigns(ctx context.Context, campaigns []*model.Campaign) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, campaign := range campaigns {
+		r.campaigns[campaign.ID] = campaign
+	}
+	return nil
+}
+
+// ImportTargetingRules stores rules as they are, replacing those with the
+// same IDs, and moves rule ID allocation past them
+func (r *MemoryRepository) ImportTargetingRules(ctx context.Context, rules []*model.TargetingRule) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, rule := range rules {
+		if existing, exists := r.rulesByID[rule.ID]; exists {
+			r.removeRule(existing.CampaignID, rule.ID)
+		}
+		r.rulesByID[rule.ID] = rule
+		r.targetingRules[rule.CampaignID] = append(r.targetingRules[rule.CampaignID], rule)
+		r.nextRuleID = max(r.nextRuleID, rule.ID+1)
+	}
+	return nil
+}
+
+// Idempotency Repository Methods
+
+func (r *MemoryRepository) ReserveIdempotencyKey(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	if held, exists := r.idempotencyKeys[record.Key]; exists && now.Before(held.ExpiresAt) {
+		copied := *held
+		return &copied, nil
+	}
+	// Expired keys are dropped as new ones are reserved
+	for key, held := range r.idempotencyKeys {
+		if !now.Before(held.ExpiresAt) {
+			delete(r.idempotencyKeys, key)
+		}
+	}
+
+	reserved := *record
+	r.idempotencyKeys[record.Key] = &reserved
+	return nil, nil
+}
+
+func (r *MemoryRepository) CompleteIdempotencyKey(ctx context.Context, key string, result []byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record, exists := r.idempotencyKeys[key]
+	if !exists {
+		return fmt.Errorf("idempotency key %s %w", key, ErrNotFound)
+	}
+	record.Result = result
+	return nil
+}
+
+func (r *MemoryRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.idempotencyKeys, key)
+	return nil
+}
+
+// Delivery Stats Repository Methods
+
+func (r *MemoryRepository) AddDeliveryCounts(ctx context.Context, counts []DeliveryCount) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, count := range counts {
+		r.deliveryCounts[deliveryCountKey{count.TenantID, count.CampaignID, count.Day, count.Country, count.OS}] += count.Served
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetDeliveryCounts(ctx context.Context, filter DeliveryCountFilter) ([]DeliveryCount, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var counts []DeliveryCount
+	for key, served := range r.deliveryCounts {
+		if key.tenantID != filter.TenantID || (filter.CampaignID != "" && key.campaignID != filter.CampaignID) ||
+			key.day < filter.From || key.day > filter.To {
+			continue
+		}
+		counts = append(counts, DeliveryCount{
+			TenantID:   key.tenantID,
+			CampaignID: key.campaignID,
+			Day:        key.day,
+			Country:    key.country,
+			OS:         key.os,
+			Served:     served,
+		})
+	}
+	return counts, nil
+}
+
+// Kill Switch Repository Methods
+
+func (r *MemoryRepository) GetKillSwitches(ctx context.Context) ([]*model.KillSwitch, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	switches := make([]*model.KillSwitch, 0, len(r.killSwitches))
+	for _, sw := range r.killSwitches {
+		copied := *sw
+		switches = append(switches, &copied)
+	}
+	return switches, nil
+}
+
+func (r *MemoryRepository) EngageKillSwitch(ctx context.Context, sw *model.KillSwitch) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	copied := *sw
+	r.killSwitches[sw.Scope()] = &copied
+	return nil
+}
+
+func (r *MemoryRepository) ReleaseKillSwitch(ctx context.Context, scope string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.killSwitches, scope)
+	return nil
+}
+
+// Decision Log Repository Methods
+
+// AddDecisions also drops the expired decisions
+func (r *MemoryRepository) AddDecisions(ctx context.Context, decisions []*model.Decision) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	r.decisions = slices.DeleteFunc(r.decisions, func(decision *model.Decision) bool {
+		return !decision.ExpiresAt.After(now)
+	})
+	for _, decision := range decisions {
+		copied := *decision
+		r.decisions = append(r.decisions, &copied)
+	}
+	slices.SortStableFunc(r.decisions, func(a, b *model.Decision) int {
+		return a.At.Compare(b.At)
+	})
+	return nil
+}
+
+func (r *MemoryRepository) GetDecisions(ctx context.Context, filter DecisionFilter) ([]*model.Decision, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	now := time.Now()
+	decisions := []*model.Decision{}
+	for i := len(r.decisions) - 1; i >= 0 && (filter.Limit <= 0 || len(decisions) < filter.Limit); i-- {
+		decision := r.decisions[i]
+		if decision.TenantID != filter.TenantID || (filter.RequestID != "" && decision.RequestID != filter.RequestID) ||
+			!decision.ExpiresAt.After(now) {
+			continue
+		}
+		copied := *decision
+		decisions = append(decisions, &copied)
+	}
+	return decisions, nil
+}
+
 func (r *MemoryRepository) initializeSampleData() {
 	now := time.Now()
 
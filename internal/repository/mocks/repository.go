@@ -0,0 +1,985 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: interface.go
+//
+// Generated by this command:
+//
+//	mockgen -source=interface.go -destination=mocks/repository.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	repository "github.com/Harshi-itaSinha/target-engine/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCampaignRepository is a mock of CampaignRepository interface.
+type MockCampaignRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockCampaignRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockCampaignRepositoryMockRecorder is the mock recorder for MockCampaignRepository.
+type MockCampaignRepositoryMockRecorder struct {
+	mock *MockCampaignRepository
+}
+
+// NewMockCampaignRepository creates a new mock instance.
+func NewMockCampaignRepository(ctrl *gomock.Controller) *MockCampaignRepository {
+	mock := &MockCampaignRepository{ctrl: ctrl}
+	mock.recorder = &MockCampaignRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCampaignRepository) EXPECT() *MockCampaignRepositoryMockRecorder {
+	return m.recorder
+}
+
+// BulkCreateCampaigns mocks base method.
+func (m *MockCampaignRepository) BulkCreateCampaigns(ctx context.Context, campaigns []*models.Campaign, rules []*models.TargetingRule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkCreateCampaigns", ctx, campaigns, rules)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BulkCreateCampaigns indicates an expected call of BulkCreateCampaigns.
+func (mr *MockCampaignRepositoryMockRecorder) BulkCreateCampaigns(ctx, campaigns, rules any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkCreateCampaigns", reflect.TypeOf((*MockCampaignRepository)(nil).BulkCreateCampaigns), ctx, campaigns, rules)
+}
+
+// CreateCampaign mocks base method.
+func (m *MockCampaignRepository) CreateCampaign(ctx context.Context, campaign *models.Campaign) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCampaign", ctx, campaign)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateCampaign indicates an expected call of CreateCampaign.
+func (mr *MockCampaignRepositoryMockRecorder) CreateCampaign(ctx, campaign any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCampaign", reflect.TypeOf((*MockCampaignRepository)(nil).CreateCampaign), ctx, campaign)
+}
+
+// DeleteCampaign mocks base method.
+func (m *MockCampaignRepository) DeleteCampaign(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCampaign", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCampaign indicates an expected call of DeleteCampaign.
+func (mr *MockCampaignRepositoryMockRecorder) DeleteCampaign(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCampaign", reflect.TypeOf((*MockCampaignRepository)(nil).DeleteCampaign), ctx, id)
+}
+
+// GetActiveCampaigns mocks base method.
+func (m *MockCampaignRepository) GetActiveCampaigns(ctx context.Context) ([]*models.Campaign, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveCampaigns", ctx)
+	ret0, _ := ret[0].([]*models.Campaign)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveCampaigns indicates an expected call of GetActiveCampaigns.
+func (mr *MockCampaignRepositoryMockRecorder) GetActiveCampaigns(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveCampaigns", reflect.TypeOf((*MockCampaignRepository)(nil).GetActiveCampaigns), ctx)
+}
+
+// GetCampaignByID mocks base method.
+func (m *MockCampaignRepository) GetCampaignByID(ctx context.Context, id string) (*models.Campaign, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCampaignByID", ctx, id)
+	ret0, _ := ret[0].(*models.Campaign)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCampaignByID indicates an expected call of GetCampaignByID.
+func (mr *MockCampaignRepositoryMockRecorder) GetCampaignByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCampaignByID", reflect.TypeOf((*MockCampaignRepository)(nil).GetCampaignByID), ctx, id)
+}
+
+// GetCampaignsByIDs mocks base method.
+func (m *MockCampaignRepository) GetCampaignsByIDs(ctx context.Context, ids []string) ([]*models.Campaign, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCampaignsByIDs", ctx, ids)
+	ret0, _ := ret[0].([]*models.Campaign)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCampaignsByIDs indicates an expected call of GetCampaignsByIDs.
+func (mr *MockCampaignRepositoryMockRecorder) GetCampaignsByIDs(ctx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCampaignsByIDs", reflect.TypeOf((*MockCampaignRepository)(nil).GetCampaignsByIDs), ctx, ids)
+}
+
+// GetCampaignsByStatus mocks base method.
+func (m *MockCampaignRepository) GetCampaignsByStatus(ctx context.Context, statuses []string) ([]*models.Campaign, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCampaignsByStatus", ctx, statuses)
+	ret0, _ := ret[0].([]*models.Campaign)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCampaignsByStatus indicates an expected call of GetCampaignsByStatus.
+func (mr *MockCampaignRepositoryMockRecorder) GetCampaignsByStatus(ctx, statuses any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCampaignsByStatus", reflect.TypeOf((*MockCampaignRepository)(nil).GetCampaignsByStatus), ctx, statuses)
+}
+
+// GetMatchingCampaignIDs mocks base method.
+func (m *MockCampaignRepository) GetMatchingCampaignIDs(ctx context.Context, dimensions []models.Dimension) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMatchingCampaignIDs", ctx, dimensions)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMatchingCampaignIDs indicates an expected call of GetMatchingCampaignIDs.
+func (mr *MockCampaignRepositoryMockRecorder) GetMatchingCampaignIDs(ctx, dimensions any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMatchingCampaignIDs", reflect.TypeOf((*MockCampaignRepository)(nil).GetMatchingCampaignIDs), ctx, dimensions)
+}
+
+// ListCampaigns mocks base method.
+func (m *MockCampaignRepository) ListCampaigns(ctx context.Context, filter repository.CampaignFilter) ([]*models.Campaign, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCampaigns", ctx, filter)
+	ret0, _ := ret[0].([]*models.Campaign)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListCampaigns indicates an expected call of ListCampaigns.
+func (mr *MockCampaignRepositoryMockRecorder) ListCampaigns(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCampaigns", reflect.TypeOf((*MockCampaignRepository)(nil).ListCampaigns), ctx, filter)
+}
+
+// SearchCampaigns mocks base method.
+func (m *MockCampaignRepository) SearchCampaigns(ctx context.Context, search repository.CampaignSearch) ([]*models.ScoredCampaign, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchCampaigns", ctx, search)
+	ret0, _ := ret[0].([]*models.ScoredCampaign)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SearchCampaigns indicates an expected call of SearchCampaigns.
+func (mr *MockCampaignRepositoryMockRecorder) SearchCampaigns(ctx, search any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchCampaigns", reflect.TypeOf((*MockCampaignRepository)(nil).SearchCampaigns), ctx, search)
+}
+
+// UpdateCampaign mocks base method.
+func (m *MockCampaignRepository) UpdateCampaign(ctx context.Context, campaign *models.Campaign) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCampaign", ctx, campaign)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCampaign indicates an expected call of UpdateCampaign.
+func (mr *MockCampaignRepositoryMockRecorder) UpdateCampaign(ctx, campaign any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCampaign", reflect.TypeOf((*MockCampaignRepository)(nil).UpdateCampaign), ctx, campaign)
+}
+
+// UpdateCampaignStatus mocks base method.
+func (m *MockCampaignRepository) UpdateCampaignStatus(ctx context.Context, id, status string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCampaignStatus", ctx, id, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCampaignStatus indicates an expected call of UpdateCampaignStatus.
+func (mr *MockCampaignRepositoryMockRecorder) UpdateCampaignStatus(ctx, id, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCampaignStatus", reflect.TypeOf((*MockCampaignRepository)(nil).UpdateCampaignStatus), ctx, id, status)
+}
+
+// MockTargetingRuleRepository is a mock of TargetingRuleRepository interface.
+type MockTargetingRuleRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTargetingRuleRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockTargetingRuleRepositoryMockRecorder is the mock recorder for MockTargetingRuleRepository.
+type MockTargetingRuleRepositoryMockRecorder struct {
+	mock *MockTargetingRuleRepository
+}
+
+// NewMockTargetingRuleRepository creates a new mock instance.
+func NewMockTargetingRuleRepository(ctrl *gomock.Controller) *MockTargetingRuleRepository {
+	mock := &MockTargetingRuleRepository{ctrl: ctrl}
+	mock.recorder = &MockTargetingRuleRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTargetingRuleRepository) EXPECT() *MockTargetingRuleRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateTargetingRule mocks base method.
+func (m *MockTargetingRuleRepository) CreateTargetingRule(ctx context.Context, rule *models.TargetingRule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTargetingRule", ctx, rule)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateTargetingRule indicates an expected call of CreateTargetingRule.
+func (mr *MockTargetingRuleRepositoryMockRecorder) CreateTargetingRule(ctx, rule any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTargetingRule", reflect.TypeOf((*MockTargetingRuleRepository)(nil).CreateTargetingRule), ctx, rule)
+}
+
+// DeleteTargetingRule mocks base method.
+func (m *MockTargetingRuleRepository) DeleteTargetingRule(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTargetingRule", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTargetingRule indicates an expected call of DeleteTargetingRule.
+func (mr *MockTargetingRuleRepositoryMockRecorder) DeleteTargetingRule(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTargetingRule", reflect.TypeOf((*MockTargetingRuleRepository)(nil).DeleteTargetingRule), ctx, id)
+}
+
+// DeleteTargetingRulesByCampaignID mocks base method.
+func (m *MockTargetingRuleRepository) DeleteTargetingRulesByCampaignID(ctx context.Context, campaignID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTargetingRulesByCampaignID", ctx, campaignID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTargetingRulesByCampaignID indicates an expected call of DeleteTargetingRulesByCampaignID.
+func (mr *MockTargetingRuleRepositoryMockRecorder) DeleteTargetingRulesByCampaignID(ctx, campaignID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTargetingRulesByCampaignID", reflect.TypeOf((*MockTargetingRuleRepository)(nil).DeleteTargetingRulesByCampaignID), ctx, campaignID)
+}
+
+// GetTargetingRuleByID mocks base method.
+func (m *MockTargetingRuleRepository) GetTargetingRuleByID(ctx context.Context, id int64) (*models.TargetingRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTargetingRuleByID", ctx, id)
+	ret0, _ := ret[0].(*models.TargetingRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTargetingRuleByID indicates an expected call of GetTargetingRuleByID.
+func (mr *MockTargetingRuleRepositoryMockRecorder) GetTargetingRuleByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTargetingRuleByID", reflect.TypeOf((*MockTargetingRuleRepository)(nil).GetTargetingRuleByID), ctx, id)
+}
+
+// GetTargetingRules mocks base method.
+func (m *MockTargetingRuleRepository) GetTargetingRules(ctx context.Context) ([]*models.TargetingRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTargetingRules", ctx)
+	ret0, _ := ret[0].([]*models.TargetingRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTargetingRules indicates an expected call of GetTargetingRules.
+func (mr *MockTargetingRuleRepositoryMockRecorder) GetTargetingRules(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTargetingRules", reflect.TypeOf((*MockTargetingRuleRepository)(nil).GetTargetingRules), ctx)
+}
+
+// GetTargetingRulesByCampaignID mocks base method.
+func (m *MockTargetingRuleRepository) GetTargetingRulesByCampaignID(ctx context.Context, campaignID string) ([]*models.TargetingRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTargetingRulesByCampaignID", ctx, campaignID)
+	ret0, _ := ret[0].([]*models.TargetingRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTargetingRulesByCampaignID indicates an expected call of GetTargetingRulesByCampaignID.
+func (mr *MockTargetingRuleRepositoryMockRecorder) GetTargetingRulesByCampaignID(ctx, campaignID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTargetingRulesByCampaignID", reflect.TypeOf((*MockTargetingRuleRepository)(nil).GetTargetingRulesByCampaignID), ctx, campaignID)
+}
+
+// UpdateTargetingRule mocks base method.
+func (m *MockTargetingRuleRepository) UpdateTargetingRule(ctx context.Context, rule *models.TargetingRule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTargetingRule", ctx, rule)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateTargetingRule indicates an expected call of UpdateTargetingRule.
+func (mr *MockTargetingRuleRepositoryMockRecorder) UpdateTargetingRule(ctx, rule any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTargetingRule", reflect.TypeOf((*MockTargetingRuleRepository)(nil).UpdateTargetingRule), ctx, rule)
+}
+
+// MockIdempotencyRepository is a mock of IdempotencyRepository interface.
+type MockIdempotencyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockIdempotencyRepositoryMockRecorder is the mock recorder for MockIdempotencyRepository.
+type MockIdempotencyRepositoryMockRecorder struct {
+	mock *MockIdempotencyRepository
+}
+
+// NewMockIdempotencyRepository creates a new mock instance.
+func NewMockIdempotencyRepository(ctrl *gomock.Controller) *MockIdempotencyRepository {
+	mock := &MockIdempotencyRepository{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyRepository) EXPECT() *MockIdempotencyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CompleteIdempotencyKey mocks base method.
+func (m *MockIdempotencyRepository) CompleteIdempotencyKey(ctx context.Context, key string, result []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteIdempotencyKey", ctx, key, result)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteIdempotencyKey indicates an expected call of CompleteIdempotencyKey.
+func (mr *MockIdempotencyRepositoryMockRecorder) CompleteIdempotencyKey(ctx, key, result any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteIdempotencyKey", reflect.TypeOf((*MockIdempotencyRepository)(nil).CompleteIdempotencyKey), ctx, key, result)
+}
+
+// ReleaseIdempotencyKey mocks base method.
+func (m *MockIdempotencyRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseIdempotencyKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseIdempotencyKey indicates an expected call of ReleaseIdempotencyKey.
+func (mr *MockIdempotencyRepositoryMockRecorder) ReleaseIdempotencyKey(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseIdempotencyKey", reflect.TypeOf((*MockIdempotencyRepository)(nil).ReleaseIdempotencyKey), ctx, key)
+}
+
+// ReserveIdempotencyKey mocks base method.
+func (m *MockIdempotencyRepository) ReserveIdempotencyKey(ctx context.Context, record *repository.IdempotencyRecord) (*repository.IdempotencyRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveIdempotencyKey", ctx, record)
+	ret0, _ := ret[0].(*repository.IdempotencyRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveIdempotencyKey indicates an expected call of ReserveIdempotencyKey.
+func (mr *MockIdempotencyRepositoryMockRecorder) ReserveIdempotencyKey(ctx, record any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveIdempotencyKey", reflect.TypeOf((*MockIdempotencyRepository)(nil).ReserveIdempotencyKey), ctx, record)
+}
+
+// MockDeliveryStatsRepository is a mock of DeliveryStatsRepository interface.
+type MockDeliveryStatsRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeliveryStatsRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockDeliveryStatsRepositoryMockRecorder is the mock recorder for MockDeliveryStatsRepository.
+type MockDeliveryStatsRepositoryMockRecorder struct {
+	mock *MockDeliveryStatsRepository
+}
+
+// NewMockDeliveryStatsRepository creates a new mock instance.
+func NewMockDeliveryStatsRepository(ctrl *gomock.Controller) *MockDeliveryStatsRepository {
+	mock := &MockDeliveryStatsRepository{ctrl: ctrl}
+	mock.recorder = &MockDeliveryStatsRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeliveryStatsRepository) EXPECT() *MockDeliveryStatsRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddDeliveryCounts mocks base method.
+func (m *MockDeliveryStatsRepository) AddDeliveryCounts(ctx context.Context, counts []repository.DeliveryCount) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDeliveryCounts", ctx, counts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDeliveryCounts indicates an expected call of AddDeliveryCounts.
+func (mr *MockDeliveryStatsRepositoryMockRecorder) AddDeliveryCounts(ctx, counts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDeliveryCounts", reflect.TypeOf((*MockDeliveryStatsRepository)(nil).AddDeliveryCounts), ctx, counts)
+}
+
+// GetDeliveryCounts mocks base method.
+func (m *MockDeliveryStatsRepository) GetDeliveryCounts(ctx context.Context, filter repository.DeliveryCountFilter) ([]repository.DeliveryCount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeliveryCounts", ctx, filter)
+	ret0, _ := ret[0].([]repository.DeliveryCount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeliveryCounts indicates an expected call of GetDeliveryCounts.
+func (mr *MockDeliveryStatsRepositoryMockRecorder) GetDeliveryCounts(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeliveryCounts", reflect.TypeOf((*MockDeliveryStatsRepository)(nil).GetDeliveryCounts), ctx, filter)
+}
+
+// MockKillSwitchRepository is a mock of KillSwitchRepository interface.
+type MockKillSwitchRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockKillSwitchRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockKillSwitchRepositoryMockRecorder is the mock recorder for MockKillSwitchRepository.
+type MockKillSwitchRepositoryMockRecorder struct {
+	mock *MockKillSwitchRepository
+}
+
+// NewMockKillSwitchRepository creates a new mock instance.
+func NewMockKillSwitchRepository(ctrl *gomock.Controller) *MockKillSwitchRepository {
+	mock := &MockKillSwitchRepository{ctrl: ctrl}
+	mock.recorder = &MockKillSwitchRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKillSwitchRepository) EXPECT() *MockKillSwitchRepositoryMockRecorder {
+	return m.recorder
+}
+
+// EngageKillSwitch mocks base method.
+func (m *MockKillSwitchRepository) EngageKillSwitch(ctx context.Context, sw *models.KillSwitch) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EngageKillSwitch", ctx, sw)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EngageKillSwitch indicates an expected call of EngageKillSwitch.
+func (mr *MockKillSwitchRepositoryMockRecorder) EngageKillSwitch(ctx, sw any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EngageKillSwitch", reflect.TypeOf((*MockKillSwitchRepository)(nil).EngageKillSwitch), ctx, sw)
+}
+
+// GetKillSwitches mocks base method.
+func (m *MockKillSwitchRepository) GetKillSwitches(ctx context.Context) ([]*models.KillSwitch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetKillSwitches", ctx)
+	ret0, _ := ret[0].([]*models.KillSwitch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetKillSwitches indicates an expected call of GetKillSwitches.
+func (mr *MockKillSwitchRepositoryMockRecorder) GetKillSwitches(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKillSwitches", reflect.TypeOf((*MockKillSwitchRepository)(nil).GetKillSwitches), ctx)
+}
+
+// ReleaseKillSwitch mocks base method.
+func (m *MockKillSwitchRepository) ReleaseKillSwitch(ctx context.Context, scope string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseKillSwitch", ctx, scope)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseKillSwitch indicates an expected call of ReleaseKillSwitch.
+func (mr *MockKillSwitchRepositoryMockRecorder) ReleaseKillSwitch(ctx, scope any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseKillSwitch", reflect.TypeOf((*MockKillSwitchRepository)(nil).ReleaseKillSwitch), ctx, scope)
+}
+
+// MockDecisionLogRepository is a mock of DecisionLogRepository interface.
+type MockDecisionLogRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockDecisionLogRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockDecisionLogRepositoryMockRecorder is the mock recorder for MockDecisionLogRepository.
+type MockDecisionLogRepositoryMockRecorder struct {
+	mock *MockDecisionLogRepository
+}
+
+// NewMockDecisionLogRepository creates a new mock instance.
+func NewMockDecisionLogRepository(ctrl *gomock.Controller) *MockDecisionLogRepository {
+	mock := &MockDecisionLogRepository{ctrl: ctrl}
+	mock.recorder = &MockDecisionLogRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDecisionLogRepository) EXPECT() *MockDecisionLogRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddDecisions mocks base method.
+func (m *MockDecisionLogRepository) AddDecisions(ctx context.Context, decisions []*models.Decision) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDecisions", ctx, decisions)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDecisions indicates an expected call of AddDecisions.
+func (mr *MockDecisionLogRepositoryMockRecorder) AddDecisions(ctx, decisions any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDecisions", reflect.TypeOf((*MockDecisionLogRepository)(nil).AddDecisions), ctx, decisions)
+}
+
+// GetDecisions mocks base method.
+func (m *MockDecisionLogRepository) GetDecisions(ctx context.Context, filter repository.DecisionFilter) ([]*models.Decision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDecisions", ctx, filter)
+	ret0, _ := ret[0].([]*models.Decision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDecisions indicates an expected call of GetDecisions.
+func (mr *MockDecisionLogRepositoryMockRecorder) GetDecisions(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDecisions", reflect.TypeOf((*MockDecisionLogRepository)(nil).GetDecisions), ctx, filter)
+}
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Campaign mocks base method.
+func (m *MockRepository) Campaign() repository.CampaignRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Campaign")
+	ret0, _ := ret[0].(repository.CampaignRepository)
+	return ret0
+}
+
+// Campaign indicates an expected call of Campaign.
+func (mr *MockRepositoryMockRecorder) Campaign() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Campaign", reflect.TypeOf((*MockRepository)(nil).Campaign))
+}
+
+// Close mocks base method.
+func (m *MockRepository) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockRepositoryMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockRepository)(nil).Close))
+}
+
+// DecisionLog mocks base method.
+func (m *MockRepository) DecisionLog() repository.DecisionLogRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecisionLog")
+	ret0, _ := ret[0].(repository.DecisionLogRepository)
+	return ret0
+}
+
+// DecisionLog indicates an expected call of DecisionLog.
+func (mr *MockRepositoryMockRecorder) DecisionLog() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecisionLog", reflect.TypeOf((*MockRepository)(nil).DecisionLog))
+}
+
+// DeliveryStats mocks base method.
+func (m *MockRepository) DeliveryStats() repository.DeliveryStatsRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeliveryStats")
+	ret0, _ := ret[0].(repository.DeliveryStatsRepository)
+	return ret0
+}
+
+// DeliveryStats indicates an expected call of DeliveryStats.
+func (mr *MockRepositoryMockRecorder) DeliveryStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeliveryStats", reflect.TypeOf((*MockRepository)(nil).DeliveryStats))
+}
+
+// Idempotency mocks base method.
+func (m *MockRepository) Idempotency() repository.IdempotencyRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Idempotency")
+	ret0, _ := ret[0].(repository.IdempotencyRepository)
+	return ret0
+}
+
+// Idempotency indicates an expected call of Idempotency.
+func (mr *MockRepositoryMockRecorder) Idempotency() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Idempotency", reflect.TypeOf((*MockRepository)(nil).Idempotency))
+}
+
+// KillSwitch mocks base method.
+func (m *MockRepository) KillSwitch() repository.KillSwitchRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KillSwitch")
+	ret0, _ := ret[0].(repository.KillSwitchRepository)
+	return ret0
+}
+
+// KillSwitch indicates an expected call of KillSwitch.
+func (mr *MockRepositoryMockRecorder) KillSwitch() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KillSwitch", reflect.TypeOf((*MockRepository)(nil).KillSwitch))
+}
+
+// TargetingRule mocks base method.
+func (m *MockRepository) TargetingRule() repository.TargetingRuleRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TargetingRule")
+	ret0, _ := ret[0].(repository.TargetingRuleRepository)
+	return ret0
+}
+
+// TargetingRule indicates an expected call of TargetingRule.
+func (mr *MockRepositoryMockRecorder) TargetingRule() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TargetingRule", reflect.TypeOf((*MockRepository)(nil).TargetingRule))
+}
+
+// MockRepositoryManager is a mock of RepositoryManager interface.
+type MockRepositoryManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryManagerMockRecorder
+	isgomock struct{}
+}
+
+// MockRepositoryManagerMockRecorder is the mock recorder for MockRepositoryManager.
+type MockRepositoryManagerMockRecorder struct {
+	mock *MockRepositoryManager
+}
+
+// NewMockRepositoryManager creates a new mock instance.
+func NewMockRepositoryManager(ctrl *gomock.Controller) *MockRepositoryManager {
+	mock := &MockRepositoryManager{ctrl: ctrl}
+	mock.recorder = &MockRepositoryManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepositoryManager) EXPECT() *MockRepositoryManagerMockRecorder {
+	return m.recorder
+}
+
+// Campaign mocks base method.
+func (m *MockRepositoryManager) Campaign() repository.CampaignRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Campaign")
+	ret0, _ := ret[0].(repository.CampaignRepository)
+	return ret0
+}
+
+// Campaign indicates an expected call of Campaign.
+func (mr *MockRepositoryManagerMockRecorder) Campaign() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Campaign", reflect.TypeOf((*MockRepositoryManager)(nil).Campaign))
+}
+
+// Close mocks base method.
+func (m *MockRepositoryManager) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockRepositoryManagerMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockRepositoryManager)(nil).Close))
+}
+
+// DecisionLog mocks base method.
+func (m *MockRepositoryManager) DecisionLog() repository.DecisionLogRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecisionLog")
+	ret0, _ := ret[0].(repository.DecisionLogRepository)
+	return ret0
+}
+
+// DecisionLog indicates an expected call of DecisionLog.
+func (mr *MockRepositoryManagerMockRecorder) DecisionLog() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecisionLog", reflect.TypeOf((*MockRepositoryManager)(nil).DecisionLog))
+}
+
+// DeliveryStats mocks base method.
+func (m *MockRepositoryManager) DeliveryStats() repository.DeliveryStatsRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeliveryStats")
+	ret0, _ := ret[0].(repository.DeliveryStatsRepository)
+	return ret0
+}
+
+// DeliveryStats indicates an expected call of DeliveryStats.
+func (mr *MockRepositoryManagerMockRecorder) DeliveryStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeliveryStats", reflect.TypeOf((*MockRepositoryManager)(nil).DeliveryStats))
+}
+
+// Health mocks base method.
+func (m *MockRepositoryManager) Health(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Health", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Health indicates an expected call of Health.
+func (mr *MockRepositoryManagerMockRecorder) Health(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*MockRepositoryManager)(nil).Health), ctx)
+}
+
+// Idempotency mocks base method.
+func (m *MockRepositoryManager) Idempotency() repository.IdempotencyRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Idempotency")
+	ret0, _ := ret[0].(repository.IdempotencyRepository)
+	return ret0
+}
+
+// Idempotency indicates an expected call of Idempotency.
+func (mr *MockRepositoryManagerMockRecorder) Idempotency() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Idempotency", reflect.TypeOf((*MockRepositoryManager)(nil).Idempotency))
+}
+
+// KillSwitch mocks base method.
+func (m *MockRepositoryManager) KillSwitch() repository.KillSwitchRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KillSwitch")
+	ret0, _ := ret[0].(repository.KillSwitchRepository)
+	return ret0
+}
+
+// KillSwitch indicates an expected call of KillSwitch.
+func (mr *MockRepositoryManagerMockRecorder) KillSwitch() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KillSwitch", reflect.TypeOf((*MockRepositoryManager)(nil).KillSwitch))
+}
+
+// Migrate mocks base method.
+func (m *MockRepositoryManager) Migrate(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Migrate", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Migrate indicates an expected call of Migrate.
+func (mr *MockRepositoryManagerMockRecorder) Migrate(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Migrate", reflect.TypeOf((*MockRepositoryManager)(nil).Migrate), ctx)
+}
+
+// TargetingRule mocks base method.
+func (m *MockRepositoryManager) TargetingRule() repository.TargetingRuleRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TargetingRule")
+	ret0, _ := ret[0].(repository.TargetingRuleRepository)
+	return ret0
+}
+
+// TargetingRule indicates an expected call of TargetingRule.
+func (mr *MockRepositoryManagerMockRecorder) TargetingRule() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TargetingRule", reflect.TypeOf((*MockRepositoryManager)(nil).TargetingRule))
+}
+
+// MockImporter is a mock of Importer interface.
+type MockImporter struct {
+	ctrl     *gomock.Controller
+	recorder *MockImporterMockRecorder
+	isgomock struct{}
+}
+
+// MockImporterMockRecorder is the mock recorder for MockImporter.
+type MockImporterMockRecorder struct {
+	mock *MockImporter
+}
+
+// NewMockImporter creates a new mock instance.
+func NewMockImporter(ctrl *gomock.Controller) *MockImporter {
+	mock := &MockImporter{ctrl: ctrl}
+	mock.recorder = &MockImporterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockImporter) EXPECT() *MockImporterMockRecorder {
+	return m.recorder
+}
+
+// ImportCampaigns mocks base method.
+func (m *MockImporter) ImportCampaigns(ctx context.Context, campaigns []*models.Campaign) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportCampaigns", ctx, campaigns)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ImportCampaigns indicates an expected call of ImportCampaigns.
+func (mr *MockImporterMockRecorder) ImportCampaigns(ctx, campaigns any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportCampaigns", reflect.TypeOf((*MockImporter)(nil).ImportCampaigns), ctx, campaigns)
+}
+
+// ImportTargetingRules mocks base method.
+func (m *MockImporter) ImportTargetingRules(ctx context.Context, rules []*models.TargetingRule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportTargetingRules", ctx, rules)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ImportTargetingRules indicates an expected call of ImportTargetingRules.
+func (mr *MockImporterMockRecorder) ImportTargetingRules(ctx, rules any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportTargetingRules", reflect.TypeOf((*MockImporter)(nil).ImportTargetingRules), ctx, rules)
+}
+
+// MockTransactor is a mock of Transactor interface.
+type MockTransactor struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransactorMockRecorder
+	isgomock struct{}
+}
+
+// MockTransactorMockRecorder is the mock recorder for MockTransactor.
+type MockTransactorMockRecorder struct {
+	mock *MockTransactor
+}
+
+// NewMockTransactor creates a new mock instance.
+func NewMockTransactor(ctrl *gomock.Controller) *MockTransactor {
+	mock := &MockTransactor{ctrl: ctrl}
+	mock.recorder = &MockTransactorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransactor) EXPECT() *MockTransactorMockRecorder {
+	return m.recorder
+}
+
+// WithTransaction mocks base method.
+func (m *MockTransactor) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTransaction", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTransaction indicates an expected call of WithTransaction.
+func (mr *MockTransactorMockRecorder) WithTransaction(ctx, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTransaction", reflect.TypeOf((*MockTransactor)(nil).WithTransaction), ctx, fn)
+}
+
+// MockChangeWatcher is a mock of ChangeWatcher interface.
+type MockChangeWatcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockChangeWatcherMockRecorder
+	isgomock struct{}
+}
+
+// MockChangeWatcherMockRecorder is the mock recorder for MockChangeWatcher.
+type MockChangeWatcherMockRecorder struct {
+	mock *MockChangeWatcher
+}
+
+// NewMockChangeWatcher creates a new mock instance.
+func NewMockChangeWatcher(ctrl *gomock.Controller) *MockChangeWatcher {
+	mock := &MockChangeWatcher{ctrl: ctrl}
+	mock.recorder = &MockChangeWatcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockChangeWatcher) EXPECT() *MockChangeWatcherMockRecorder {
+	return m.recorder
+}
+
+// WatchChanges mocks base method.
+func (m *MockChangeWatcher) WatchChanges(ctx context.Context, handle func(repository.ChangeEvent)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchChanges", ctx, handle)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WatchChanges indicates an expected call of WatchChanges.
+func (mr *MockChangeWatcherMockRecorder) WatchChanges(ctx, handle any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchChanges", reflect.TypeOf((*MockChangeWatcher)(nil).WatchChanges), ctx, handle)
+}
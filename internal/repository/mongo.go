@@ -4,7 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	models "github.com/Harshi-itaSinha/target-engine/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -27,11 +32,33 @@ const (
 	CollectionCampaigns      = "campaigns"
 	CollectionTargetingRules = "targeting_rules"
 	CollectionActiveCampaign = "active_targeting_rules" // pre-computed
+	CollectionCounters       = "counters"
+	CollectionIdempotency    = "idempotency_keys"
+	CollectionDeliveryStats  = "delivery_stats"
+	CollectionKillSwitches   = "kill_switches"
+	CollectionDecisionLog    = "decision_log"
 )
 
+// mappingDimensions lists every dimension written to the pre-computed mapping
+// collection. Each active campaign gets at least one document per dimension so
+// the match pipeline can require full dimension coverage.
+var mappingDimensions = models.DeliveryDimensions
+
+// mappingNormalizers normalize the values of mapped dimensions as delivery
+// requests are normalized. Values of other dimensions are mapped as they are.
+var mappingNormalizers = map[string]func(string) string{
+	"country":     strings.ToUpper,
+	"os":          strings.ToLower,
+	"device_type": strings.ToLower,
+	"region":      strings.ToUpper,
+	"city":        normalizeCity,
+}
+
 type RepositoryImpl struct {
 	client   *mongo.Client
 	database *mongo.Database
+	// transactions caches whether the deployment supports transactions
+	transactions atomic.Int32
 }
 
 // NewRepository creates a new RepositoryImpl with an injected MongoDB collection.
@@ -65,6 +92,26 @@ func (r *RepositoryImpl) TargetingRule() TargetingRuleRepository {
 	return r
 }
 
+// Idempotency returns the IdempotencyRepository implementation.
+func (r *RepositoryImpl) Idempotency() IdempotencyRepository {
+	return r
+}
+
+// DeliveryStats returns the DeliveryStatsRepository implementation.
+func (r *RepositoryImpl) DeliveryStats() DeliveryStatsRepository {
+	return r
+}
+
+// KillSwitch returns the KillSwitchRepository implementation.
+func (r *RepositoryImpl) KillSwitch() KillSwitchRepository {
+	return r
+}
+
+// DecisionLog returns the DecisionLogRepository implementation.
+func (r *RepositoryImpl) DecisionLog() DecisionLogRepository {
+	return r
+}
+
 // Close closes the MongoDB client (noop if not set, assuming collection is injected).
 func (r *RepositoryImpl) Close() error {
 	// Note: Client is not managed here since collection is injected. Close should be handled by the caller (e.g., config).
@@ -79,15 +126,75 @@ func (r *RepositoryImpl) Health(ctx context.Context) error {
 	return r.client.Ping(ctx, nil)
 }
 
-// Migrate sets up the MongoDB collection with indexes (simplified migration).
+// Migrate sets up the MongoDB collections with indexes (simplified migration).
 func (r *RepositoryImpl) Migrate(ctx context.Context) error {
-	indexes := []mongo.IndexModel{
-		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "dimension", Value: 1}, {Key: "value", Value: 1}}},
-		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "campaign_id", Value: 1}}},
-		{Keys: bson.D{{Key: "campaign_details.status", Value: 1}}},
+	campaignIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "cid", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+		// Support ListCampaigns' newest-first ordering, with and without a status filter
+		{Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "cid", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}, {Key: "cid", Value: 1}}},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}, {Key: "cid", Value: 1}}},
+		// Full-text index for SearchCampaigns
+		{
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "cta", Value: "text"}},
+			Options: options.Index().SetName("campaign_search").
+				SetWeights(bson.D{{Key: "name", Value: nameSearchWeight}, {Key: "cta", Value: ctaSearchWeight}}),
+		},
 	}
-	_, err := r.GetCollection(CollectionCampaigns).Indexes().CreateMany(ctx, indexes)
-	return err
+	if _, err := r.GetCollection(CollectionCampaigns).Indexes().CreateMany(ctx, campaignIndexes); err != nil {
+		return err
+	}
+
+	ruleIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "campaign_id", Value: 1}}},
+	}
+	if _, err := r.GetCollection(CollectionTargetingRules).Indexes().CreateMany(ctx, ruleIndexes); err != nil {
+		return err
+	}
+
+	mappingIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "dimension", Value: 1}, {Key: "type", Value: 1}, {Key: "values", Value: 1}}},
+		{Keys: bson.D{{Key: "campaign_id", Value: 1}}},
+	}
+	if _, err := r.GetCollection(CollectionActiveCampaign).Indexes().CreateMany(ctx, mappingIndexes); err != nil {
+		return err
+	}
+
+	// MongoDB removes expired idempotency records in the background
+	idempotencyIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	if _, err := r.GetCollection(CollectionIdempotency).Indexes().CreateOne(ctx, idempotencyIndex); err != nil {
+		return err
+	}
+
+	// One document per tenant, campaign, day, country and OS, read by day range
+	deliveryStatsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "day", Value: 1}, {Key: "campaign_id", Value: 1}, {Key: "country", Value: 1}, {Key: "os", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "campaign_id", Value: 1}, {Key: "day", Value: 1}}},
+	}
+	if _, err := r.GetCollection(CollectionDeliveryStats).Indexes().CreateMany(ctx, deliveryStatsIndexes); err != nil {
+		return err
+	}
+
+	// Decisions are looked up by request ID or listed newest first, and
+	// MongoDB removes expired ones in the background
+	decisionLogIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "request_id", Value: 1}}},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "at", Value: -1}}},
+	}
+	if _, err := r.GetCollection(CollectionDecisionLog).Indexes().CreateMany(ctx, decisionLogIndexes); err != nil {
+		return err
+	}
+
+	return r.backfillMappings(ctx)
 }
 
 func (r *MongoCampaignRepo) FindActiveCampaigns() ([]*models.Campaign, error) {
@@ -111,65 +218,51 @@ func (r *MongoCampaignRepo) FindActiveCampaigns() ([]*models.Campaign, error) {
 	return results, nil
 }
 
+// CampaignRepository implementation
 // CampaignRepository implementation
 func (r *RepositoryImpl) GetActiveCampaigns(ctx context.Context) ([]*models.Campaign, error) {
-	filter := bson.M{"type": "rule", "campaign_details.status": "ACTIVE"}
-	cursor, err := r.GetCollection(CollectionCampaigns).Find(ctx, filter, options.Find().SetProjection(bson.M{
-		"campaign_id":            1,
-		"campaign_details.name":  1,
-		"campaign_details.image": 1,
-		"campaign_details.cta":   1,
-	}))
+	ctx, span := startMongoSpan(ctx, "find", CollectionCampaigns)
+	defer span.End()
+
+	cursor, err := r.GetCollection(CollectionCampaigns).Find(ctx, bson.M{"status": models.StatusActive})
 	if err != nil {
-		return nil, err
+		return nil, spanError(span, err)
 	}
 	defer cursor.Close(ctx)
 
 	campaigns := make([]*models.Campaign, 0)
-	for cursor.Next(ctx) {
-		var result struct {
-			CampaignID string `bson:"campaign_id"`
-			Details    struct {
-				Name  string `bson:"name"`
-				Image string `bson:"image"`
-				CTA   string `bson:"cta"`
-			} `bson:"campaign_details"`
-		}
-		if err := cursor.Decode(&result); err != nil {
-			return nil, err
-		}
-		campaigns = append(campaigns, &models.Campaign{
-			ID:    result.CampaignID,
-			Name:  result.Details.Name,
-			Image: result.Details.Image,
-			CTA:   result.Details.CTA,
-		})
+	if err := cursor.All(ctx, &campaigns); err != nil {
+		return nil, spanError(span, fmt.Errorf("failed to decode campaigns: %w", err))
 	}
-	if err := cursor.Err(); err != nil {
-		return nil, err
+	return campaigns, nil
+}
+
+func (r *RepositoryImpl) GetCampaignsByStatus(ctx context.Context, statuses []string) ([]*models.Campaign, error) {
+	ctx, span := startMongoSpan(ctx, "find", CollectionCampaigns)
+	defer span.End()
+
+	cursor, err := r.GetCollection(CollectionCampaigns).Find(ctx, bson.M{"status": bson.M{"$in": statuses}})
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer cursor.Close(ctx)
+
+	campaigns := make([]*models.Campaign, 0)
+	if err := cursor.All(ctx, &campaigns); err != nil {
+		return nil, spanError(span, fmt.Errorf("failed to decode campaigns: %w", err))
 	}
 	return campaigns, nil
 }
 
 func (r *RepositoryImpl) GetCampaignByID(ctx context.Context, id string) (*models.Campaign, error) {
-	filter := bson.M{"type": "rule", "campaign_id": id, "campaign_details.status": "ACTIVE"}
-	var result struct {
-		CampaignID string `bson:"campaign_id"`
-		Details    struct {
-			Name  string `bson:"name"`
-			Image string `bson:"image"`
-			CTA   string `bson:"cta"`
-		} `bson:"campaign_details"`
-	}
-	if err := r.GetCollection(CollectionCampaigns).FindOne(ctx, filter).Decode(&result); err != nil {
+	var campaign models.Campaign
+	if err := r.GetCollection(CollectionCampaigns).FindOne(ctx, bson.M{"cid": id}).Decode(&campaign); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("campaign with ID %s %w", id, ErrNotFound)
+		}
 		return nil, err
 	}
-	return &models.Campaign{
-		ID:    result.CampaignID,
-		Name:  result.Details.Name,
-		Image: result.Details.Image,
-		CTA:   result.Details.CTA,
-	}, nil
+	return &campaign, nil
 }
 
 func (r *RepositoryImpl) GetCampaignsByIDs(ctx context.Context, ids []string) ([]*models.Campaign, error) {
@@ -177,19 +270,22 @@ func (r *RepositoryImpl) GetCampaignsByIDs(ctx context.Context, ids []string) ([
 		return nil, nil
 	}
 
+	ctx, span := startMongoSpan(ctx, "find", CollectionCampaigns)
+	defer span.End()
+
 	filter := bson.M{"cid": bson.M{"$in": ids}}
 	cursor, err := r.GetCollection(CollectionCampaigns).Find(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch campaigns by cid: %w", err)
+		return nil, spanError(span, fmt.Errorf("failed to fetch campaigns by cid: %w", err))
 	}
 	defer cursor.Close(ctx)
 
 	campaigns := []*models.Campaign{}
 	if err := cursor.All(ctx, &campaigns); err != nil {
-		return nil, fmt.Errorf("failed to decode campaigns: %w", err)
+		return nil, spanError(span, fmt.Errorf("failed to decode campaigns: %w", err))
 	}
 
-	log.Printf("Found %d campaigns using cid field", len(campaigns))
+	slog.DebugContext(ctx, "fetched campaigns by cid", "requested", len(ids), "found", len(campaigns))
 	
 	if len(campaigns) == 0 {
 		return nil, nil
@@ -202,50 +298,285 @@ func (r *RepositoryImpl) GetCampaignsByIDs(ctx context.Context, ids []string) ([
 
 }
 
+
+// ListCampaigns pages through campaigns newest first. Search is an unanchored
+// case-insensitive regex, so it scans the campaigns left by the other filters.
+func (r *RepositoryImpl) ListCampaigns(ctx context.Context, filter CampaignFilter) ([]*models.Campaign, int64, error) {
+	ctx, span := startMongoSpan(ctx, "find", CollectionCampaigns)
+	defer span.End()
+
+	query := bson.M{"tenant_id": filter.TenantID}
+	if filter.TenantID == "" {
+		// Campaigns written before multi-tenancy have no tenant_id field
+		query["tenant_id"] = bson.M{"$in": bson.A{nil, ""}}
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	} else if !filter.IncludeArchived {
+		query["status"] = bson.M{"$ne": models.StatusArchived}
+	}
+	if !filter.CreatedAfter.IsZero() {
+		query["created_at"] = bson.M{"$gt": filter.CreatedAfter}
+	}
+	if filter.Search != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(filter.Search), Options: "i"}
+		query["$or"] = bson.A{bson.M{"cid": pattern}, bson.M{"name": pattern}}
+	}
+
+	collection := r.GetCollection(CollectionCampaigns)
+	total, err := collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, spanError(span, fmt.Errorf("failed to count campaigns: %w", err))
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "cid", Value: 1}}).
+		SetSkip(int64(filter.Offset))
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, spanError(span, fmt.Errorf("failed to list campaigns: %w", err))
+	}
+	defer cursor.Close(ctx)
+
+	campaigns := make([]*models.Campaign, 0)
+	if err := cursor.All(ctx, &campaigns); err != nil {
+		return nil, 0, spanError(span, fmt.Errorf("failed to decode campaigns: %w", err))
+	}
+	return campaigns, total, nil
+}
+
+// SearchCampaigns runs a $text query against the campaign_search index,
+// ordered by text score. MongoDB matches whole words, stemmed, rather than
+// substrings.
+func (r *RepositoryImpl) SearchCampaigns(ctx context.Context, search CampaignSearch) ([]*models.ScoredCampaign, int64, error) {
+	ctx, span := startMongoSpan(ctx, "find", CollectionCampaigns)
+	defer span.End()
+
+	query := bson.M{
+		"$text":     bson.M{"$search": search.Query},
+		"tenant_id": search.TenantID,
+	}
+	if search.TenantID == "" {
+		// Campaigns written before multi-tenancy have no tenant_id field
+		query["tenant_id"] = bson.M{"$in": bson.A{nil, ""}}
+	}
+	if !search.IncludeArchived {
+		query["status"] = bson.M{"$ne": models.StatusArchived}
+	}
+
+	collection := r.GetCollection(CollectionCampaigns)
+	total, err := collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, spanError(span, fmt.Errorf("failed to count campaigns: %w", err))
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "created_at", Value: -1}, {Key: "cid", Value: 1}}).
+		SetSkip(int64(search.Offset))
+	if search.Limit > 0 {
+		opts.SetLimit(int64(search.Limit))
+	}
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, spanError(span, fmt.Errorf("failed to search campaigns: %w", err))
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		models.Campaign `bson:",inline"`
+		Score           float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, spanError(span, fmt.Errorf("failed to decode campaigns: %w", err))
+	}
+	campaigns := make([]*models.ScoredCampaign, 0, len(docs))
+	for i := range docs {
+		campaigns = append(campaigns, &models.ScoredCampaign{Campaign: &docs[i].Campaign, Score: docs[i].Score})
+	}
+	return campaigns, total, nil
+}
+
 func (r *RepositoryImpl) CreateCampaign(ctx context.Context, campaign *models.Campaign) error {
-	// Assuming campaign includes rules; create rule documents
-	// for _, rule := range campaign.Rules {
-	// 	doc := bson.M{
-	// 		"type":        "rule",
-	// 		"campaign_id": campaign.ID,
-	// 		"dimension":   rule.Dimension,
-	// 		"include":     rule.Include,
-	// 		"exclude":     rule.Exclude,
-	// 		"campaign_details": bson.M{
-	// 			"name":   campaign.Name,
-	// 			"image":  campaign.Image,
-	// 			"cta":    campaign.CTA,
-	// 			"status": campaign.Status,
-	// 		},
-	// 	}
-	// 	if _, err := r.GetCollection(CollectionCampaigns).InsertOne(ctx, doc); err != nil {
-	// 		return err
-	// 	}
-	// 	// Update mappings (simplified; use Change Streams in production)
-	// 	if err := r.updateMappings(ctx, campaign.ID, rule); err != nil {
-	// 		return err
-	// 	}
-	// }
-	// return nil
-	return nil
+	now := time.Now().UTC()
+	campaign.CreatedAt = now
+	campaign.UpdatedAt = now
+
+	if _, err := r.GetCollection(CollectionCampaigns).InsertOne(ctx, campaign); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("campaign with ID %s %w", campaign.ID, ErrAlreadyExists)
+		}
+		return err
+	}
+
+	return r.updateMappings(ctx, campaign.ID)
+}
+
+// BulkCreateCampaigns inserts campaigns and rules inside a single transaction.
+// Transactions require a replica set or sharded cluster.
+func (r *RepositoryImpl) BulkCreateCampaigns(ctx context.Context, campaigns []*models.Campaign, rules []*models.TargetingRule) error {
+	if r.client == nil {
+		return errors.New("MongoDB client not initialized")
+	}
+
+	session, err := r.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		now := time.Now().UTC()
+
+		campaignDocs := make([]interface{}, 0, len(campaigns))
+		for _, campaign := range campaigns {
+			campaign.CreatedAt = now
+			campaign.UpdatedAt = now
+			campaignDocs = append(campaignDocs, campaign)
+		}
+		if _, err := r.GetCollection(CollectionCampaigns).InsertMany(sessCtx, campaignDocs); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, fmt.Errorf("campaign %w", ErrAlreadyExists)
+			}
+			return nil, err
+		}
+
+		if len(rules) > 0 {
+			ruleDocs := make([]interface{}, 0, len(rules))
+			for _, rule := range rules {
+				id, err := r.nextSequence(sessCtx, CollectionTargetingRules)
+				if err != nil {
+					return nil, fmt.Errorf("failed to allocate targeting rule ID: %w", err)
+				}
+				rule.ID = id
+				rule.CreatedAt = now
+				rule.UpdatedAt = now
+				ruleDocs = append(ruleDocs, rule)
+			}
+			if _, err := r.GetCollection(CollectionTargetingRules).InsertMany(sessCtx, ruleDocs); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, campaign := range campaigns {
+			if err := r.updateMappings(sessCtx, campaign.ID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// Values of RepositoryImpl.transactions
+const (
+	transactionsUnknown int32 = iota
+	transactionsSupported
+	transactionsUnsupported
+)
+
+// WithTransaction runs fn in a multi-document transaction, retrying it on
+// transient transaction errors. A call made while a transaction is open joins
+// it. Transactions require a replica set or sharded cluster; against a
+// standalone server fn runs without one, which is logged once.
+func (r *RepositoryImpl) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r.client == nil {
+		return errors.New("MongoDB client not initialized")
+	}
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	supported, err := r.supportsTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return fn(ctx)
+	}
+
+	session, err := r.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// supportsTransactions reports whether the server is a replica set member or
+// a mongos, asking it once
+func (r *RepositoryImpl) supportsTransactions(ctx context.Context) (bool, error) {
+	switch r.transactions.Load() {
+	case transactionsSupported:
+		return true, nil
+	case transactionsUnsupported:
+		return false, nil
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := r.database.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, fmt.Errorf("failed to check transaction support: %w", err)
+	}
+	if hello.SetName != "" || hello.Msg == "isdbgrid" {
+		r.transactions.Store(transactionsSupported)
+		return true, nil
+	}
+	if r.transactions.CompareAndSwap(transactionsUnknown, transactionsUnsupported) {
+		slog.WarnContext(ctx, "MongoDB is a standalone server without transactions; multi-document writes are not atomic")
+	}
+	return false, nil
 }
 
 func buildMappingMatchPipeline(dimensions []models.Dimension) mongo.Pipeline {
 
 
-	//Build filters for each dimension-value pair
-	filters := bson.A{}
+	// Only mapped dimensions can be matched here; custom dimensions, segments
+	// and app version constraints are evaluated once the targeting cache is
+	// loaded
+	mapped := make([]models.Dimension, 0, len(dimensions))
 	for _, d := range dimensions {
+		if slices.Contains(mappingDimensions, d.Name) {
+			mapped = append(mapped, d)
+		}
+	}
+	dimensions = mapped
+
+	// A dimension requested with several values is included by a mapping
+	// including any of them, and excluded by one excluding any, as the
+	// targeting cache matches rules
+	var names []string
+	values := make(map[string]bson.A)
+	for _, d := range dimensions {
+		if _, ok := values[d.Name]; !ok {
+			names = append(names, d.Name)
+		}
+		values[d.Name] = append(values[d.Name], d.Value)
+	}
+
+	//Build filters for each dimension
+	filters := bson.A{}
+	for _, name := range names {
 		dimensionFilter := bson.D{
-			{Key: "dimension", Value: d.Name}, // Match specific dimension
+			{Key: "dimension", Value: name}, // Match specific dimension
 			{Key: "$or", Value: bson.A{
 				bson.D{
 					{Key: "type", Value: "include"},
-					{Key: "values", Value: bson.D{{Key: "$in", Value: bson.A{d.Value}}}},
+					{Key: "values", Value: bson.D{{Key: "$in", Value: values[name]}}},
+					{Key: "exclude", Value: bson.D{{Key: "$nin", Value: values[name]}}},
 				},
 				bson.D{
 					{Key: "type", Value: "exclude"},
-					{Key: "values", Value: bson.D{{Key: "$nin", Value: bson.A{d.Value}}}},
+					{Key: "values", Value: bson.D{{Key: "$nin", Value: values[name]}}},
 				},
 				bson.D{{Key: "type", Value: primitive.Null{}}}, // Handle null type
 			}},
@@ -260,18 +591,24 @@ func buildMappingMatchPipeline(dimensions []models.Dimension) mongo.Pipeline {
 		//Stage 1: Match documents for any dimension
 		{{Key: "$match", Value: bson.D{{Key: "$or", Value: filters}}}},
 		
-		//Stage 2: Group by campaign_id and collect covered dimensions
+		//Stage 2: Group by rule and collect covered dimensions
 		{{Key: "$group", Value: bson.D{
-			{Key: "_id", Value: "$campaign_id"},
+			{Key: "_id", Value: bson.D{{Key: "campaign_id", Value: "$campaign_id"}, {Key: "rule_id", Value: "$rule_id"}}},
 			{Key: "coveredDimensions", Value: bson.D{{Key: "$addToSet", Value: "$dimension"}}},
 		}}},
 		
-		// Stage 3: Filter campaigns that cover all required dimensions
+		// Stage 3: Filter rules that cover all required dimensions on their
+		// own, since a campaign matches when one of its rules does
 		{{Key: "$match", Value: bson.D{
-			{Key: "coveredDimensions", Value: bson.D{{Key: "$size", Value: len(dimensions)}}},
+			{Key: "coveredDimensions", Value: bson.D{{Key: "$size", Value: len(names)}}},
 		}}},
 		
-		//Stage 4: Project the final result
+		// Stage 4: Collapse the matching rules of each campaign
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$_id.campaign_id"},
+		}}},
+		
+		//Stage 5: Project the final result
 		{{Key: "$project", Value: bson.D{
 			{Key: "campaign_id", Value: "$_id"},
 			{Key: "_id", Value: 0},
@@ -283,9 +620,12 @@ func buildMappingMatchPipeline(dimensions []models.Dimension) mongo.Pipeline {
 }
 
 func fetchValidCampaignIDs(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) ([]string, error) {
+	ctx, span := startMongoSpan(ctx, "aggregate", collection.Name())
+	defer span.End()
+
 	cursor, err := collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, spanError(span, err)
 	}
 	defer cursor.Close(ctx)
 
@@ -316,166 +656,554 @@ func (r *RepositoryImpl) GetMatchingCampaignIDs(ctx context.Context, dimensions
 
 }
 
+
 func (r *RepositoryImpl) UpdateCampaign(ctx context.Context, campaign *models.Campaign) error {
-	// Delete existing rules and recreate
-	if err := r.DeleteCampaign(ctx, campaign.ID); err != nil {
+	campaign.UpdatedAt = time.Now().UTC()
+
+	result, err := r.GetCollection(CollectionCampaigns).ReplaceOne(ctx, bson.M{"cid": campaign.ID}, campaign)
+	if err != nil {
 		return err
 	}
-	return r.CreateCampaign(ctx, campaign)
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("campaign with ID %s %w", campaign.ID, ErrNotFound)
+	}
+
+	// Status may have changed, so the campaign can enter or leave the active set
+	return r.updateMappings(ctx, campaign.ID)
 }
 
 func (r *RepositoryImpl) DeleteCampaign(ctx context.Context, id string) error {
-	// _, err := r.GetCollection(CollectionCampaigns).DeleteMany(ctx, bson.M{"type": "rule", "campaign_id": id})
-	// if err != nil {
-	// 	return err
-	// }
-	// // Clean up mappings (simplified; use Change Streams in production)
-	// return r.GetCollection(CollectionCampaigns).DeleteMany(ctx, bson.M{"type": "mapping", "valid_campaigns": id})
-	return nil
+	result, err := r.GetCollection(CollectionCampaigns).DeleteOne(ctx, bson.M{"cid": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("campaign with ID %s %w", id, ErrNotFound)
+	}
+
+	// Also delete associated targeting rules and their mappings
+	if _, err := r.GetCollection(CollectionTargetingRules).DeleteMany(ctx, bson.M{"campaign_id": id}); err != nil {
+		return err
+	}
+	_, err = r.GetCollection(CollectionActiveCampaign).DeleteMany(ctx, bson.M{"campaign_id": id})
+	return err
 }
 
 func (r *RepositoryImpl) UpdateCampaignStatus(ctx context.Context, id, status string) error {
-	update := bson.M{"$set": bson.M{"campaign_details.status": status}}
-	_, err := r.GetCollection(CollectionCampaigns).UpdateMany(ctx, bson.M{"type": "rule", "campaign_id": id}, update)
-	return err
+	update := bson.M{"$set": bson.M{"status": status, "updated_at": time.Now().UTC()}}
+	result, err := r.GetCollection(CollectionCampaigns).UpdateOne(ctx, bson.M{"cid": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("campaign with ID %s %w", id, ErrNotFound)
+	}
+	return r.updateMappings(ctx, id)
 }
 
 func (r *RepositoryImpl) GetTargetingRules(ctx context.Context) ([]*models.TargetingRule, error) {
-	// filter := bson.M{"type": "rule"}
-	// cursor, err := r.GetCollection(CollectionCampaigns).Find(ctx, filter)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// defer cursor.Close(ctx)
-
-	// rules := make([]*models.TargetingRule, 0)
-	// for cursor.Next(ctx) {
-	// 	var result struct {
-	// 		CampaignID string   `bson:"campaign_id"`
-	// 		Dimension  string   `bson:"dimension"`
-	// 		Include    []string `bson:"include"`
-	// 		Exclude    []string `bson:"exclude"`
-	// 	}
-	// 	if err := cursor.Decode(&result); err != nil {
-	// 		return nil, err
-	// 	}
-	// 	rules = append(rules, &models.TargetingRule{
-	// 		CampaignID: result.CampaignID,
-	// 		Dimension:  result.Dimension,
-	// 		Include:    result.Include,
-	// 		Exclude:    result.Exclude,
-	// 	})
-	// }
-	// if err := cursor.Err(); err != nil {
-	// 	return nil, err
-	// }
-	// return rules, nil
-	return nil, nil
+	return r.findTargetingRules(ctx, bson.M{})
 }
 
 func (r *RepositoryImpl) GetTargetingRulesByCampaignID(ctx context.Context, campaignID string) ([]*models.TargetingRule, error) {
-	// filter := bson.M{"type": "rule", "campaign_id": campaignID}
-	// cursor, err := r.GetCollection(CollectionCampaigns).Find(ctx, filter)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// defer cursor.Close(ctx)
-
-	// rules := make([]*models.TargetingRule, 0)
-	// for cursor.Next(ctx) {
-	// 	var result struct {
-	// 		CampaignID string   `bson:"campaign_id"`
-	// 		Dimension  string   `bson:"dimension"`
-	// 		Include    []string `bson:"include"`
-	// 		Exclude    []string `bson:"exclude"`
-	// 	}
-	// 	if err := cursor.Decode(&result); err != nil {
-	// 		return nil, err
-	// 	}
-	// 	rules = append(rules, &models.TargetingRule{
-	// 		CampaignID: result.CampaignID,
-	// 		Dimension:  result.Dimension,
-	// 		Include:    result.Include,
-	// 		Exclude:    result.Exclude,
-	// 	})
-	// }
-	// if err := cursor.Err(); err != nil {
-	// 	return nil, err
-	// }
-	// return rules, nil
-	return nil, nil
+	return r.findTargetingRules(ctx, bson.M{"campaign_id": campaignID})
+}
+
+func (r *RepositoryImpl) findTargetingRules(ctx context.Context, filter bson.M) ([]*models.TargetingRule, error) {
+	ctx, span := startMongoSpan(ctx, "find", CollectionTargetingRules)
+	defer span.End()
+
+	cursor, err := r.GetCollection(CollectionTargetingRules).Find(ctx, filter)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer cursor.Close(ctx)
+
+	rules := make([]*models.TargetingRule, 0)
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, spanError(span, fmt.Errorf("failed to decode targeting rules: %w", err))
+	}
+	return rules, nil
 }
 
 func (r *RepositoryImpl) CreateTargetingRule(ctx context.Context, rule *models.TargetingRule) error {
-	// doc := bson.M{
-	// 	"type":        "rule",
-	// 	"campaign_id": rule.CampaignID,
-	// 	"dimension":   rule.Dimension,
-	// 	"include":     rule.Include,
-	// 	"exclude":     rule.Exclude,
-	// 	"created_at":  time.Now().UTC(),
-	// 	"updated_at":  time.Now().UTC(),
-	// }
-
-	// if _, err := r.GetCollection(CollectionCampaigns).InsertOne(ctx, doc); err != nil {
-	// 	return err
-	// }
-	// // Update mappings (simplified; use Change Streams in production)
-	// return r.updateMappings(ctx, rule.CampaignID, rule)
-	return nil
+	id, err := r.nextSequence(ctx, CollectionTargetingRules)
+	if err != nil {
+		return fmt.Errorf("failed to allocate targeting rule ID: %w", err)
+	}
+
+	now := time.Now().UTC()
+	rule.ID = id
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	if _, err := r.GetCollection(CollectionTargetingRules).InsertOne(ctx, rule); err != nil {
+		return err
+	}
+	return r.updateMappings(ctx, rule.CampaignID)
+}
+
+func (r *RepositoryImpl) GetTargetingRuleByID(ctx context.Context, id int64) (*models.TargetingRule, error) {
+	var rule models.TargetingRule
+	if err := r.GetCollection(CollectionTargetingRules).FindOne(ctx, bson.M{"id": id}).Decode(&rule); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("targeting rule with ID %d %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+	return &rule, nil
 }
 
 func (r *RepositoryImpl) UpdateTargetingRule(ctx context.Context, rule *models.TargetingRule) error {
-	// filter := bson.M{"type": "rule", "campaign_id": rule.CampaignID, "dimension": rule.Dimension}
-	// update := bson.M{"$set": bson.M{
-	// 	"include": rule.Include,
-	// 	"exclude": rule.Exclude,
-	// }}
-	// if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
-	// 	return err
-	// }
-	// // Update mappings (simplified; use Change Streams in production)
-	// return r.updateMappings(ctx, rule.CampaignID, rule)
-	return nil
+	var existing models.TargetingRule
+	if err := r.GetCollection(CollectionTargetingRules).FindOne(ctx, bson.M{"id": rule.ID}).Decode(&existing); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("targeting rule with ID %d %w", rule.ID, ErrNotFound)
+		}
+		return err
+	}
+
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now().UTC()
+	if _, err := r.GetCollection(CollectionTargetingRules).ReplaceOne(ctx, bson.M{"id": rule.ID}, rule); err != nil {
+		return err
+	}
+
+	// The rule may have moved between campaigns, so refresh both
+	if existing.CampaignID != rule.CampaignID {
+		if err := r.updateMappings(ctx, existing.CampaignID); err != nil {
+			return err
+		}
+	}
+	return r.updateMappings(ctx, rule.CampaignID)
 }
 
 func (r *RepositoryImpl) DeleteTargetingRule(ctx context.Context, id int64) error {
-	// Assuming id is a placeholder; adjust if it's a different field (e.g., _id as ObjectID)
-	return fmt.Errorf("DeleteTargetingRule not implemented: id type mismatch")
+	var existing models.TargetingRule
+	if err := r.GetCollection(CollectionTargetingRules).FindOneAndDelete(ctx, bson.M{"id": id}).Decode(&existing); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("targeting rule with ID %d %w", id, ErrNotFound)
+		}
+		return err
+	}
+	return r.updateMappings(ctx, existing.CampaignID)
 }
 
 func (r *RepositoryImpl) DeleteTargetingRulesByCampaignID(ctx context.Context, campaignID string) error {
-	// _, err := r.GetCollection(CollectionCampaigns).DeleteMany(ctx, bson.M{"type": "rule", "campaign_id": campaignID})
-	// if err != nil {
-	// 	return err
-	// }
-	// // Clean up mappings (simplified; use Change Streams in production)
-	// return r.GetCollection(CollectionCampaigns).DeleteMany(ctx, bson.M{"type": "mapping", "valid_campaigns": campaignID})
+	if _, err := r.GetCollection(CollectionTargetingRules).DeleteMany(ctx, bson.M{"campaign_id": campaignID}); err != nil {
+		return err
+	}
+	return r.updateMappings(ctx, campaignID)
+}
+
+// ImportCampaigns upserts campaigns as they are and rebuilds their mappings
+func (r *RepositoryImpl) ImportCampaigns(ctx context.Context, campaigns []*models.Campaign) error {
+	opts := options.Replace().SetUpsert(true)
+	for _, campaign := range campaigns {
+		if _, err := r.GetCollection(CollectionCampaigns).ReplaceOne(ctx, bson.M{"cid": campaign.ID}, campaign, opts); err != nil {
+			return err
+		}
+		if err := r.updateMappings(ctx, campaign.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportTargetingRules upserts rules as they are, rebuilds the mappings of
+// the campaigns they are in or moved out of, and raises the rule ID sequence
+// past them
+func (r *RepositoryImpl) ImportTargetingRules(ctx context.Context, rules []*models.TargetingRule) error {
+	opts := options.FindOneAndReplace().SetUpsert(true)
+	campaignIDs := make(map[string]struct{})
+	var maxID int64
+	for _, rule := range rules {
+		var existing models.TargetingRule
+		err := r.GetCollection(CollectionTargetingRules).FindOneAndReplace(ctx, bson.M{"id": rule.ID}, rule, opts).Decode(&existing)
+		switch {
+		case err == nil:
+			campaignIDs[existing.CampaignID] = struct{}{}
+		case !errors.Is(err, mongo.ErrNoDocuments):
+			return err
+		}
+		campaignIDs[rule.CampaignID] = struct{}{}
+		maxID = max(maxID, rule.ID)
+	}
+
+	for campaignID := range campaignIDs {
+		if err := r.updateMappings(ctx, campaignID); err != nil {
+			return err
+		}
+	}
+	if maxID == 0 {
+		return nil
+	}
+	_, err := r.GetCollection(CollectionCounters).UpdateOne(ctx,
+		bson.M{"_id": CollectionTargetingRules},
+		bson.M{"$max": bson.M{"seq": maxID}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// ReserveIdempotencyKey inserts the record, replacing one that has expired
+// but not yet been removed by the TTL index. A duplicate key error means the
+// key is held.
+func (r *RepositoryImpl) ReserveIdempotencyKey(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	collection := r.GetCollection(CollectionIdempotency)
+	filter := bson.M{"_id": record.Key, "expires_at": bson.M{"$lte": time.Now().UTC()}}
+	_, err := collection.ReplaceOne(ctx, filter, record, options.Replace().SetUpsert(true))
+	if err == nil {
+		return nil, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	var held IdempotencyRecord
+	if err := collection.FindOne(ctx, bson.M{"_id": record.Key}).Decode(&held); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// The record was released in between; claim the key again
+			return r.ReserveIdempotencyKey(ctx, record)
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	return &held, nil
+}
+
+func (r *RepositoryImpl) CompleteIdempotencyKey(ctx context.Context, key string, result []byte) error {
+	res, err := r.GetCollection(CollectionIdempotency).UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": bson.M{"result": result}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("idempotency key %s %w", key, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *RepositoryImpl) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := r.GetCollection(CollectionIdempotency).DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+// AddDeliveryCounts upserts the document of each count, adding to its served
+func (r *RepositoryImpl) AddDeliveryCounts(ctx context.Context, counts []DeliveryCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, 0, len(counts))
+	for _, count := range counts {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"tenant_id": count.TenantID, "campaign_id": count.CampaignID, "day": count.Day, "country": count.Country, "os": count.OS}).
+			SetUpdate(bson.M{"$inc": bson.M{"served": count.Served}}).
+			SetUpsert(true))
+	}
+	if _, err := r.GetCollection(CollectionDeliveryStats).BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to add delivery counts: %w", err)
+	}
+	return nil
+}
+
+func (r *RepositoryImpl) GetDeliveryCounts(ctx context.Context, filter DeliveryCountFilter) ([]DeliveryCount, error) {
+	query := bson.M{"tenant_id": filter.TenantID, "day": bson.M{"$gte": filter.From, "$lte": filter.To}}
+	if filter.CampaignID != "" {
+		query["campaign_id"] = filter.CampaignID
+	}
+	cursor, err := r.GetCollection(CollectionDeliveryStats).Find(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery counts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var counts []DeliveryCount
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, fmt.Errorf("failed to decode delivery counts: %w", err)
+	}
+	return counts, nil
+}
+
+func (r *RepositoryImpl) GetKillSwitches(ctx context.Context) ([]*models.KillSwitch, error) {
+	cursor, err := r.GetCollection(CollectionKillSwitches).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kill switches: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	switches := []*models.KillSwitch{}
+	if err := cursor.All(ctx, &switches); err != nil {
+		return nil, fmt.Errorf("failed to decode kill switches: %w", err)
+	}
+	return switches, nil
+}
+
+// EngageKillSwitch stores the switch under its scope, replacing one engaged before
+func (r *RepositoryImpl) EngageKillSwitch(ctx context.Context, sw *models.KillSwitch) error {
+	_, err := r.GetCollection(CollectionKillSwitches).ReplaceOne(ctx, bson.M{"_id": sw.Scope()}, sw, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *RepositoryImpl) ReleaseKillSwitch(ctx context.Context, scope string) error {
+	_, err := r.GetCollection(CollectionKillSwitches).DeleteOne(ctx, bson.M{"_id": scope})
+	return err
+}
+
+func (r *RepositoryImpl) AddDecisions(ctx context.Context, decisions []*models.Decision) error {
+	if len(decisions) == 0 {
+		return nil
+	}
+	documents := make([]interface{}, len(decisions))
+	for i, decision := range decisions {
+		documents[i] = decision
+	}
+	if _, err := r.GetCollection(CollectionDecisionLog).InsertMany(ctx, documents, options.InsertMany().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to add decisions: %w", err)
+	}
 	return nil
 }
 
-// updateMappings updates pre-aggregated mappings (simplified implementation).
-func (r *RepositoryImpl) updateMappings(ctx context.Context, campaignID string, rule *models.TargetingRule) error {
-	// This is a simplified version; in production, use Change Streams to recompute all mappings
-	// if rule.Include != nil {
-	// 	for _, value := range rule.Include {
-	// 		filter := bson.M{"type": "mapping", "dimension": rule.Dimension, "value": value}
-	// 		update := bson.M{"$addToSet": bson.M{"valid_campaigns": campaignID}}
-	// 		_, err := r.GetCollection(CollectionCampaigns).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
-	// 		if err != nil {
-	// 			return err
-	// 		}
-	// 	}
-	// }
-	// if rule.Exclude != nil {
-	// 	for _, value := range rule.Exclude {
-	// 		filter := bson.M{"type": "mapping", "dimension": rule.Dimension, "value": value}
-	// 		update := bson.M{"$pull": bson.M{"valid_campaigns": campaignID}}
-	// 		_, err := r.GetCollection(CollectionCampaigns).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
-	// 		if err != nil {
-	// 			return err
-	// 		}
-	// 	}
-	// }
+// GetDecisions skips expired decisions the TTL monitor hasn't removed yet
+func (r *RepositoryImpl) GetDecisions(ctx context.Context, filter DecisionFilter) ([]*models.Decision, error) {
+	query := bson.M{"tenant_id": filter.TenantID, "expires_at": bson.M{"$gt": time.Now()}}
+	if filter.RequestID != "" {
+		query["request_id"] = filter.RequestID
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	cursor, err := r.GetCollection(CollectionDecisionLog).Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get decisions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	decisions := []*models.Decision{}
+	if err := cursor.All(ctx, &decisions); err != nil {
+		return nil, fmt.Errorf("failed to decode decisions: %w", err)
+	}
+	return decisions, nil
+}
+
+// nextSequence atomically increments and returns the named counter.
+func (r *RepositoryImpl) nextSequence(ctx context.Context, name string) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := r.GetCollection(CollectionCounters).FindOneAndUpdate(ctx,
+		bson.M{"_id": name},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		opts,
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return counter.Seq, nil
+}
+
+// updateMappings recomputes the pre-aggregated mapping documents for a campaign.
+// Only active campaigns are present in the mapping collection; every rule
+// contributes an include, exclude or unconstrained (null type) document per
+// dimension, and a campaign without rules matches every value.
+func (r *RepositoryImpl) updateMappings(ctx context.Context, campaignID string) error {
+	mappings := r.GetCollection(CollectionActiveCampaign)
+	if _, err := mappings.DeleteMany(ctx, bson.M{"campaign_id": campaignID}); err != nil {
+		return err
+	}
+
+	campaign, err := r.GetCampaignByID(ctx, campaignID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !campaign.IsActive() {
+		return nil
+	}
+
+	rules, err := r.GetTargetingRulesByCampaignID(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	// Shadow rules never affect delivery, so they get no mappings
+	rules = liveRules(rules)
+
+	docs := make([]interface{}, 0)
+	if len(rules) == 0 {
+		for _, dimension := range mappingDimensions {
+			docs = append(docs, mappingDocument(campaignID, 0, dimension, nil, nil)...)
+		}
+	}
+	// Expressions are ANDed with the lists and aren't mapped, so a rule's
+	// mappings may match requests its expression rejects until the
+	// targeting cache is loaded. The same goes for effective windows.
+	for _, rule := range rules {
+		for _, dimension := range mappingDimensions {
+			include, exclude := rule.DimensionLists(dimension)
+			if normalize, ok := mappingNormalizers[dimension]; ok {
+				include, exclude = normalizeValues(include, normalize), normalizeValues(exclude, normalize)
+			}
+			docs = append(docs, mappingDocument(campaignID, rule.ID, dimension, include, exclude)...)
+		}
+	}
+
+	_, err = mappings.InsertMany(ctx, docs)
+	return err
+}
+
+// normalizeCity lower-cases a city name and collapses its whitespace, as
+// delivery requests are normalized.
+func normalizeCity(city string) string {
+	return strings.ToLower(strings.Join(strings.Fields(city), " "))
+}
+
+// backfillMappings rebuilds the mappings of campaigns that are missing a
+// dimension, such as those written before the dimension was added, and of
+// campaigns with include documents written before they carried their rule's
+// exclusions.
+func (r *RepositoryImpl) backfillMappings(ctx context.Context) error {
+	mapped, err := r.mappedCampaignIDs(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	stale, err := r.mappedCampaignIDs(ctx, bson.M{"type": "include", "exclude": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	for _, dimension := range mappingDimensions {
+		covered, err := r.mappedCampaignIDs(ctx, bson.M{"dimension": dimension})
+		if err != nil {
+			return err
+		}
+		for id := range mapped {
+			if _, ok := covered[id]; !ok {
+				stale[id] = struct{}{}
+			}
+		}
+	}
+
+	for id := range stale {
+		if err := r.updateMappings(ctx, id); err != nil {
+			return fmt.Errorf("failed to rebuild mappings for campaign %s: %w", id, err)
+		}
+	}
 	return nil
 }
+
+// mappedCampaignIDs returns the campaigns with mapping documents matching filter.
+func (r *RepositoryImpl) mappedCampaignIDs(ctx context.Context, filter bson.M) (map[string]struct{}, error) {
+	values, err := r.GetCollection(CollectionActiveCampaign).Distinct(ctx, "campaign_id", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			ids[id] = struct{}{}
+		}
+	}
+	return ids, nil
+}
+
+// mappingDocument builds the mapping documents for one dimension of a rule.
+func mappingDocument(campaignID string, ruleID int64, dimension string, include, exclude []string) []interface{} {
+	base := func(kind interface{}, values []string) bson.M {
+		return bson.M{
+			"campaign_id": campaignID,
+			"rule_id":     ruleID,
+			"dimension":   dimension,
+			"type":        kind,
+			"values":      values,
+		}
+	}
+
+	if len(include) == 0 && len(exclude) == 0 {
+		return []interface{}{base(primitive.Null{}, []string{})}
+	}
+
+	// A rule with both lists gets a single include document carrying its
+	// exclusions, so a request with any excluded value is rejected even when
+	// another of its values is included. The exclusions are always written,
+	// empty or not, to tell these documents from ones written before.
+	if len(include) > 0 {
+		doc := base("include", include)
+		doc["exclude"] = append([]string{}, exclude...)
+		return []interface{}{doc}
+	}
+	return []interface{}{base("exclude", exclude)}
+}
+
+// normalizeValues applies the same normalization used for delivery requests.
+func normalizeValues(values []string, normalize func(string) string) []string {
+	normalized := make([]string, 0, len(values))
+	for _, v := range values {
+		normalized = append(normalized, normalize(strings.TrimSpace(v)))
+	}
+	return normalized
+}
+
+// WatchChanges streams writes to the campaigns and targeting rules collections
+// using MongoDB change streams. Change streams require a replica set or
+// sharded cluster; on a standalone server the initial Watch call fails.
+func (r *RepositoryImpl) WatchChanges(ctx context.Context, handle func(ChangeEvent)) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "ns.coll", Value: bson.D{{Key: "$in", Value: bson.A{CollectionCampaigns, CollectionTargetingRules}}}},
+		}}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	stream, err := r.database.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+	defer stream.Close(ctx)
+
+	for stream.Next(ctx) {
+		var raw struct {
+			OperationType string `bson:"operationType"`
+			Ns            struct {
+				Coll string `bson:"coll"`
+			} `bson:"ns"`
+			FullDocument bson.Raw `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&raw); err != nil {
+			return fmt.Errorf("failed to decode change event: %w", err)
+		}
+
+		event := ChangeEvent{Operation: raw.OperationType}
+		switch raw.Ns.Coll {
+		case CollectionCampaigns:
+			event.Kind = ChangeKindCampaign
+			if len(raw.FullDocument) > 0 {
+				var campaign models.Campaign
+				if err := bson.Unmarshal(raw.FullDocument, &campaign); err != nil {
+					return fmt.Errorf("failed to decode campaign change: %w", err)
+				}
+				event.Campaign = &campaign
+			}
+		case CollectionTargetingRules:
+			event.Kind = ChangeKindTargetingRule
+			if len(raw.FullDocument) > 0 {
+				var rule models.TargetingRule
+				if err := bson.Unmarshal(raw.FullDocument, &rule); err != nil {
+					return fmt.Errorf("failed to decode targeting rule change: %w", err)
+				}
+				event.Rule = &rule
+			}
+		default:
+			continue
+		}
+
+		handle(event)
+	}
+
+	if err := stream.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,102 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/config"
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/service"
+)
+
+// mongoTestURIEnv names the MongoDB server the MongoDB tests run against;
+// they are skipped when it's unset
+const mongoTestURIEnv = "TARGET_TEST_MONGODB_URI"
+
+// newMongoRepository returns a repository over a fresh database that is
+// dropped when the test ends
+func newMongoRepository(t *testing.T) *repository.RepositoryImpl {
+	uri := os.Getenv(mongoTestURIEnv)
+	if uri == "" {
+		t.Skipf("%s not set", mongoTestURIEnv)
+	}
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	require.NoError(t, err)
+	db := client.Database("target_engine_test_" + strconv.FormatInt(time.Now().UnixNano(), 36))
+	t.Cleanup(func() {
+		_ = db.Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+	return repository.NewRepository(db, client)
+}
+
+func TestMongoMatchParity(t *testing.T) {
+	campaigns := []string{"open", "included", "excluded", "exclude-only", "split"}
+	// Rules are created anew for each repository, which assigns their IDs
+	rules := func() []*model.TargetingRule {
+		return []*model.TargetingRule{
+			{CampaignID: "included", IncludeCountry: []string{"US"}},
+			// CA is excluded even though US is included
+			{CampaignID: "excluded", IncludeCountry: []string{"US", "CA"}, ExcludeCountry: []string{"CA"}},
+			{CampaignID: "exclude-only", ExcludeCountry: []string{"CA"}},
+			// Neither rule matches on its own, though together they cover
+			// every dimension
+			{CampaignID: "split", IncludeCountry: []string{"US"}, IncludeOS: []string{"ios"}},
+			{CampaignID: "split", IncludeCountry: []string{"DE"}, IncludeOS: []string{"android"}},
+		}
+	}
+	populate := func(repo repository.Repository) {
+		ctx := context.Background()
+		for _, id := range campaigns {
+			campaign := &model.Campaign{ID: id, Name: id, Image: "https://cdn.example.com/" + id + ".png", CTA: "Install", Status: model.StatusActive}
+			require.NoError(t, repo.Campaign().CreateCampaign(ctx, campaign))
+		}
+		for _, rule := range rules() {
+			require.NoError(t, repo.TargetingRule().CreateTargetingRule(ctx, rule))
+		}
+	}
+
+	ctx := context.Background()
+	req := &model.DeliveryRequest{App: "com.example.app", Country: "US", Countries: []string{"CA"}, OS: "android"}
+	want := []string{"included", "open"}
+
+	// The targeting cache's matcher is the reference
+	memoryRepo := repository.NewEmptyMemoryRepository()
+	populate(memoryRepo)
+	cfg := &config.Config{}
+	cfg.Cache.MaxSize = 100
+	cfg.Cache.TTL = time.Minute
+	svc := service.NewTargetingService(memoryRepo, cfg, nil, nil, nil)
+	_, err := svc.RefreshCache()
+	require.NoError(t, err)
+	matches, err := svc.GetMatchingCampaigns(ctx, req)
+	require.NoError(t, err)
+	var cached []string
+	for _, match := range matches {
+		cached = append(cached, match.CID)
+	}
+	slices.Sort(cached)
+	require.Equal(t, want, cached)
+
+	fromMemory, err := memoryRepo.GetMatchingCampaignIDs(ctx, req.Dimensions())
+	require.NoError(t, err)
+	assert.Equal(t, want, fromMemory)
+
+	mongoRepo := newMongoRepository(t)
+	populate(mongoRepo)
+	fromMongo, err := mongoRepo.GetMatchingCampaignIDs(ctx, req.Dimensions())
+	require.NoError(t, err)
+	slices.Sort(fromMongo)
+	assert.Equal(t, want, fromMongo)
+}
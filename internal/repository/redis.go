@@ -0,0 +1,958 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisRepository stores campaigns as hashes and indexes them with sets.
+//
+// Key layout (all keys carry the configured prefix):
+//
+//	campaigns               set of every campaign ID
+//	campaigns:active        set of active campaign IDs
+//	campaign:<id>           hash of campaign fields
+//	campaign:<id>:rules     set of targeting rule IDs for the campaign
+//	rules                   hash of rule ID -> JSON encoded targeting rule
+//	rules:seq               counter used to allocate rule IDs
+//	idempotency:<key>       JSON encoded idempotency record, expiring with it
+//	deliveries:<day>        hash of tenant, campaign, country and OS -> served
+//	killswitches            hash of scope -> JSON encoded kill switch
+//	decisions:<tenant>      sorted set of JSON encoded decisions, scored by
+//	                        their expiry in Unix milliseconds
+//	decision:<tenant>:<id>  list of JSON encoded decisions of a request ID,
+//	                        expiring with the last
+type RedisRepository struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisRepository creates a Redis backed repository. prefix namespaces
+// every key so several deployments can share one Redis instance.
+func NewRedisRepository(client *redis.Client, prefix string) *RedisRepository {
+	if client == nil {
+		panic("redis client cannot be nil")
+	}
+	if prefix != "" {
+		prefix += ":"
+	}
+	return &RedisRepository{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (r *RedisRepository) Campaign() CampaignRepository {
+	return r
+}
+
+func (r *RedisRepository) TargetingRule() TargetingRuleRepository {
+	return r
+}
+
+func (r *RedisRepository) Idempotency() IdempotencyRepository {
+	return r
+}
+
+func (r *RedisRepository) DeliveryStats() DeliveryStatsRepository {
+	return r
+}
+
+func (r *RedisRepository) KillSwitch() KillSwitchRepository {
+	return r
+}
+
+func (r *RedisRepository) DecisionLog() DecisionLogRepository {
+	return r
+}
+
+func (r *RedisRepository) Close() error {
+	return r.client.Close()
+}
+
+func (r *RedisRepository) Health(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Migrate is a no-op; Redis keys are created on first write.
+func (r *RedisRepository) Migrate(ctx context.Context) error {
+	return nil
+}
+
+func (r *RedisRepository) campaignsKey() string {
+	return r.prefix + "campaigns"
+}
+
+func (r *RedisRepository) activeCampaignsKey() string {
+	return r.prefix + "campaigns:active"
+}
+
+func (r *RedisRepository) campaignKey(id string) string {
+	return r.prefix + "campaign:" + id
+}
+
+func (r *RedisRepository) campaignRulesKey(id string) string {
+	return r.prefix + "campaign:" + id + ":rules"
+}
+
+func (r *RedisRepository) rulesKey() string {
+	return r.prefix + "rules"
+}
+
+func (r *RedisRepository) ruleSeqKey() string {
+	return r.prefix + "rules:seq"
+}
+
+func (r *RedisRepository) idempotencyKey(key string) string {
+	return r.prefix + "idempotency:" + key
+}
+
+func (r *RedisRepository) deliveriesKey(day string) string {
+	return r.prefix + "deliveries:" + day
+}
+
+func (r *RedisRepository) killSwitchesKey() string {
+	return r.prefix + "killswitches"
+}
+
+func (r *RedisRepository) decisionsKey(tenantID string) string {
+	return r.prefix + "decisions:" + tenantID
+}
+
+func (r *RedisRepository) requestDecisionsKey(tenantID, requestID string) string {
+	return r.prefix + "decision:" + tenantID + ":" + requestID
+}
+
+// deliveryFieldSeparator joins the parts of a deliveries hash field; it
+// can't appear in IDs, country codes or OS names
+const deliveryFieldSeparator = "\x1f"
+
+// Campaign Repository Methods
+
+func (r *RedisRepository) GetActiveCampaigns(ctx context.Context) ([]*model.Campaign, error) {
+	ids, err := r.client.SMembers(ctx, r.activeCampaignsKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	return r.getCampaigns(ctx, ids)
+}
+
+// GetCampaignsByStatus loads every campaign and filters them by status
+func (r *RedisRepository) GetCampaignsByStatus(ctx context.Context, statuses []string) ([]*model.Campaign, error) {
+	ids, err := r.client.SMembers(ctx, r.campaignsKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	campaigns, err := r.getCampaigns(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(campaigns, func(campaign *model.Campaign) bool {
+		return !slices.Contains(statuses, campaign.Status)
+	}), nil
+}
+
+func (r *RedisRepository) GetCampaignByID(ctx context.Context, id string) (*model.Campaign, error) {
+	fields, err := r.client.HGetAll(ctx, r.campaignKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("campaign with ID %s %w", id, ErrNotFound)
+	}
+	return decodeCampaign(fields), nil
+}
+
+func (r *RedisRepository) GetCampaignsByIDs(ctx context.Context, ids []string) ([]*model.Campaign, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return r.getCampaigns(ctx, ids)
+}
+
+func (r *RedisRepository) getCampaigns(ctx context.Context, ids []string) ([]*model.Campaign, error) {
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, len(ids))
+	for _, id := range ids {
+		cmds = append(cmds, pipe.HGetAll(ctx, r.campaignKey(id)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	campaigns := make([]*model.Campaign, 0, len(ids))
+	for _, cmd := range cmds {
+		if fields := cmd.Val(); len(fields) > 0 {
+			campaigns = append(campaigns, decodeCampaign(fields))
+		}
+	}
+	return campaigns, nil
+}
+
+// ListCampaigns loads every campaign and filters them in memory; Redis holds
+// no secondary indexes on status, creation time or name
+func (r *RedisRepository) ListCampaigns(ctx context.Context, filter CampaignFilter) ([]*model.Campaign, int64, error) {
+	key := r.campaignsKey()
+	if filter.Status == model.StatusActive {
+		key = r.activeCampaignsKey()
+	}
+	ids, err := r.client.SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return []*model.Campaign{}, 0, nil
+	}
+
+	campaigns, err := r.getCampaigns(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, total := filterCampaigns(campaigns, filter)
+	return page, total, nil
+}
+
+// SearchCampaigns loads every campaign and scans their names and CTAs in
+// memory, like ListCampaigns
+func (r *RedisRepository) SearchCampaigns(ctx context.Context, search CampaignSearch) ([]*model.ScoredCampaign, int64, error) {
+	ids, err := r.client.SMembers(ctx, r.campaignsKey()).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return []*model.ScoredCampaign{}, 0, nil
+	}
+
+	campaigns, err := r.getCampaigns(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, total := searchCampaigns(campaigns, search)
+	return page, total, nil
+}
+
+func (r *RedisRepository) CreateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	added, err := r.client.SAdd(ctx, r.campaignsKey(), campaign.ID).Result()
+	if err != nil {
+		return err
+	}
+	if added == 0 {
+		return fmt.Errorf("campaign with ID %s %w", campaign.ID, ErrAlreadyExists)
+	}
+
+	campaign.CreatedAt = time.Now()
+	campaign.UpdatedAt = time.Now()
+	return r.writeCampaign(ctx, campaign)
+}
+
+// BulkCreateCampaigns writes all campaigns and rules in a single MULTI/EXEC
+// transaction after checking that none of the campaign IDs are taken
+func (r *RedisRepository) BulkCreateCampaigns(ctx context.Context, campaigns []*model.Campaign, rules []*model.TargetingRule) error {
+	if len(campaigns) == 0 {
+		return nil
+	}
+
+	ids := make([]interface{}, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		ids = append(ids, campaign.ID)
+	}
+	exists, err := r.client.SMIsMember(ctx, r.campaignsKey(), ids...).Result()
+	if err != nil {
+		return err
+	}
+	for i, found := range exists {
+		if found {
+			return fmt.Errorf("campaign with ID %s %w", campaigns[i].ID, ErrAlreadyExists)
+		}
+	}
+
+	var lastRuleID int64
+	if len(rules) > 0 {
+		if lastRuleID, err = r.client.IncrBy(ctx, r.ruleSeqKey(), int64(len(rules))).Result(); err != nil {
+			return fmt.Errorf("failed to allocate targeting rule IDs: %w", err)
+		}
+	}
+
+	now := time.Now()
+	encodedRules := make([][]byte, 0, len(rules))
+	for i, rule := range rules {
+		rule.ID = lastRuleID - int64(len(rules)) + int64(i) + 1
+		rule.CreatedAt = now
+		rule.UpdatedAt = now
+
+		data, err := json.Marshal(rule)
+		if err != nil {
+			return fmt.Errorf("failed to encode targeting rule: %w", err)
+		}
+		encodedRules = append(encodedRules, data)
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, campaign := range campaigns {
+			campaign.CreatedAt = now
+			campaign.UpdatedAt = now
+			pipe.SAdd(ctx, r.campaignsKey(), campaign.ID)
+			pipe.HSet(ctx, r.campaignKey(campaign.ID), encodeCampaign(campaign))
+			if campaign.IsActive() {
+				pipe.SAdd(ctx, r.activeCampaignsKey(), campaign.ID)
+			}
+		}
+		for i, rule := range rules {
+			id := strconv.FormatInt(rule.ID, 10)
+			pipe.HSet(ctx, r.rulesKey(), id, encodedRules[i])
+			pipe.SAdd(ctx, r.campaignRulesKey(rule.CampaignID), id)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *RedisRepository) UpdateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	if err := r.ensureCampaign(ctx, campaign.ID); err != nil {
+		return err
+	}
+
+	campaign.UpdatedAt = time.Now()
+	return r.writeCampaign(ctx, campaign)
+}
+
+func (r *RedisRepository) DeleteCampaign(ctx context.Context, id string) error {
+	removed, err := r.client.SRem(ctx, r.campaignsKey(), id).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("campaign with ID %s %w", id, ErrNotFound)
+	}
+
+	ruleIDs, err := r.client.SMembers(ctx, r.campaignRulesKey(id)).Result()
+	if err != nil {
+		return err
+	}
+
+	// Also delete associated targeting rules
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.campaignKey(id), r.campaignRulesKey(id))
+		pipe.SRem(ctx, r.activeCampaignsKey(), id)
+		if len(ruleIDs) > 0 {
+			pipe.HDel(ctx, r.rulesKey(), ruleIDs...)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *RedisRepository) UpdateCampaignStatus(ctx context.Context, id, status string) error {
+	if err := r.ensureCampaign(ctx, id); err != nil {
+		return err
+	}
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.campaignKey(id),
+			"status", status,
+			"updated_at", time.Now().Format(time.RFC3339Nano),
+		)
+		if status == model.StatusActive {
+			pipe.SAdd(ctx, r.activeCampaignsKey(), id)
+		} else {
+			pipe.SRem(ctx, r.activeCampaignsKey(), id)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *RedisRepository) GetMatchingCampaignIDs(ctx context.Context, dimensions []model.Dimension) ([]string, error) {
+	activeIDs, err := r.client.SMembers(ctx, r.activeCampaignsKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := r.GetTargetingRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules = liveRules(rules)
+
+	rulesByCampaign := make(map[string][]*model.TargetingRule)
+	for _, rule := range rules {
+		rulesByCampaign[rule.CampaignID] = append(rulesByCampaign[rule.CampaignID], rule)
+	}
+
+	var matched []string
+	for _, id := range activeIDs {
+		if campaignMatchesDimensions(rulesByCampaign[id], dimensions) {
+			matched = append(matched, id)
+		}
+	}
+	sort.Strings(matched)
+
+	return matched, nil
+}
+
+func (r *RedisRepository) ensureCampaign(ctx context.Context, id string) error {
+	exists, err := r.client.SIsMember(ctx, r.campaignsKey(), id).Result()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("campaign with ID %s %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *RedisRepository) writeCampaign(ctx context.Context, campaign *model.Campaign) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.campaignKey(campaign.ID), encodeCampaign(campaign))
+		if campaign.IsActive() {
+			pipe.SAdd(ctx, r.activeCampaignsKey(), campaign.ID)
+		} else {
+			pipe.SRem(ctx, r.activeCampaignsKey(), campaign.ID)
+		}
+		return nil
+	})
+	return err
+}
+
+func encodeCampaign(c *model.Campaign) map[string]interface{} {
+	// Experiments are stored as JSON; an empty field means none
+	experiment := ""
+	if c.Experiment != nil {
+		if data, err := json.Marshal(c.Experiment); err == nil {
+			experiment = string(data)
+		}
+	}
+	// Creatives and blocked categories are stored the same way
+	creatives := ""
+	if len(c.Creatives) > 0 {
+		if data, err := json.Marshal(c.Creatives); err == nil {
+			creatives = string(data)
+		}
+	}
+	blockedCategories := ""
+	if len(c.BlockedCategories) > 0 {
+		if data, err := json.Marshal(c.BlockedCategories); err == nil {
+			blockedCategories = string(data)
+		}
+	}
+	optionalTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+	return map[string]interface{}{
+		"cid":                c.ID,
+		"tenant_id":          c.TenantID,
+		"name":               c.Name,
+		"img":                c.Image,
+		"cta":                c.CTA,
+		"status":             c.Status,
+		"frequency_cap":      c.FrequencyCap,
+		"priority":           c.Priority,
+		"weight":             c.Weight,
+		"daily_budget":       c.DailyBudget,
+		"total_budget":       c.TotalBudget,
+		"app_daily_cap":      c.AppDailyCap,
+		"max_daily_apps":     c.MaxDailyApps,
+		"experiment":         experiment,
+		"creatives":          creatives,
+		"rotation":           c.Rotation,
+		"blocked_categories": blockedCategories,
+		"click_url":          c.ClickURL,
+		"start_at":           optionalTime(c.StartAt),
+		"end_at":             optionalTime(c.EndAt),
+		"created_at":         c.CreatedAt.Format(time.RFC3339Nano),
+		"updated_at":         c.UpdatedAt.Format(time.RFC3339Nano),
+		"deleted_at":         optionalTime(c.DeletedAt),
+	}
+}
+
+func decodeCampaign(fields map[string]string) *model.Campaign {
+	createdAt, _ := time.Parse(time.RFC3339Nano, fields["created_at"])
+	updatedAt, _ := time.Parse(time.RFC3339Nano, fields["updated_at"])
+	frequencyCap, _ := strconv.Atoi(fields["frequency_cap"])
+	priority, _ := strconv.Atoi(fields["priority"])
+	weight, _ := strconv.Atoi(fields["weight"])
+	dailyBudget, _ := strconv.ParseInt(fields["daily_budget"], 10, 64)
+	totalBudget, _ := strconv.ParseInt(fields["total_budget"], 10, 64)
+	appDailyCap, _ := strconv.Atoi(fields["app_daily_cap"])
+	maxDailyApps, _ := strconv.Atoi(fields["max_daily_apps"])
+	var experiment *model.Experiment
+	if data := fields["experiment"]; data != "" {
+		experiment = &model.Experiment{}
+		if err := json.Unmarshal([]byte(data), experiment); err != nil {
+			experiment = nil
+		}
+	}
+	var creatives []model.Creative
+	if data := fields["creatives"]; data != "" {
+		if err := json.Unmarshal([]byte(data), &creatives); err != nil {
+			creatives = nil
+		}
+	}
+	var blockedCategories []string
+	if data := fields["blocked_categories"]; data != "" {
+		if err := json.Unmarshal([]byte(data), &blockedCategories); err != nil {
+			blockedCategories = nil
+		}
+	}
+	optionalTime := func(name string) *time.Time {
+		if t, err := time.Parse(time.RFC3339Nano, fields[name]); err == nil {
+			return &t
+		}
+		return nil
+	}
+	return &model.Campaign{
+		ID:                fields["cid"],
+		TenantID:          fields["tenant_id"],
+		Name:              fields["name"],
+		Image:             fields["img"],
+		CTA:               fields["cta"],
+		Status:            fields["status"],
+		FrequencyCap:      frequencyCap,
+		Priority:          priority,
+		Weight:            weight,
+		DailyBudget:       dailyBudget,
+		TotalBudget:       totalBudget,
+		AppDailyCap:       appDailyCap,
+		MaxDailyApps:      maxDailyApps,
+		Experiment:        experiment,
+		Creatives:         creatives,
+		Rotation:          fields["rotation"],
+		BlockedCategories: blockedCategories,
+		ClickURL:          fields["click_url"],
+		StartAt:           optionalTime("start_at"),
+		EndAt:             optionalTime("end_at"),
+		CreatedAt:         createdAt,
+		UpdatedAt:         updatedAt,
+		DeletedAt:         optionalTime("deleted_at"),
+	}
+}
+
+// Targeting Rule Repository Methods
+
+func (r *RedisRepository) GetTargetingRules(ctx context.Context) ([]*model.TargetingRule, error) {
+	values, err := r.client.HVals(ctx, r.rulesKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeRules(values)
+}
+
+func (r *RedisRepository) GetTargetingRulesByCampaignID(ctx context.Context, campaignID string) ([]*model.TargetingRule, error) {
+	ids, err := r.client.SMembers(ctx, r.campaignRulesKey(campaignID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []*model.TargetingRule{}, nil
+	}
+
+	values, err := r.client.HMGet(ctx, r.rulesKey(), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			encoded = append(encoded, s)
+		}
+	}
+	return decodeRules(encoded)
+}
+
+func (r *RedisRepository) CreateTargetingRule(ctx context.Context, rule *model.TargetingRule) error {
+	id, err := r.client.Incr(ctx, r.ruleSeqKey()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to allocate targeting rule ID: %w", err)
+	}
+
+	rule.ID = id
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = time.Now()
+
+	return r.writeRule(ctx, rule, "")
+}
+
+func (r *RedisRepository) GetTargetingRuleByID(ctx context.Context, id int64) (*model.TargetingRule, error) {
+	return r.getRule(ctx, id)
+}
+
+func (r *RedisRepository) UpdateTargetingRule(ctx context.Context, rule *model.TargetingRule) error {
+	existing, err := r.getRule(ctx, rule.ID)
+	if err != nil {
+		return err
+	}
+
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now()
+
+	return r.writeRule(ctx, rule, existing.CampaignID)
+}
+
+func (r *RedisRepository) DeleteTargetingRule(ctx context.Context, id int64) error {
+	existing, err := r.getRule(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, r.rulesKey(), strconv.FormatInt(id, 10))
+		pipe.SRem(ctx, r.campaignRulesKey(existing.CampaignID), strconv.FormatInt(id, 10))
+		return nil
+	})
+	return err
+}
+
+func (r *RedisRepository) DeleteTargetingRulesByCampaignID(ctx context.Context, campaignID string) error {
+	ids, err := r.client.SMembers(ctx, r.campaignRulesKey(campaignID)).Result()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, r.rulesKey(), ids...)
+		pipe.Del(ctx, r.campaignRulesKey(campaignID))
+		return nil
+	})
+	return err
+}
+
+func (r *RedisRepository) getRule(ctx context.Context, id int64) (*model.TargetingRule, error) {
+	value, err := r.client.HGet(ctx, r.rulesKey(), strconv.FormatInt(id, 10)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("targeting rule with ID %d %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	var rule model.TargetingRule
+	if err := json.Unmarshal([]byte(value), &rule); err != nil {
+		return nil, fmt.Errorf("failed to decode targeting rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// writeRule stores a rule and moves it out of previousCampaignID's index if it changed campaigns
+func (r *RedisRepository) writeRule(ctx context.Context, rule *model.TargetingRule, previousCampaignID string) error {
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return fmt.Errorf("failed to encode targeting rule: %w", err)
+	}
+
+	id := strconv.FormatInt(rule.ID, 10)
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.rulesKey(), id, data)
+		if previousCampaignID != "" && previousCampaignID != rule.CampaignID {
+			pipe.SRem(ctx, r.campaignRulesKey(previousCampaignID), id)
+		}
+		pipe.SAdd(ctx, r.campaignRulesKey(rule.CampaignID), id)
+		return nil
+	})
+	return err
+}
+
+func decodeRules(values []string) ([]*model.TargetingRule, error) {
+	rules := make([]*model.TargetingRule, 0, len(values))
+	for _, v := range values {
+		var rule model.TargetingRule
+		if err := json.Unmarshal([]byte(v), &rule); err != nil {
+			return nil, fmt.Errorf("failed to decode targeting rule: %w", err)
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, nil
+}
+
+// Import Methods
+
+// raiseSeqScript raises a counter to at least ARGV[1], so IDs allocated
+// afterwards don't collide with imported ones
+var raiseSeqScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1])) or 0
+if current < tonumber(ARGV[1]) then
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// ImportCampaigns stores campaigns as they are, replacing those with the
+// same IDs
+func (r *RedisRepository) ImportCampaigns(ctx context.Context, campaigns []*model.Campaign) error {
+	for _, campaign := range campaigns {
+		if err := r.client.SAdd(ctx, r.campaignsKey(), campaign.ID).Err(); err != nil {
+			return err
+		}
+		if err := r.writeCampaign(ctx, campaign); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportTargetingRules stores rules as they are, replacing those with the
+// same IDs, and raises the rule ID counter past them
+func (r *RedisRepository) ImportTargetingRules(ctx context.Context, rules []*model.TargetingRule) error {
+	var maxID int64
+	for _, rule := range rules {
+		previousCampaignID := ""
+		existing, err := r.getRule(ctx, rule.ID)
+		switch {
+		case err == nil:
+			previousCampaignID = existing.CampaignID
+		case !errors.Is(err, ErrNotFound):
+			return err
+		}
+		if err := r.writeRule(ctx, rule, previousCampaignID); err != nil {
+			return err
+		}
+		maxID = max(maxID, rule.ID)
+	}
+	if maxID == 0 {
+		return nil
+	}
+	return raiseSeqScript.Run(ctx, r.client, []string{r.ruleSeqKey()}, maxID).Err()
+}
+
+// Idempotency Repository Methods
+
+func (r *RedisRepository) ReserveIdempotencyKey(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Until(record.ExpiresAt)
+	if ttl <= 0 {
+		return nil, fmt.Errorf("idempotency record for %s has already expired", record.Key)
+	}
+
+	reserved, err := r.client.SetNX(ctx, r.idempotencyKey(record.Key), encoded, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := r.client.Get(ctx, r.idempotencyKey(record.Key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// The record expired in between; claim the key again
+		return r.ReserveIdempotencyKey(ctx, record)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	var held IdempotencyRecord
+	if err := json.Unmarshal(existing, &held); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency record: %w", err)
+	}
+	return &held, nil
+}
+
+func (r *RedisRepository) CompleteIdempotencyKey(ctx context.Context, key string, result []byte) error {
+	encoded, err := r.client.Get(ctx, r.idempotencyKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return fmt.Errorf("idempotency key %s %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	var record IdempotencyRecord
+	if err := json.Unmarshal(encoded, &record); err != nil {
+		return fmt.Errorf("failed to decode idempotency record: %w", err)
+	}
+
+	record.Result = result
+	if encoded, err = json.Marshal(record); err != nil {
+		return err
+	}
+	err = r.client.SetArgs(ctx, r.idempotencyKey(key), encoded, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+	if errors.Is(err, redis.Nil) {
+		return fmt.Errorf("idempotency key %s %w", key, ErrNotFound)
+	}
+	return err
+}
+
+func (r *RedisRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.idempotencyKey(key)).Err()
+}
+
+// Delivery Stats Repository Methods
+
+func (r *RedisRepository) AddDeliveryCounts(ctx context.Context, counts []DeliveryCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	pipe := r.client.Pipeline()
+	for _, count := range counts {
+		field := strings.Join([]string{count.TenantID, count.CampaignID, count.Country, count.OS}, deliveryFieldSeparator)
+		pipe.HIncrBy(ctx, r.deliveriesKey(count.Day), field, count.Served)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisRepository) GetDeliveryCounts(ctx context.Context, filter DeliveryCountFilter) ([]DeliveryCount, error) {
+	from, err := time.Parse(deliveryDayLayout, filter.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from day: %w", err)
+	}
+	to, err := time.Parse(deliveryDayLayout, filter.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to day: %w", err)
+	}
+
+	pipe := r.client.Pipeline()
+	var days []string
+	var results []*redis.MapStringStringCmd
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, day.Format(deliveryDayLayout))
+		results = append(results, pipe.HGetAll(ctx, r.deliveriesKey(days[len(days)-1])))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get delivery counts: %w", err)
+	}
+
+	var counts []DeliveryCount
+	for i, result := range results {
+		for field, value := range result.Val() {
+			parts := strings.Split(field, deliveryFieldSeparator)
+			if len(parts) != 4 || parts[0] != filter.TenantID || (filter.CampaignID != "" && parts[1] != filter.CampaignID) {
+				continue
+			}
+			served, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode delivery count: %w", err)
+			}
+			counts = append(counts, DeliveryCount{
+				TenantID:   parts[0],
+				CampaignID: parts[1],
+				Day:        days[i],
+				Country:    parts[2],
+				OS:         parts[3],
+				Served:     served,
+			})
+		}
+	}
+	return counts, nil
+}
+
+// Kill Switch Repository Methods
+
+func (r *RedisRepository) GetKillSwitches(ctx context.Context) ([]*model.KillSwitch, error) {
+	values, err := r.client.HVals(ctx, r.killSwitchesKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	switches := make([]*model.KillSwitch, 0, len(values))
+	for _, v := range values {
+		var sw model.KillSwitch
+		if err := json.Unmarshal([]byte(v), &sw); err != nil {
+			return nil, fmt.Errorf("failed to decode kill switch: %w", err)
+		}
+		switches = append(switches, &sw)
+	}
+	return switches, nil
+}
+
+func (r *RedisRepository) EngageKillSwitch(ctx context.Context, sw *model.KillSwitch) error {
+	data, err := json.Marshal(sw)
+	if err != nil {
+		return fmt.Errorf("failed to encode kill switch: %w", err)
+	}
+	return r.client.HSet(ctx, r.killSwitchesKey(), sw.Scope(), data).Err()
+}
+
+func (r *RedisRepository) ReleaseKillSwitch(ctx context.Context, scope string) error {
+	return r.client.HDel(ctx, r.killSwitchesKey(), scope).Err()
+}
+
+// Decision Log Repository Methods
+
+// AddDecisions also removes the expired decisions of the tenants it adds to.
+// Decisions of a tenant are ordered by expiry, which follows the order they
+// were made in as long as the TTL doesn't change.
+func (r *RedisRepository) AddDecisions(ctx context.Context, decisions []*model.Decision) error {
+	if len(decisions) == 0 {
+		return nil
+	}
+	pipe := r.client.Pipeline()
+	expiries := make(map[string]time.Time)
+	for _, decision := range decisions {
+		data, err := json.Marshal(decision)
+		if err != nil {
+			return fmt.Errorf("failed to encode decision: %w", err)
+		}
+		pipe.ZAdd(ctx, r.decisionsKey(decision.TenantID), redis.Z{Score: float64(decision.ExpiresAt.UnixMilli()), Member: data})
+		requestKey := r.requestDecisionsKey(decision.TenantID, decision.RequestID)
+		pipe.RPush(ctx, requestKey, data)
+		pipe.ExpireAt(ctx, requestKey, decision.ExpiresAt)
+		if decision.ExpiresAt.After(expiries[decision.TenantID]) {
+			expiries[decision.TenantID] = decision.ExpiresAt
+		}
+	}
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	for tenantID, expiresAt := range expiries {
+		pipe.ZRemRangeByScore(ctx, r.decisionsKey(tenantID), "-inf", now)
+		pipe.ExpireAt(ctx, r.decisionsKey(tenantID), expiresAt)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to add decisions: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisRepository) GetDecisions(ctx context.Context, filter DecisionFilter) ([]*model.Decision, error) {
+	now := time.Now()
+	var values []string
+	var err error
+	if filter.RequestID != "" {
+		values, err = r.client.LRange(ctx, r.requestDecisionsKey(filter.TenantID, filter.RequestID), 0, -1).Result()
+		slices.Reverse(values)
+	} else {
+		values, err = r.client.ZRevRangeByScore(ctx, r.decisionsKey(filter.TenantID), &redis.ZRangeBy{
+			Min:   "(" + strconv.FormatInt(now.UnixMilli(), 10),
+			Max:   "+inf",
+			Count: int64(filter.Limit),
+		}).Result()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get decisions: %w", err)
+	}
+
+	decisions := []*model.Decision{}
+	for _, value := range values {
+		var decision model.Decision
+		if err := json.Unmarshal([]byte(value), &decision); err != nil {
+			return nil, fmt.Errorf("failed to decode decision: %w", err)
+		}
+		if decision.ExpiresAt.After(now) {
+			decisions = append(decisions, &decision)
+		}
+	}
+	slices.SortStableFunc(decisions, func(a, b *model.Decision) int {
+		return b.At.Compare(a.At)
+	})
+	if filter.Limit > 0 && len(decisions) > filter.Limit {
+		decisions = decisions[:filter.Limit]
+	}
+	return decisions, nil
+}
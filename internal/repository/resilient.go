@@ -0,0 +1,510 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Defaults for zero ResilienceOptions fields
+const (
+	DefaultMaxRetries       = 2
+	DefaultBaseDelay        = 50 * time.Millisecond
+	DefaultMaxDelay         = time.Second
+	DefaultFailureThreshold = 5
+	DefaultOpenTimeout      = 30 * time.Second
+)
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets every operation through
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a single probe through to test recovery
+	BreakerHalfOpen
+	// BreakerOpen fails operations immediately with ErrUnavailable
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half_open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// ResilienceOptions configures a ResilientRepository
+type ResilienceOptions struct {
+	// MaxRetries is how often a read failing with a transient error is retried
+	MaxRetries int
+	// BaseDelay and MaxDelay bound the jittered exponential backoff between
+	// retries
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// FailureThreshold is the number of consecutive transient failures that
+	// trips the breaker
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before letting a probe
+	// through
+	OpenTimeout time.Duration
+	// OnStateChange, if set, is called whenever the breaker changes state
+	OnStateChange func(state BreakerState)
+	// OnRetry, if set, is called before each retry of the named operation
+	OnRetry func(operation string)
+}
+
+// ResilientRepository wraps a repository with retries and a circuit breaker.
+// Reads failing with a transient error are retried with jittered exponential
+// backoff; writes are not, since the driver already retries the ones that are
+// safe to repeat. After FailureThreshold consecutive transient failures the
+// breaker opens and operations fail fast with ErrUnavailable until a probe
+// succeeds, giving the store room to recover. Health, Migrate and change
+// streams bypass the breaker.
+type ResilientRepository struct {
+	inner   RepositoryManager
+	opts    ResilienceOptions
+	breaker *circuitBreaker
+}
+
+// NewResilientRepository wraps inner with retries and a circuit breaker
+func NewResilientRepository(inner RepositoryManager, opts ResilienceOptions) *ResilientRepository {
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = DefaultBaseDelay
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = DefaultMaxDelay
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = DefaultOpenTimeout
+	}
+	return &ResilientRepository{
+		inner: inner,
+		opts:  opts,
+		breaker: &circuitBreaker{
+			threshold:   opts.FailureThreshold,
+			openTimeout: opts.OpenTimeout,
+			onChange:    opts.OnStateChange,
+		},
+	}
+}
+
+// BreakerState returns the current state of the circuit breaker
+func (r *ResilientRepository) BreakerState() BreakerState {
+	return r.breaker.current()
+}
+
+func (r *ResilientRepository) Campaign() CampaignRepository {
+	return &resilientCampaignRepo{r: r, inner: r.inner.Campaign()}
+}
+
+func (r *ResilientRepository) TargetingRule() TargetingRuleRepository {
+	return &resilientRuleRepo{r: r, inner: r.inner.TargetingRule()}
+}
+
+func (r *ResilientRepository) Idempotency() IdempotencyRepository {
+	return &resilientIdempotencyRepo{r: r, inner: r.inner.Idempotency()}
+}
+
+func (r *ResilientRepository) DeliveryStats() DeliveryStatsRepository {
+	return &resilientDeliveryStatsRepo{r: r, inner: r.inner.DeliveryStats()}
+}
+
+func (r *ResilientRepository) KillSwitch() KillSwitchRepository {
+	return &resilientKillSwitchRepo{r: r, inner: r.inner.KillSwitch()}
+}
+
+func (r *ResilientRepository) DecisionLog() DecisionLogRepository {
+	return &resilientDecisionLogRepo{r: r, inner: r.inner.DecisionLog()}
+}
+
+func (r *ResilientRepository) Close() error {
+	return r.inner.Close()
+}
+
+func (r *ResilientRepository) Health(ctx context.Context) error {
+	return r.inner.Health(ctx)
+}
+
+func (r *ResilientRepository) Migrate(ctx context.Context) error {
+	return r.inner.Migrate(ctx)
+}
+
+// WatchChanges streams changes from the wrapped repository
+func (r *ResilientRepository) WatchChanges(ctx context.Context, handle func(ChangeEvent)) error {
+	watcher, ok := r.inner.(ChangeWatcher)
+	if !ok {
+		return errors.New("repository does not support change streams")
+	}
+	return watcher.WatchChanges(ctx, handle)
+}
+
+// ImportCampaigns imports campaigns into the wrapped repository
+func (r *ResilientRepository) ImportCampaigns(ctx context.Context, campaigns []*model.Campaign) error {
+	importer, ok := r.inner.(Importer)
+	if !ok {
+		return errors.New("repository does not support imports")
+	}
+	return r.write(func() error { return importer.ImportCampaigns(ctx, campaigns) })
+}
+
+// ImportTargetingRules imports rules into the wrapped repository
+func (r *ResilientRepository) ImportTargetingRules(ctx context.Context, rules []*model.TargetingRule) error {
+	importer, ok := r.inner.(Importer)
+	if !ok {
+		return errors.New("repository does not support imports")
+	}
+	return r.write(func() error { return importer.ImportTargetingRules(ctx, rules) })
+}
+
+// WithTransaction runs fn in a transaction of the wrapped repository, if it
+// supports them. The transaction isn't retried; the calls fn makes are
+// guarded like any other.
+func (r *ResilientRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	transactor, ok := r.inner.(Transactor)
+	if !ok {
+		return fn(ctx)
+	}
+	return transactor.WithTransaction(ctx, fn)
+}
+
+// read runs a read operation, retrying transient failures
+func read[T any](ctx context.Context, r *ResilientRepository, operation string, fn func() (T, error)) (T, error) {
+	var zero T
+	for attempt := 0; ; attempt++ {
+		if !r.breaker.allow() {
+			return zero, fmt.Errorf("%w: circuit breaker open", ErrUnavailable)
+		}
+		result, err := fn()
+		r.breaker.record(err)
+		if err == nil {
+			return result, nil
+		}
+		if !isTransient(err) {
+			return zero, err
+		}
+		if attempt == r.opts.MaxRetries || r.breaker.current() == BreakerOpen {
+			slog.ErrorContext(ctx, "repository read failed", "operation", operation, "attempts", attempt+1, "error", err)
+			return zero, fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+
+		slog.WarnContext(ctx, "retrying repository read", "operation", operation, "attempt", attempt+1, "error", err)
+		if r.opts.OnRetry != nil {
+			r.opts.OnRetry(operation)
+		}
+		select {
+		case <-time.After(r.backoff(attempt)):
+		case <-ctx.Done():
+			return zero, fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+	}
+}
+
+// write runs a write operation once, guarded by the breaker
+func (r *ResilientRepository) write(fn func() error) error {
+	if !r.breaker.allow() {
+		return fmt.Errorf("%w: circuit breaker open", ErrUnavailable)
+	}
+	err := fn()
+	r.breaker.record(err)
+	if err != nil && isTransient(err) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return err
+}
+
+// backoff returns the delay before retry attempt+1: exponential in the
+// attempt, capped at MaxDelay, with jitter over its upper half so retries of
+// concurrent callers spread out
+func (r *ResilientRepository) backoff(attempt int) time.Duration {
+	delay := r.opts.BaseDelay << attempt
+	if delay <= 0 || delay > r.opts.MaxDelay {
+		delay = r.opts.MaxDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// isTransient reports whether err is a failure of the store itself, such as
+// a network error or timeout, rather than an answer like a missing document
+func isTransient(err error) bool {
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+	var labeled mongo.LabeledError
+	return errors.As(err, &labeled) &&
+		(labeled.HasErrorLabel("RetryableWriteError") || labeled.HasErrorLabel("TransientTransactionError"))
+}
+
+// circuitBreaker counts consecutive transient failures
+type circuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+	onChange    func(BreakerState)
+
+	mutex    sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+}
+
+// allow reports whether an operation may run. Once the open timeout has
+// passed, the first caller is let through as a probe.
+func (b *circuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		return true
+	case BreakerHalfOpen:
+		// A probe is in flight
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of an operation
+func (b *circuitBreaker) record(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err == nil || !isTransient(err) {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		if b.state != BreakerOpen {
+			b.setState(BreakerOpen)
+		}
+	}
+}
+
+func (b *circuitBreaker) current() BreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state
+}
+
+// setState must be called with the mutex held
+func (b *circuitBreaker) setState(state BreakerState) {
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
+
+type resilientCampaignRepo struct {
+	r     *ResilientRepository
+	inner CampaignRepository
+}
+
+func (c *resilientCampaignRepo) GetActiveCampaigns(ctx context.Context) ([]*model.Campaign, error) {
+	return read(ctx, c.r, "GetActiveCampaigns", func() ([]*model.Campaign, error) {
+		return c.inner.GetActiveCampaigns(ctx)
+	})
+}
+
+func (c *resilientCampaignRepo) GetCampaignsByStatus(ctx context.Context, statuses []string) ([]*model.Campaign, error) {
+	return read(ctx, c.r, "GetCampaignsByStatus", func() ([]*model.Campaign, error) {
+		return c.inner.GetCampaignsByStatus(ctx, statuses)
+	})
+}
+
+func (c *resilientCampaignRepo) GetCampaignByID(ctx context.Context, id string) (*model.Campaign, error) {
+	return read(ctx, c.r, "GetCampaignByID", func() (*model.Campaign, error) {
+		return c.inner.GetCampaignByID(ctx, id)
+	})
+}
+
+func (c *resilientCampaignRepo) GetCampaignsByIDs(ctx context.Context, ids []string) ([]*model.Campaign, error) {
+	return read(ctx, c.r, "GetCampaignsByIDs", func() ([]*model.Campaign, error) {
+		return c.inner.GetCampaignsByIDs(ctx, ids)
+	})
+}
+
+func (c *resilientCampaignRepo) ListCampaigns(ctx context.Context, filter CampaignFilter) ([]*model.Campaign, int64, error) {
+	var total int64
+	campaigns, err := read(ctx, c.r, "ListCampaigns", func() ([]*model.Campaign, error) {
+		var campaigns []*model.Campaign
+		var err error
+		campaigns, total, err = c.inner.ListCampaigns(ctx, filter)
+		return campaigns, err
+	})
+	return campaigns, total, err
+}
+
+func (c *resilientCampaignRepo) SearchCampaigns(ctx context.Context, search CampaignSearch) ([]*model.ScoredCampaign, int64, error) {
+	var total int64
+	campaigns, err := read(ctx, c.r, "SearchCampaigns", func() ([]*model.ScoredCampaign, error) {
+		var campaigns []*model.ScoredCampaign
+		var err error
+		campaigns, total, err = c.inner.SearchCampaigns(ctx, search)
+		return campaigns, err
+	})
+	return campaigns, total, err
+}
+
+func (c *resilientCampaignRepo) CreateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	return c.r.write(func() error { return c.inner.CreateCampaign(ctx, campaign) })
+}
+
+func (c *resilientCampaignRepo) BulkCreateCampaigns(ctx context.Context, campaigns []*model.Campaign, rules []*model.TargetingRule) error {
+	return c.r.write(func() error { return c.inner.BulkCreateCampaigns(ctx, campaigns, rules) })
+}
+
+func (c *resilientCampaignRepo) UpdateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	return c.r.write(func() error { return c.inner.UpdateCampaign(ctx, campaign) })
+}
+
+func (c *resilientCampaignRepo) DeleteCampaign(ctx context.Context, id string) error {
+	return c.r.write(func() error { return c.inner.DeleteCampaign(ctx, id) })
+}
+
+func (c *resilientCampaignRepo) GetMatchingCampaignIDs(ctx context.Context, dimensions []model.Dimension) ([]string, error) {
+	return read(ctx, c.r, "GetMatchingCampaignIDs", func() ([]string, error) {
+		return c.inner.GetMatchingCampaignIDs(ctx, dimensions)
+	})
+}
+
+func (c *resilientCampaignRepo) UpdateCampaignStatus(ctx context.Context, id, status string) error {
+	return c.r.write(func() error { return c.inner.UpdateCampaignStatus(ctx, id, status) })
+}
+
+type resilientRuleRepo struct {
+	r     *ResilientRepository
+	inner TargetingRuleRepository
+}
+
+func (t *resilientRuleRepo) GetTargetingRules(ctx context.Context) ([]*model.TargetingRule, error) {
+	return read(ctx, t.r, "GetTargetingRules", func() ([]*model.TargetingRule, error) {
+		return t.inner.GetTargetingRules(ctx)
+	})
+}
+
+func (t *resilientRuleRepo) GetTargetingRulesByCampaignID(ctx context.Context, campaignID string) ([]*model.TargetingRule, error) {
+	return read(ctx, t.r, "GetTargetingRulesByCampaignID", func() ([]*model.TargetingRule, error) {
+		return t.inner.GetTargetingRulesByCampaignID(ctx, campaignID)
+	})
+}
+
+func (t *resilientRuleRepo) GetTargetingRuleByID(ctx context.Context, id int64) (*model.TargetingRule, error) {
+	return read(ctx, t.r, "GetTargetingRuleByID", func() (*model.TargetingRule, error) {
+		return t.inner.GetTargetingRuleByID(ctx, id)
+	})
+}
+
+func (t *resilientRuleRepo) CreateTargetingRule(ctx context.Context, rule *model.TargetingRule) error {
+	return t.r.write(func() error { return t.inner.CreateTargetingRule(ctx, rule) })
+}
+
+func (t *resilientRuleRepo) UpdateTargetingRule(ctx context.Context, rule *model.TargetingRule) error {
+	return t.r.write(func() error { return t.inner.UpdateTargetingRule(ctx, rule) })
+}
+
+func (t *resilientRuleRepo) DeleteTargetingRule(ctx context.Context, id int64) error {
+	return t.r.write(func() error { return t.inner.DeleteTargetingRule(ctx, id) })
+}
+
+func (t *resilientRuleRepo) DeleteTargetingRulesByCampaignID(ctx context.Context, campaignID string) error {
+	return t.r.write(func() error { return t.inner.DeleteTargetingRulesByCampaignID(ctx, campaignID) })
+}
+
+type resilientIdempotencyRepo struct {
+	r     *ResilientRepository
+	inner IdempotencyRepository
+}
+
+func (i *resilientIdempotencyRepo) ReserveIdempotencyKey(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	var held *IdempotencyRecord
+	err := i.r.write(func() error {
+		var err error
+		held, err = i.inner.ReserveIdempotencyKey(ctx, record)
+		return err
+	})
+	return held, err
+}
+
+func (i *resilientIdempotencyRepo) CompleteIdempotencyKey(ctx context.Context, key string, result []byte) error {
+	return i.r.write(func() error { return i.inner.CompleteIdempotencyKey(ctx, key, result) })
+}
+
+func (i *resilientIdempotencyRepo) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return i.r.write(func() error { return i.inner.ReleaseIdempotencyKey(ctx, key) })
+}
+
+type resilientDeliveryStatsRepo struct {
+	r     *ResilientRepository
+	inner DeliveryStatsRepository
+}
+
+func (d *resilientDeliveryStatsRepo) AddDeliveryCounts(ctx context.Context, counts []DeliveryCount) error {
+	return d.r.write(func() error { return d.inner.AddDeliveryCounts(ctx, counts) })
+}
+
+func (d *resilientDeliveryStatsRepo) GetDeliveryCounts(ctx context.Context, filter DeliveryCountFilter) ([]DeliveryCount, error) {
+	return read(ctx, d.r, "GetDeliveryCounts", func() ([]DeliveryCount, error) {
+		return d.inner.GetDeliveryCounts(ctx, filter)
+	})
+}
+
+type resilientKillSwitchRepo struct {
+	r     *ResilientRepository
+	inner KillSwitchRepository
+}
+
+func (k *resilientKillSwitchRepo) GetKillSwitches(ctx context.Context) ([]*model.KillSwitch, error) {
+	return read(ctx, k.r, "GetKillSwitches", func() ([]*model.KillSwitch, error) {
+		return k.inner.GetKillSwitches(ctx)
+	})
+}
+
+func (k *resilientKillSwitchRepo) EngageKillSwitch(ctx context.Context, sw *model.KillSwitch) error {
+	return k.r.write(func() error { return k.inner.EngageKillSwitch(ctx, sw) })
+}
+
+func (k *resilientKillSwitchRepo) ReleaseKillSwitch(ctx context.Context, scope string) error {
+	return k.r.write(func() error { return k.inner.ReleaseKillSwitch(ctx, scope) })
+}
+
+type resilientDecisionLogRepo struct {
+	r     *ResilientRepository
+	inner DecisionLogRepository
+}
+
+func (d *resilientDecisionLogRepo) AddDecisions(ctx context.Context, decisions []*model.Decision) error {
+	return d.r.write(func() error { return d.inner.AddDecisions(ctx, decisions) })
+}
+
+func (d *resilientDecisionLogRepo) GetDecisions(ctx context.Context, filter DecisionFilter) ([]*model.Decision, error) {
+	return read(ctx, d.r, "GetDecisions", func() ([]*model.Decision, error) {
+		return d.inner.GetDecisions(ctx, filter)
+	})
+}
@@ -0,0 +1,781 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// SQLiteRepository stores campaigns and targeting rules in an SQLite
+// database, for local development and tests without MongoDB or Redis.
+// Campaigns and rules are stored as JSON documents next to the columns they
+// are looked up by; filtering beyond those columns happens in memory, as
+// with Redis.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// sqliteSchema creates the tables Migrate sets up. Statements are
+// idempotent, so it runs on every startup.
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS campaigns (
+		id         TEXT PRIMARY KEY,
+		tenant_id  TEXT NOT NULL DEFAULT '',
+		status     TEXT NOT NULL,
+		data       TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS campaigns_tenant_status ON campaigns (tenant_id, status)`,
+	`CREATE INDEX IF NOT EXISTS campaigns_status ON campaigns (status)`,
+	`CREATE TABLE IF NOT EXISTS targeting_rules (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		campaign_id TEXT NOT NULL,
+		data        TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS targeting_rules_campaign ON targeting_rules (campaign_id)`,
+	`CREATE TABLE IF NOT EXISTS idempotency_keys (
+		key         TEXT PRIMARY KEY,
+		fingerprint TEXT NOT NULL,
+		result      BLOB,
+		expires_at  INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS delivery_counts (
+		tenant_id   TEXT NOT NULL,
+		campaign_id TEXT NOT NULL,
+		day         TEXT NOT NULL,
+		country     TEXT NOT NULL,
+		os          TEXT NOT NULL,
+		served      INTEGER NOT NULL,
+		PRIMARY KEY (tenant_id, campaign_id, day, country, os)
+	)`,
+	`CREATE TABLE IF NOT EXISTS kill_switches (
+		scope TEXT PRIMARY KEY,
+		data  TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS decision_log (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		tenant_id  TEXT NOT NULL,
+		request_id TEXT NOT NULL,
+		at         INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL,
+		data       TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS decision_log_request ON decision_log (tenant_id, request_id)`,
+	`CREATE INDEX IF NOT EXISTS decision_log_at ON decision_log (tenant_id, at)`,
+	`CREATE INDEX IF NOT EXISTS decision_log_expires_at ON decision_log (expires_at)`,
+}
+
+// NewSQLiteRepository creates an SQLite backed repository on an open
+// database. Call Migrate before use to create the tables.
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	if db == nil {
+		panic("sqlite database cannot be nil")
+	}
+	return &SQLiteRepository{db: db}
+}
+
+func (r *SQLiteRepository) Campaign() CampaignRepository {
+	return r
+}
+
+func (r *SQLiteRepository) TargetingRule() TargetingRuleRepository {
+	return r
+}
+
+func (r *SQLiteRepository) Idempotency() IdempotencyRepository {
+	return r
+}
+
+func (r *SQLiteRepository) DeliveryStats() DeliveryStatsRepository {
+	return r
+}
+
+func (r *SQLiteRepository) KillSwitch() KillSwitchRepository {
+	return r
+}
+
+func (r *SQLiteRepository) DecisionLog() DecisionLogRepository {
+	return r
+}
+
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *SQLiteRepository) Health(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// Migrate creates the tables and indexes that don't exist yet
+func (r *SQLiteRepository) Migrate(ctx context.Context) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		for _, statement := range sqliteSchema {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to migrate sqlite schema: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// sqliteTxKey is the context key of the transaction repository calls join
+type sqliteTxKey struct{}
+
+// WithTransaction runs fn in a transaction, committing it if fn succeeds.
+// The repository calls fn makes with the context it is given, including
+// nested WithTransaction calls, run in the transaction.
+func (r *SQLiteRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		return fn(context.WithValue(ctx, sqliteTxKey{}, tx))
+	})
+}
+
+// conn returns the transaction ctx carries, or the database outside of one.
+// The database has a single connection, so calls made in a transaction must
+// use it.
+func (r *SQLiteRepository) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(sqliteTxKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return r.db
+}
+
+// withTx runs fn in a transaction, committing it if fn succeeds. Within a
+// transaction ctx carries, fn runs in that one.
+func (r *SQLiteRepository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(sqliteTxKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Campaign Repository Methods
+
+func (r *SQLiteRepository) GetActiveCampaigns(ctx context.Context) ([]*model.Campaign, error) {
+	return r.queryCampaigns(ctx, `SELECT data FROM campaigns WHERE status = ?`, model.StatusActive)
+}
+
+func (r *SQLiteRepository) GetCampaignsByStatus(ctx context.Context, statuses []string) ([]*model.Campaign, error) {
+	if len(statuses) == 0 {
+		return []*model.Campaign{}, nil
+	}
+	return r.queryCampaigns(ctx,
+		`SELECT data FROM campaigns WHERE status IN (`+placeholders(len(statuses))+`)`,
+		stringArgs(statuses)...)
+}
+
+func (r *SQLiteRepository) GetCampaignByID(ctx context.Context, id string) (*model.Campaign, error) {
+	return getSQLiteCampaign(ctx, r.conn(ctx), id)
+}
+
+func (r *SQLiteRepository) GetCampaignsByIDs(ctx context.Context, ids []string) ([]*model.Campaign, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return r.queryCampaigns(ctx,
+		`SELECT data FROM campaigns WHERE id IN (`+placeholders(len(ids))+`)`,
+		stringArgs(ids)...)
+}
+
+// ListCampaigns selects the tenant's campaigns by status in SQL and applies
+// the rest of the filter in memory
+func (r *SQLiteRepository) ListCampaigns(ctx context.Context, filter CampaignFilter) ([]*model.Campaign, int64, error) {
+	query, args := `SELECT data FROM campaigns WHERE tenant_id = ?`, []any{filter.TenantID}
+	if filter.Status != "" {
+		query, args = query+` AND status = ?`, append(args, filter.Status)
+	}
+	campaigns, err := r.queryCampaigns(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, total := filterCampaigns(campaigns, filter)
+	return page, total, nil
+}
+
+// SearchCampaigns loads the tenant's campaigns and scans their names and
+// CTAs in memory, like ListCampaigns
+func (r *SQLiteRepository) SearchCampaigns(ctx context.Context, search CampaignSearch) ([]*model.ScoredCampaign, int64, error) {
+	campaigns, err := r.queryCampaigns(ctx, `SELECT data FROM campaigns WHERE tenant_id = ?`, search.TenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, total := searchCampaigns(campaigns, search)
+	return page, total, nil
+}
+
+func (r *SQLiteRepository) CreateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	campaign.CreatedAt = time.Now()
+	campaign.UpdatedAt = time.Now()
+	return insertSQLiteCampaign(ctx, r.conn(ctx), campaign)
+}
+
+// BulkCreateCampaigns writes all campaigns and rules in one transaction,
+// which is rolled back if any campaign ID is taken
+func (r *SQLiteRepository) BulkCreateCampaigns(ctx context.Context, campaigns []*model.Campaign, rules []*model.TargetingRule) error {
+	if len(campaigns) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		for _, campaign := range campaigns {
+			campaign.CreatedAt = now
+			campaign.UpdatedAt = now
+			if err := insertSQLiteCampaign(ctx, tx, campaign); err != nil {
+				return err
+			}
+		}
+		for _, rule := range rules {
+			rule.CreatedAt = now
+			rule.UpdatedAt = now
+			if err := insertSQLiteRule(ctx, tx, rule); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteRepository) UpdateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	campaign.UpdatedAt = time.Now()
+	return updateSQLiteCampaign(ctx, r.conn(ctx), campaign)
+}
+
+func (r *SQLiteRepository) DeleteCampaign(ctx context.Context, id string) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM campaigns WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return fmt.Errorf("campaign with ID %s %w", id, ErrNotFound)
+		}
+
+		// Also delete associated targeting rules
+		_, err = tx.ExecContext(ctx, `DELETE FROM targeting_rules WHERE campaign_id = ?`, id)
+		return err
+	})
+}
+
+func (r *SQLiteRepository) UpdateCampaignStatus(ctx context.Context, id, status string) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		campaign, err := getSQLiteCampaign(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		campaign.Status = status
+		campaign.UpdatedAt = time.Now()
+		return updateSQLiteCampaign(ctx, tx, campaign)
+	})
+}
+
+func (r *SQLiteRepository) GetMatchingCampaignIDs(ctx context.Context, dimensions []model.Dimension) ([]string, error) {
+	active, err := r.GetActiveCampaigns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := r.GetTargetingRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules = liveRules(rules)
+
+	rulesByCampaign := make(map[string][]*model.TargetingRule)
+	for _, rule := range rules {
+		rulesByCampaign[rule.CampaignID] = append(rulesByCampaign[rule.CampaignID], rule)
+	}
+
+	var matched []string
+	for _, campaign := range active {
+		if campaignMatchesDimensions(rulesByCampaign[campaign.ID], dimensions) {
+			matched = append(matched, campaign.ID)
+		}
+	}
+	sort.Strings(matched)
+
+	return matched, nil
+}
+
+func (r *SQLiteRepository) queryCampaigns(ctx context.Context, query string, args ...any) ([]*model.Campaign, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := []*model.Campaign{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var campaign model.Campaign
+		if err := json.Unmarshal([]byte(data), &campaign); err != nil {
+			return nil, fmt.Errorf("failed to decode campaign: %w", err)
+		}
+		campaigns = append(campaigns, &campaign)
+	}
+	return campaigns, rows.Err()
+}
+
+func getSQLiteCampaign(ctx context.Context, q querier, id string) (*model.Campaign, error) {
+	var data string
+	err := q.QueryRowContext(ctx, `SELECT data FROM campaigns WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("campaign with ID %s %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var campaign model.Campaign
+	if err := json.Unmarshal([]byte(data), &campaign); err != nil {
+		return nil, fmt.Errorf("failed to decode campaign: %w", err)
+	}
+	return &campaign, nil
+}
+
+func insertSQLiteCampaign(ctx context.Context, q querier, campaign *model.Campaign) error {
+	data, err := json.Marshal(campaign)
+	if err != nil {
+		return fmt.Errorf("failed to encode campaign: %w", err)
+	}
+	result, err := q.ExecContext(ctx,
+		`INSERT INTO campaigns (id, tenant_id, status, data) VALUES (?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`,
+		campaign.ID, campaign.TenantID, campaign.Status, string(data))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("campaign with ID %s %w", campaign.ID, ErrAlreadyExists)
+	}
+	return nil
+}
+
+func updateSQLiteCampaign(ctx context.Context, q querier, campaign *model.Campaign) error {
+	data, err := json.Marshal(campaign)
+	if err != nil {
+		return fmt.Errorf("failed to encode campaign: %w", err)
+	}
+	result, err := q.ExecContext(ctx,
+		`UPDATE campaigns SET tenant_id = ?, status = ?, data = ? WHERE id = ?`,
+		campaign.TenantID, campaign.Status, string(data), campaign.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("campaign with ID %s %w", campaign.ID, ErrNotFound)
+	}
+	return nil
+}
+
+// Targeting Rule Repository Methods
+
+func (r *SQLiteRepository) GetTargetingRules(ctx context.Context) ([]*model.TargetingRule, error) {
+	return r.queryRules(ctx, `SELECT data FROM targeting_rules ORDER BY id`)
+}
+
+func (r *SQLiteRepository) GetTargetingRulesByCampaignID(ctx context.Context, campaignID string) ([]*model.TargetingRule, error) {
+	return r.queryRules(ctx, `SELECT data FROM targeting_rules WHERE campaign_id = ? ORDER BY id`, campaignID)
+}
+
+func (r *SQLiteRepository) GetTargetingRuleByID(ctx context.Context, id int64) (*model.TargetingRule, error) {
+	return getSQLiteRule(ctx, r.conn(ctx), id)
+}
+
+func (r *SQLiteRepository) CreateTargetingRule(ctx context.Context, rule *model.TargetingRule) error {
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = time.Now()
+	return insertSQLiteRule(ctx, r.conn(ctx), rule)
+}
+
+func (r *SQLiteRepository) UpdateTargetingRule(ctx context.Context, rule *model.TargetingRule) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		existing, err := getSQLiteRule(ctx, tx, rule.ID)
+		if err != nil {
+			return err
+		}
+
+		rule.CreatedAt = existing.CreatedAt
+		rule.UpdatedAt = time.Now()
+		return writeSQLiteRule(ctx, tx, rule)
+	})
+}
+
+func (r *SQLiteRepository) DeleteTargetingRule(ctx context.Context, id int64) error {
+	result, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM targeting_rules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("targeting rule with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) DeleteTargetingRulesByCampaignID(ctx context.Context, campaignID string) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM targeting_rules WHERE campaign_id = ?`, campaignID)
+	return err
+}
+
+func (r *SQLiteRepository) queryRules(ctx context.Context, query string, args ...any) ([]*model.TargetingRule, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*model.TargetingRule{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var rule model.TargetingRule
+		if err := json.Unmarshal([]byte(data), &rule); err != nil {
+			return nil, fmt.Errorf("failed to decode targeting rule: %w", err)
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, rows.Err()
+}
+
+func getSQLiteRule(ctx context.Context, q querier, id int64) (*model.TargetingRule, error) {
+	var data string
+	err := q.QueryRowContext(ctx, `SELECT data FROM targeting_rules WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("targeting rule with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rule model.TargetingRule
+	if err := json.Unmarshal([]byte(data), &rule); err != nil {
+		return nil, fmt.Errorf("failed to decode targeting rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// insertSQLiteRule stores a new rule under an ID allocated by SQLite. The
+// document is written once the ID is known so it carries it.
+func insertSQLiteRule(ctx context.Context, q querier, rule *model.TargetingRule) error {
+	result, err := q.ExecContext(ctx,
+		`INSERT INTO targeting_rules (campaign_id, data) VALUES (?, '{}')`, rule.CampaignID)
+	if err != nil {
+		return err
+	}
+	if rule.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to allocate targeting rule ID: %w", err)
+	}
+	return writeSQLiteRule(ctx, q, rule)
+}
+
+// writeSQLiteRule stores a rule under its ID, replacing any rule with it
+func writeSQLiteRule(ctx context.Context, q querier, rule *model.TargetingRule) error {
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return fmt.Errorf("failed to encode targeting rule: %w", err)
+	}
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO targeting_rules (id, campaign_id, data) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET campaign_id = excluded.campaign_id, data = excluded.data`,
+		rule.ID, rule.CampaignID, string(data))
+	return err
+}
+
+// Import Methods
+
+// ImportCampaigns stores campaigns as they are, replacing those with the
+// same IDs
+func (r *SQLiteRepository) ImportCampaigns(ctx context.Context, campaigns []*model.Campaign) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		for _, campaign := range campaigns {
+			data, err := json.Marshal(campaign)
+			if err != nil {
+				return fmt.Errorf("failed to encode campaign: %w", err)
+			}
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO campaigns (id, tenant_id, status, data) VALUES (?, ?, ?, ?)
+				ON CONFLICT (id) DO UPDATE SET tenant_id = excluded.tenant_id, status = excluded.status, data = excluded.data`,
+				campaign.ID, campaign.TenantID, campaign.Status, string(data))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ImportTargetingRules stores rules as they are, replacing those with the
+// same IDs. AUTOINCREMENT keeps later IDs past the imported ones.
+func (r *SQLiteRepository) ImportTargetingRules(ctx context.Context, rules []*model.TargetingRule) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		for _, rule := range rules {
+			if err := writeSQLiteRule(ctx, tx, rule); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Idempotency Repository Methods
+
+func (r *SQLiteRepository) ReserveIdempotencyKey(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	if !record.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("idempotency record for %s has already expired", record.Key)
+	}
+
+	var held *IdempotencyRecord
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		// An expired record no longer holds the key
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM idempotency_keys WHERE key = ? AND expires_at <= ?`,
+			record.Key, time.Now().UnixNano()); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO idempotency_keys (key, fingerprint, result, expires_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (key) DO NOTHING`,
+			record.Key, record.Fingerprint, record.Result, record.ExpiresAt.UnixNano())
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+
+		held = &IdempotencyRecord{Key: record.Key}
+		var expiresAt int64
+		if err := tx.QueryRowContext(ctx,
+			`SELECT fingerprint, result, expires_at FROM idempotency_keys WHERE key = ?`,
+			record.Key).Scan(&held.Fingerprint, &held.Result, &expiresAt); err != nil {
+			return err
+		}
+		held.ExpiresAt = time.Unix(0, expiresAt)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	return held, nil
+}
+
+func (r *SQLiteRepository) CompleteIdempotencyKey(ctx context.Context, key string, result []byte) error {
+	res, err := r.conn(ctx).ExecContext(ctx,
+		`UPDATE idempotency_keys SET result = ? WHERE key = ? AND expires_at > ?`,
+		result, key, time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("idempotency key %s %w", key, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = ?`, key)
+	return err
+}
+
+// Delivery Stats Repository Methods
+
+func (r *SQLiteRepository) AddDeliveryCounts(ctx context.Context, counts []DeliveryCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		for _, count := range counts {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO delivery_counts (tenant_id, campaign_id, day, country, os, served) VALUES (?, ?, ?, ?, ?, ?)
+				ON CONFLICT (tenant_id, campaign_id, day, country, os) DO UPDATE SET served = served + excluded.served`,
+				count.TenantID, count.CampaignID, count.Day, count.Country, count.OS, count.Served)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetDeliveryCounts selects counts by day range; YYYYMMDD days compare in
+// calendar order as strings
+func (r *SQLiteRepository) GetDeliveryCounts(ctx context.Context, filter DeliveryCountFilter) ([]DeliveryCount, error) {
+	if _, err := time.Parse(deliveryDayLayout, filter.From); err != nil {
+		return nil, fmt.Errorf("invalid from day: %w", err)
+	}
+	if _, err := time.Parse(deliveryDayLayout, filter.To); err != nil {
+		return nil, fmt.Errorf("invalid to day: %w", err)
+	}
+
+	query := `SELECT tenant_id, campaign_id, day, country, os, served FROM delivery_counts
+		WHERE tenant_id = ? AND day >= ? AND day <= ?`
+	args := []any{filter.TenantID, filter.From, filter.To}
+	if filter.CampaignID != "" {
+		query, args = query+` AND campaign_id = ?`, append(args, filter.CampaignID)
+	}
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []DeliveryCount
+	for rows.Next() {
+		var count DeliveryCount
+		if err := rows.Scan(&count.TenantID, &count.CampaignID, &count.Day, &count.Country, &count.OS, &count.Served); err != nil {
+			return nil, fmt.Errorf("failed to decode delivery count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// Kill Switch Repository Methods
+
+func (r *SQLiteRepository) GetKillSwitches(ctx context.Context) ([]*model.KillSwitch, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT data FROM kill_switches`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	switches := []*model.KillSwitch{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var sw model.KillSwitch
+		if err := json.Unmarshal([]byte(data), &sw); err != nil {
+			return nil, fmt.Errorf("failed to decode kill switch: %w", err)
+		}
+		switches = append(switches, &sw)
+	}
+	return switches, rows.Err()
+}
+
+func (r *SQLiteRepository) EngageKillSwitch(ctx context.Context, sw *model.KillSwitch) error {
+	data, err := json.Marshal(sw)
+	if err != nil {
+		return fmt.Errorf("failed to encode kill switch: %w", err)
+	}
+	_, err = r.conn(ctx).ExecContext(ctx,
+		`INSERT INTO kill_switches (scope, data) VALUES (?, ?) ON CONFLICT (scope) DO UPDATE SET data = excluded.data`,
+		sw.Scope(), string(data))
+	return err
+}
+
+func (r *SQLiteRepository) ReleaseKillSwitch(ctx context.Context, scope string) error {
+	_, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM kill_switches WHERE scope = ?`, scope)
+	return err
+}
+
+// Decision Log Repository Methods
+
+// AddDecisions also removes the expired decisions, which SQLite has no TTL
+// index for
+func (r *SQLiteRepository) AddDecisions(ctx context.Context, decisions []*model.Decision) error {
+	if len(decisions) == 0 {
+		return nil
+	}
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM decision_log WHERE expires_at <= ?`, time.Now().UnixNano()); err != nil {
+			return err
+		}
+		for _, decision := range decisions {
+			data, err := json.Marshal(decision)
+			if err != nil {
+				return fmt.Errorf("failed to encode decision: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO decision_log (tenant_id, request_id, at, expires_at, data) VALUES (?, ?, ?, ?, ?)`,
+				decision.TenantID, decision.RequestID, decision.At.UnixNano(), decision.ExpiresAt.UnixNano(), string(data)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteRepository) GetDecisions(ctx context.Context, filter DecisionFilter) ([]*model.Decision, error) {
+	query := `SELECT data FROM decision_log WHERE tenant_id = ? AND expires_at > ?`
+	args := []any{filter.TenantID, time.Now().UnixNano()}
+	if filter.RequestID != "" {
+		query, args = query+` AND request_id = ?`, append(args, filter.RequestID)
+	}
+	query += ` ORDER BY at DESC, id DESC`
+	if filter.Limit > 0 {
+		query, args = query+` LIMIT ?`, append(args, filter.Limit)
+	}
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get decisions: %w", err)
+	}
+	defer rows.Close()
+
+	decisions := []*model.Decision{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var decision model.Decision
+		if err := json.Unmarshal([]byte(data), &decision); err != nil {
+			return nil, fmt.Errorf("failed to decode decision: %w", err)
+		}
+		decisions = append(decisions, &decision)
+	}
+	return decisions, rows.Err()
+}
+
+// placeholders returns n comma separated bind parameters
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// stringArgs converts values to query arguments
+func stringArgs(values []string) []any {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Harshi-itaSinha/target-engine/internal/repository")
+
+// startMongoSpan starts a client span for a MongoDB operation on a collection
+func startMongoSpan(ctx context.Context, operation, collection string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "mongo."+operation+" "+collection,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "mongodb"),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.collection.name", collection),
+		),
+	)
+}
+
+// spanError records err on span, if any, and returns it unchanged
+func spanError(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
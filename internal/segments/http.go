@@ -0,0 +1,71 @@
+package segments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultTimeout bounds an HTTP segment lookup when none is configured
+const DefaultTimeout = 100 * time.Millisecond
+
+// HTTPProvider asks a segment service for the segments of a user with
+// GET <url>?user_id=<user ID>, which answers {"segments": ["id", ...]}. A 404
+// means the service doesn't know the user, who then belongs to no segment.
+type HTTPProvider struct {
+	url    *url.URL
+	client *http.Client
+}
+
+// NewHTTPProvider creates a provider calling the segment service at rawURL,
+// giving up on a lookup after timeout, or DefaultTimeout if it isn't positive
+func NewHTTPProvider(rawURL string, timeout time.Duration) (*HTTPProvider, error) {
+	parsed, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid segment service URL: %w", err)
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &HTTPProvider{url: parsed, client: &http.Client{Timeout: timeout}}, nil
+}
+
+// segmentsResponse is the body returned by the segment service
+type segmentsResponse struct {
+	Segments []string `json:"segments"`
+}
+
+func (p *HTTPProvider) Segments(ctx context.Context, userID string) ([]string, error) {
+	target := *p.url
+	query := target.Query()
+	query.Set("user_id", userID)
+	target.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("segment lookup failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("segment lookup failed: %s", resp.Status)
+	}
+	var body segmentsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid segment lookup response: %w", err)
+	}
+	return body.Segments, nil
+}
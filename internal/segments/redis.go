@@ -0,0 +1,27 @@
+package segments
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisProvider reads the segments of a user from a Redis set keyed by the
+// user ID, which segment pipelines keep up to date with SADD and SREM
+type RedisProvider struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisProvider creates a provider reading the set <prefix>:<user ID>, or
+// the user ID alone without a prefix
+func NewRedisProvider(client *redis.Client, prefix string) *RedisProvider {
+	if prefix != "" {
+		prefix += ":"
+	}
+	return &RedisProvider{client: client, prefix: prefix}
+}
+
+func (p *RedisProvider) Segments(ctx context.Context, userID string) ([]string, error) {
+	return p.client.SMembers(ctx, p.prefix+userID).Result()
+}
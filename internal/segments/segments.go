@@ -0,0 +1,114 @@
+// Package segments resolves the audience segments a user belongs to, for
+// targeting rules that include or exclude segment IDs. Segments are kept in
+// an external store, such as Redis sets or an HTTP segment service.
+package segments
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Provider looks up the segments of users
+type Provider interface {
+	// Segments returns the IDs of the segments userID belongs to, or none
+	// for unknown users
+	Segments(ctx context.Context, userID string) ([]string, error)
+}
+
+// Defaults of CachedProvider
+const (
+	DefaultCacheTTL  = time.Minute
+	DefaultCacheSize = 10000
+)
+
+// CachedProvider remembers the segments of recently looked up users, so
+// frequent delivery requests of the same user don't each reach the store.
+// Failed lookups aren't cached.
+type CachedProvider struct {
+	provider Provider
+	ttl      time.Duration
+	maxSize  int
+	ll       *list.List
+	items    map[string]*list.Element
+	mutex    sync.Mutex
+}
+
+// cacheEntry holds the segments of one user
+type cacheEntry struct {
+	userID    string
+	segments  []string
+	expiresAt time.Time
+}
+
+// NewCachedProvider caches the lookups of provider for ttl, keeping at most
+// maxSize users. Non-positive values use DefaultCacheTTL and
+// DefaultCacheSize.
+func NewCachedProvider(provider Provider, ttl time.Duration, maxSize int) *CachedProvider {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultCacheSize
+	}
+	return &CachedProvider{
+		provider: provider,
+		ttl:      ttl,
+		maxSize:  maxSize,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *CachedProvider) Segments(ctx context.Context, userID string) ([]string, error) {
+	if segments, ok := c.get(userID); ok {
+		return segments, nil
+	}
+	segments, err := c.provider.Segments(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	c.set(userID, segments)
+	return segments, nil
+}
+
+// get returns the cached segments of userID, dropping them once expired
+func (c *CachedProvider) get(userID string) ([]string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.items[userID]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(elem)
+		delete(c.items, userID)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.segments, true
+}
+
+// set caches the segments of userID, evicting the least recently used user
+// when the cache is full
+func (c *CachedProvider) set(userID string, segments []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.items[userID]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.segments, entry.expiresAt = segments, expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+	c.items[userID] = c.ll.PushFront(&cacheEntry{userID: userID, segments: segments, expiresAt: expiresAt})
+	if c.ll.Len() > c.maxSize {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).userID)
+	}
+}
@@ -0,0 +1,142 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// comparator checks a version against a single bound
+type comparator struct {
+	operator string
+	version  Version
+}
+
+func (c comparator) check(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.operator {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	default: // "<="
+		return cmp <= 0
+	}
+}
+
+// Constraint is a set of version ranges. A version satisfies it when it
+// satisfies every comparator of any one range.
+type Constraint struct {
+	text   string
+	ranges [][]comparator
+}
+
+// operators are the comparison operators, longest first so that a prefix
+// like > doesn't shadow >=
+var operators = []string{">=", "<=", "!=", "==", ">", "<", "=", "~", "^"}
+
+// ParseConstraint parses a constraint made of comparators separated by
+// spaces or commas, all of which must hold, with || separating alternative
+// ranges, e.g. ">=2.3.0 <3.0.0 || >=4.0.0". Comparators use =, !=, >, >=, <
+// and <=, or a bare version for equality. ~1.2.3 allows patch releases
+// (>=1.2.3 <1.3.0) and ^1.2.3 allows releases that don't change the left-most
+// non-zero number (>=1.2.3 <2.0.0).
+func ParseConstraint(s string) (*Constraint, error) {
+	constraint := &Constraint{text: strings.TrimSpace(s)}
+	if constraint.text == "" {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+
+	for _, alternative := range strings.Split(constraint.text, "||") {
+		fields := strings.FieldsFunc(alternative, func(r rune) bool { return r == ' ' || r == ',' })
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty range in version constraint %q", s)
+		}
+
+		var comparators []comparator
+		for i := 0; i < len(fields); i++ {
+			operator, text := splitOperator(fields[i])
+			if text == "" && i+1 < len(fields) {
+				// The operator was written apart from its version, as in ">= 2.3"
+				i++
+				text = fields[i]
+			}
+			version, err := Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("version constraint %q: %w", s, err)
+			}
+			comparators = append(comparators, expand(operator, version)...)
+		}
+		constraint.ranges = append(constraint.ranges, comparators)
+	}
+	return constraint, nil
+}
+
+// splitOperator splits the leading operator off a comparator, defaulting to =
+func splitOperator(field string) (string, string) {
+	for _, operator := range operators {
+		if text, ok := strings.CutPrefix(field, operator); ok {
+			if operator == "==" {
+				operator = "="
+			}
+			return operator, text
+		}
+	}
+	return "=", field
+}
+
+// expand turns tilde and caret comparators into their lower and upper
+// bounds
+func expand(operator string, v Version) []comparator {
+	lower := comparator{operator: ">=", version: v}
+	var upper Version
+	switch operator {
+	case "~":
+		if v.parts == 1 {
+			upper = Version{Major: v.Major + 1}
+		} else {
+			upper = Version{Major: v.Major, Minor: v.Minor + 1}
+		}
+	case "^":
+		switch {
+		case v.Major > 0 || v.parts == 1:
+			upper = Version{Major: v.Major + 1}
+		case v.Minor > 0 || v.parts == 2:
+			upper = Version{Minor: v.Minor + 1}
+		default:
+			upper = Version{Patch: v.Patch + 1}
+		}
+	default:
+		return []comparator{{operator: operator, version: v}}
+	}
+	// The upper bound excludes the prereleases of the next release too
+	upper.Prerelease = []string{"0"}
+	return []comparator{lower, {operator: "<", version: upper}}
+}
+
+// Check reports whether v satisfies the constraint
+func (c *Constraint) Check(v Version) bool {
+	for _, comparators := range c.ranges {
+		satisfied := true
+		for _, comparator := range comparators {
+			if !comparator.check(v) {
+				satisfied = false
+				break
+			}
+		}
+		if satisfied {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the constraint as written
+func (c *Constraint) String() string {
+	return c.text
+}
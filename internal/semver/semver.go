@@ -0,0 +1,132 @@
+// Package semver parses and compares semantic versions, such as the app
+// versions delivery requests carry, and evaluates version constraints like
+// ">=2.3.0 <3.0.0" against them.
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version. Build metadata is dropped when parsing
+// since it doesn't take part in ordering.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+
+	// parts is how many of major, minor and patch were written, which
+	// decides the range of tilde and caret constraints
+	parts int
+}
+
+// Parse parses a version such as 2.3.1, v2.3 or 3.0.0-beta.2+build.7.
+// Missing minor and patch numbers default to zero, as apps often report
+// versions like 4.2.
+func Parse(s string) (Version, error) {
+	var v Version
+	text := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	text, _, _ = strings.Cut(text, "+")
+	text, prerelease, hasPrerelease := strings.Cut(text, "-")
+
+	numbers := strings.Split(text, ".")
+	if text == "" || len(numbers) > 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	for i, number := range numbers {
+		if number == "" || (len(number) > 1 && number[0] == '0') {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		n, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		switch i {
+		case 0:
+			v.Major = n
+		case 1:
+			v.Minor = n
+		case 2:
+			v.Patch = n
+		}
+	}
+	v.parts = len(numbers)
+
+	if hasPrerelease {
+		for _, identifier := range strings.Split(prerelease, ".") {
+			if identifier == "" {
+				return Version{}, fmt.Errorf("invalid version %q", s)
+			}
+		}
+		v.Prerelease = strings.Split(prerelease, ".")
+	}
+	return v, nil
+}
+
+// String formats the version with all three numbers
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as v is lower than, equal to or higher than w,
+// following semantic versioning precedence: a prerelease ranks below the
+// release it precedes.
+func (v Version) Compare(w Version) int {
+	for _, pair := range [][2]uint64{{v.Major, w.Major}, {v.Minor, w.Minor}, {v.Patch, w.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case len(v.Prerelease) == 0 && len(w.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(w.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(w.Prerelease); i++ {
+		if c := compareIdentifiers(v.Prerelease[i], w.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(v.Prerelease) < len(w.Prerelease):
+		return -1
+	case len(v.Prerelease) > len(w.Prerelease):
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifiers compares prerelease identifiers: numeric ones
+// numerically and below alphanumeric ones, which compare as text
+func compareIdentifiers(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if an == bn {
+			return 0
+		}
+		if an < bn {
+			return -1
+		}
+		return 1
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
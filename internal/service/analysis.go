@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// ErrRuleConflict is returned when a targeting rule contradicts itself so
+// that it can never match
+var ErrRuleConflict = errors.New("targeting rule conflicts")
+
+// RuleConflictError lists the conflicts a targeting rule was rejected for
+type RuleConflictError struct {
+	Conflicts []models.RuleConflict
+}
+
+func (e *RuleConflictError) Error() string {
+	messages := make([]string, len(e.Conflicts))
+	for i, conflict := range e.Conflicts {
+		messages[i] = conflict.Message
+	}
+	return ErrRuleConflict.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *RuleConflictError) Unwrap() error {
+	return ErrRuleConflict
+}
+
+// RuleConflicts returns the conflicts reported by a *RuleConflictError in
+// err's chain, or nil if there is none
+func RuleConflicts(err error) []models.RuleConflict {
+	var conflictErr *RuleConflictError
+	if errors.As(err, &conflictErr) {
+		return conflictErr.Conflicts
+	}
+	return nil
+}
+
+// AnalyzeTargetingRule reports the conflicts of a targeting rule, on its own
+// and against the other rules of its campaign, without storing it. A rule
+// with an ID is analyzed as a replacement of that rule.
+func (s *TargetingService) AnalyzeTargetingRule(ctx context.Context, rule *models.TargetingRule) (*models.RuleAnalysis, error) {
+	rule.CampaignID = strings.TrimSpace(rule.CampaignID)
+	if rule.CampaignID == "" {
+		return nil, fmt.Errorf("%w: campaign_id is required", ErrInvalidRule)
+	}
+	normalizeRuleValues(rule)
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	others, err := s.ListTargetingRules(ctx, rule.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+	conflicts := analyzeRule(rule, others)
+	if conflicts == nil {
+		conflicts = []models.RuleConflict{}
+	}
+	return &models.RuleAnalysis{Conflicts: conflicts}, nil
+}
+
+// checkRuleConflicts rejects a rule that contradicts itself. Conflicts with
+// other rules only make rules redundant, so they don't stop a write.
+func checkRuleConflicts(rule *models.TargetingRule) error {
+	var errs []models.RuleConflict
+	for _, conflict := range analyzeRule(rule, nil) {
+		if conflict.Severity == models.ConflictError {
+			errs = append(errs, conflict)
+		}
+	}
+	if len(errs) > 0 {
+		return &RuleConflictError{Conflicts: errs}
+	}
+	return nil
+}
+
+// valueSets holds the include and exclude values of one dimension of a rule,
+// folded the way exact matching compares them
+type valueSets struct {
+	exact   bool
+	include map[string]bool
+	exclude map[string]bool
+}
+
+// ruleConditions returns the value sets of every dimension a rule constrains
+func ruleConditions(rule *models.TargetingRule) map[string]valueSets {
+	conditions := make(map[string]valueSets)
+	for name, lists := range ruleDimensionValues(rule) {
+		if len(lists[0]) == 0 && len(lists[1]) == 0 {
+			continue
+		}
+		operator := rule.Operators[name]
+		sets := valueSets{
+			exact:   operator == "" || operator == models.OperatorExact,
+			include: make(map[string]bool, len(lists[0])),
+			exclude: make(map[string]bool, len(lists[1])),
+		}
+		for _, value := range lists[0] {
+			sets.include[foldDimension(name, value)] = true
+		}
+		for _, value := range lists[1] {
+			sets.exclude[foldDimension(name, value)] = true
+		}
+		conditions[name] = sets
+	}
+	return conditions
+}
+
+// analyzeRule reports the conflicts of rule on its own and against others,
+// the other rules of its campaign. Only exactly matched values are compared,
+// since the values a pattern matches can't be enumerated.
+func analyzeRule(rule *models.TargetingRule, others []*models.TargetingRule) []models.RuleConflict {
+	conditions := ruleConditions(rule)
+	var conflicts []models.RuleConflict
+
+	names := make([]string, 0, len(conditions))
+	for name := range conditions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		sets := conditions[name]
+		if !sets.exact {
+			continue
+		}
+
+		var both, excludedOnly []string
+		for value := range sets.exclude {
+			if sets.include[value] {
+				both = append(both, value)
+			} else if len(sets.include) > 0 {
+				excludedOnly = append(excludedOnly, value)
+			}
+		}
+		slices.Sort(both)
+		slices.Sort(excludedOnly)
+
+		switch {
+		case len(sets.include) > 0 && len(both) == len(sets.include):
+			conflicts = append(conflicts, models.RuleConflict{
+				Severity:  models.ConflictError,
+				Kind:      models.ConflictEmptyInclude,
+				Dimension: name,
+				Values:    both,
+				Message:   fmt.Sprintf("every included %s is also excluded, so the rule never matches", name),
+			})
+		case len(both) > 0:
+			conflicts = append(conflicts, models.RuleConflict{
+				Severity:  models.ConflictWarning,
+				Kind:      models.ConflictIncludeExcluded,
+				Dimension: name,
+				Values:    both,
+				Message:   fmt.Sprintf("%s %s is both included and excluded, so it is excluded", name, strings.Join(both, ", ")),
+			})
+		}
+		if len(excludedOnly) > 0 {
+			conflicts = append(conflicts, models.RuleConflict{
+				Severity:  models.ConflictWarning,
+				Kind:      models.ConflictRedundantExclude,
+				Dimension: name,
+				Values:    excludedOnly,
+				Message:   fmt.Sprintf("%s %s is excluded but not included, so excluding it has no effect", name, strings.Join(excludedOnly, ", ")),
+			})
+		}
+	}
+
+	// Rules of a campaign are OR-ed, so one accepting every request another
+	// accepts makes that one redundant. Shadow rules are compared among
+	// themselves since they don't affect delivery.
+	for _, other := range others {
+		if (rule.ID != 0 && other.ID == rule.ID) || other.Shadow != rule.Shadow {
+			continue
+		}
+		otherConditions := ruleConditions(other)
+		covered := ruleCovers(other, otherConditions, rule, conditions)
+		covers := ruleCovers(rule, conditions, other, otherConditions)
+
+		conflict := models.RuleConflict{Severity: models.ConflictWarning, RuleID: other.ID}
+		switch {
+		case covered && covers:
+			conflict.Kind = models.ConflictDuplicate
+			conflict.Message = fmt.Sprintf("rule %d has the same conditions", other.ID)
+		case covered:
+			conflict.Kind = models.ConflictCovered
+			conflict.Message = fmt.Sprintf("rule %d already matches every request this rule matches, so this rule has no effect", other.ID)
+		case covers:
+			conflict.Kind = models.ConflictCovers
+			conflict.Message = fmt.Sprintf("this rule matches every request rule %d matches, so rule %d has no effect", other.ID, other.ID)
+		default:
+			continue
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts
+}
+
+// windowCovers reports whether the effective window of rule a contains that
+// of rule b
+func windowCovers(a, b *models.TargetingRule) bool {
+	if a.EffectiveFrom != nil && (b.EffectiveFrom == nil || b.EffectiveFrom.Before(*a.EffectiveFrom)) {
+		return false
+	}
+	return a.EffectiveUntil == nil || (b.EffectiveUntil != nil && !b.EffectiveUntil.After(*a.EffectiveUntil))
+}
+
+// ruleCovers reports whether rule a matches every request rule b matches.
+// Rules using non-exact operators are never reported as covering, nor are
+// rules with an expression other than b's.
+func ruleCovers(a *models.TargetingRule, aConditions map[string]valueSets, b *models.TargetingRule, bConditions map[string]valueSets) bool {
+	if a.AppVersion != "" && a.AppVersion != b.AppVersion {
+		return false
+	}
+	if a.Schedule != nil && !reflect.DeepEqual(a.Schedule, b.Schedule) {
+		return false
+	}
+	if !windowCovers(a, b) {
+		return false
+	}
+	if a.Expression != nil && !reflect.DeepEqual(a.Expression, b.Expression) {
+		return false
+	}
+	// Users belong to several segments, so only identical segment lists are
+	// known to cover each other
+	if (len(a.IncludeSegment) > 0 || len(a.ExcludeSegment) > 0) &&
+		(!slices.Equal(a.IncludeSegment, b.IncludeSegment) || !slices.Equal(a.ExcludeSegment, b.ExcludeSegment)) {
+		return false
+	}
+	for _, sets := range bConditions {
+		if !sets.exact {
+			return false
+		}
+	}
+
+	for name, aSets := range aConditions {
+		if !aSets.exact {
+			return false
+		}
+		bSets := bConditions[name]
+
+		// Every value b lets through must be included by a
+		if len(aSets.include) > 0 {
+			if len(bSets.include) == 0 {
+				return false
+			}
+			for value := range bSets.include {
+				if !bSets.exclude[value] && !aSets.include[value] {
+					return false
+				}
+			}
+		}
+		// and every value a excludes must be kept out by b
+		for value := range aSets.exclude {
+			if !bSets.exclude[value] && (len(bSets.include) == 0 || bSets.include[value]) {
+				return false
+			}
+		}
+	}
+	return true
+}
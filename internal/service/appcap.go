@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// appCapKeys returns the counter keys for a campaign's impressions in an app
+// on a UTC day and for the number of distinct apps it served in that day
+func appCapKeys(campaignID, app, day string) (impressions, apps string) {
+	return fmt.Sprintf("appcap:%s:%s:%s", campaignID, app, day), fmt.Sprintf("appcap:%s:apps:%s", campaignID, day)
+}
+
+// hasAppCaps reports whether a campaign caps its delivery per app
+func hasAppCaps(match *models.DeliveryResponse) bool {
+	return match.AppDailyCap() > 0 || match.MaxDailyApps() > 0
+}
+
+// withinAppCaps reports whether the campaign may serve another impression in
+// app today: the app must be under the campaign's per-app daily cap, and an
+// app not served in yet today must not exceed its cap on distinct apps.
+// Like budgets, caps are checked before the impression is recorded, and
+// counter store failures are logged and the campaign served.
+func (s *TargetingService) withinAppCaps(ctx context.Context, match *models.DeliveryResponse, app, day string) bool {
+	if s.counters == nil || app == "" || !hasAppCaps(match) {
+		return true
+	}
+	impressionsKey, appsKey := appCapKeys(match.CID, app, day)
+
+	served, err := s.counters.Get(ctx, impressionsKey)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read app cap counter", "campaign_id", match.CID, "error", err)
+		return true
+	}
+	if limit := match.AppDailyCap(); limit > 0 && served >= int64(limit) {
+		return false
+	}
+	if limit := match.MaxDailyApps(); limit > 0 && served == 0 {
+		apps, err := s.counters.Get(ctx, appsKey)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to read app cap counter", "campaign_id", match.CID, "error", err)
+			return true
+		}
+		if apps >= int64(limit) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordAppImpression counts a served impression against the campaign's
+// caps for app, counting the app as distinct on its first impression of
+// the day
+func (s *TargetingService) recordAppImpression(ctx context.Context, match *models.DeliveryResponse, app, day string) {
+	if s.counters == nil || app == "" || !hasAppCaps(match) {
+		return
+	}
+	impressionsKey, appsKey := appCapKeys(match.CID, app, day)
+
+	served, err := s.counters.Increment(ctx, impressionsKey, budgetDayWindow)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to record app impression", "campaign_id", match.CID, "error", err)
+		return
+	}
+	if served == 1 && match.MaxDailyApps() > 0 {
+		if _, err := s.counters.Increment(ctx, appsKey, budgetDayWindow); err != nil {
+			slog.ErrorContext(ctx, "failed to record app impression", "campaign_id", match.CID, "error", err)
+		}
+	}
+}
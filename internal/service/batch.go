@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// MaxBatchDeliveryRequests is the most requests a batch delivery may carry
+const MaxBatchDeliveryRequests = 50
+
+// DefaultBatchDeliveryTimeout bounds a batch delivery when no batch timeout
+// is configured
+const DefaultBatchDeliveryTimeout = time.Second
+
+// GetMatchingCampaignsBatch serves every request of a batch as
+// GetMatchingCampaigns would, concurrently, and returns their results in
+// request order. The requests share one deadline, the configured batch
+// timeout or ctx's own if sooner; a request still running when it passes
+// reports the deadline as its error.
+func (s *TargetingService) GetMatchingCampaignsBatch(ctx context.Context, batch *models.BatchDeliveryRequest) ([]*models.BatchDeliveryResult, error) {
+	if len(batch.Requests) == 0 || len(batch.Requests) > MaxBatchDeliveryRequests {
+		return nil, validation.Invalid("requests", fmt.Sprintf("must hold between 1 and %d requests", MaxBatchDeliveryRequests))
+	}
+
+	timeout := s.config.Delivery.BatchTimeout
+	if timeout <= 0 {
+		timeout = DefaultBatchDeliveryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		index  int
+		result *models.BatchDeliveryResult
+	}
+	// Buffered so that requests finishing after the deadline don't block
+	done := make(chan outcome, len(batch.Requests))
+	for i, req := range batch.Requests {
+		if req == nil {
+			req = &models.DeliveryRequest{}
+		}
+		if req.UserID == "" {
+			req.UserID = batch.UserID
+		}
+		go func() {
+			matches, err := s.GetMatchingCampaigns(ctx, req)
+			done <- outcome{index: i, result: batchResult(matches, err)}
+		}()
+	}
+
+	results := make([]*models.BatchDeliveryResult, len(batch.Requests))
+	for received := 0; received < len(results); received++ {
+		select {
+		case o := <-done:
+			results[o.index] = o.result
+		case <-ctx.Done():
+			// Keep the results that came in by the deadline
+			for len(done) > 0 {
+				o := <-done
+				results[o.index] = o.result
+			}
+			for i := range results {
+				if results[i] == nil {
+					results[i] = batchResult(nil, ctx.Err())
+				}
+			}
+			return results, nil
+		}
+	}
+	return results, nil
+}
+
+// batchResult reports the outcome of one request of a batch
+func batchResult(matches []*models.DeliveryResponse, err error) *models.BatchDeliveryResult {
+	if err != nil {
+		return &models.BatchDeliveryResult{
+			Campaigns: []*models.DeliveryResponse{},
+			Error:     err.Error(),
+			Fields:    validation.Fields(err),
+		}
+	}
+	if matches == nil {
+		matches = []*models.DeliveryResponse{}
+	}
+	return &models.BatchDeliveryResult{Campaigns: matches}
+}
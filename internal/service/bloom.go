@@ -0,0 +1,60 @@
+package service
+
+import (
+	"hash/maphash"
+	"math"
+)
+
+// Bloom filters pre-check exact value lists of at least bloomMinValues
+// values, sized for a false positive rate of about 1%. Shorter lists are
+// scanned faster than they are hashed.
+const (
+	bloomMinValues    = 64
+	bloomBitsPerValue = 10
+	bloomHashes       = 7
+)
+
+// bloomSeed seeds the hashes of every filter; filters are rebuilt with the
+// cache, so they never outlive the process
+var bloomSeed = maphash.MakeSeed()
+
+// bloomFilter is a probabilistic set of strings: contains may report strings
+// that were never added, but never misses one that was. It is read-only once
+// built, so it is safe for concurrent use.
+type bloomFilter struct {
+	bits []uint64
+	size uint64
+}
+
+// newBloomFilter builds a filter holding values
+func newBloomFilter(values []string) *bloomFilter {
+	size := uint64(max(len(values)*bloomBitsPerValue, 64))
+	f := &bloomFilter{bits: make([]uint64, (size+63)/64), size: size}
+	for _, value := range values {
+		h1, h2 := bloomHash(value)
+		for i := uint64(0); i < bloomHashes; i++ {
+			bit := (h1 + i*h2) % f.size
+			f.bits[bit/64] |= 1 << (bit % 64)
+		}
+	}
+	return f
+}
+
+// contains reports whether value may have been added
+func (f *bloomFilter) contains(value string) bool {
+	h1, h2 := bloomHash(value)
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) % f.size
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHash derives the two hashes the bit positions of a value are
+// combined from
+func bloomHash(value string) (uint64, uint64) {
+	h := maphash.String(bloomSeed, value)
+	return h & math.MaxUint32, h>>32 | 1
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+const (
+	// budgetDayWindow keeps daily spend counters a little past their UTC day
+	budgetDayWindow = 25 * time.Hour
+	// budgetTotalWindow keeps lifetime spend counters for the life of any campaign
+	budgetTotalWindow = 10 * 365 * 24 * time.Hour
+	// pacingAllowance is the share of a daily budget a campaign may run ahead
+	// of an even spend before pacing starts skipping it
+	pacingAllowance = 0.01
+)
+
+// budgetKeys returns the counter keys for a campaign's daily and lifetime spend
+func budgetKeys(campaignID, day string) (daily, total string) {
+	return fmt.Sprintf("budget:%s:%s", campaignID, day), fmt.Sprintf("budget:%s:total", campaignID)
+}
+
+// withinBudget reports whether the campaign may serve another impression at
+// now. Exhausted budgets never serve. A campaign spending its daily budget
+// faster than an even pace is skipped with a probability that grows with how
+// far ahead it is, spreading delivery across the day. Budgets are checked
+// before the impression is recorded, so concurrent requests can overshoot a
+// budget slightly. Counter store failures are logged and the campaign served.
+func (s *TargetingService) withinBudget(ctx context.Context, match *models.DeliveryResponse, day string, now time.Time) bool {
+	daily, total := match.DailyBudget(), match.TotalBudget()
+	if s.counters == nil || (daily <= 0 && total <= 0) {
+		return true
+	}
+	dailyKey, totalKey := budgetKeys(match.CID, day)
+
+	if total > 0 {
+		spent, err := s.counters.Get(ctx, totalKey)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to read budget counter", "campaign_id", match.CID, "error", err)
+			return true
+		}
+		if spent >= total {
+			return false
+		}
+	}
+
+	if daily > 0 {
+		spent, err := s.counters.Get(ctx, dailyKey)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to read budget counter", "campaign_id", match.CID, "error", err)
+			return true
+		}
+		if spent >= daily {
+			return false
+		}
+
+		// Compare spend with an even pace through the UTC day, plus a small allowance
+		midnight := now.UTC().Truncate(24 * time.Hour)
+		elapsed := float64(now.Sub(midnight)) / float64(24*time.Hour)
+		target := float64(daily)*elapsed + max(1, float64(daily)*pacingAllowance)
+		if float64(spent) > target && rand.Float64() > target/float64(spent) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordSpend counts a served impression against the campaign's budgets
+func (s *TargetingService) recordSpend(ctx context.Context, match *models.DeliveryResponse, day string) {
+	if s.counters == nil {
+		return
+	}
+	dailyKey, totalKey := budgetKeys(match.CID, day)
+	if match.DailyBudget() > 0 {
+		if _, err := s.counters.Increment(ctx, dailyKey, budgetDayWindow); err != nil {
+			slog.ErrorContext(ctx, "failed to record budget spend", "campaign_id", match.CID, "error", err)
+		}
+	}
+	if match.TotalBudget() > 0 {
+		if _, err := s.counters.Increment(ctx, totalKey, budgetTotalWindow); err != nil {
+			slog.ErrorContext(ctx, "failed to record budget spend", "campaign_id", match.CID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// Defaults of the cache file settings
+const (
+	DefaultCacheFileInterval = time.Minute
+	DefaultCacheFileMaxAge   = 24 * time.Hour
+)
+
+// cacheFileFormat is the layout version of cache files; files of another
+// version are ignored rather than misread
+const cacheFileFormat = 1
+
+// cacheFile is where the targeting cache is persisted between restarts
+type cacheFile struct {
+	path     string
+	interval time.Duration
+	// mutex serializes saves and guards what was saved last
+	mutex         sync.Mutex
+	savedCache    *cacheSnapshot
+	savedSwitches *killSwitchState
+}
+
+// cacheFileContents is the encoded form of a cache file. SavedAt is when the
+// cache it holds was last loaded or updated from the repository.
+type cacheFileContents struct {
+	Format       int                     `json:"format"`
+	SavedAt      time.Time               `json:"saved_at"`
+	Campaigns    []*models.Campaign      `json:"campaigns"`
+	Rules        []*models.TargetingRule `json:"rules"`
+	KillSwitches []*models.KillSwitch    `json:"kill_switches"`
+}
+
+// SetCacheFile persists the targeting cache, along with the engaged kill
+// switches, to the file at path every interval whenever they changed. Unless
+// the cache has been loaded already, it is restored right away from a file
+// up to maxAge old, so the service can serve its last known campaigns while
+// the repository is unreachable. The restored cache is replaced as soon as
+// the repository can be loaded. Non-positive values use
+// DefaultCacheFileInterval and DefaultCacheFileMaxAge. It must be called
+// before requests are served.
+func (s *TargetingService) SetCacheFile(path string, interval, maxAge time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCacheFileInterval
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultCacheFileMaxAge
+	}
+	file := &cacheFile{path: path, interval: interval}
+	s.cacheFile.Store(file)
+
+	if !s.Warm() {
+		s.restoreCacheFile(file, maxAge)
+	}
+	go s.startCacheFileWorker(file)
+}
+
+// restoreCacheFile loads the cache and the kill switches from the file, if
+// it holds a recent enough cache
+func (s *TargetingService) restoreCacheFile(file *cacheFile, maxAge time.Duration) {
+	data, err := os.ReadFile(file.path)
+	if os.IsNotExist(err) {
+		return
+	}
+	var contents cacheFileContents
+	if err == nil {
+		err = json.Unmarshal(data, &contents)
+	}
+	switch {
+	case err != nil:
+		slog.Warn("failed to read the cache file, warming up from the repository", "path", file.path, "error", err)
+		return
+	case contents.Format != cacheFileFormat:
+		slog.Warn("unknown cache file format, warming up from the repository", "path", file.path, "format", contents.Format)
+		return
+	case time.Since(contents.SavedAt) > maxAge:
+		slog.Info("cache file too old, warming up from the repository", "path", file.path, "saved_at", contents.SavedAt)
+		return
+	}
+
+	next := buildCacheSnapshot(contents.Campaigns, contents.Rules)
+	next.lastUpdate = contents.SavedAt
+	next.restored = true
+	if !s.cache.replaceUnloaded(next) {
+		// A repository load got there first
+		return
+	}
+	s.killSwitchMutex.Lock()
+	if s.killSwitches.Load() == nil {
+		stored := make(map[string]*models.KillSwitch, len(contents.KillSwitches))
+		for _, sw := range contents.KillSwitches {
+			stored[sw.Scope()] = sw
+		}
+		s.killSwitches.Store(&killSwitchState{stored: stored, unsaved: map[string]*models.KillSwitch{}})
+	}
+	s.killSwitchMutex.Unlock()
+
+	// The restored cache is as stale as a cache whose refreshes fail
+	s.mutex.Lock()
+	s.lastRefresh = next.lastUpdate
+	if s.staleSince.IsZero() {
+		s.staleSince = time.Now()
+	}
+	s.metrics.SetCacheStaleness(s.stalenessLocked())
+	s.mutex.Unlock()
+	s.markWarm()
+	slog.Info("targeting cache restored from file", "path", file.path, "campaigns", len(next.campaigns), "saved_at", contents.SavedAt)
+}
+
+// startCacheFileWorker saves the cache to the file every interval
+func (s *TargetingService) startCacheFileWorker(file *cacheFile) {
+	ticker := time.NewTicker(file.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.saveCacheFile(file); err != nil {
+			slog.Error("failed to save the cache file", "path", file.path, "error", err)
+		}
+	}
+}
+
+// SaveCacheFile saves the cache to the file right away if it changed since
+// it was last saved, e.g. on shutdown. It does nothing unless the cache is
+// persisted.
+func (s *TargetingService) SaveCacheFile() error {
+	file := s.cacheFile.Load()
+	if file == nil {
+		return nil
+	}
+	return s.saveCacheFile(file)
+}
+
+// saveCacheFile writes the cache and the kill switches to the file unless
+// neither changed since they were last saved. Caches that weren't loaded
+// from the repository are never saved. The file is replaced atomically, so a
+// crash mid-write leaves the previous one in place.
+func (s *TargetingService) saveCacheFile(file *cacheFile) error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	snap := s.cache.snapshot.Load()
+	switches := s.killSwitches.Load()
+	if !snap.loaded() || snap.restored || (snap == file.savedCache && switches == file.savedSwitches) {
+		return nil
+	}
+
+	contents := cacheFileContents{
+		Format:       cacheFileFormat,
+		SavedAt:      snap.lastUpdate.UTC(),
+		Campaigns:    make([]*models.Campaign, 0, len(snap.campaigns)),
+		Rules:        []*models.TargetingRule{},
+		KillSwitches: []*models.KillSwitch{},
+	}
+	for _, id := range slices.Sorted(maps.Keys(snap.campaigns)) {
+		contents.Campaigns = append(contents.Campaigns, snap.campaigns[id])
+		contents.Rules = append(contents.Rules, snap.cachedRules(id)...)
+	}
+	if switches != nil {
+		engaged := maps.Clone(switches.stored)
+		maps.Copy(engaged, switches.unsaved)
+		for _, scope := range slices.Sorted(maps.Keys(engaged)) {
+			contents.KillSwitches = append(contents.KillSwitches, engaged[scope])
+		}
+	}
+	data, err := json.Marshal(contents)
+	if err != nil {
+		return fmt.Errorf("failed to encode the cache: %w", err)
+	}
+	if err := writeFileAtomic(file.path, data); err != nil {
+		return err
+	}
+	file.savedCache, file.savedSwitches = snap, switches
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path once it is on disk
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// ErrInvalidCampaign is returned when a campaign payload fails validation
+var ErrInvalidCampaign = errors.New("invalid campaign")
+
+// ErrInvalidFilter is returned when campaign listing parameters are out of range
+var ErrInvalidFilter = errors.New("invalid filter")
+
+const (
+	// DefaultListLimit is the page size used when a listing doesn't set one
+	DefaultListLimit = 50
+	// MaxListLimit bounds the page size of a campaign listing
+	MaxListLimit = 500
+)
+
+// ListCampaigns returns a page of the tenant's campaigns matching filter,
+// newest first
+func (s *TargetingService) ListCampaigns(ctx context.Context, filter repository.CampaignFilter) (*models.CampaignList, error) {
+	if err := normalizeCampaignFilter(ctx, &filter); err != nil {
+		return nil, err
+	}
+	switch {
+	case filter.Limit < 0 || filter.Limit > MaxListLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, MaxListLimit)
+	case filter.Offset < 0:
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidFilter)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultListLimit
+	}
+
+	campaigns, total, err := s.repo.Campaign().ListCampaigns(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	return &models.CampaignList{
+		Campaigns: campaigns,
+		Total:     total,
+		Limit:     filter.Limit,
+		Offset:    filter.Offset,
+	}, nil
+}
+
+// MaxSearchQueryLength bounds the length of a campaign search query
+const MaxSearchQueryLength = 256
+
+// SearchCampaigns returns a page of the tenant's campaigns whose name or CTA
+// matches the words of search.Query, most relevant first
+func (s *TargetingService) SearchCampaigns(ctx context.Context, search repository.CampaignSearch) (*models.CampaignSearchResult, error) {
+	search.TenantID = tenant.FromContext(ctx)
+	search.Query = strings.TrimSpace(search.Query)
+	switch {
+	case search.Query == "":
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidFilter)
+	case len(search.Query) > MaxSearchQueryLength:
+		return nil, fmt.Errorf("%w: q must be at most %d characters", ErrInvalidFilter, MaxSearchQueryLength)
+	case search.Limit < 0 || search.Limit > MaxListLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, MaxListLimit)
+	case search.Offset < 0:
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidFilter)
+	}
+	if search.Limit == 0 {
+		search.Limit = DefaultListLimit
+	}
+
+	campaigns, total, err := s.repo.Campaign().SearchCampaigns(ctx, search)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search campaigns: %w", err)
+	}
+	return &models.CampaignSearchResult{
+		Query:     search.Query,
+		Campaigns: campaigns,
+		Total:     total,
+		Limit:     search.Limit,
+		Offset:    search.Offset,
+	}, nil
+}
+
+// campaignStatuses are the statuses campaigns can be listed by
+var campaignStatuses = []string{
+	models.StatusDraft, models.StatusScheduled, models.StatusActive, models.StatusPaused,
+	models.StatusCompleted, models.StatusArchived, models.StatusInactive,
+}
+
+// normalizeCampaignFilter scopes filter to the request's tenant and checks
+// its status
+func normalizeCampaignFilter(ctx context.Context, filter *repository.CampaignFilter) error {
+	filter.TenantID = tenant.FromContext(ctx)
+	filter.Status = strings.ToUpper(strings.TrimSpace(filter.Status))
+	filter.Search = strings.TrimSpace(filter.Search)
+	if filter.Status != "" && !slices.Contains(campaignStatuses, filter.Status) {
+		return fmt.Errorf("%w: status must be one of %s", ErrInvalidFilter, strings.Join(campaignStatuses, ", "))
+	}
+	return nil
+}
+
+// CreateCampaign validates and stores a new campaign
+func (s *TargetingService) CreateCampaign(ctx context.Context, req *models.CampaignRequest) (*models.Campaign, error) {
+	if err := s.validateCampaignRequest(req, true); err != nil {
+		return nil, err
+	}
+
+	campaign, err := newCampaign(req)
+	if err != nil {
+		return nil, err
+	}
+	campaign.TenantID = tenant.FromContext(ctx)
+	if err := s.repo.Campaign().CreateCampaign(ctx, campaign); err != nil {
+		return nil, fmt.Errorf("failed to create campaign: %w", err)
+	}
+	s.refreshAfterWrite()
+	s.notifyWrite(campaign, true)
+	return campaign, nil
+}
+
+// UpdateCampaign applies the non-empty fields of req to an existing campaign.
+// Status changes must follow the campaign lifecycle, and setting the status
+// of an archived campaign restores it.
+func (s *TargetingService) UpdateCampaign(ctx context.Context, id string, req *models.CampaignRequest) (*models.Campaign, error) {
+	if err := s.validateCampaignRequest(req, false); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.getCampaign(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	// Work on a copy so a failed update never leaks into the stored campaign
+	campaign := *existing
+	applyCampaignRequest(&campaign, req)
+	now := time.Now()
+	if req.Status != "" {
+		if err := transitionStatus(&campaign, req.Status, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateFlight(&campaign); err != nil {
+		return nil, err
+	}
+	advanceStatus(&campaign, now)
+
+	if err := s.repo.Campaign().UpdateCampaign(ctx, &campaign); err != nil {
+		return nil, fmt.Errorf("failed to update campaign: %w", err)
+	}
+	s.refreshAfterWrite()
+	s.notifyWrite(&campaign, false)
+	return &campaign, nil
+}
+
+// DeleteCampaign archives a campaign, which stops its delivery but keeps it
+// and its targeting rules so it can be restored. With hard set it removes
+// the campaign together with its targeting rules instead.
+func (s *TargetingService) DeleteCampaign(ctx context.Context, id string, hard bool) error {
+	existing, err := s.getCampaign(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	if !hard {
+		if existing.IsArchived() {
+			return nil
+		}
+		campaign := *existing
+		now := time.Now().UTC()
+		campaign.Status = models.StatusArchived
+		campaign.DeletedAt = &now
+		if err := s.repo.Campaign().UpdateCampaign(ctx, &campaign); err != nil {
+			return fmt.Errorf("failed to archive campaign: %w", err)
+		}
+		s.refreshAfterWrite()
+		s.notifyWrite(&campaign, false)
+		return nil
+	}
+
+	err = repository.WithTransaction(ctx, s.repo, func(ctx context.Context) error {
+		if err := s.repo.TargetingRule().DeleteTargetingRulesByCampaignID(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete targeting rules: %w", err)
+		}
+		if err := s.repo.Campaign().DeleteCampaign(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete campaign: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.refreshAfterWrite()
+	s.notifyDelete(existing)
+	return nil
+}
+
+// MaxBulkCampaigns bounds the number of campaigns accepted in one bulk import
+const MaxBulkCampaigns = 1000
+
+// BulkCreateCampaigns validates every item and stores the valid ones, with
+// their targeting rules, in a single repository transaction. Items that fail
+// validation are reported individually; if the transaction fails, every valid
+// item is reported with the transaction error.
+func (s *TargetingService) BulkCreateCampaigns(ctx context.Context, items []*models.BulkCampaignRequest) ([]*models.BulkCampaignResult, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: no campaigns supplied", ErrInvalidCampaign)
+	}
+	if len(items) > MaxBulkCampaigns {
+		return nil, fmt.Errorf("%w: at most %d campaigns can be imported at once", ErrInvalidCampaign, MaxBulkCampaigns)
+	}
+
+	results := make([]*models.BulkCampaignResult, len(items))
+	seen := make(map[string]bool, len(items))
+	var campaigns []*models.Campaign
+	var rules []*models.TargetingRule
+	var valid []*models.BulkCampaignResult
+
+	for i, item := range items {
+		result := &models.BulkCampaignResult{CID: strings.TrimSpace(item.ID)}
+		results[i] = result
+
+		campaign, itemRules, err := s.prepareBulkItem(ctx, item, seen)
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+
+		campaigns = append(campaigns, campaign)
+		rules = append(rules, itemRules...)
+		valid = append(valid, result)
+	}
+
+	if len(campaigns) == 0 {
+		return results, nil
+	}
+
+	if err := s.repo.Campaign().BulkCreateCampaigns(ctx, campaigns, rules); err != nil {
+		for _, result := range valid {
+			result.Error = fmt.Sprintf("failed to import campaigns: %v", err)
+		}
+		return results, nil
+	}
+
+	for _, result := range valid {
+		result.Success = true
+	}
+	for _, campaign := range campaigns {
+		s.notifyWrite(campaign, true)
+	}
+	s.refreshAfterWrite()
+	return results, nil
+}
+
+// prepareBulkItem validates one campaign of a bulk request and builds it,
+// together with its targeting rules. seen holds the campaign IDs already
+// accepted from the request and gains the item's ID.
+func (s *TargetingService) prepareBulkItem(ctx context.Context, item *models.BulkCampaignRequest, seen map[string]bool) (*models.Campaign, []*models.TargetingRule, error) {
+	if err := s.validateCampaignRequest(&item.CampaignRequest, true); err != nil {
+		return nil, nil, err
+	}
+	id := strings.TrimSpace(item.ID)
+	if seen[id] {
+		return nil, nil, fmt.Errorf("%w: duplicate cid in request", ErrInvalidCampaign)
+	}
+
+	campaign, err := newCampaign(&item.CampaignRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+	campaign.TenantID = tenant.FromContext(ctx)
+	rules, err := s.validateBulkRules(campaign, item.Rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	seen[id] = true
+	return campaign, rules, nil
+}
+
+// newCampaign builds a campaign from a create request, defaulting to ACTIVE.
+// A campaign may start out in any status, moved on by its flight dates.
+func newCampaign(req *models.CampaignRequest) (*models.Campaign, error) {
+	campaign := &models.Campaign{
+		ID:     strings.TrimSpace(req.ID),
+		Status: models.StatusActive,
+	}
+	applyCampaignRequest(campaign, req)
+	switch req.Status {
+	case "":
+	case models.StatusInactive:
+		campaign.Status = models.StatusPaused
+	default:
+		campaign.Status = req.Status
+	}
+	if err := validateFlight(campaign); err != nil {
+		return nil, err
+	}
+	advanceStatus(campaign, time.Now())
+	return campaign, nil
+}
+
+// applyCampaignRequest copies the fields set in req onto campaign, except for
+// the status, which follows the campaign lifecycle
+func applyCampaignRequest(campaign *models.Campaign, req *models.CampaignRequest) {
+	if req.Name != "" {
+		campaign.Name = req.Name
+	}
+	if req.Image != "" {
+		campaign.Image = req.Image
+	}
+	if req.CTA != "" {
+		campaign.CTA = req.CTA
+	}
+	if req.FrequencyCap != nil {
+		campaign.FrequencyCap = *req.FrequencyCap
+	}
+	if req.Priority != nil {
+		campaign.Priority = *req.Priority
+	}
+	if req.Weight != nil {
+		campaign.Weight = *req.Weight
+	}
+	if req.DailyBudget != nil {
+		campaign.DailyBudget = *req.DailyBudget
+	}
+	if req.TotalBudget != nil {
+		campaign.TotalBudget = *req.TotalBudget
+	}
+	if req.AppDailyCap != nil {
+		campaign.AppDailyCap = *req.AppDailyCap
+	}
+	if req.MaxDailyApps != nil {
+		campaign.MaxDailyApps = *req.MaxDailyApps
+	}
+	if req.Experiment != nil {
+		campaign.Experiment = nil
+		if req.Experiment.Name != "" {
+			experiment := *req.Experiment
+			if experiment.BucketKey == "" {
+				experiment.BucketKey = defaultBucketKey
+			}
+			campaign.Experiment = &experiment
+		}
+	}
+	if req.Creatives != nil {
+		campaign.Creatives = nil
+		if len(req.Creatives) > 0 {
+			campaign.Creatives = append([]models.Creative{}, req.Creatives...)
+		}
+	}
+	if req.Rotation != "" {
+		campaign.Rotation = req.Rotation
+	}
+	if req.BlockedCategories != nil {
+		campaign.BlockedCategories = normalizeCategories(req.BlockedCategories)
+	}
+	if req.ClickURL != "" {
+		campaign.ClickURL = req.ClickURL
+	}
+	if req.StartAt != nil {
+		start := req.StartAt.UTC()
+		campaign.StartAt = &start
+	}
+	if req.EndAt != nil {
+		end := req.EndAt.UTC()
+		campaign.EndAt = &end
+	}
+	if len(campaign.Creatives) > 0 && campaign.Rotation == "" {
+		campaign.Rotation = models.RotationRoundRobin
+	}
+}
+
+// validateBulkRules assigns the rules of a bulk item to its campaign and validates them
+func (s *TargetingService) validateBulkRules(campaign *models.Campaign, rules []*models.TargetingRule) ([]*models.TargetingRule, error) {
+	valid := make([]*models.TargetingRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule == nil {
+			continue
+		}
+		rule.CampaignID = campaign.ID
+		rule.TenantID = campaign.TenantID
+		normalizeRuleValues(rule)
+		if err := s.validateRule(rule); err != nil {
+			return nil, err
+		}
+		valid = append(valid, rule)
+	}
+	return valid, nil
+}
+
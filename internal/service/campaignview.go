@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// Bounds of what a campaign view reports
+const (
+	// CampaignViewDays is how many days of serve counts, today included, a
+	// campaign view reports
+	CampaignViewDays = 7
+	// CampaignViewChanges is how many of a campaign's latest changes a
+	// campaign view reports
+	CampaignViewChanges = 20
+)
+
+// CampaignView assembles everything admin UIs show about a campaign of the
+// tenant ctx acts for: the campaign and its targeting rules, whether it is
+// being served, its flight, its serve counts over the last CampaignViewDays
+// days, its tracked impressions and clicks today and overall, and its latest
+// changes. Changes are those this instance has seen, within the history kept
+// for the change stream.
+func (s *TargetingService) CampaignView(ctx context.Context, id string) (*models.CampaignView, error) {
+	campaign, err := s.getCampaign(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	rules, err := s.campaignRules(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	today := now.UTC().Format(statsDayLayout)
+	view := &models.CampaignView{
+		Campaign:      campaign,
+		Rules:         rules,
+		Status:        s.servingStatus(campaign, now),
+		Schedule:      campaignSchedule(campaign, now),
+		RecentChanges: s.changes.recent(campaign.TenantID, campaign.ID, CampaignViewChanges),
+	}
+
+	if s.deliveries != nil {
+		from := now.UTC().AddDate(0, 0, 1-CampaignViewDays).Format(statsDayLayout)
+		filter, err := statsFilter(tenant.FromContext(ctx), id, from, today)
+		if err != nil {
+			return nil, err
+		}
+		report, err := s.deliveryStats(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		view.Served = &models.CampaignDeliveryStats{CID: id, Days: map[string]int64{}}
+		if len(report.Campaigns) > 0 {
+			view.Served = report.Campaigns[0]
+		}
+	}
+
+	if view.Tracking, err = s.campaignTracking(ctx, id, today); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// servingStatus reports whether a campaign is being served at now, and the
+// first reason it isn't
+func (s *TargetingService) servingStatus(campaign *models.Campaign, now time.Time) models.CampaignServingStatus {
+	status := models.CampaignServingStatus{Status: campaign.Status}
+	switch {
+	case !campaign.IsActive():
+		status.Reason = fmt.Sprintf("campaign is %s", campaign.Status)
+	case !campaign.InFlight(now):
+		status.Reason = "campaign is outside its flight"
+	case s.killed(campaign.TenantID):
+		status.Reason = "a kill switch stops serving the tenant's campaigns"
+	default:
+		if _, cached := s.cache.snapshot.Load().campaigns[campaign.ID]; !cached {
+			status.Reason = "campaign hasn't been loaded into the targeting cache yet"
+		} else {
+			status.Serving = true
+		}
+	}
+	return status
+}
+
+// campaignSchedule returns the flight of a campaign and its phase at now
+func campaignSchedule(campaign *models.Campaign, now time.Time) models.CampaignSchedule {
+	schedule := models.CampaignSchedule{StartAt: campaign.StartAt, EndAt: campaign.EndAt, Phase: models.FlightRunning}
+	switch {
+	case campaign.StartAt != nil && now.Before(*campaign.StartAt):
+		schedule.Phase = models.FlightUpcoming
+	case campaign.EndAt != nil && !now.Before(*campaign.EndAt):
+		schedule.Phase = models.FlightEnded
+	}
+	return schedule
+}
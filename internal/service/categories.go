@@ -0,0 +1,20 @@
+package service
+
+import (
+	"slices"
+	"strings"
+)
+
+// normalizeCategories upper-cases IAB content category codes, such as
+// iab7-39, and drops empty ones. They are sorted and deduplicated so equal
+// sets share query cache entries. It returns nil when none are left.
+func normalizeCategories(categories []string) []string {
+	var normalized []string
+	for _, category := range categories {
+		if category = strings.ToUpper(strings.TrimSpace(category)); category != "" {
+			normalized = append(normalized, category)
+		}
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
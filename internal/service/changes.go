@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+)
+
+// minWatchRetryDelay is the initial wait before reopening a failed change stream
+const minWatchRetryDelay = time.Second
+
+// startChangeWatcher keeps the cache up to date from the repository's change
+// stream. Whenever the stream fails the cache is rebuilt from scratch, so any
+// missed changes are picked up, and the stream is reopened with exponential
+// backoff capped at the cache refresh interval. A failed rebuild is retried
+// with the same backoff before the stream is reopened.
+func (s *TargetingService) startChangeWatcher(watcher repository.ChangeWatcher) {
+	maxDelay := s.config.Cache.CleanupInterval
+	if maxDelay <= 0 {
+		maxDelay = time.Minute
+	}
+	delay := minWatchRetryDelay
+	s.watchingChanges.Store(true)
+
+	for {
+		started := time.Now()
+		err := watcher.WatchChanges(context.Background(), s.applyChange)
+		slog.Warn("change stream stopped", "error", err)
+
+		// A stream that ran for a while was healthy, so start backing off afresh
+		if time.Since(started) > maxDelay {
+			delay = minWatchRetryDelay
+		}
+
+		// Changes made while the stream was down are only picked up by a
+		// rebuild, so keep retrying it before the stream is reopened
+		for {
+			err := s.refreshCache()
+			if err == nil {
+				break
+			}
+			slog.Error("failed to refresh cache", "retry_in", delay, "error", err)
+			time.Sleep(delay)
+			if delay *= 2; delay > maxDelay {
+				delay = maxDelay
+			}
+		}
+		time.Sleep(delay)
+		if delay *= 2; delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+// applyChange updates the cache for a single repository change
+func (s *TargetingService) applyChange(event repository.ChangeEvent) {
+	s.publishStreamChange(event)
+	switch {
+	case event.Kind == repository.ChangeKindCampaign && event.Campaign != nil:
+		s.applyCampaignChange(event.Campaign)
+		s.metrics.RecordCacheUpdate()
+	case event.Kind == repository.ChangeKindTargetingRule && event.Rule != nil:
+		s.applyRuleChange(event.Rule)
+		s.metrics.RecordCacheUpdate()
+	default:
+		// Deletes don't identify the removed document, so rebuild everything
+		if err := s.refreshCache(); err != nil {
+			slog.Error("failed to refresh cache", "error", err)
+		}
+	}
+}
+
+// applyCampaignChange stores or evicts a campaign depending on its status
+func (s *TargetingService) applyCampaignChange(campaign *models.Campaign) {
+	s.cache.update(func(next *cacheSnapshot) {
+		if campaign.IsActive() {
+			next.campaigns[campaign.ID] = campaign
+		} else {
+			delete(next.campaigns, campaign.ID)
+		}
+		next.reindexCampaign(campaign.ID)
+	})
+}
+
+// applyRuleChange replaces a targeting rule, moving it if its campaign changed
+func (s *TargetingService) applyRuleChange(rule *models.TargetingRule) {
+	isRule := func(existing *models.TargetingRule) bool { return existing.ID == rule.ID }
+
+	s.cache.update(func(next *cacheSnapshot) {
+		affected := map[string]bool{rule.CampaignID: true}
+		for campaignID, rules := range next.targetingRules {
+			if slices.ContainsFunc(rules, isRule) {
+				affected[campaignID] = true
+			}
+		}
+		for campaignID := range next.shadowRules {
+			if slices.ContainsFunc(next.cachedRules(campaignID), isRule) {
+				affected[campaignID] = true
+			}
+		}
+
+		for campaignID := range affected {
+			rules := slices.DeleteFunc(next.cachedRules(campaignID), isRule)
+			if campaignID == rule.CampaignID {
+				rules = append(rules, rule)
+			}
+			next.setCampaignRules(campaignID, rules)
+			next.reindexCampaign(campaignID)
+		}
+	})
+}
+
+// refreshCampaignRules reloads the targeting rules of the given campaigns
+// into the cache straight away, so a rule write is served by the next request
+// rather than after a full refresh
+func (s *TargetingService) refreshCampaignRules(ctx context.Context, campaignIDs ...string) error {
+	loaded := make(map[string][]*models.TargetingRule, len(campaignIDs))
+	for _, campaignID := range campaignIDs {
+		if _, ok := loaded[campaignID]; ok {
+			continue
+		}
+		rules, err := s.repo.TargetingRule().GetTargetingRulesByCampaignID(ctx, campaignID)
+		if err != nil {
+			return fmt.Errorf("failed to get targeting rules: %w", err)
+		}
+		loaded[campaignID] = rules
+	}
+
+	s.cache.update(func(next *cacheSnapshot) {
+		for campaignID, rules := range loaded {
+			next.setCampaignRules(campaignID, rules)
+			next.reindexCampaign(campaignID)
+		}
+	})
+	return nil
+}
+
+// refreshAfterWrite reloads the cache in the background after an admin write
+// so changes are served without waiting for the next scheduled refresh. When
+// a change stream is active it already delivers the change.
+func (s *TargetingService) refreshAfterWrite() {
+	if s.watchingChanges.Load() {
+		return
+	}
+
+	go func() {
+		if err := s.refreshCache(); err != nil {
+			slog.Error("failed to refresh cache", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/contextkey"
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// ClickURLMacros are the macros click URL templates may use. Each is
+// replaced, query-escaped, by a value of the delivery it is served in:
+//
+//	{CID}             the campaign ID
+//	{CREATIVE}        the ID of the creative served, if the campaign has several
+//	{REQUEST_ID}      the ID of the HTTP request, empty over gRPC
+//	{TENANT}          the tenant the request was served for
+//	{APP}             the requesting app
+//	{COUNTRY}         the country of the request
+//	{OS}              the operating system of the request
+//	{DEVICE_TYPE}     the device type of the request
+//	{TIMESTAMP}       the Unix time the campaign was served at, in seconds
+//	{TRACKING_TOKEN}  the token the tracking endpoints require, empty over gRPC
+var ClickURLMacros = []string{
+	"{CID}", "{CREATIVE}", "{REQUEST_ID}", "{TENANT}", "{APP}", "{COUNTRY}", "{OS}", "{DEVICE_TYPE}", "{TIMESTAMP}", "{TRACKING_TOKEN}",
+}
+
+// perRequestMacros are the click URL macros whose values differ between
+// requests with the same parameters
+var perRequestMacros = []string{"{REQUEST_ID}", "{TIMESTAMP}", "{TRACKING_TOKEN}"}
+
+// perRequestClickURL reports whether the click URL of match is expanded with
+// values that differ between requests with the same parameters, so responses
+// serving it mustn't be shared between requests
+func perRequestClickURL(match *models.DeliveryResponse) bool {
+	template := match.ClickURLTemplate()
+	for _, macro := range perRequestMacros {
+		if strings.Contains(template, macro) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServedPerRequest reports whether match carries values only valid for the
+// request it was served to, a tracking token or a click URL with per-request
+// macros, so responses serving it mustn't be shared or revalidated
+func ServedPerRequest(match *models.DeliveryResponse) bool {
+	return match.TrackingToken != "" || perRequestClickURL(match)
+}
+
+// expandClickURL returns match with its click URL template expanded for req.
+// Campaigns without a template are returned unchanged; others are returned
+// as a copy, since match may be shared through the query cache.
+func expandClickURL(ctx context.Context, req *models.DeliveryRequest, match *models.DeliveryResponse, now time.Time) *models.DeliveryResponse {
+	template := match.ClickURLTemplate()
+	if template == "" {
+		return match
+	}
+
+	var pairs []string
+	for _, macro := range []struct{ name, value string }{
+		{"{CID}", match.CID},
+		{"{CREATIVE}", match.Creative},
+		{"{REQUEST_ID}", contextkey.RequestID(ctx)},
+		{"{TENANT}", tenant.FromContext(ctx)},
+		{"{APP}", req.App},
+		{"{COUNTRY}", req.Country},
+		{"{OS}", req.OS},
+		{"{DEVICE_TYPE}", req.DeviceType},
+		{"{TIMESTAMP}", strconv.FormatInt(now.Unix(), 10)},
+		{"{TRACKING_TOKEN}", match.TrackingToken},
+	} {
+		pairs = append(pairs, macro.name, url.QueryEscape(macro.value))
+	}
+
+	expanded := *match
+	expanded.ClickURL = strings.NewReplacer(pairs...).Replace(template)
+	return &expanded
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// CloneCampaign copies a campaign and all its targeting rules to a new
+// campaign. The fields set in req override those of the copy. Without a cid
+// the copy gets the source's ID with a random suffix, without a name the
+// source's name followed by " (copy)", and without a status it starts out as
+// DRAFT so it isn't delivered before it's been reviewed. The campaign and its
+// rules are stored together, so a failed clone leaves nothing behind.
+func (s *TargetingService) CloneCampaign(ctx context.Context, id string, req *models.CampaignRequest) (*models.CampaignClone, error) {
+	if err := s.validateCampaignRequest(req, false); err != nil {
+		return nil, err
+	}
+	source, err := s.getCampaign(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	sourceRules, err := s.ListTargetingRules(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	campaign := copyCampaign(source)
+	campaign.ID = strings.TrimSpace(req.ID)
+	if campaign.ID == "" {
+		campaign.ID = source.ID + "-copy-" + randomSuffix()
+	}
+	campaign.Name = source.Name + " (copy)"
+	campaign.Status = models.StatusDraft
+	campaign.CreatedAt, campaign.UpdatedAt, campaign.DeletedAt = time.Time{}, time.Time{}, nil
+	applyCampaignRequest(campaign, req)
+	switch req.Status {
+	case "":
+	case models.StatusInactive:
+		campaign.Status = models.StatusPaused
+	default:
+		campaign.Status = req.Status
+	}
+	if err := validateFlight(campaign); err != nil {
+		return nil, err
+	}
+	advanceStatus(campaign, time.Now())
+
+	rules := make([]*models.TargetingRule, 0, len(sourceRules))
+	for _, rule := range sourceRules {
+		rule := copyRule(rule)
+		rule.ID = 0
+		rules = append(rules, rule)
+	}
+	if rules, err = s.validateBulkRules(campaign, rules); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Campaign().BulkCreateCampaigns(ctx, []*models.Campaign{campaign}, rules); err != nil {
+		return nil, fmt.Errorf("failed to clone campaign: %w", err)
+	}
+	s.refreshAfterWrite()
+	s.notifyWrite(campaign, true)
+	return &models.CampaignClone{Campaign: campaign, Rules: rules}, nil
+}
+
+// copyCampaign returns a copy of campaign that shares no mutable state with it
+func copyCampaign(campaign *models.Campaign) *models.Campaign {
+	c := *campaign
+	c.Creatives = slices.Clone(campaign.Creatives)
+	c.BlockedCategories = slices.Clone(campaign.BlockedCategories)
+	if campaign.Experiment != nil {
+		experiment := *campaign.Experiment
+		c.Experiment = &experiment
+	}
+	return &c
+}
+
+// copyRule returns a copy of rule that shares no mutable state with it
+func copyRule(rule *models.TargetingRule) *models.TargetingRule {
+	r := *rule
+	for _, values := range []*[]string{
+		&r.IncludeCountry, &r.ExcludeCountry, &r.IncludeOS, &r.ExcludeOS,
+		&r.IncludeApp, &r.ExcludeApp, &r.IncludeDeviceType, &r.ExcludeDeviceType,
+		&r.IncludeRegion, &r.ExcludeRegion, &r.IncludeCity, &r.ExcludeCity,
+		&r.IncludeSegment, &r.ExcludeSegment,
+	} {
+		*values = slices.Clone(*values)
+	}
+	r.Operators = maps.Clone(rule.Operators)
+	if rule.Custom != nil {
+		r.Custom = make(map[string]models.DimensionValues, len(rule.Custom))
+		for name, values := range rule.Custom {
+			r.Custom[name] = models.DimensionValues{Include: slices.Clone(values.Include), Exclude: slices.Clone(values.Exclude)}
+		}
+	}
+	if rule.Schedule != nil {
+		schedule := *rule.Schedule
+		schedule.Weekdays = slices.Clone(rule.Schedule.Weekdays)
+		schedule.Hours = slices.Clone(rule.Schedule.Hours)
+		r.Schedule = &schedule
+	}
+	if rule.EffectiveFrom != nil {
+		from := *rule.EffectiveFrom
+		r.EffectiveFrom = &from
+	}
+	if rule.EffectiveUntil != nil {
+		until := *rule.EffectiveUntil
+		r.EffectiveUntil = &until
+	}
+	r.Expression = copyExpression(rule.Expression)
+	return &r
+}
+
+// copyExpression returns a deep copy of an expression
+func copyExpression(expr *models.Expression) *models.Expression {
+	if expr == nil {
+		return nil
+	}
+	e := &models.Expression{
+		Not:       copyExpression(expr.Not),
+		Dimension: expr.Dimension,
+		Operator:  expr.Operator,
+		Values:    slices.Clone(expr.Values),
+	}
+	for _, child := range expr.And {
+		e.And = append(e.And, copyExpression(child))
+	}
+	for _, child := range expr.Or {
+		e.Or = append(e.Or, copyExpression(child))
+	}
+	return e
+}
+
+// randomSuffix returns 8 random hex digits for generated campaign IDs
+func randomSuffix() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// rotationCounters holds round robin positions by key, such as a campaign
+// ID for creative rotation. Rotation is per server instance, so with several
+// instances each creative is still served evenly overall.
+type rotationCounters struct {
+	counters sync.Map // key -> *atomic.Uint64
+}
+
+// next returns the key's next position and advances it
+func (r *rotationCounters) next(key string) uint64 {
+	counter, ok := r.counters.Load(key)
+	if !ok {
+		counter, _ = r.counters.LoadOrStore(key, new(atomic.Uint64))
+	}
+	return counter.(*atomic.Uint64).Add(1) - 1
+}
+
+// retain drops the positions of the keys keep rejects
+func (r *rotationCounters) retain(keep func(key string) bool) {
+	r.counters.Range(func(key, _ any) bool {
+		if !keep(key.(string)) {
+			r.counters.Delete(key)
+		}
+		return true
+	})
+}
+
+// assignCreative picks the creative to serve for a campaign with several.
+// Campaigns without creatives are returned unchanged; others are returned as
+// a copy carrying the chosen creative, since match may be shared through the
+// query cache.
+func (s *TargetingService) assignCreative(match *models.DeliveryResponse) *models.DeliveryResponse {
+	creatives, rotation := match.CampaignCreatives()
+	if len(creatives) == 0 {
+		return match
+	}
+
+	var creative models.Creative
+	switch rotation {
+	case models.RotationRandom:
+		creative = creatives[rand.IntN(len(creatives))]
+	case models.RotationWeighted:
+		creative = weightedCreative(creatives)
+	default:
+		creative = creatives[s.rotations.next(match.CID)%uint64(len(creatives))]
+	}
+
+	assigned := *match
+	assigned.Image = creative.Image
+	assigned.CTA = creative.CTA
+	assigned.Creative = creative.ID
+	return &assigned
+}
+
+// weightedCreative picks a creative with a probability proportional to its
+// weight, counting zero weights as one
+func weightedCreative(creatives []models.Creative) models.Creative {
+	weight := func(c models.Creative) int {
+		if c.Weight <= 0 {
+			return 1
+		}
+		return c.Weight
+	}
+
+	total := 0
+	for _, c := range creatives {
+		total += weight(c)
+	}
+	pick := rand.IntN(total)
+	for _, c := range creatives {
+		if pick -= weight(c); pick < 0 {
+			return c
+		}
+	}
+	return creatives[len(creatives)-1]
+}
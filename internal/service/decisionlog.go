@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/config"
+	"github.com/Harshi-itaSinha/target-engine/internal/contextkey"
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// ErrDecisionLogDisabled is returned for decision lookups while the
+// decision log is disabled
+var ErrDecisionLogDisabled = errors.New("decision log is disabled")
+
+const (
+	// DefaultDecisionLogTTL is how long logged decisions are kept when
+	// decisionLog.ttl is unset
+	DefaultDecisionLogTTL = 7 * 24 * time.Hour
+	// DefaultDecisionLogFlushInterval is how often logged decisions are
+	// flushed to the repository when decisionLog.flushInterval is unset
+	DefaultDecisionLogFlushInterval = 5 * time.Second
+	// DefaultDecisionLogBufferSize bounds the decisions held between flushes
+	// when decisionLog.bufferSize is unset
+	DefaultDecisionLogBufferSize = 10000
+	// DefaultDecisionLimit and MaxDecisionLimit bound the decisions returned
+	// by a lookup
+	DefaultDecisionLimit = 20
+	MaxDecisionLimit     = 100
+)
+
+// decisionLog samples delivery decisions and holds them in memory until they
+// are flushed to the repository. It is safe for concurrent use.
+type decisionLog struct {
+	sampleRate float64
+	ttl        time.Duration
+	bufferSize int
+	mutex      sync.Mutex
+	pending    []*models.Decision
+	dropped    atomic.Int64
+}
+
+func newDecisionLog(cfg config.DecisionLogConfig) *decisionLog {
+	l := &decisionLog{sampleRate: cfg.SampleRate, ttl: cfg.TTL, bufferSize: cfg.BufferSize}
+	if l.ttl <= 0 {
+		l.ttl = DefaultDecisionLogTTL
+	}
+	if l.bufferSize <= 0 {
+		l.bufferSize = DefaultDecisionLogBufferSize
+	}
+	return l
+}
+
+// sampled reports whether the decision of a request is to be logged
+func (l *decisionLog) sampled() bool {
+	return rand.Float64()*100 < l.sampleRate
+}
+
+// add holds a decision until the next flush, dropping it if the buffer is
+// full
+func (l *decisionLog) add(decision *models.Decision) {
+	l.mutex.Lock()
+	full := len(l.pending) >= l.bufferSize
+	if !full {
+		l.pending = append(l.pending, decision)
+	}
+	l.mutex.Unlock()
+
+	if full {
+		if dropped := l.dropped.Add(1); dropped%1000 == 1 {
+			slog.Warn("dropping delivery decisions, decision log buffer is full", "dropped_total", dropped)
+		}
+	}
+}
+
+// take removes and returns the decisions held
+func (l *decisionLog) take() []*models.Decision {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	pending := l.pending
+	l.pending = nil
+	return pending
+}
+
+// restore puts back decisions that failed to flush, so the next flush
+// retries them. The oldest are dropped beyond the buffer size.
+func (l *decisionLog) restore(decisions []*models.Decision) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.pending = append(decisions, l.pending...)
+	if over := len(l.pending) - l.bufferSize; over > 0 {
+		l.pending = l.pending[over:]
+	}
+}
+
+// matching returns the decisions held that filter selects, newest first
+func (l *decisionLog) matching(filter repository.DecisionFilter) []*models.Decision {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var decisions []*models.Decision
+	for i := len(l.pending) - 1; i >= 0; i-- {
+		decision := l.pending[i]
+		if decision.TenantID == filter.TenantID && (filter.RequestID == "" || decision.RequestID == filter.RequestID) {
+			decisions = append(decisions, decision)
+		}
+	}
+	return decisions
+}
+
+// logDecision adds the decision made for a served request to the decision
+// log, if the request is sampled
+func (s *TargetingService) logDecision(ctx context.Context, req *models.DeliveryRequest, matches []*models.DeliveryResponse, cacheHit bool, start time.Time) {
+	if s.decisions == nil || !s.decisions.sampled() {
+		return
+	}
+
+	campaignIDs := make([]string, len(matches))
+	for i, match := range matches {
+		campaignIDs[i] = match.CID
+	}
+	normalized := s.normalizeRequest(req)
+	s.decisions.add(&models.Decision{
+		RequestID:   contextkey.RequestID(ctx),
+		TenantID:    tenant.FromContext(ctx),
+		At:          start.UTC(),
+		App:         normalized.App,
+		Country:     normalized.Country,
+		OS:          normalized.OS,
+		DeviceType:  normalized.DeviceType,
+		Region:      normalized.Region,
+		City:        normalized.City,
+		AppVersion:  normalized.AppVersion,
+		Categories:  normalized.Categories,
+		Custom:      normalized.Custom,
+		CampaignIDs: campaignIDs,
+		LatencyMs:   float64(time.Since(start)) / float64(time.Millisecond),
+		CacheHit:    cacheHit,
+		ExpiresAt:   start.Add(s.decisions.ttl).UTC(),
+	})
+}
+
+// startDecisionLogWorker flushes logged decisions to the repository every
+// flush interval
+func (s *TargetingService) startDecisionLogWorker() {
+	interval := s.config.DecisionLog.FlushInterval
+	if interval <= 0 {
+		interval = DefaultDecisionLogFlushInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.FlushDecisionLog(context.Background()); err != nil {
+			slog.Error("failed to flush decision log", "error", err)
+		}
+	}
+}
+
+// FlushDecisionLog adds the decisions logged since the last flush to the
+// repository. Decisions that fail to be stored are kept for the next flush.
+// It is a no-op while the decision log is disabled.
+func (s *TargetingService) FlushDecisionLog(ctx context.Context) error {
+	if s.decisions == nil {
+		return nil
+	}
+	pending := s.decisions.take()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err := s.repo.DecisionLog().AddDecisions(ctx, pending); err != nil {
+		s.decisions.restore(pending)
+		return fmt.Errorf("failed to add decisions: %w", err)
+	}
+	return nil
+}
+
+// Decisions returns up to limit of the latest logged decisions of the
+// tenant, newest first, only those of the request with requestID when it is
+// set. A zero limit returns DefaultDecisionLimit decisions. Decisions not
+// yet flushed by this instance are included.
+func (s *TargetingService) Decisions(ctx context.Context, requestID string, limit int) (*models.DecisionList, error) {
+	if s.decisions == nil {
+		return nil, ErrDecisionLogDisabled
+	}
+	if limit < 0 || limit > MaxDecisionLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, MaxDecisionLimit)
+	}
+	if limit == 0 {
+		limit = DefaultDecisionLimit
+	}
+
+	filter := repository.DecisionFilter{TenantID: tenant.FromContext(ctx), RequestID: requestID, Limit: limit}
+	stored, err := s.repo.DecisionLog().GetDecisions(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get decisions: %w", err)
+	}
+
+	decisions := append(s.decisions.matching(filter), stored...)
+	slices.SortStableFunc(decisions, func(a, b *models.Decision) int {
+		return b.At.Compare(a.At)
+	})
+	if len(decisions) > limit {
+		decisions = decisions[:limit]
+	}
+	return &models.DecisionList{Decisions: decisions}, nil
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/config"
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// ErrCampaignStatsDisabled is returned for campaign stats while
+// stats.campaigns is disabled
+var ErrCampaignStatsDisabled = errors.New("campaign stats are disabled")
+
+const (
+	// DefaultStatsFlushInterval is how often delivery counts are flushed to
+	// the repository when stats.campaigns.flushInterval is unset
+	DefaultStatsFlushInterval = 30 * time.Second
+	// MaxStatsDays bounds the range of days of a campaign stats request
+	MaxStatsDays = 92
+	// maxPendingDeliveryCounts bounds the counts held between flushes, since
+	// countries come from clients. Beyond it, new countries and OSes are
+	// counted as other.
+	maxPendingDeliveryCounts = 100000
+	otherDimensionValue      = "other"
+	statsDayLayout           = "20060102"
+)
+
+// deliveryCountKey identifies a delivery count held between flushes
+type deliveryCountKey struct {
+	tenantID, campaignID, day, country, os string
+}
+
+// deliveryCounter aggregates delivery counts in memory until they are
+// flushed to the repository. It is safe for concurrent use.
+type deliveryCounter struct {
+	mutex     sync.Mutex
+	pending   map[deliveryCountKey]int64
+	byCountry bool
+	byOS      bool
+}
+
+func newDeliveryCounter(cfg config.CampaignStatsConfig) *deliveryCounter {
+	return &deliveryCounter{
+		pending:   make(map[deliveryCountKey]int64),
+		byCountry: cfg.ByCountry,
+		byOS:      cfg.ByOS,
+	}
+}
+
+// record counts the campaigns served for a request of a tenant
+func (c *deliveryCounter) record(tenantID, country, os string, served []string, now time.Time) {
+	key := deliveryCountKey{tenantID: tenantID, day: now.UTC().Format(statsDayLayout)}
+	if c.byCountry {
+		key.country = strings.ToUpper(strings.TrimSpace(country))
+	}
+	if c.byOS {
+		key.os = strings.ToLower(strings.TrimSpace(os))
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, id := range served {
+		key := key
+		key.campaignID = id
+		if _, ok := c.pending[key]; !ok && len(c.pending) >= maxPendingDeliveryCounts {
+			if c.byCountry {
+				key.country = otherDimensionValue
+			}
+			if c.byOS {
+				key.os = otherDimensionValue
+			}
+		}
+		c.pending[key]++
+	}
+}
+
+// take removes and returns the counts held
+func (c *deliveryCounter) take() map[deliveryCountKey]int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	pending := c.pending
+	c.pending = make(map[deliveryCountKey]int64)
+	return pending
+}
+
+// restore adds back counts that failed to flush, so the next flush retries
+// them
+func (c *deliveryCounter) restore(counts map[deliveryCountKey]int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, served := range counts {
+		c.pending[key] += served
+	}
+}
+
+// snapshot returns a copy of the counts held
+func (c *deliveryCounter) snapshot() map[deliveryCountKey]int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return maps.Clone(c.pending)
+}
+
+// recordDeliveries counts the campaigns served for a request in the
+// campaign stats
+func (s *TargetingService) recordDeliveries(ctx context.Context, req *models.DeliveryRequest, matches []*models.DeliveryResponse) {
+	if s.deliveries == nil || len(matches) == 0 {
+		return
+	}
+	served := make([]string, len(matches))
+	for i, match := range matches {
+		served[i] = match.CID
+	}
+	s.deliveries.record(tenant.FromContext(ctx), req.Country, req.OS, served, time.Now())
+}
+
+// startDeliveryStatsWorker flushes delivery counts to the repository every
+// flush interval
+func (s *TargetingService) startDeliveryStatsWorker() {
+	interval := s.config.Stats.Campaigns.FlushInterval
+	if interval <= 0 {
+		interval = DefaultStatsFlushInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.FlushDeliveryStats(context.Background()); err != nil {
+			slog.Error("failed to flush delivery counts", "error", err)
+		}
+	}
+}
+
+// FlushDeliveryStats adds the delivery counts aggregated since the last
+// flush to the repository. Counts that fail to be stored are kept for the
+// next flush. It is a no-op while campaign stats are disabled.
+func (s *TargetingService) FlushDeliveryStats(ctx context.Context) error {
+	if s.deliveries == nil {
+		return nil
+	}
+	pending := s.deliveries.take()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	counts := make([]repository.DeliveryCount, 0, len(pending))
+	for key, served := range pending {
+		counts = append(counts, repository.DeliveryCount{
+			TenantID:   key.tenantID,
+			CampaignID: key.campaignID,
+			Day:        key.day,
+			Country:    key.country,
+			OS:         key.os,
+			Served:     served,
+		})
+	}
+	if err := s.repo.DeliveryStats().AddDeliveryCounts(ctx, counts); err != nil {
+		s.deliveries.restore(pending)
+		return fmt.Errorf("failed to add delivery counts: %w", err)
+	}
+	return nil
+}
+
+// CampaignStats returns the delivery counts of the tenant's campaigns, or of
+// campaignID only when it is set, from day from through day to (YYYYMMDD,
+// both today in UTC when empty). Counts not yet flushed by this instance are
+// included.
+func (s *TargetingService) CampaignStats(ctx context.Context, campaignID, from, to string) (*models.CampaignStatsReport, error) {
+	if s.deliveries == nil {
+		return nil, ErrCampaignStatsDisabled
+	}
+	filter, err := statsFilter(tenant.FromContext(ctx), campaignID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if campaignID != "" {
+		if _, err := s.getCampaign(ctx, campaignID); err != nil {
+			return nil, fmt.Errorf("failed to get campaign: %w", err)
+		}
+	}
+	return s.deliveryStats(ctx, filter)
+}
+
+// deliveryStats returns the delivery counts matching filter, including those
+// not yet flushed by this instance
+func (s *TargetingService) deliveryStats(ctx context.Context, filter repository.DeliveryCountFilter) (*models.CampaignStatsReport, error) {
+	counts, err := s.repo.DeliveryStats().GetDeliveryCounts(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery counts: %w", err)
+	}
+	for key, served := range s.deliveries.snapshot() {
+		if key.tenantID != filter.TenantID || (filter.CampaignID != "" && key.campaignID != filter.CampaignID) ||
+			key.day < filter.From || key.day > filter.To {
+			continue
+		}
+		counts = append(counts, repository.DeliveryCount{CampaignID: key.campaignID, Day: key.day, Country: key.country, OS: key.os, Served: served})
+	}
+
+	byCampaign := make(map[string]*models.CampaignDeliveryStats)
+	for _, count := range counts {
+		stats, ok := byCampaign[count.CampaignID]
+		if !ok {
+			stats = &models.CampaignDeliveryStats{CID: count.CampaignID, Days: make(map[string]int64)}
+			byCampaign[count.CampaignID] = stats
+		}
+		stats.Served += count.Served
+		stats.Days[count.Day] += count.Served
+		if count.Country != "" {
+			if stats.Countries == nil {
+				stats.Countries = make(map[string]int64)
+			}
+			stats.Countries[count.Country] += count.Served
+		}
+		if count.OS != "" {
+			if stats.OS == nil {
+				stats.OS = make(map[string]int64)
+			}
+			stats.OS[count.OS] += count.Served
+		}
+	}
+
+	report := &models.CampaignStatsReport{From: filter.From, To: filter.To, Campaigns: slices.Collect(maps.Values(byCampaign))}
+	slices.SortFunc(report.Campaigns, func(a, b *models.CampaignDeliveryStats) int {
+		return cmp.Or(cmp.Compare(b.Served, a.Served), strings.Compare(a.CID, b.CID))
+	})
+	if report.Campaigns == nil {
+		report.Campaigns = []*models.CampaignDeliveryStats{}
+	}
+	return report, nil
+}
+
+// statsFilter checks the day range of a campaign stats request, defaulting
+// both ends to today
+func statsFilter(tenantID, campaignID, from, to string) (repository.DeliveryCountFilter, error) {
+	today := time.Now().UTC().Format(statsDayLayout)
+	if from == "" {
+		from = today
+	}
+	if to == "" {
+		to = today
+	}
+	fromDay, err := time.Parse(statsDayLayout, from)
+	if err != nil {
+		return repository.DeliveryCountFilter{}, fmt.Errorf("%w: %w", ErrInvalidFilter, validation.Invalid("from", "must be a date in YYYYMMDD form"))
+	}
+	toDay, err := time.Parse(statsDayLayout, to)
+	if err != nil {
+		return repository.DeliveryCountFilter{}, fmt.Errorf("%w: %w", ErrInvalidFilter, validation.Invalid("to", "must be a date in YYYYMMDD form"))
+	}
+	switch days := int(toDay.Sub(fromDay)/(24*time.Hour)) + 1; {
+	case days < 1:
+		return repository.DeliveryCountFilter{}, fmt.Errorf("%w: %w", ErrInvalidFilter, validation.Invalid("to", "must not be before from"))
+	case days > MaxStatsDays:
+		return repository.DeliveryCountFilter{}, fmt.Errorf("%w: %w", ErrInvalidFilter, validation.Invalid("to", fmt.Sprintf("must be less than %d days after from", MaxStatsDays)))
+	}
+	return repository.DeliveryCountFilter{TenantID: tenantID, CampaignID: campaignID, From: from, To: to}, nil
+}
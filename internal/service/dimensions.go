@@ -0,0 +1,133 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/config"
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// dimensionNamePattern restricts custom dimension names so they are safe to
+// use as query parameters and cache key parts
+var dimensionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// dimensionRegistry holds the custom dimensions rules may target and
+// requests may carry. Custom dimension values are matched case-insensitively
+// and aren't used by the campaign index.
+type dimensionRegistry struct {
+	custom map[string]struct{}
+}
+
+// newDimensionRegistry builds a registry from config, skipping and logging
+// invalid names and names of built-in dimensions
+func newDimensionRegistry(cfg config.DimensionsConfig) *dimensionRegistry {
+	registry := &dimensionRegistry{custom: make(map[string]struct{}, len(cfg.Custom))}
+	for _, name := range cfg.Custom {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !dimensionNamePattern.MatchString(name) || isBuiltinDimension(name) {
+			slog.Warn("skipping invalid custom dimension", "name", name)
+			continue
+		}
+		registry.custom[name] = struct{}{}
+	}
+	return registry
+}
+
+// registered reports whether name is a registered custom dimension
+func (r *dimensionRegistry) registered(name string) bool {
+	_, ok := r.custom[name]
+	return ok
+}
+
+// Names of the request dimensions that aren't delivery dimensions
+const (
+	appVersionDimension = models.AppVersionDimension
+	segmentDimension    = models.SegmentDimension
+)
+
+// isBuiltinDimension reports whether name is one of the fixed request fields
+func isBuiltinDimension(name string) bool {
+	if name == appVersionDimension || name == segmentDimension {
+		return true
+	}
+	for _, builtin := range indexedDimensions {
+		if name == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyDimensionConfig replaces the registered custom dimensions. Cached
+// query results are dropped since requests may now carry other dimensions.
+// Rules targeting a dimension that is no longer registered stop matching,
+// as requests no longer carry a value for it.
+func (s *TargetingService) ApplyDimensionConfig(cfg config.DimensionsConfig) {
+	s.dimensions.Store(newDimensionRegistry(cfg))
+	s.cache.queryCache.Purge()
+}
+
+// customDimensions returns the registered custom dimensions of a request
+// with normalized names and trimmed values, dropping empty values
+func (s *TargetingService) customDimensions(custom map[string]string) map[string]string {
+	if len(custom) == 0 {
+		return nil
+	}
+
+	registry := s.dimensions.Load()
+	normalized := make(map[string]string, len(custom))
+	for name, value := range custom {
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if value == "" || !registry.registered(name) {
+			continue
+		}
+		normalized[name] = value
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
+
+// validateCustomDimensions checks that every custom dimension a rule targets
+// is registered
+func (s *TargetingService) validateCustomDimensions(rule *models.TargetingRule) error {
+	registry := s.dimensions.Load()
+	for name := range rule.Custom {
+		if !registry.registered(name) {
+			return fmt.Errorf("%w: dimension %q is not registered", ErrInvalidRule, name)
+		}
+	}
+	return nil
+}
+
+// sortedDimensionNames returns the keys of a custom dimension map in order
+func sortedDimensionNames[V any](custom map[string]V) []string {
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// normalizeFurtherValues normalizes the further values of a dimension,
+// dropping empty ones and those equal to its first value. They are sorted so
+// requests carrying the same values share cached results.
+func normalizeFurtherValues(values []string, first string, normalize func(string) string) []string {
+	var normalized []string
+	for _, value := range values {
+		value = normalize(value)
+		if value != "" && value != first && !slices.Contains(normalized, value) {
+			normalized = append(normalized, value)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
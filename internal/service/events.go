@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/contextkey"
+	"github.com/Harshi-itaSinha/target-engine/internal/events"
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// publishDelivery emits the delivery event for a served request
+func (s *TargetingService) publishDelivery(ctx context.Context, req *models.DeliveryRequest, matches []*models.DeliveryResponse, start time.Time) {
+	if s.events == nil {
+		return
+	}
+
+	campaignIDs := make([]string, 0, len(matches))
+	var creatives map[string]string
+	for _, match := range matches {
+		campaignIDs = append(campaignIDs, match.CID)
+		if match.Creative != "" {
+			if creatives == nil {
+				creatives = make(map[string]string)
+			}
+			creatives[match.CID] = match.Creative
+		}
+	}
+	requestID := contextkey.RequestID(ctx)
+	normalized := s.normalizeRequest(req)
+
+	s.events.Publish(events.DeliveryEvent{
+		RequestID:   requestID,
+		Timestamp:   start.UTC(),
+		App:         normalized.App,
+		Country:     normalized.Country,
+		OS:          normalized.OS,
+		DeviceType:  normalized.DeviceType,
+		CampaignIDs: campaignIDs,
+		Creatives:   creatives,
+		LatencyMs:   float64(time.Since(start)) / float64(time.Millisecond),
+	})
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"hash/fnv"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// experimentBuckets is the number of buckets users are hashed into; each
+// bucket holds one percent of traffic
+const experimentBuckets = 100
+
+// defaultBucketKey is the request value users are bucketed by when an
+// experiment doesn't name one
+const defaultBucketKey = "user_id"
+
+// assignExperiment decides whether a campaign in an experiment is served to
+// the user. Campaigns without an experiment are returned unchanged. Included
+// campaigns are returned as a copy carrying the assignment, since match may
+// be shared through the query cache.
+func assignExperiment(match *models.DeliveryResponse, userID string) (*models.DeliveryResponse, bool) {
+	experiment := match.CampaignExperiment()
+	if experiment == nil {
+		return match, true
+	}
+
+	// Only user_id is supported as a bucket key for now
+	if userID == "" {
+		return match, experiment.TrafficPercent >= experimentBuckets
+	}
+
+	bucket := experimentBucket(experiment.Name, userID)
+	if bucket >= experiment.TrafficPercent {
+		return nil, false
+	}
+
+	assigned := *match
+	assigned.Experiment = &models.ExperimentAssignment{Name: experiment.Name, Bucket: bucket}
+	return &assigned, true
+}
+
+// experimentBucket hashes the bucket key value into one of the experiment
+// buckets. The experiment name salts the hash so that a user's buckets in
+// different experiments are independent.
+func experimentBucket(experimentName, value string) int {
+	h := fnv.New32a()
+	h.Write([]byte(experimentName))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return int(h.Sum32() % experimentBuckets)
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// ExplainDelivery evaluates a delivery request against the targeting rules of
+// every active campaign of the tenant ctx acts for, or only campaignID when
+// it is set, and reports which
+// rules matched or failed and on which dimension. Frequency caps and limits
+// are not applied. Rule schedules and effective windows are evaluated at the
+// time at, or now when at is zero.
+func (s *TargetingService) ExplainDelivery(ctx context.Context, req *models.DeliveryRequest, campaignID string, at time.Time) ([]*models.CampaignExplanation, error) {
+	if err := s.validateRequest(req); err != nil {
+		return nil, err
+	}
+	normalized := s.normalizeRequest(req)
+	s.resolveSegments(ctx, normalized)
+	dimensions := normalized.Dimensions()
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	if !s.cache.snapshot.Load().loaded() {
+		if err := s.refreshCache(); err != nil {
+			return nil, fmt.Errorf("failed to load targeting cache: %w", err)
+		}
+	}
+
+	tenantID := tenant.FromContext(ctx)
+
+	snap := s.cache.snapshot.Load()
+	if campaignID == "" {
+		explanations := make([]*models.CampaignExplanation, 0, len(snap.campaigns))
+		for id, campaign := range snap.campaigns {
+			if campaign.TenantID != tenantID {
+				continue
+			}
+			explanations = append(explanations, explainCampaign(campaign, snap.cachedRules(id), dimensions, normalized.Categories, at))
+		}
+		sort.Slice(explanations, func(i, j int) bool { return explanations[i].CID < explanations[j].CID })
+		return explanations, nil
+	}
+
+	if campaign, exists := snap.campaigns[campaignID]; exists && campaign.TenantID == tenantID {
+		return []*models.CampaignExplanation{explainCampaign(campaign, snap.cachedRules(campaignID), dimensions, normalized.Categories, at)}, nil
+	}
+
+	// The cache only holds active campaigns; report why others never serve
+	campaign, err := s.getCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	return []*models.CampaignExplanation{{
+		CID:    campaign.ID,
+		Status: campaign.Status,
+		Reason: fmt.Sprintf("campaign is %s", campaign.Status),
+		Rules:  []models.RuleExplanation{},
+	}}, nil
+}
+
+// explainCampaign evaluates each of a campaign's rules. Rules are OR-ed, so
+// the campaign matches when any of its live rules does, unless it blocks one
+// of the request's content categories; shadow rules are evaluated and
+// reported without affecting the outcome.
+func explainCampaign(campaign *models.Campaign, rules []*models.TargetingRule, dimensions []models.Dimension, categories []string, at time.Time) *models.CampaignExplanation {
+	explanation := &models.CampaignExplanation{
+		CID:    campaign.ID,
+		Status: campaign.Status,
+		Rules:  make([]models.RuleExplanation, 0, len(rules)),
+	}
+	live := 0
+	for _, rule := range rules {
+		if !rule.Shadow {
+			live++
+		}
+	}
+	if live == 0 {
+		explanation.Matched = true
+		explanation.Reason = "campaign has no targeting rules"
+	}
+
+	for _, rule := range rules {
+		result := models.RuleExplanation{RuleID: rule.ID, Shadow: rule.Shadow}
+		compiled, err := compileRule(rule)
+		if err != nil {
+			result.Reason = fmt.Sprintf("invalid rule: %v", err)
+		} else {
+			result.Dimension, result.Reason = compiled.explain(dimensions, at)
+			result.Matched = result.Reason == ""
+		}
+		if !rule.Shadow {
+			explanation.Matched = explanation.Matched || result.Matched
+		}
+		explanation.Rules = append(explanation.Rules, result)
+	}
+	if !explanation.Matched {
+		explanation.Reason = "no targeting rule matched"
+	}
+	if category := campaign.BlockedCategory(categories); category != "" {
+		explanation.Matched = false
+		explanation.Reason = fmt.Sprintf("content category %q is blocked by the campaign", category)
+	}
+	return explanation
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+)
+
+// MaxImportCampaigns bounds the number of campaigns accepted in one import
+const MaxImportCampaigns = 10 * MaxBulkCampaigns
+
+// ExportCampaigns calls fn with each of the tenant's campaigns matching
+// filter, newest first, together with its targeting rules. Campaigns are read
+// a page at a time so exports of any size run in bounded memory; the limit
+// and offset of filter are ignored. It stops at the first error from fn.
+func (s *TargetingService) ExportCampaigns(ctx context.Context, filter repository.CampaignFilter, fn func(*models.CampaignExport) error) error {
+	if err := normalizeCampaignFilter(ctx, &filter); err != nil {
+		return err
+	}
+	filter.Limit = MaxListLimit
+
+	for filter.Offset = 0; ; filter.Offset += filter.Limit {
+		campaigns, _, err := s.repo.Campaign().ListCampaigns(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to list campaigns: %w", err)
+		}
+
+		for _, campaign := range campaigns {
+			rules, err := s.repo.TargetingRule().GetTargetingRulesByCampaignID(ctx, campaign.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get targeting rules: %w", err)
+			}
+			if err := fn(&models.CampaignExport{Campaign: campaign, Rules: rules}); err != nil {
+				return err
+			}
+		}
+
+		if len(campaigns) < filter.Limit {
+			return nil
+		}
+	}
+}
+
+// ImportCampaigns validates every item and upserts the valid ones: new
+// campaigns are created atomically with their targeting rules, while existing
+// campaigns of the tenant are replaced by the imported ones and get their
+// targeting rules swapped for the imported set. Every item is reported
+// individually.
+func (s *TargetingService) ImportCampaigns(ctx context.Context, items []*models.BulkCampaignRequest) ([]*models.BulkCampaignResult, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: no campaigns supplied", ErrInvalidCampaign)
+	}
+	if len(items) > MaxImportCampaigns {
+		return nil, fmt.Errorf("%w: at most %d campaigns can be imported at once", ErrInvalidCampaign, MaxImportCampaigns)
+	}
+
+	results := make([]*models.BulkCampaignResult, len(items))
+	seen := make(map[string]bool, len(items))
+	written := false
+
+	for i, item := range items {
+		result := &models.BulkCampaignResult{CID: strings.TrimSpace(item.ID)}
+		results[i] = result
+
+		campaign, rules, err := s.prepareBulkItem(ctx, item, seen)
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+
+		existing, err := s.getCampaign(ctx, campaign.ID)
+		switch {
+		case err == nil:
+			campaign.CreatedAt = existing.CreatedAt
+			err = s.replaceCampaign(ctx, campaign, rules)
+			result.Action = models.ImportUpdated
+		case errors.Is(err, repository.ErrNotFound):
+			err = s.repo.Campaign().BulkCreateCampaigns(ctx, []*models.Campaign{campaign}, rules)
+			if err != nil {
+				err = fmt.Errorf("failed to create campaign: %w", err)
+			}
+			result.Action = models.ImportCreated
+		default:
+			err = fmt.Errorf("failed to get campaign: %w", err)
+		}
+		if err != nil {
+			result.Action = ""
+			result.Error = err.Error()
+			continue
+		}
+
+		result.Success = true
+		written = true
+		s.notifyWrite(campaign, result.Action == models.ImportCreated)
+	}
+
+	if written {
+		s.refreshAfterWrite()
+	}
+	return results, nil
+}
+
+// replaceCampaign overwrites a stored campaign and swaps its targeting rules
+// for rules in one repository transaction, so the campaign is never left
+// with only part of its new rules. Repositories without transactions apply
+// the steps one by one; if one fails there, importing the campaign again
+// repairs it.
+func (s *TargetingService) replaceCampaign(ctx context.Context, campaign *models.Campaign, rules []*models.TargetingRule) error {
+	return repository.WithTransaction(ctx, s.repo, func(ctx context.Context) error {
+		if err := s.repo.Campaign().UpdateCampaign(ctx, campaign); err != nil {
+			return fmt.Errorf("failed to update campaign: %w", err)
+		}
+		if err := s.repo.TargetingRule().DeleteTargetingRulesByCampaignID(ctx, campaign.ID); err != nil {
+			return fmt.Errorf("failed to delete targeting rules: %w", err)
+		}
+		for _, rule := range rules {
+			if err := s.repo.TargetingRule().CreateTargetingRule(ctx, rule); err != nil {
+				return fmt.Errorf("failed to create targeting rule: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+const (
+	// MaxExpressionDepth bounds the nesting of a rule's expression
+	MaxExpressionDepth = 10
+	// MaxExpressionNodes bounds the number of nodes of a rule's expression
+	MaxExpressionNodes = 100
+)
+
+// compiledExpression is a targeting expression with the values of its leaves
+// compiled into matchers
+type compiledExpression struct {
+	and       []*compiledExpression
+	or        []*compiledExpression
+	not       *compiledExpression
+	dimension string
+	values    []valueMatcher
+}
+
+// matches evaluates the expression against the request dimensions.
+// Dimensions the request doesn't carry are matched as an empty value, and a
+// leaf matches when any of the request's values does, such as any of the
+// user's segments.
+func (e *compiledExpression) matches(dimensions []models.Dimension) bool {
+	switch {
+	case e.and != nil:
+		for _, child := range e.and {
+			if !child.matches(dimensions) {
+				return false
+			}
+		}
+		return true
+	case e.or != nil:
+		for _, child := range e.or {
+			if child.matches(dimensions) {
+				return true
+			}
+		}
+		return false
+	case e.not != nil:
+		return !e.not.matches(dimensions)
+	}
+	values := requestValues(dimensions, e.dimension)
+	if e.dimension == segmentDimension {
+		values = dimensionValues(dimensions, segmentDimension)
+	}
+	for _, value := range values {
+		for _, match := range e.values {
+			if match(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compileExpression checks the shape and limits of an expression and
+// compiles it. Errors name the offending node by its path, such as or[1].and[0].
+func compileExpression(expr *models.Expression) (*compiledExpression, error) {
+	nodes := 0
+	return compileExpressionNode(expr, "expression", 1, &nodes)
+}
+
+// compileExpressionNode compiles the node at path, counting it and its
+// descendants in nodes
+func compileExpressionNode(expr *models.Expression, path string, depth int, nodes *int) (*compiledExpression, error) {
+	if expr == nil {
+		return nil, fmt.Errorf("%s: is empty", path)
+	}
+	if depth > MaxExpressionDepth {
+		return nil, fmt.Errorf("%s: nested deeper than %d levels", path, MaxExpressionDepth)
+	}
+	if *nodes++; *nodes > MaxExpressionNodes {
+		return nil, fmt.Errorf("expression has more than %d nodes", MaxExpressionNodes)
+	}
+
+	forms := 0
+	for _, set := range []bool{expr.And != nil, expr.Or != nil, expr.Not != nil, expr.Dimension != ""} {
+		if set {
+			forms++
+		}
+	}
+	if forms != 1 {
+		return nil, fmt.Errorf("%s: must set exactly one of and, or, not and dimension", path)
+	}
+
+	children := func(name string, exprs []*models.Expression) ([]*compiledExpression, error) {
+		if len(exprs) == 0 {
+			return nil, fmt.Errorf("%s.%s: must not be empty", path, name)
+		}
+		compiled := make([]*compiledExpression, len(exprs))
+		for i, child := range exprs {
+			var err error
+			if compiled[i], err = compileExpressionNode(child, fmt.Sprintf("%s.%s[%d]", path, name, i), depth+1, nodes); err != nil {
+				return nil, err
+			}
+		}
+		return compiled, nil
+	}
+
+	compiled := &compiledExpression{}
+	var err error
+	switch {
+	case expr.And != nil:
+		compiled.and, err = children("and", expr.And)
+	case expr.Or != nil:
+		compiled.or, err = children("or", expr.Or)
+	case expr.Not != nil:
+		compiled.not, err = compileExpressionNode(expr.Not, path+".not", depth+1, nodes)
+	default:
+		if expr.Dimension == appVersionDimension {
+			return nil, fmt.Errorf("%s: %s can't be used in expressions, use the rule's app_version", path, appVersionDimension)
+		}
+		if len(expr.Values) == 0 {
+			return nil, fmt.Errorf("%s: values must not be empty", path)
+		}
+		compiled.dimension = expr.Dimension
+		if compiled.values, err = compileValues(expr.Operator, expr.Values, caseSensitiveDimensions[expr.Dimension]); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
+
+// walkExpression calls fn for every leaf of an expression
+func walkExpression(expr *models.Expression, fn func(leaf *models.Expression)) {
+	if expr == nil {
+		return
+	}
+	for _, child := range expr.And {
+		walkExpression(child, fn)
+	}
+	for _, child := range expr.Or {
+		walkExpression(child, fn)
+	}
+	walkExpression(expr.Not, fn)
+	if expr.Dimension != "" {
+		fn(expr)
+	}
+}
+
+// normalizeExpression normalizes the dimension names of an expression's
+// leaves, and exactly matched regions and cities the way requests are
+// normalized
+func normalizeExpression(expr *models.Expression) {
+	walkExpression(expr, func(leaf *models.Expression) {
+		leaf.Dimension = strings.ToLower(strings.TrimSpace(leaf.Dimension))
+		if leaf.Operator != "" && leaf.Operator != models.OperatorExact {
+			return
+		}
+		for i, value := range leaf.Values {
+			switch leaf.Dimension {
+			case "region":
+				leaf.Values[i] = strings.ToUpper(strings.TrimSpace(value))
+			case "city":
+				leaf.Values[i] = normalizeCity(value)
+			}
+		}
+	})
+}
+
+// validateExpressionDimensions checks that every leaf of an expression
+// targets a built-in or registered custom dimension, and that exactly
+// matched regions are ISO 3166-2 codes
+func (s *TargetingService) validateExpressionDimensions(expr *models.Expression) error {
+	registry := s.dimensions.Load()
+	var err error
+	walkExpression(expr, func(leaf *models.Expression) {
+		if err != nil {
+			return
+		}
+		switch {
+		case !isBuiltinDimension(leaf.Dimension) && !registry.registered(leaf.Dimension):
+			err = fmt.Errorf("%w: dimension %q in expression is not registered", ErrInvalidRule, leaf.Dimension)
+		case leaf.Dimension == "region" && (leaf.Operator == "" || leaf.Operator == models.OperatorExact):
+			for _, region := range leaf.Values {
+				if !strings.Contains(region, "-") {
+					err = fmt.Errorf("%w: region %q is not an ISO 3166-2 code such as US-CA", ErrInvalidRule, region)
+					break
+				}
+			}
+		}
+	})
+	return err
+}
+
+// requiredValues returns, for each indexed dimension, exact values of which
+// the request must carry one for the expression to match. It is
+// conservative: a dimension is only listed when every way of satisfying the
+// expression constrains it, so NOT and non-exact leaves constrain nothing.
+func requiredValues(expr *models.Expression) map[string][]string {
+	switch {
+	case expr == nil || expr.Not != nil:
+		return nil
+	case expr.And != nil:
+		// Any child's constraint holds for the whole conjunction
+		required := make(map[string][]string)
+		for _, child := range expr.And {
+			for name, values := range requiredValues(child) {
+				if _, ok := required[name]; !ok {
+					required[name] = values
+				}
+			}
+		}
+		return required
+	case expr.Or != nil:
+		// Only dimensions every alternative constrains, with their values
+		// combined
+		var required map[string][]string
+		for i, child := range expr.Or {
+			childRequired := requiredValues(child)
+			if i == 0 {
+				required = childRequired
+				continue
+			}
+			for name, values := range required {
+				if childValues, ok := childRequired[name]; ok {
+					required[name] = append(append([]string{}, values...), childValues...)
+				} else {
+					delete(required, name)
+				}
+			}
+		}
+		return required
+	}
+	if (expr.Operator != "" && expr.Operator != models.OperatorExact) || !slices.Contains(indexedDimensions, expr.Dimension) {
+		return nil
+	}
+	return map[string][]string{expr.Dimension: expr.Values}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// frequencyWindow is the period a frequency cap applies to
+const frequencyWindow = 24 * time.Hour
+
+// frequencyKey returns the counter key for a user's impressions of a campaign
+func frequencyKey(campaignID, userID, day string) string {
+	return fmt.Sprintf("freq:%s:%s:%s", campaignID, userID, day)
+}
+
+// allowImpression reports whether the user may be served the campaign under
+// its frequency cap and counts an impression when it may. When impressions
+// are counted as they are tracked, it only checks the count. Counter store
+// failures are logged and the campaign is served anyway.
+func (s *TargetingService) allowImpression(ctx context.Context, userID, day string, match *models.DeliveryResponse) bool {
+	limit := match.FrequencyCap()
+	if userID == "" || s.counters == nil || limit <= 0 {
+		return true
+	}
+
+	key := frequencyKey(match.CID, userID, day)
+	if s.config.Tracking.CountImpressions {
+		count, err := s.counters.Get(ctx, key)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to read frequency counter", "campaign_id", match.CID, "error", err)
+			return true
+		}
+		return count < int64(limit)
+	}
+
+	count, err := s.counters.Increment(ctx, key, frequencyWindow)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to increment frequency counter", "campaign_id", match.CID, "error", err)
+		return true
+	}
+	return count <= int64(limit)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/buildinfo"
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+)
+
+// readinessTimeout bounds the dependency checks of a single readiness probe
+const readinessTimeout = 2 * time.Second
+
+// healthChecker is implemented by repositories that can report whether their
+// backing store is reachable
+type healthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// breakerReporter is implemented by repositories guarded by a circuit breaker
+type breakerReporter interface {
+	BreakerState() repository.BreakerState
+}
+
+// CheckReadiness reports whether the service can serve delivery traffic: the
+// repository must be reachable and the targeting cache loaded at least once.
+// In serve-stale mode an unreachable repository is tolerated until the cache
+// has been stale for longer than the maximum staleness.
+// checks holds "ok" or the failure reason for each dependency, plus the
+// repository's circuit breaker state when it has one.
+func (s *TargetingService) CheckReadiness(ctx context.Context) (checks map[string]string, ready bool) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	checks = make(map[string]string)
+	ready = true
+
+	checks["repository"] = "ok"
+	if checker, ok := s.repo.(healthChecker); ok {
+		if err := checker.Health(ctx); err != nil {
+			checks["repository"] = err.Error()
+			// In serve-stale mode delivery goes on from the loaded cache
+			// until it is too stale, which the cache check reports
+			if !s.config.Cache.ServeStale.Enabled || !s.Warm() {
+				ready = false
+			}
+		}
+	}
+
+	// The breaker state is informational: while it is open, delivery is
+	// still served from the targeting cache
+	if reporter, ok := s.repo.(breakerReporter); ok {
+		checks["circuit_breaker"] = reporter.BreakerState().String()
+	}
+
+	// Serving could resume against a stored kill switch until it is loaded
+	checks["kill_switches"] = "ok"
+	if s.killSwitches.Load() == nil {
+		checks["kill_switches"] = "kill switches have not been loaded yet"
+		ready = false
+	}
+
+	checks["cache"] = "ok"
+	if !s.Warm() {
+		checks["cache"] = "targeting cache has not been loaded yet"
+		ready = false
+	} else if staleness := s.Staleness(); staleness > 0 {
+		checks["cache"] = fmt.Sprintf("refreshes have failed for %s", staleness.Round(time.Second))
+		if err := s.checkStaleness(); err != nil {
+			checks["cache"] = err.Error()
+			ready = false
+		}
+	}
+
+	return checks, ready
+}
+
+// Health reports the state of the process and its dependencies: how long a
+// ping of the repository takes, and the age and last refresh error of the
+// targeting cache. Unlike CheckReadiness it never fails; a failing
+// dependency or an unloaded cache makes the status degraded.
+func (s *TargetingService) Health(ctx context.Context) *models.HealthDetail {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	now := time.Now()
+	detail := &models.HealthDetail{
+		Status:        "ok",
+		Timestamp:     now.UTC(),
+		UptimeSeconds: now.Sub(s.started).Seconds(),
+		Goroutines:    runtime.NumGoroutine(),
+		Build:         buildinfo.Get(),
+		Dependencies:  make(map[string]models.DependencyHealth),
+	}
+
+	repo := models.DependencyHealth{Status: "ok"}
+	if checker, ok := s.repo.(healthChecker); ok {
+		started := time.Now()
+		err := checker.Health(ctx)
+		repo.LatencyMS = float64(time.Since(started).Microseconds()) / 1000
+		if err != nil {
+			repo.Status, repo.Error = "error", err.Error()
+			detail.Status = "degraded"
+		}
+	}
+	if reporter, ok := s.repo.(breakerReporter); ok {
+		repo.CircuitBreaker = reporter.BreakerState().String()
+	}
+	detail.Dependencies["repository"] = repo
+
+	snap := s.cache.snapshot.Load()
+	detail.Cache = models.CacheHealth{Loaded: snap.loaded(), Campaigns: len(snap.campaigns)}
+	for _, rules := range snap.targetingRules {
+		detail.Cache.TargetingRules += len(rules)
+	}
+	for _, rules := range snap.shadowRules {
+		detail.Cache.TargetingRules += len(rules)
+	}
+	if snap.loaded() {
+		lastUpdate := snap.lastUpdate.UTC()
+		detail.Cache.LastRefresh = &lastUpdate
+		detail.Cache.AgeSeconds = now.Sub(lastUpdate).Seconds()
+	} else {
+		detail.Status = "degraded"
+	}
+
+	s.mutex.RLock()
+	if s.lastRefreshError != nil {
+		detail.Cache.LastRefreshError = s.lastRefreshError.Error()
+	}
+	detail.Cache.StaleSeconds = s.stalenessLocked().Seconds()
+	s.mutex.RUnlock()
+	return detail
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// ErrInvalidIdempotencyKey is returned for an idempotency key that is too long
+var ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+
+// ErrIdempotencyKeyReused is returned when an idempotency key is sent again
+// with a different request
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+// ErrIdempotencyKeyInProgress is returned while the first request made with
+// an idempotency key is still running
+var ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is in progress")
+
+const (
+	// MaxIdempotencyKeyLength bounds client supplied idempotency keys
+	MaxIdempotencyKeyLength = 255
+	defaultIdempotencyTTL   = 24 * time.Hour
+)
+
+// CreateCampaignIdempotent creates a campaign once per idempotency key.
+// Repeating the request with the same key returns the campaign created the
+// first time, with replayed set, until the key expires. An empty key creates
+// the campaign unconditionally.
+func (s *TargetingService) CreateCampaignIdempotent(ctx context.Context, key string, req *models.CampaignRequest) (campaign *models.Campaign, replayed bool, err error) {
+	return idempotent(ctx, s, "campaign", key, req, func() (*models.Campaign, error) {
+		return s.CreateCampaign(ctx, req)
+	})
+}
+
+// CreateTargetingRuleIdempotent creates a targeting rule once per
+// idempotency key, like CreateCampaignIdempotent
+func (s *TargetingService) CreateTargetingRuleIdempotent(ctx context.Context, key string, rule *models.TargetingRule) (created *models.TargetingRule, replayed bool, err error) {
+	return idempotent(ctx, s, "target", key, rule, func() (*models.TargetingRule, error) {
+		return s.CreateTargetingRule(ctx, rule)
+	})
+}
+
+// CloneCampaignIdempotent clones a campaign once per idempotency key, like
+// CreateCampaignIdempotent. A key is bound to the source campaign as well as
+// the overrides.
+func (s *TargetingService) CloneCampaignIdempotent(ctx context.Context, key, id string, req *models.CampaignRequest) (clone *models.CampaignClone, replayed bool, err error) {
+	fingerprinted := struct {
+		Source string                  `json:"source"`
+		Req    *models.CampaignRequest `json:"request"`
+	}{id, req}
+	return idempotent(ctx, s, "clone", key, fingerprinted, func() (*models.CampaignClone, error) {
+		return s.CloneCampaign(ctx, id, req)
+	})
+}
+
+// idempotent runs create unless an earlier request with the same key and
+// operation, made by the same tenant, already has, in which case its stored
+// result is returned. The key is released when create fails, so the request
+// can be retried.
+func idempotent[T any](ctx context.Context, s *TargetingService, operation, key string, req any, create func() (*T, error)) (*T, bool, error) {
+	if key == "" {
+		result, err := create()
+		return result, false, err
+	}
+	if len(key) > MaxIdempotencyKeyLength {
+		return nil, false, fmt.Errorf("%w: must be at most %d characters", ErrInvalidIdempotencyKey, MaxIdempotencyKeyLength)
+	}
+
+	// Hash the request before create runs, as it fills in generated fields
+	fingerprint, err := requestFingerprint(req)
+	if err != nil {
+		return nil, false, err
+	}
+	ttl := s.config.Idempotency.TTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	record := &repository.IdempotencyRecord{
+		Key:         tenant.FromContext(ctx) + "/" + operation + "/" + key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(ttl).UTC(),
+	}
+
+	store := s.repo.Idempotency()
+	held, err := store.ReserveIdempotencyKey(ctx, record)
+	if err != nil {
+		return nil, false, err
+	}
+	if held != nil {
+		switch {
+		case held.Fingerprint != fingerprint:
+			return nil, false, ErrIdempotencyKeyReused
+		case held.Result == nil:
+			return nil, false, ErrIdempotencyKeyInProgress
+		}
+		var result T
+		if err := json.Unmarshal(held.Result, &result); err != nil {
+			return nil, false, fmt.Errorf("failed to decode idempotent result: %w", err)
+		}
+		return &result, true, nil
+	}
+
+	result, err := create()
+	if err != nil {
+		if releaseErr := store.ReleaseIdempotencyKey(ctx, record.Key); releaseErr != nil {
+			slog.ErrorContext(ctx, "failed to release idempotency key", "key", key, "error", releaseErr)
+		}
+		return nil, false, err
+	}
+
+	// The write has happened, so a failure to store its result is logged
+	// rather than returned; retries then see the key as in progress until it
+	// expires
+	encoded, err := json.Marshal(result)
+	if err == nil {
+		err = store.CompleteIdempotencyKey(ctx, record.Key, encoded)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to store idempotent result", "key", key, "error", err)
+	}
+	return result, false, nil
+}
+
+// requestFingerprint hashes the JSON encoding of a request
+func requestFingerprint(req any) (string, error) {
+	encoded, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"maps"
+	"strings"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// indexedDimensions lists the request dimensions the campaign index is keyed
+// by, every delivery dimension
+var indexedDimensions = models.DeliveryDimensions
+
+// campaignIndex is an inverted index from request dimension values to the
+// campaigns that may match them. It narrows the campaigns a request has to be
+// evaluated against; candidates are still checked with their compiled rules.
+type campaignIndex struct {
+	dimensions map[string]*dimensionIndex
+	// indexed records which dimensions and values each campaign was added
+	// under so it can be removed again
+	indexed map[string][]indexEntry
+}
+
+// dimensionIndex holds the campaigns that may match a single dimension
+type dimensionIndex struct {
+	// values maps an exact include value, case folded where the dimension
+	// is case-insensitive, to the campaigns including it
+	values map[string]map[string]struct{}
+	// open holds campaigns that can't be narrowed by value: those with no
+	// include list for the dimension or one using a non-exact operator
+	open map[string]struct{}
+}
+
+// indexEntry is one position a campaign occupies in the index. An empty
+// value with open set refers to the open set of the dimension.
+type indexEntry struct {
+	dimension string
+	value     string
+	open      bool
+}
+
+// newCampaignIndex creates an empty index
+func newCampaignIndex() *campaignIndex {
+	idx := &campaignIndex{
+		dimensions: make(map[string]*dimensionIndex, len(indexedDimensions)),
+		indexed:    make(map[string][]indexEntry),
+	}
+	for _, name := range indexedDimensions {
+		idx.dimensions[name] = &dimensionIndex{
+			values: make(map[string]map[string]struct{}),
+			open:   make(map[string]struct{}),
+		}
+	}
+	return idx
+}
+
+// clone returns a deep copy of the index that can be modified without
+// affecting readers of the original
+func (idx *campaignIndex) clone() *campaignIndex {
+	c := &campaignIndex{
+		dimensions: make(map[string]*dimensionIndex, len(idx.dimensions)),
+		indexed:    maps.Clone(idx.indexed),
+	}
+	for name, dim := range idx.dimensions {
+		values := make(map[string]map[string]struct{}, len(dim.values))
+		for value, set := range dim.values {
+			values[value] = maps.Clone(set)
+		}
+		c.dimensions[name] = &dimensionIndex{values: values, open: maps.Clone(dim.open)}
+	}
+	return c
+}
+
+// add indexes a campaign under its compiled rules. A campaign without rules
+// matches every request and is open on every dimension; one whose rules all
+// failed to compile never matches and is left out. Rules are OR-ed, so the
+// campaign is a candidate for a value when any of its rules may accept it.
+// A dimension without an exact include list is narrowed by the values the
+// rule's expression requires, if any.
+func (idx *campaignIndex) add(campaignID string, hasRules bool, rules []*compiledRule) {
+	idx.remove(campaignID)
+
+	var entries []indexEntry
+	if !hasRules {
+		for _, name := range indexedDimensions {
+			entries = append(entries, indexEntry{dimension: name, open: true})
+		}
+	}
+
+	for _, rule := range rules {
+		values := ruleDimensionValues(rule.rule)
+		required := requiredValues(rule.rule.Expression)
+		for _, name := range indexedDimensions {
+			include := values[name][0]
+			operator := rule.rule.Operators[name]
+			if len(include) == 0 || (operator != models.OperatorExact && operator != "") {
+				include = required[name]
+			}
+			if len(include) == 0 {
+				entries = append(entries, indexEntry{dimension: name, open: true})
+				continue
+			}
+			for _, value := range include {
+				entries = append(entries, indexEntry{dimension: name, value: foldDimension(name, value)})
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		dim := idx.dimensions[entry.dimension]
+		if entry.open {
+			dim.open[campaignID] = struct{}{}
+			continue
+		}
+		set, ok := dim.values[entry.value]
+		if !ok {
+			set = make(map[string]struct{})
+			dim.values[entry.value] = set
+		}
+		set[campaignID] = struct{}{}
+	}
+	if len(entries) > 0 {
+		idx.indexed[campaignID] = entries
+	}
+}
+
+// remove drops a campaign from the index
+func (idx *campaignIndex) remove(campaignID string) {
+	for _, entry := range idx.indexed[campaignID] {
+		dim := idx.dimensions[entry.dimension]
+		if entry.open {
+			delete(dim.open, campaignID)
+			continue
+		}
+		if set, ok := dim.values[entry.value]; ok {
+			delete(set, campaignID)
+			if len(set) == 0 {
+				delete(dim.values, entry.value)
+			}
+		}
+	}
+	delete(idx.indexed, campaignID)
+}
+
+// candidates returns the campaigns that may match the request dimensions by
+// intersecting the per-dimension sets, starting from the smallest. Dimensions
+// the request doesn't carry are looked up as an empty value, as in matching,
+// and those it carries several values of are looked up under each.
+func (idx *campaignIndex) candidates(dimensions []models.Dimension) []string {
+	type lookup struct {
+		exact []map[string]struct{}
+		open  map[string]struct{}
+		size  int
+	}
+
+	lookups := make([]lookup, 0, len(indexedDimensions))
+	smallest := -1
+	for _, name := range indexedDimensions {
+		dim := idx.dimensions[name]
+		l := lookup{open: dim.open, size: len(dim.open)}
+		for _, value := range requestValues(dimensions, name) {
+			if set := dim.values[foldDimension(name, value)]; len(set) > 0 {
+				l.exact = append(l.exact, set)
+				l.size += len(set)
+			}
+		}
+		lookups = append(lookups, l)
+		if smallest < 0 || l.size < lookups[smallest].size {
+			smallest = len(lookups) - 1
+		}
+	}
+
+	contains := func(l lookup, id string) bool {
+		for _, set := range l.exact {
+			if _, ok := set[id]; ok {
+				return true
+			}
+		}
+		_, ok := l.open[id]
+		return ok
+	}
+
+	var ids []string
+	seen := make(map[string]struct{})
+	driver := lookups[smallest]
+	for _, set := range append(driver.exact, driver.open) {
+		for id := range set {
+			if _, dup := seen[id]; dup {
+				continue
+			}
+			seen[id] = struct{}{}
+
+			matched := true
+			for i, l := range lookups {
+				if i != smallest && !contains(l, id) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// foldDimension normalizes a value the way exact matching compares it
+func foldDimension(name, value string) string {
+	if caseSensitiveDimensions[name] {
+		return value
+	}
+	return strings.ToLower(value)
+}
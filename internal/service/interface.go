@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+)
+
+//go:generate mockgen -destination=mocks/targeting.go -package=mocks . Targeting
+
+// Targeting is the targeting service as the HTTP and gRPC handlers use it.
+// TargetingService implements it; handlers can be tested against the mock
+// in the mocks package instead.
+type Targeting interface {
+	GetMatchingCampaigns(ctx context.Context, req *models.DeliveryRequest) ([]*models.DeliveryResponse, error)
+	GetMatchingCampaignsBatch(ctx context.Context, batch *models.BatchDeliveryRequest) ([]*models.BatchDeliveryResult, error)
+	SelectCampaign(ctx context.Context, req *models.DeliveryRequest, strategy string) (*models.DeliveryResponse, error)
+	ExplainDelivery(ctx context.Context, req *models.DeliveryRequest, campaignID string, at time.Time) ([]*models.CampaignExplanation, error)
+	TrackEvent(ctx context.Context, event *models.TrackingEvent) error
+
+	ListCampaigns(ctx context.Context, filter repository.CampaignFilter) (*models.CampaignList, error)
+	SearchCampaigns(ctx context.Context, search repository.CampaignSearch) (*models.CampaignSearchResult, error)
+	CreateCampaignIdempotent(ctx context.Context, key string, req *models.CampaignRequest) (*models.Campaign, bool, error)
+	CloneCampaignIdempotent(ctx context.Context, key, id string, req *models.CampaignRequest) (*models.CampaignClone, bool, error)
+	BulkCreateCampaigns(ctx context.Context, items []*models.BulkCampaignRequest) ([]*models.BulkCampaignResult, error)
+	UpdateCampaign(ctx context.Context, id string, req *models.CampaignRequest) (*models.Campaign, error)
+	UpdateCampaignStatus(ctx context.Context, id, status string) (*models.Campaign, error)
+	DeleteCampaign(ctx context.Context, id string, hard bool) error
+	ExportCampaigns(ctx context.Context, filter repository.CampaignFilter, fn func(*models.CampaignExport) error) error
+	ImportCampaigns(ctx context.Context, items []*models.BulkCampaignRequest) ([]*models.BulkCampaignResult, error)
+	CampaignStats(ctx context.Context, campaignID, from, to string) (*models.CampaignStatsReport, error)
+	CampaignTracking(ctx context.Context, id, day string) (*models.CampaignTracking, error)
+	CampaignView(ctx context.Context, id string) (*models.CampaignView, error)
+
+	ListTargetingRules(ctx context.Context, campaignID string) ([]*models.TargetingRule, error)
+	CreateTargetingRuleIdempotent(ctx context.Context, key string, rule *models.TargetingRule) (*models.TargetingRule, bool, error)
+	UpdateTargetingRule(ctx context.Context, id int64, rule *models.TargetingRule) (*models.TargetingRule, error)
+	DeleteTargetingRule(ctx context.Context, id int64) error
+	AnalyzeTargetingRule(ctx context.Context, rule *models.TargetingRule) (*models.RuleAnalysis, error)
+	EstimateReach(ctx context.Context, req *models.ReachRequest) (*models.ReachEstimate, error)
+
+	EngageKillSwitch(ctx context.Context, req *models.KillSwitchRequest, actor string) (*models.KillSwitch, error)
+	ReleaseKillSwitch(ctx context.Context, req *models.KillSwitchRequest, actor string) error
+	KillSwitches(ctx context.Context) *models.KillSwitchList
+
+	SubscribeCampaignChanges(ctx context.Context, lastEventID string) (<-chan *models.CampaignChange, error)
+
+	RefreshCache() (*models.CacheRefreshResult, error)
+	GetCacheStats() map[string]interface{}
+	TrafficStats() *models.TrafficStats
+	Decisions(ctx context.Context, requestID string, limit int) (*models.DecisionList, error)
+	ServingStale() bool
+	CheckReadiness(ctx context.Context) (map[string]string, bool)
+	Health(ctx context.Context) *models.HealthDetail
+}
+
+var _ Targeting = (*TargetingService)(nil)
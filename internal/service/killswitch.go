@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// ErrInvalidKillSwitch is returned for kill switch requests that fail
+// validation
+var ErrInvalidKillSwitch = errors.New("invalid kill switch request")
+
+// DefaultKillSwitchPollInterval is how often kill switches are reloaded when
+// killSwitch.pollInterval is unset
+const DefaultKillSwitchPollInterval = 10 * time.Second
+
+// killSwitchState is the kill switches in effect on this instance, by scope.
+// stored holds the switches loaded from the repository; unsaved holds those
+// engaged here that failed to be stored, which stay engaged until released
+// here so a repository outage can't undo them.
+type killSwitchState struct {
+	stored  map[string]*models.KillSwitch
+	unsaved map[string]*models.KillSwitch
+}
+
+// engaged returns the switch with scope, or nil
+func (k *killSwitchState) engaged(scope string) *models.KillSwitch {
+	if sw, ok := k.unsaved[scope]; ok {
+		return sw
+	}
+	return k.stored[scope]
+}
+
+// killed reports whether a kill switch stops serving the campaigns of the
+// tenant. Until the switches are loaded for the first time none is engaged;
+// the service isn't ready meanwhile.
+func (s *TargetingService) killed(tenantID string) bool {
+	state := s.killSwitches.Load()
+	if state == nil {
+		return false
+	}
+	return state.engaged(models.KillSwitchScope(true, "")) != nil ||
+		state.engaged(models.KillSwitchScope(false, tenantID)) != nil
+}
+
+// updateKillSwitches publishes a copy of the kill switch state changed by fn
+func (s *TargetingService) updateKillSwitches(fn func(state *killSwitchState)) {
+	s.killSwitchMutex.Lock()
+	defer s.killSwitchMutex.Unlock()
+
+	next := &killSwitchState{stored: map[string]*models.KillSwitch{}, unsaved: map[string]*models.KillSwitch{}}
+	if current := s.killSwitches.Load(); current != nil {
+		next.stored = maps.Clone(current.stored)
+		next.unsaved = maps.Clone(current.unsaved)
+	}
+	fn(next)
+	s.killSwitches.Store(next)
+}
+
+// EngageKillSwitch stops serving the campaigns of every tenant, or of the
+// tenant ctx acts for, on this instance at once and stores the switch so
+// other instances pick it up and restarts honor it. When storing fails the
+// switch stays engaged on this instance only and the error is returned.
+func (s *TargetingService) EngageKillSwitch(ctx context.Context, req *models.KillSwitchRequest, actor string) (*models.KillSwitch, error) {
+	if err := validateKillSwitchRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	sw := &models.KillSwitch{
+		Global:    req.Global,
+		Reason:    strings.TrimSpace(req.Reason),
+		EngagedBy: actor,
+		EngagedAt: time.Now().UTC(),
+	}
+	if !sw.Global {
+		sw.TenantID = tenant.FromContext(ctx)
+	}
+	scope := sw.Scope()
+
+	s.updateKillSwitches(func(state *killSwitchState) { state.unsaved[scope] = sw })
+	slog.WarnContext(ctx, "kill switch engaged", "audit", true, "scope", scope, "actor", actor, "reason", sw.Reason)
+
+	if err := s.repo.KillSwitch().EngageKillSwitch(ctx, sw); err != nil {
+		slog.ErrorContext(ctx, "failed to store kill switch", "scope", scope, "error", err)
+		return nil, fmt.Errorf("kill switch is engaged on this instance only, failed to store it: %w", err)
+	}
+	s.updateKillSwitches(func(state *killSwitchState) {
+		delete(state.unsaved, scope)
+		state.stored[scope] = sw
+	})
+	return sw, nil
+}
+
+// ReleaseKillSwitch resumes serving the campaigns stopped by the kill switch
+// of every tenant, or of the tenant ctx acts for. The switch is removed from
+// the repository first; if that fails it stays engaged.
+func (s *TargetingService) ReleaseKillSwitch(ctx context.Context, req *models.KillSwitchRequest, actor string) error {
+	if err := validateKillSwitchRequest(ctx, req); err != nil {
+		return err
+	}
+	scope := models.KillSwitchScope(req.Global, tenant.FromContext(ctx))
+
+	if err := s.repo.KillSwitch().ReleaseKillSwitch(ctx, scope); err != nil {
+		return fmt.Errorf("failed to release kill switch: %w", err)
+	}
+	s.updateKillSwitches(func(state *killSwitchState) {
+		delete(state.unsaved, scope)
+		delete(state.stored, scope)
+	})
+	slog.WarnContext(ctx, "kill switch released", "audit", true, "scope", scope, "actor", actor, "reason", strings.TrimSpace(req.Reason))
+	return nil
+}
+
+// validateKillSwitchRequest checks a kill switch request. Only requests
+// acting for the default tenant may switch every tenant, so a tenant can't
+// stop the campaigns of others.
+func validateKillSwitchRequest(ctx context.Context, req *models.KillSwitchRequest) error {
+	if err := validation.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidKillSwitch, err)
+	}
+	if req.Global && tenant.FromContext(ctx) != "" {
+		return fmt.Errorf("%w: %w", ErrInvalidKillSwitch, validation.Invalid("global", "requires acting for the default tenant"))
+	}
+	return nil
+}
+
+// KillSwitches lists the kill switches engaged on this instance, the global
+// one first. Requests acting for a tenant only see the switches that apply to
+// it; those acting for the default tenant see every switch.
+func (s *TargetingService) KillSwitches(ctx context.Context) *models.KillSwitchList {
+	list := &models.KillSwitchList{KillSwitches: []*models.KillSwitch{}}
+	state := s.killSwitches.Load()
+	if state == nil {
+		return list
+	}
+
+	scopes := []string{models.KillSwitchScope(true, ""), models.KillSwitchScope(false, tenant.FromContext(ctx))}
+	if tenant.FromContext(ctx) == "" {
+		engaged := maps.Clone(state.stored)
+		maps.Copy(engaged, state.unsaved)
+		scopes = slices.Sorted(maps.Keys(engaged))
+	}
+	for _, scope := range scopes {
+		if sw := state.engaged(scope); sw != nil {
+			list.KillSwitches = append(list.KillSwitches, sw)
+		}
+	}
+	return list
+}
+
+// loadKillSwitches replaces the stored kill switches with those in the
+// repository, picking up the ones engaged and released on other instances
+func (s *TargetingService) loadKillSwitches(ctx context.Context) error {
+	switches, err := s.repo.KillSwitch().GetKillSwitches(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load kill switches: %w", err)
+	}
+
+	stored := make(map[string]*models.KillSwitch, len(switches))
+	for _, sw := range switches {
+		stored[sw.Scope()] = sw
+	}
+	s.updateKillSwitches(func(state *killSwitchState) {
+		for scope := range stored {
+			if _, ok := state.stored[scope]; !ok {
+				slog.Warn("kill switch engaged", "scope", scope)
+			}
+		}
+		for scope := range state.stored {
+			if _, ok := stored[scope]; !ok {
+				slog.Warn("kill switch released", "scope", scope)
+			}
+		}
+		state.stored = stored
+	})
+	return nil
+}
+
+// startKillSwitchWorker loads the kill switches at startup and reloads them
+// every poll interval
+func (s *TargetingService) startKillSwitchWorker() {
+	interval := s.config.KillSwitch.PollInterval
+	if interval <= 0 {
+		interval = DefaultKillSwitchPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.loadKillSwitches(context.Background()); err != nil {
+			slog.Error("failed to reload kill switches", "error", err)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// ErrInvalidTransition is returned when a campaign can't move from its
+// status to the one requested
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// DefaultLifecycleInterval is how often flight dates are checked when
+// lifecycle.interval is unset
+const DefaultLifecycleInterval = time.Minute
+
+// statusTransitions lists the statuses a campaign may be moved to from each
+// status. COMPLETED is final, and an archived campaign can be restored to any
+// status but COMPLETED.
+var statusTransitions = map[string][]string{
+	models.StatusDraft:     {models.StatusScheduled, models.StatusActive},
+	models.StatusScheduled: {models.StatusDraft, models.StatusActive, models.StatusPaused},
+	models.StatusActive:    {models.StatusPaused, models.StatusCompleted},
+	models.StatusPaused:    {models.StatusActive, models.StatusCompleted},
+	models.StatusInactive:  {models.StatusActive, models.StatusPaused, models.StatusCompleted},
+	models.StatusCompleted: {},
+	models.StatusArchived:  {models.StatusDraft, models.StatusScheduled, models.StatusActive, models.StatusPaused},
+}
+
+// advancingStatuses are the statuses flight dates move campaigns out of
+var advancingStatuses = []string{models.StatusScheduled, models.StatusActive, models.StatusPaused, models.StatusInactive}
+
+// UpdateCampaignStatus moves a campaign to status, enforcing the lifecycle.
+// Setting the status of an archived campaign restores it.
+func (s *TargetingService) UpdateCampaignStatus(ctx context.Context, id, status string) (*models.Campaign, error) {
+	if status == "" {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCampaign, validation.Invalid("status", "is required"))
+	}
+	return s.UpdateCampaign(ctx, id, &models.CampaignRequest{Status: status})
+}
+
+// transitionStatus moves campaign to status as of now if its lifecycle
+// allows it. The caller applies the transitions flight dates trigger
+// afterwards, so activating a campaign whose flight hasn't started schedules
+// it instead.
+func transitionStatus(campaign *models.Campaign, status string, now time.Time) error {
+	if status == models.StatusInactive {
+		status = models.StatusPaused
+	}
+	from := campaign.Status
+	if status == from || (status == models.StatusPaused && campaign.IsPaused()) {
+		return nil
+	}
+	if !slices.Contains(statusTransitions[from], status) {
+		return fmt.Errorf("%w: can't move a campaign from %s to %s", ErrInvalidTransition, from, status)
+	}
+
+	if status == models.StatusActive || status == models.StatusScheduled {
+		if campaign.EndAt != nil && !now.Before(*campaign.EndAt) {
+			return fmt.Errorf("%w: %w", ErrInvalidCampaign, validation.Invalid("end_at", "has passed, so the campaign can't run"))
+		}
+	}
+	campaign.Status = status
+	campaign.DeletedAt = nil
+	return nil
+}
+
+// validateFlight checks the flight dates of a campaign and that a scheduled
+// campaign has a start
+func validateFlight(campaign *models.Campaign) error {
+	if campaign.StartAt != nil && campaign.EndAt != nil && !campaign.EndAt.After(*campaign.StartAt) {
+		return fmt.Errorf("%w: %w", ErrInvalidCampaign, validation.Invalid("end_at", "must be after start_at"))
+	}
+	if campaign.Status == models.StatusScheduled && campaign.StartAt == nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCampaign, validation.Invalid("start_at", "is required to schedule a campaign"))
+	}
+	return nil
+}
+
+// advanceStatus applies the transitions flight dates trigger as of now and
+// reports whether the status changed. A scheduled campaign starts once its
+// start passes, and an active one whose start lies ahead is scheduled again.
+// Scheduled, active and paused campaigns complete once their end passes.
+func advanceStatus(campaign *models.Campaign, now time.Time) bool {
+	status := campaign.Status
+	started := campaign.StartAt == nil || !now.Before(*campaign.StartAt)
+	switch {
+	case slices.Contains(advancingStatuses, status) && campaign.EndAt != nil && !now.Before(*campaign.EndAt):
+		status = models.StatusCompleted
+	case status == models.StatusScheduled && started:
+		status = models.StatusActive
+	case status == models.StatusActive && !started:
+		status = models.StatusScheduled
+	}
+	if status == campaign.Status {
+		return false
+	}
+	campaign.Status = status
+	return true
+}
+
+// startLifecycleWorker applies the status transitions flight dates trigger
+// every lifecycle interval
+func (s *TargetingService) startLifecycleWorker() {
+	interval := s.config.Lifecycle.Interval
+	if interval <= 0 {
+		interval = DefaultLifecycleInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.advanceCampaigns(context.Background()); err != nil {
+			slog.Error("failed to advance campaign statuses", "error", err)
+		}
+	}
+}
+
+// advanceCampaigns applies the status transitions flight dates trigger to
+// the campaigns of every tenant. Campaigns outside their flight aren't
+// delivered in the meantime, whatever their status.
+func (s *TargetingService) advanceCampaigns(ctx context.Context) error {
+	campaigns, err := s.repo.Campaign().GetCampaignsByStatus(ctx, advancingStatuses)
+	if err != nil {
+		return fmt.Errorf("failed to get campaigns: %w", err)
+	}
+
+	now := time.Now()
+	changed := false
+	var errs []error
+	for _, campaign := range campaigns {
+		next := *campaign
+		if !advanceStatus(&next, now) {
+			continue
+		}
+		if err := s.repo.Campaign().UpdateCampaignStatus(ctx, next.ID, next.Status); err != nil {
+			errs = append(errs, fmt.Errorf("failed to update status of campaign %s: %w", next.ID, err))
+			continue
+		}
+		slog.Info("campaign status advanced", "campaign_id", next.ID, "tenant_id", next.TenantID, "from", campaign.Status, "to", next.Status)
+		s.notifyWrite(&next, false)
+		changed = true
+	}
+	if changed {
+		s.refreshAfterWrite()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// lruShard is an LRU cache of delivery results with a per-entry TTL, making
+// up one shard of a queryCache
+type lruShard struct {
+	maxSize int
+	ttl     time.Duration
+	ll      *list.List
+	items   map[string]*list.Element
+	mutex   sync.Mutex
+}
+
+// queryCacheEntry is a single cached delivery result
+type queryCacheEntry struct {
+	key       string
+	value     []*models.DeliveryResponse
+	expiresAt time.Time
+}
+
+// newLRUShard creates an LRU cache holding at most maxSize entries, each
+// valid for ttl. A non-positive maxSize or ttl disables that bound.
+func newLRUShard(maxSize int, ttl time.Duration) *lruShard {
+	return &lruShard{
+		maxSize: maxSize,
+		ttl:     ttl,
+		ll:      list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached value for key, dropping it if it has expired
+func (c *lruShard) Get(key string) ([]*models.DeliveryResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, exists := c.items[key]
+	if !exists {
+		return nil, false
+	}
+
+	entry := elem.Value.(*queryCacheEntry)
+	if c.expired(entry, time.Now()) {
+		c.removeElement(elem)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Contains reports whether key holds an unexpired entry, without marking it
+// as recently used
+func (c *lruShard) Contains(key string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, exists := c.items[key]
+	return exists && !c.expired(elem.Value.(*queryCacheEntry), time.Now())
+}
+
+// Set stores value under key, evicting the least recently used entry if full
+func (c *lruShard) Set(key string, value []*models.DeliveryResponse) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = time.Now().Add(c.ttl)
+	}
+
+	if elem, exists := c.items[key]; exists {
+		entry := elem.Value.(*queryCacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	elem := c.ll.PushFront(&queryCacheEntry{key: key, value: value, expiresAt: expiresAt})
+	c.items[key] = elem
+
+	if c.maxSize > 0 && c.ll.Len() > c.maxSize {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// RemoveExpired drops every expired entry and returns how many were removed
+func (c *lruShard) RemoveExpired() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for elem := c.ll.Back(); elem != nil; {
+		prev := elem.Prev()
+		if c.expired(elem.Value.(*queryCacheEntry), now) {
+			c.removeElement(elem)
+			removed++
+		}
+		elem = prev
+	}
+	return removed
+}
+
+// Resize changes the size bound and TTL. Entries beyond the new size are
+// evicted, least recently used first; entries already cached keep their
+// expiry and the new TTL applies from their next Set.
+func (c *lruShard) Resize(maxSize int, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.maxSize = maxSize
+	c.ttl = ttl
+	for c.maxSize > 0 && c.ll.Len() > c.maxSize {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Purge removes all entries
+func (c *lruShard) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// Len returns the number of cached entries, including expired ones not yet removed
+func (c *lruShard) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *lruShard) expired(entry *queryCacheEntry, now time.Time) bool {
+	return !entry.expiresAt.IsZero() && now.After(entry.expiresAt)
+}
+
+func (c *lruShard) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*queryCacheEntry).key)
+}
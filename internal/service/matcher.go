@@ -0,0 +1,437 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/semver"
+)
+
+// caseSensitiveDimensions lists the dimensions whose values are compared
+// case-sensitively. Country is normalized to upper case before matching.
+var caseSensitiveDimensions = map[string]bool{
+	"country":        true,
+	"app":            true,
+	segmentDimension: true,
+}
+
+// valueMatcher reports whether a request value matches one rule value
+type valueMatcher func(value string) bool
+
+// dimensionMatcher holds the compiled include and exclude lists of a dimension
+type dimensionMatcher struct {
+	include []valueMatcher
+	exclude []valueMatcher
+	// includeFilter and excludeFilter pre-check long exact lists, so values
+	// they rule out skip the scan; nil for other lists
+	includeFilter *bloomFilter
+	excludeFilter *bloomFilter
+	// caseSensitive is false when values are folded to lower case before
+	// the filters are checked
+	caseSensitive bool
+}
+
+// dimensionResult is the outcome of checking a value against a dimension
+type dimensionResult int
+
+const (
+	dimensionMatched dimensionResult = iota
+	dimensionExcluded
+	dimensionNotIncluded
+)
+
+// checkAny checks the values a request carries of a dimension, applying
+// exclusions first: any excluded value excludes the request, and any
+// included one includes it. An empty include list matches everything.
+func (m *dimensionMatcher) checkAny(values []string) dimensionResult {
+	for _, value := range values {
+		if m.mayContain(m.excludeFilter, value) && anyMatches(m.exclude, value) {
+			return dimensionExcluded
+		}
+	}
+
+	if len(m.include) == 0 {
+		return dimensionMatched
+	}
+	for _, value := range values {
+		if m.mayContain(m.includeFilter, value) && anyMatches(m.include, value) {
+			return dimensionMatched
+		}
+	}
+	return dimensionNotIncluded
+}
+
+// mayContain reports whether a list pre-checked by filter may match value.
+// Lists without a filter may match anything.
+func (m *dimensionMatcher) mayContain(filter *bloomFilter, value string) bool {
+	if filter == nil {
+		return true
+	}
+	if !m.caseSensitive {
+		value = strings.ToLower(value)
+	}
+	return filter.contains(value)
+}
+
+// anyMatches reports whether any of matchers matches value
+func anyMatches(matchers []valueMatcher, value string) bool {
+	for _, match := range matchers {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether values pass the dimension
+func (m *dimensionMatcher) matches(values []string) bool {
+	return m.checkAny(values) == dimensionMatched
+}
+
+// explain returns why values fail the dimension, or an empty string if they match
+func (m *dimensionMatcher) explain(values []string) string {
+	result := m.checkAny(values)
+	if len(values) == 1 {
+		switch result {
+		case dimensionExcluded:
+			return fmt.Sprintf("value %q is excluded", values[0])
+		case dimensionNotIncluded:
+			return fmt.Sprintf("value %q is not included", values[0])
+		}
+		return ""
+	}
+	switch result {
+	case dimensionExcluded:
+		return fmt.Sprintf("one of values %q is excluded", values)
+	case dimensionNotIncluded:
+		return fmt.Sprintf("none of values %q is included", values)
+	default:
+		return ""
+	}
+}
+
+// compiledRule is a targeting rule with its values compiled into matchers
+type compiledRule struct {
+	rule       *models.TargetingRule
+	dimensions map[string]*dimensionMatcher
+	schedule   *compiledSchedule   // nil when the rule serves at any time
+	appVersion *semver.Constraint  // nil when the rule allows any app version
+	expression *compiledExpression // nil when the rule has no expression
+	segments   *dimensionMatcher   // nil when the rule doesn't target segments
+}
+
+// matches checks the rule against the request dimensions and the time of the
+// request. Dimensions the request doesn't carry are matched as an empty
+// value, and those it carries several values of as described by checkAny.
+func (c *compiledRule) matches(dimensions []models.Dimension, now time.Time) bool {
+	if !c.rule.InEffect(now) {
+		return false
+	}
+	if c.schedule != nil && !c.schedule.allows(now) {
+		return false
+	}
+	return c.matchesDimensions(dimensions)
+}
+
+// matchesDimensions checks the rule against the request dimensions alone,
+// whatever the time
+func (c *compiledRule) matchesDimensions(dimensions []models.Dimension) bool {
+	if c.appVersion != nil && c.explainAppVersion(dimensions) != "" {
+		return false
+	}
+	for name, matcher := range c.dimensions {
+		if !matcher.matches(requestValues(dimensions, name)) {
+			return false
+		}
+	}
+	if c.segments != nil && c.segments.checkAny(dimensionValues(dimensions, segmentDimension)) != dimensionMatched {
+		return false
+	}
+	return c.expression == nil || c.expression.matches(dimensions)
+}
+
+// explain returns the first dimension the request fails on and why,
+// checking the effective window, schedule and app version first, then
+// built-in dimensions in indexedDimensions order, custom dimensions by name
+// and segments, and the expression last. Both are empty when the rule
+// matches.
+func (c *compiledRule) explain(dimensions []models.Dimension, now time.Time) (string, string) {
+	if !c.rule.InEffect(now) {
+		return "effective", c.explainEffective(now)
+	}
+	if c.schedule != nil && !c.schedule.allows(now) {
+		return "schedule", fmt.Sprintf("outside schedule at %s", now.In(c.schedule.location).Format("Mon 15:04 MST"))
+	}
+	if c.appVersion != nil {
+		if reason := c.explainAppVersion(dimensions); reason != "" {
+			return appVersionDimension, reason
+		}
+	}
+	names := append([]string{}, indexedDimensions...)
+	names = append(names, sortedDimensionNames(c.rule.Custom)...)
+	for _, name := range names {
+		matcher, ok := c.dimensions[name]
+		if !ok {
+			continue
+		}
+		if reason := matcher.explain(requestValues(dimensions, name)); reason != "" {
+			return name, reason
+		}
+	}
+	if c.segments != nil {
+		segments := dimensionValues(dimensions, segmentDimension)
+		switch c.segments.checkAny(segments) {
+		case dimensionExcluded:
+			return segmentDimension, fmt.Sprintf("user segments %q include one of the excluded %q", segments, c.rule.ExcludeSegment)
+		case dimensionNotIncluded:
+			return segmentDimension, fmt.Sprintf("user segments %q include none of %q", segments, c.rule.IncludeSegment)
+		}
+	}
+	if c.expression != nil && !c.expression.matches(dimensions) {
+		return "expression", fmt.Sprintf("expression %s is false", c.rule.Expression)
+	}
+	return "", ""
+}
+
+// explainEffective returns why the rule isn't in effect at now
+func (c *compiledRule) explainEffective(now time.Time) string {
+	if from := c.rule.EffectiveFrom; from != nil && now.Before(*from) {
+		return fmt.Sprintf("rule takes effect at %s", from.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("rule stopped applying at %s", c.rule.EffectiveUntil.UTC().Format(time.RFC3339))
+}
+
+// explainAppVersion returns why the request's app version fails the rule's
+// constraint, or an empty string if it satisfies it
+func (c *compiledRule) explainAppVersion(dimensions []models.Dimension) string {
+	value := dimensionValue(dimensions, appVersionDimension)
+	if value == "" {
+		return fmt.Sprintf("no app version to check against %q", c.appVersion)
+	}
+	version, err := semver.Parse(value)
+	if err != nil {
+		return err.Error()
+	}
+	if !c.appVersion.Check(version) {
+		return fmt.Sprintf("version %q does not satisfy %q", value, c.appVersion)
+	}
+	return ""
+}
+
+// dimensionValue returns the request value of a dimension, or an empty string
+func dimensionValue(dimensions []models.Dimension, name string) string {
+	for _, d := range dimensions {
+		if d.Name == name {
+			return d.Value
+		}
+	}
+	return ""
+}
+
+// dimensionValues returns every request value of a dimension carried several
+// times, such as segment or a built-in dimension with further values
+func dimensionValues(dimensions []models.Dimension, name string) []string {
+	var values []string
+	for _, d := range dimensions {
+		if d.Name == name {
+			values = append(values, d.Value)
+		}
+	}
+	return values
+}
+
+// requestValues returns every request value of a dimension, or a single
+// empty value when the request doesn't carry it
+func requestValues(dimensions []models.Dimension, name string) []string {
+	if values := dimensionValues(dimensions, name); len(values) > 0 {
+		return values
+	}
+	return []string{""}
+}
+
+// compileRule compiles every constrained dimension of a rule using the
+// operator configured for that dimension
+func compileRule(rule *models.TargetingRule) (*compiledRule, error) {
+	compiled := &compiledRule{
+		rule:       rule,
+		dimensions: make(map[string]*dimensionMatcher),
+	}
+
+	if err := validateEffective(rule); err != nil {
+		return nil, err
+	}
+
+	if rule.Schedule != nil {
+		schedule, err := compileSchedule(rule.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("schedule: %w", err)
+		}
+		compiled.schedule = schedule
+	}
+
+	if rule.AppVersion != "" {
+		constraint, err := semver.ParseConstraint(rule.AppVersion)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", appVersionDimension, err)
+		}
+		compiled.appVersion = constraint
+	}
+
+	if rule.Expression != nil {
+		expression, err := compileExpression(rule.Expression)
+		if err != nil {
+			return nil, err
+		}
+		compiled.expression = expression
+	}
+
+	if len(rule.IncludeSegment) > 0 || len(rule.ExcludeSegment) > 0 {
+		// Segment IDs are always matched exactly
+		segments, err := compileDimension(models.OperatorExact, rule.IncludeSegment, rule.ExcludeSegment, caseSensitiveDimensions[segmentDimension])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", segmentDimension, err)
+		}
+		compiled.segments = segments
+	}
+
+	for name := range rule.Custom {
+		if isBuiltinDimension(name) {
+			return nil, fmt.Errorf("dimension %q is built in and can't be targeted as custom", name)
+		}
+	}
+
+	values := ruleDimensionValues(rule)
+	for name := range rule.Operators {
+		if _, known := values[name]; !known {
+			return nil, fmt.Errorf("unknown dimension %q in operators", name)
+		}
+	}
+
+	for name, lists := range values {
+		include, exclude := lists[0], lists[1]
+		if len(include) == 0 && len(exclude) == 0 {
+			continue
+		}
+
+		matcher, err := compileDimension(rule.Operators[name], include, exclude, caseSensitiveDimensions[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		compiled.dimensions[name] = matcher
+	}
+
+	return compiled, nil
+}
+
+// ruleDimensionValues returns the include and exclude lists of a rule keyed
+// by dimension name, built-in and custom
+func ruleDimensionValues(rule *models.TargetingRule) map[string][2][]string {
+	values := make(map[string][2][]string, len(indexedDimensions)+len(rule.Custom))
+	for _, name := range indexedDimensions {
+		include, exclude := rule.DimensionLists(name)
+		values[name] = [2][]string{include, exclude}
+	}
+	for name := range rule.Custom {
+		if _, builtin := values[name]; !builtin {
+			include, exclude := rule.DimensionLists(name)
+			values[name] = [2][]string{include, exclude}
+		}
+	}
+	return values
+}
+
+// compileDimension compiles the include and exclude lists of a dimension
+// using the given operator. Exact lists of at least bloomMinValues values get
+// a Bloom filter, so values missing from them are ruled out without scanning
+// the list.
+func compileDimension(operator string, include, exclude []string, caseSensitive bool) (*dimensionMatcher, error) {
+	matcher := &dimensionMatcher{caseSensitive: caseSensitive}
+	var err error
+	if matcher.include, err = compileValues(operator, include, caseSensitive); err != nil {
+		return nil, err
+	}
+	if matcher.exclude, err = compileValues(operator, exclude, caseSensitive); err != nil {
+		return nil, err
+	}
+	if operator == models.OperatorExact || operator == "" {
+		matcher.includeFilter = compileFilter(include, caseSensitive)
+		matcher.excludeFilter = compileFilter(exclude, caseSensitive)
+	}
+	return matcher, nil
+}
+
+// compileFilter builds a Bloom filter of exact values folded like
+// compileValue folds them, or nil for lists too short to benefit
+func compileFilter(values []string, caseSensitive bool) *bloomFilter {
+	if len(values) < bloomMinValues {
+		return nil
+	}
+	if !caseSensitive {
+		folded := make([]string, len(values))
+		for i, v := range values {
+			folded[i] = strings.ToLower(v)
+		}
+		values = folded
+	}
+	return newBloomFilter(values)
+}
+
+// compileValues builds a matcher for each value using the given operator
+func compileValues(operator string, values []string, caseSensitive bool) ([]valueMatcher, error) {
+	matchers := make([]valueMatcher, 0, len(values))
+	for _, v := range values {
+		matcher, err := compileValue(operator, v, caseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+// compileValue builds a matcher for a single rule value
+func compileValue(operator, pattern string, caseSensitive bool) (valueMatcher, error) {
+	fold := func(s string) string { return s }
+	if !caseSensitive {
+		fold = strings.ToLower
+	}
+	folded := fold(pattern)
+
+	switch operator {
+	case models.OperatorExact, "":
+		return func(value string) bool { return fold(value) == folded }, nil
+	case models.OperatorPrefix:
+		return func(value string) bool { return strings.HasPrefix(fold(value), folded) }, nil
+	case models.OperatorSuffix:
+		return func(value string) bool { return strings.HasSuffix(fold(value), folded) }, nil
+	case models.OperatorWildcard:
+		return compileRegex(wildcardToRegex(pattern), caseSensitive)
+	case models.OperatorRegex:
+		return compileRegex(pattern, caseSensitive)
+	default:
+		return nil, fmt.Errorf("unknown operator %q", operator)
+	}
+}
+
+// compileRegex compiles an anchored-as-written regular expression
+func compileRegex(pattern string, caseSensitive bool) (valueMatcher, error) {
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re.MatchString, nil
+}
+
+// wildcardToRegex converts a glob using * and ? into an anchored regular expression
+func wildcardToRegex(pattern string) string {
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return "^" + quoted + "$"
+}
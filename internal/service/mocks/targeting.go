@@ -0,0 +1,562 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Harshi-itaSinha/target-engine/internal/service (interfaces: Targeting)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mocks/targeting.go -package=mocks . Targeting
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	repository "github.com/Harshi-itaSinha/target-engine/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTargeting is a mock of Targeting interface.
+type MockTargeting struct {
+	ctrl     *gomock.Controller
+	recorder *MockTargetingMockRecorder
+	isgomock struct{}
+}
+
+// MockTargetingMockRecorder is the mock recorder for MockTargeting.
+type MockTargetingMockRecorder struct {
+	mock *MockTargeting
+}
+
+// NewMockTargeting creates a new mock instance.
+func NewMockTargeting(ctrl *gomock.Controller) *MockTargeting {
+	mock := &MockTargeting{ctrl: ctrl}
+	mock.recorder = &MockTargetingMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTargeting) EXPECT() *MockTargetingMockRecorder {
+	return m.recorder
+}
+
+// AnalyzeTargetingRule mocks base method.
+func (m *MockTargeting) AnalyzeTargetingRule(ctx context.Context, rule *model.TargetingRule) (*model.RuleAnalysis, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnalyzeTargetingRule", ctx, rule)
+	ret0, _ := ret[0].(*model.RuleAnalysis)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AnalyzeTargetingRule indicates an expected call of AnalyzeTargetingRule.
+func (mr *MockTargetingMockRecorder) AnalyzeTargetingRule(ctx, rule any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnalyzeTargetingRule", reflect.TypeOf((*MockTargeting)(nil).AnalyzeTargetingRule), ctx, rule)
+}
+
+// BulkCreateCampaigns mocks base method.
+func (m *MockTargeting) BulkCreateCampaigns(ctx context.Context, items []*model.BulkCampaignRequest) ([]*model.BulkCampaignResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkCreateCampaigns", ctx, items)
+	ret0, _ := ret[0].([]*model.BulkCampaignResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkCreateCampaigns indicates an expected call of BulkCreateCampaigns.
+func (mr *MockTargetingMockRecorder) BulkCreateCampaigns(ctx, items any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkCreateCampaigns", reflect.TypeOf((*MockTargeting)(nil).BulkCreateCampaigns), ctx, items)
+}
+
+// CampaignStats mocks base method.
+func (m *MockTargeting) CampaignStats(ctx context.Context, campaignID, from, to string) (*model.CampaignStatsReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CampaignStats", ctx, campaignID, from, to)
+	ret0, _ := ret[0].(*model.CampaignStatsReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CampaignStats indicates an expected call of CampaignStats.
+func (mr *MockTargetingMockRecorder) CampaignStats(ctx, campaignID, from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CampaignStats", reflect.TypeOf((*MockTargeting)(nil).CampaignStats), ctx, campaignID, from, to)
+}
+
+// CampaignTracking mocks base method.
+func (m *MockTargeting) CampaignTracking(ctx context.Context, id, day string) (*model.CampaignTracking, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CampaignTracking", ctx, id, day)
+	ret0, _ := ret[0].(*model.CampaignTracking)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CampaignTracking indicates an expected call of CampaignTracking.
+func (mr *MockTargetingMockRecorder) CampaignTracking(ctx, id, day any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CampaignTracking", reflect.TypeOf((*MockTargeting)(nil).CampaignTracking), ctx, id, day)
+}
+
+// CampaignView mocks base method.
+func (m *MockTargeting) CampaignView(ctx context.Context, id string) (*model.CampaignView, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CampaignView", ctx, id)
+	ret0, _ := ret[0].(*model.CampaignView)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CampaignView indicates an expected call of CampaignView.
+func (mr *MockTargetingMockRecorder) CampaignView(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CampaignView", reflect.TypeOf((*MockTargeting)(nil).CampaignView), ctx, id)
+}
+
+// CheckReadiness mocks base method.
+func (m *MockTargeting) CheckReadiness(ctx context.Context) (map[string]string, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckReadiness", ctx)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// CheckReadiness indicates an expected call of CheckReadiness.
+func (mr *MockTargetingMockRecorder) CheckReadiness(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckReadiness", reflect.TypeOf((*MockTargeting)(nil).CheckReadiness), ctx)
+}
+
+// CloneCampaignIdempotent mocks base method.
+func (m *MockTargeting) CloneCampaignIdempotent(ctx context.Context, key, id string, req *model.CampaignRequest) (*model.CampaignClone, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloneCampaignIdempotent", ctx, key, id, req)
+	ret0, _ := ret[0].(*model.CampaignClone)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CloneCampaignIdempotent indicates an expected call of CloneCampaignIdempotent.
+func (mr *MockTargetingMockRecorder) CloneCampaignIdempotent(ctx, key, id, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloneCampaignIdempotent", reflect.TypeOf((*MockTargeting)(nil).CloneCampaignIdempotent), ctx, key, id, req)
+}
+
+// CreateCampaignIdempotent mocks base method.
+func (m *MockTargeting) CreateCampaignIdempotent(ctx context.Context, key string, req *model.CampaignRequest) (*model.Campaign, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCampaignIdempotent", ctx, key, req)
+	ret0, _ := ret[0].(*model.Campaign)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateCampaignIdempotent indicates an expected call of CreateCampaignIdempotent.
+func (mr *MockTargetingMockRecorder) CreateCampaignIdempotent(ctx, key, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCampaignIdempotent", reflect.TypeOf((*MockTargeting)(nil).CreateCampaignIdempotent), ctx, key, req)
+}
+
+// CreateTargetingRuleIdempotent mocks base method.
+func (m *MockTargeting) CreateTargetingRuleIdempotent(ctx context.Context, key string, rule *model.TargetingRule) (*model.TargetingRule, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTargetingRuleIdempotent", ctx, key, rule)
+	ret0, _ := ret[0].(*model.TargetingRule)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateTargetingRuleIdempotent indicates an expected call of CreateTargetingRuleIdempotent.
+func (mr *MockTargetingMockRecorder) CreateTargetingRuleIdempotent(ctx, key, rule any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTargetingRuleIdempotent", reflect.TypeOf((*MockTargeting)(nil).CreateTargetingRuleIdempotent), ctx, key, rule)
+}
+
+// Decisions mocks base method.
+func (m *MockTargeting) Decisions(ctx context.Context, requestID string, limit int) (*model.DecisionList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Decisions", ctx, requestID, limit)
+	ret0, _ := ret[0].(*model.DecisionList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Decisions indicates an expected call of Decisions.
+func (mr *MockTargetingMockRecorder) Decisions(ctx, requestID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Decisions", reflect.TypeOf((*MockTargeting)(nil).Decisions), ctx, requestID, limit)
+}
+
+// DeleteCampaign mocks base method.
+func (m *MockTargeting) DeleteCampaign(ctx context.Context, id string, hard bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCampaign", ctx, id, hard)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCampaign indicates an expected call of DeleteCampaign.
+func (mr *MockTargetingMockRecorder) DeleteCampaign(ctx, id, hard any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCampaign", reflect.TypeOf((*MockTargeting)(nil).DeleteCampaign), ctx, id, hard)
+}
+
+// DeleteTargetingRule mocks base method.
+func (m *MockTargeting) DeleteTargetingRule(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTargetingRule", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTargetingRule indicates an expected call of DeleteTargetingRule.
+func (mr *MockTargetingMockRecorder) DeleteTargetingRule(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTargetingRule", reflect.TypeOf((*MockTargeting)(nil).DeleteTargetingRule), ctx, id)
+}
+
+// EngageKillSwitch mocks base method.
+func (m *MockTargeting) EngageKillSwitch(ctx context.Context, req *model.KillSwitchRequest, actor string) (*model.KillSwitch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EngageKillSwitch", ctx, req, actor)
+	ret0, _ := ret[0].(*model.KillSwitch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EngageKillSwitch indicates an expected call of EngageKillSwitch.
+func (mr *MockTargetingMockRecorder) EngageKillSwitch(ctx, req, actor any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EngageKillSwitch", reflect.TypeOf((*MockTargeting)(nil).EngageKillSwitch), ctx, req, actor)
+}
+
+// EstimateReach mocks base method.
+func (m *MockTargeting) EstimateReach(ctx context.Context, req *model.ReachRequest) (*model.ReachEstimate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EstimateReach", ctx, req)
+	ret0, _ := ret[0].(*model.ReachEstimate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EstimateReach indicates an expected call of EstimateReach.
+func (mr *MockTargetingMockRecorder) EstimateReach(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EstimateReach", reflect.TypeOf((*MockTargeting)(nil).EstimateReach), ctx, req)
+}
+
+// ExplainDelivery mocks base method.
+func (m *MockTargeting) ExplainDelivery(ctx context.Context, req *model.DeliveryRequest, campaignID string, at time.Time) ([]*model.CampaignExplanation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExplainDelivery", ctx, req, campaignID, at)
+	ret0, _ := ret[0].([]*model.CampaignExplanation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExplainDelivery indicates an expected call of ExplainDelivery.
+func (mr *MockTargetingMockRecorder) ExplainDelivery(ctx, req, campaignID, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExplainDelivery", reflect.TypeOf((*MockTargeting)(nil).ExplainDelivery), ctx, req, campaignID, at)
+}
+
+// ExportCampaigns mocks base method.
+func (m *MockTargeting) ExportCampaigns(ctx context.Context, filter repository.CampaignFilter, fn func(*model.CampaignExport) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportCampaigns", ctx, filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportCampaigns indicates an expected call of ExportCampaigns.
+func (mr *MockTargetingMockRecorder) ExportCampaigns(ctx, filter, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportCampaigns", reflect.TypeOf((*MockTargeting)(nil).ExportCampaigns), ctx, filter, fn)
+}
+
+// GetCacheStats mocks base method.
+func (m *MockTargeting) GetCacheStats() map[string]any {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCacheStats")
+	ret0, _ := ret[0].(map[string]any)
+	return ret0
+}
+
+// GetCacheStats indicates an expected call of GetCacheStats.
+func (mr *MockTargetingMockRecorder) GetCacheStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCacheStats", reflect.TypeOf((*MockTargeting)(nil).GetCacheStats))
+}
+
+// GetMatchingCampaigns mocks base method.
+func (m *MockTargeting) GetMatchingCampaigns(ctx context.Context, req *model.DeliveryRequest) ([]*model.DeliveryResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMatchingCampaigns", ctx, req)
+	ret0, _ := ret[0].([]*model.DeliveryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMatchingCampaigns indicates an expected call of GetMatchingCampaigns.
+func (mr *MockTargetingMockRecorder) GetMatchingCampaigns(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMatchingCampaigns", reflect.TypeOf((*MockTargeting)(nil).GetMatchingCampaigns), ctx, req)
+}
+
+// GetMatchingCampaignsBatch mocks base method.
+func (m *MockTargeting) GetMatchingCampaignsBatch(ctx context.Context, batch *model.BatchDeliveryRequest) ([]*model.BatchDeliveryResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMatchingCampaignsBatch", ctx, batch)
+	ret0, _ := ret[0].([]*model.BatchDeliveryResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMatchingCampaignsBatch indicates an expected call of GetMatchingCampaignsBatch.
+func (mr *MockTargetingMockRecorder) GetMatchingCampaignsBatch(ctx, batch any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMatchingCampaignsBatch", reflect.TypeOf((*MockTargeting)(nil).GetMatchingCampaignsBatch), ctx, batch)
+}
+
+// Health mocks base method.
+func (m *MockTargeting) Health(ctx context.Context) *model.HealthDetail {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Health", ctx)
+	ret0, _ := ret[0].(*model.HealthDetail)
+	return ret0
+}
+
+// Health indicates an expected call of Health.
+func (mr *MockTargetingMockRecorder) Health(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*MockTargeting)(nil).Health), ctx)
+}
+
+// ImportCampaigns mocks base method.
+func (m *MockTargeting) ImportCampaigns(ctx context.Context, items []*model.BulkCampaignRequest) ([]*model.BulkCampaignResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportCampaigns", ctx, items)
+	ret0, _ := ret[0].([]*model.BulkCampaignResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportCampaigns indicates an expected call of ImportCampaigns.
+func (mr *MockTargetingMockRecorder) ImportCampaigns(ctx, items any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportCampaigns", reflect.TypeOf((*MockTargeting)(nil).ImportCampaigns), ctx, items)
+}
+
+// KillSwitches mocks base method.
+func (m *MockTargeting) KillSwitches(ctx context.Context) *model.KillSwitchList {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KillSwitches", ctx)
+	ret0, _ := ret[0].(*model.KillSwitchList)
+	return ret0
+}
+
+// KillSwitches indicates an expected call of KillSwitches.
+func (mr *MockTargetingMockRecorder) KillSwitches(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KillSwitches", reflect.TypeOf((*MockTargeting)(nil).KillSwitches), ctx)
+}
+
+// ListCampaigns mocks base method.
+func (m *MockTargeting) ListCampaigns(ctx context.Context, filter repository.CampaignFilter) (*model.CampaignList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCampaigns", ctx, filter)
+	ret0, _ := ret[0].(*model.CampaignList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCampaigns indicates an expected call of ListCampaigns.
+func (mr *MockTargetingMockRecorder) ListCampaigns(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCampaigns", reflect.TypeOf((*MockTargeting)(nil).ListCampaigns), ctx, filter)
+}
+
+// ListTargetingRules mocks base method.
+func (m *MockTargeting) ListTargetingRules(ctx context.Context, campaignID string) ([]*model.TargetingRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTargetingRules", ctx, campaignID)
+	ret0, _ := ret[0].([]*model.TargetingRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTargetingRules indicates an expected call of ListTargetingRules.
+func (mr *MockTargetingMockRecorder) ListTargetingRules(ctx, campaignID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTargetingRules", reflect.TypeOf((*MockTargeting)(nil).ListTargetingRules), ctx, campaignID)
+}
+
+// RefreshCache mocks base method.
+func (m *MockTargeting) RefreshCache() (*model.CacheRefreshResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshCache")
+	ret0, _ := ret[0].(*model.CacheRefreshResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshCache indicates an expected call of RefreshCache.
+func (mr *MockTargetingMockRecorder) RefreshCache() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshCache", reflect.TypeOf((*MockTargeting)(nil).RefreshCache))
+}
+
+// ReleaseKillSwitch mocks base method.
+func (m *MockTargeting) ReleaseKillSwitch(ctx context.Context, req *model.KillSwitchRequest, actor string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseKillSwitch", ctx, req, actor)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseKillSwitch indicates an expected call of ReleaseKillSwitch.
+func (mr *MockTargetingMockRecorder) ReleaseKillSwitch(ctx, req, actor any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseKillSwitch", reflect.TypeOf((*MockTargeting)(nil).ReleaseKillSwitch), ctx, req, actor)
+}
+
+// SearchCampaigns mocks base method.
+func (m *MockTargeting) SearchCampaigns(ctx context.Context, search repository.CampaignSearch) (*model.CampaignSearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchCampaigns", ctx, search)
+	ret0, _ := ret[0].(*model.CampaignSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchCampaigns indicates an expected call of SearchCampaigns.
+func (mr *MockTargetingMockRecorder) SearchCampaigns(ctx, search any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchCampaigns", reflect.TypeOf((*MockTargeting)(nil).SearchCampaigns), ctx, search)
+}
+
+// SelectCampaign mocks base method.
+func (m *MockTargeting) SelectCampaign(ctx context.Context, req *model.DeliveryRequest, strategy string) (*model.DeliveryResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectCampaign", ctx, req, strategy)
+	ret0, _ := ret[0].(*model.DeliveryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectCampaign indicates an expected call of SelectCampaign.
+func (mr *MockTargetingMockRecorder) SelectCampaign(ctx, req, strategy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectCampaign", reflect.TypeOf((*MockTargeting)(nil).SelectCampaign), ctx, req, strategy)
+}
+
+// ServingStale mocks base method.
+func (m *MockTargeting) ServingStale() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ServingStale")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// ServingStale indicates an expected call of ServingStale.
+func (mr *MockTargetingMockRecorder) ServingStale() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ServingStale", reflect.TypeOf((*MockTargeting)(nil).ServingStale))
+}
+
+// SubscribeCampaignChanges mocks base method.
+func (m *MockTargeting) SubscribeCampaignChanges(ctx context.Context, lastEventID string) (<-chan *model.CampaignChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeCampaignChanges", ctx, lastEventID)
+	ret0, _ := ret[0].(<-chan *model.CampaignChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubscribeCampaignChanges indicates an expected call of SubscribeCampaignChanges.
+func (mr *MockTargetingMockRecorder) SubscribeCampaignChanges(ctx, lastEventID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeCampaignChanges", reflect.TypeOf((*MockTargeting)(nil).SubscribeCampaignChanges), ctx, lastEventID)
+}
+
+// TrackEvent mocks base method.
+func (m *MockTargeting) TrackEvent(ctx context.Context, event *model.TrackingEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrackEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TrackEvent indicates an expected call of TrackEvent.
+func (mr *MockTargetingMockRecorder) TrackEvent(ctx, event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrackEvent", reflect.TypeOf((*MockTargeting)(nil).TrackEvent), ctx, event)
+}
+
+// TrafficStats mocks base method.
+func (m *MockTargeting) TrafficStats() *model.TrafficStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrafficStats")
+	ret0, _ := ret[0].(*model.TrafficStats)
+	return ret0
+}
+
+// TrafficStats indicates an expected call of TrafficStats.
+func (mr *MockTargetingMockRecorder) TrafficStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrafficStats", reflect.TypeOf((*MockTargeting)(nil).TrafficStats))
+}
+
+// UpdateCampaign mocks base method.
+func (m *MockTargeting) UpdateCampaign(ctx context.Context, id string, req *model.CampaignRequest) (*model.Campaign, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCampaign", ctx, id, req)
+	ret0, _ := ret[0].(*model.Campaign)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateCampaign indicates an expected call of UpdateCampaign.
+func (mr *MockTargetingMockRecorder) UpdateCampaign(ctx, id, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCampaign", reflect.TypeOf((*MockTargeting)(nil).UpdateCampaign), ctx, id, req)
+}
+
+// UpdateCampaignStatus mocks base method.
+func (m *MockTargeting) UpdateCampaignStatus(ctx context.Context, id, status string) (*model.Campaign, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCampaignStatus", ctx, id, status)
+	ret0, _ := ret[0].(*model.Campaign)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateCampaignStatus indicates an expected call of UpdateCampaignStatus.
+func (mr *MockTargetingMockRecorder) UpdateCampaignStatus(ctx, id, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCampaignStatus", reflect.TypeOf((*MockTargeting)(nil).UpdateCampaignStatus), ctx, id, status)
+}
+
+// UpdateTargetingRule mocks base method.
+func (m *MockTargeting) UpdateTargetingRule(ctx context.Context, id int64, rule *model.TargetingRule) (*model.TargetingRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTargetingRule", ctx, id, rule)
+	ret0, _ := ret[0].(*model.TargetingRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTargetingRule indicates an expected call of UpdateTargetingRule.
+func (mr *MockTargetingMockRecorder) UpdateTargetingRule(ctx, id, rule any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTargetingRule", reflect.TypeOf((*MockTargeting)(nil).UpdateTargetingRule), ctx, id, rule)
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// selectCampaigns returns, in order, up to the request's limit of the
+// ordered matches that the user is bucketed into, are within budget and
+// their caps for the request's app, and pass the user's frequency caps,
+// each with its creative chosen. Impressions are only counted for the
+// campaigns returned, unless they are counted as they are tracked. A limit
+// of zero returns every allowed match.
+func (s *TargetingService) selectCampaigns(ctx context.Context, req *models.DeliveryRequest, matches []*models.DeliveryResponse) []*models.DeliveryResponse {
+	if len(matches) == 0 {
+		return matches
+	}
+
+	userID, limit := req.UserID, req.Limit
+	now := time.Now()
+	day := now.UTC().Format("20060102")
+	selected := make([]*models.DeliveryResponse, 0, len(matches))
+	for _, match := range matches {
+		if limit > 0 && len(selected) >= limit {
+			break
+		}
+		match, ok := assignExperiment(match, userID)
+		if !ok {
+			continue
+		}
+		if !s.withinBudget(ctx, match, day, now) || !s.withinAppCaps(ctx, match, req.App, day) || !s.allowImpression(ctx, userID, day, match) {
+			continue
+		}
+		if !s.config.Tracking.CountImpressions {
+			s.recordSpend(ctx, match, day)
+			s.recordAppImpression(ctx, match, req.App, day)
+		}
+		selected = append(selected, expandClickURL(ctx, req, s.issueTrackingToken(ctx, s.assignCreative(match), now), now))
+	}
+	return selected
+}
+
+// orderMatches returns a copy of matches in the order campaigns are tried
+// under a selection strategy. Round robin starts each request one campaign
+// further along the matches cached under its cache key.
+func (s *TargetingService) orderMatches(strategy, cacheKey string, matches []*models.DeliveryResponse) []*models.DeliveryResponse {
+	switch strategy {
+	case models.SelectionRandom:
+		ordered := slices.Clone(matches)
+		rand.Shuffle(len(ordered), func(i, j int) { ordered[i], ordered[j] = ordered[j], ordered[i] })
+		return ordered
+	case models.SelectionRoundRobin:
+		if len(matches) == 0 {
+			return matches
+		}
+		sorted := slices.SortedFunc(slices.Values(matches), func(a, b *models.DeliveryResponse) int {
+			return strings.Compare(a.CID, b.CID)
+		})
+		start := int(s.selections.next(cacheKey) % uint64(len(sorted)))
+		return slices.Concat(sorted[start:], sorted[:start])
+	default:
+		return orderCampaigns(matches)
+	}
+}
+
+// orderCampaigns returns a copy of matches sorted by priority, highest first.
+// Campaigns of equal priority are shuffled so that each one comes first with
+// a probability proportional to its weight. The input slice may be shared
+// through the query cache and is never modified.
+func orderCampaigns(matches []*models.DeliveryResponse) []*models.DeliveryResponse {
+	type ranked struct {
+		match *models.DeliveryResponse
+		key   float64
+	}
+
+	ranks := make([]ranked, len(matches))
+	for i, match := range matches {
+		weight := match.Weight()
+		if weight <= 0 {
+			weight = 1
+		}
+		// Weighted random sampling without replacement (Efraimidis-Spirakis)
+		ranks[i] = ranked{match: match, key: math.Pow(rand.Float64(), 1/float64(weight))}
+	}
+
+	sort.SliceStable(ranks, func(i, j int) bool {
+		if pi, pj := ranks[i].match.Priority(), ranks[j].match.Priority(); pi != pj {
+			return pi > pj
+		}
+		return ranks[i].key > ranks[j].key
+	})
+
+	ordered := make([]*models.DeliveryResponse, len(ranks))
+	for i, r := range ranks {
+		ordered[i] = r.match
+	}
+	return ordered
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"hash/maphash"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// queryCacheShards is the number of independently locked shards of the query
+// cache. Every lookup marks its entry as recently used, so even reads take a
+// shard's lock; spreading keys over shards keeps concurrent requests from
+// queueing on one lock.
+const queryCacheShards = 32
+
+// queryCache is an LRU cache of delivery results with a per-entry TTL,
+// sharded by key hash. Each shard evicts on its own, so entries are evicted
+// in approximately least recently used order.
+type queryCache struct {
+	seed   maphash.Seed
+	shards [queryCacheShards]*lruShard
+}
+
+// newQueryCache creates a cache holding about maxSize entries, each valid
+// for ttl. A non-positive maxSize or ttl disables that bound.
+func newQueryCache(maxSize int, ttl time.Duration) *queryCache {
+	c := &queryCache{seed: maphash.MakeSeed()}
+	for i := range c.shards {
+		c.shards[i] = newLRUShard(shardSize(maxSize), ttl)
+	}
+	return c
+}
+
+// shardSize splits maxSize over the shards, rounding up so that a small
+// cache still holds at least one entry per shard
+func shardSize(maxSize int) int {
+	if maxSize <= 0 {
+		return 0
+	}
+	return (maxSize + queryCacheShards - 1) / queryCacheShards
+}
+
+func (c *queryCache) shard(key string) *lruShard {
+	return c.shards[maphash.String(c.seed, key)%queryCacheShards]
+}
+
+// Get returns the cached value for key, dropping it if it has expired
+func (c *queryCache) Get(key string) ([]*models.DeliveryResponse, bool) {
+	return c.shard(key).Get(key)
+}
+
+// Contains reports whether key holds an unexpired entry, without marking it
+// as recently used
+func (c *queryCache) Contains(key string) bool {
+	return c.shard(key).Contains(key)
+}
+
+// Set stores value under key, evicting the least recently used entry of its
+// shard if full
+func (c *queryCache) Set(key string, value []*models.DeliveryResponse) {
+	c.shard(key).Set(key, value)
+}
+
+// RemoveExpired drops every expired entry and returns how many were removed
+func (c *queryCache) RemoveExpired() int {
+	removed := 0
+	for _, shard := range c.shards {
+		removed += shard.RemoveExpired()
+	}
+	return removed
+}
+
+// Resize changes the size bound and TTL of every shard
+func (c *queryCache) Resize(maxSize int, ttl time.Duration) {
+	for _, shard := range c.shards {
+		shard.Resize(shardSize(maxSize), ttl)
+	}
+}
+
+// Purge removes all entries
+func (c *queryCache) Purge() {
+	for _, shard := range c.shards {
+		shard.Purge()
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not yet removed
+func (c *queryCache) Len() int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.Len()
+	}
+	return n
+}
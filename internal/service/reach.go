@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/stats"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// MaxReachRules is the most rules a reach estimate may take
+const MaxReachRules = 50
+
+// EstimateReach estimates how many of the delivery requests recorded over
+// the reach window would have matched a set of proposed targeting rules,
+// without storing them. Rules are validated as they would be when written.
+// Schedules and effective windows are left out, and requests are recorded
+// with the first value of each dimension only and without custom dimensions
+// and segments, which are matched as empty.
+func (s *TargetingService) EstimateReach(ctx context.Context, req *models.ReachRequest) (*models.ReachEstimate, error) {
+	if len(req.Rules) == 0 || len(req.Rules) > MaxReachRules {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRule, validation.Invalid("rules", fmt.Sprintf("must hold between 1 and %d rules", MaxReachRules)))
+	}
+	compiled := make([]*compiledRule, len(req.Rules))
+	for i, rule := range req.Rules {
+		if rule == nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRule, validation.Invalid(fmt.Sprintf("rules[%d]", i), "must be a targeting rule"))
+		}
+		normalizeRuleValues(rule)
+		if err := s.validateRule(rule); err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		var err error
+		if compiled[i], err = compileRule(rule); err != nil {
+			return nil, fmt.Errorf("%w: rules[%d]: %v", ErrInvalidRule, i, err)
+		}
+	}
+
+	table := s.reach.Table()
+	estimate := &models.ReachEstimate{
+		RuleReach:     make([]int64, len(compiled)),
+		Combinations:  len(table.Counts),
+		Untracked:     table.Untracked,
+		WindowSeconds: table.Window.Seconds(),
+	}
+	for combination, n := range table.Counts {
+		dimensions := combinationRequest(combination).Dimensions()
+		estimate.Requests += n
+		matched := false
+		for i, rule := range compiled {
+			if rule.matchesDimensions(dimensions) {
+				estimate.RuleReach[i] += n
+				matched = true
+			}
+		}
+		if matched {
+			estimate.Reach += n
+		}
+	}
+	if estimate.Requests > 0 {
+		estimate.Share = float64(estimate.Reach) / float64(estimate.Requests)
+	}
+	return estimate, nil
+}
+
+// recordCombination counts the dimensions of a served request in the
+// frequency tables reach is estimated from
+func (s *TargetingService) recordCombination(req *models.DeliveryRequest) {
+	normalized := s.normalizeRequest(req)
+	s.reach.Record(stats.Combination{
+		App:        normalized.App,
+		Country:    normalized.Country,
+		OS:         strings.ToLower(normalized.OS),
+		DeviceType: normalized.DeviceType,
+		Region:     normalized.Region,
+		City:       normalized.City,
+		AppVersion: normalized.AppVersion,
+	})
+}
+
+// combinationRequest returns a request carrying the dimensions of a recorded
+// combination
+func combinationRequest(c stats.Combination) *models.DeliveryRequest {
+	return &models.DeliveryRequest{
+		App:        c.App,
+		Country:    c.Country,
+		OS:         c.OS,
+		DeviceType: c.DeviceType,
+		Region:     c.Region,
+		City:       c.City,
+		AppVersion: c.AppVersion,
+	}
+}
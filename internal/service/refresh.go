@@ -0,0 +1,78 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// defaultRefreshJitter is the fraction of the refresh interval scheduled
+// refreshes are spread by when cache.refreshJitter is unset
+const defaultRefreshJitter = 0.1
+
+// startCacheRefreshWorker reloads the cache every Cache.CleanupInterval,
+// give or take the configured jitter. A failed reload is retried with
+// exponential backoff capped at the interval, so a recovering repository is
+// picked up again well before the next scheduled refresh.
+func (s *TargetingService) startCacheRefreshWorker() {
+	interval := s.config.Cache.CleanupInterval
+	if interval <= 0 {
+		slog.Warn("scheduled cache refresh disabled", "cleanup_interval", interval)
+		return
+	}
+	jitter := s.config.Cache.RefreshJitter
+	if jitter <= 0 {
+		jitter = defaultRefreshJitter
+	}
+
+	timer := time.NewTimer(jittered(interval, jitter))
+	defer timer.Stop()
+	retryDelay := minWatchRetryDelay
+
+	for range timer.C {
+		if err := s.refreshCache(); err != nil {
+			slog.Error("failed to refresh cache", "retry_in", retryDelay, "error", err)
+			timer.Reset(retryDelay)
+			if retryDelay *= 2; retryDelay > interval {
+				retryDelay = interval
+			}
+			continue
+		}
+		retryDelay = minWatchRetryDelay
+		timer.Reset(jittered(interval, jitter))
+	}
+}
+
+// jittered returns interval moved randomly by up to fraction of it either way
+func jittered(interval time.Duration, fraction float64) time.Duration {
+	spread := time.Duration(float64(interval) * min(fraction, 1))
+	if spread <= 0 {
+		return interval
+	}
+	return interval - spread + rand.N(2*spread+1)
+}
+
+// RefreshCache reloads the whole targeting cache from the repository right
+// away, e.g. after changing data behind the service's back, and reports what
+// was loaded
+func (s *TargetingService) RefreshCache() (*models.CacheRefreshResult, error) {
+	started := time.Now()
+	if err := s.refreshCache(); err != nil {
+		return nil, fmt.Errorf("failed to refresh cache: %w", err)
+	}
+
+	snap := s.cache.snapshot.Load()
+	rules := 0
+	for _, campaignRules := range snap.targetingRules {
+		rules += len(campaignRules)
+	}
+	return &models.CacheRefreshResult{
+		Campaigns:      len(snap.campaigns),
+		TargetingRules: rules,
+		DurationMS:     time.Since(started).Milliseconds(),
+		RefreshedAt:    snap.lastUpdate.UTC(),
+	}, nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/semver"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// ErrInvalidRule is returned when a targeting rule payload fails validation
+var ErrInvalidRule = errors.New("invalid targeting rule")
+
+// CreateTargetingRule stores a new targeting rule for an existing campaign
+func (s *TargetingService) CreateTargetingRule(ctx context.Context, rule *models.TargetingRule) (*models.TargetingRule, error) {
+	rule.CampaignID = strings.TrimSpace(rule.CampaignID)
+	if rule.CampaignID == "" {
+		return nil, fmt.Errorf("%w: campaign_id is required", ErrInvalidRule)
+	}
+	normalizeRuleValues(rule)
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+	if err := checkRuleConflicts(rule); err != nil {
+		return nil, err
+	}
+
+	campaign, err := s.getCampaign(ctx, rule.CampaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	rule.TenantID = campaign.TenantID
+
+	if err := s.repo.TargetingRule().CreateTargetingRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create targeting rule: %w", err)
+	}
+	s.refreshRules(ctx, rule.CampaignID)
+	return rule, nil
+}
+
+// ListTargetingRules returns the targeting rules of an existing campaign
+func (s *TargetingService) ListTargetingRules(ctx context.Context, campaignID string) ([]*models.TargetingRule, error) {
+	campaignID = strings.TrimSpace(campaignID)
+	if campaignID == "" {
+		return nil, fmt.Errorf("%w: campaign_id is required", ErrInvalidRule)
+	}
+
+	if _, err := s.getCampaign(ctx, campaignID); err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	return s.campaignRules(ctx, campaignID)
+}
+
+// campaignRules returns the targeting rules of a campaign, from the
+// targeting cache while the repository is unavailable
+func (s *TargetingService) campaignRules(ctx context.Context, campaignID string) ([]*models.TargetingRule, error) {
+	rules, err := s.repo.TargetingRule().GetTargetingRulesByCampaignID(ctx, campaignID)
+	if errors.Is(err, repository.ErrUnavailable) && s.Warm() {
+		// Fall back to the rules held by the targeting cache
+		rules, err = s.cache.snapshot.Load().cachedRules(campaignID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get targeting rules: %w", err)
+	}
+	return rules, nil
+}
+
+// UpdateTargetingRule replaces an existing targeting rule. The rule may be
+// moved to another existing campaign.
+func (s *TargetingService) UpdateTargetingRule(ctx context.Context, id int64, rule *models.TargetingRule) (*models.TargetingRule, error) {
+	existing, err := s.getTargetingRule(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get targeting rule: %w", err)
+	}
+
+	rule.ID = id
+	rule.TenantID = existing.TenantID
+	rule.CampaignID = strings.TrimSpace(rule.CampaignID)
+	if rule.CampaignID == "" {
+		rule.CampaignID = existing.CampaignID
+	}
+	normalizeRuleValues(rule)
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+	if err := checkRuleConflicts(rule); err != nil {
+		return nil, err
+	}
+
+	if rule.CampaignID != existing.CampaignID {
+		if _, err := s.getCampaign(ctx, rule.CampaignID); err != nil {
+			return nil, fmt.Errorf("failed to get campaign: %w", err)
+		}
+	}
+
+	if err := s.repo.TargetingRule().UpdateTargetingRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to update targeting rule: %w", err)
+	}
+	s.refreshRules(ctx, existing.CampaignID, rule.CampaignID)
+	return rule, nil
+}
+
+// DeleteTargetingRule removes a targeting rule
+func (s *TargetingService) DeleteTargetingRule(ctx context.Context, id int64) error {
+	existing, err := s.getTargetingRule(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get targeting rule: %w", err)
+	}
+
+	if err := s.repo.TargetingRule().DeleteTargetingRule(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete targeting rule: %w", err)
+	}
+	s.refreshRules(ctx, existing.CampaignID)
+	return nil
+}
+
+// refreshRules brings the cached rules of the affected campaigns up to date
+// after a rule write and publishes their change. The write has already
+// succeeded, so a failed reload is logged and left to the change stream or
+// the next scheduled refresh.
+func (s *TargetingService) refreshRules(ctx context.Context, campaignIDs ...string) {
+	if err := s.refreshCampaignRules(ctx, campaignIDs...); err != nil {
+		slog.Error("failed to refresh targeting rules", "campaign_ids", campaignIDs, "error", err)
+	}
+	s.notifyRuleWrite(ctx, campaignIDs...)
+}
+
+// validateRule checks that every custom dimension is registered, regions are
+// full subdivision codes, the app version constraint parses, every operator
+// is known, every pattern compiles and the expression is well formed
+func (s *TargetingService) validateRule(rule *models.TargetingRule) error {
+	if err := s.validateCustomDimensions(rule); err != nil {
+		return err
+	}
+	if err := validateRegions(rule); err != nil {
+		return err
+	}
+	if err := validateSegments(rule); err != nil {
+		return err
+	}
+	if err := s.validateExpressionDimensions(rule.Expression); err != nil {
+		return err
+	}
+	if rule.AppVersion != "" {
+		if _, err := semver.ParseConstraint(rule.AppVersion); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidRule, validation.Invalid(appVersionDimension, err.Error()))
+		}
+	}
+	if _, err := compileRule(rule); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return nil
+}
+
+// normalizeRuleValues normalizes exactly matched regions and cities the way
+// delivery requests are normalized, so they compare equal, and trims segment
+// IDs
+func normalizeRuleValues(rule *models.TargetingRule) {
+	exact := func(dimension string) bool {
+		operator := rule.Operators[dimension]
+		return operator == "" || operator == models.OperatorExact
+	}
+	normalize := func(values []string, fn func(string) string) {
+		for i, v := range values {
+			values[i] = fn(v)
+		}
+	}
+
+	if exact("region") {
+		upper := func(v string) string { return strings.ToUpper(strings.TrimSpace(v)) }
+		normalize(rule.IncludeRegion, upper)
+		normalize(rule.ExcludeRegion, upper)
+	}
+	if exact("city") {
+		normalize(rule.IncludeCity, normalizeCity)
+		normalize(rule.ExcludeCity, normalizeCity)
+	}
+	normalize(rule.IncludeSegment, strings.TrimSpace)
+	normalize(rule.ExcludeSegment, strings.TrimSpace)
+	normalizeExpression(rule.Expression)
+}
+
+// validateSegments checks that segment IDs aren't blank
+func validateSegments(rule *models.TargetingRule) error {
+	for _, segment := range append(append([]string{}, rule.IncludeSegment...), rule.ExcludeSegment...) {
+		if segment == "" {
+			return fmt.Errorf("%w: segment IDs must not be empty", ErrInvalidRule)
+		}
+	}
+	return nil
+}
+
+// validateRegions checks that exactly matched regions are ISO 3166-2 codes
+// such as US-CA. Requests carry full codes, so a bare subdivision would
+// never match.
+func validateRegions(rule *models.TargetingRule) error {
+	if operator := rule.Operators["region"]; operator != "" && operator != models.OperatorExact {
+		return nil
+	}
+	for _, region := range append(append([]string{}, rule.IncludeRegion...), rule.ExcludeRegion...) {
+		if !strings.Contains(region, "-") {
+			return fmt.Errorf("%w: region %q is not an ISO 3166-2 code such as US-CA", ErrInvalidRule, region)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // schedules name IANA zones; don't depend on the host's zoneinfo
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// weekdayNames maps the day names accepted in a schedule to time.Weekday
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// compiledSchedule is a rule schedule with its timezone resolved
+type compiledSchedule struct {
+	location *time.Location
+	weekdays [7]bool // indexed by time.Weekday; all false allows every day
+	anyDay   bool
+	hours    []models.HourRange
+}
+
+// compileSchedule validates a schedule and resolves its timezone
+func compileSchedule(schedule *models.Schedule) (*compiledSchedule, error) {
+	location := time.UTC
+	if schedule.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(schedule.Timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", schedule.Timezone)
+		}
+	}
+
+	compiled := &compiledSchedule{
+		location: location,
+		anyDay:   len(schedule.Weekdays) == 0,
+		hours:    schedule.Hours,
+	}
+	for _, name := range schedule.Weekdays {
+		day, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", name)
+		}
+		compiled.weekdays[day] = true
+	}
+	for _, hours := range schedule.Hours {
+		if hours.Start < 0 || hours.Start > 23 || hours.End < 1 || hours.End > 24 || hours.Start == hours.End {
+			return nil, fmt.Errorf("invalid hour range %d-%d", hours.Start, hours.End)
+		}
+	}
+	return compiled, nil
+}
+
+// validateEffective checks that a rule's effective window isn't empty and
+// that its bounds fall on a schedule bucket, so cached results switch over
+// exactly when the rule starts or stops applying
+func validateEffective(rule *models.TargetingRule) error {
+	bounds := []struct {
+		name string
+		at   *time.Time
+	}{{"effective_from", rule.EffectiveFrom}, {"effective_until", rule.EffectiveUntil}}
+	for _, bound := range bounds {
+		if bound.at != nil && !bound.at.Truncate(scheduleBucket).Equal(*bound.at) {
+			return fmt.Errorf("%s: %s is not a multiple of %s past the hour", bound.name, bound.at.Format(time.RFC3339), scheduleBucket)
+		}
+	}
+	if rule.EffectiveFrom != nil && rule.EffectiveUntil != nil && !rule.EffectiveUntil.After(*rule.EffectiveFrom) {
+		return fmt.Errorf("effective_until must be after effective_from")
+	}
+	return nil
+}
+
+// allows reports whether the schedule allows serving at now
+func (s *compiledSchedule) allows(now time.Time) bool {
+	local := now.In(s.location)
+	if !s.anyDay && !s.weekdays[local.Weekday()] {
+		return false
+	}
+	if len(s.hours) == 0 {
+		return true
+	}
+
+	hour := local.Hour()
+	for _, r := range s.hours {
+		if r.Start < r.End && hour >= r.Start && hour < r.End {
+			return true
+		}
+		if r.Start > r.End && (hour >= r.Start || hour < r.End) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/segments"
+)
+
+// SetSegmentProvider sets where the segments of users are looked up for
+// rules targeting segments. It must be called before requests are served.
+// Without a provider users belong to no segment.
+func (s *TargetingService) SetSegmentProvider(provider segments.Provider) {
+	s.segments = provider
+}
+
+// resolveSegments sets the segments of the user of a normalized request.
+// Requests without a user ID belong to no segment, and a failed lookup is
+// logged and treated the same, so delivery goes on without segments.
+func (s *TargetingService) resolveSegments(ctx context.Context, req *models.DeliveryRequest) {
+	if s.segments == nil || req.UserID == "" {
+		return
+	}
+	userSegments, err := s.segments.Segments(ctx, req.UserID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to look up user segments", "user_id", req.UserID, "error", err)
+		return
+	}
+	// Sorted and deduplicated so equal sets share query cache entries
+	req.Segments = slices.Compact(slices.Sorted(slices.Values(userSegments)))
+}
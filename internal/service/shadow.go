@@ -0,0 +1,62 @@
+package service
+
+import (
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// Outcomes recorded for a delivery whose matches shadow rules would change
+const (
+	shadowGained = "gained"
+	shadowLost   = "lost"
+)
+
+// evaluateShadowRules works out how enabling the tenant's shadow rules would
+// change the campaigns matched for a request and records every campaign that
+// would be gained or lost. matches is left untouched.
+func (s *TargetingService) evaluateShadowRules(tenantID string, dimensions []models.Dimension, now time.Time, matches []*models.DeliveryResponse) {
+	if s.metrics == nil {
+		return
+	}
+
+	snap := s.cache.snapshot.Load()
+	if len(snap.shadowRules) == 0 {
+		return
+	}
+
+	matched := make(map[string]bool, len(matches))
+	for _, match := range matches {
+		matched[match.CID] = true
+	}
+
+	for campaignID, shadow := range snap.shadowRules {
+		if campaign, active := snap.campaigns[campaignID]; !active || campaign.TenantID != tenantID {
+			continue
+		}
+
+		// Rules are OR-ed; a campaign without live rules matches everything
+		// today but only what its shadow rules accept once they are enabled
+		wouldMatch := anyRuleMatches(shadow, dimensions, now)
+		if !wouldMatch && len(snap.targetingRules[campaignID]) > 0 {
+			wouldMatch = anyRuleMatches(snap.compiledRules[campaignID], dimensions, now)
+		}
+
+		switch {
+		case wouldMatch && !matched[campaignID]:
+			s.metrics.RecordShadowMatch(campaignID, shadowGained)
+		case !wouldMatch && matched[campaignID]:
+			s.metrics.RecordShadowMatch(campaignID, shadowLost)
+		}
+	}
+}
+
+// anyRuleMatches reports whether any of the rules matches the request
+func anyRuleMatches(rules []*compiledRule, dimensions []models.Dimension, now time.Time) bool {
+	for _, rule := range rules {
+		if rule.matches(dimensions, now) {
+			return true
+		}
+	}
+	return false
+}
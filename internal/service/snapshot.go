@@ -0,0 +1,164 @@
+package service
+
+import (
+	"maps"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// cacheSnapshot is an immutable view of the active campaigns and their rules.
+// Writers never modify a published snapshot; they build or copy one off to
+// the side and swap it in, so delivery requests never wait on a refresh.
+type cacheSnapshot struct {
+	campaigns      map[string]*models.Campaign
+	targetingRules map[string][]*models.TargetingRule
+	compiledRules  map[string][]*compiledRule
+	shadowRules    map[string][]*compiledRule
+	index          *campaignIndex
+	// lastUpdate is zero until the cache has been loaded
+	lastUpdate time.Time
+	// version identifies the contents of a snapshot loaded from the
+	// repository or a standby store when the cache is shared with other
+	// instances. Updated copies have none, so they share nothing until the
+	// next full load.
+	version string
+	// restored is set on a snapshot restored from the cache file, and the
+	// copies made of it, until the cache is loaded from the repository
+	restored bool
+}
+
+// newCacheSnapshot creates an empty snapshot
+func newCacheSnapshot() *cacheSnapshot {
+	return &cacheSnapshot{
+		campaigns:      make(map[string]*models.Campaign),
+		targetingRules: make(map[string][]*models.TargetingRule),
+		compiledRules:  make(map[string][]*compiledRule),
+		shadowRules:    make(map[string][]*compiledRule),
+		index:          newCampaignIndex(),
+	}
+}
+
+// clone returns a copy of the snapshot that can be modified before it is
+// published. Campaigns and rule slices are shared since they are replaced
+// rather than modified.
+func (snap *cacheSnapshot) clone() *cacheSnapshot {
+	return &cacheSnapshot{
+		campaigns:      maps.Clone(snap.campaigns),
+		targetingRules: maps.Clone(snap.targetingRules),
+		compiledRules:  maps.Clone(snap.compiledRules),
+		shadowRules:    maps.Clone(snap.shadowRules),
+		index:          snap.index.clone(),
+		lastUpdate:     snap.lastUpdate,
+		restored:       snap.restored,
+	}
+}
+
+// loaded reports whether the snapshot holds the repository contents rather
+// than the empty cache the service starts with
+func (snap *cacheSnapshot) loaded() bool {
+	return !snap.lastUpdate.IsZero()
+}
+
+// setCampaignRules replaces the rules of a campaign. Shadow rules are kept
+// apart from the live rules that decide delivery. Only snapshots that haven't
+// been published may be modified, and the caller must reindex the campaign.
+func (snap *cacheSnapshot) setCampaignRules(campaignID string, rules []*models.TargetingRule) {
+	var live, shadow []*models.TargetingRule
+	for _, rule := range rules {
+		if rule.Shadow {
+			shadow = append(shadow, rule)
+		} else {
+			live = append(live, rule)
+		}
+	}
+
+	if len(live) == 0 {
+		delete(snap.targetingRules, campaignID)
+		delete(snap.compiledRules, campaignID)
+	} else {
+		snap.targetingRules[campaignID] = live
+		snap.compiledRules[campaignID] = compileRules(live)
+	}
+	if len(shadow) == 0 {
+		delete(snap.shadowRules, campaignID)
+	} else {
+		snap.shadowRules[campaignID] = compileRules(shadow)
+	}
+}
+
+// reindexCampaign brings the campaign index up to date for one campaign. Only
+// snapshots that haven't been published may be modified.
+func (snap *cacheSnapshot) reindexCampaign(campaignID string) {
+	if _, exists := snap.campaigns[campaignID]; !exists {
+		snap.index.remove(campaignID)
+		return
+	}
+	snap.index.add(campaignID, len(snap.targetingRules[campaignID]) > 0, snap.compiledRules[campaignID])
+}
+
+// cachedRules returns the live and shadow rules of a campaign
+func (snap *cacheSnapshot) cachedRules(campaignID string) []*models.TargetingRule {
+	rules := append([]*models.TargetingRule{}, snap.targetingRules[campaignID]...)
+	for _, compiled := range snap.shadowRules[campaignID] {
+		rules = append(rules, compiled.rule)
+	}
+	return rules
+}
+
+// campaignMatches checks if a campaign matches the targeting criteria
+func (snap *cacheSnapshot) campaignMatches(campaignID string, dimensions []models.Dimension, now time.Time) bool {
+	if len(snap.targetingRules[campaignID]) == 0 {
+		// No targeting rules means the campaign matches all requests
+		return true
+	}
+
+	// Check each targeting rule (OR logic between rules, AND logic within a rule)
+	for _, rule := range snap.compiledRules[campaignID] {
+		if rule.matches(dimensions, now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// update applies fn to a copy of the current snapshot and publishes it.
+// Writers are serialized so that concurrent updates aren't lost. Updates to a
+// cache that hasn't been loaded leave it unloaded, so delivery doesn't serve
+// from a partial cache; replace stamps the first load.
+func (c *targetingCache) update(fn func(next *cacheSnapshot)) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	next := c.snapshot.Load().clone()
+	fn(next)
+	if next.loaded() {
+		next.lastUpdate = time.Now()
+	}
+	c.snapshot.Store(next)
+	c.queryCache.Purge()
+}
+
+// replace publishes a snapshot built from scratch
+func (c *targetingCache) replace(next *cacheSnapshot) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	c.snapshot.Store(next)
+	c.queryCache.Purge()
+}
+
+// replaceUnloaded publishes a snapshot built from scratch unless the cache
+// has been loaded already, and reports whether it did
+func (c *targetingCache) replaceUnloaded(next *cacheSnapshot) bool {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if c.snapshot.Load().loaded() {
+		return false
+	}
+	c.snapshot.Store(next)
+	c.queryCache.Purge()
+	return true
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCacheStale is returned for delivery requests in serve-stale mode once
+// the targeting cache has failed to refresh for longer than the configured
+// maximum staleness
+var ErrCacheStale = errors.New("targeting cache is stale")
+
+// DefaultMaxStaleness bounds serving a cache that fails to refresh when
+// cache.serveStale.maxStaleness is unset
+const DefaultMaxStaleness = time.Hour
+
+// recordRefreshResult tracks whether the targeting cache is stale: from the
+// first failed refresh until the next successful one
+func (s *TargetingService) recordRefreshResult(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastRefreshError = err
+	if err == nil {
+		s.staleSince = time.Time{}
+	} else if s.staleSince.IsZero() {
+		s.staleSince = time.Now()
+	}
+	s.metrics.SetCacheStaleness(s.stalenessLocked())
+}
+
+// Staleness returns how long refreshes of the loaded targeting cache have
+// been failing, or zero while the cache is fresh
+func (s *TargetingService) Staleness() time.Duration {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.stalenessLocked()
+}
+
+// stalenessLocked is Staleness for callers holding mutex. A cache that was
+// never loaded isn't stale, since there is nothing to serve.
+func (s *TargetingService) stalenessLocked() time.Duration {
+	if s.staleSince.IsZero() || s.lastRefresh.IsZero() {
+		return 0
+	}
+	return time.Since(s.staleSince)
+}
+
+// ServingStale reports whether delivery is being served from a stale cache
+// in serve-stale mode, so responses can be marked as such
+func (s *TargetingService) ServingStale() bool {
+	if !s.config.Cache.ServeStale.Enabled {
+		return false
+	}
+	staleness := s.Staleness()
+	return staleness > 0 && staleness <= s.maxStaleness()
+}
+
+// checkStaleness fails with ErrCacheStale in serve-stale mode once the cache
+// has been stale for longer than the maximum staleness
+func (s *TargetingService) checkStaleness() error {
+	if !s.config.Cache.ServeStale.Enabled {
+		return nil
+	}
+	staleness := s.Staleness()
+	s.metrics.SetCacheStaleness(staleness)
+	if max := s.maxStaleness(); staleness > max {
+		return fmt.Errorf("%w: refreshes have failed for %s, longer than %s", ErrCacheStale, staleness.Round(time.Second), max)
+	}
+	return nil
+}
+
+func (s *TargetingService) maxStaleness() time.Duration {
+	if max := s.config.Cache.ServeStale.MaxStaleness; max > 0 {
+		return max
+	}
+	return DefaultMaxStaleness
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/standby"
+)
+
+// Defaults of the cache standby settings
+const (
+	DefaultStandbyMaxAge  = 5 * time.Minute
+	DefaultStandbyTimeout = 50 * time.Millisecond
+)
+
+// standbyLoadTimeout bounds loading a whole published cache at warm-up
+const standbyLoadTimeout = 10 * time.Second
+
+// cacheStandby is where the targeting cache is shared with other instances
+type cacheStandby struct {
+	store   standby.Store
+	maxAge  time.Duration
+	timeout time.Duration
+}
+
+// SetCacheStandby shares the targeting cache with other instances through
+// store. Every cache loaded from the repository is published there, and
+// query results are looked up there before being computed. Unless the cache
+// has been loaded already, it is warmed up right away from a published cache
+// up to maxAge old, so the service doesn't wait on the repository to start.
+// Changes made since that cache was published are picked up by the next
+// refresh; with change streams, which only refresh when they reconnect, the
+// service warms up from the repository instead. timeout bounds the lookups
+// of query results. Non-positive values use DefaultStandbyMaxAge and
+// DefaultStandbyTimeout. It must be called before requests are served.
+func (s *TargetingService) SetCacheStandby(store standby.Store, maxAge, timeout time.Duration) {
+	if maxAge <= 0 {
+		maxAge = DefaultStandbyMaxAge
+	}
+	if timeout <= 0 {
+		timeout = DefaultStandbyTimeout
+	}
+	s.standby.Store(&cacheStandby{store: store, maxAge: maxAge, timeout: timeout})
+
+	_, watching := s.repo.(repository.ChangeWatcher)
+	if !s.Warm() && !(watching && s.config.Cache.WatchChanges) {
+		s.warmFromStandby()
+	}
+}
+
+// warmFromStandby loads the cache from the snapshot published by another
+// instance, if there is a recent enough one
+func (s *TargetingService) warmFromStandby() {
+	sb := s.standby.Load()
+	ctx, cancel := context.WithTimeout(context.Background(), standbyLoadTimeout)
+	defer cancel()
+
+	published, err := sb.store.LoadSnapshot(ctx)
+	switch {
+	case err != nil:
+		slog.Warn("failed to load the standby cache, warming up from the repository", "error", err)
+		return
+	case published == nil:
+		return
+	case time.Since(published.SavedAt) > sb.maxAge:
+		slog.Info("standby cache too old, warming up from the repository", "saved_at", published.SavedAt)
+		return
+	}
+
+	next := buildCacheSnapshot(published.Campaigns, published.Rules)
+	next.version = published.Version
+	next.lastUpdate = published.SavedAt
+	if !s.cache.replaceUnloaded(next) {
+		// A repository load got there first
+		return
+	}
+	s.mutex.Lock()
+	s.lastRefresh = next.lastUpdate
+	s.mutex.Unlock()
+	s.markWarm()
+	slog.Info("targeting cache warmed up from standby", "campaigns", len(next.campaigns), "version", next.version)
+}
+
+// standbySnapshot returns the snapshot of campaigns and rules loaded from
+// the repository to publish, or nil when the cache isn't shared
+func (s *TargetingService) standbySnapshot(campaigns []*models.Campaign, rules []*models.TargetingRule) *standby.Snapshot {
+	if s.standby.Load() == nil {
+		return nil
+	}
+	published, err := standby.NewSnapshot(campaigns, rules)
+	if err != nil {
+		slog.Error("failed to encode the standby cache", "error", err)
+		return nil
+	}
+	return published
+}
+
+// publishSnapshot saves a snapshot to the standby store. Failures are
+// logged; other instances then warm up from the repository.
+func (s *TargetingService) publishSnapshot(ctx context.Context, published *standby.Snapshot) {
+	sb := s.standby.Load()
+	if sb == nil || published == nil {
+		return
+	}
+	if err := sb.store.SaveSnapshot(ctx, published); err != nil {
+		slog.Error("failed to publish the standby cache", "error", err)
+	}
+}
+
+// sharedMatches returns the matches another instance holding the same cache
+// as snap computed for cacheKey. ok is false when there are none, including
+// when they name a campaign snap doesn't hold.
+func (s *TargetingService) sharedMatches(ctx context.Context, snap *cacheSnapshot, cacheKey string) ([]*models.DeliveryResponse, bool) {
+	sb := s.standby.Load()
+	if sb == nil || snap.version == "" {
+		return nil, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, sb.timeout)
+	defer cancel()
+
+	campaignIDs, ok, err := sb.store.LoadQuery(ctx, snap.version, cacheKey)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to look up shared query result", "error", err)
+		return nil, false
+	}
+	if !ok {
+		s.metrics.RecordCacheMiss("standby")
+		return nil, false
+	}
+
+	matches := make([]*models.DeliveryResponse, 0, len(campaignIDs))
+	for _, id := range campaignIDs {
+		campaign, exists := snap.campaigns[id]
+		if !exists {
+			s.metrics.RecordCacheMiss("standby")
+			return nil, false
+		}
+		matches = append(matches, campaign.ToDeliveryResponse())
+	}
+	s.metrics.RecordCacheHit("standby")
+	return matches, true
+}
+
+// shareMatches saves the matches computed for cacheKey for other instances
+// holding the same cache, provided snap is still the current cache and so
+// the one they were computed from
+func (s *TargetingService) shareMatches(ctx context.Context, snap *cacheSnapshot, cacheKey string, matches []*models.DeliveryResponse) {
+	sb := s.standby.Load()
+	if sb == nil || snap.version == "" || s.cache.snapshot.Load() != snap {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, sb.timeout)
+	defer cancel()
+
+	campaignIDs := make([]string, len(matches))
+	for i, match := range matches {
+		campaignIDs[i] = match.CID
+	}
+	if err := sb.store.SaveQuery(ctx, snap.version, cacheKey, campaignIDs, s.config.Cache.TTL); err != nil {
+		slog.WarnContext(ctx, "failed to share query result", "error", err)
+	}
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// ErrTooManySubscribers is returned for stream subscriptions beyond
+// stream.maxSubscribers
+var ErrTooManySubscribers = errors.New("too many stream subscribers")
+
+// Defaults for unset stream settings
+const (
+	DefaultStreamHistory        = 1000
+	DefaultStreamHeartbeat      = 15 * time.Second
+	DefaultStreamMaxSubscribers = 1000
+	// streamBuffer is how many changes a subscriber may fall behind by before
+	// it is dropped, to resume with Last-Event-ID
+	streamBuffer = 64
+)
+
+// changeFeed fans campaign changes out to subscribers, keeping the most
+// recent ones so reconnecting subscribers can catch up. It is safe for
+// concurrent use.
+type changeFeed struct {
+	mutex          sync.Mutex
+	lastID         uint64
+	history        []*models.CampaignChange
+	maxHistory     int
+	subscribers    map[*changeSubscriber]struct{}
+	maxSubscribers int
+}
+
+// changeSubscriber receives the changes of one tenant until events is closed
+type changeSubscriber struct {
+	tenantID string
+	events   chan *models.CampaignChange
+}
+
+// newChangeFeed creates a feed numbering changes after the current time, so
+// IDs from before a restart are never mistaken for later ones
+func newChangeFeed(maxHistory, maxSubscribers int) *changeFeed {
+	if maxHistory <= 0 {
+		maxHistory = DefaultStreamHistory
+	}
+	if maxSubscribers <= 0 {
+		maxSubscribers = DefaultStreamMaxSubscribers
+	}
+	return &changeFeed{
+		lastID:         uint64(time.Now().UnixMicro()),
+		maxHistory:     maxHistory,
+		subscribers:    make(map[*changeSubscriber]struct{}),
+		maxSubscribers: maxSubscribers,
+	}
+}
+
+// publish numbers a change and sends it to the subscribers of its tenant.
+// Subscribers too far behind are dropped rather than blocking the writer.
+func (f *changeFeed) publish(change *models.CampaignChange) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.lastID++
+	change.ID = f.lastID
+	if len(f.history) == f.maxHistory {
+		f.history = f.history[1:]
+	}
+	f.history = append(f.history, change)
+
+	for sub := range f.subscribers {
+		if change.Type != models.CampaignsReset && sub.tenantID != change.TenantID {
+			continue
+		}
+		select {
+		case sub.events <- change:
+		default:
+			f.removeLocked(sub)
+		}
+	}
+}
+
+// subscribe registers a subscriber for the changes of tenantID. After lastID
+// it first gets the changes it missed, or a reset when they are no longer
+// known; without lastID it only gets new changes.
+func (f *changeFeed) subscribe(tenantID, lastID string) (*changeSubscriber, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.subscribers) >= f.maxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	var missed []*models.CampaignChange
+	if lastID != "" {
+		missed = f.missedLocked(tenantID, lastID)
+	}
+	sub := &changeSubscriber{
+		tenantID: tenantID,
+		events:   make(chan *models.CampaignChange, streamBuffer+len(missed)),
+	}
+	for _, change := range missed {
+		sub.events <- change
+	}
+	f.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// missedLocked returns the changes of tenantID after lastID, or a reset when
+// lastID isn't one of the changes held. The caller holds mutex.
+func (f *changeFeed) missedLocked(tenantID, lastID string) []*models.CampaignChange {
+	id, err := strconv.ParseUint(lastID, 10, 64)
+	oldest := f.lastID
+	if len(f.history) > 0 {
+		oldest = f.history[0].ID - 1
+	}
+	if err != nil || id < oldest || id > f.lastID {
+		return []*models.CampaignChange{{ID: f.lastID, Type: models.CampaignsReset, At: time.Now().UTC()}}
+	}
+
+	var missed []*models.CampaignChange
+	for _, change := range f.history {
+		if change.ID > id && (change.Type == models.CampaignsReset || change.TenantID == tenantID) {
+			missed = append(missed, change)
+		}
+	}
+	return missed
+}
+
+// recent returns up to n of the changes held of a campaign, newest first
+func (f *changeFeed) recent(tenantID, campaignID string, n int) []*models.CampaignChange {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	changes := []*models.CampaignChange{}
+	for i := len(f.history) - 1; i >= 0 && len(changes) < n; i-- {
+		if change := f.history[i]; change.CampaignID == campaignID && change.TenantID == tenantID {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// unsubscribe removes a subscriber, closing its channel unless that was
+// done already
+func (f *changeFeed) unsubscribe(sub *changeSubscriber) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.removeLocked(sub)
+}
+
+func (f *changeFeed) removeLocked(sub *changeSubscriber) {
+	if _, ok := f.subscribers[sub]; ok {
+		delete(f.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// SubscribeCampaignChanges streams the changes of the campaigns of the
+// tenant ctx acts for until ctx is done. Resuming after lastEventID, the ID
+// of the last change received, first replays the changes missed since. The
+// channel is also closed when the subscriber falls too far behind; it should
+// then subscribe again with the last ID it got.
+func (s *TargetingService) SubscribeCampaignChanges(ctx context.Context, lastEventID string) (<-chan *models.CampaignChange, error) {
+	sub, err := s.changes.subscribe(tenant.FromContext(ctx), lastEventID)
+	if err != nil {
+		return nil, err
+	}
+	s.metrics.SetStreamSubscribers(s.changes.subscriberCount())
+	go func() {
+		<-ctx.Done()
+		s.changes.unsubscribe(sub)
+		s.metrics.SetStreamSubscribers(s.changes.subscriberCount())
+	}()
+	return sub.events, nil
+}
+
+// subscriberCount returns the number of subscribers
+func (f *changeFeed) subscriberCount() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.subscribers)
+}
+
+// notifyWrite publishes a campaign change made through this service. While a
+// change stream keeps the cache fresh it reports every write, from any
+// instance, so the write is published when it arrives instead.
+func (s *TargetingService) notifyWrite(campaign *models.Campaign, created bool) {
+	if s.watchingChanges.Load() {
+		return
+	}
+	s.publishChange(campaign, campaignChangeType(campaign, created))
+}
+
+// campaignChangeType returns the type of a write leaving campaign as it is
+func campaignChangeType(campaign *models.Campaign, created bool) string {
+	switch {
+	case created:
+		return models.CampaignCreated
+	case campaign.IsArchived():
+		return models.CampaignDeleted
+	case campaign.IsPaused():
+		return models.CampaignPaused
+	default:
+		return models.CampaignUpdated
+	}
+}
+
+// publishChange publishes a change of a campaign as it is after the change
+func (s *TargetingService) publishChange(campaign *models.Campaign, changeType string) {
+	s.changes.publish(&models.CampaignChange{
+		Type:       changeType,
+		CampaignID: campaign.ID,
+		TenantID:   campaign.TenantID,
+		Status:     campaign.Status,
+		At:         time.Now().UTC(),
+	})
+}
+
+// notifyDelete publishes the removal of a campaign made through this service
+func (s *TargetingService) notifyDelete(campaign *models.Campaign) {
+	if s.watchingChanges.Load() {
+		return
+	}
+	s.changes.publish(&models.CampaignChange{
+		Type:       models.CampaignDeleted,
+		CampaignID: campaign.ID,
+		TenantID:   campaign.TenantID,
+		At:         time.Now().UTC(),
+	})
+}
+
+// notifyRuleWrite publishes a write of the targeting rules of campaigns as
+// an update of each
+func (s *TargetingService) notifyRuleWrite(ctx context.Context, campaignIDs ...string) {
+	if s.watchingChanges.Load() {
+		return
+	}
+	for i, campaignID := range campaignIDs {
+		if slices.Contains(campaignIDs[:i], campaignID) {
+			continue
+		}
+		campaign, err := s.getCampaign(ctx, campaignID)
+		if err != nil {
+			// The campaign is gone, which was published already
+			continue
+		}
+		s.publishChange(campaign, models.CampaignUpdated)
+	}
+}
+
+// publishStreamChange publishes a change read from the repository's change
+// stream. Deletes don't identify the removed document, so subscribers are
+// told to reset.
+func (s *TargetingService) publishStreamChange(event repository.ChangeEvent) {
+	switch {
+	case event.Kind == repository.ChangeKindCampaign && event.Campaign != nil:
+		s.publishChange(event.Campaign, campaignChangeType(event.Campaign, event.Operation == "insert"))
+	case event.Kind == repository.ChangeKindTargetingRule && event.Rule != nil:
+		s.changes.publish(&models.CampaignChange{
+			Type:       models.CampaignUpdated,
+			CampaignID: event.Rule.CampaignID,
+			TenantID:   event.Rule.TenantID,
+			At:         time.Now().UTC(),
+		})
+	default:
+		s.changes.publish(&models.CampaignChange{Type: models.CampaignsReset, At: time.Now().UTC()})
+	}
+}
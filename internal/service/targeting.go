@@ -5,112 +5,429 @@ import (
 	"context"
 	
 	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
 
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Harshi-itaSinha/target-engine/internal/config"
+	"github.com/Harshi-itaSinha/target-engine/internal/events"
 	models "github.com/Harshi-itaSinha/target-engine/internal/models"
 	"github.com/Harshi-itaSinha/target-engine/internal/repository"
-	"github.com/go-playground/validator/v10"
+	"github.com/Harshi-itaSinha/target-engine/internal/segments"
+	"github.com/Harshi-itaSinha/target-engine/internal/semver"
+	"github.com/Harshi-itaSinha/target-engine/internal/stats"
+	"github.com/Harshi-itaSinha/target-engine/internal/storage"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+	"github.com/Harshi-itaSinha/target-engine/monitoring"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/sync/singleflight"
 )
 
+var tracer = otel.Tracer("github.com/Harshi-itaSinha/target-engine/internal/service")
+
 // TargetingService handles the core business logic for campaign targeting
 type TargetingService struct {
 	repo        repository.Repository
 	cache       *targetingCache
 	config      *config.Config
+	metrics     *monitoring.Metrics
+	counters    storage.CounterStore
+	events      events.Publisher
+	// inflight collapses concurrent query cache misses for the same key
+	inflight singleflight.Group
+	// watchingChanges is set once the cache is kept fresh by a change stream
+	watchingChanges atomic.Bool
+	// dimensions holds the registered custom dimensions
+	dimensions atomic.Pointer[dimensionRegistry]
+	// rotations tracks round robin creative rotation per campaign
+	rotations rotationCounters
+	// selections tracks round robin campaign selection per query cache key
+	selections rotationCounters
+	// traffic aggregates recent deliveries for the stats endpoint
+	traffic *stats.Aggregator
+	// reach counts the dimension combinations of recent requests for reach
+	// estimates
+	reach *stats.Frequencies
+	// deliveries aggregates delivery counts per campaign until they are
+	// flushed to the repository; nil while campaign stats are disabled
+	deliveries *deliveryCounter
+	// decisions holds sampled delivery decisions until they are flushed to
+	// the repository; nil while the decision log is disabled
+	decisions *decisionLog
+	// segments looks up the segments of users; nil when none are configured
+	segments segments.Provider
+	// trackingTokens issues the tokens of tracking events; nil when events
+	// aren't tracked
+	trackingTokens *TrackingTokens
+	// standby shares the cache with other instances; nil when it isn't shared
+	standby atomic.Pointer[cacheStandby]
+	// cacheFile persists the cache between restarts; nil when it isn't
+	cacheFile atomic.Pointer[cacheFile]
+	// killSwitches holds the engaged kill switches; nil until they are
+	// loaded for the first time
+	killSwitches    atomic.Pointer[killSwitchState]
+	killSwitchMutex sync.Mutex
+	// changes fans campaign changes out to stream subscribers
+	changes *changeFeed
+	// warmed is closed once the cache has been loaded for the first time
+	warmed      chan struct{}
+	warmOnce    sync.Once
+	// mutex guards lastRefresh, staleSince and lastRefreshError
 	mutex       sync.RWMutex
 	lastRefresh time.Time
+	// staleSince is when cache refreshes started failing, zero while they
+	// succeed
+	staleSince time.Time
+	// lastRefreshError is the error of the last cache refresh, nil once one
+	// succeeds
+	lastRefreshError error
+	// started is when the service was created, for its uptime
+	started time.Time
 }
 
-// targetingCache represents an in-memory cache for targeting data
+// targetingCache represents an in-memory cache for targeting data. Readers
+// load the current snapshot without locking; writers publish a new one.
 type targetingCache struct {
-	campaigns      map[string]*models.Campaign
-	targetingRules map[string][]*models.TargetingRule
-	queryCache     map[string][]*models.DeliveryResponse
-	mutex          sync.RWMutex
-	lastUpdate     time.Time
+	snapshot   atomic.Pointer[cacheSnapshot]
+	writeMutex sync.Mutex
+	queryCache *queryCache
 }
 
-// NewTargetingService creates a new targeting service
-func NewTargetingService(repo repository.Repository, cfg *config.Config) *TargetingService {
+// NewTargetingService creates a new targeting service. metrics may be nil when
+// metrics collection is disabled, counters may be nil to disable frequency
+// capping and budgets, and publisher may be nil to disable delivery events.
+func NewTargetingService(repo repository.Repository, cfg *config.Config, metrics *monitoring.Metrics, counters storage.CounterStore, publisher events.Publisher) *TargetingService {
 	service := &TargetingService{
-		repo:   repo,
-		config: cfg,
+		repo:     repo,
+		config:   cfg,
+		metrics:  metrics,
+		counters: counters,
+		events:   publisher,
+		traffic:  stats.New(cfg.Stats.Window),
+		reach:    stats.NewFrequencies(cfg.Stats.Reach.Window, cfg.Stats.Reach.MaxCombinations),
+		started:  time.Now(),
+		warmed:   make(chan struct{}),
+		changes:  newChangeFeed(cfg.Stream.History, cfg.Stream.MaxSubscribers),
 		cache: &targetingCache{
-			campaigns:      make(map[string]*models.Campaign),
-			targetingRules: make(map[string][]*models.TargetingRule),
-			queryCache:     make(map[string][]*models.DeliveryResponse),
+			queryCache: newQueryCache(cfg.Cache.MaxSize, cfg.Cache.TTL),
 		},
 	}
+	service.cache.snapshot.Store(newCacheSnapshot())
+
+	service.dimensions.Store(newDimensionRegistry(cfg.Dimensions))
 
 	// Initialize cache
-	go service.refreshCache()
+	go service.warmUp()
+
+	// Keep the cache fresh, incrementally via change streams when the
+	// repository supports them and periodically otherwise
+	if watcher, ok := repo.(repository.ChangeWatcher); ok && cfg.Cache.WatchChanges {
+		go service.startChangeWatcher(watcher)
+	} else {
+		go service.startCacheRefreshWorker()
+	}
+
+	// Start periodic removal of expired query cache entries
+	go service.startCacheCleanupWorker()
 
-	// Start periodic cache refresh
-	go service.startCacheRefreshWorker()
+	// Start and complete campaigns as their flight dates pass
+	go service.startLifecycleWorker()
+
+	// Load kill switches and pick up those engaged on other instances
+	go service.startKillSwitchWorker()
+
+	if cfg.Stats.Campaigns.Enabled {
+		service.deliveries = newDeliveryCounter(cfg.Stats.Campaigns)
+		go service.startDeliveryStatsWorker()
+	}
+
+	if cfg.DecisionLog.Enabled {
+		service.decisions = newDecisionLog(cfg.DecisionLog)
+		go service.startDecisionLogWorker()
+	}
 
 	return service
 }
 
 // GetMatchingCampaigns returns campaigns that match the targeting criteria
+// and publishes a delivery event for every valid request. None are returned
+// while a kill switch stops serving the tenant's campaigns.
 func (s *TargetingService) GetMatchingCampaigns(ctx context.Context, req *models.DeliveryRequest) ([]*models.DeliveryResponse, error) {
+	start := time.Now()
+	matches, cacheHit, err := s.getMatchingCampaigns(ctx, req, models.SelectionPriorityWeight)
+	if err == nil {
+		s.recordTraffic(ctx, req, matches, time.Since(start))
+		s.publishDelivery(ctx, req, matches, start)
+		s.logDecision(ctx, req, matches, cacheHit, start)
+	}
+	return matches, err
+}
+
+// SelectCampaign returns the single campaign to serve for req, or nil when
+// none can be served. The campaigns matching req are tried in the order of
+// strategy, or of the configured default strategy when it is empty, and the
+// first the user is allowed to see wins.
+func (s *TargetingService) SelectCampaign(ctx context.Context, req *models.DeliveryRequest, strategy string) (*models.DeliveryResponse, error) {
+	if strategy == "" {
+		strategy = s.config.Delivery.SelectStrategy
+	}
+	if strategy == "" {
+		strategy = models.SelectionPriorityWeight
+	}
+	if !ValidSelectionStrategy(strategy) {
+		return nil, validation.Invalid("strategy", "must be one of priority_weight, random or round_robin")
+	}
+
+	single := *req
+	single.Limit = 1
+	start := time.Now()
+	matches, cacheHit, err := s.getMatchingCampaigns(ctx, &single, strategy)
+	if err != nil {
+		return nil, err
+	}
+	s.recordTraffic(ctx, &single, matches, time.Since(start))
+	s.publishDelivery(ctx, &single, matches, start)
+	s.logDecision(ctx, &single, matches, cacheHit, start)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	return matches[0], nil
+}
+
+// ValidSelectionStrategy reports whether strategy names a selection
+// strategy; the empty string stands for the default
+func ValidSelectionStrategy(strategy string) bool {
+	switch strategy {
+	case "", models.SelectionPriorityWeight, models.SelectionRandom, models.SelectionRoundRobin:
+		return true
+	}
+	return false
+}
+
+// recordTraffic counts a served request in the traffic, reach and campaign
+// stats and the delivery metrics
+func (s *TargetingService) recordTraffic(ctx context.Context, req *models.DeliveryRequest, matches []*models.DeliveryResponse, latency time.Duration) {
+	served := make([]string, len(matches))
+	for i, match := range matches {
+		served[i] = match.CID
+	}
+	s.traffic.RecordDelivery(req.Country, req.OS, served, latency)
+	s.metrics.RecordDelivery(tenant.FromContext(ctx), served)
+	s.recordCombination(req)
+	s.recordDeliveries(ctx, req, matches)
+}
+
+// TrafficStats summarizes delivery traffic over the configured stats window
+func (s *TargetingService) TrafficStats() *models.TrafficStats {
+	return s.traffic.Snapshot()
+}
+
+// getMatchingCampaigns validates, matches and selects campaigns for a
+// request, trying them in the order of the given selection strategy. cacheHit
+// reports whether the matches came from the query cache.
+func (s *TargetingService) getMatchingCampaigns(ctx context.Context, req *models.DeliveryRequest, strategy string) (matches []*models.DeliveryResponse, cacheHit bool, err error) {
+	ctx, span := tracer.Start(ctx, "TargetingService.GetMatchingCampaigns")
+	defer span.End()
+
 	// Validate request
 	if err := s.validateRequest(req); err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if err := s.checkStaleness(); err != nil {
+		return nil, false, err
+	}
+	if s.killed(tenant.FromContext(ctx)) {
+		span.SetAttributes(attribute.Bool("targeting.killed", true))
+		return nil, false, nil
 	}
 
 	// Normalize request parameters
 	normalizedReq := s.normalizeRequest(req)
+	s.resolveSegments(ctx, normalizedReq)
+	span.SetAttributes(
+		attribute.String("targeting.app", normalizedReq.App),
+		attribute.String("targeting.country", normalizedReq.Country),
+		attribute.String("targeting.os", normalizedReq.OS),
+		attribute.String("targeting.device_type", normalizedReq.DeviceType),
+	)
 
 	// Check query cache first
-	cacheKey := s.generateCacheKey(normalizedReq)
-	if cached := s.getFromQueryCache(cacheKey); cached != nil {
-		return cached, nil
+	now := time.Now()
+	tenantID := tenant.FromContext(ctx)
+	cacheKey := s.generateCacheKey(tenantID, normalizedReq, now)
+	if cached, ok := s.getFromQueryCache(cacheKey); ok {
+		span.SetAttributes(attribute.Bool("targeting.query_cache_hit", true))
+		s.evaluateShadowRules(tenantID, normalizedReq.Dimensions(), now, cached)
+		return s.selectCampaigns(ctx, normalizedReq, s.orderMatches(strategy, cacheKey, cached)), true, nil
 	}
+	span.SetAttributes(attribute.Bool("targeting.query_cache_hit", false))
+
+	// Get matching campaigns. Concurrent misses on the same key share one
+	// lookup; it runs detached from the caller's cancellation so one client
+	// going away doesn't fail the others waiting on it.
+	result, err, shared := s.inflight.Do(cacheKey, func() (interface{}, error) {
+		snap := s.cache.snapshot.Load()
+		if matches, ok := s.sharedMatches(context.WithoutCancel(ctx), snap, cacheKey); ok {
+			s.setToQueryCache(cacheKey, matches)
+			return matches, nil
+		}
+		matches, err := s.findMatchingCampaigns(context.WithoutCancel(ctx), normalizedReq, now)
+		if err != nil {
+			return nil, err
+		}
+		go s.shareMatches(context.WithoutCancel(ctx), snap, cacheKey, matches)
 
-	// Get matching campaigns
-	matches, err := s.findMatchingCampaigns(ctx, normalizedReq)
+		// Cache the result before ordering and per-user filtering so it can be shared
+		s.setToQueryCache(cacheKey, matches)
+		return matches, nil
+	})
+	span.SetAttributes(attribute.Bool("targeting.shared_lookup", shared))
 	if err != nil {
-		return nil, fmt.Errorf("failed to find matching campaigns: %w", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, false, fmt.Errorf("failed to find matching campaigns: %w", err)
 	}
+	matches = result.([]*models.DeliveryResponse)
+	s.evaluateShadowRules(tenantID, normalizedReq.Dimensions(), now, matches)
 
-	// Cache the result
-	s.setToQueryCache(cacheKey, matches)
-
-	return matches, nil
+	return s.selectCampaigns(ctx, normalizedReq, s.orderMatches(strategy, cacheKey, matches)), false, nil
 }
 
-// validateRequest validates the delivery request
+// validateRequest validates the delivery request, reporting invalid fields
+// in a *validation.Error
 func (s *TargetingService) validateRequest(req *models.DeliveryRequest) error {
-	var validate = validator.New()
-	return validate.Struct(req)
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+	if req.AppVersion != "" {
+		if _, err := semver.Parse(req.AppVersion); err != nil {
+			return validation.Invalid(appVersionDimension, "must be a semantic version such as 2.3.1")
+		}
+	}
+	return nil
 }
 
 // normalizeRequest normalizes request parameters for consistent matching
 func (s *TargetingService) normalizeRequest(req *models.DeliveryRequest) *models.DeliveryRequest {
-	return &models.DeliveryRequest{
-		App:     strings.TrimSpace(req.App),
-		Country: strings.ToUpper(strings.TrimSpace(req.Country)),
-		OS:      strings.TrimSpace(req.OS),
+	normalized := &models.DeliveryRequest{
+		UserID:     strings.TrimSpace(req.UserID),
+		AppVersion: strings.TrimSpace(req.AppVersion),
+		Limit:      req.Limit,
+		Custom:     s.customDimensions(req.Custom),
+		Categories: normalizeCategories(req.Categories),
 	}
+	// Regions without a country are taken to be in the first one
+	country := normalizeDimensionValue("country", req.Country, "")
+	for _, name := range models.DeliveryDimensions {
+		values := req.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		normalize := func(value string) string { return normalizeDimensionValue(name, value, country) }
+		first := normalize(values[0])
+		normalized.SetValues(name, append([]string{first}, normalizeFurtherValues(values[1:], first, normalize)...))
+	}
+	return normalized
+}
+
+// normalizeDimensionValue normalizes a value of the delivery dimension name
+// as rules are matched against it. Bare regions are taken to be in country.
+func normalizeDimensionValue(name, value, country string) string {
+	switch name {
+	case "country":
+		return strings.ToUpper(strings.TrimSpace(value))
+	case "device_type":
+		return strings.ToLower(strings.TrimSpace(value))
+	case "region":
+		return normalizeRegion(value, country)
+	case "city":
+		return normalizeCity(value)
+	}
+	return strings.TrimSpace(value)
+}
+
+// normalizeRegion returns region as an upper-case ISO 3166-2 subdivision
+// code. A bare subdivision such as CA is prefixed with the normalized country
+// when that is a two-letter code.
+func normalizeRegion(region, country string) string {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if region == "" || strings.Contains(region, "-") || len(country) != 2 {
+		return region
+	}
+	return country + "-" + region
 }
 
-// generateCacheKey generates a cache key for the request
-func (s *TargetingService) generateCacheKey(req *models.DeliveryRequest) string {
-	return fmt.Sprintf("%s|%s|%s", req.App, req.Country, strings.ToLower(req.OS))
+// normalizeCity lower-cases a city name and collapses its whitespace
+func normalizeCity(city string) string {
+	return strings.ToLower(strings.Join(strings.Fields(city), " "))
+}
+
+// scheduleBucket is the granularity at which cached results are keyed by
+// time. Rule schedules change hour in their own timezone, every UTC offset
+// is a multiple of 15 minutes, and rule effective windows start and end on a
+// bucket.
+const scheduleBucket = 15 * time.Minute
+
+// generateCacheKey generates a cache key for the request. The key starts
+// with the tenant, so tenants never share cached results, and includes the
+// current schedule bucket so cached results never outlive a schedule
+// boundary, followed by the first value of each delivery dimension, the app
+// version, the further values of delivery dimensions, any custom dimensions
+// in name order, the placement's content categories and the user's segments.
+func (s *TargetingService) generateCacheKey(tenantID string, req *models.DeliveryRequest, now time.Time) string {
+	key := fmt.Sprintf("%s|%d", tenantID, now.Unix()/int64(scheduleBucket/time.Second))
+	var further []string
+	for _, name := range models.DeliveryDimensions {
+		values := req.Values(name)
+		if len(values) == 0 {
+			key += "|"
+			continue
+		}
+		if name == "os" {
+			values[0] = strings.ToLower(values[0])
+		}
+		key += "|" + values[0]
+		if len(values) > 1 {
+			further = append(further, "|"+name+"+="+strings.Join(values[1:], ","))
+		}
+	}
+	key += "|" + req.AppVersion + strings.Join(further, "")
+	for _, name := range sortedDimensionNames(req.Custom) {
+		key += "|" + name + "=" + req.Custom[name]
+	}
+	if len(req.Categories) > 0 {
+		key += "|categories=" + strings.Join(req.Categories, ",")
+	}
+	if len(req.Segments) > 0 {
+		key += "|" + segmentDimension + "=" + strings.Join(req.Segments, ",")
+	}
+	return key
 }
 
-// findMatchingCampaigns finds campaigns that match the targeting criteria
-func (s *TargetingService) findMatchingCampaigns(ctx context.Context, req *models.DeliveryRequest) ([]*models.DeliveryResponse, error) {
+// findMatchingCampaigns finds the campaigns of the tenant ctx acts for that
+// match the targeting criteria
+func (s *TargetingService) findMatchingCampaigns(ctx context.Context, req *models.DeliveryRequest, now time.Time) ([]*models.DeliveryResponse, error) {
+	ctx, span := tracer.Start(ctx, "TargetingService.findMatchingCampaigns")
+	defer span.End()
 
-	dimensions := []models.Dimension{
-		{Name: "os", Value: req.OS},
-		{Name: "country", Value: req.Country},
-		{Name: "app", Value: req.App},
+	tenantID := tenant.FromContext(ctx)
+	dimensions := req.Dimensions()
+
+	// Evaluate against the cached rule set once it has been loaded, so rule
+	// operators are honoured; fall back to the repository before that
+	if matches, ok := s.matchFromCache(tenantID, dimensions, req.Categories, now); ok {
+		span.SetAttributes(attribute.String("targeting.source", "cache"), attribute.Int("targeting.matches", len(matches)))
+		return matches, nil
 	}
+	span.SetAttributes(attribute.String("targeting.source", "repository"))
 
 	validCampaignIDs, err := s.repo.Campaign().GetMatchingCampaignIDs(ctx, dimensions)
 
@@ -118,11 +435,14 @@ func (s *TargetingService) findMatchingCampaigns(ctx context.Context, req *model
 		return nil, fmt.Errorf("failed to get matching campaign IDs: %w", err)
 	}
 
-	campaigns, err := s.repo.Campaign().GetCampaignsByIDs(ctx, validCampaignIDs)
+	campaigns, err := s.getCampaigns(ctx, validCampaignIDs)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get campaigns by IDs: %w", err)
 	}
+	campaigns = slices.DeleteFunc(campaigns, func(campaign *models.Campaign) bool {
+		return campaign.TenantID != tenantID || !campaign.InFlight(now) || campaign.BlockedCategory(req.Categories) != ""
+	})
 
 	if len(campaigns) == 0 {
 		return nil, nil
@@ -133,121 +453,112 @@ func (s *TargetingService) findMatchingCampaigns(ctx context.Context, req *model
 
 }
 
-func MarshalCampaignsToDeliveryResponses(campaigns []*models.Campaign) []*models.DeliveryResponse {
-	matches := make([]*models.DeliveryResponse, 0, len(campaigns))
-	for _, c := range campaigns {
-		matches = append(matches, c.ToDeliveryResponse())
-	}
-	return matches
-}
-
-// campaignMatches checks if a campaign matches the targeting criteria
-func (s *TargetingService) campaignMatches(campaignID string, req *models.DeliveryRequest) bool {
-	rules, exists := s.cache.targetingRules[campaignID]
-	if !exists || len(rules) == 0 {
-		// No targeting rules means the campaign matches all requests
-		return true
+// matchFromCache evaluates the tenant's cached campaigns and compiled rules,
+// skipping campaigns that block any of the request's content categories.
+// ok is false when the cache hasn't been populated yet.
+func (s *TargetingService) matchFromCache(tenantID string, dimensions []models.Dimension, categories []string, now time.Time) ([]*models.DeliveryResponse, bool) {
+	snap := s.cache.snapshot.Load()
+	if !snap.loaded() {
+		return nil, false
 	}
 
-	// Check each targeting rule (OR logic between rules, AND logic within a rule)
-	for _, rule := range rules {
-		if s.ruleMatches(rule, req) {
-			return true
+	var campaigns []*models.Campaign
+	for _, id := range snap.index.candidates(dimensions) {
+		campaign, exists := snap.campaigns[id]
+		if exists && campaign.TenantID == tenantID && campaign.InFlight(now) && campaign.BlockedCategory(categories) == "" && snap.campaignMatches(id, dimensions, now) {
+			campaigns = append(campaigns, campaign)
 		}
 	}
+	if len(campaigns) == 0 {
+		return nil, true
+	}
 
-	return false
+	// Index iteration order is random; keep responses stable for clients and caches
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].ID < campaigns[j].ID })
+
+	return MarshalCampaignsToDeliveryResponses(campaigns), true
 }
 
-// ruleMatches checks if a single targeting rule matches the request
-func (s *TargetingService) ruleMatches(rule *models.TargetingRule, req *models.DeliveryRequest) bool {
-	// Check country targeting
-	if !s.matchesDimension(req.Country, rule.IncludeCountry, rule.ExcludeCountry, true) {
-		return false
+// getCampaigns returns the campaigns for ids, serving from the campaign cache
+// and only going to the repository for campaigns it doesn't hold
+func (s *TargetingService) getCampaigns(ctx context.Context, ids []string) ([]*models.Campaign, error) {
+	if len(ids) == 0 {
+		return nil, nil
 	}
 
-	// Check OS targeting
-	if !s.matchesDimension(req.OS, rule.IncludeOS, rule.ExcludeOS, false) {
-		return false
-	}
+	campaigns := make([]*models.Campaign, 0, len(ids))
+	var missing []string
 
-	// Check app targeting
-	if !s.matchesDimension(req.App, rule.IncludeApp, rule.ExcludeApp, true) {
-		return false
+	snap := s.cache.snapshot.Load()
+	for _, id := range ids {
+		if campaign, exists := snap.campaigns[id]; exists {
+			campaigns = append(campaigns, campaign)
+			s.metrics.RecordCacheHit("campaign")
+		} else {
+			missing = append(missing, id)
+			s.metrics.RecordCacheMiss("campaign")
+		}
 	}
 
-	return true
-}
-
-// matchesDimension checks if a value matches the include/exclude lists for a dimension
-func (s *TargetingService) matchesDimension(value string, include, exclude []string, caseSensitive bool) bool {
-	// Check exclusions first
-	if len(exclude) > 0 {
-		if s.containsValue(exclude, value, caseSensitive) {
-			return false
-		}
+	if len(missing) == 0 {
+		return campaigns, nil
 	}
 
-	// Check inclusions
-	if len(include) > 0 {
-		return s.containsValue(include, value, caseSensitive)
+	fetched, err := s.repo.Campaign().GetCampaignsByIDs(ctx, missing)
+	if err != nil {
+		return nil, err
 	}
+	return append(campaigns, fetched...), nil
+}
 
-	// No include/exclude rules for this dimension means it matches
-	return true
+func MarshalCampaignsToDeliveryResponses(campaigns []*models.Campaign) []*models.DeliveryResponse {
+	matches := make([]*models.DeliveryResponse, 0, len(campaigns))
+	for _, c := range campaigns {
+		matches = append(matches, c.ToDeliveryResponse())
+	}
+	return matches
 }
 
-// containsValue checks if a slice contains a value
-func (s *TargetingService) containsValue(slice []string, value string, caseSensitive bool) bool {
-	for _, item := range slice {
-		if caseSensitive {
-			if item == value {
-				return true
-			}
-		} else {
-			if strings.EqualFold(item, value) {
-				return true
-			}
+// compileRules compiles the rules of a campaign, skipping and logging any
+// that can't be compiled so one bad rule never takes the cache down
+func compileRules(rules []*models.TargetingRule) []*compiledRule {
+	compiled := make([]*compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compileRule(rule)
+		if err != nil {
+			slog.Error("skipping invalid targeting rule", "rule_id", rule.ID, "campaign_id", rule.CampaignID, "error", err)
+			continue
 		}
+		compiled = append(compiled, c)
 	}
-	return false
+	return compiled
 }
 
 // getFromQueryCache retrieves a cached query result
-func (s *TargetingService) getFromQueryCache(key string) []*models.DeliveryResponse {
-	s.cache.mutex.RLock()
-	defer s.cache.mutex.RUnlock()
-
-	// Check if cache is still valid
-	if time.Since(s.cache.lastUpdate) > s.config.Cache.TTL {
-		return nil
+func (s *TargetingService) getFromQueryCache(key string) ([]*models.DeliveryResponse, bool) {
+	result, ok := s.cache.queryCache.Get(key)
+	s.traffic.RecordCacheLookup(ok)
+	if ok {
+		s.metrics.RecordCacheHit("query")
+	} else {
+		s.metrics.RecordCacheMiss("query")
 	}
-
-	if result, exists := s.cache.queryCache[key]; exists {
-		return result
-	}
-	return nil
+	return result, ok
 }
 
 // setToQueryCache stores a query result in cache
 func (s *TargetingService) setToQueryCache(key string, result []*models.DeliveryResponse) {
-	s.cache.mutex.Lock()
-	defer s.cache.mutex.Unlock()
-
-	//Implement simple LRU eviction if cache is full
-	if len(s.cache.queryCache) >= s.config.Cache.MaxSize {
-		// Remove oldest entries (simple approach - in production, use proper LRU)
-		for k := range s.cache.queryCache {
-			delete(s.cache.queryCache, k)
-			break
-		}
-	}
-
-	s.cache.queryCache[key] = result
+	s.cache.queryCache.Set(key, result)
 }
 
 // refreshCache refreshes the campaign and targeting rule cache from repository
-func (s *TargetingService) refreshCache() error {
+func (s *TargetingService) refreshCache() (err error) {
+	started := time.Now()
+	defer func() {
+		s.metrics.RecordCacheRefresh(time.Since(started), err)
+		s.recordRefreshResult(err)
+	}()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -263,54 +574,84 @@ func (s *TargetingService) refreshCache() error {
 		return fmt.Errorf("failed to get targeting rules: %w", err)
 	}
 
-	// Update cache
-	s.cache.mutex.Lock()
-	defer s.cache.mutex.Unlock()
+	// Build the new cache off to the side so requests keep being served
+	// from the current one meanwhile
+	next := buildCacheSnapshot(campaigns, targetingRules)
+	published := s.standbySnapshot(campaigns, targetingRules)
+	if published != nil {
+		next.version = published.Version
+	}
+
+	next.lastUpdate = time.Now()
+	s.cache.replace(next) // Clears the query cache too
+
+	s.mutex.Lock()
+	s.lastRefresh = next.lastUpdate
+	s.mutex.Unlock()
+	s.markWarm()
+
+	s.publishSnapshot(ctx, published)
+	return nil
+}
 
-	// Clear existing cache
-	s.cache.campaigns = make(map[string]*models.Campaign)
-	s.cache.targetingRules = make(map[string][]*models.TargetingRule)
-	s.cache.queryCache = make(map[string][]*models.DeliveryResponse) // Clear query cache too
+// buildCacheSnapshot creates an unpublished snapshot holding campaigns and
+// their targeting rules
+func buildCacheSnapshot(campaigns []*models.Campaign, targetingRules []*models.TargetingRule) *cacheSnapshot {
+	next := newCacheSnapshot()
 
 	// Populate campaigns
 	for _, campaign := range campaigns {
-		s.cache.campaigns[campaign.ID] = campaign
+		next.campaigns[campaign.ID] = campaign
 	}
 
 	// Populate targeting rules grouped by campaign ID
+	rulesByCampaign := make(map[string][]*models.TargetingRule)
 	for _, rule := range targetingRules {
-		s.cache.targetingRules[rule.CampaignID] = append(s.cache.targetingRules[rule.CampaignID], rule)
+		rulesByCampaign[rule.CampaignID] = append(rulesByCampaign[rule.CampaignID], rule)
 	}
-
-	s.cache.lastUpdate = time.Now()
-	s.lastRefresh = time.Now()
-
-	return nil
+	for campaignID, rules := range rulesByCampaign {
+		next.setCampaignRules(campaignID, rules)
+	}
+	for campaignID := range next.campaigns {
+		next.reindexCampaign(campaignID)
+	}
+	return next
 }
 
-// startCacheRefreshWorker starts a background worker to refresh cache periodically
-func (s *TargetingService) startCacheRefreshWorker() {
+// startCacheCleanupWorker starts a background worker that drops expired query
+// cache entries, along with the round robin selection positions of requests
+// no longer cached
+func (s *TargetingService) startCacheCleanupWorker() {
+	if s.config.Cache.CleanupInterval <= 0 {
+		return
+	}
+
 	ticker := time.NewTicker(s.config.Cache.CleanupInterval)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		if err := s.refreshCache(); err != nil {
-			// In production, use proper logging
-			fmt.Printf("Failed to refresh cache: %v\n", err)
-		}
+		s.cache.queryCache.RemoveExpired()
+		s.selections.retain(s.cache.queryCache.Contains)
 	}
 }
 
+// ApplyCacheConfig applies a reloaded cache config to the query cache. The
+// refresh and cleanup intervals are only read at startup.
+func (s *TargetingService) ApplyCacheConfig(cfg config.CacheConfig) {
+	s.cache.queryCache.Resize(cfg.MaxSize, cfg.TTL)
+}
+
 // GetCacheStats returns cache statistics for monitoring
 func (s *TargetingService) GetCacheStats() map[string]interface{} {
-	s.cache.mutex.RLock()
-	defer s.cache.mutex.RUnlock()
+	snap := s.cache.snapshot.Load()
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 
 	return map[string]interface{}{
-		"campaigns_count":       len(s.cache.campaigns),
-		"targeting_rules_count": len(s.cache.targetingRules),
-		"query_cache_size":      len(s.cache.queryCache),
+		"campaigns_count":       len(snap.campaigns),
+		"targeting_rules_count": len(snap.targetingRules),
+		"query_cache_size":      s.cache.queryCache.Len(),
 		"last_refresh":          s.lastRefresh,
-		"cache_age_seconds":     time.Since(s.cache.lastUpdate).Seconds(),
+		"cache_age_seconds":     time.Since(snap.lastUpdate).Seconds(),
 	}
 }
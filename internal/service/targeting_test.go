@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/config"
+	"github.com/Harshi-itaSinha/target-engine/internal/contextkey"
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/service"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// newTargetingService returns a service whose cache is loaded with campaigns
+// and rules
+func newTargetingService(t *testing.T, campaigns []*models.Campaign, rules []*models.TargetingRule) *service.TargetingService {
+	ctx := context.Background()
+	repo := repository.NewEmptyMemoryRepository()
+	for _, campaign := range campaigns {
+		require.NoError(t, repo.Campaign().CreateCampaign(ctx, campaign))
+	}
+	for _, rule := range rules {
+		require.NoError(t, repo.TargetingRule().CreateTargetingRule(ctx, rule))
+	}
+
+	cfg := &config.Config{}
+	cfg.Cache.MaxSize = 100
+	cfg.Cache.TTL = time.Minute
+	svc := service.NewTargetingService(repo, cfg, nil, nil, nil)
+	_, err := svc.RefreshCache()
+	require.NoError(t, err)
+	return svc
+}
+
+// activeCampaign returns an active campaign with the given ID
+func activeCampaign(id string) *models.Campaign {
+	return &models.Campaign{ID: id, Name: id, Image: "https://cdn.example.com/" + id + ".png", CTA: "Install", Status: models.StatusActive}
+}
+
+// servedIDs returns the IDs of the served campaigns
+func servedIDs(matches []*models.DeliveryResponse) []string {
+	var ids []string
+	for _, match := range matches {
+		ids = append(ids, match.CID)
+	}
+	return ids
+}
+
+func TestDeviceTypeCaseInsensitive(t *testing.T) {
+	svc := newTargetingService(t,
+		[]*models.Campaign{activeCampaign("tv"), activeCampaign("mobile")},
+		[]*models.TargetingRule{
+			{CampaignID: "tv", IncludeDeviceType: []string{models.DeviceTypeCTV}},
+			{CampaignID: "mobile", IncludeDeviceType: []string{models.DeviceTypePhone, models.DeviceTypeTablet}},
+		})
+
+	tests := []struct {
+		name        string
+		deviceType  string
+		deviceTypes []string
+		want        []string
+	}{
+		{name: "lower case", deviceType: "ctv", want: []string{"tv"}},
+		{name: "upper case", deviceType: "CTV", want: []string{"tv"}},
+		{name: "mixed case", deviceType: "Phone", want: []string{"mobile"}},
+		{name: "mixed case further values", deviceType: "ctv", deviceTypes: []string{"Tablet"}, want: []string{"tv", "mobile"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := svc.GetMatchingCampaigns(context.Background(), &models.DeliveryRequest{
+				App: "com.example.app", Country: "us", OS: "android",
+				DeviceType: tt.deviceType, DeviceTypes: tt.deviceTypes,
+			})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, servedIDs(matches))
+		})
+	}
+}
+
+func TestDeviceTypeUnknown(t *testing.T) {
+	svc := newTargetingService(t, nil, nil)
+
+	_, err := svc.GetMatchingCampaigns(context.Background(), &models.DeliveryRequest{
+		App: "com.example.app", Country: "us", OS: "android", DeviceType: "Watch",
+	})
+	fields := validation.Fields(err)
+	require.Len(t, fields, 1)
+	assert.Equal(t, "device_type", fields[0].Field)
+	assert.Equal(t, []string{"phone", "tablet", "ctv"}, fields[0].Allowed)
+}
+
+func TestTrackingTokenIssued(t *testing.T) {
+	tracked := activeCampaign("tracked")
+	tracked.ClickURL = "https://t.example.com/c?rid={REQUEST_ID}&token={TRACKING_TOKEN}"
+	svc := newTargetingService(t, []*models.Campaign{tracked}, []*models.TargetingRule{{CampaignID: "tracked", IncludeOS: []string{"android"}}})
+	tokens := service.NewTrackingTokens("secret", time.Hour)
+	svc.SetTrackingTokens(tokens)
+	req := &models.DeliveryRequest{App: "com.example.app", Country: "us", OS: "android"}
+
+	ctx := contextkey.WithRequestID(context.Background(), "req-1")
+	matches, err := svc.GetMatchingCampaigns(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	token := matches[0].TrackingToken
+	require.NotEmpty(t, token)
+	assert.NoError(t, tokens.Verify(token, "req-1", "tracked", "", time.Now()))
+	assert.ErrorIs(t, tokens.Verify(token, "req-2", "tracked", "", time.Now()), service.ErrInvalidTrackingToken)
+	assert.ErrorIs(t, tokens.Verify(token, "req-1", "tracked", "acme", time.Now()), service.ErrInvalidTrackingToken)
+	assert.ErrorIs(t, tokens.Verify(token, "req-1", "tracked", "", time.Now().Add(2*time.Hour)), service.ErrInvalidTrackingToken)
+	assert.Equal(t, "https://t.example.com/c?rid=req-1&token="+url.QueryEscape(token), matches[0].ClickURL)
+
+	// Without a request ID, i.e. over gRPC, no token is issued
+	matches, err = svc.GetMatchingCampaigns(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Empty(t, matches[0].TrackingToken)
+}
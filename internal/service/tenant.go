@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// getCampaign returns a campaign of the tenant ctx acts for. Campaigns of
+// other tenants are reported as not found so their existence isn't revealed.
+// While the repository is unavailable, campaigns held by the targeting cache
+// are served from it.
+func (s *TargetingService) getCampaign(ctx context.Context, id string) (*models.Campaign, error) {
+	campaign, err := s.repo.Campaign().GetCampaignByID(ctx, id)
+	if errors.Is(err, repository.ErrUnavailable) {
+		cached, ok := s.cache.snapshot.Load().campaigns[id]
+		if ok {
+			campaign, err = cached, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if campaign.TenantID != tenant.FromContext(ctx) {
+		return nil, fmt.Errorf("campaign with ID %s %w", id, repository.ErrNotFound)
+	}
+	return campaign, nil
+}
+
+// getTargetingRule returns a targeting rule of the tenant ctx acts for,
+// reporting rules of other tenants as not found
+func (s *TargetingService) getTargetingRule(ctx context.Context, id int64) (*models.TargetingRule, error) {
+	rule, err := s.repo.TargetingRule().GetTargetingRuleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rule.TenantID != tenant.FromContext(ctx) {
+		return nil, fmt.Errorf("targeting rule with ID %d %w", id, repository.ErrNotFound)
+	}
+	return rule, nil
+}
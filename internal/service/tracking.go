@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// ErrInvalidTrackingEvent is returned for impressions and clicks that can't
+// be tracked as reported
+var ErrInvalidTrackingEvent = errors.New("invalid tracking event")
+
+const (
+	// trackingDayWindow keeps daily tracking counters for a week of reporting
+	trackingDayWindow = 8 * 24 * time.Hour
+	// trackingDedupWindow is how long the request ID of a tracked event is
+	// remembered to drop repeats of it
+	trackingDedupWindow = 24 * time.Hour
+	// maxTrackingIDLength bounds the request and user IDs of tracking events
+	maxTrackingIDLength = 128
+)
+
+// trackingKeys returns the counter keys for a campaign's daily and lifetime
+// count of an event type
+func trackingKeys(eventType, campaignID, day string) (daily, total string) {
+	return fmt.Sprintf("track:%s:%s:%s", eventType, campaignID, day), fmt.Sprintf("track:%s:%s:total", eventType, campaignID)
+}
+
+// TrackEvent records an impression or click of a campaign of the tenant ctx
+// acts for. It is counted in the traffic stats and, with a counter store, in
+// the campaign's daily and lifetime counts. When impressions are counted as
+// they are tracked, an impression also counts against the campaign's budgets
+// and the user's frequency cap. Repeats of an event with the same request ID
+// are dropped. Counter store failures are logged and the event is otherwise
+// recorded.
+func (s *TargetingService) TrackEvent(ctx context.Context, event *models.TrackingEvent) error {
+	if err := validateTrackingEvent(event); err != nil {
+		return err
+	}
+	campaign, err := s.trackedCampaign(ctx, event.CampaignID)
+	if err != nil {
+		return err
+	}
+
+	if s.counters != nil && event.RequestID != "" {
+		key := fmt.Sprintf("track:seen:%s:%s:%s", event.Type, campaign.ID, event.RequestID)
+		seen, err := s.counters.Increment(ctx, key, trackingDedupWindow)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to check tracking event for repeats", "campaign_id", campaign.ID, "error", err)
+		} else if seen > 1 {
+			slog.DebugContext(ctx, "dropping repeated tracking event", "campaign_id", campaign.ID, "type", event.Type, "tracked_request_id", event.RequestID)
+			return nil
+		}
+	}
+
+	s.traffic.RecordTracking(campaign.ID, event.Type == models.TrackClick)
+	s.metrics.RecordTrackedEvent(event.Type)
+	if s.counters == nil {
+		return nil
+	}
+
+	day := time.Now().UTC().Format("20060102")
+	dailyKey, totalKey := trackingKeys(event.Type, campaign.ID, day)
+	if _, err := s.counters.Increment(ctx, dailyKey, trackingDayWindow); err != nil {
+		slog.ErrorContext(ctx, "failed to record tracking event", "campaign_id", campaign.ID, "error", err)
+	}
+	if _, err := s.counters.Increment(ctx, totalKey, budgetTotalWindow); err != nil {
+		slog.ErrorContext(ctx, "failed to record tracking event", "campaign_id", campaign.ID, "error", err)
+	}
+
+	if event.Type == models.TrackImpression && s.config.Tracking.CountImpressions {
+		match := campaign.ToDeliveryResponse()
+		s.recordSpend(ctx, match, day)
+		s.recordAppImpression(ctx, match, event.App, day)
+		if event.UserID != "" && match.FrequencyCap() > 0 {
+			if _, err := s.counters.Increment(ctx, frequencyKey(campaign.ID, event.UserID, day), frequencyWindow); err != nil {
+				slog.ErrorContext(ctx, "failed to increment frequency counter", "campaign_id", campaign.ID, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateTrackingEvent checks the fields of a tracking event
+func validateTrackingEvent(event *models.TrackingEvent) error {
+	switch {
+	case event.Type != models.TrackImpression && event.Type != models.TrackClick:
+		return fmt.Errorf("%w: %w", ErrInvalidTrackingEvent, validation.Invalid("type", "must be impression or click"))
+	case event.CampaignID == "":
+		return fmt.Errorf("%w: %w", ErrInvalidTrackingEvent, validation.Invalid("campaign_id", "is required"))
+	case len(event.RequestID) > maxTrackingIDLength:
+		return fmt.Errorf("%w: %w", ErrInvalidTrackingEvent, validation.Invalid("request_id", fmt.Sprintf("must be at most %d characters", maxTrackingIDLength)))
+	case len(event.UserID) > maxTrackingIDLength:
+		return fmt.Errorf("%w: %w", ErrInvalidTrackingEvent, validation.Invalid("user_id", fmt.Sprintf("must be at most %d characters", maxTrackingIDLength)))
+	case len(event.App) > maxTrackingIDLength:
+		return fmt.Errorf("%w: %w", ErrInvalidTrackingEvent, validation.Invalid("app", fmt.Sprintf("must be at most %d characters", maxTrackingIDLength)))
+	}
+	return nil
+}
+
+// trackedCampaign returns the campaign an event is tracked for. Events
+// arrive right after delivery, so the campaign is looked up in the targeting
+// cache first, and in the repository for campaigns that have stopped
+// serving since.
+func (s *TargetingService) trackedCampaign(ctx context.Context, id string) (*models.Campaign, error) {
+	if campaign, ok := s.cache.snapshot.Load().campaigns[id]; ok {
+		if campaign.TenantID != tenant.FromContext(ctx) {
+			return nil, fmt.Errorf("campaign with ID %s %w", id, repository.ErrNotFound)
+		}
+		return campaign, nil
+	}
+	campaign, err := s.getCampaign(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	return campaign, nil
+}
+
+// CampaignTracking returns the impressions and clicks tracked for a campaign
+// on day (YYYYMMDD, today in UTC when empty) and over its lifetime
+func (s *TargetingService) CampaignTracking(ctx context.Context, id, day string) (*models.CampaignTracking, error) {
+	if day == "" {
+		day = time.Now().UTC().Format("20060102")
+	} else if _, err := time.Parse("20060102", day); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFilter, validation.Invalid("day", "must be a date in YYYYMMDD form"))
+	}
+	if _, err := s.getCampaign(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	return s.campaignTracking(ctx, id, day)
+}
+
+// campaignTracking reads the tracking counters of a campaign for day and
+// its lifetime
+func (s *TargetingService) campaignTracking(ctx context.Context, id, day string) (*models.CampaignTracking, error) {
+	tracking := &models.CampaignTracking{CID: id, Day: day}
+	if s.counters == nil {
+		return tracking, nil
+	}
+	for _, count := range []struct {
+		eventType    string
+		daily, total *int64
+	}{
+		{models.TrackImpression, &tracking.Daily.Impressions, &tracking.Total.Impressions},
+		{models.TrackClick, &tracking.Daily.Clicks, &tracking.Total.Clicks},
+	} {
+		dailyKey, totalKey := trackingKeys(count.eventType, id, day)
+		var err error
+		if *count.daily, err = s.counters.Get(ctx, dailyKey); err != nil {
+			return nil, fmt.Errorf("failed to read tracking counter: %w", err)
+		}
+		if *count.total, err = s.counters.Get(ctx, totalKey); err != nil {
+			return nil, fmt.Errorf("failed to read tracking counter: %w", err)
+		}
+	}
+	tracking.Daily.ClickThroughRate = clickThroughRate(tracking.Daily)
+	tracking.Total.ClickThroughRate = clickThroughRate(tracking.Total)
+	return tracking, nil
+}
+
+// clickThroughRate returns the share of impressions that were clicked
+func clickThroughRate(counts models.TrackingCounts) float64 {
+	if counts.Impressions == 0 {
+		return 0
+	}
+	return float64(counts.Clicks) / float64(counts.Impressions)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/contextkey"
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+)
+
+// DefaultTrackingTokenTTL is how long a tracking token is accepted when no
+// TTL is configured, as long as repeats of its request ID are dropped
+const DefaultTrackingTokenTTL = trackingDedupWindow
+
+// ErrInvalidTrackingToken is returned for tracking tokens that weren't
+// issued for the event they come with or have expired
+var ErrInvalidTrackingToken = errors.New("invalid tracking token")
+
+// TrackingTokens issues and verifies the tokens that authenticate tracking
+// events. A token is an HMAC-SHA256 over the request ID of the delivery that
+// served a campaign, the campaign ID, the tenant and the token's expiry, so
+// it can only report events of a campaign actually served, and only until it
+// expires. Tokens have the form <expiry Unix time>.<base64url MAC>.
+type TrackingTokens struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewTrackingTokens creates tokens signed with secret and valid for ttl, or
+// DefaultTrackingTokenTTL when ttl isn't positive
+func NewTrackingTokens(secret string, ttl time.Duration) *TrackingTokens {
+	if ttl <= 0 {
+		ttl = DefaultTrackingTokenTTL
+	}
+	return &TrackingTokens{secret: []byte(secret), ttl: ttl}
+}
+
+// Issue returns a token for events of the campaign campaignID served to the
+// request requestID of tenantID at now
+func (t *TrackingTokens) Issue(requestID, campaignID, tenantID string, now time.Time) string {
+	expiry := strconv.FormatInt(now.Add(t.ttl).Unix(), 10)
+	return expiry + "." + base64.RawURLEncoding.EncodeToString(t.sign(requestID, campaignID, tenantID, expiry))
+}
+
+// Verify checks that token was issued for the request, campaign and tenant
+// of an event and hasn't expired at now
+func (t *TrackingTokens) Verify(token, requestID, campaignID, tenantID string, now time.Time) error {
+	expiry, encoded, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidTrackingToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidTrackingToken
+	}
+	if !hmac.Equal(mac, t.sign(requestID, campaignID, tenantID, expiry)) {
+		return ErrInvalidTrackingToken
+	}
+	// The expiry is only trusted once the MAC has proven it was issued
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.Unix() > expires {
+		return fmt.Errorf("%w: expired", ErrInvalidTrackingToken)
+	}
+	return nil
+}
+
+// sign returns the MAC of a token's fields. Each is prefixed with its length
+// so no two sets of fields sign the same bytes.
+func (t *TrackingTokens) sign(fields ...string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	for _, field := range fields {
+		fmt.Fprintf(mac, "%d:%s", len(field), field)
+	}
+	return mac.Sum(nil)
+}
+
+// SetTrackingTokens sets the tokens issued with every campaign served over
+// HTTP, which the tracking endpoints require to record its impressions and
+// clicks. It must be called before requests are served. Without tokens none
+// are issued.
+func (s *TargetingService) SetTrackingTokens(tokens *TrackingTokens) {
+	s.trackingTokens = tokens
+}
+
+// issueTrackingToken returns match with a tracking token for the request
+// ctx belongs to. Requests without a request ID, i.e. over gRPC, get none,
+// since events are only accepted with the request ID they were served to.
+// Matches are returned as a copy, since match may be shared through the
+// query cache.
+func (s *TargetingService) issueTrackingToken(ctx context.Context, match *models.DeliveryResponse, now time.Time) *models.DeliveryResponse {
+	requestID := contextkey.RequestID(ctx)
+	if s.trackingTokens == nil || requestID == "" {
+		return match
+	}
+	issued := *match
+	issued.TrackingToken = s.trackingTokens.Issue(requestID, match.CID, tenant.FromContext(ctx), now)
+	return &issued
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// MaxCampaignNameLength bounds the length of campaign names, in characters
+const MaxCampaignNameLength = 128
+
+// DefaultAllowedCTAs are the calls to action campaigns may use when
+// campaigns.allowedCTAs is unset
+var DefaultAllowedCTAs = []string{
+	"Install", "Download", "Play", "Open", "Learn More", "Shop Now", "Sign Up", "Subscribe", "Buy Now", "Get Offer",
+}
+
+// campaignIDPattern is the format of campaign IDs, which appear in paths and
+// cache keys: up to 64 letters, digits, dots, dashes and underscores,
+// starting with a letter or digit
+var campaignIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// clickURLMacroPattern matches anything in a click URL template that looks
+// like a macro, so misspelt ones are rejected rather than served verbatim
+var clickURLMacroPattern = regexp.MustCompile(`\{[A-Za-z0-9_]+\}`)
+
+// campaignCheck checks one aspect of a campaign payload, reporting every
+// field it finds invalid. Creating a campaign requires its ID, name, image
+// and CTA; updates only check the fields they set.
+type campaignCheck func(s *TargetingService, req *models.CampaignRequest, create bool) []models.FieldError
+
+// campaignChecks are applied to every campaign payload after its struct tags
+var campaignChecks = []campaignCheck{
+	checkCampaignRequired,
+	checkCampaignID,
+	checkCampaignName,
+	checkCampaignImages,
+	checkCampaignCTAs,
+	checkCreativeIDs,
+	checkClickURL,
+}
+
+// validateCampaignRequest validates a campaign payload, reporting every
+// invalid field at once. With create set the fields required of a new
+// campaign must be present.
+func (s *TargetingService) validateCampaignRequest(req *models.CampaignRequest, create bool) error {
+	var fields []models.FieldError
+	if err := validation.Struct(req); err != nil {
+		var validationErr *validation.Error
+		if !errors.As(err, &validationErr) {
+			return fmt.Errorf("%w: %w", ErrInvalidCampaign, err)
+		}
+		fields = append(fields, validationErr.Fields...)
+	}
+	for _, check := range campaignChecks {
+		fields = append(fields, check(s, req, create)...)
+	}
+	if len(fields) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidCampaign, &validation.Error{Fields: fields})
+	}
+	return nil
+}
+
+// checkCampaignRequired checks that a new campaign has an ID, name, image
+// and CTA
+func checkCampaignRequired(_ *TargetingService, req *models.CampaignRequest, create bool) []models.FieldError {
+	if !create {
+		return nil
+	}
+	var fields []models.FieldError
+	for _, field := range []struct{ name, value string }{
+		{"cid", req.ID}, {"name", req.Name}, {"img", req.Image}, {"cta", req.CTA},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			fields = append(fields, models.FieldError{Field: field.name, Reason: "is required"})
+		}
+	}
+	return fields
+}
+
+// checkCampaignID checks the format of the campaign ID
+func checkCampaignID(_ *TargetingService, req *models.CampaignRequest, _ bool) []models.FieldError {
+	id := strings.TrimSpace(req.ID)
+	if id == "" || campaignIDPattern.MatchString(id) {
+		return nil
+	}
+	return []models.FieldError{{Field: "cid", Reason: "must be up to 64 letters, digits, dots, dashes and underscores, starting with a letter or digit"}}
+}
+
+// checkCampaignName checks that a name set isn't blank or too long
+func checkCampaignName(_ *TargetingService, req *models.CampaignRequest, _ bool) []models.FieldError {
+	if req.Name == "" {
+		return nil
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return []models.FieldError{{Field: "name", Reason: "must not be blank"}}
+	}
+	if utf8.RuneCountInString(req.Name) > MaxCampaignNameLength {
+		return []models.FieldError{{Field: "name", Reason: fmt.Sprintf("must be at most %d characters long", MaxCampaignNameLength)}}
+	}
+	return nil
+}
+
+// checkCampaignImages checks that the images of the campaign and its
+// creatives are absolute HTTPS URLs, so they can be loaded by apps that
+// forbid cleartext traffic
+func checkCampaignImages(_ *TargetingService, req *models.CampaignRequest, _ bool) []models.FieldError {
+	var fields []models.FieldError
+	check := func(field, image string) {
+		if image == "" {
+			return
+		}
+		if reason := imageURLProblem(image); reason != "" {
+			fields = append(fields, models.FieldError{Field: field, Reason: reason})
+		}
+	}
+	check("img", req.Image)
+	for i, creative := range req.Creatives {
+		check(fmt.Sprintf("creatives[%d].img", i), creative.Image)
+	}
+	return fields
+}
+
+// imageURLProblem returns why image isn't an absolute HTTPS URL, or an empty
+// string if it is one
+func imageURLProblem(image string) string {
+	u, err := url.Parse(image)
+	switch {
+	case err != nil || !u.IsAbs() || u.Host == "":
+		return "must be an absolute URL"
+	case u.Scheme != "https":
+		return "must use https"
+	}
+	return ""
+}
+
+// checkCampaignCTAs checks that the CTAs of the campaign and its creatives
+// are allowed
+func checkCampaignCTAs(s *TargetingService, req *models.CampaignRequest, _ bool) []models.FieldError {
+	allowed := s.config.Campaigns.AllowedCTAs
+	if len(allowed) == 0 {
+		allowed = DefaultAllowedCTAs
+	}
+	if slices.Contains(allowed, "*") {
+		return nil
+	}
+
+	var fields []models.FieldError
+	check := func(field, cta string) {
+		if cta == "" {
+			return
+		}
+		if !slices.ContainsFunc(allowed, func(a string) bool { return strings.EqualFold(a, strings.TrimSpace(cta)) }) {
+			fields = append(fields, models.FieldError{Field: field, Reason: "must be one of " + strings.Join(allowed, ", "), Allowed: allowed})
+		}
+	}
+	check("cta", req.CTA)
+	for i, creative := range req.Creatives {
+		check(fmt.Sprintf("creatives[%d].cta", i), creative.CTA)
+	}
+	return fields
+}
+
+// checkCreativeIDs checks that the creatives of the campaign have distinct
+// IDs
+func checkCreativeIDs(_ *TargetingService, req *models.CampaignRequest, _ bool) []models.FieldError {
+	var fields []models.FieldError
+	seen := make(map[string]bool, len(req.Creatives))
+	for i, creative := range req.Creatives {
+		if seen[creative.ID] {
+			fields = append(fields, models.FieldError{Field: fmt.Sprintf("creatives[%d].id", i), Reason: fmt.Sprintf("duplicates creative id %q", creative.ID)})
+		}
+		seen[creative.ID] = true
+	}
+	return fields
+}
+
+// checkClickURL checks that a click URL template only uses known macros and,
+// with them expanded, is an absolute HTTPS URL
+func checkClickURL(_ *TargetingService, req *models.CampaignRequest, _ bool) []models.FieldError {
+	if req.ClickURL == "" {
+		return nil
+	}
+	for _, macro := range clickURLMacroPattern.FindAllString(req.ClickURL, -1) {
+		if !slices.Contains(ClickURLMacros, macro) {
+			return []models.FieldError{{Field: "click_url", Reason: fmt.Sprintf("uses unknown macro %s; allowed are %s", macro, strings.Join(ClickURLMacros, ", ")), Allowed: ClickURLMacros}}
+		}
+	}
+	if reason := imageURLProblem(clickURLMacroPattern.ReplaceAllString(req.ClickURL, "x")); reason != "" {
+		return []models.FieldError{{Field: "click_url", Reason: reason}}
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/config"
+	"github.com/Harshi-itaSinha/target-engine/internal/handler"
+	models "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/service"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// newValidationService returns a service over an empty memory repository
+// allowing ctas, or the default CTAs when ctas is empty
+func newValidationService(ctas ...string) *service.TargetingService {
+	cfg := &config.Config{}
+	cfg.Cache.MaxSize = 100
+	cfg.Cache.TTL = time.Minute
+	cfg.Campaigns.AllowedCTAs = ctas
+	return service.NewTargetingService(repository.NewEmptyMemoryRepository(), cfg, nil, nil, nil)
+}
+
+// validCampaign returns a campaign payload passing every check
+func validCampaign() *models.CampaignRequest {
+	return &models.CampaignRequest{
+		ID:    "summer-sale_2025.v1",
+		Name:  "Summer sale",
+		Image: "https://cdn.example.com/summer.png",
+		CTA:   "Install",
+	}
+}
+
+func TestValidateCampaign(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctas   []string
+		update bool
+		modify func(req *models.CampaignRequest)
+		// want maps every field expected to be reported to part of its
+		// reasons
+		want map[string]string
+	}{
+		{name: "valid", modify: func(req *models.CampaignRequest) {}},
+
+		// Required fields
+		{
+			name:   "required fields missing on create",
+			modify: func(req *models.CampaignRequest) { *req = models.CampaignRequest{} },
+			want:   map[string]string{"cid": "is required", "name": "is required", "img": "is required", "cta": "is required"},
+		},
+		{
+			name:   "required fields blank on create",
+			modify: func(req *models.CampaignRequest) { req.Image, req.CTA = " ", "" },
+			want:   map[string]string{"img": "is required", "cta": "is required"},
+		},
+		{
+			name:   "required fields optional on update",
+			update: true,
+			modify: func(req *models.CampaignRequest) { *req = models.CampaignRequest{} },
+		},
+
+		// ID pattern
+		{name: "id of 64 characters", modify: func(req *models.CampaignRequest) { req.ID = strings.Repeat("a", 64) }},
+		{
+			name:   "id of 65 characters",
+			modify: func(req *models.CampaignRequest) { req.ID = strings.Repeat("a", 65) },
+			want:   map[string]string{"cid": "up to 64 letters"},
+		},
+		{
+			name:   "id starting with a dash",
+			modify: func(req *models.CampaignRequest) { req.ID = "-sale" },
+			want:   map[string]string{"cid": "starting with a letter or digit"},
+		},
+		{
+			name:   "id with a slash",
+			modify: func(req *models.CampaignRequest) { req.ID = "summer/sale" },
+			want:   map[string]string{"cid": "up to 64 letters"},
+		},
+		{
+			name:   "id with a space",
+			modify: func(req *models.CampaignRequest) { req.ID = "summer sale" },
+			want:   map[string]string{"cid": "up to 64 letters"},
+		},
+
+		// Name length
+		{name: "name of 128 characters", modify: func(req *models.CampaignRequest) { req.Name = strings.Repeat("é", 128) }},
+		{
+			name:   "name of 129 characters",
+			modify: func(req *models.CampaignRequest) { req.Name = strings.Repeat("é", 129) },
+			want:   map[string]string{"name": "at most 128 characters"},
+		},
+		{
+			name:   "blank name on update",
+			update: true,
+			modify: func(req *models.CampaignRequest) { *req = models.CampaignRequest{Name: "  "} },
+			want:   map[string]string{"name": "must not be blank"},
+		},
+
+		// HTTPS-only images
+		{
+			name:   "image over http",
+			modify: func(req *models.CampaignRequest) { req.Image = "http://cdn.example.com/summer.png" },
+			want:   map[string]string{"img": "must use https"},
+		},
+		{
+			name:   "relative image",
+			modify: func(req *models.CampaignRequest) { req.Image = "/summer.png" },
+			want:   map[string]string{"img": "absolute URL"},
+		},
+		{
+			name:   "image without host",
+			modify: func(req *models.CampaignRequest) { req.Image = "https:///summer.png" },
+			want:   map[string]string{"img": "absolute URL"},
+		},
+		{
+			name: "creative image over http",
+			modify: func(req *models.CampaignRequest) {
+				req.Creatives = []models.Creative{
+					{ID: "a", Image: "https://cdn.example.com/a.png", CTA: "Install"},
+					{ID: "b", Image: "http://cdn.example.com/b.png", CTA: "Install"},
+				}
+			},
+			want: map[string]string{"creatives[1].img": "must use https"},
+		},
+
+		// CTA whitelist
+		{name: "cta in another case", modify: func(req *models.CampaignRequest) { req.CTA = "shop now" }},
+		{
+			name:   "cta not allowed",
+			modify: func(req *models.CampaignRequest) { req.CTA = "Click Here" },
+			want:   map[string]string{"cta": "must be one of Install"},
+		},
+		{
+			name: "creative cta not allowed",
+			modify: func(req *models.CampaignRequest) {
+				req.Creatives = []models.Creative{{ID: "a", Image: req.Image, CTA: "Win"}}
+			},
+			want: map[string]string{"creatives[0].cta": "must be one of"},
+		},
+		{
+			name:   "cta allowed by config",
+			ctas:   []string{"Book"},
+			modify: func(req *models.CampaignRequest) { req.CTA = "Book" },
+		},
+		{
+			name:   "default cta not allowed by config",
+			ctas:   []string{"Book"},
+			modify: func(req *models.CampaignRequest) {},
+			want:   map[string]string{"cta": "must be one of Book"},
+		},
+		{
+			name:   "any cta allowed by config",
+			ctas:   []string{"*"},
+			modify: func(req *models.CampaignRequest) { req.CTA = "Anything" },
+		},
+
+		// Creative IDs
+		{
+			name: "distinct creative ids",
+			modify: func(req *models.CampaignRequest) {
+				req.Creatives = []models.Creative{{ID: "a", Image: req.Image, CTA: "Play"}, {ID: "b", Image: req.Image, CTA: "Open"}}
+			},
+		},
+		{
+			name: "duplicate creative ids",
+			modify: func(req *models.CampaignRequest) {
+				req.Creatives = []models.Creative{
+					{ID: "a", Image: req.Image, CTA: "Play"},
+					{ID: "b", Image: req.Image, CTA: "Play"},
+					{ID: "a", Image: req.Image, CTA: "Play"},
+				}
+			},
+			want: map[string]string{"creatives[2].id": `duplicates creative id "a"`},
+		},
+
+		// Click URL macros
+		{
+			name: "click url with macros",
+			modify: func(req *models.CampaignRequest) {
+				req.ClickURL = "https://{TENANT}.track.example.com/c?cid={CID}&rid={REQUEST_ID}&ts={TIMESTAMP}"
+			},
+		},
+		{
+			name:   "click url with an unknown macro",
+			modify: func(req *models.CampaignRequest) { req.ClickURL = "https://track.example.com/c?cid={CAMPAIGN}" },
+			want:   map[string]string{"click_url": "unknown macro {CAMPAIGN}"},
+		},
+		{
+			name:   "click url with a lower-case macro",
+			modify: func(req *models.CampaignRequest) { req.ClickURL = "https://track.example.com/c?cid={cid}" },
+			want:   map[string]string{"click_url": "unknown macro {cid}"},
+		},
+		{
+			name:   "click url over http",
+			modify: func(req *models.CampaignRequest) { req.ClickURL = "http://track.example.com/c?cid={CID}" },
+			want:   map[string]string{"click_url": "must use https"},
+		},
+		{
+			name:   "click url that is only a macro",
+			modify: func(req *models.CampaignRequest) { req.ClickURL = "{APP}" },
+			want:   map[string]string{"click_url": "absolute URL"},
+		},
+
+		// Every invalid field is reported at once
+		{
+			name: "several invalid fields",
+			modify: func(req *models.CampaignRequest) {
+				req.ID, req.Image, req.CTA, req.ClickURL = "bad id", "ftp://cdn.example.com/a.png", "Nope", "https://x.example.com/{NOPE}"
+			},
+			want: map[string]string{"cid": "up to 64 letters", "img": "must use https", "cta": "must be one of", "click_url": "unknown macro"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := newValidationService(tt.ctas...)
+			req := validCampaign()
+			tt.modify(req)
+
+			var err error
+			if tt.update {
+				_, err = svc.CreateCampaign(ctx, &models.CampaignRequest{ID: "existing", Name: "Existing", Image: "https://cdn.example.com/e.png", CTA: "Install"})
+				require.NoError(t, err)
+				_, err = svc.UpdateCampaign(ctx, "existing", req)
+			} else {
+				_, err = svc.CreateCampaign(ctx, req)
+			}
+
+			if len(tt.want) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, service.ErrInvalidCampaign), "error %v should wrap ErrInvalidCampaign", err)
+
+			reported := make(map[string]string)
+			for _, field := range validation.Fields(err) {
+				reported[field.Field] += field.Reason + "; "
+			}
+			assert.Len(t, reported, len(tt.want), "reported fields: %v", reported)
+			for field, reason := range tt.want {
+				if assert.Contains(t, reported, field) {
+					assert.Contains(t, reported[field], reason)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCampaignAllowedCTAs(t *testing.T) {
+	_, err := newValidationService().CreateCampaign(context.Background(), &models.CampaignRequest{ID: "c1", Name: "n", Image: "https://cdn.example.com/a.png", CTA: "Click"})
+	fields := validation.Fields(err)
+	require.Len(t, fields, 1)
+	assert.Equal(t, service.DefaultAllowedCTAs, fields[0].Allowed)
+}
+
+func TestInvalidCampaignResponse(t *testing.T) {
+	svc := newValidationService()
+	deliveryHandler := handler.NewDeliveryHandler(svc, nil)
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/campaign", deliveryHandler.CreateCampaign).Methods(http.MethodPost)
+	router.HandleFunc("/v1/campaign/{id}", deliveryHandler.UpdateCampaign).Methods(http.MethodPut)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       *models.CampaignRequest
+		wantStatus int
+		wantFields []string
+	}{
+		{
+			name:       "valid create",
+			method:     http.MethodPost,
+			path:       "/v1/campaign",
+			body:       validCampaign(),
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "invalid create",
+			method:     http.MethodPost,
+			path:       "/v1/campaign",
+			body:       &models.CampaignRequest{ID: "bad id", Image: "http://cdn.example.com/a.png", CTA: "Nope"},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"name", "cid", "img", "cta"},
+		},
+		{
+			name:       "invalid update",
+			method:     http.MethodPut,
+			path:       "/v1/campaign/summer-sale_2025.v1",
+			body:       &models.CampaignRequest{ClickURL: "https://track.example.com/{NOPE}"},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"click_url"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			require.NoError(t, err)
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus != http.StatusUnprocessableEntity {
+				return
+			}
+			var resp models.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+			var fields []string
+			for _, field := range resp.Fields {
+				fields = append(fields, field.Field)
+			}
+			assert.ElementsMatch(t, tt.wantFields, fields)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// maxWarmUpRetryDelay caps the wait between failed initial cache loads
+const maxWarmUpRetryDelay = 30 * time.Second
+
+// warmUp loads the cache for the first time, retrying with exponential
+// backoff until a load succeeds. Until then the service reports itself as
+// not ready. It stops without loading once the cache has been warmed up from
+// a standby store, but keeps going after a restore from the cache file, whose
+// contents may be much older.
+func (s *TargetingService) warmUp() {
+	started := time.Now()
+	delay := minWatchRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		if s.warmedUp() {
+			return
+		}
+		err := s.refreshCache()
+		if err == nil {
+			break
+		}
+		if s.warmedUp() {
+			// Another refresh got there first
+			return
+		}
+		slog.Warn("cache warm-up failed, retrying", "attempt", attempt, "retry_in", delay, "error", err)
+		time.Sleep(delay)
+		if delay *= 2; delay > maxWarmUpRetryDelay {
+			delay = maxWarmUpRetryDelay
+		}
+	}
+
+	campaigns := len(s.cache.snapshot.Load().campaigns)
+	slog.Info("targeting cache warmed up", "campaigns", campaigns, "duration", time.Since(started))
+}
+
+// markWarm records that the cache has been loaded at least once
+func (s *TargetingService) markWarm() {
+	s.warmOnce.Do(func() { close(s.warmed) })
+}
+
+// warmedUp reports whether the cache has been loaded other than from the
+// cache file
+func (s *TargetingService) warmedUp() bool {
+	return s.Warm() && !s.cache.snapshot.Load().restored
+}
+
+// Warm reports whether the cache has been loaded at least once
+func (s *TargetingService) Warm() bool {
+	select {
+	case <-s.warmed:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForWarmUp blocks until the cache has been loaded at least once or ctx
+// is done
+func (s *TargetingService) WaitForWarmUp(ctx context.Context) error {
+	select {
+	case <-s.warmed:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("cache warm-up did not complete: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,104 @@
+package standby
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps the shared targeting cache in Redis. The snapshot is a
+// hash at <prefix>:snapshot with its version, save time and contents, and
+// each query result a JSON list of campaign IDs at
+// <prefix>:query:<version>:<key>, expiring with the query cache TTL.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a store keeping its keys under prefix
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	if prefix != "" {
+		prefix += ":"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// SaveSnapshot only rewrites the contents when their version changed, and
+// otherwise just records that they are still current
+func (s *RedisStore) SaveSnapshot(ctx context.Context, snapshot *Snapshot) error {
+	key := s.prefix + "snapshot"
+	savedAt := snapshot.SavedAt.UTC().Format(time.RFC3339Nano)
+
+	version, err := s.client.HGet(ctx, key, "version").Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if version == snapshot.Version {
+		return s.client.HSet(ctx, key, "saved_at", savedAt).Err()
+	}
+
+	data := snapshot.data
+	if data == nil {
+		if data, err = json.Marshal(snapshotData{Campaigns: snapshot.Campaigns, Rules: snapshot.Rules}); err != nil {
+			return err
+		}
+	}
+	return s.client.HSet(ctx, key, "version", snapshot.Version, "saved_at", savedAt, "data", data).Err()
+}
+
+func (s *RedisStore) LoadSnapshot(ctx context.Context) (*Snapshot, error) {
+	fields, err := s.client.HGetAll(ctx, s.prefix+"snapshot").Result()
+	if err != nil {
+		return nil, err
+	}
+	if fields["version"] == "" {
+		return nil, nil
+	}
+
+	savedAt, err := time.Parse(time.RFC3339Nano, fields["saved_at"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot save time: %w", err)
+	}
+	var contents snapshotData
+	if err := json.Unmarshal([]byte(fields["data"]), &contents); err != nil {
+		return nil, fmt.Errorf("invalid snapshot contents: %w", err)
+	}
+	return &Snapshot{
+		Version:   fields["version"],
+		SavedAt:   savedAt,
+		Campaigns: contents.Campaigns,
+		Rules:     contents.Rules,
+	}, nil
+}
+
+func (s *RedisStore) SaveQuery(ctx context.Context, version, key string, campaignIDs []string, ttl time.Duration) error {
+	data, err := json.Marshal(campaignIDs)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.queryKey(version, key), data, ttl).Err()
+}
+
+func (s *RedisStore) LoadQuery(ctx context.Context, version, key string) ([]string, bool, error) {
+	data, err := s.client.Get(ctx, s.queryKey(version, key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var campaignIDs []string
+	if err := json.Unmarshal(data, &campaignIDs); err != nil {
+		return nil, false, fmt.Errorf("invalid query result: %w", err)
+	}
+	return campaignIDs, true, nil
+}
+
+// queryKey returns the key of a query result
+func (s *RedisStore) queryKey(version, key string) string {
+	return s.prefix + "query:" + version + ":" + key
+}
@@ -0,0 +1,78 @@
+// Package standby shares the targeting cache between instances. An instance
+// publishes the campaigns and rules it loads from the repository, so new
+// instances can warm up from them without querying the repository, and the
+// query results it computes, so instances holding the same cache share them.
+package standby
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// Snapshot is the contents of a targeting cache as loaded from the repository
+type Snapshot struct {
+	// Version identifies the contents, so instances holding equal caches
+	// agree on it whenever they loaded them
+	Version   string
+	SavedAt   time.Time
+	Campaigns []*model.Campaign
+	Rules     []*model.TargetingRule
+
+	// data is the encoded contents the version was computed from
+	data []byte
+}
+
+// snapshotData is the encoded form of the contents of a snapshot
+type snapshotData struct {
+	Campaigns []*model.Campaign      `json:"campaigns"`
+	Rules     []*model.TargetingRule `json:"rules"`
+}
+
+// NewSnapshot creates the snapshot of a cache holding campaigns and rules.
+// They are ordered by ID first, since the version must not depend on the
+// order the repository returned them in.
+func NewSnapshot(campaigns []*model.Campaign, rules []*model.TargetingRule) (*Snapshot, error) {
+	campaigns = slices.SortedFunc(slices.Values(campaigns), func(a, b *model.Campaign) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	rules = slices.SortedFunc(slices.Values(rules), func(a, b *model.TargetingRule) int {
+		return cmp.Or(strings.Compare(a.CampaignID, b.CampaignID), cmp.Compare(a.ID, b.ID))
+	})
+	data, err := json.Marshal(snapshotData{Campaigns: campaigns, Rules: rules})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return &Snapshot{
+		Version:   hex.EncodeToString(sum[:16]),
+		SavedAt:   time.Now(),
+		Campaigns: campaigns,
+		Rules:     rules,
+		data:      data,
+	}, nil
+}
+
+// Store keeps the shared targeting cache
+type Store interface {
+	// SaveSnapshot publishes a snapshot, replacing the previous one
+	SaveSnapshot(ctx context.Context, snapshot *Snapshot) error
+
+	// LoadSnapshot returns the last published snapshot, or nil if there is none
+	LoadSnapshot(ctx context.Context) (*Snapshot, error)
+
+	// SaveQuery stores the IDs of the campaigns matching the query cached
+	// under key by a cache of the given version, for ttl
+	SaveQuery(ctx context.Context, version, key string, campaignIDs []string, ttl time.Duration) error
+
+	// LoadQuery returns the IDs saved for the query cached under key by a
+	// cache of the given version. ok is false if none are.
+	LoadQuery(ctx context.Context, version, key string) (campaignIDs []string, ok bool, err error)
+}
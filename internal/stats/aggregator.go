@@ -0,0 +1,324 @@
+// Package stats aggregates delivery traffic over a sliding window, for the
+// breakdowns served by the stats endpoint. Prometheus metrics cover long-term
+// trends; this answers "what is the engine doing right now" without one. It
+// also keeps the frequency tables of request dimensions reach is estimated
+// from.
+package stats
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// DefaultWindow is the sliding window used when none is configured
+const DefaultWindow = time.Minute
+
+const (
+	// bucketCount is the number of buckets the window is divided into; the
+	// window slides one bucket at a time
+	bucketCount = 60
+	// maxDimensionValues bounds the distinct countries or OSes counted per
+	// bucket, since they come from clients; the rest are counted as other
+	maxDimensionValues = 256
+	otherValue         = "other"
+	topCampaignCount   = 10
+)
+
+// latencyBounds are the upper bounds of the latency histogram bins, growing
+// by 25% from 10µs to about 10s. Percentiles are reported as the upper bound
+// of the bin they fall in.
+var latencyBounds = func() []time.Duration {
+	var bounds []time.Duration
+	for bound := float64(10 * time.Microsecond); bound < float64(10*time.Second); bound *= 1.25 {
+		bounds = append(bounds, time.Duration(bound))
+	}
+	return bounds
+}()
+
+// bucket holds the traffic of one slice of the window
+type bucket struct {
+	epoch       int64 // start of the slice, in bucket widths since the Unix epoch
+	requests    int64
+	matched     int64
+	cacheHits   int64
+	cacheMisses int64
+	latency     []int64 // counts per latencyBounds bin, plus one for slower requests
+	impressions int64
+	clicks      int64
+	countries   map[string]*dimensionCount
+	os          map[string]*dimensionCount
+	campaigns   map[string]*campaignCount
+}
+
+// dimensionCount counts the requests carrying a dimension value and how
+// many of them matched
+type dimensionCount struct {
+	requests int64
+	matched  int64
+}
+
+// campaignCount counts how often a campaign was served and the impressions
+// and clicks tracked for it
+type campaignCount struct {
+	served      int64
+	impressions int64
+	clicks      int64
+}
+
+// Aggregator counts delivery traffic in a ring of time buckets. It is safe
+// for concurrent use.
+type Aggregator struct {
+	mutex   sync.Mutex
+	width   time.Duration
+	buckets [bucketCount]bucket
+	started time.Time
+}
+
+// New creates an aggregator over the given window, or DefaultWindow if it
+// isn't positive
+func New(window time.Duration) *Aggregator {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	a := &Aggregator{
+		width:   max(window/bucketCount, time.Millisecond),
+		started: time.Now(),
+	}
+	for i := range a.buckets {
+		a.buckets[i] = bucket{
+			epoch:     -1,
+			latency:   make([]int64, len(latencyBounds)+1),
+			countries: make(map[string]*dimensionCount),
+			os:        make(map[string]*dimensionCount),
+			campaigns: make(map[string]*campaignCount),
+		}
+	}
+	return a
+}
+
+// current returns the bucket for now, clearing it if it last held an older
+// slice. The caller holds the mutex.
+func (a *Aggregator) current(now time.Time) *bucket {
+	epoch := now.UnixNano() / int64(a.width)
+	b := &a.buckets[epoch%bucketCount]
+	if b.epoch != epoch {
+		b.epoch = epoch
+		b.requests, b.matched, b.cacheHits, b.cacheMisses = 0, 0, 0, 0
+		b.impressions, b.clicks = 0, 0
+		clear(b.latency)
+		clear(b.countries)
+		clear(b.os)
+		clear(b.campaigns)
+	}
+	return b
+}
+
+// RecordDelivery counts a served delivery request with the IDs of the
+// campaigns it was served and how long matching took
+func (a *Aggregator) RecordDelivery(country, os string, served []string, latency time.Duration) {
+	matched := len(served) > 0
+	country = strings.ToUpper(strings.TrimSpace(country))
+	os = strings.ToLower(strings.TrimSpace(os))
+	bin := sort.Search(len(latencyBounds), func(i int) bool { return latencyBounds[i] >= latency })
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	b := a.current(time.Now())
+	b.requests++
+	if matched {
+		b.matched++
+	}
+	b.latency[bin]++
+	countDimension(b.countries, country, matched)
+	countDimension(b.os, os, matched)
+	for _, id := range served {
+		campaign(b.campaigns, id).served++
+	}
+}
+
+// RecordTracking counts an impression, or a click, tracked for a campaign
+func (a *Aggregator) RecordTracking(campaignID string, click bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	b := a.current(time.Now())
+	c := campaign(b.campaigns, campaignID)
+	if click {
+		b.clicks++
+		c.clicks++
+	} else {
+		b.impressions++
+		c.impressions++
+	}
+}
+
+// campaign returns the counts of a campaign, adding them if missing
+func campaign(counts map[string]*campaignCount, id string) *campaignCount {
+	c, ok := counts[id]
+	if !ok {
+		c = &campaignCount{}
+		counts[id] = c
+	}
+	return c
+}
+
+// countDimension counts a request under value, or under other once the
+// bucket holds maxDimensionValues distinct values
+func countDimension(counts map[string]*dimensionCount, value string, matched bool) {
+	c, ok := counts[value]
+	if !ok {
+		if len(counts) >= maxDimensionValues {
+			value = otherValue
+		}
+		if c, ok = counts[value]; !ok {
+			c = &dimensionCount{}
+			counts[value] = c
+		}
+	}
+	c.requests++
+	if matched {
+		c.matched++
+	}
+}
+
+// RecordCacheLookup counts a query cache hit or miss
+func (a *Aggregator) RecordCacheLookup(hit bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	b := a.current(time.Now())
+	if hit {
+		b.cacheHits++
+	} else {
+		b.cacheMisses++
+	}
+}
+
+// Snapshot summarizes the traffic over the window. Until the process has run
+// for a whole window, rates are computed over its uptime.
+func (a *Aggregator) Snapshot() *model.TrafficStats {
+	now := time.Now()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var requests, matched, hits, misses, impressions, clicks int64
+	latency := make([]int64, len(latencyBounds)+1)
+	countries := make(map[string]*dimensionCount)
+	oses := make(map[string]*dimensionCount)
+	campaigns := make(map[string]*campaignCount)
+
+	oldest := now.UnixNano()/int64(a.width) - bucketCount
+	for i := range a.buckets {
+		b := &a.buckets[i]
+		if b.epoch <= oldest {
+			continue
+		}
+		requests += b.requests
+		matched += b.matched
+		hits += b.cacheHits
+		misses += b.cacheMisses
+		impressions += b.impressions
+		clicks += b.clicks
+		for bin, n := range b.latency {
+			latency[bin] += n
+		}
+		mergeDimension(countries, b.countries)
+		mergeDimension(oses, b.os)
+		for id, c := range b.campaigns {
+			merged := campaign(campaigns, id)
+			merged.served += c.served
+			merged.impressions += c.impressions
+			merged.clicks += c.clicks
+		}
+	}
+
+	// Right after startup, rates over a few milliseconds of uptime would be
+	// meaningless, so cover at least one bucket
+	window := max(min(a.width*bucketCount, now.Sub(a.started)), a.width)
+	stats := &model.TrafficStats{
+		WindowSeconds: window.Seconds(),
+		Requests:      requests,
+		MatchRate:     ratio(matched, requests),
+		LatencyMS: model.LatencyPercentiles{
+			P50: percentile(latency, requests, 0.50),
+			P95: percentile(latency, requests, 0.95),
+			P99: percentile(latency, requests, 0.99),
+		},
+		CacheHitRatio: ratio(hits, hits+misses),
+		Countries:     dimensionStats(countries),
+		OS:            dimensionStats(oses),
+		TopCampaigns:  topCampaigns(campaigns),
+		Impressions:   impressions,
+		Clicks:        clicks,
+	}
+	stats.ClickThroughRate = ratio(clicks, impressions)
+	stats.RequestsPerSecond = float64(requests) / window.Seconds()
+	return stats
+}
+
+func mergeDimension(dst, src map[string]*dimensionCount) {
+	for value, c := range src {
+		merged, ok := dst[value]
+		if !ok {
+			merged = &dimensionCount{}
+			dst[value] = merged
+		}
+		merged.requests += c.requests
+		merged.matched += c.matched
+	}
+}
+
+func dimensionStats(counts map[string]*dimensionCount) map[string]model.DimensionStats {
+	stats := make(map[string]model.DimensionStats, len(counts))
+	for value, c := range counts {
+		stats[value] = model.DimensionStats{Requests: c.requests, MatchRate: ratio(c.matched, c.requests)}
+	}
+	return stats
+}
+
+// topCampaigns returns the most served campaigns, most served first
+func topCampaigns(counts map[string]*campaignCount) []model.CampaignServed {
+	top := make([]model.CampaignServed, 0, len(counts))
+	for id, c := range counts {
+		top = append(top, model.CampaignServed{CID: id, Served: c.served, Impressions: c.impressions, Clicks: c.clicks})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Served != top[j].Served {
+			return top[i].Served > top[j].Served
+		}
+		return top[i].CID < top[j].CID
+	})
+	if len(top) > topCampaignCount {
+		top = top[:topCampaignCount]
+	}
+	return top
+}
+
+// percentile returns the q-th latency percentile in milliseconds
+func percentile(bins []int64, total int64, q float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(total)))
+	var seen int64
+	for bin, n := range bins {
+		if seen += n; seen >= rank {
+			bound := latencyBounds[min(bin, len(latencyBounds)-1)]
+			return float64(bound) / float64(time.Millisecond)
+		}
+	}
+	return float64(latencyBounds[len(latencyBounds)-1]) / float64(time.Millisecond)
+}
+
+func ratio(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults of Frequencies
+const (
+	DefaultReachWindow     = time.Hour
+	DefaultMaxCombinations = 10000
+)
+
+// reachBucketCount is the number of buckets the reach window is divided into
+const reachBucketCount = 12
+
+// Combination is the built-in dimension values of a delivery request,
+// normalized the way requests are matched. Only the first value of each
+// dimension is recorded, and custom dimensions and user segments aren't.
+type Combination struct {
+	App        string
+	Country    string
+	OS         string
+	DeviceType string
+	Region     string
+	City       string
+	AppVersion string
+}
+
+// reachBucket counts the combinations of one slice of the window
+type reachBucket struct {
+	epoch     int64
+	counts    map[Combination]int64
+	untracked int64
+}
+
+// Frequencies counts how often each combination of request dimensions was
+// seen over a sliding window, so the reach of proposed targeting rules can
+// be estimated from past traffic. Each slice of the window tracks at most
+// maxCombinations distinct combinations; requests with further ones are
+// only counted as untracked. It is safe for concurrent use.
+type Frequencies struct {
+	mutex           sync.Mutex
+	width           time.Duration
+	maxCombinations int
+	buckets         [reachBucketCount]reachBucket
+	started         time.Time
+}
+
+// FrequencyTable is the combinations seen over a window and how often
+type FrequencyTable struct {
+	Counts map[Combination]int64
+	// Untracked counts requests whose combination wasn't recorded because
+	// their slice of the window was full
+	Untracked int64
+	// Window is the time covered, shorter than the configured window until
+	// the process has run for a whole one
+	Window time.Duration
+}
+
+// NewFrequencies creates frequency tables over window, keeping at most
+// maxCombinations per slice of it. Non-positive values use
+// DefaultReachWindow and DefaultMaxCombinations.
+func NewFrequencies(window time.Duration, maxCombinations int) *Frequencies {
+	if window <= 0 {
+		window = DefaultReachWindow
+	}
+	if maxCombinations <= 0 {
+		maxCombinations = DefaultMaxCombinations
+	}
+	f := &Frequencies{
+		width:           max(window/reachBucketCount, time.Millisecond),
+		maxCombinations: maxCombinations,
+		started:         time.Now(),
+	}
+	for i := range f.buckets {
+		f.buckets[i] = reachBucket{epoch: -1, counts: make(map[Combination]int64)}
+	}
+	return f
+}
+
+// Record counts a request with the given combination
+func (f *Frequencies) Record(c Combination) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	epoch := time.Now().UnixNano() / int64(f.width)
+	b := &f.buckets[epoch%reachBucketCount]
+	if b.epoch != epoch {
+		b.epoch = epoch
+		b.untracked = 0
+		clear(b.counts)
+	}
+	if _, ok := b.counts[c]; !ok && len(b.counts) >= f.maxCombinations {
+		b.untracked++
+		return
+	}
+	b.counts[c]++
+}
+
+// Table merges the combinations seen over the window
+func (f *Frequencies) Table() *FrequencyTable {
+	now := time.Now()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	table := &FrequencyTable{Counts: make(map[Combination]int64)}
+	oldest := now.UnixNano()/int64(f.width) - reachBucketCount
+	for i := range f.buckets {
+		b := &f.buckets[i]
+		if b.epoch <= oldest {
+			continue
+		}
+		for c, n := range b.counts {
+			table.Counts[c] += n
+		}
+		table.Untracked += b.untracked
+	}
+	table.Window = max(min(f.width*reachBucketCount, now.Sub(f.started)), f.width)
+	return table
+}
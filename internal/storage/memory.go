@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCounterStore is an in-process CounterStore. Counters are not shared
+// between instances.
+type MemoryCounterStore struct {
+	counters map[string]*memoryCounter
+	mutex    sync.Mutex
+}
+
+type memoryCounter struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// NewMemoryCounterStore creates an empty in-memory counter store
+func NewMemoryCounterStore() *MemoryCounterStore {
+	return &MemoryCounterStore{
+		counters: make(map[string]*memoryCounter),
+	}
+}
+
+func (s *MemoryCounterStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	counter, exists := s.counters[key]
+	if !exists || now.After(counter.expiresAt) {
+		counter = &memoryCounter{expiresAt: now.Add(ttl)}
+		s.counters[key] = counter
+	}
+	counter.value++
+
+	return counter.value, nil
+}
+
+func (s *MemoryCounterStore) Get(ctx context.Context, key string) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counter, exists := s.counters[key]
+	if !exists || time.Now().After(counter.expiresAt) {
+		return 0, nil
+	}
+	return counter.value, nil
+}
+
+// Cleanup removes expired counters
+func (s *MemoryCounterStore) Cleanup() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for key, counter := range s.counters {
+		if now.After(counter.expiresAt) {
+			delete(s.counters, key)
+		}
+	}
+}
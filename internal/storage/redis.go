@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCounterStore is a CounterStore shared by every instance using the same Redis
+type RedisCounterStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisCounterStore creates a Redis backed counter store. prefix namespaces every key.
+func NewRedisCounterStore(client *redis.Client, prefix string) *RedisCounterStore {
+	if prefix != "" {
+		prefix += ":"
+	}
+	return &RedisCounterStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisCounterStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, s.prefix+key)
+		pipe.ExpireNX(ctx, s.prefix+key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (s *RedisCounterStore) Get(ctx context.Context, key string) (int64, error) {
+	value, err := s.client.Get(ctx, s.prefix+key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return value, err
+}
@@ -1 +1,18 @@
-package storage
\ No newline at end of file
+// Package storage provides counter stores used to enforce delivery limits
+// such as frequency caps
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// CounterStore keeps integer counters that expire after a TTL
+type CounterStore interface {
+	// Increment atomically adds one to key and returns the new value. The TTL
+	// is applied when the counter is first created.
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
+	// Get returns the current value of key, or zero if it doesn't exist
+	Get(ctx context.Context, key string) (int64, error)
+}
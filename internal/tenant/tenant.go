@@ -0,0 +1,45 @@
+// Package tenant carries the tenant a request acts for. Campaigns and
+// targeting rules belong to exactly one tenant and are only visible to
+// requests acting for it. The empty ID is the default tenant, which holds
+// all data when multi-tenancy is disabled.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// MaxIDLength bounds the length of a tenant ID
+const MaxIDLength = 64
+
+// ErrInvalidID is returned for tenant IDs that aren't 1 to MaxIDLength
+// letters, digits, dashes or underscores
+var ErrInvalidID = errors.New("invalid tenant ID")
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type contextKey struct{}
+
+// WithID returns a copy of ctx acting for the tenant
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant ctx acts for, or the default tenant
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Validate checks that id is a well-formed tenant ID. The default tenant is
+// valid.
+func Validate(id string) error {
+	if id == "" {
+		return nil
+	}
+	if len(id) > MaxIDLength || !validID.MatchString(id) {
+		return ErrInvalidID
+	}
+	return nil
+}
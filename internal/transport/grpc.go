@@ -0,0 +1,128 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	deliveryv1 "github.com/Harshi-itaSinha/target-engine/api/delivery/v1"
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/service"
+	"github.com/Harshi-itaSinha/target-engine/internal/tenant"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DeliveryServer implements the gRPC Delivery service on top of the targeting service
+type DeliveryServer struct {
+	deliveryv1.UnimplementedDeliveryServer
+	targetingService service.Targeting
+}
+
+// NewDeliveryServer creates a new gRPC delivery server
+func NewDeliveryServer(targetingService service.Targeting) *DeliveryServer {
+	return &DeliveryServer{
+		targetingService: targetingService,
+	}
+}
+
+// NewGRPCServer creates a gRPC server with the delivery service registered.
+// When tenantHeader is set, calls act for the tenant named in that metadata
+// key; otherwise they act for the default tenant.
+func NewGRPCServer(targetingService service.Targeting, tenantHeader string) *grpc.Server {
+	var opts []grpc.ServerOption
+	if tenantHeader != "" {
+		opts = append(opts, grpc.UnaryInterceptor(tenantInterceptor(tenantHeader)))
+	}
+	server := grpc.NewServer(opts...)
+	deliveryv1.RegisterDeliveryServer(server, NewDeliveryServer(targetingService))
+	return server
+}
+
+// tenantInterceptor scopes each call to the tenant named in the header
+// metadata key
+func tenantInterceptor(header string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var id string
+		if values := metadata.ValueFromIncomingContext(ctx, header); len(values) > 0 {
+			id = strings.TrimSpace(values[0])
+		}
+		if err := tenant.Validate(id); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return handler(tenant.WithID(ctx, id), req)
+	}
+}
+
+// GetCampaigns returns the campaigns matching the request dimensions
+func (s *DeliveryServer) GetCampaigns(ctx context.Context, in *deliveryv1.GetCampaignsRequest) (*deliveryv1.GetCampaignsResponse, error) {
+	req := &model.DeliveryRequest{
+		App:        in.GetApp(),
+		Country:    in.GetCountry(),
+		OS:         in.GetOs(),
+		DeviceType: in.GetDeviceType(),
+		Region:     in.GetRegion(),
+		City:       in.GetCity(),
+		UserID:     in.GetUserId(),
+		AppVersion: in.GetAppVersion(),
+		Limit:      int(in.GetLimit()),
+		Custom:     in.GetCustom(),
+	}
+
+	campaigns, err := s.targetingService.GetMatchingCampaigns(ctx, req)
+	if err != nil {
+		if fields := validation.Fields(err); fields != nil {
+			return nil, invalidArgument(err, fields)
+		}
+		if errors.Is(err, service.ErrCacheStale) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	// Mirror the X-Cache-Stale header of the HTTP API
+	if s.targetingService.ServingStale() {
+		grpc.SetHeader(ctx, metadata.Pairs("x-cache-stale", "true"))
+	}
+
+	resp := &deliveryv1.GetCampaignsResponse{
+		Campaigns: make([]*deliveryv1.Campaign, 0, len(campaigns)),
+	}
+	for _, c := range campaigns {
+		campaign := &deliveryv1.Campaign{
+			Cid:      c.CID,
+			Img:      c.Image,
+			Cta:      c.CTA,
+			Creative: c.Creative,
+			ClickUrl: c.ClickURL,
+		}
+		if c.Experiment != nil {
+			campaign.Experiment = &deliveryv1.ExperimentAssignment{
+				Name:   c.Experiment.Name,
+				Bucket: int32(c.Experiment.Bucket),
+			}
+		}
+		resp.Campaigns = append(resp.Campaigns, campaign)
+	}
+	return resp, nil
+}
+
+// invalidArgument returns an InvalidArgument status carrying the invalid
+// fields as BadRequest details
+func invalidArgument(err error, fields []model.FieldError) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(fields))
+	for i, field := range fields {
+		violations[i] = &errdetails.BadRequest_FieldViolation{
+			Field:       field.Field,
+			Description: field.Reason,
+		}
+	}
+	if detailed, detailErr := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); detailErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
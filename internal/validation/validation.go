@@ -0,0 +1,118 @@
+// Package validation validates request payloads and reports failures per
+// field, using the JSON names API consumers know the fields by
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// validate is shared since a validator caches struct metadata
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// Error is returned when a payload fails validation. It lists every invalid
+// field.
+type Error struct {
+	Fields []model.FieldError
+}
+
+// Invalid returns an Error for a single field, for checks that struct tags
+// can't express
+func Invalid(field, reason string) *Error {
+	return &Error{Fields: []model.FieldError{{Field: field, Reason: reason}}}
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		parts[i] = field.Field + " " + field.Reason
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Struct validates v against its validate struct tags, returning an *Error
+// describing every invalid field
+func Struct(v any) error {
+	err := validate.Struct(v)
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	fields := make([]model.FieldError, len(validationErrs))
+	for i, fe := range validationErrs {
+		fields[i] = fieldError(fe)
+	}
+	return &Error{Fields: fields}
+}
+
+// Fields returns the invalid fields reported by an *Error in err's chain, or
+// nil if there is none
+func Fields(err error) []model.FieldError {
+	var validationErr *Error
+	if errors.As(err, &validationErr) {
+		return validationErr.Fields
+	}
+	return nil
+}
+
+// fieldError translates a validator failure
+func fieldError(fe validator.FieldError) model.FieldError {
+	field := model.FieldError{Field: fieldPath(fe.Namespace())}
+
+	switch fe.Tag() {
+	case "required":
+		field.Reason = "is required"
+	case "oneof", "oneofci":
+		field.Allowed = strings.Fields(fe.Param())
+		field.Reason = "must be one of " + strings.Join(field.Allowed, ", ")
+	case "min":
+		field.Reason = "must be at least " + bound(fe)
+	case "max":
+		field.Reason = "must be at most " + bound(fe)
+	default:
+		field.Reason = fmt.Sprintf("failed %s validation", fe.Tag())
+	}
+	return field
+}
+
+// fieldPath drops the name of the top-level struct from a namespace such as
+// CampaignRequest.creatives[0].cta
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+// bound describes the param of a min or max failure in the field's terms
+func bound(fe validator.FieldError) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return fe.Param() + " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fe.Param() + " items"
+	default:
+		return fe.Param()
+	}
+}
@@ -2,74 +2,225 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
 	"github.com/Harshi-itaSinha/target-engine/internal/config"
 	"github.com/Harshi-itaSinha/target-engine/internal/database.go"
+	"github.com/Harshi-itaSinha/target-engine/internal/errortracking"
+	"github.com/Harshi-itaSinha/target-engine/internal/events"
+	"github.com/Harshi-itaSinha/target-engine/internal/geo"
 	"github.com/Harshi-itaSinha/target-engine/internal/handler"
+	"github.com/Harshi-itaSinha/target-engine/internal/logger"
 	"github.com/Harshi-itaSinha/target-engine/internal/middleware"
+	"github.com/Harshi-itaSinha/target-engine/internal/recording"
 	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/segments"
 	"github.com/Harshi-itaSinha/target-engine/internal/service"
+	"github.com/Harshi-itaSinha/target-engine/internal/standby"
+	"github.com/Harshi-itaSinha/target-engine/internal/storage"
+	"github.com/Harshi-itaSinha/target-engine/internal/tracing"
+	"github.com/Harshi-itaSinha/target-engine/internal/transport"
 	"github.com/Harshi-itaSinha/target-engine/monitoring"
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
 )
 
 func main() {
 
 	cfg := config.LoadConfig()
+	if err := logger.Init(cfg.Log.Level, cfg.Log.Format); err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
 	// repo := repository.NewMemoryRepository()
 	// defer repo.Close()
 
-	// 2. Initialize MongoDB client
-	uri := config.GetEnv("MONGO_URI")
-	//cfg.Database.ConnectionString
-	dbClient, err := database.NewMongoClient(uri)
-	if err != nil {
-		log.Fatalf("Failed to initialize MongoDB client: %v", err)
+	var metrics *monitoring.Metrics
+	if cfg.Metrics.Enabled {
+		metrics = monitoring.NewMetrics()
+		labels := cfg.Metrics.Labels
+		if labels.Tenant {
+			metrics.EnableTenantLabels(labels.TopTenants, labels.RankInterval)
+		}
+		if labels.Campaign {
+			metrics.EnableCampaignLabels(labels.TopCampaigns, labels.RankInterval)
+		}
 	}
 
-	// 3. Get the database
-	database := dbClient.Database(cfg.Database.DatabaseName)
-
-	// 4. Initialize repository with MongoDB database and client
-	repo := repository.NewRepository(database, dbClient)
+	// 2. Initialize the repository for the configured driver
+	repo, err := newRepository(cfg, metrics)
+	if err != nil {
+		log.Fatalf("Failed to initialize repository: %v", err)
+	}
 	defer func() {
 		if err := repo.Close(); err != nil {
 			log.Printf("Failed to close repository: %v", err)
 		}
 	}()
-	defer repo.Close()
 
-	targetingService := service.NewTargetingService(repo, cfg)
+	migrateCtx, migrateCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := repo.Migrate(migrateCtx); err != nil {
+		log.Printf("Failed to migrate repository: %v", err)
+	}
+	migrateCancel()
 
-	deliveryHandler := handler.NewDeliveryHandler(targetingService)
+	if cfg.Tracing.Enabled {
+		shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+		if err != nil {
+			log.Fatalf("Failed to initialize tracing: %v", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				log.Printf("Failed to flush traces: %v", err)
+			}
+		}()
+	}
 
-	var metrics *monitoring.Metrics
-	if cfg.Metrics.Enabled {
-		metrics = monitoring.NewMetrics()
+	var reporter errortracking.Reporter
+	if cfg.ErrorTracking.Enabled {
+		sentryReporter, err := errortracking.NewSentryReporter(cfg.ErrorTracking)
+		if err != nil {
+			log.Fatalf("Failed to initialize error tracking: %v", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := sentryReporter.Close(ctx); err != nil {
+				log.Printf("Failed to flush panic reports: %v", err)
+			}
+		}()
+		reporter = sentryReporter
+	}
+
+	counters, err := newCounterStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize counter store: %v", err)
+	}
+
+	var publisher events.Publisher
+	if cfg.Events.Enabled {
+		kafkaPublisher := events.NewKafkaPublisher(cfg.Events)
+		defer func() {
+			if err := kafkaPublisher.Close(); err != nil {
+				log.Printf("Failed to close event publisher: %v", err)
+			}
+		}()
+		publisher = kafkaPublisher
+	}
+
+	if !service.ValidSelectionStrategy(cfg.Delivery.SelectStrategy) {
+		log.Fatalf("Unknown delivery select strategy %q", cfg.Delivery.SelectStrategy)
+	}
+	targetingService := service.NewTargetingService(repo, cfg, metrics, counters, publisher)
+	segmentProvider, err := newSegmentProvider(cfg.Segments)
+	if err != nil {
+		log.Fatalf("Failed to initialize segment provider: %v", err)
+	}
+	if segmentProvider != nil {
+		targetingService.SetSegmentProvider(segmentProvider)
+	}
+	if cfg.Cache.Standby.Enabled {
+		client, err := database.NewRedisClient(cfg.Cache.Standby.RedisURI)
+		if err != nil {
+			log.Fatalf("Failed to initialize cache standby: %v", err)
+		}
+		standbyStore := standby.NewRedisStore(client, cfg.Cache.Standby.Prefix)
+		targetingService.SetCacheStandby(standbyStore, cfg.Cache.Standby.MaxAge, cfg.Cache.Standby.Timeout)
+	}
+	if cfg.Cache.File.Enabled {
+		targetingService.SetCacheFile(cfg.Cache.File.Path, cfg.Cache.File.Interval, cfg.Cache.File.MaxAge)
+	}
+	waitForWarmUp(targetingService, cfg.Cache.WarmupTimeout)
+
+	var geoResolver handler.CountryResolver
+	if cfg.Geo.Enabled {
+		resolver, err := geo.Open(cfg.Geo.DatabasePath)
+		if err != nil {
+			log.Fatalf("Failed to initialize geo resolver: %v", err)
+		}
+		defer resolver.Close()
+		geoResolver = resolver
+	}
+
+	deliveryHandler := handler.NewDeliveryHandler(targetingService, geoResolver)
+	deliveryHandler.SetStreamHeartbeat(cfg.Stream.Heartbeat)
+	if cfg.Tracking.Secret != "" {
+		trackingTokens := service.NewTrackingTokens(cfg.Tracking.Secret, cfg.Tracking.TokenTTL)
+		targetingService.SetTrackingTokens(trackingTokens)
+		deliveryHandler.SetTrackingTokens(trackingTokens)
+	}
+
+	rateLimiter, err := newRateLimiter(cfg, metrics)
+	if err != nil {
+		log.Fatalf("Failed to initialize rate limiter: %v", err)
+	}
+
+	cors, err := newCORSPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize CORS policy: %v", err)
 	}
 
-	router := setupRouter(deliveryHandler, cfg, metrics)
+	proxies, err := middleware.NewTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to initialize trusted proxies: %v", err)
+	}
+
+	var recorder *recording.Recorder
+	if cfg.Recording.Enabled {
+		if recorder, err = recording.New(context.Background(), cfg.Recording); err != nil {
+			log.Fatalf("Failed to initialize request recording: %v", err)
+		}
+		defer func() {
+			if err := recorder.Close(); err != nil {
+				log.Printf("Failed to close request recorder: %v", err)
+			}
+		}()
+	}
+
+	router := setupRouter(deliveryHandler, cfg, metrics, rateLimiter, proxies, cors, recorder, reporter)
+
+	watcherCtx, stopWatcher := context.WithCancel(context.Background())
+	defer stopWatcher()
+	go watchConfig(watcherCtx, cfg, targetingService, metrics, rateLimiter)
 
 	if cfg.Metrics.Enabled {
-		go startMetricsServer(cfg.Metrics.Port, metrics)
+		go startMetricsServer(cfg.Metrics, metrics)
+	}
+
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		var tenantHeader string
+		if cfg.Tenancy.Enabled {
+			tenantHeader = cfg.Tenancy.Header
+			if tenantHeader == "" {
+				tenantHeader = middleware.DefaultTenantHeader
+			}
+		}
+		grpcServer = transport.NewGRPCServer(targetingService, tenantHeader)
+		go startGRPCServer(cfg.GRPC.Port, grpcServer)
 	}
 
 	server := &http.Server{
-		Addr:         ":8080",
+		Addr:         ":" + cfg.Server.Port,
 		Handler:      router,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
 	go func() {
-		log.Println("Starting server on port 8080")
+		log.Printf("Starting server on port %s", cfg.Server.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed: %v", err)
 		}
@@ -84,43 +235,476 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Forced shutdown: %v", err)
 	}
 
+	// Keep the delivery counts aggregated and decisions logged since the last flush
+	if err := targetingService.FlushDeliveryStats(ctx); err != nil {
+		log.Printf("Failed to flush delivery counts: %v", err)
+	}
+	if err := targetingService.FlushDecisionLog(ctx); err != nil {
+		log.Printf("Failed to flush the decision log: %v", err)
+	}
+	if err := targetingService.SaveCacheFile(); err != nil {
+		log.Printf("Failed to save the cache file: %v", err)
+	}
+
 	log.Println("Server exited gracefully")
 }
 
-func setupRouter(deliveryHandler *handler.DeliveryHandler, cfg *config.Config, metrics *monitoring.Metrics) *mux.Router {
+// defaultWarmupTimeout is used when cache.warmupTimeout is unset
+const defaultWarmupTimeout = 30 * time.Second
+
+// waitForWarmUp holds startup until the targeting cache has been loaded so
+// the first requests aren't served from an empty cache. If the load takes
+// longer than timeout the server starts anyway and /readyz keeps reporting
+// not ready until it completes.
+func waitForWarmUp(targetingService *service.TargetingService, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultWarmupTimeout
+	}
+
+	log.Printf("Waiting up to %s for the targeting cache to warm up", timeout)
+	started := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := targetingService.WaitForWarmUp(ctx); err != nil {
+		log.Printf("Starting before the targeting cache is loaded, readiness will fail until it is: %v", err)
+		return
+	}
+	log.Printf("Targeting cache ready after %s", time.Since(started).Round(time.Millisecond))
+}
+
+// newRepository connects to the backing store selected by Database.Driver.
+// Writes are mirrored to a second store when Database.DualWrite is enabled,
+// and MongoDB operations are wrapped with retries and a circuit breaker when
+// Database.Resilience is enabled.
+func newRepository(cfg *config.Config, metrics *monitoring.Metrics) (repository.RepositoryManager, error) {
+	uri := cfg.Database.ConnectionString
+	switch cfg.Database.Driver {
+	case "redis":
+		if uri == "" {
+			uri = config.GetEnv("REDIS_URI")
+		}
+	case "mongo", "":
+		if env := config.GetEnv("MONGO_URI"); env != "" {
+			uri = env
+		}
+	}
+	// With a cache file to serve from, start even while MongoDB is down
+	mongoOpts := mongoOptions(cfg.Database)
+	mongoOpts.AllowUnreachable = cfg.Cache.File.Enabled
+	repo, err := openRepository(cfg.Database.Driver, uri, cfg.Database.DatabaseName, mongoOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if dualWrite := cfg.Database.DualWrite; dualWrite.Enabled {
+		secondary, err := openRepository(dualWrite.Driver, dualWrite.ConnectionString, dualWrite.DatabaseName, mongoOpts)
+		if err != nil {
+			return nil, fmt.Errorf("dual write: %w", err)
+		}
+		if repo, err = repository.NewDualWriteRepository(repo, secondary); err != nil {
+			return nil, fmt.Errorf("dual write: %w", err)
+		}
+		log.Printf("Mirroring writes to the %s repository", dualWrite.Driver)
+	}
+
+	resilience := cfg.Database.Resilience
+	if !resilience.Enabled || (cfg.Database.Driver != "mongo" && cfg.Database.Driver != "") {
+		return repo, nil
+	}
+	return repository.NewResilientRepository(repo, repository.ResilienceOptions{
+		MaxRetries:       resilience.MaxRetries,
+		BaseDelay:        resilience.BaseDelay,
+		MaxDelay:         resilience.MaxDelay,
+		FailureThreshold: resilience.FailureThreshold,
+		OpenTimeout:      resilience.OpenTimeout,
+		OnStateChange: func(state repository.BreakerState) {
+			log.Printf("Repository circuit breaker is now %s", state)
+			metrics.SetCircuitState(state.String())
+		},
+		OnRetry: metrics.RecordRepositoryRetry,
+	}), nil
+}
+
+// openRepository connects to a redis, mongo or sqlite store. mongoOpts
+// apply to MongoDB; uri is the database file with sqlite.
+func openRepository(driver, uri, name string, mongoOpts database.MongoOptions) (repository.RepositoryManager, error) {
+	switch driver {
+	case "redis":
+		client, err := database.NewRedisClient(uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Redis client: %w", err)
+		}
+		return repository.NewRedisRepository(client, name), nil
+
+	case "sqlite":
+		sqliteDB, err := database.NewSQLiteDB(uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+		}
+		return repository.NewSQLiteRepository(sqliteDB), nil
+
+	case "mongo", "":
+		client, err := database.NewMongoClient(uri, mongoOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize MongoDB client: %w", err)
+		}
+		return repository.NewRepository(client.Database(name), client), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
+
+// mongoOptions maps the database config onto the Mongo client options. The
+// driver has no connection lifetime, so ConnMaxLifetime bounds idle time.
+func mongoOptions(cfg config.DatabaseConfig) database.MongoOptions {
+	return database.MongoOptions{
+		MaxPoolSize:            uint64(max(cfg.MaxOpenConns, 0)),
+		MinPoolSize:            uint64(max(cfg.MaxIdleConns, 0)),
+		MaxConnIdleTime:        cfg.ConnMaxLifetime,
+		ConnectTimeout:         cfg.ConnectTimeout,
+		ServerSelectionTimeout: cfg.ServerSelectionTimeout,
+		SocketTimeout:          cfg.SocketTimeout,
+		ReadPreference:         cfg.ReadPreference,
+	}
+}
+
+// newCounterStore creates the counter store selected by Counters.Backend
+func newCounterStore(cfg *config.Config) (storage.CounterStore, error) {
+	switch cfg.Counters.Backend {
+	case "redis":
+		client, err := database.NewRedisClient(cfg.Counters.RedisURI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Redis client: %w", err)
+		}
+		return storage.NewRedisCounterStore(client, cfg.Counters.Prefix), nil
+
+	case "memory", "":
+		store := storage.NewMemoryCounterStore()
+		go func() {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for range ticker.C {
+				store.Cleanup()
+			}
+		}()
+		return store, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported counters backend %q", cfg.Counters.Backend)
+	}
+}
+
+// newSegmentProvider creates the cached segment provider selected by
+// Segments.Provider, or nil when segment lookups are disabled
+func newSegmentProvider(cfg config.SegmentsConfig) (segments.Provider, error) {
+	var provider segments.Provider
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+
+	case "redis":
+		client, err := database.NewRedisClient(cfg.RedisURI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Redis client: %w", err)
+		}
+		provider = segments.NewRedisProvider(client, cfg.Prefix)
+
+	case "http":
+		httpProvider, err := segments.NewHTTPProvider(cfg.URL, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		provider = httpProvider
+
+	default:
+		return nil, fmt.Errorf("unsupported segment provider %q", cfg.Provider)
+	}
+	return segments.NewCachedProvider(provider, cfg.CacheTTL, cfg.CacheSize), nil
+}
+
+// newCORSPolicy creates the CORS policy. Unless headers are configured, a
+// custom tenant header is allowed besides the default ones.
+func newCORSPolicy(cfg *config.Config) (*middleware.CORSPolicy, error) {
+	headers := cfg.CORS.AllowedHeaders
+	if len(headers) == 0 && cfg.Tenancy.Enabled && cfg.Tenancy.Header != "" && !slices.Contains(middleware.DefaultCORSHeaders, cfg.Tenancy.Header) {
+		headers = append(slices.Clone(middleware.DefaultCORSHeaders), cfg.Tenancy.Header)
+	}
+	return middleware.NewCORSPolicy(cfg.CORS.AllowedOrigins, cfg.CORS.AllowedMethods, headers, cfg.CORS.AllowCredentials, cfg.CORS.MaxAge)
+}
+
+// newRateLimiter creates the per-client rate limiter with its route
+// overrides on the backend selected by RateLimit.Backend and starts
+// forgetting idle clients periodically. Clients are counted per API key when
+// auth is enabled and per IP otherwise. It returns nil when rate limiting is
+// disabled. Rejected requests and the clients tracked in process are
+// reported to metrics.
+func newRateLimiter(appCfg *config.Config, metrics *monitoring.Metrics) (*middleware.RouteRateLimiter, error) {
+	cfg := appCfg.RateLimit
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	var limiters middleware.LimiterFactory
+	switch cfg.Backend {
+	case "redis":
+		client, err := database.NewRedisClient(cfg.RedisURI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Redis client: %w", err)
+		}
+		limiters = middleware.RedisLimiters(client, cfg.Prefix)
+
+	case "memory", "":
+		limiters = middleware.MemoryLimiters(cfg.IdleTTL, cfg.MaxClients)
+
+	default:
+		return nil, fmt.Errorf("unsupported rate limit backend %q", cfg.Backend)
+	}
+
+	var clientKey func(r *http.Request) string
+	if appCfg.Auth.Enabled {
+		keys, err := appCfg.Auth.LoadAPIKeys()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load API keys: %w", err)
+		}
+		clientKey = middleware.APIKeyOrIP(keys)
+	}
+
+	limiter := middleware.NewRouteRateLimiter(limiters, clientKey, cfg.RPS, cfg.BurstSize)
+	for _, route := range cfg.Routes {
+		limiter.SetRouteLimit(route.Method, route.Path, route.RPS, route.BurstSize)
+	}
+	if metrics != nil {
+		limiter.SetOnReject(metrics.RecordRateLimitRejection)
+		metrics.WatchRateLimitClients(limiter.Clients)
+	}
+
+	interval := cfg.CleanupInterval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			limiter.Cleanup()
+		}
+	}()
+	return limiter, nil
+}
+
+func setupRouter(deliveryHandler *handler.DeliveryHandler, cfg *config.Config, metrics *monitoring.Metrics, rateLimiter *middleware.RouteRateLimiter, proxies *middleware.TrustedProxies, cors *middleware.CORSPolicy, recorder *recording.Recorder, reporter errortracking.Reporter) *mux.Router {
 
 	router := mux.NewRouter()
 
 	// Apply global middleware
+	router.Use(proxies.Handler)
 	router.Use(middleware.RequestID)
+	if cfg.Tracing.Enabled {
+		router.Use(middleware.Tracing)
+	}
 	router.Use(middleware.Logger)
-	router.Use(middleware.CORS)
-	router.Use(middleware.Recovery)
-	router.Use(middleware.Health)
-	router.Use(middleware.Timeout(10 * time.Second))
+	router.Use(cors.Handler)
+	router.Use(middleware.Recovery(reporter))
+	if rateLimiter != nil {
+		router.Use(rateLimiter.RateLimit)
+	}
+	if cfg.Compression.Enabled {
+		router.Use(middleware.Compress(cfg.Compression.MinSize))
+	}
+	router.Use(middleware.Timeout(10*time.Second, "/v1/stream"))
 
 	if cfg.Metrics.Enabled && metrics != nil {
 		router.Use(metrics.MetricsMiddleware)
+		// Router middleware only wraps matched routes, so wrap the fallbacks
+		// to count the rest under the unmatched endpoint
+		router.NotFoundHandler = metrics.MetricsMiddleware(http.NotFoundHandler())
+		router.MethodNotAllowedHandler = metrics.MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}))
+	}
+
+	// Admin endpoints require an API key or a bearer token with the route's
+	// scope, depending on which auth methods are enabled
+	var apiKeyAuth func(http.Handler) http.Handler
+	if cfg.Auth.Enabled {
+		keys, err := cfg.Auth.LoadAPIKeys()
+		if err != nil {
+			log.Fatalf("Failed to load API keys: %v", err)
+		}
+		if len(keys) == 0 {
+			log.Fatalf("Auth is enabled but no API keys are configured")
+		}
+		apiKeyAuth = middleware.APIKeyAuth(keys)
+	}
+	var verifier *middleware.JWTVerifier
+	if cfg.Auth.JWT.Enabled {
+		var err error
+		if verifier, err = newJWTVerifier(cfg.Auth.JWT); err != nil {
+			log.Fatalf("Failed to initialize JWT auth: %v", err)
+		}
+	}
+	// With tenancy enabled every route that touches campaigns is scoped to
+	// the request's tenant, after authentication so token claims are known
+	scoped := func(h http.Handler) http.Handler { return h }
+	var tenantHeaders []string
+	if cfg.Tenancy.Enabled {
+		resolver := middleware.NewTenantResolver(cfg.Tenancy.Header, cfg.Tenancy.Claim, cfg.Tenancy.APIKeys)
+		scoped = resolver.Resolve
+		tenantHeaders = resolver.Headers()
+	}
+	deliveryHandler.SetDeliveryCaching(cfg.Delivery.Cache.MaxAge, cfg.Delivery.Cache.StaleWhileRevalidate, tenantHeaders...)
+	protect := func(scope string, next http.HandlerFunc) http.Handler {
+		h := scoped(next)
+		switch {
+		case verifier != nil && apiKeyAuth != nil:
+			// Callers presenting an API key are checked against the keys, everyone else needs a token
+			keyAuth, jwtAuth := apiKeyAuth(h), middleware.JWTAuth(verifier, scope)(h)
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-API-Key") != "" {
+					keyAuth.ServeHTTP(w, r)
+					return
+				}
+				jwtAuth.ServeHTTP(w, r)
+			})
+		case verifier != nil:
+			return middleware.JWTAuth(verifier, scope)(h)
+		case apiKeyAuth != nil:
+			return apiKeyAuth(h)
+		default:
+			return h
+		}
+	}
+
+	// A sample of delivery requests is recorded for replay testing
+	record := func(h http.Handler) http.Handler { return h }
+	if recorder != nil {
+		record = recorder.Middleware
 	}
 
 	apiRouter := router.PathPrefix("/v1").Subrouter()
-	apiRouter.HandleFunc("/delivery", deliveryHandler.GetCampaigns).Methods("GET")
+	apiRouter.Handle("/delivery", scoped(record(http.HandlerFunc(deliveryHandler.GetCampaigns)))).Methods("GET", "HEAD")
+	apiRouter.Handle("/delivery", scoped(record(http.HandlerFunc(deliveryHandler.PostCampaigns)))).Methods("POST")
+	apiRouter.Handle("/delivery/select", scoped(http.HandlerFunc(deliveryHandler.SelectCampaign))).Methods("GET")
+	apiRouter.Handle("/delivery/batch", scoped(http.HandlerFunc(deliveryHandler.BatchDelivery))).Methods("POST")
+	apiRouter.Handle("/track/impression", scoped(http.HandlerFunc(deliveryHandler.TrackImpression))).Methods("GET")
+	apiRouter.Handle("/track/click", scoped(http.HandlerFunc(deliveryHandler.TrackClick))).Methods("GET")
+	apiRouter.Handle("/delivery/explain", protect(middleware.ScopeCampaignsRead, deliveryHandler.ExplainDelivery)).Methods("GET")
 	apiRouter.HandleFunc("/stats", deliveryHandler.GetStats).Methods("GET")
-	apiRouter.HandleFunc("/target",deliveryHandler.CreateTargetingRule).Methods("POST")
-	apiRouter.HandleFunc("/campaign",deliveryHandler.CreateCampaign).Methods("POST")
+	apiRouter.Handle("/stats/campaigns", protect(middleware.ScopeCampaignsRead, deliveryHandler.GetCampaignStats)).Methods("GET")
+	apiRouter.Handle("/decisions", protect(middleware.ScopeCampaignsRead, deliveryHandler.GetDecisions)).Methods("GET")
+	apiRouter.Handle("/admin/cache/refresh", protect(middleware.ScopeCacheRefresh, deliveryHandler.RefreshCache)).Methods("POST")
+	apiRouter.Handle("/admin/killswitch", protect(middleware.ScopeKillSwitch, deliveryHandler.EngageKillSwitch)).Methods("POST")
+	apiRouter.Handle("/admin/killswitch", protect(middleware.ScopeKillSwitch, deliveryHandler.ListKillSwitches)).Methods("GET")
+	apiRouter.Handle("/admin/killswitch/resume", protect(middleware.ScopeKillSwitch, deliveryHandler.ReleaseKillSwitch)).Methods("POST")
+	apiRouter.Handle("/stream", protect(middleware.ScopeCampaignsRead, deliveryHandler.StreamCampaignChanges)).Methods("GET")
+	apiRouter.HandleFunc("/openapi.json", deliveryHandler.OpenAPI).Methods("GET")
+	apiRouter.Handle("/target", protect(middleware.ScopeRulesWrite, deliveryHandler.CreateTargetingRule)).Methods("POST")
+	apiRouter.Handle("/target", protect(middleware.ScopeCampaignsRead, deliveryHandler.ListTargetingRules)).Methods("GET")
+	apiRouter.Handle("/target/analyze", protect(middleware.ScopeCampaignsRead, deliveryHandler.AnalyzeTargetingRule)).Methods("POST")
+	apiRouter.Handle("/target/estimate", protect(middleware.ScopeCampaignsRead, deliveryHandler.EstimateReach)).Methods("POST")
+	apiRouter.Handle("/target/{id}", protect(middleware.ScopeRulesWrite, deliveryHandler.UpdateTargetingRule)).Methods("PUT")
+	apiRouter.Handle("/target/{id}", protect(middleware.ScopeRulesWrite, deliveryHandler.DeleteTargetingRule)).Methods("DELETE")
+	apiRouter.Handle("/campaigns", protect(middleware.ScopeCampaignsRead, deliveryHandler.ListCampaigns)).Methods("GET")
+	apiRouter.Handle("/campaigns/search", protect(middleware.ScopeCampaignsRead, deliveryHandler.SearchCampaigns)).Methods("GET")
+	apiRouter.Handle("/campaign", protect(middleware.ScopeCampaignsWrite, deliveryHandler.CreateCampaign)).Methods("POST")
+	apiRouter.Handle("/campaigns/bulk", protect(middleware.ScopeCampaignsWrite, deliveryHandler.BulkCreateCampaigns)).Methods("POST")
+	apiRouter.Handle("/campaigns/export", protect(middleware.ScopeCampaignsRead, deliveryHandler.ExportCampaigns)).Methods("GET")
+	apiRouter.Handle("/campaigns/import", protect(middleware.ScopeCampaignsWrite, deliveryHandler.ImportCampaigns)).Methods("POST")
+	apiRouter.Handle("/campaign/{id}", protect(middleware.ScopeCampaignsWrite, deliveryHandler.UpdateCampaign)).Methods("PUT")
+	apiRouter.Handle("/campaign/{id}", protect(middleware.ScopeCampaignsWrite, deliveryHandler.DeleteCampaign)).Methods("DELETE")
+	apiRouter.Handle("/campaign/{id}/status", protect(middleware.ScopeCampaignsWrite, deliveryHandler.UpdateCampaignStatus)).Methods("PUT")
+	apiRouter.Handle("/campaign/{id}/clone", protect(middleware.ScopeCampaignsWrite, deliveryHandler.CloneCampaign)).Methods("POST")
+	apiRouter.Handle("/campaign/{id}/tracking", protect(middleware.ScopeCampaignsRead, deliveryHandler.GetCampaignTracking)).Methods("GET")
+	apiRouter.Handle("/campaign/{id}/full", protect(middleware.ScopeCampaignsRead, deliveryHandler.GetCampaignView)).Methods("GET")
+	router.HandleFunc("/healthz", deliveryHandler.Live).Methods("GET")
 	router.HandleFunc("/health", deliveryHandler.Health).Methods("GET")
+	router.HandleFunc("/readyz", deliveryHandler.Ready).Methods("GET")
+	// Middleware only runs for matched routes, so match every OPTIONS request
+	// to let the CORS policy answer preflights
+	router.PathPrefix("/").Methods("OPTIONS").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
 
 	return router
 }
 
-func startMetricsServer(port string, metrics *monitoring.Metrics) {
+// watchConfig reloads the config file on SIGHUP or when it changes and
+// applies the settings that can change at runtime
+func watchConfig(ctx context.Context, cfg *config.Config, targetingService *service.TargetingService, metrics *monitoring.Metrics, rateLimiter *middleware.RouteRateLimiter) {
+	watcher := config.NewWatcher(cfg)
+	watcher.OnChange(func(old, updated *config.Config) {
+		if err := logger.SetLevel(updated.Log.Level); err != nil {
+			log.Printf("Ignoring reloaded log level: %v", err)
+		}
+
+		targetingService.ApplyCacheConfig(updated.Cache)
+		targetingService.ApplyDimensionConfig(updated.Dimensions)
+
+		if metrics != nil {
+			metrics.SetEnabled(updated.Metrics.Enabled)
+		} else if updated.Metrics.Enabled {
+			log.Printf("Metrics were disabled at startup; restart to enable them")
+		}
+
+		// Route overrides removed from the config keep their limits until restart
+		if rateLimiter != nil {
+			rateLimiter.SetLimit(updated.RateLimit.RPS, updated.RateLimit.BurstSize)
+			for _, route := range updated.RateLimit.Routes {
+				rateLimiter.SetRouteLimit(route.Method, route.Path, route.RPS, route.BurstSize)
+			}
+		} else if updated.RateLimit.Enabled {
+			log.Printf("Rate limiting was disabled at startup; restart to enable it")
+		}
+	})
+
+	if err := watcher.Run(ctx); err != nil {
+		log.Printf("Config watcher stopped: %v", err)
+	}
+}
+
+// newJWTVerifier builds a bearer token verifier using the JWKS URL or, when
+// none is configured, the shared HMAC secret
+func newJWTVerifier(cfg config.JWTConfig) (*middleware.JWTVerifier, error) {
+	if cfg.JWKSURL != "" {
+		jwks, err := keyfunc.NewDefaultCtx(context.Background(), []string{cfg.JWKSURL})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWKS from %s: %w", cfg.JWKSURL, err)
+		}
+		return middleware.NewJWTVerifier(jwks.Keyfunc, cfg.Issuer, cfg.Audience), nil
+	}
+
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("either jwksUrl or secret must be configured")
+	}
+	secret := []byte(cfg.Secret)
+	return middleware.NewJWTVerifier(func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return secret, nil
+	}, cfg.Issuer, cfg.Audience), nil
+}
+
+// startMetricsServer serves the Prometheus metrics, and the debug endpoints
+// when enabled, on their own port so they aren't exposed with the API
+func startMetricsServer(cfg config.MetricsConfig, metrics *monitoring.Metrics) {
 	metricsRouter := mux.NewRouter()
-	metricsRouter.Handle("/metrics", metrics.Handler())
+	metricsRouter.Handle(cfg.Path, metrics.Handler())
+	if cfg.Debug {
+		monitoring.RegisterDebugHandlers(metricsRouter)
+	}
 
+	port := cfg.Port
 	metricsServer := &http.Server{
 		Addr:    ":" + port,
 		Handler: metricsRouter,
@@ -131,3 +715,16 @@ func startMetricsServer(port string, metrics *monitoring.Metrics) {
 		log.Printf("Metrics server error: %v", err)
 	}
 }
+
+func startGRPCServer(port string, server *grpc.Server) {
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Printf("gRPC server error: %v", err)
+		return
+	}
+
+	log.Printf("Starting gRPC server on port %s", port)
+	if err := server.Serve(listener); err != nil && err != grpc.ErrServerStopped {
+		log.Printf("gRPC server error: %v", err)
+	}
+}
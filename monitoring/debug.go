@@ -0,0 +1,83 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/metrics"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterDebugHandlers adds the pprof profiles under /debug/pprof/, expvar
+// under /debug/vars and a runtime snapshot under /debug/runtime. They expose
+// internals and should only be served on an internal port.
+func RegisterDebugHandlers(router *mux.Router) {
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	// Index also serves the named profiles, e.g. /debug/pprof/heap
+	router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+	router.Handle("/debug/vars", expvar.Handler())
+	router.HandleFunc("/debug/runtime", runtimeSnapshot)
+}
+
+// RuntimeSnapshot is a point-in-time view of the goroutines, heap and
+// garbage collector
+type RuntimeSnapshot struct {
+	Time         time.Time  `json:"time"`
+	GoVersion    string     `json:"go_version"`
+	NumCPU       int        `json:"num_cpu"`
+	GOMAXPROCS   int        `json:"gomaxprocs"`
+	Goroutines   int        `json:"goroutines"`
+	HeapAlloc    uint64     `json:"heap_alloc_bytes"`
+	HeapInuse    uint64     `json:"heap_inuse_bytes"`
+	HeapIdle     uint64     `json:"heap_idle_bytes"`
+	HeapObjects  uint64     `json:"heap_objects"`
+	TotalAlloc   uint64     `json:"total_alloc_bytes"`
+	Sys          uint64     `json:"sys_bytes"`
+	NumGC        uint32     `json:"num_gc"`
+	LastGC       *time.Time `json:"last_gc,omitempty"`
+	PauseTotalNs uint64     `json:"gc_pause_total_ns"`
+	GCPercent    int        `json:"gc_percent"`
+	MemoryLimit  int64      `json:"memory_limit_bytes"`
+}
+
+// runtimeSnapshot handles /debug/runtime. Reading the memory statistics
+// briefly stops the world, so it isn't meant to be scraped frequently.
+func runtimeSnapshot(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	settings := []metrics.Sample{{Name: "/gc/gogc:percent"}, {Name: "/gc/gomemlimit:bytes"}}
+	metrics.Read(settings)
+
+	snapshot := RuntimeSnapshot{
+		Time:         time.Now().UTC(),
+		GoVersion:    runtime.Version(),
+		NumCPU:       runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapInuse:    mem.HeapInuse,
+		HeapIdle:     mem.HeapIdle,
+		HeapObjects:  mem.HeapObjects,
+		TotalAlloc:   mem.TotalAlloc,
+		Sys:          mem.Sys,
+		NumGC:        mem.NumGC,
+		PauseTotalNs: mem.PauseTotalNs,
+		GCPercent:    int(settings[0].Value.Uint64()),
+		MemoryLimit:  int64(settings[1].Value.Uint64()),
+	}
+	if mem.LastGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC)).UTC()
+		snapshot.LastGC = &lastGC
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
@@ -3,18 +3,52 @@ package monitoring
 import (
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type Metrics struct {
-	RequestsTotal    *prometheus.CounterVec
-	RequestDuration  *prometheus.HistogramVec
-	CampaignsMatched *prometheus.HistogramVec
-	ActiveCampaigns  prometheus.Gauge
-	TargetingRules   prometheus.Gauge
+	RequestsTotal     *prometheus.CounterVec
+	RequestDuration   *prometheus.HistogramVec
+	CampaignsMatched  *prometheus.HistogramVec
+	ActiveCampaigns   prometheus.Gauge
+	TargetingRules    prometheus.Gauge
+	CacheHits         *prometheus.CounterVec
+	CacheMisses       *prometheus.CounterVec
+	ShadowMatches     *prometheus.CounterVec
+	CircuitState      *prometheus.GaugeVec
+	RepositoryRetries *prometheus.CounterVec
+	CacheRefresh      *prometheus.HistogramVec
+	// CacheLastRefresh and CacheRefreshFailures let alerts catch a cache
+	// that has stopped refreshing
+	CacheLastRefresh     prometheus.Gauge
+	CacheRefreshFailures prometheus.Counter
+	// CacheStaleness is how long delivery has been served from a cache that
+	// failed to refresh
+	CacheStaleness prometheus.Gauge
+	TrackedEvents  *prometheus.CounterVec
+	// RateLimitRejected counts requests rejected by each rate limiter
+	RateLimitRejected *prometheus.CounterVec
+	// StreamSubscribers is the number of open campaign change streams
+	StreamSubscribers prometheus.Gauge
+	// TenantDeliveries, TenantCampaignsMatched and CampaignMatches break
+	// deliveries down by tenant and campaign once enabled with
+	// EnableTenantLabels and EnableCampaignLabels
+	TenantDeliveries       *prometheus.CounterVec
+	TenantCampaignsMatched *prometheus.HistogramVec
+	CampaignMatches        *prometheus.CounterVec
+
+	// tenants and campaigns pick the label values of the tenants and
+	// campaigns with series of their own; nil until enabled
+	tenants   *topK
+	campaigns *topK
+
+	// disabled pauses recording without unregistering collectors
+	disabled atomic.Bool
 }
 
 func NewMetrics() *Metrics {
@@ -54,7 +88,109 @@ func NewMetrics() *Metrics {
 				Help: "Number of targeting rules",
 			},
 		),
-		
+		CacheHits: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "targeting_engine_cache_hits_total",
+				Help: "Total number of cache hits",
+			},
+			[]string{"cache"},
+		),
+		CacheMisses: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "targeting_engine_cache_misses_total",
+				Help: "Total number of cache misses",
+			},
+			[]string{"cache"},
+		),
+		ShadowMatches: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "targeting_engine_shadow_matches_total",
+				Help: "Deliveries whose matches would change if shadow rules were enabled, by campaign and outcome (gained or lost)",
+			},
+			[]string{"campaign_id", "outcome"},
+		),
+		CircuitState: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "targeting_engine_repository_circuit_state",
+				Help: "Repository circuit breaker state; 1 for the current state (closed, half_open or open), 0 otherwise",
+			},
+			[]string{"state"},
+		),
+		RepositoryRetries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "targeting_engine_repository_retries_total",
+				Help: "Retries of repository reads after transient errors, by operation",
+			},
+			[]string{"operation"},
+		),
+		CacheRefresh: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "targeting_engine_cache_refresh_duration_seconds",
+				Help:    "Duration of full targeting cache reloads, by result (success or error)",
+				Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+			},
+			[]string{"result"},
+		),
+		CacheLastRefresh: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "targeting_engine_cache_last_refresh_timestamp",
+				Help: "Unix time in seconds of the last successful targeting cache reload or change stream update",
+			},
+		),
+		CacheRefreshFailures: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "targeting_engine_cache_refresh_failures_total",
+				Help: "Failed full targeting cache reloads",
+			},
+		),
+		CacheStaleness: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "targeting_engine_cache_staleness_seconds",
+				Help: "Seconds since targeting cache refreshes started failing, 0 while the cache is fresh",
+			},
+		),
+		TrackedEvents: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "targeting_engine_tracked_events_total",
+				Help: "Impressions and clicks reported to the tracking endpoints, by type, not counting repeats",
+			},
+			[]string{"type"},
+		),
+		RateLimitRejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "targeting_engine_rate_limit_rejected_total",
+				Help: "Requests rejected by rate limiting, by limiter (default or a route override)",
+			},
+			[]string{"limiter"},
+		),
+		StreamSubscribers: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "targeting_engine_stream_subscribers",
+				Help: "Open campaign change streams",
+			},
+		),
+		TenantDeliveries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "targeting_engine_tenant_deliveries_total",
+				Help: "Delivery requests served, by tenant; tenants outside the most frequent ones are counted as other",
+			},
+			[]string{"tenant"},
+		),
+		TenantCampaignsMatched: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "targeting_engine_tenant_campaigns_matched",
+				Help:    "Number of campaigns served per delivery request, by tenant",
+				Buckets: []float64{0, 1, 2, 5, 10, 20, 50},
+			},
+			[]string{"tenant"},
+		),
+		CampaignMatches: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "targeting_engine_campaign_matches_total",
+				Help: "Delivery requests each campaign was served for, by tenant and campaign; campaigns outside the most frequent ones are counted as other",
+			},
+			[]string{"tenant", "campaign_id"},
+		),
 	}
 
 	prometheus.MustRegister(
@@ -63,7 +199,23 @@ func NewMetrics() *Metrics {
 		metrics.CampaignsMatched,
 		metrics.ActiveCampaigns,
 		metrics.TargetingRules,
+		metrics.CacheHits,
+		metrics.CacheMisses,
+		metrics.ShadowMatches,
+		metrics.CircuitState,
+		metrics.RepositoryRetries,
+		metrics.CacheRefresh,
+		metrics.CacheLastRefresh,
+		metrics.CacheRefreshFailures,
+		metrics.CacheStaleness,
+		metrics.TrackedEvents,
+		metrics.RateLimitRejected,
+		metrics.StreamSubscribers,
+		metrics.TenantDeliveries,
+		metrics.TenantCampaignsMatched,
+		metrics.CampaignMatches,
 	)
+	metrics.SetCircuitState(circuitStates[0])
 
 	return metrics
 }
@@ -78,8 +230,265 @@ func (m *Metrics) RecordCampaignsMatched(country, os string, count int) {
 	m.CampaignsMatched.WithLabelValues(country, os).Observe(float64(count))
 }
 
+// RecordCacheHit counts a hit on the named cache. It is a no-op on a nil Metrics
+// so callers don't need to check whether metrics are enabled.
+func (m *Metrics) RecordCacheHit(cache string) {
+	if m == nil || m.disabled.Load() {
+		return
+	}
+	m.CacheHits.WithLabelValues(cache).Inc()
+}
+
+// RecordCacheMiss counts a miss on the named cache. It is a no-op on a nil Metrics.
+func (m *Metrics) RecordCacheMiss(cache string) {
+	if m == nil || m.disabled.Load() {
+		return
+	}
+	m.CacheMisses.WithLabelValues(cache).Inc()
+}
+
+// RecordShadowMatch counts a delivery where enabling a campaign's shadow
+// rules would have gained or lost the campaign. It is a no-op on a nil Metrics.
+func (m *Metrics) RecordShadowMatch(campaignID, outcome string) {
+	if m == nil || m.disabled.Load() {
+		return
+	}
+	m.ShadowMatches.WithLabelValues(campaignID, outcome).Inc()
+}
+
+// circuitStates are the values of the state label of CircuitState
+var circuitStates = []string{"closed", "half_open", "open"}
+
+// SetCircuitState marks state as the repository circuit breaker's current
+// state. It is a no-op on a nil Metrics.
+func (m *Metrics) SetCircuitState(state string) {
+	if m == nil {
+		return
+	}
+	for _, s := range circuitStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		m.CircuitState.WithLabelValues(s).Set(value)
+	}
+}
+
+// RecordRepositoryRetry counts a retried repository operation. It is a no-op
+// on a nil Metrics.
+func (m *Metrics) RecordRepositoryRetry(operation string) {
+	if m == nil || m.disabled.Load() {
+		return
+	}
+	m.RepositoryRetries.WithLabelValues(operation).Inc()
+}
+
+// RecordCacheRefresh records the duration of a full cache reload and whether
+// it failed, counting failures and stamping the time of successes. It is a
+// no-op on a nil Metrics.
+func (m *Metrics) RecordCacheRefresh(duration time.Duration, err error) {
+	if m == nil || m.disabled.Load() {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "error"
+		m.CacheRefreshFailures.Inc()
+	} else {
+		m.CacheLastRefresh.SetToCurrentTime()
+	}
+	m.CacheRefresh.WithLabelValues(result).Observe(duration.Seconds())
+}
+
+// RecordCacheUpdate stamps the time the cache was last brought up to date by
+// an incremental change. It is a no-op on a nil Metrics.
+func (m *Metrics) RecordCacheUpdate() {
+	if m == nil || m.disabled.Load() {
+		return
+	}
+	m.CacheLastRefresh.SetToCurrentTime()
+}
+
+// SetCacheStaleness records how long the targeting cache has failed to
+// refresh, zero when it is fresh. It is a no-op on a nil Metrics.
+func (m *Metrics) SetCacheStaleness(staleness time.Duration) {
+	if m == nil || m.disabled.Load() {
+		return
+	}
+	m.CacheStaleness.Set(staleness.Seconds())
+}
+
+// RecordTrackedEvent counts an impression or click reported to the tracking
+// endpoints. It is a no-op on a nil Metrics.
+func (m *Metrics) RecordTrackedEvent(eventType string) {
+	if m == nil || m.disabled.Load() {
+		return
+	}
+	m.TrackedEvents.WithLabelValues(eventType).Inc()
+}
+
+// RecordRateLimitRejection counts a request rejected by the named rate
+// limiter. It is a no-op on a nil Metrics.
+func (m *Metrics) RecordRateLimitRejection(limiter string) {
+	if m == nil || m.disabled.Load() {
+		return
+	}
+	m.RateLimitRejected.WithLabelValues(limiter).Inc()
+}
+
+// SetStreamSubscribers records the number of open campaign change streams.
+// It is a no-op on a nil Metrics.
+func (m *Metrics) SetStreamSubscribers(n int) {
+	if m == nil || m.disabled.Load() {
+		return
+	}
+	m.StreamSubscribers.Set(float64(n))
+}
+
+// defaultTenantLabel is the tenant label value of the default tenant
+const defaultTenantLabel = "default"
+
+// Defaults for unset label limits
+const (
+	DefaultTopTenants        = 20
+	DefaultTopCampaigns      = 100
+	DefaultLabelRankInterval = time.Minute
+)
+
+// EnableTenantLabels starts recording deliveries by tenant, giving the top
+// most frequent tenants, re-ranked every rankInterval, series of their own.
+// Non-positive values select the defaults. It must be called before
+// deliveries are recorded.
+func (m *Metrics) EnableTenantLabels(top int, rankInterval time.Duration) {
+	if m == nil {
+		return
+	}
+	if top <= 0 {
+		top = DefaultTopTenants
+	}
+	if rankInterval <= 0 {
+		rankInterval = DefaultLabelRankInterval
+	}
+	m.tenants = newTopK(top, rankInterval, func(tenant string) {
+		m.TenantDeliveries.DeleteLabelValues(tenant)
+		m.TenantCampaignsMatched.DeleteLabelValues(tenant)
+		m.CampaignMatches.DeleteLabelValues(tenant, OtherLabel)
+	})
+}
+
+// EnableCampaignLabels starts recording the campaigns served, giving the top
+// most frequent campaigns, re-ranked every rankInterval, series of their
+// own. Non-positive values select the defaults. It must be called before
+// deliveries are recorded.
+func (m *Metrics) EnableCampaignLabels(top int, rankInterval time.Duration) {
+	if m == nil {
+		return
+	}
+	if top <= 0 {
+		top = DefaultTopCampaigns
+	}
+	if rankInterval <= 0 {
+		rankInterval = DefaultLabelRankInterval
+	}
+	m.campaigns = newTopK(top, rankInterval, func(campaignID string) {
+		m.CampaignMatches.DeletePartialMatch(prometheus.Labels{"campaign_id": campaignID})
+	})
+}
+
+// RecordDelivery counts a delivery request of a tenant and the campaigns
+// served for it, for the labels enabled. It is a no-op on a nil Metrics.
+func (m *Metrics) RecordDelivery(tenantID string, campaignIDs []string) {
+	if m == nil || m.disabled.Load() {
+		return
+	}
+	if tenantID == "" {
+		tenantID = defaultTenantLabel
+	}
+	// Campaigns outside the top share a series per tenant with a series of
+	// its own, and one for all other tenants
+	tenant := OtherLabel
+	if m.tenants != nil {
+		tenant = m.tenants.label(tenantID)
+		m.TenantDeliveries.WithLabelValues(tenant).Inc()
+		m.TenantCampaignsMatched.WithLabelValues(tenant).Observe(float64(len(campaignIDs)))
+	}
+	if m.campaigns != nil {
+		for _, campaignID := range campaignIDs {
+			if campaign := m.campaigns.label(campaignID); campaign != OtherLabel {
+				m.CampaignMatches.WithLabelValues(tenantID, campaign).Inc()
+			} else {
+				m.CampaignMatches.WithLabelValues(tenant, OtherLabel).Inc()
+			}
+		}
+	}
+}
+
+// rateLimitClientsDesc describes the clients tracked by in-process rate
+// limiters
+var rateLimitClientsDesc = prometheus.NewDesc(
+	"targeting_engine_rate_limit_clients",
+	"Clients tracked by each in-process rate limiter, by limiter (default or a route override)",
+	[]string{"limiter"}, nil,
+)
+
+// rateLimitClients collects the number of clients rate limiters track when
+// metrics are scraped
+type rateLimitClients func() map[string]int
+
+func (c rateLimitClients) Describe(ch chan<- *prometheus.Desc) {
+	ch <- rateLimitClientsDesc
+}
+
+func (c rateLimitClients) Collect(ch chan<- prometheus.Metric) {
+	for limiter, n := range c() {
+		ch <- prometheus.MustNewConstMetric(rateLimitClientsDesc, prometheus.GaugeValue, float64(n), limiter)
+	}
+}
+
+// WatchRateLimitClients exports the number of clients each in-process rate
+// limiter tracks, read from clients whenever metrics are scraped. It must be
+// called at most once, and is a no-op on a nil Metrics.
+func (m *Metrics) WatchRateLimitClients(clients func() map[string]int) {
+	if m == nil {
+		return
+	}
+	prometheus.MustRegister(rateLimitClients(clients))
+}
+
+// SetEnabled pauses or resumes recording, e.g. when metrics are toggled in a
+// reloaded config
+func (m *Metrics) SetEnabled(enabled bool) {
+	m.disabled.Store(!enabled)
+}
+
+// unmatchedEndpoint is the endpoint label of requests matching no route, so
+// probes of arbitrary paths share one series
+const unmatchedEndpoint = "unmatched"
+
+// endpointLabel returns the template of the mux route a request matched,
+// such as /v1/campaign/{id}, so IDs in paths don't explode the cardinality
+// of the endpoint label
+func endpointLabel(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return unmatchedEndpoint
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return unmatchedEndpoint
+	}
+	return tpl
+}
+
+// MetricsMiddleware records the count and duration of requests, labelled by
+// method, route template and status
 func (m *Metrics) MetricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.disabled.Load() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		start := time.Now()
 
 		wrapped := &metricsResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
@@ -87,7 +496,7 @@ func (m *Metrics) MetricsMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(wrapped, r)
 
 		duration := time.Since(start)
-		m.RecordRequest(r.Method, r.URL.Path, wrapped.statusCode, duration)
+		m.RecordRequest(r.Method, endpointLabel(r), wrapped.statusCode, duration)
 	})
 }
 
@@ -104,3 +513,9 @@ func (w *metricsResponseWriter) WriteHeader(statusCode int) {
 	w.statusCode = statusCode
 	w.ResponseWriter.WriteHeader(statusCode)
 }
+
+// Unwrap returns the wrapped ResponseWriter, so http.ResponseController can
+// flush streamed responses
+func (w *metricsResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
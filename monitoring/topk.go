@@ -0,0 +1,129 @@
+package monitoring
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// OtherLabel is the label value shared by the tenants and campaigns that
+// aren't among the most frequent ones
+const OtherLabel = "other"
+
+// topKCandidates is how many values a topK tracks per value it labels, so a
+// value on the rise can overtake one of the top ones
+const topKCandidates = 4
+
+// topK bounds the cardinality of a label to the k most frequent values seen.
+// Frequencies are estimated with the Space-Saving algorithm over a bounded
+// set of candidates and re-ranked every interval, halving them so shifts in
+// traffic are followed. Until k values have been seen every new one is
+// admitted at once. It is safe for concurrent use.
+type topK struct {
+	mutex    sync.Mutex
+	k        int
+	interval time.Duration
+	counts   map[string]uint64
+	top      map[string]bool
+	ranked   time.Time
+	// evict is called with the values that left the top, whose series
+	// should be deleted
+	evict func(value string)
+}
+
+func newTopK(k int, interval time.Duration, evict func(value string)) *topK {
+	return &topK{
+		k:        k,
+		interval: interval,
+		counts:   make(map[string]uint64, k*topKCandidates),
+		top:      make(map[string]bool, k),
+		ranked:   time.Now(),
+		evict:    evict,
+	}
+}
+
+// label counts an occurrence of value and returns the label value to record
+// it under: value itself while it is among the top k, OtherLabel otherwise
+func (t *topK) label(value string) string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.observeLocked(value)
+	if time.Since(t.ranked) >= t.interval {
+		t.rankLocked()
+	}
+	if !t.top[value] && len(t.top) < t.k {
+		t.top[value] = true
+	}
+	if t.top[value] {
+		return value
+	}
+	return OtherLabel
+}
+
+// observeLocked counts value, replacing the least frequent candidate when it
+// isn't one and all slots are taken. The caller holds mutex.
+func (t *topK) observeLocked(value string) {
+	if _, ok := t.counts[value]; ok || len(t.counts) < t.k*topKCandidates {
+		t.counts[value]++
+		return
+	}
+	var (
+		minValue string
+		minCount uint64
+		found    bool
+	)
+	for candidate, count := range t.counts {
+		if t.top[candidate] {
+			// Labelled values keep their slot until the next ranking
+			continue
+		}
+		if !found || count < minCount {
+			minValue, minCount, found = candidate, count, true
+		}
+	}
+	if !found {
+		return
+	}
+	delete(t.counts, minValue)
+	t.counts[value] = minCount + 1
+}
+
+// rankLocked makes the k most frequent candidates the top ones, evicting
+// those that dropped out, and halves every count. The caller holds mutex.
+func (t *topK) rankLocked() {
+	t.ranked = time.Now()
+
+	candidates := make([]string, 0, len(t.counts))
+	for value := range t.counts {
+		candidates = append(candidates, value)
+	}
+	slices.SortFunc(candidates, func(a, b string) int {
+		switch ca, cb := t.counts[a], t.counts[b]; {
+		case ca > cb:
+			return -1
+		case ca < cb:
+			return 1
+		}
+		return 0
+	})
+
+	top := make(map[string]bool, t.k)
+	for _, value := range candidates[:min(t.k, len(candidates))] {
+		top[value] = true
+	}
+	for value := range t.top {
+		if !top[value] && t.evict != nil {
+			t.evict(value)
+		}
+	}
+	t.top = top
+
+	for value, count := range t.counts {
+		if count /= 2; count == 0 {
+			delete(t.counts, value)
+		} else {
+			t.counts[value] = count
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a cached delivery response
+type cacheEntry struct {
+	key       string
+	campaigns []*Campaign
+	etag      string
+	expires   time.Time
+}
+
+// responseCache holds delivery responses by query, evicting the least
+// recently used beyond its size. Expired entries are kept so they can be
+// revalidated with their ETag. It is safe for concurrent use.
+type responseCache struct {
+	mutex   sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func newResponseCache(size int, ttl time.Duration) *responseCache {
+	return &responseCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the entry for key, if any, and whether it is still fresh
+func (c *responseCache) get(key string) (*cacheEntry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	entry := element.Value.(*cacheEntry)
+	return entry, time.Now().Before(entry.expires)
+}
+
+// put stores entry for key, fresh for the cache's TTL
+func (c *responseCache) put(key string, entry *cacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry.key = key
+	entry.expires = time.Now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,325 @@
+// Package client calls the targeting engine's HTTP API from Go, so services
+// don't have to build delivery query strings and handle retries themselves.
+//
+//	c, err := client.New(client.Options{BaseURL: "http://localhost:8080", APIKey: "..."})
+//	campaigns, err := c.GetCampaigns(ctx, client.Request{App: "com.example", Country: "US", OS: "android"})
+//
+// Failed calls are retried with exponential backoff when the server is
+// unavailable or rate limits the client, and delivery responses may be
+// cached for a short while with Options.CacheTTL.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+)
+
+// Types shared with the HTTP API, documented in its OpenAPI description
+type (
+	Request       = model.DeliveryRequest
+	Campaign      = model.DeliveryResponse
+	ErrorResponse = model.ErrorResponse
+	FieldError    = model.FieldError
+)
+
+// Defaults for unset Options
+const (
+	DefaultTimeout    = 2 * time.Second
+	DefaultMaxRetries = 2
+	DefaultMinBackoff = 50 * time.Millisecond
+	DefaultMaxBackoff = time.Second
+	DefaultCacheSize  = 1000
+)
+
+// Options configures a Client. Only BaseURL is required.
+type Options struct {
+	// BaseURL is the server's address, such as http://localhost:8080
+	BaseURL string
+	// APIKey is sent in X-API-Key and Token as a bearer token, when set
+	APIKey string
+	Token  string
+	// Tenant is the tenant requests act for, sent in X-Tenant-ID
+	Tenant string
+	// Timeout bounds each attempt of a call, retries getting their own
+	Timeout time.Duration
+	// MaxRetries is how often a call is retried after a network error or a
+	// 429, 502, 503 or 504 response. Negative values disable retries.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the jittered exponential wait between
+	// attempts. A Retry-After header is honored when it is within
+	// MaxBackoff; otherwise the call fails without waiting.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// CacheTTL is how long delivery responses are cached; zero disables
+	// caching. Expired entries are revalidated with their ETag, and requests
+	// with a UserID are never cached since frequency caps and creative
+	// rotation depend on every call reaching the server.
+	CacheTTL time.Duration
+	// CacheSize bounds the number of cached responses
+	CacheSize int
+	// HTTPClient sends the requests; http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// Client calls the targeting engine's HTTP API. It is safe for concurrent
+// use.
+type Client struct {
+	baseURL string
+	opts    Options
+	http    *http.Client
+	cache   *responseCache
+}
+
+// Error is an error response from the API
+type Error struct {
+	StatusCode int
+	Response   ErrorResponse
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Response.Message != "" {
+		message += ": " + e.Response.Message
+	}
+	for _, field := range e.Response.Fields {
+		message += fmt.Sprintf("; %s: %s", field.Field, field.Reason)
+	}
+	return message
+}
+
+// New creates a Client for the server at opts.BaseURL
+func New(opts Options) (*Client, error) {
+	base, err := url.Parse(opts.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", opts.BaseURL)
+	}
+
+	opts.Timeout = orDefault(opts.Timeout, DefaultTimeout)
+	opts.MinBackoff = orDefault(opts.MinBackoff, DefaultMinBackoff)
+	opts.MaxBackoff = max(orDefault(opts.MaxBackoff, DefaultMaxBackoff), opts.MinBackoff)
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+	c := &Client{
+		baseURL: strings.TrimRight(opts.BaseURL, "/"),
+		opts:    opts,
+		http:    opts.HTTPClient,
+	}
+	if c.http == nil {
+		c.http = http.DefaultClient
+	}
+	if opts.CacheTTL > 0 {
+		c.cache = newResponseCache(orDefault(opts.CacheSize, DefaultCacheSize), opts.CacheTTL)
+	}
+	return c, nil
+}
+
+// orDefault returns value, or fallback when value is zero or negative
+func orDefault[T int | time.Duration](value, fallback T) T {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// GetCampaigns returns the campaigns matching req from GET /v1/delivery, none
+// when nothing matches. Invalid requests fail with an *Error carrying the
+// invalid fields. Cached campaigns are shared between calls and must not be
+// modified.
+func (c *Client) GetCampaigns(ctx context.Context, req Request) ([]*Campaign, error) {
+	query := DeliveryQuery(&req)
+	key := query.Encode()
+	cacheable := c.cache != nil && req.UserID == ""
+
+	var cached *cacheEntry
+	if cacheable {
+		var fresh bool
+		if cached, fresh = c.cache.get(key); fresh {
+			return cached.campaigns, nil
+		}
+	}
+
+	header := http.Header{}
+	if cached != nil && cached.etag != "" {
+		header.Set("If-None-Match", cached.etag)
+	}
+	var campaigns []*Campaign
+	resp, err := c.call(ctx, http.MethodGet, "/v1/delivery", query, header, &campaigns)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		campaigns = cached.campaigns
+	}
+	if cacheable {
+		c.cache.put(key, &cacheEntry{campaigns: campaigns, etag: resp.Header.Get("ETag")})
+	}
+	return campaigns, nil
+}
+
+// DeliveryQuery encodes req as the query parameters of the delivery
+// endpoints. Further values of a dimension repeat its parameter, and custom
+// dimensions are sent as parameters of their own.
+func DeliveryQuery(req *Request) url.Values {
+	query := url.Values{}
+	for name, value := range req.Custom {
+		query.Set(name, value)
+	}
+	for _, param := range []struct {
+		name    string
+		value   string
+		further []string
+	}{
+		{"app", req.App, req.Apps},
+		{"country", req.Country, req.Countries},
+		{"os", req.OS, req.OSes},
+		{"device_type", req.DeviceType, req.DeviceTypes},
+		{"region", req.Region, req.Regions},
+		{"city", req.City, req.Cities},
+		{"user_id", req.UserID, nil},
+		{"app_version", req.AppVersion, nil},
+	} {
+		if param.value == "" {
+			continue
+		}
+		query.Set(param.name, param.value)
+		for _, value := range param.further {
+			query.Add(param.name, value)
+		}
+	}
+	if len(req.Categories) > 0 {
+		query.Set("categories", strings.Join(req.Categories, ","))
+	}
+	if req.Limit > 0 {
+		query.Set("limit", strconv.Itoa(req.Limit))
+	}
+	return query
+}
+
+// call sends a request, retrying it as configured, and decodes a 200
+// response into out. It returns the response of the last attempt, whose body
+// has been closed; error responses are returned as an *Error.
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, header http.Header, out any) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, method, path, query, header, out)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= c.opts.MaxRetries || !retryable(resp, err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		wait := c.backoff(attempt)
+		if after, ok := retryAfter(resp); ok {
+			if after > c.opts.MaxBackoff {
+				return nil, err
+			}
+			wait = max(wait, after)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends a request once, bounded by the configured timeout
+func (c *Client) attempt(ctx context.Context, method, path string, query url.Values, header http.Header, out any) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.opts.APIKey != "" {
+		req.Header.Set("X-API-Key", c.opts.APIKey)
+	}
+	if c.opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.opts.Token)
+	}
+	if c.opts.Tenant != "" {
+		req.Header.Set("X-Tenant-ID", c.opts.Tenant)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK && out != nil:
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp, fmt.Errorf("failed to decode response: %w", err)
+		}
+	case resp.StatusCode >= http.StatusBadRequest:
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(&apiErr.Response)
+		return resp, apiErr
+	}
+	// Drain the body so the connection is reused
+	io.Copy(io.Discard, resp.Body)
+	return resp, nil
+}
+
+// retryable reports whether a failed attempt may succeed when retried: the
+// server couldn't be reached or was overloaded
+func retryable(resp *http.Response, err error) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		// Network errors and timeouts; a body that failed to decode won't
+		// decode any better the next time
+		return resp == nil
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the jittered wait before the retry following attempt
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.opts.MinBackoff << min(attempt, 16)
+	if wait <= 0 || wait > c.opts.MaxBackoff {
+		wait = c.opts.MaxBackoff
+	}
+	// Jitter the second half of the wait so clients don't retry in step
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// retryAfter returns the wait a response asks for in its Retry-After header,
+// given in seconds
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
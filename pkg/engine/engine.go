@@ -0,0 +1,290 @@
+// Package engine embeds the targeting engine in a Go program. Campaigns and
+// targeting rules are fed in programmatically and matched in-process, with
+// the same validation and matching semantics as the HTTP service but without
+// a network hop or a database.
+//
+//	e := engine.New(engine.Options{})
+//	_, err := e.CreateCampaign(ctx, engine.CampaignRequest{ID: "spotify", Name: "Spotify", Image: "https://...", CTA: "Download"},
+//		engine.TargetingRule{IncludeCountry: []string{"US"}})
+//	campaigns, err := e.Match(ctx, engine.Request{App: "com.example", Country: "US", OS: "android"})
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Harshi-itaSinha/target-engine/internal/config"
+	model "github.com/Harshi-itaSinha/target-engine/internal/models"
+	"github.com/Harshi-itaSinha/target-engine/internal/repository"
+	"github.com/Harshi-itaSinha/target-engine/internal/segments"
+	"github.com/Harshi-itaSinha/target-engine/internal/service"
+	"github.com/Harshi-itaSinha/target-engine/internal/storage"
+	"github.com/Harshi-itaSinha/target-engine/internal/validation"
+)
+
+// Types shared with the HTTP API, documented in its OpenAPI description
+type (
+	Campaign            = model.Campaign
+	CampaignRequest     = model.CampaignRequest
+	TargetingRule       = model.TargetingRule
+	DimensionValues     = model.DimensionValues
+	Schedule            = model.Schedule
+	Expression          = model.Expression
+	HourRange           = model.HourRange
+	Experiment          = model.Experiment
+	Creative            = model.Creative
+	Request             = model.DeliveryRequest
+	Result              = model.DeliveryResponse
+	BatchResult         = model.BatchDeliveryResult
+	CampaignExplanation = model.CampaignExplanation
+	RuleExplanation     = model.RuleExplanation
+	FieldError          = model.FieldError
+	RuleConflict        = model.RuleConflict
+)
+
+// SegmentProvider looks up the audience segments of users, for rules with
+// IncludeSegment or ExcludeSegment
+type SegmentProvider = segments.Provider
+
+// Errors returned by the engine, to be checked with errors.Is
+var (
+	ErrNotFound        = repository.ErrNotFound
+	ErrAlreadyExists   = repository.ErrAlreadyExists
+	ErrInvalidCampaign = service.ErrInvalidCampaign
+	ErrInvalidRule     = service.ErrInvalidRule
+	ErrRuleConflict    = service.ErrRuleConflict
+	// ErrInvalidTransition is returned for status changes the campaign
+	// lifecycle doesn't allow
+	ErrInvalidTransition = service.ErrInvalidTransition
+)
+
+// Campaign statuses. Only ACTIVE campaigns are matched; SCHEDULED ones
+// become ACTIVE at their StartAt, and running ones COMPLETED at their EndAt.
+const (
+	StatusDraft     = model.StatusDraft
+	StatusScheduled = model.StatusScheduled
+	StatusActive    = model.StatusActive
+	StatusPaused    = model.StatusPaused
+	StatusCompleted = model.StatusCompleted
+)
+
+// Strategies for Select
+const (
+	SelectPriorityWeight = model.SelectionPriorityWeight
+	SelectRandom         = model.SelectionRandom
+	SelectRoundRobin     = model.SelectionRoundRobin
+)
+
+// Defaults for unset Options
+const (
+	DefaultCacheSize       = 10000
+	DefaultCacheTTL        = 5 * time.Minute
+	DefaultRefreshInterval = 10 * time.Minute
+)
+
+// Options configures an Engine. The zero value is ready to use.
+type Options struct {
+	// CacheSize bounds the number of cached match results
+	CacheSize int
+	// CacheTTL is how long match results are cached
+	CacheTTL time.Duration
+	// RefreshInterval is how often the compiled rule set is rebuilt. Writes
+	// through the Engine take effect immediately, so this only bounds how
+	// long expired query results linger.
+	RefreshInterval time.Duration
+	// CustomDimensions lists the custom dimensions rules may target and
+	// requests may carry
+	CustomDimensions []string
+	// Segments looks up the segments of the UserID of requests. Without it
+	// users belong to no segment.
+	Segments SegmentProvider
+}
+
+// Engine matches requests against campaigns held in memory. It is safe for
+// concurrent use. Its background workers run for the life of the process, so
+// create one Engine and share it.
+type Engine struct {
+	svc *service.TargetingService
+}
+
+// New creates an empty Engine. Frequency caps and budgets are counted in
+// memory, per Engine.
+func New(opts Options) *Engine {
+	cfg := &config.Config{}
+	cfg.Cache.MaxSize = orDefault(opts.CacheSize, DefaultCacheSize)
+	cfg.Cache.TTL = orDefault(opts.CacheTTL, DefaultCacheTTL)
+	cfg.Cache.CleanupInterval = orDefault(opts.RefreshInterval, DefaultRefreshInterval)
+	cfg.Dimensions.Custom = opts.CustomDimensions
+
+	svc := service.NewTargetingService(repository.NewEmptyMemoryRepository(), cfg, nil, storage.NewMemoryCounterStore(), nil)
+	// Loading an empty memory repository can't fail, so this returns at once
+	svc.WaitForWarmUp(context.Background())
+	if opts.Segments != nil {
+		svc.SetSegmentProvider(opts.Segments)
+	}
+	return &Engine{svc: svc}
+}
+
+// orDefault returns value, or fallback when value is zero or negative
+func orDefault[T int | time.Duration](value, fallback T) T {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// CreateCampaign stores a campaign together with its targeting rules. Rules
+// are matched ORed together, and a campaign without rules matches every
+// request. If a rule is invalid nothing is stored.
+func (e *Engine) CreateCampaign(ctx context.Context, req CampaignRequest, rules ...TargetingRule) (*Campaign, error) {
+	campaign, err := e.svc.CreateCampaign(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		rule.CampaignID = campaign.ID
+		if _, err := e.svc.CreateTargetingRule(ctx, &rule); err != nil {
+			if err := e.svc.DeleteCampaign(ctx, campaign.ID, true); err != nil {
+				return nil, fmt.Errorf("failed to roll back campaign %s: %w", campaign.ID, err)
+			}
+			return nil, err
+		}
+	}
+	return campaign, e.refresh()
+}
+
+// UpdateCampaign applies the non-empty fields of req to a campaign
+func (e *Engine) UpdateCampaign(ctx context.Context, id string, req CampaignRequest) (*Campaign, error) {
+	campaign, err := e.svc.UpdateCampaign(ctx, id, &req)
+	if err != nil {
+		return nil, err
+	}
+	return campaign, e.refresh()
+}
+
+// UpdateCampaignStatus moves a campaign to status along its lifecycle
+func (e *Engine) UpdateCampaignStatus(ctx context.Context, id, status string) (*Campaign, error) {
+	campaign, err := e.svc.UpdateCampaignStatus(ctx, id, status)
+	if err != nil {
+		return nil, err
+	}
+	return campaign, e.refresh()
+}
+
+// CloneCampaign copies a campaign and its targeting rules to a new DRAFT
+// campaign. The fields set in overrides replace those of the copy; without a
+// cid the copy's ID is generated.
+func (e *Engine) CloneCampaign(ctx context.Context, id string, overrides CampaignRequest) (*Campaign, []*TargetingRule, error) {
+	clone, err := e.svc.CloneCampaign(ctx, id, &overrides)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clone.Campaign, clone.Rules, e.refresh()
+}
+
+// DeleteCampaign removes a campaign and its targeting rules
+func (e *Engine) DeleteCampaign(ctx context.Context, id string) error {
+	if err := e.svc.DeleteCampaign(ctx, id, true); err != nil {
+		return err
+	}
+	return e.refresh()
+}
+
+// Campaigns returns every campaign, including inactive ones
+func (e *Engine) Campaigns(ctx context.Context) ([]*Campaign, error) {
+	var campaigns []*Campaign
+	filter := repository.CampaignFilter{Limit: service.MaxListLimit}
+	for {
+		page, err := e.svc.ListCampaigns(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, page.Campaigns...)
+		if len(page.Campaigns) < filter.Limit {
+			return campaigns, nil
+		}
+		filter.Offset += len(page.Campaigns)
+	}
+}
+
+// AddRule adds a targeting rule to the campaign named by rule.CampaignID.
+// Rules that can never match are rejected with ErrRuleConflict.
+func (e *Engine) AddRule(ctx context.Context, rule TargetingRule) (*TargetingRule, error) {
+	return e.svc.CreateTargetingRule(ctx, &rule)
+}
+
+// UpdateRule replaces a targeting rule
+func (e *Engine) UpdateRule(ctx context.Context, id int64, rule TargetingRule) (*TargetingRule, error) {
+	return e.svc.UpdateTargetingRule(ctx, id, &rule)
+}
+
+// AnalyzeRule reports the conflicts a targeting rule has on its own and with
+// the other rules of its campaign, without adding it
+func (e *Engine) AnalyzeRule(ctx context.Context, rule TargetingRule) ([]RuleConflict, error) {
+	analysis, err := e.svc.AnalyzeTargetingRule(ctx, &rule)
+	if err != nil {
+		return nil, err
+	}
+	return analysis.Conflicts, nil
+}
+
+// DeleteRule removes a targeting rule
+func (e *Engine) DeleteRule(ctx context.Context, id int64) error {
+	return e.svc.DeleteTargetingRule(ctx, id)
+}
+
+// Rules returns the targeting rules of a campaign
+func (e *Engine) Rules(ctx context.Context, campaignID string) ([]*TargetingRule, error) {
+	return e.svc.ListTargetingRules(ctx, campaignID)
+}
+
+// Match returns the active campaigns matching req, ordered by priority and
+// limited to req.Limit. Frequency caps apply when req.UserID is set.
+func (e *Engine) Match(ctx context.Context, req Request) ([]*Result, error) {
+	return e.svc.GetMatchingCampaigns(ctx, &req)
+}
+
+// MatchBatch matches several requests concurrently, as Match would, and
+// returns their results in request order. A request that fails gets an error
+// in its result rather than failing the batch.
+func (e *Engine) MatchBatch(ctx context.Context, reqs []Request) ([]*BatchResult, error) {
+	batch := &model.BatchDeliveryRequest{Requests: make([]*Request, len(reqs))}
+	for i, req := range reqs {
+		batch.Requests[i] = &req
+	}
+	return e.svc.GetMatchingCampaignsBatch(ctx, batch)
+}
+
+// Select returns the single campaign to serve for req, picked from the
+// matches by strategy (priority_weight when empty), or nil when none can be
+// served
+func (e *Engine) Select(ctx context.Context, req Request, strategy string) (*Result, error) {
+	return e.svc.SelectCampaign(ctx, &req, strategy)
+}
+
+// Explain reports which targeting rules of each active campaign, or only of
+// campaignID if set, match req and why the others don't. at is the time
+// rule schedules are evaluated at; the zero time means now.
+func (e *Engine) Explain(ctx context.Context, req Request, campaignID string, at time.Time) ([]*CampaignExplanation, error) {
+	return e.svc.ExplainDelivery(ctx, &req, campaignID, at)
+}
+
+// Fields returns the invalid fields of a validation error returned by the
+// engine, or nil for other errors
+func Fields(err error) []FieldError {
+	return validation.Fields(err)
+}
+
+// Conflicts returns the conflicts a rule was rejected for by an error
+// returned by the engine, or nil for other errors
+func Conflicts(err error) []RuleConflict {
+	return service.RuleConflicts(err)
+}
+
+// refresh rebuilds the compiled rule set, so campaign writes are matched
+// as soon as they return rather than after the service's background reload
+func (e *Engine) refresh() error {
+	_, err := e.svc.RefreshCache()
+	return err
+}
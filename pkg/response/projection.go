@@ -0,0 +1,103 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ParseFields parses a comma separated fields parameter selecting the
+// top-level JSON fields of a response, checking each against the JSON field
+// names of the struct type of of. An empty parameter selects every field and
+// returns nil.
+func ParseFields(param string, of any) ([]string, error) {
+	if strings.TrimSpace(param) == "" {
+		return nil, nil
+	}
+	known := jsonFieldNames(reflect.TypeOf(of))
+	var fields []string
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || slices.Contains(fields, field) {
+			continue
+		}
+		if !slices.Contains(known, field) {
+			return nil, fmt.Errorf("unknown field %q in fields, expected one of %s", field, strings.Join(known, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Project returns data with only the given top-level fields of its JSON
+// objects kept. Objects in a list are projected one by one, and other values
+// are returned as they encode. Without fields, data is returned unchanged.
+func Project(data any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var decoded any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+	switch decoded := decoded.(type) {
+	case map[string]any:
+		return projectObject(decoded, fields), nil
+	case []any:
+		for i, item := range decoded {
+			if object, ok := item.(map[string]any); ok {
+				decoded[i] = projectObject(object, fields)
+			}
+		}
+		return decoded, nil
+	}
+	return decoded, nil
+}
+
+// projectObject drops the keys of object not listed in fields
+func projectObject(object map[string]any, fields []string) map[string]any {
+	for key := range object {
+		if !slices.Contains(fields, key) {
+			delete(object, key)
+		}
+	}
+	return object
+}
+
+// jsonFieldNames returns the names the exported fields of a struct type, or
+// of the element type of a pointer or slice of structs, encode to in JSON
+func jsonFieldNames(t reflect.Type) []string {
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var names []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			if field.Anonymous {
+				names = append(names, jsonFieldNames(field.Type)...)
+				continue
+			}
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
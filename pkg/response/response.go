@@ -22,11 +22,21 @@ func Success(w http.ResponseWriter, data interface{}) {
 	JSON(w, http.StatusOK, data)
 }
 
+func Created(w http.ResponseWriter, data interface{}) {
+	JSON(w, http.StatusCreated, data)
+}
+
 func NoContent(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// NotModified writes a 304 response telling the client its cached copy,
+// identified by the ETag header already set, is still current
+func NotModified(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotModified)
+}
+
 func BadRequest(w http.ResponseWriter, message string) {
 	JSON(w, http.StatusBadRequest, &model.ErrorResponse{
 		Error:   "Bad Request",
@@ -35,6 +45,55 @@ func BadRequest(w http.ResponseWriter, message string) {
 	})
 }
 
+// InvalidFields writes a 400 response listing the fields that failed
+// validation
+func InvalidFields(w http.ResponseWriter, message string, fields []model.FieldError) {
+	JSON(w, http.StatusBadRequest, &model.ErrorResponse{
+		Error:   "Bad Request",
+		Message: message,
+		Code:    http.StatusBadRequest,
+		Fields:  fields,
+	})
+}
+
+// UnprocessableFields writes a 422 response listing the fields of a
+// well-formed payload that failed validation
+func UnprocessableFields(w http.ResponseWriter, message string, fields []model.FieldError) {
+	JSON(w, http.StatusUnprocessableEntity, &model.ErrorResponse{
+		Error:   "Unprocessable Entity",
+		Message: message,
+		Code:    http.StatusUnprocessableEntity,
+		Fields:  fields,
+	})
+}
+
+func Conflict(w http.ResponseWriter, message string) {
+	JSON(w, http.StatusConflict, &model.ErrorResponse{
+		Error:   "Conflict",
+		Message: message,
+		Code:    http.StatusConflict,
+	})
+}
+
+func UnprocessableEntity(w http.ResponseWriter, message string) {
+	JSON(w, http.StatusUnprocessableEntity, &model.ErrorResponse{
+		Error:   "Unprocessable Entity",
+		Message: message,
+		Code:    http.StatusUnprocessableEntity,
+	})
+}
+
+// RuleConflicts writes a 422 response listing the conflicts a targeting rule
+// was rejected for
+func RuleConflicts(w http.ResponseWriter, message string, conflicts []model.RuleConflict) {
+	JSON(w, http.StatusUnprocessableEntity, &model.ErrorResponse{
+		Error:     "Unprocessable Entity",
+		Message:   message,
+		Code:      http.StatusUnprocessableEntity,
+		Conflicts: conflicts,
+	})
+}
+
 func InternalServerError(w http.ResponseWriter, message string) {
 	JSON(w, http.StatusInternalServerError, &model.ErrorResponse{
 		Error:   "Internal Server Error",
@@ -74,3 +133,19 @@ func Forbidden(w http.ResponseWriter, message string) {
 		Code:    http.StatusForbidden,
 	})
 }
+
+func UnsupportedMediaType(w http.ResponseWriter, message string) {
+	JSON(w, http.StatusUnsupportedMediaType, &model.ErrorResponse{
+		Error:   "Unsupported Media Type",
+		Message: message,
+		Code:    http.StatusUnsupportedMediaType,
+	})
+}
+
+func ServiceUnavailable(w http.ResponseWriter, message string) {
+	JSON(w, http.StatusServiceUnavailable, &model.ErrorResponse{
+		Error:   "Service Unavailable",
+		Message: message,
+		Code:    http.StatusServiceUnavailable,
+	})
+}